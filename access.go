@@ -0,0 +1,105 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"vault-plugin-host/handlers"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+// sourceFilter admits requests by their source address. The host serves admin and
+// storage endpoints without authentication, so an instance that ends up on a shared
+// network can be limited to the machine it runs on or to known addresses.
+type sourceFilter struct {
+	localOnly bool
+	allowed   []*net.IPNet // empty admits every address (subject to localOnly)
+	logger    hclog.Logger
+}
+
+// newSourceFilter parses a comma-separated list of IP addresses and CIDR ranges. It
+// returns nil when neither an allowlist nor local-only mode is configured.
+func newSourceFilter(allowList string, localOnly bool, logger hclog.Logger) (*sourceFilter, error) {
+	filter := &sourceFilter{localOnly: localOnly, logger: logger}
+	for _, entry := range strings.Split(allowList, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid address %q in allowlist", entry)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			filter.allowed = append(filter.allowed, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid range %q in allowlist", entry)
+		}
+		filter.allowed = append(filter.allowed, network)
+	}
+	if !localOnly && len(filter.allowed) == 0 {
+		return nil, nil
+	}
+	return filter, nil
+}
+
+// admits reports whether a request from remoteAddr (host:port) is served
+func (f *sourceFilter) admits(remoteAddr string) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	if f.localOnly && !ip.IsLoopback() {
+		return false
+	}
+	if len(f.allowed) == 0 {
+		return true
+	}
+	for _, network := range f.allowed {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// wrap rejects requests from addresses the filter does not admit with 403
+func (f *sourceFilter) wrap(next http.Handler) http.Handler {
+	if f == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !f.admits(r.RemoteAddr) {
+			f.logger.Warn("rejected request from address outside the allowlist", "remote_addr", r.RemoteAddr, "method", r.Method, "path", r.URL.Path)
+			handlers.WriteError(w, http.StatusForbidden, "permission denied")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// listenAddr is the address the server binds: only the loopback interface in
+// local-only mode, otherwise every interface
+func listenAddr(port string, localOnly bool) string {
+	if localOnly {
+		return net.JoinHostPort("127.0.0.1", port)
+	}
+	return ":" + port
+}
@@ -0,0 +1,102 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+func TestSourceFilter(t *testing.T) {
+	t.Run("nothing configured", func(t *testing.T) {
+		filter, err := newSourceFilter(" , ", false, hclog.NewNullLogger())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if filter != nil {
+			t.Fatalf("expected no filter, got %+v", filter)
+		}
+		next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+		if filter.wrap(next) == nil {
+			t.Fatal("a nil filter should pass requests through")
+		}
+	})
+
+	t.Run("invalid entries", func(t *testing.T) {
+		for _, allow := range []string{"not-an-ip", "10.0.0.0/33", "10.0.0.1,bogus/8"} {
+			if _, err := newSourceFilter(allow, false, hclog.NewNullLogger()); err == nil {
+				t.Errorf("expected an error for %q", allow)
+			}
+		}
+	})
+
+	t.Run("admits", func(t *testing.T) {
+		tests := []struct {
+			allow     string
+			localOnly bool
+			addr      string
+			want      bool
+		}{
+			{"", true, "127.0.0.1:5000", true},
+			{"", true, "[::1]:5000", true},
+			{"", true, "192.168.1.20:5000", false},
+			{"10.0.0.0/8", false, "10.1.2.3:5000", true},
+			{"10.0.0.0/8", false, "11.1.2.3:5000", false},
+			{"192.168.1.20", false, "192.168.1.20:5000", true},
+			{"192.168.1.20", false, "192.168.1.21:5000", false},
+			{"fd00::/8", false, "[fd00::1]:5000", true},
+			{"fd00::/8", false, "[fe80::1]:5000", false},
+			{"10.0.0.0/8", true, "10.1.2.3:5000", false},
+			{"127.0.0.0/8", true, "127.0.0.1:5000", true},
+			{"10.0.0.0/8", false, "garbage", false},
+		}
+		for _, tt := range tests {
+			filter, err := newSourceFilter(tt.allow, tt.localOnly, hclog.NewNullLogger())
+			if err != nil {
+				t.Fatalf("newSourceFilter(%q): %v", tt.allow, err)
+			}
+			if got := filter.admits(tt.addr); got != tt.want {
+				t.Errorf("allow=%q local-only=%t: admits(%q) = %t, want %t", tt.allow, tt.localOnly, tt.addr, got, tt.want)
+			}
+		}
+	})
+
+	t.Run("middleware", func(t *testing.T) {
+		filter, err := newSourceFilter("10.0.0.0/8", false, hclog.NewNullLogger())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		served := false
+		handler := filter.wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			served = true
+		}))
+
+		req := httptest.NewRequest(http.MethodGet, "/v1/sys/storage", nil)
+		req.RemoteAddr = "192.168.1.20:5000"
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if w.Code != http.StatusForbidden || served {
+			t.Fatalf("expected 403 without reaching the handler, got %d (served=%t)", w.Code, served)
+		}
+
+		req.RemoteAddr = "10.0.0.5:5000"
+		w = httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if w.Code != http.StatusOK || !served {
+			t.Fatalf("expected the request to be served, got %d", w.Code)
+		}
+	})
+
+	t.Run("listen address", func(t *testing.T) {
+		if got := listenAddr("8300", true); got != "127.0.0.1:8300" {
+			t.Errorf("local-only listen address = %q", got)
+		}
+		if got := listenAddr("8300", false); got != ":8300" {
+			t.Errorf("listen address = %q", got)
+		}
+	})
+}
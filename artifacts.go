@@ -0,0 +1,205 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"vault-plugin-host/handlers"
+)
+
+// artifactsDirEnv is the environment variable through which the plugin is told where to write artifacts
+const artifactsDirEnv = "VAULT_PLUGIN_ARTIFACTS_DIR"
+
+// Artifact describes a file the plugin left in the artifact directory
+type Artifact struct {
+	Path     string    `json:"path"`
+	Size     int64     `json:"size"`
+	Modified time.Time `json:"modified"`
+}
+
+// ArtifactDir exposes a scratch directory that plugins under test can drop debug files into
+type ArtifactDir struct {
+	dir        string
+	passphrase string // encrypts downloads when set
+}
+
+// NewArtifactDir creates the directory if needed
+func NewArtifactDir(dir string) (*ArtifactDir, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create artifact directory: %w", err)
+	}
+	return &ArtifactDir{dir: dir}, nil
+}
+
+// Path returns the directory on disk
+func (a *ArtifactDir) Path() string {
+	return a.dir
+}
+
+// SetExportPassphrase makes artifact downloads encrypted with passphrase, in the
+// format of encrypted storage snapshots
+func (a *ArtifactDir) SetExportPassphrase(passphrase string) {
+	a.passphrase = passphrase
+}
+
+// List returns every file under the directory, with slash-separated relative paths
+func (a *ArtifactDir) List() ([]Artifact, error) {
+	root, err := os.OpenRoot(a.dir)
+	if err != nil {
+		return nil, err
+	}
+	defer root.Close()
+
+	artifacts := []Artifact{}
+	err = fs.WalkDir(root.FS(), ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		artifacts = append(artifacts, Artifact{Path: name, Size: info.Size(), Modified: info.ModTime()})
+		return nil
+	})
+	return artifacts, err
+}
+
+// Clear removes everything in the directory, keeping the directory itself
+func (a *ArtifactDir) Clear() error {
+	root, err := os.OpenRoot(a.dir)
+	if err != nil {
+		return err
+	}
+	defer root.Close()
+
+	entries, err := fs.ReadDir(root.FS(), ".")
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if err := root.RemoveAll(entry.Name()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// HandleArtifacts serves the artifact directory at /v1/sys/host/artifacts:
+//
+//	GET    /v1/sys/host/artifacts         - list artifacts
+//	GET    /v1/sys/host/artifacts/<path>  - download an artifact
+//	DELETE /v1/sys/host/artifacts         - remove all artifacts
+//	DELETE /v1/sys/host/artifacts/<path>  - remove one artifact
+func (a *ArtifactDir) HandleArtifacts(w http.ResponseWriter, r *http.Request) {
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/v1/sys/host/artifacts"), "/")
+	if name != "" && (!fs.ValidPath(name) || path.Clean(name) != name) {
+		handlers.WriteError(w, http.StatusBadRequest, fmt.Sprintf("invalid artifact path %q", name))
+		return
+	}
+
+	switch {
+	case name == "" && r.Method == http.MethodGet:
+		artifacts, err := a.List()
+		if err != nil {
+			handlers.WriteError(w, http.StatusInternalServerError, fmt.Sprintf("failed to list artifacts: %v", err))
+			return
+		}
+		handlers.WriteJSON(w, http.StatusOK, map[string]interface{}{
+			"dir":       a.dir,
+			"artifacts": artifacts,
+		})
+
+	case name == "" && r.Method == http.MethodDelete:
+		if err := a.Clear(); err != nil {
+			handlers.WriteError(w, http.StatusInternalServerError, fmt.Sprintf("failed to clear artifacts: %v", err))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	case r.Method == http.MethodGet:
+		a.serveArtifact(w, r, name)
+
+	case r.Method == http.MethodDelete:
+		root, err := os.OpenRoot(a.dir)
+		if err != nil {
+			handlers.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		defer root.Close()
+
+		if err := root.Remove(name); err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				handlers.WriteError(w, http.StatusNotFound, fmt.Sprintf("artifact %q not found", name))
+				return
+			}
+			handlers.WriteError(w, http.StatusInternalServerError, fmt.Sprintf("failed to remove artifact: %v", err))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		handlers.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// serveArtifact writes a single file, refusing paths that escape the directory
+func (a *ArtifactDir) serveArtifact(w http.ResponseWriter, r *http.Request, name string) {
+	root, err := os.OpenRoot(a.dir)
+	if err != nil {
+		handlers.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer root.Close()
+
+	f, err := root.Open(name)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			handlers.WriteError(w, http.StatusNotFound, fmt.Sprintf("artifact %q not found", name))
+			return
+		}
+		handlers.WriteError(w, http.StatusBadRequest, fmt.Sprintf("failed to open artifact: %v", err))
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		handlers.WriteError(w, http.StatusNotFound, fmt.Sprintf("artifact %q not found", name))
+		return
+	}
+
+	if a.passphrase == "" {
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", path.Base(name)))
+		http.ServeContent(w, r, name, info.ModTime(), f)
+		return
+	}
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		handlers.WriteError(w, http.StatusInternalServerError, fmt.Sprintf("failed to read artifact: %v", err))
+		return
+	}
+	encrypted, err := handlers.EncryptExport(data, a.passphrase)
+	if err != nil {
+		handlers.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", path.Base(name)+".enc"))
+	w.WriteHeader(http.StatusOK)
+	w.Write(encrypted)
+}
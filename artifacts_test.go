@@ -0,0 +1,74 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"vault-plugin-host/handlers"
+)
+
+func TestArtifactDir(t *testing.T) {
+	dir := t.TempDir()
+	artifacts, err := NewArtifactDir(dir)
+	if err != nil {
+		t.Fatalf("NewArtifactDir failed: %v", err)
+	}
+
+	os.MkdirAll(filepath.Join(dir, "certs"), 0o755)
+	os.WriteFile(filepath.Join(dir, "certs", "ca.pem"), []byte("-----BEGIN CERTIFICATE-----"), 0o644)
+	os.WriteFile(filepath.Join(dir, "report.txt"), []byte("ok"), 0o644)
+
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		artifacts.HandleArtifacts(w, httptest.NewRequest(method, path, nil))
+		return w
+	}
+
+	w := serve("GET", "/v1/sys/host/artifacts")
+	var listing struct {
+		Artifacts []Artifact `json:"artifacts"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&listing); err != nil {
+		t.Fatalf("failed to decode listing: %v", err)
+	}
+	if len(listing.Artifacts) != 2 || listing.Artifacts[0].Path != "certs/ca.pem" || listing.Artifacts[1].Size != 2 {
+		t.Errorf("listing = %+v", listing.Artifacts)
+	}
+
+	if w := serve("GET", "/v1/sys/host/artifacts/certs/ca.pem"); w.Code != http.StatusOK || w.Body.String() != "-----BEGIN CERTIFICATE-----" {
+		t.Errorf("download: status %d body %q", w.Code, w.Body.String())
+	}
+	artifacts.SetExportPassphrase("passphrase")
+	w = serve("GET", "/v1/sys/host/artifacts/certs/ca.pem")
+	if plaintext, err := handlers.DecryptExport(w.Body.Bytes(), "passphrase"); w.Code != http.StatusOK || err != nil || string(plaintext) != "-----BEGIN CERTIFICATE-----" {
+		t.Errorf("encrypted download: status %d, %q, %v", w.Code, plaintext, err)
+	}
+	artifacts.SetExportPassphrase("")
+
+	if w := serve("GET", "/v1/sys/host/artifacts/missing"); w.Code != http.StatusNotFound {
+		t.Errorf("missing artifact: status %d", w.Code)
+	}
+	if w := serve("GET", "/v1/sys/host/artifacts/certs/../../etc/passwd"); w.Code != http.StatusBadRequest {
+		t.Errorf("path traversal: status %d", w.Code)
+	}
+
+	if w := serve("DELETE", "/v1/sys/host/artifacts/report.txt"); w.Code != http.StatusNoContent {
+		t.Errorf("delete one: status %d", w.Code)
+	}
+	if w := serve("DELETE", "/v1/sys/host/artifacts"); w.Code != http.StatusNoContent {
+		t.Errorf("clear: status %d", w.Code)
+	}
+	if list, _ := artifacts.List(); len(list) != 0 {
+		t.Errorf("artifacts remain after clear: %+v", list)
+	}
+	if _, err := os.Stat(dir); err != nil {
+		t.Errorf("artifact directory removed by clear: %v", err)
+	}
+}
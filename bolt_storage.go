@@ -0,0 +1,160 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	bolt "go.etcd.io/bbolt"
+)
+
+// boltStorageFile is the database of -storage=bbolt below -storage-path
+const boltStorageFile = "storage.db"
+
+// OpenBoltDB opens (creating if necessary) a bbolt database. bbolt locks the file, so
+// a second host started on the same -storage-path fails here instead of waiting.
+func OpenBoltDB(path string) (*bolt.DB, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s (is another host using it?): %w", path, err)
+	}
+	return db, nil
+}
+
+// BoltStorage implements logical.Storage on top of InMemoryStorage, persisting every
+// entry in a bucket of a bbolt database. Reads are served from memory; each Put and
+// Delete commits a transaction before memory is updated, and a restore replaces the
+// bucket's contents in a single transaction, so a crash never leaves it half written.
+type BoltStorage struct {
+	*InMemoryStorage
+	db      *bolt.DB
+	bucket  []byte
+	writeMu sync.Mutex // orders database and memory updates so they cannot diverge
+}
+
+// NewBoltStorage opens (creating if necessary) a bucket of db and loads its entries
+func NewBoltStorage(db *bolt.DB, bucket string) (*BoltStorage, error) {
+	s := &BoltStorage{
+		InMemoryStorage: NewInMemoryStorage(),
+		db:              db,
+		bucket:          []byte(bucket),
+	}
+	err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(s.bucket)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create storage bucket %s: %w", bucket, err)
+	}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Path returns the database file and the bucket of the storage
+func (s *BoltStorage) Path() string {
+	return fmt.Sprintf("%s (bucket %s)", s.db.Path(), s.bucket)
+}
+
+// load reads every persisted entry into memory
+func (s *BoltStorage) load() error {
+	ctx := context.Background()
+	return s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(s.bucket).ForEach(func(key, data []byte) error {
+			var entry logical.StorageEntry
+			if err := json.Unmarshal(data, &entry); err != nil {
+				return fmt.Errorf("failed to parse storage entry %q: %w", key, err)
+			}
+			return s.InMemoryStorage.Put(ctx, &entry)
+		})
+	})
+}
+
+func (s *BoltStorage) Put(ctx context.Context, entry *logical.StorageEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(s.bucket).Put([]byte(entry.Key), data)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to persist %q: %w", entry.Key, err)
+	}
+	return s.InMemoryStorage.Put(ctx, entry)
+}
+
+func (s *BoltStorage) Delete(ctx context.Context, key string) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(s.bucket).Delete([]byte(key))
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete %q: %w", key, err)
+	}
+	return s.InMemoryStorage.Delete(ctx, key)
+}
+
+// RestoreEntries swaps in the contents a storage snapshot restore leaves behind, as
+// InMemoryStorage.RestoreEntries does, and rewrites the bucket to match
+func (s *BoltStorage) RestoreEntries(ctx context.Context, entries []*logical.StorageEntry, merge bool) error {
+	return s.Restore(s.restoredContents(ctx, entries, merge))
+}
+
+// Restore replaces the contents of the storage with a snapshot and rewrites the
+// bucket to match in one transaction
+func (s *BoltStorage) Restore(snapshot *InMemoryStorage) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	ctx := context.Background()
+	keys, err := snapshot.List(ctx, "")
+	if err != nil {
+		return err
+	}
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.DeleteBucket(s.bucket); err != nil {
+			return err
+		}
+		bucket, err := tx.CreateBucket(s.bucket)
+		if err != nil {
+			return err
+		}
+		for _, key := range keys {
+			entry, err := snapshot.Get(ctx, key)
+			if err != nil {
+				return err
+			}
+			if entry == nil {
+				continue
+			}
+			data, err := json.Marshal(entry)
+			if err != nil {
+				return err
+			}
+			if err := bucket.Put([]byte(key), data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to restore storage: %w", err)
+	}
+
+	s.InMemoryStorage.Restore(snapshot)
+	return nil
+}
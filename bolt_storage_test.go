@@ -0,0 +1,106 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestBoltStoragePersistsAcrossRestarts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), boltStorageFile)
+	ctx := context.Background()
+
+	db, err := OpenBoltDB(path)
+	if err != nil {
+		t.Fatalf("OpenBoltDB failed: %v", err)
+	}
+	storage, err := NewBoltStorage(db, "plugin")
+	if err != nil {
+		t.Fatalf("NewBoltStorage failed: %v", err)
+	}
+	cubbyhole, _ := NewBoltStorage(db, "cubbyhole")
+
+	for _, key := range []string{"config", "config/roles", "creds/a"} {
+		if err := storage.Put(ctx, &logical.StorageEntry{Key: key, Value: []byte(key), SealWrap: key == "config"}); err != nil {
+			t.Fatalf("Put %s failed: %v", key, err)
+		}
+	}
+	storage.Put(ctx, &logical.StorageEntry{Key: "creds/a", Value: []byte("updated")})
+	if err := storage.Delete(ctx, "config/roles"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := storage.Delete(ctx, "missing"); err != nil {
+		t.Errorf("Delete of a missing key failed: %v", err)
+	}
+	cubbyhole.Put(ctx, &logical.StorageEntry{Key: "wrapped", Value: []byte("x")})
+
+	// The database is locked while it is open
+	if _, err := OpenBoltDB(path); err == nil || !strings.Contains(err.Error(), "another host") {
+		t.Errorf("second open = %v, want a lock error", err)
+	}
+	db.Close()
+
+	db, err = OpenBoltDB(path)
+	if err != nil {
+		t.Fatalf("reopen failed: %v", err)
+	}
+	defer db.Close()
+	reopened, err := NewBoltStorage(db, "plugin")
+	if err != nil {
+		t.Fatalf("NewBoltStorage after reopen failed: %v", err)
+	}
+
+	keys, _ := reopened.List(ctx, "")
+	if got := strings.Join(keys, ","); got != "config,creds/a" {
+		t.Errorf("keys after reopen = %s", got)
+	}
+	if entry, _ := reopened.Get(ctx, "creds/a"); entry == nil || string(entry.Value) != "updated" {
+		t.Errorf("creds/a after reopen = %v", entry)
+	}
+	if entry, _ := reopened.Get(ctx, "config"); entry == nil || !entry.SealWrap {
+		t.Errorf("config lost SealWrap after reopen: %v", entry)
+	}
+	if cubbyhole, _ := NewBoltStorage(db, "cubbyhole"); cubbyhole.Stats().Entries != 1 {
+		t.Errorf("cubbyhole bucket has %d entries, want 1", cubbyhole.Stats().Entries)
+	}
+}
+
+func TestBoltStorageRestore(t *testing.T) {
+	db, err := OpenBoltDB(filepath.Join(t.TempDir(), boltStorageFile))
+	if err != nil {
+		t.Fatalf("OpenBoltDB failed: %v", err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+
+	storage, _ := NewBoltStorage(db, "plugin")
+	storage.Put(ctx, &logical.StorageEntry{Key: "seed", Value: []byte("seed")})
+	checkpoint := storage.Snapshot()
+
+	storage.Put(ctx, &logical.StorageEntry{Key: "scratch", Value: []byte("x")})
+	storage.Delete(ctx, "seed")
+
+	if err := storage.Restore(checkpoint); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	reloaded, _ := NewBoltStorage(db, "plugin")
+	keys, _ := reloaded.List(ctx, "")
+	if got := strings.Join(keys, ","); got != "seed" {
+		t.Errorf("keys in the database after restore = %s, want seed", got)
+	}
+
+	if err := storage.RestoreEntries(ctx, []*logical.StorageEntry{{Key: "config", Value: []byte("a")}}, true); err != nil {
+		t.Fatalf("RestoreEntries failed: %v", err)
+	}
+	reloaded, _ = NewBoltStorage(db, "plugin")
+	keys, _ = reloaded.List(ctx, "")
+	if got := strings.Join(keys, ","); got != "config,seed" {
+		t.Errorf("keys in the database after a merge = %s, want config,seed", got)
+	}
+}
@@ -0,0 +1,386 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/hcl"
+	"gopkg.in/yaml.v3"
+)
+
+// configSections maps the keys of each block of a -config-file to the flags they set
+var configSections = map[string]map[string]string{
+	"listener": {
+		"port":             "port",
+		"local_only":       "local-only",
+		"allow_ips":        "allow-ips",
+		"tls_cert":         "tls-cert",
+		"tls_key":          "tls-key",
+		"tls_client_ca":    "tls-client-ca",
+		"max_conns":        "max-conns",
+		"max_header_bytes": "max-header-bytes",
+		"read_timeout":     "read-timeout",
+		"write_timeout":    "write-timeout",
+		"idle_timeout":     "idle-timeout",
+		"shutdown_timeout": "shutdown-timeout",
+	},
+	"storage": {
+		"type": "storage",
+		"path": "storage-path",
+		"view": "storage-view",
+		"seed": "seed-storage",
+
+		"vault_addr":  "storage-vault-addr",
+		"vault_token": "storage-vault-token",
+		"vault_path":  "storage-vault-path",
+	},
+	"system_view": {
+		"default_lease_ttl": "default-lease-ttl",
+		"max_lease_ttl":     "max-lease-ttl",
+		"mlock":             "mlock",
+		"local_mount":       "local-mount",
+		"cluster_id":        "cluster-id",
+		"vault_version":     "vault-version",
+
+		"passthrough_request_headers": "passthrough-request-headers",
+		"allowed_response_headers":    "allowed-response-headers",
+		"response_wrap_ttl":           "response-wrap-ttl",
+		"max_response_wrap_ttl":       "max-response-wrap-ttl",
+	},
+	"audit": {
+		"path": "audit-path",
+		"hmac": "audit-hmac",
+	},
+}
+
+// configEnvReference matches ${NAME} or ${NAME:-default} in a -config-file string
+var configEnvReference = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// hostConfig is a parsed -config-file: flag values by flag name and the mounts in the
+// order they were given
+type hostConfig struct {
+	flags  map[string]string
+	mounts []configMount
+}
+
+// configMount is a mount block: its path and the options POST /v1/sys/mounts accepts
+type configMount struct {
+	path    string
+	options map[string]interface{}
+}
+
+// loadHostConfig reads a -config-file. .yaml and .yml files are YAML; anything else
+// is HCL, which includes JSON. ${NAME} in strings is replaced with the environment
+// variable NAME, or with the default of ${NAME:-default} when it is unset.
+func loadHostConfig(path string) (*hostConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw map[string]interface{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &raw)
+	default:
+		err = hcl.Decode(&raw, string(data))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	expanded, err := expandConfigEnv(raw)
+	if err != nil {
+		return nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+	config, err := parseHostConfig(expanded.(map[string]interface{}))
+	if err != nil {
+		return nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+	return config, nil
+}
+
+// parseHostConfig turns a decoded config file into flag values and mounts
+func parseHostConfig(raw map[string]interface{}) (*hostConfig, error) {
+	config := &hostConfig{flags: make(map[string]string)}
+	keys := make([]string, 0, len(raw))
+	for key := range raw {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := raw[key]
+		if section, ok := configSections[key]; ok {
+			block, err := configBlock(key, value)
+			if err != nil {
+				return nil, err
+			}
+			for name, item := range block {
+				flagName, ok := section[name]
+				if !ok {
+					return nil, fmt.Errorf("unknown setting %s.%s", key, name)
+				}
+				if config.flags[flagName], err = configFlagValue(key+"."+name, item); err != nil {
+					return nil, err
+				}
+			}
+			continue
+		}
+
+		switch key {
+		case "mount", "mounts":
+			mounts, err := configMounts(value, key == "mount")
+			if err != nil {
+				return nil, err
+			}
+			config.mounts = append(config.mounts, mounts...)
+		case "plugin", "config-file", "config_file":
+			return nil, fmt.Errorf("%s cannot be set in a config file; use mount blocks for plugins", key)
+		default:
+			// Any other setting is a flag, named with dashes or underscores
+			flagName := strings.ReplaceAll(key, "_", "-")
+			formatted, err := configFlagValue(key, value)
+			if err != nil {
+				return nil, err
+			}
+			config.flags[flagName] = formatted
+		}
+	}
+	return config, nil
+}
+
+// configBlock returns the settings of a block. HCL decodes a block as a list of
+// objects, YAML and JSON as an object.
+func configBlock(name string, value interface{}) (map[string]interface{}, error) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return v, nil
+	case []map[string]interface{}:
+		block := make(map[string]interface{})
+		for _, item := range v {
+			for key, setting := range item {
+				block[key] = setting
+			}
+		}
+		return block, nil
+	}
+	return nil, fmt.Errorf("%s must be a block", name)
+}
+
+// configMounts reads mounts given as HCL blocks (mount "path" { plugin = ... }) or as
+// a list of objects with a path (mounts: [{path: ..., plugin: ...}])
+func configMounts(value interface{}, labeled bool) ([]configMount, error) {
+	// HCL decodes a list of objects as it does blocks
+	if list, ok := value.([]map[string]interface{}); ok && !labeled {
+		items := make([]interface{}, len(list))
+		for i, item := range list {
+			items[i] = item
+		}
+		value = items
+	}
+
+	var mounts []configMount
+	switch v := value.(type) {
+	case []map[string]interface{}:
+		for _, block := range v {
+			paths := make([]string, 0, len(block))
+			for path := range block {
+				paths = append(paths, path)
+			}
+			sort.Strings(paths)
+			for _, path := range paths {
+				options, err := configBlock("mount "+path, block[path])
+				if err != nil {
+					return nil, err
+				}
+				mounts = append(mounts, configMount{path: path, options: options})
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			options, ok := item.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("mount %d must be an object", i+1)
+			}
+			path, _ := options["path"].(string)
+			if path == "" {
+				return nil, fmt.Errorf("mount %d has no path", i+1)
+			}
+			rest := make(map[string]interface{}, len(options))
+			for key, option := range options {
+				if key != "path" {
+					rest[key] = option
+				}
+			}
+			mounts = append(mounts, configMount{path: path, options: rest})
+		}
+	default:
+		return nil, fmt.Errorf("mounts must be mount blocks or a list")
+	}
+
+	for _, mount := range mounts {
+		for _, key := range []string{"config", "system_view"} {
+			if option, ok := mount.options[key]; ok {
+				mount.options[key] = configObject(option)
+			}
+		}
+		if plugin, _ := mount.options["plugin"].(string); plugin == "" {
+			return nil, fmt.Errorf("mount %s has no plugin", mount.path)
+		}
+		for key := range mount.options {
+			if key != "plugin" && key != "config" && key != "system_view" && key != "sha256" {
+				return nil, fmt.Errorf("unknown setting %q in mount %s", key, mount.path)
+			}
+		}
+	}
+	return mounts, nil
+}
+
+// configFlagValue formats a setting as a flag value
+func configFlagValue(name string, value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case []interface{}:
+		// Lists, such as of headers or IP ranges, become comma-separated flag values
+		items := make([]string, len(v))
+		for i, item := range v {
+			formatted, err := configFlagValue(name, item)
+			if err != nil {
+				return "", err
+			}
+			items[i] = formatted
+		}
+		return strings.Join(items, ","), nil
+	}
+	return "", fmt.Errorf("%s must be a string, number, boolean or list", name)
+}
+
+// expandConfigEnv replaces environment references in every string of a decoded config
+func expandConfigEnv(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		var err error
+		expanded := configEnvReference.ReplaceAllStringFunc(v, func(ref string) string {
+			m := configEnvReference.FindStringSubmatch(ref)
+			if env, ok := os.LookupEnv(m[1]); ok {
+				return env
+			}
+			if m[2] != "" {
+				return m[3]
+			}
+			if err == nil {
+				err = fmt.Errorf("environment variable %q is not set", m[1])
+			}
+			return ref
+		})
+		return expanded, err
+	case map[string]interface{}:
+		for key, item := range v {
+			expanded, err := expandConfigEnv(item)
+			if err != nil {
+				return nil, err
+			}
+			v[key] = expanded
+		}
+	case []map[string]interface{}:
+		for _, item := range v {
+			if _, err := expandConfigEnv(item); err != nil {
+				return nil, err
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			expanded, err := expandConfigEnv(item)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = expanded
+		}
+	}
+	return value, nil
+}
+
+// apply sets the flags of the config file that were not given on the command line,
+// which take precedence, and returns the options of the additional mounts by path.
+// The first mount becomes the -plugin/-mount pair, with its config as -config and its
+// sha256 as -plugin-sha256; when the command line names plugins, the file's mounts are
+// ignored.
+func (c *hostConfig) apply(fs *flag.FlagSet) (map[string]map[string]interface{}, error) {
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+
+	names := make([]string, 0, len(c.flags))
+	for name := range c.flags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if fs.Lookup(name) == nil {
+			return nil, fmt.Errorf("unknown setting %q", name)
+		}
+		if explicit[name] {
+			continue
+		}
+		if err := fs.Set(name, c.flags[name]); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", name, err)
+		}
+	}
+
+	options := make(map[string]map[string]interface{})
+	if explicit["plugin"] || explicit["mount"] {
+		return options, nil
+	}
+	for i, mount := range c.mounts {
+		fs.Set("plugin", mount.options["plugin"].(string))
+		fs.Set("mount", mount.path)
+		if i > 0 {
+			options[strings.Trim(mount.path, "/")] = mount.options
+			continue
+		}
+
+		// The first mount is tuned by the system_view block and the flags
+		if mount.options["system_view"] != nil {
+			return nil, fmt.Errorf("mount %s is the primary mount; tune it with the system_view block", mount.path)
+		}
+		if config, ok := mount.options["config"]; ok && !explicit["config"] {
+			data, err := json.Marshal(config)
+			if err != nil {
+				return nil, fmt.Errorf("invalid config of mount %s: %w", mount.path, err)
+			}
+			fs.Set("config", string(data))
+		}
+		if digest, ok := mount.options["sha256"]; ok && !explicit["plugin-sha256"] {
+			fs.Set("plugin-sha256", fmt.Sprint(digest))
+		}
+	}
+	return options, nil
+}
+
+// configObject returns a nested object of a mount, such as its config, as a map; HCL
+// decodes a nested block as a list of objects
+func configObject(value interface{}) interface{} {
+	if block, ok := value.([]map[string]interface{}); ok {
+		object, _ := configBlock("", block)
+		return object
+	}
+	return value
+}
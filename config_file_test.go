@@ -0,0 +1,186 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// configFlagSet returns a flag set with the flags a config file is applied to in tests
+func configFlagSet() (*flag.FlagSet, *stringsFlag, *stringsFlag) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.String("port", "8300", "")
+	fs.Bool("local-only", false, "")
+	fs.String("storage", "inmem", "")
+	fs.String("storage-path", "", "")
+	fs.Duration("default-lease-ttl", 30*time.Second, "")
+	fs.String("audit-path", "", "")
+	fs.String("config", "", "")
+	fs.String("plugin-sha256", "", "")
+	fs.Bool("verbose", false, "")
+	plugins, mounts := &stringsFlag{}, &stringsFlag{}
+	fs.Var(plugins, "plugin", "")
+	fs.Var(mounts, "mount", "")
+	return fs, plugins, mounts
+}
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestHostConfigFormats(t *testing.T) {
+	t.Setenv("HOST_PORT", "9000")
+	hclConfig := `
+listener {
+  port       = "${HOST_PORT}"
+  local_only = true
+}
+storage {
+  type = "file"
+  path = "${DATA_DIR:-/tmp/data}"
+}
+system_view {
+  default_lease_ttl = "1h"
+}
+audit {
+  path = "audit.log"
+}
+verbose = true
+mount "kv" {
+  plugin = "./kv-plugin"
+  sha256 = "%[1]s"
+  config {
+    region = "us-east-1"
+  }
+}
+mount "db" {
+  plugin = "./db-plugin"
+  sha256 = "%[2]s"
+  system_view {
+    max_lease_ttl = "2h"
+  }
+}
+`
+	yamlConfig := `
+listener:
+  port: ${HOST_PORT}
+  local_only: true
+storage:
+  type: file
+  path: ${DATA_DIR:-/tmp/data}
+system_view:
+  default_lease_ttl: 1h
+audit:
+  path: audit.log
+verbose: true
+mounts:
+  - path: kv
+    plugin: ./kv-plugin
+    sha256: %[1]s
+    config:
+      region: us-east-1
+  - path: db
+    plugin: ./db-plugin
+    sha256: %[2]s
+    system_view:
+      max_lease_ttl: 2h
+`
+	kvSHA256, dbSHA256 := strings.Repeat("a", 64), strings.Repeat("d", 64)
+	for name, content := range map[string]string{"host.hcl": hclConfig, "host.yaml": yamlConfig} {
+		t.Run(name, func(t *testing.T) {
+			config, err := loadHostConfig(writeConfigFile(t, name, fmt.Sprintf(content, kvSHA256, dbSHA256)))
+			if err != nil {
+				t.Fatalf("loadHostConfig failed: %v", err)
+			}
+			fs, plugins, mounts := configFlagSet()
+			options, err := config.apply(fs)
+			if err != nil {
+				t.Fatalf("apply failed: %v", err)
+			}
+
+			want := map[string]string{
+				"port": "9000", "local-only": "true", "storage": "file", "storage-path": "/tmp/data",
+				"default-lease-ttl": "1h0m0s", "audit-path": "audit.log", "verbose": "true",
+				"config": `{"region":"us-east-1"}`, "plugin-sha256": kvSHA256,
+			}
+			for flagName, value := range want {
+				if got := fs.Lookup(flagName).Value.String(); got != value {
+					t.Errorf("-%s = %q, want %q", flagName, got, value)
+				}
+			}
+			if !reflect.DeepEqual([]string(*plugins), []string{"./kv-plugin", "./db-plugin"}) ||
+				!reflect.DeepEqual([]string(*mounts), []string{"kv", "db"}) {
+				t.Errorf("mounts should become -plugin/-mount pairs in order, got %v %v", *plugins, *mounts)
+			}
+			wantDB := map[string]interface{}{
+				"plugin":      "./db-plugin",
+				"sha256":      dbSHA256,
+				"system_view": map[string]interface{}{"max_lease_ttl": "2h"},
+			}
+			if len(options) != 1 || !reflect.DeepEqual(options["db"], wantDB) {
+				t.Errorf("additional mount options = %v, want db: %v", options, wantDB)
+			}
+		})
+	}
+}
+
+func TestHostConfigPrecedence(t *testing.T) {
+	config, err := loadHostConfig(writeConfigFile(t, "host.json",
+		`{"listener": {"port": 9000}, "storage": {"type": "file"}, "mounts": [{"path": "kv", "plugin": "./kv-plugin"}]}`))
+	if err != nil {
+		t.Fatalf("loadHostConfig failed: %v", err)
+	}
+	fs, plugins, _ := configFlagSet()
+	if err := fs.Parse([]string{"-port", "8500", "-plugin", "./other"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := config.apply(fs); err != nil {
+		t.Fatalf("apply failed: %v", err)
+	}
+	if got := fs.Lookup("port").Value.String(); got != "8500" {
+		t.Errorf("command-line -port should win, got %s", got)
+	}
+	if got := fs.Lookup("storage").Value.String(); got != "file" {
+		t.Errorf("-storage = %s, want file from the config file", got)
+	}
+	if !reflect.DeepEqual([]string(*plugins), []string{"./other"}) {
+		t.Errorf("config file mounts should be ignored when -plugin is given, got %v", *plugins)
+	}
+}
+
+func TestHostConfigErrors(t *testing.T) {
+	tests := map[string]string{
+		`listener { port = "${VAULT_PLUGIN_HOST_UNSET_TEST}" }`:       "is not set",
+		`listener { bind = "0.0.0.0" }`:                               "unknown setting listener.bind",
+		`plugin = "./kv"`:                                             "use mount blocks",
+		`mount "kv" { config { a = "b" } }`:                           "has no plugin",
+		`mount "kv" { plugin = "./kv" image = "x" }`:                  "unknown setting",
+		`mount "kv" { plugin = "./kv" system_view { mlock = true } }`: "primary mount",
+		`no_such_flag = true`:                                         "unknown setting",
+		`listener { port = { a = 1 } }`:                               "must be a string",
+		`system_view { default_lease_ttl = "soon" }`:                  "invalid default-lease-ttl",
+	}
+	for content, want := range tests {
+		config, err := loadHostConfig(writeConfigFile(t, "host.hcl", content))
+		if err == nil {
+			fs, _, _ := configFlagSet()
+			_, err = config.apply(fs)
+		}
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: got error %v, want one containing %q", content, err, want)
+		}
+	}
+}
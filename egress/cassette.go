@@ -0,0 +1,92 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package egress
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+	"unicode/utf8"
+)
+
+// Message bodies that are not valid UTF-8 are stored base64 encoded
+const encodingBase64 = "base64"
+
+// Request is a recorded outbound request
+type Request struct {
+	Method       string      `json:"method"`
+	URL          string      `json:"url"`
+	Headers      http.Header `json:"headers,omitempty"`
+	Body         string      `json:"body,omitempty"`
+	BodyEncoding string      `json:"body_encoding,omitempty"`
+}
+
+// Response is a recorded or stubbed response
+type Response struct {
+	Status       int         `json:"status"`
+	Headers      http.Header `json:"headers,omitempty"`
+	Body         string      `json:"body,omitempty"`
+	BodyEncoding string      `json:"body_encoding,omitempty"`
+}
+
+// Interaction is one request/response exchange made by the plugin
+type Interaction struct {
+	ID       int64     `json:"id,omitempty"`
+	Time     time.Time `json:"time"`
+	Duration string    `json:"duration,omitempty"`
+	// Source is "live" for forwarded requests, "cassette" for replayed ones,
+	// "unmatched" for requests refused in replay mode and "blocked" for requests
+	// refused by the destination policy
+	Source   string   `json:"source,omitempty"`
+	Error    string   `json:"error,omitempty"`
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Cassette is a set of interactions that can be saved and replayed
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// LoadCassette reads a cassette from a JSON file
+func LoadCassette(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var cassette Cassette
+	if err := json.Unmarshal(data, &cassette); err != nil {
+		return nil, fmt.Errorf("failed to parse cassette: %w", err)
+	}
+	return &cassette, nil
+}
+
+// Save writes the cassette to a JSON file
+func (c *Cassette) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o600)
+}
+
+// encodeBody returns a body as text, base64 encoding it when it is not valid UTF-8
+func encodeBody(body []byte) (string, string) {
+	if utf8.Valid(body) {
+		return string(body), ""
+	}
+	return base64.StdEncoding.EncodeToString(body), encodingBase64
+}
+
+// decodeBody reverses encodeBody
+func decodeBody(body, encoding string) ([]byte, error) {
+	if encoding == encodingBase64 {
+		return base64.StdEncoding.DecodeString(body)
+	}
+	return []byte(body), nil
+}
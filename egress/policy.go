@@ -0,0 +1,160 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package egress
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Policy restricts the destinations a plugin may reach. Rules have the form
+// "host[:port][/path-prefix]"; the host may be "*" or start with "*." to match any
+// subdomain. Deny rules take precedence, and when allow rules are given a request must
+// match one of them.
+type Policy struct {
+	Allow []string `json:"allow"`
+	Deny  []string `json:"deny"`
+	// ReportOnly records violations without blocking the requests
+	ReportOnly bool `json:"report_only"`
+
+	allow []rule
+	deny  []rule
+}
+
+// Violation is a request that broke the policy
+type Violation struct {
+	Time    time.Time `json:"time"`
+	Method  string    `json:"method"`
+	URL     string    `json:"url"`
+	Reason  string    `json:"reason"`
+	Blocked bool      `json:"blocked"`
+}
+
+// rule is a parsed policy rule
+type rule struct {
+	raw  string
+	host string
+	port string
+	path string
+}
+
+// LoadPolicy reads a policy from a JSON file
+func LoadPolicy(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var policy Policy
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return nil, fmt.Errorf("failed to parse egress policy: %w", err)
+	}
+	if err := policy.compile(); err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// NewPolicy builds a policy from allow and deny rules
+func NewPolicy(allow, deny []string, reportOnly bool) (*Policy, error) {
+	policy := &Policy{Allow: allow, Deny: deny, ReportOnly: reportOnly}
+	if err := policy.compile(); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// compile parses the rule strings
+func (p *Policy) compile() error {
+	p.allow, p.deny = nil, nil
+	for _, raw := range p.Allow {
+		r, err := parseRule(raw)
+		if err != nil {
+			return err
+		}
+		p.allow = append(p.allow, r)
+	}
+	for _, raw := range p.Deny {
+		r, err := parseRule(raw)
+		if err != nil {
+			return err
+		}
+		p.deny = append(p.deny, r)
+	}
+	return nil
+}
+
+// parseRule parses "host[:port][/path-prefix]"
+func parseRule(raw string) (rule, error) {
+	r := rule{raw: raw}
+	hostport := strings.TrimSpace(raw)
+	if i := strings.Index(hostport, "/"); i >= 0 {
+		hostport, r.path = hostport[:i], hostport[i:]
+	}
+	r.host = hostport
+	if host, port, err := net.SplitHostPort(hostport); err == nil {
+		r.host, r.port = host, port
+	}
+	r.host = strings.ToLower(r.host)
+	if r.host == "" || strings.Contains(r.host[1:], "*") || (strings.HasPrefix(r.host, "*") && r.host != "*" && !strings.HasPrefix(r.host, "*.")) {
+		return rule{}, fmt.Errorf("invalid egress rule %q", raw)
+	}
+	return r, nil
+}
+
+// matches reports whether the rule covers a request URL
+func (r rule) matches(u *url.URL) bool {
+	host := strings.ToLower(u.Hostname())
+	switch {
+	case r.host == "*":
+	case strings.HasPrefix(r.host, "*."):
+		if !strings.HasSuffix(host, r.host[1:]) {
+			return false
+		}
+	case host != r.host:
+		return false
+	}
+
+	if r.port != "" && r.port != urlPort(u) {
+		return false
+	}
+	path := u.Path
+	if path == "" {
+		path = "/"
+	}
+	return strings.HasPrefix(path, r.path)
+}
+
+// urlPort returns the explicit or scheme default port of a URL
+func urlPort(u *url.URL) string {
+	if port := u.Port(); port != "" {
+		return port
+	}
+	if u.Scheme == "https" {
+		return "443"
+	}
+	return "80"
+}
+
+// Check returns why a request to u breaks the policy, or "" if it is allowed
+func (p *Policy) Check(u *url.URL) string {
+	for _, r := range p.deny {
+		if r.matches(u) {
+			return fmt.Sprintf("denied by rule %q", r.raw)
+		}
+	}
+	if len(p.allow) == 0 {
+		return ""
+	}
+	for _, r := range p.allow {
+		if r.matches(u) {
+			return ""
+		}
+	}
+	return "destination is not in the allowlist"
+}
@@ -0,0 +1,124 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package egress
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+func TestPolicyCheck(t *testing.T) {
+	policy, err := NewPolicy(
+		[]string{"sts.amazonaws.com", "*.googleapis.com:443", "api.example.com/v2/"},
+		[]string{"metadata.googleapis.com"},
+		false,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		url     string
+		allowed bool
+	}{
+		{"https://sts.amazonaws.com/?Action=GetCallerIdentity", true},
+		{"https://STS.amazonaws.com/", true},
+		{"https://iam.amazonaws.com/", false},
+		{"https://oauth2.googleapis.com/token", true},
+		{"http://oauth2.googleapis.com/token", false},
+		{"https://googleapis.com/", false},
+		{"http://metadata.googleapis.com/computeMetadata/v1/", false},
+		{"https://metadata.googleapis.com:443/", false},
+		{"https://api.example.com/v2/users", true},
+		{"https://api.example.com/v1/users", false},
+	}
+	for _, tt := range tests {
+		u, _ := url.Parse(tt.url)
+		reason := policy.Check(u)
+		if (reason == "") != tt.allowed {
+			t.Errorf("Check(%s) = %q, want allowed %v", tt.url, reason, tt.allowed)
+		}
+	}
+
+	// Without allow rules everything that is not denied passes
+	denyOnly, _ := NewPolicy(nil, []string{"*.internal"}, false)
+	if reason := denyOnly.Check(&url.URL{Scheme: "https", Host: "example.com"}); reason != "" {
+		t.Errorf("deny-only policy rejected an unrelated host: %s", reason)
+	}
+	if reason := denyOnly.Check(&url.URL{Scheme: "https", Host: "db.internal"}); !strings.Contains(reason, "*.internal") {
+		t.Errorf("deny reason = %q, want the matching rule", reason)
+	}
+}
+
+func TestParseRuleInvalid(t *testing.T) {
+	for _, raw := range []string{"", "/path", "api.*.com", "*example.com"} {
+		if _, err := parseRule(raw); err == nil {
+			t.Errorf("parseRule(%q) should fail", raw)
+		}
+	}
+}
+
+func TestLoadPolicy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.json")
+	os.WriteFile(path, []byte(`{"allow": ["example.com"], "report_only": true}`), 0o600)
+
+	policy, err := LoadPolicy(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !policy.ReportOnly || policy.Check(&url.URL{Scheme: "https", Host: "other.com"}) == "" {
+		t.Fatalf("unexpected policy %+v", policy)
+	}
+
+	os.WriteFile(path, []byte(`{"deny": ["a*b"]}`), 0o600)
+	if _, err := LoadPolicy(path); err == nil {
+		t.Error("LoadPolicy should reject an invalid rule")
+	}
+}
+
+func TestProxyEnforcesPolicy(t *testing.T) {
+	hits := 0
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.Write([]byte("live"))
+	}))
+	defer upstream.Close()
+
+	for _, reportOnly := range []bool{false, true} {
+		hits = 0
+		p, err := New(ModeRecord, nil, hclog.NewNullLogger())
+		if err != nil {
+			t.Fatal(err)
+		}
+		policy, _ := NewPolicy([]string{"example.com"}, nil, reportOnly)
+		p.SetPolicy(policy)
+		client := startProxy(t, p)
+
+		status, _ := get(t, client, upstream.URL+"/secret", nil)
+		violations := p.Violations()
+		if len(violations) != 1 || violations[0].URL != upstream.URL+"/secret" || violations[0].Blocked == reportOnly {
+			t.Fatalf("report_only=%v: unexpected violations %+v", reportOnly, violations)
+		}
+
+		if reportOnly {
+			if status != http.StatusOK || hits != 1 {
+				t.Errorf("report-only policy should forward the request, got %d (hits %d)", status, hits)
+			}
+			continue
+		}
+		if status != http.StatusForbidden || hits != 0 {
+			t.Errorf("policy should block the request, got %d (hits %d)", status, hits)
+		}
+		if interactions := p.Interactions(); len(interactions) != 1 || interactions[0].Source != sourceBlocked {
+			t.Errorf("unexpected interactions %+v", interactions)
+		}
+	}
+}
@@ -0,0 +1,512 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package egress implements a forward proxy that records a plugin's outbound HTTP(S)
+// calls and can answer them from a cassette instead of the network. HTTPS is
+// intercepted by terminating TLS with certificates from a CA the plugin is told to trust.
+package egress
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+// Mode selects how requests without a cassette match are handled
+type Mode string
+
+const (
+	// ModeRecord forwards unmatched requests to the network and records them
+	ModeRecord Mode = "record"
+	// ModeReplay refuses unmatched requests, keeping tests hermetic
+	ModeReplay Mode = "replay"
+)
+
+// ParseMode validates a mode name
+func ParseMode(s string) (Mode, error) {
+	switch Mode(s) {
+	case ModeRecord, ModeReplay:
+		return Mode(s), nil
+	}
+	return "", fmt.Errorf("unknown egress mode %q (expected record or replay)", s)
+}
+
+// Interaction sources
+const (
+	sourceLive      = "live"
+	sourceCassette  = "cassette"
+	sourceUnmatched = "unmatched"
+	sourceBlocked   = "blocked"
+)
+
+// maxInteractions is the number of interactions kept in memory, as is maxViolations
+// for policy violations
+const (
+	maxInteractions = 1000
+	maxViolations   = 1000
+)
+
+// redactedHeaders hold credentials and are not recorded
+var redactedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "X-Vault-Token"}
+
+// hopHeaders apply to a single connection and are not forwarded
+var hopHeaders = []string{"Connection", "Proxy-Connection", "Keep-Alive", "Proxy-Authenticate", "Proxy-Authorization", "Te", "Trailer", "Transfer-Encoding", "Upgrade"}
+
+// Proxy is an HTTP forward proxy that records traffic and replays cassettes
+type Proxy struct {
+	mode      Mode
+	ca        *CA
+	logger    hclog.Logger
+	transport http.RoundTripper
+	addr      net.Addr
+	policy    *Policy
+
+	mu           sync.Mutex
+	stubs        map[string][]Interaction // cassette interactions by method and URL
+	replayed     map[string]int           // next stub index per key
+	cassette     []Interaction            // loaded interactions, kept for Cassette
+	interactions []Interaction
+	violations   []Violation
+	nextID       int64
+}
+
+// New creates a proxy. Interactions from cassette, if any, answer matching requests in
+// both modes; repeated requests consume them in order and then reuse the last one.
+func New(mode Mode, cassette *Cassette, logger hclog.Logger) (*Proxy, error) {
+	ca, err := NewCA()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create proxy CA: %w", err)
+	}
+
+	p := &Proxy{
+		mode:      mode,
+		ca:        ca,
+		logger:    logger,
+		transport: &http.Transport{Proxy: http.ProxyFromEnvironment, ForceAttemptHTTP2: true},
+		stubs:     make(map[string][]Interaction),
+		replayed:  make(map[string]int),
+	}
+	if cassette != nil {
+		p.cassette = append([]Interaction(nil), cassette.Interactions...)
+		for _, interaction := range cassette.Interactions {
+			key := matchKey(interaction.Request.Method, interaction.Request.URL)
+			p.stubs[key] = append(p.stubs[key], interaction)
+		}
+	}
+	return p, nil
+}
+
+// Mode returns the proxy mode
+func (p *Proxy) Mode() Mode {
+	return p.mode
+}
+
+// Addr returns the listen address, or nil before ListenAndServe
+func (p *Proxy) Addr() net.Addr {
+	return p.addr
+}
+
+// CA returns the CA used to intercept HTTPS
+func (p *Proxy) CA() *CA {
+	return p.ca
+}
+
+// SetPolicy restricts the destinations the plugin may reach; it must be called before
+// the proxy serves requests
+func (p *Proxy) SetPolicy(policy *Policy) {
+	p.policy = policy
+}
+
+// Policy returns the destination policy, or nil if all destinations are allowed
+func (p *Proxy) Policy() *Policy {
+	return p.policy
+}
+
+// matchKey identifies requests for cassette matching
+func matchKey(method, url string) string {
+	return strings.ToUpper(method) + " " + url
+}
+
+// Interactions returns the recorded interactions, oldest first
+func (p *Proxy) Interactions() []Interaction {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]Interaction(nil), p.interactions...)
+}
+
+// Violations returns the policy violations, oldest first
+func (p *Proxy) Violations() []Violation {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]Violation(nil), p.violations...)
+}
+
+// Clear discards the recorded interactions and policy violations
+func (p *Proxy) Clear() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.interactions = nil
+	p.violations = nil
+}
+
+// Cassette returns the loaded interactions followed by those recorded live, ready to be
+// saved and replayed
+func (p *Proxy) Cassette() *Cassette {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	cassette := &Cassette{Interactions: append([]Interaction(nil), p.cassette...)}
+	for _, interaction := range p.interactions {
+		if interaction.Source != sourceLive || interaction.Error != "" {
+			continue
+		}
+		interaction.ID = 0
+		interaction.Source = ""
+		cassette.Interactions = append(cassette.Interactions, interaction)
+	}
+	return cassette
+}
+
+// record stores an interaction, dropping the oldest beyond maxInteractions
+func (p *Proxy) record(interaction Interaction) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.nextID++
+	interaction.ID = p.nextID
+	p.interactions = append(p.interactions, interaction)
+	if len(p.interactions) > maxInteractions {
+		p.interactions = p.interactions[len(p.interactions)-maxInteractions:]
+	}
+}
+
+// checkPolicy records a violation if a request breaks the policy and reports whether
+// it must be blocked
+func (p *Proxy) checkPolicy(method string, u *url.URL) bool {
+	if p.policy == nil {
+		return false
+	}
+	reason := p.policy.Check(u)
+	if reason == "" {
+		return false
+	}
+
+	blocked := !p.policy.ReportOnly
+	p.logger.Warn("egress policy violation", "method", method, "url", u.String(), "reason", reason, "blocked", blocked)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.violations = append(p.violations, Violation{
+		Time:    time.Now(),
+		Method:  method,
+		URL:     u.String(),
+		Reason:  reason,
+		Blocked: blocked,
+	})
+	if len(p.violations) > maxViolations {
+		p.violations = p.violations[len(p.violations)-maxViolations:]
+	}
+	return blocked
+}
+
+// stub returns the next cassette interaction for a request
+func (p *Proxy) stub(method, url string) (Interaction, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key := matchKey(method, url)
+	stubs := p.stubs[key]
+	if len(stubs) == 0 {
+		return Interaction{}, false
+	}
+	i := p.replayed[key]
+	if i < len(stubs)-1 {
+		p.replayed[key] = i + 1
+	}
+	return stubs[i], true
+}
+
+// ServeHTTP handles proxied requests: absolute-URI HTTP requests and CONNECT tunnels
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodConnect {
+		p.handleConnect(w, r)
+		return
+	}
+	if !r.URL.IsAbs() {
+		http.Error(w, "this is a forward proxy; requests must use an absolute URL", http.StatusBadRequest)
+		return
+	}
+
+	resp := p.exchange(r)
+	defer resp.Body.Close()
+	for k, values := range resp.Header {
+		for _, v := range values {
+			w.Header().Add(k, v)
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+	io.Copy(w, resp.Body)
+}
+
+// handleConnect intercepts a CONNECT tunnel, terminating TLS so requests can be recorded
+func (p *Proxy) handleConnect(w http.ResponseWriter, r *http.Request) {
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "connection cannot be hijacked", http.StatusInternalServerError)
+		return
+	}
+	conn, _, err := hijacker.Hijack()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n")); err != nil {
+		return
+	}
+
+	hostport := r.Host
+	host, _, err := net.SplitHostPort(hostport)
+	if err != nil {
+		host = hostport
+	}
+	tlsConn := tls.Server(conn, &tls.Config{
+		GetCertificate: func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+			name := hello.ServerName
+			if name == "" {
+				name = host
+			}
+			return p.ca.certFor(name)
+		},
+		NextProtos: []string{"http/1.1"},
+	})
+	if err := tlsConn.Handshake(); err != nil {
+		p.logger.Debug("egress TLS handshake failed", "host", hostport, "error", err)
+		return
+	}
+
+	reader := bufio.NewReader(tlsConn)
+	for {
+		req, err := http.ReadRequest(reader)
+		if err != nil {
+			return
+		}
+		req.URL.Scheme = "https"
+		req.URL.Host = hostport
+		if strings.HasSuffix(hostport, ":443") {
+			req.URL.Host = host
+		}
+
+		resp := p.exchange(req)
+		err = resp.Write(tlsConn)
+		resp.Body.Close()
+		if err != nil || req.Close {
+			return
+		}
+	}
+}
+
+// exchange answers a request from the cassette or the network and records it
+func (p *Proxy) exchange(r *http.Request) *http.Response {
+	start := time.Now()
+	body, err := io.ReadAll(r.Body)
+	r.Body.Close()
+
+	url := r.URL.String()
+	interaction := Interaction{
+		Time:    start,
+		Request: Request{Method: r.Method, URL: url, Headers: recordedHeaders(r.Header)},
+	}
+	interaction.Request.Body, interaction.Request.BodyEncoding = encodeBody(body)
+
+	var resp *http.Response
+	blocked := p.checkPolicy(r.Method, r.URL)
+	var stub Interaction
+	var ok bool
+	if !blocked && err == nil {
+		stub, ok = p.stub(r.Method, url)
+	}
+	switch {
+	case blocked:
+		interaction.Source = sourceBlocked
+		resp = p.failure(&interaction, http.StatusForbidden, fmt.Errorf("egress policy forbids %s %s", r.Method, url))
+
+	case err != nil:
+		interaction.Source = sourceLive
+		resp = p.failure(&interaction, http.StatusBadRequest, fmt.Errorf("failed to read request body: %w", err))
+
+	case ok:
+		interaction.Source = sourceCassette
+		interaction.Response = stub.Response
+		resp = stubResponse(r, stub.Response)
+
+	case p.mode == ModeReplay:
+		interaction.Source = sourceUnmatched
+		resp = p.failure(&interaction, http.StatusBadGateway, fmt.Errorf("no recorded interaction for %s %s", r.Method, url))
+
+	default:
+		interaction.Source = sourceLive
+		resp = p.forward(r, body, &interaction)
+	}
+
+	interaction.Duration = time.Since(start).String()
+	p.record(interaction)
+	return resp
+}
+
+// forward sends a request to its destination and records the response
+func (p *Proxy) forward(r *http.Request, body []byte, interaction *Interaction) *http.Response {
+	out, err := http.NewRequestWithContext(r.Context(), r.Method, r.URL.String(), bytes.NewReader(body))
+	if err != nil {
+		return p.failure(interaction, http.StatusBadRequest, err)
+	}
+	out.Header = r.Header.Clone()
+	for _, h := range hopHeaders {
+		out.Header.Del(h)
+	}
+
+	upstream, err := p.transport.RoundTrip(out)
+	if err != nil {
+		return p.failure(interaction, http.StatusBadGateway, err)
+	}
+	defer upstream.Body.Close()
+
+	respBody, err := io.ReadAll(upstream.Body)
+	if err != nil {
+		return p.failure(interaction, http.StatusBadGateway, fmt.Errorf("failed to read upstream response: %w", err))
+	}
+
+	header := upstream.Header.Clone()
+	for _, h := range hopHeaders {
+		header.Del(h)
+	}
+	header.Del("Content-Length")
+	interaction.Response = Response{Status: upstream.StatusCode, Headers: header}
+	interaction.Response.Body, interaction.Response.BodyEncoding = encodeBody(respBody)
+	return newResponse(r, upstream.StatusCode, header, respBody)
+}
+
+// failure records an error and builds the proxy's own error response
+func (p *Proxy) failure(interaction *Interaction, status int, err error) *http.Response {
+	p.logger.Debug("egress request failed", "method", interaction.Request.Method, "url", interaction.Request.URL, "error", err)
+	interaction.Error = err.Error()
+	interaction.Response = Response{Status: status}
+
+	body, _ := json.Marshal(map[string][]string{"errors": {err.Error()}})
+	header := http.Header{"Content-Type": {"application/json"}, "X-Vault-Host-Egress-Error": {"true"}}
+	return newResponse(nil, status, header, body)
+}
+
+// stubResponse builds a response from a cassette entry
+func stubResponse(r *http.Request, stub Response) *http.Response {
+	body, err := decodeBody(stub.Body, stub.BodyEncoding)
+	if err != nil {
+		body = []byte(stub.Body)
+	}
+	header := stub.Headers.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	status := stub.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	return newResponse(r, status, header, body)
+}
+
+func newResponse(r *http.Request, status int, header http.Header, body []byte) *http.Response {
+	return &http.Response{
+		StatusCode:    status,
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       r,
+	}
+}
+
+// recordedHeaders copies request headers for recording, with credentials redacted
+func recordedHeaders(header http.Header) http.Header {
+	recorded := header.Clone()
+	for _, h := range hopHeaders {
+		recorded.Del(h)
+	}
+	for _, h := range redactedHeaders {
+		if recorded.Get(h) != "" {
+			recorded.Set(h, "[redacted]")
+		}
+	}
+	return recorded
+}
+
+// HandleInteractions serves the recorded traffic:
+//
+//	GET    ?format=cassette - the interactions as a replayable cassette
+//	GET                     - mode, recorded interactions and policy violations
+//	DELETE                  - clear the recorded interactions and violations
+func (p *Proxy) HandleInteractions(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		if r.URL.Query().Get("format") == "cassette" {
+			w.Header().Set("Content-Disposition", `attachment; filename="cassette.json"`)
+			writeJSON(w, http.StatusOK, p.Cassette())
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"mode":         p.mode,
+			"interactions": p.Interactions(),
+			"policy":       p.policy,
+			"violations":   p.Violations(),
+		})
+	case http.MethodDelete:
+		p.Clear()
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeJSON(w, http.StatusMethodNotAllowed, map[string][]string{"errors": {"method not allowed"}})
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// ListenAndServe listens on addr and serves proxy connections in the background
+func (p *Proxy) ListenAndServe(addr string) (net.Addr, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	p.addr = listener.Addr()
+	go http.Serve(listener, p)
+	return p.addr, nil
+}
+
+// Env returns the environment that routes a Go process's HTTP(S) traffic through the
+// proxy at addr and makes it trust the CA bundle at bundlePath. Go never proxies requests
+// to localhost, so host-local mocks are not recorded.
+func Env(addr net.Addr, bundlePath string) []string {
+	proxyURL := "http://" + addr.String()
+	return []string{
+		"HTTP_PROXY=" + proxyURL,
+		"HTTPS_PROXY=" + proxyURL,
+		"http_proxy=" + proxyURL,
+		"https_proxy=" + proxyURL,
+		"SSL_CERT_FILE=" + bundlePath,
+	}
+}
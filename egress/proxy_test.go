@@ -0,0 +1,172 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package egress
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+// startProxy serves p and returns a client that sends all requests through it
+func startProxy(t *testing.T, p *Proxy) *http.Client {
+	t.Helper()
+
+	server := httptest.NewServer(p)
+	t.Cleanup(server.Close)
+	proxyURL, _ := url.Parse(server.URL)
+
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(p.CA().CertPEM())
+	return &http.Client{Transport: &http.Transport{
+		Proxy:           http.ProxyURL(proxyURL),
+		TLSClientConfig: &tls.Config{RootCAs: roots},
+	}}
+}
+
+func get(t *testing.T, client *http.Client, url string, header http.Header) (int, string) {
+	t.Helper()
+
+	req, _ := http.NewRequest(http.MethodGet, url, nil)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("GET %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(body)
+}
+
+func TestProxyRecordAndReplay(t *testing.T) {
+	hits := 0
+	upstream := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"path":"` + r.URL.Path + `"}`))
+	}))
+	defer upstream.Close()
+
+	recorder, err := New(ModeRecord, nil, hclog.NewNullLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder.transport = upstream.Client().Transport
+	client := startProxy(t, recorder)
+
+	status, body := get(t, client, upstream.URL+"/v1/token", http.Header{"Authorization": {"Bearer secret"}})
+	if status != http.StatusOK || body != `{"path":"/v1/token"}` {
+		t.Fatalf("unexpected response %d %s", status, body)
+	}
+
+	interactions := recorder.Interactions()
+	if len(interactions) != 1 || hits != 1 {
+		t.Fatalf("expected one recorded interaction, got %d (hits %d)", len(interactions), hits)
+	}
+	recorded := interactions[0]
+	if recorded.Source != sourceLive || recorded.Request.URL != upstream.URL+"/v1/token" || recorded.Response.Status != http.StatusOK {
+		t.Fatalf("unexpected interaction: %+v", recorded)
+	}
+	if got := recorded.Request.Headers.Get("Authorization"); got != "[redacted]" {
+		t.Fatalf("Authorization recorded as %q", got)
+	}
+
+	// Replay serves the cassette without touching the network
+	replayer, err := New(ModeReplay, recorder.Cassette(), hclog.NewNullLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	client = startProxy(t, replayer)
+	upstream.Close()
+
+	status, body = get(t, client, upstream.URL+"/v1/token", nil)
+	if status != http.StatusOK || body != `{"path":"/v1/token"}` {
+		t.Fatalf("unexpected replayed response %d %s", status, body)
+	}
+	status, body = get(t, client, upstream.URL+"/v1/other", nil)
+	if status != http.StatusBadGateway || !strings.Contains(body, "no recorded interaction") {
+		t.Fatalf("expected unmatched request to fail, got %d %s", status, body)
+	}
+
+	sources := []string{}
+	for _, interaction := range replayer.Interactions() {
+		sources = append(sources, interaction.Source)
+	}
+	if strings.Join(sources, ",") != "cassette,unmatched" {
+		t.Fatalf("unexpected sources %v", sources)
+	}
+}
+
+func TestProxyStubsInRecordMode(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("live"))
+	}))
+	defer upstream.Close()
+
+	cassette := &Cassette{Interactions: []Interaction{
+		{Request: Request{Method: "GET", URL: upstream.URL + "/stubbed"}, Response: Response{Status: 201, Body: "first"}},
+		{Request: Request{Method: "GET", URL: upstream.URL + "/stubbed"}, Response: Response{Status: 200, Body: "second"}},
+	}}
+	p, err := New(ModeRecord, cassette, hclog.NewNullLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := startProxy(t, p)
+
+	for _, want := range []string{"first", "second", "second"} {
+		if _, body := get(t, client, upstream.URL+"/stubbed", nil); body != want {
+			t.Fatalf("expected %q, got %q", want, body)
+		}
+	}
+	if _, body := get(t, client, upstream.URL+"/other", nil); body != "live" {
+		t.Fatalf("expected unmatched request to go live, got %q", body)
+	}
+
+	// The saved cassette holds the loaded stubs plus the new live interaction
+	saved := p.Cassette()
+	if len(saved.Interactions) != 3 || saved.Interactions[2].Request.URL != upstream.URL+"/other" {
+		t.Fatalf("unexpected cassette: %+v", saved.Interactions)
+	}
+}
+
+func TestHandleInteractions(t *testing.T) {
+	p, err := New(ModeRecord, nil, hclog.NewNullLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.record(Interaction{Source: sourceLive, Request: Request{Method: "GET", URL: "http://example.com/"}})
+
+	w := httptest.NewRecorder()
+	p.HandleInteractions(w, httptest.NewRequest(http.MethodGet, "/v1/sys/host/egress", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"mode":"record"`) {
+		t.Fatalf("unexpected response %d %s", w.Code, w.Body)
+	}
+
+	w = httptest.NewRecorder()
+	p.HandleInteractions(w, httptest.NewRequest(http.MethodDelete, "/v1/sys/host/egress", nil))
+	if w.Code != http.StatusNoContent || len(p.Interactions()) != 0 {
+		t.Fatalf("expected interactions to be cleared")
+	}
+}
+
+func TestEncodeBody(t *testing.T) {
+	binary := []byte{0xff, 0x00, 0x10}
+	body, encoding := encodeBody(binary)
+	if encoding != encodingBase64 {
+		t.Fatalf("expected binary body to be base64 encoded")
+	}
+	decoded, err := decodeBody(body, encoding)
+	if err != nil || string(decoded) != string(binary) {
+		t.Fatalf("round trip failed: %v", err)
+	}
+}
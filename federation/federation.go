@@ -0,0 +1,356 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package federation joins several host instances into one test topology. A host
+// registers the others as peers and can then report their combined mounts and health
+// and fan a request out to all of them, which helps when testing plugins whose
+// behavior depends on being mounted on several Vault clusters.
+package federation
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LocalName is the member name of the host serving the federation endpoints
+const LocalName = "local"
+
+// PathPrefix is where the federation endpoints are served
+const PathPrefix = "/v1/sys/host/federation"
+
+// defaultTimeout bounds each request made to a peer
+const defaultTimeout = 30 * time.Second
+
+// Peer is another host instance taking part in the federation
+type Peer struct {
+	Name    string    `json:"name"`
+	Address string    `json:"address"`
+	Token   string    `json:"-"` // sent as X-Vault-Token to peers started with -token
+	AddedAt time.Time `json:"added_at"`
+}
+
+// Federation keeps the registered peers of a host
+type Federation struct {
+	local  http.Handler
+	client *http.Client
+
+	mu    sync.RWMutex
+	peers map[string]*Peer
+}
+
+// New creates a federation whose local member is served by local, normally the
+// host's own router, so the host takes part without calling itself over the network
+func New(local http.Handler) *Federation {
+	return &Federation{
+		local:  local,
+		client: &http.Client{Timeout: defaultTimeout},
+		peers:  make(map[string]*Peer),
+	}
+}
+
+// ParsePeer parses a peer given on the command line as "name=address" or just
+// "address", in which case the address's host:port is the name
+func ParsePeer(spec string) (name, address string, err error) {
+	name, address, found := strings.Cut(spec, "=")
+	if !found {
+		address = spec
+		u, err := url.Parse(address)
+		if err != nil || u.Host == "" {
+			return "", "", fmt.Errorf("invalid peer address %q", spec)
+		}
+		name = u.Host
+	}
+	return name, address, nil
+}
+
+// Register adds a peer, or updates the address and token of an existing one
+func (f *Federation) Register(name, address, token string) error {
+	if name == "" || name == LocalName || strings.Contains(name, "/") {
+		return fmt.Errorf("invalid peer name %q", name)
+	}
+	u, err := url.Parse(address)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("peer address must be an http or https URL, got %q", address)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.peers[name] = &Peer{
+		Name:    name,
+		Address: strings.TrimSuffix(u.String(), "/"),
+		Token:   token,
+		AddedAt: time.Now().UTC(),
+	}
+	return nil
+}
+
+// Remove removes a peer and reports whether it was registered
+func (f *Federation) Remove(name string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, ok := f.peers[name]
+	delete(f.peers, name)
+	return ok
+}
+
+// Peers returns the registered peers sorted by name
+func (f *Federation) Peers() []Peer {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	peers := make([]Peer, 0, len(f.peers))
+	for _, peer := range f.peers {
+		peers = append(peers, *peer)
+	}
+	sort.Slice(peers, func(i, j int) bool { return peers[i].Name < peers[j].Name })
+	return peers
+}
+
+// MemberResult is the outcome of one request to a federation member
+type MemberResult struct {
+	Status   int         `json:"status,omitempty"`
+	Body     interface{} `json:"body,omitempty"`
+	Duration string      `json:"duration"`
+	Error    string      `json:"error,omitempty"`
+}
+
+// memberRequest describes a request sent to every member
+type memberRequest struct {
+	method string
+	path   string // including the query
+	body   []byte
+	header http.Header
+}
+
+// fanOut sends req to the local host and every peer concurrently
+func (f *Federation) fanOut(req memberRequest) map[string]*MemberResult {
+	peers := f.Peers()
+	results := make(map[string]*MemberResult, len(peers)+1)
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	call := func(name string, do func() *MemberResult) {
+		defer wg.Done()
+		start := time.Now()
+		result := do()
+		result.Duration = time.Since(start).String()
+		mu.Lock()
+		results[name] = result
+		mu.Unlock()
+	}
+
+	wg.Add(len(peers) + 1)
+	go call(LocalName, func() *MemberResult { return f.callLocal(req) })
+	for _, peer := range peers {
+		go call(peer.Name, func() *MemberResult { return f.callPeer(peer, req) })
+	}
+	wg.Wait()
+	return results
+}
+
+// callLocal serves req with the local handler
+func (f *Federation) callLocal(req memberRequest) *MemberResult {
+	r := httptest.NewRequest(req.method, req.path, bytes.NewReader(req.body))
+	for key, values := range req.header {
+		r.Header[key] = values
+	}
+	w := httptest.NewRecorder()
+	f.local.ServeHTTP(w, r)
+	return &MemberResult{Status: w.Code, Body: decodeBody(w.Body.Bytes())}
+}
+
+// callPeer sends req to a peer. The peer's own token replaces the caller's when set.
+func (f *Federation) callPeer(peer Peer, req memberRequest) *MemberResult {
+	r, err := http.NewRequest(req.method, peer.Address+req.path, bytes.NewReader(req.body))
+	if err != nil {
+		return &MemberResult{Error: err.Error()}
+	}
+	for key, values := range req.header {
+		r.Header[key] = values
+	}
+	if peer.Token != "" {
+		r.Header.Set("X-Vault-Token", peer.Token)
+	}
+
+	resp, err := f.client.Do(r)
+	if err != nil {
+		return &MemberResult{Error: err.Error()}
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return &MemberResult{Status: resp.StatusCode, Error: fmt.Sprintf("failed to read response: %v", err)}
+	}
+	return &MemberResult{Status: resp.StatusCode, Body: decodeBody(body)}
+}
+
+// decodeBody returns a JSON body as a value and any other body as a string
+func decodeBody(body []byte) interface{} {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil
+	}
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return string(body)
+	}
+	return value
+}
+
+// forwardedHeaders are passed from a fan-out request to every member
+var forwardedHeaders = []string{"Content-Type", "Authorization", "X-Vault-Token", "X-Vault-Wrap-TTL"}
+
+// ServeHTTP serves the federation endpoints below PathPrefix:
+//
+//	GET    /peers          - list peers
+//	POST   /peers/<name>   - register a peer ({"address": ..., "token": ...})
+//	DELETE /peers/<name>   - remove a peer
+//	GET    /health         - health of every member
+//	GET    /catalog        - mounts of every member, and the members serving each mount
+//	*      /fanout/<path>  - send the request to /v1/<path> on every member
+func (f *Federation) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rest := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, PathPrefix), "/")
+	section, name, _ := strings.Cut(rest, "/")
+
+	switch section {
+	case "peers":
+		f.handlePeers(w, r, name)
+	case "health":
+		f.handleHealth(w, r)
+	case "catalog":
+		f.handleCatalog(w, r)
+	case "fanout":
+		f.handleFanOut(w, r, name)
+	default:
+		writeError(w, http.StatusNotFound, fmt.Sprintf("unknown federation endpoint %q", rest))
+	}
+}
+
+func (f *Federation) handlePeers(w http.ResponseWriter, r *http.Request, name string) {
+	switch {
+	case name == "" && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, map[string]interface{}{"peers": f.Peers()})
+
+	case name != "" && (r.Method == http.MethodPost || r.Method == http.MethodPut):
+		var data struct {
+			Address string `json:"address"`
+			Token   string `json:"token"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("failed to parse JSON: %v", err))
+			return
+		}
+		if err := f.Register(name, data.Address, data.Token); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	case name != "" && r.Method == http.MethodDelete:
+		if !f.Remove(name) {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("no peer named %q", name))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (f *Federation) handleHealth(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	results := f.fanOut(memberRequest{method: http.MethodGet, path: "/v1/sys/health"})
+	healthy := 0
+	for _, result := range results {
+		if result.Error == "" && result.Status == http.StatusOK {
+			healthy++
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"members": results,
+		"healthy": healthy,
+		"total":   len(results),
+	})
+}
+
+func (f *Federation) handleCatalog(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	results := f.fanOut(memberRequest{method: http.MethodGet, path: "/v1/sys/mounts"})
+	members := make(map[string]interface{}, len(results))
+	mounts := make(map[string][]string)
+	for name, result := range results {
+		if result.Error != "" || result.Status != http.StatusOK {
+			members[name] = result
+			continue
+		}
+		body, _ := result.Body.(map[string]interface{})
+		data, _ := body["data"].(map[string]interface{})
+		members[name] = map[string]interface{}{"mounts": data}
+		for mount := range data {
+			mounts[mount] = append(mounts[mount], name)
+		}
+	}
+	for _, names := range mounts {
+		sort.Strings(names)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"members": members,
+		"mounts":  mounts,
+	})
+}
+
+func (f *Federation) handleFanOut(w http.ResponseWriter, r *http.Request, path string) {
+	if path == "" {
+		writeError(w, http.StatusBadRequest, "a path to fan out is required")
+		return
+	}
+	if strings.HasPrefix("/v1/"+path, PathPrefix) {
+		// Members would fan the request out again, to each other
+		writeError(w, http.StatusBadRequest, "federation endpoints cannot be fanned out")
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("failed to read body: %v", err))
+		return
+	}
+
+	req := memberRequest{method: r.Method, path: "/v1/" + path, body: body, header: http.Header{}}
+	if r.URL.RawQuery != "" {
+		req.path += "?" + r.URL.RawQuery
+	}
+	for _, key := range forwardedHeaders {
+		if value := r.Header.Get(key); value != "" {
+			req.header.Set(key, value)
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"members": f.fanOut(req)})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]interface{}{"errors": []string{message}})
+}
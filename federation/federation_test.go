@@ -0,0 +1,161 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package federation
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// fakeHost answers like a host with the given mounts; plugin requests echo the
+// method, path and token they received
+func fakeHost(mounts ...string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/sys/health", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]interface{}{"plugin_running": true})
+	})
+	mux.HandleFunc("/v1/sys/mounts", func(w http.ResponseWriter, r *http.Request) {
+		data := map[string]interface{}{}
+		for _, mount := range mounts {
+			data[mount+"/"] = map[string]interface{}{"type": mount}
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"data": data})
+	})
+	mux.HandleFunc("/v1/", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"method": r.Method,
+			"path":   r.URL.RequestURI(),
+			"token":  r.Header.Get("X-Vault-Token"),
+			"body":   string(body),
+		})
+	})
+	return mux
+}
+
+func do(f *Federation, method, path, body string) (*httptest.ResponseRecorder, map[string]interface{}) {
+	w := httptest.NewRecorder()
+	f.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+	var resp map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	return w, resp
+}
+
+func TestParsePeer(t *testing.T) {
+	tests := []struct{ spec, name, address string }{
+		{"dr=http://10.0.0.2:8300", "dr", "http://10.0.0.2:8300"},
+		{"http://10.0.0.3:8300", "10.0.0.3:8300", "http://10.0.0.3:8300"},
+	}
+	for _, tt := range tests {
+		name, address, err := ParsePeer(tt.spec)
+		if err != nil || name != tt.name || address != tt.address {
+			t.Errorf("ParsePeer(%q) = %q, %q, %v", tt.spec, name, address, err)
+		}
+	}
+	if _, _, err := ParsePeer("not a url"); err == nil {
+		t.Error("ParsePeer should reject an address without a host")
+	}
+}
+
+func TestPeerRegistration(t *testing.T) {
+	f := New(fakeHost())
+
+	if w, _ := do(f, "POST", PathPrefix+"/peers/dr", `{"address": "http://127.0.0.1:8301/"}`); w.Code != http.StatusNoContent {
+		t.Fatalf("register: status = %d, body = %s", w.Code, w.Body.String())
+	}
+	for _, body := range []string{`{"address": "ftp://example.com"}`, `{}`} {
+		if w, _ := do(f, "POST", PathPrefix+"/peers/bad", body); w.Code != http.StatusBadRequest {
+			t.Errorf("register %s: status = %d, want 400", body, w.Code)
+		}
+	}
+	if w, _ := do(f, "POST", PathPrefix+"/peers/"+LocalName, `{"address": "http://127.0.0.1:8302"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("registering the local name: status = %d, want 400", w.Code)
+	}
+
+	peers := f.Peers()
+	if len(peers) != 1 || peers[0].Name != "dr" || peers[0].Address != "http://127.0.0.1:8301" {
+		t.Errorf("peers = %+v", peers)
+	}
+
+	if w, _ := do(f, "DELETE", PathPrefix+"/peers/dr", ""); w.Code != http.StatusNoContent {
+		t.Errorf("remove: status = %d", w.Code)
+	}
+	if w, _ := do(f, "DELETE", PathPrefix+"/peers/dr", ""); w.Code != http.StatusNotFound {
+		t.Errorf("removing a missing peer: status = %d, want 404", w.Code)
+	}
+}
+
+func TestHealthAndCatalog(t *testing.T) {
+	peer := httptest.NewServer(fakeHost("kv", "aws"))
+	defer peer.Close()
+
+	f := New(fakeHost("kv"))
+	f.Register("dr", peer.URL, "")
+	f.Register("down", "http://127.0.0.1:1", "")
+
+	_, health := do(f, "GET", PathPrefix+"/health", "")
+	if health["healthy"] != float64(2) || health["total"] != float64(3) {
+		t.Errorf("health = %v", health)
+	}
+	members := health["members"].(map[string]interface{})
+	if down := members["down"].(map[string]interface{}); down["error"] == nil {
+		t.Errorf("unreachable peer = %v, want an error", down)
+	}
+
+	_, catalog := do(f, "GET", PathPrefix+"/catalog", "")
+	mounts := catalog["mounts"].(map[string]interface{})
+	if got := mounts["kv/"].([]interface{}); len(got) != 2 || got[0] != "dr" || got[1] != LocalName {
+		t.Errorf("kv/ is served by %v, want [dr local]", got)
+	}
+	if got := mounts["aws/"].([]interface{}); len(got) != 1 || got[0] != "dr" {
+		t.Errorf("aws/ is served by %v, want [dr]", got)
+	}
+}
+
+func TestFanOut(t *testing.T) {
+	peer := httptest.NewServer(fakeHost())
+	defer peer.Close()
+	secured := httptest.NewServer(fakeHost())
+	defer secured.Close()
+
+	f := New(fakeHost())
+	f.Register("dr", peer.URL, "")
+	f.Register("secured", secured.URL, "peer-token")
+
+	req := httptest.NewRequest("POST", PathPrefix+"/fanout/kv/config?force=true", strings.NewReader(`{"a":1}`))
+	req.Header.Set("X-Vault-Token", "caller-token")
+	w := httptest.NewRecorder()
+	f.ServeHTTP(w, req)
+
+	var resp struct {
+		Members map[string]struct {
+			Status int                    `json:"status"`
+			Body   map[string]interface{} `json:"body"`
+		} `json:"members"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if len(resp.Members) != 3 {
+		t.Fatalf("got %d members, want 3: %s", len(resp.Members), w.Body.String())
+	}
+	for name, member := range resp.Members {
+		if member.Status != http.StatusOK || member.Body["method"] != "POST" ||
+			member.Body["path"] != "/v1/kv/config?force=true" || member.Body["body"] != `{"a":1}` {
+			t.Errorf("%s: %+v", name, member)
+		}
+	}
+	if token := resp.Members["dr"].Body["token"]; token != "caller-token" {
+		t.Errorf("dr got token %v, want the caller's", token)
+	}
+	if token := resp.Members["secured"].Body["token"]; token != "peer-token" {
+		t.Errorf("secured got token %v, want its own", token)
+	}
+
+	if w, _ := do(f, "GET", PathPrefix+"/fanout/sys/host/federation/health", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("fanning out a federation endpoint: status = %d, want 400", w.Code)
+	}
+}
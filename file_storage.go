@@ -0,0 +1,172 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// fileStorageExt is the extension of persisted entry files
+const fileStorageExt = ".json"
+
+// FileStorage implements logical.Storage on top of InMemoryStorage, persisting every
+// entry to its own file so plugin state survives host restarts. Reads are served from
+// memory; writes go to disk first and then to memory.
+type FileStorage struct {
+	*InMemoryStorage
+	dir     string
+	writeMu sync.Mutex // orders disk and memory updates so they cannot diverge
+}
+
+// NewFileStorage opens (creating if necessary) a storage directory and loads its entries
+func NewFileStorage(dir string) (*FileStorage, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create storage directory: %w", err)
+	}
+
+	s := &FileStorage{
+		InMemoryStorage: NewInMemoryStorage(),
+		dir:             dir,
+	}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Dir returns the storage directory
+func (s *FileStorage) Dir() string {
+	return s.dir
+}
+
+// entryPath maps a key to a flat file name, since keys may contain path separators
+// and one key can be a prefix of another ("foo" and "foo/bar")
+func (s *FileStorage) entryPath(key string) string {
+	return filepath.Join(s.dir, base64.RawURLEncoding.EncodeToString([]byte(key))+fileStorageExt)
+}
+
+// load reads every persisted entry into memory
+func (s *FileStorage) load() error {
+	files, err := os.ReadDir(s.dir)
+	if err != nil {
+		return fmt.Errorf("failed to read storage directory: %w", err)
+	}
+
+	ctx := context.Background()
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), fileStorageExt) {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(s.dir, file.Name()))
+		if err != nil {
+			return fmt.Errorf("failed to read storage entry %s: %w", file.Name(), err)
+		}
+		var entry logical.StorageEntry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			return fmt.Errorf("failed to parse storage entry %s: %w", file.Name(), err)
+		}
+		if err := s.InMemoryStorage.Put(ctx, &entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeEntry atomically replaces the file for an entry
+func (s *FileStorage) writeEntry(entry *logical.StorageEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(s.dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), s.entryPath(entry.Key))
+}
+
+func (s *FileStorage) Put(ctx context.Context, entry *logical.StorageEntry) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	if err := s.writeEntry(entry); err != nil {
+		return fmt.Errorf("failed to persist %q: %w", entry.Key, err)
+	}
+	return s.InMemoryStorage.Put(ctx, entry)
+}
+
+func (s *FileStorage) Delete(ctx context.Context, key string) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	if err := os.Remove(s.entryPath(key)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to delete %q: %w", key, err)
+	}
+	return s.InMemoryStorage.Delete(ctx, key)
+}
+
+// RestoreEntries swaps in the contents a storage snapshot restore leaves behind, as
+// InMemoryStorage.RestoreEntries does, and rewrites the directory to match
+func (s *FileStorage) RestoreEntries(ctx context.Context, entries []*logical.StorageEntry, merge bool) error {
+	return s.Restore(s.restoredContents(ctx, entries, merge))
+}
+
+// Restore replaces the contents of the storage with a snapshot and rewrites the directory to match
+func (s *FileStorage) Restore(snapshot *InMemoryStorage) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	ctx := context.Background()
+	current, err := s.InMemoryStorage.List(ctx, "")
+	if err != nil {
+		return err
+	}
+	for _, key := range current {
+		if err := os.Remove(s.entryPath(key)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+
+	keys, err := snapshot.List(ctx, "")
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		entry, err := snapshot.Get(ctx, key)
+		if err != nil {
+			return err
+		}
+		if entry == nil {
+			continue
+		}
+		if err := s.writeEntry(entry); err != nil {
+			return err
+		}
+	}
+
+	s.InMemoryStorage.Restore(snapshot)
+	return nil
+}
@@ -0,0 +1,91 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestFileStoragePersistsAcrossRestarts(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	storage, err := NewFileStorage(dir)
+	if err != nil {
+		t.Fatalf("NewFileStorage failed: %v", err)
+	}
+
+	// "config" is a prefix of "config/roles", which a naive key-to-path mapping can't store
+	for _, key := range []string{"config", "config/roles", "creds/a"} {
+		if err := storage.Put(ctx, &logical.StorageEntry{Key: key, Value: []byte(key), SealWrap: key == "config"}); err != nil {
+			t.Fatalf("Put %s failed: %v", key, err)
+		}
+	}
+	storage.Put(ctx, &logical.StorageEntry{Key: "creds/a", Value: []byte("updated")})
+	if err := storage.Delete(ctx, "config/roles"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := storage.Delete(ctx, "missing"); err != nil {
+		t.Errorf("Delete of a missing key failed: %v", err)
+	}
+
+	reopened, err := NewFileStorage(dir)
+	if err != nil {
+		t.Fatalf("reopen failed: %v", err)
+	}
+
+	keys, _ := reopened.List(ctx, "")
+	if got := strings.Join(keys, ","); got != "config,creds/a" {
+		t.Errorf("keys after reopen = %s", got)
+	}
+	if entry, _ := reopened.Get(ctx, "creds/a"); entry == nil || string(entry.Value) != "updated" {
+		t.Errorf("creds/a after reopen = %v", entry)
+	}
+	if entry, _ := reopened.Get(ctx, "config"); entry == nil || !entry.SealWrap {
+		t.Errorf("config lost SealWrap after reopen: %v", entry)
+	}
+}
+
+func TestFileStorageRestore(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	storage, _ := NewFileStorage(dir)
+	storage.Put(ctx, &logical.StorageEntry{Key: "seed", Value: []byte("seed")})
+	checkpoint := storage.Snapshot()
+
+	storage.Put(ctx, &logical.StorageEntry{Key: "scratch", Value: []byte("x")})
+	storage.Delete(ctx, "seed")
+
+	if err := storage.Restore(checkpoint); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+
+	reopened, _ := NewFileStorage(dir)
+	keys, _ := reopened.List(ctx, "")
+	if got := strings.Join(keys, ","); got != "seed" {
+		t.Errorf("keys on disk after restore = %s, want seed", got)
+	}
+}
+
+func TestFileStorageRestoreEntries(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	storage, _ := NewFileStorage(dir)
+	storage.Put(ctx, &logical.StorageEntry{Key: "stale", Value: []byte("x")})
+	if err := storage.RestoreEntries(ctx, []*logical.StorageEntry{{Key: "config", Value: []byte("a")}}, false); err != nil {
+		t.Fatalf("RestoreEntries failed: %v", err)
+	}
+
+	reopened, _ := NewFileStorage(dir)
+	keys, _ := reopened.List(ctx, "")
+	if got := strings.Join(keys, ","); got != "config" {
+		t.Errorf("keys on disk after restore = %s, want config", got)
+	}
+}
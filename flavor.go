@@ -0,0 +1,73 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"vault-plugin-host/handlers"
+
+	"github.com/hashicorp/go-plugin"
+)
+
+// pluginMagicCookie is the value of the handshake variable that tells a plugin binary
+// it was launched by a server rather than run by hand
+const pluginMagicCookie = "6669da05-b1c8-4f49-97d9-c8e5bed98e20"
+
+// pluginProtocolVersion is the version of the plugin protocol launched plugins are
+// offered
+const pluginProtocolVersion = 4
+
+// hostFlavor is the server whose plugin contract the host follows. OpenBao, the fork of
+// Vault, kept Vault's protocol and API but renamed the variables it hands to plugins.
+type hostFlavor struct {
+	name          string
+	envPrefix     string // of the handshake variables, such as VAULT_BACKEND_PLUGIN
+	pluginVersion string // handed to plugins in the handshake
+	version       string // reported by sys/seal-status
+}
+
+// hostFlavors are the flavors -flavor accepts
+var hostFlavors = map[string]hostFlavor{
+	"vault":   {name: "vault", envPrefix: "VAULT_", pluginVersion: "1.18.0", version: handlers.VaultVersion},
+	"openbao": {name: "openbao", envPrefix: "BAO_", pluginVersion: handlers.OpenBaoVersion, version: handlers.OpenBaoVersion},
+}
+
+// parseHostFlavor returns the flavor named by -flavor
+func parseHostFlavor(name string) (hostFlavor, error) {
+	flavor, ok := hostFlavors[strings.ToLower(name)]
+	if !ok {
+		names := make([]string, 0, len(hostFlavors))
+		for name := range hostFlavors {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return hostFlavor{}, fmt.Errorf("unknown flavor %q (expected %s)", name, strings.Join(names, " or "))
+	}
+	return flavor, nil
+}
+
+// handshakeEnv is the environment a server of this flavor launches a plugin with: the
+// go-plugin protocol version, the magic cookie, AutoMTLS and the server's version
+func (f hostFlavor) handshakeEnv() []string {
+	return []string{
+		"PLUGIN_PROTOCOL_VERSIONS=" + strconv.Itoa(pluginProtocolVersion),
+		f.envPrefix + "BACKEND_PLUGIN=" + pluginMagicCookie,
+		f.envPrefix + "PLUGIN_AUTOMTLS_ENABLED=true",
+		f.envPrefix + "VERSION=" + f.pluginVersion,
+	}
+}
+
+// handshakeConfig is the handshake go-plugin launches a plugin of this flavor with; it
+// sets the same protocol version and magic cookie as handshakeEnv
+func (f hostFlavor) handshakeConfig() plugin.HandshakeConfig {
+	return plugin.HandshakeConfig{
+		ProtocolVersion:  pluginProtocolVersion,
+		MagicCookieKey:   f.envPrefix + "BACKEND_PLUGIN",
+		MagicCookieValue: pluginMagicCookie,
+	}
+}
@@ -0,0 +1,43 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHostFlavor(t *testing.T) {
+	vault, err := parseHostFlavor("vault")
+	if err != nil {
+		t.Fatalf("parseHostFlavor(vault) failed: %v", err)
+	}
+	env := strings.Join(vault.handshakeEnv(), "\n")
+	for _, want := range []string{"PLUGIN_PROTOCOL_VERSIONS=4", "VAULT_BACKEND_PLUGIN=" + pluginMagicCookie, "VAULT_PLUGIN_AUTOMTLS_ENABLED=true", "VAULT_VERSION=1.18.0"} {
+		if !strings.Contains(env, want) {
+			t.Errorf("vault handshake lacks %s:\n%s", want, env)
+		}
+	}
+
+	openbao, err := parseHostFlavor("OpenBao")
+	if err != nil {
+		t.Fatalf("parseHostFlavor(OpenBao) failed: %v", err)
+	}
+	env = strings.Join(openbao.handshakeEnv(), "\n")
+	for _, want := range []string{"PLUGIN_PROTOCOL_VERSIONS=4", "BAO_BACKEND_PLUGIN=" + pluginMagicCookie, "BAO_PLUGIN_AUTOMTLS_ENABLED=true", "BAO_VERSION=" + openbao.version} {
+		if !strings.Contains(env, want) {
+			t.Errorf("openbao handshake lacks %s:\n%s", want, env)
+		}
+	}
+	if strings.Contains(env, "VAULT_") {
+		t.Errorf("openbao handshake has Vault variables:\n%s", env)
+	}
+	if config := openbao.handshakeConfig(); config.MagicCookieKey != "BAO_BACKEND_PLUGIN" || config.MagicCookieValue != pluginMagicCookie || config.ProtocolVersion != pluginProtocolVersion {
+		t.Errorf("openbao handshake config = %+v", config)
+	}
+
+	if _, err := parseHostFlavor("consul"); err == nil || !strings.Contains(err.Error(), "openbao or vault") {
+		t.Errorf("parseHostFlavor(consul) = %v, want an error naming the flavors", err)
+	}
+}
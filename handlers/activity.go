@@ -0,0 +1,330 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// activityRetentionMonths matches Vault's default client count retention
+const activityRetentionMonths = 48
+
+// activityClient is a client seen in a month
+type activityClient struct {
+	entity bool
+	mount  string
+}
+
+// activityMonth holds the requests and clients of one calendar month
+type activityMonth struct {
+	start    time.Time
+	requests int
+	clients  map[string]activityClient
+	first    map[string]bool // clients first seen in this month
+}
+
+// ActivityLog counts requests and distinct clients per month, shaped like Vault's
+// sys/internal/counters APIs. Logins that return an alias count as entity clients;
+// requests carrying a token count as non-entity clients.
+type ActivityLog struct {
+	mu     sync.Mutex
+	now    func() time.Time
+	months map[time.Time]*activityMonth
+	seen   map[string]bool
+}
+
+// NewActivityLog creates an empty activity log
+func NewActivityLog() *ActivityLog {
+	return &ActivityLog{
+		now:    time.Now,
+		months: make(map[time.Time]*activityMonth),
+		seen:   make(map[string]bool),
+	}
+}
+
+// monthStart returns the first instant of t's month in UTC
+func monthStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// clientID derives a stable, non-reversible ID from identifying parts
+func clientID(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:16])
+}
+
+// requestToken returns the Vault token sent with a request, if any
+func requestToken(r *http.Request) string {
+	if token := r.Header.Get("X-Vault-Token"); token != "" {
+		return token
+	}
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return token
+	}
+	return ""
+}
+
+// Record counts a request to a mount and the client that made it
+func (a *ActivityLog) Record(mount string, r *http.Request, resp *logical.Response) {
+	var id string
+	var entity bool
+	switch {
+	case resp != nil && resp.Auth != nil && resp.Auth.EntityID != "":
+		id, entity = resp.Auth.EntityID, true
+	case resp != nil && resp.Auth != nil && resp.Auth.Alias != nil && resp.Auth.Alias.Name != "":
+		id, entity = clientID("entity", mount, resp.Auth.Alias.Name), true
+	default:
+		if token := requestToken(r); token != "" {
+			id = clientID("token", token)
+		}
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	start := monthStart(a.now())
+	month, ok := a.months[start]
+	if !ok {
+		month = &activityMonth{start: start, clients: make(map[string]activityClient), first: make(map[string]bool)}
+		a.months[start] = month
+		a.prune(start)
+	}
+	month.requests++
+
+	if id == "" {
+		return
+	}
+	if _, ok := month.clients[id]; !ok {
+		month.clients[id] = activityClient{entity: entity, mount: mount}
+	}
+	if !a.seen[id] {
+		a.seen[id] = true
+		month.first[id] = true
+	}
+}
+
+// prune drops months outside the retention window; the caller must hold a.mu
+func (a *ActivityLog) prune(current time.Time) {
+	cutoff := current.AddDate(0, -activityRetentionMonths, 0)
+	for start := range a.months {
+		if start.Before(cutoff) {
+			delete(a.months, start)
+		}
+	}
+}
+
+// sortedMonths returns the months that start within [from, to], oldest first; the caller
+// must hold a.mu
+func (a *ActivityLog) sortedMonths(from, to time.Time) []*activityMonth {
+	var months []*activityMonth
+	for start, month := range a.months {
+		if !start.Before(monthStart(from)) && !start.After(to) {
+			months = append(months, month)
+		}
+	}
+	sort.Slice(months, func(i, j int) bool { return months[i].start.Before(months[j].start) })
+	return months
+}
+
+// clientCounts is the "counts" object of Vault's activity responses
+type clientCounts struct {
+	Clients          int `json:"clients"`
+	EntityClients    int `json:"entity_clients"`
+	NonEntityClients int `json:"non_entity_clients"`
+	DistinctEntities int `json:"distinct_entities"`
+	NonEntityTokens  int `json:"non_entity_tokens"`
+}
+
+func (c *clientCounts) add(client activityClient) {
+	c.Clients++
+	if client.entity {
+		c.EntityClients++
+		c.DistinctEntities++
+	} else {
+		c.NonEntityClients++
+		c.NonEntityTokens++
+	}
+}
+
+type mountCounts struct {
+	MountPath string       `json:"mount_path"`
+	Counts    clientCounts `json:"counts"`
+}
+
+type namespaceCounts struct {
+	NamespaceID   string        `json:"namespace_id"`
+	NamespacePath string        `json:"namespace_path"`
+	Counts        clientCounts  `json:"counts"`
+	Mounts        []mountCounts `json:"mounts"`
+}
+
+// breakdown counts distinct clients in total and per mount, all under the root namespace
+func breakdown(clients map[string]activityClient) (clientCounts, []namespaceCounts) {
+	var total clientCounts
+	perMount := make(map[string]*clientCounts)
+	for _, client := range clients {
+		total.add(client)
+		if perMount[client.mount] == nil {
+			perMount[client.mount] = &clientCounts{}
+		}
+		perMount[client.mount].add(client)
+	}
+
+	root := namespaceCounts{NamespaceID: "root", NamespacePath: "", Counts: total, Mounts: []mountCounts{}}
+	for mount, counts := range perMount {
+		root.Mounts = append(root.Mounts, mountCounts{MountPath: mount, Counts: *counts})
+	}
+	sort.Slice(root.Mounts, func(i, j int) bool { return root.Mounts[i].MountPath < root.Mounts[j].MountPath })
+	return total, []namespaceCounts{root}
+}
+
+// monthReport is one entry of the "months" list of an activity response
+func monthReport(month *activityMonth) map[string]interface{} {
+	counts, namespaces := breakdown(month.clients)
+
+	newClients := make(map[string]activityClient, len(month.first))
+	for id := range month.first {
+		newClients[id] = month.clients[id]
+	}
+	newCounts, newNamespaces := breakdown(newClients)
+
+	return map[string]interface{}{
+		"timestamp":  month.start.Format(time.RFC3339),
+		"counts":     counts,
+		"namespaces": namespaces,
+		"new_clients": map[string]interface{}{
+			"counts":     newCounts,
+			"namespaces": newNamespaces,
+		},
+	}
+}
+
+// HandleRequests serves GET /v1/sys/internal/counters/requests: total requests per month
+func (a *ActivityLog) HandleRequests(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	a.mu.Lock()
+	months := a.sortedMonths(time.Time{}, a.now())
+	counters := make([]map[string]interface{}, 0, len(months))
+	for _, month := range months {
+		counters = append(counters, map[string]interface{}{
+			"start_time": month.start.Format(time.RFC3339),
+			"total":      month.requests,
+		})
+	}
+	a.mu.Unlock()
+
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"data": map[string]interface{}{"counters": counters},
+	})
+}
+
+// HandleActivity serves GET /v1/sys/internal/counters/activity: distinct clients between
+// the start_time and end_time query parameters (RFC 3339), by default the retention window
+func (a *ActivityLog) HandleActivity(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	end := a.now().UTC()
+	start := monthStart(end).AddDate(0, -activityRetentionMonths+1, 0)
+	for name, target := range map[string]*time.Time{"start_time": &start, "end_time": &end} {
+		if value := r.URL.Query().Get(name); value != "" {
+			parsed, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				WriteError(w, http.StatusBadRequest, "failed to parse "+name+": "+err.Error())
+				return
+			}
+			*target = parsed.UTC()
+		}
+	}
+	if end.Before(start) {
+		WriteError(w, http.StatusBadRequest, "start_time is later than end_time")
+		return
+	}
+
+	months := a.sortedMonths(start, end)
+	clients := make(map[string]activityClient)
+	reports := make([]map[string]interface{}, 0, len(months))
+	for _, month := range months {
+		for id, client := range month.clients {
+			if _, ok := clients[id]; !ok {
+				clients[id] = client
+			}
+		}
+		reports = append(reports, monthReport(month))
+	}
+	total, namespaces := breakdown(clients)
+
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"data": map[string]interface{}{
+			"start_time":   start.Format(time.RFC3339),
+			"end_time":     end.Format(time.RFC3339),
+			"total":        total,
+			"by_namespace": namespaces,
+			"months":       reports,
+		},
+	})
+}
+
+// HandleActivityMonthly serves GET /v1/sys/internal/counters/activity/monthly: distinct
+// clients in the current month
+func (a *ActivityLog) HandleActivityMonthly(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	a.mu.Lock()
+	clients := map[string]activityClient{}
+	if month, ok := a.months[monthStart(a.now())]; ok {
+		clients = month.clients
+	}
+	total, namespaces := breakdown(clients)
+	a.mu.Unlock()
+
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"data": map[string]interface{}{
+			"clients":            total.Clients,
+			"entity_clients":     total.EntityClients,
+			"non_entity_clients": total.NonEntityClients,
+			"distinct_entities":  total.DistinctEntities,
+			"non_entity_tokens":  total.NonEntityTokens,
+			"by_namespace":       namespaces,
+			"months":             []interface{}{},
+		},
+	})
+}
+
+// HandleConfig serves GET /v1/sys/internal/counters/config with Vault's defaults
+func (a *ActivityLog) HandleConfig(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"data": map[string]interface{}{
+			"default_report_months": 12,
+			"enabled":               "default-enabled",
+			"queries_available":     true,
+			"retention_months":      activityRetentionMonths,
+		},
+	})
+}
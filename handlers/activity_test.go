@@ -0,0 +1,124 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// getData calls a counters handler and decodes the "data" object of its response
+func getData(t *testing.T, handler http.HandlerFunc, target string) map[string]interface{} {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest("GET", target, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET %s: status = %d, body %s", target, w.Code, w.Body)
+	}
+	var response struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return response.Data
+}
+
+func TestActivityLog(t *testing.T) {
+	activity := NewActivityLog()
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	activity.now = func() time.Time { return now }
+
+	login := &logical.Response{Auth: &logical.Auth{Alias: &logical.Alias{Name: "alice"}}}
+	withToken := func(token string) *http.Request {
+		r := httptest.NewRequest("GET", "/v1/plugin/creds", nil)
+		r.Header.Set("X-Vault-Token", token)
+		return r
+	}
+
+	// February: alice logs in twice and one token is used
+	now = time.Date(2025, 2, 5, 0, 0, 0, 0, time.UTC)
+	activity.Record("auth/", httptest.NewRequest("POST", "/v1/auth/login", nil), login)
+	activity.Record("auth/", httptest.NewRequest("POST", "/v1/auth/login", nil), login)
+	activity.Record("plugin/", withToken("token-a"), nil)
+
+	// March: alice again, a new token and an anonymous request
+	now = time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	activity.Record("auth/", httptest.NewRequest("POST", "/v1/auth/login", nil), login)
+	activity.Record("plugin/", withToken("token-b"), nil)
+	activity.Record("plugin/", httptest.NewRequest("GET", "/v1/plugin/health", nil), nil)
+
+	requests := getData(t, activity.HandleRequests, "/v1/sys/internal/counters/requests")
+	counters := requests["counters"].([]interface{})
+	if len(counters) != 2 {
+		t.Fatalf("got %d request counters, want 2", len(counters))
+	}
+	feb := counters[0].(map[string]interface{})
+	if feb["start_time"] != "2025-02-01T00:00:00Z" || feb["total"] != float64(3) {
+		t.Errorf("unexpected February counter %v", feb)
+	}
+
+	data := getData(t, activity.HandleActivity, "/v1/sys/internal/counters/activity")
+	total := data["total"].(map[string]interface{})
+	if total["clients"] != float64(3) || total["entity_clients"] != float64(1) || total["non_entity_clients"] != float64(2) {
+		t.Errorf("unexpected totals %v", total)
+	}
+	months := data["months"].([]interface{})
+	if len(months) != 2 {
+		t.Fatalf("got %d months, want 2", len(months))
+	}
+	march := months[1].(map[string]interface{})
+	marchNew := march["new_clients"].(map[string]interface{})["counts"].(map[string]interface{})
+	if march["counts"].(map[string]interface{})["clients"] != float64(2) || marchNew["clients"] != float64(1) {
+		t.Errorf("unexpected March report %v", march)
+	}
+
+	namespace := data["by_namespace"].([]interface{})[0].(map[string]interface{})
+	if mounts := namespace["mounts"].([]interface{}); len(mounts) != 2 {
+		t.Errorf("got %d mounts, want 2", len(mounts))
+	}
+
+	// A range covering only February
+	data = getData(t, activity.HandleActivity, "/v1/sys/internal/counters/activity?start_time=2025-02-01T00:00:00Z&end_time=2025-02-28T23:59:59Z")
+	if total := data["total"].(map[string]interface{}); total["clients"] != float64(2) {
+		t.Errorf("February clients = %v, want 2", total["clients"])
+	}
+
+	monthly := getData(t, activity.HandleActivityMonthly, "/v1/sys/internal/counters/activity/monthly")
+	if monthly["clients"] != float64(2) || monthly["entity_clients"] != float64(1) {
+		t.Errorf("unexpected monthly counts %v", monthly)
+	}
+
+	w := httptest.NewRecorder()
+	activity.HandleActivity(w, httptest.NewRequest("GET", "/v1/sys/internal/counters/activity?start_time=yesterday", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("invalid start_time: status = %d, want 400", w.Code)
+	}
+}
+
+func TestHandleRequestRecordsActivity(t *testing.T) {
+	backend := funcBackend(func(ctx context.Context, req *logical.Request) (*logical.Response, error) {
+		return &logical.Response{Data: map[string]interface{}{"ok": true}}, nil
+	})
+	handler := NewHandler(backend, newMockStorage(), hclog.NewNullLogger(), "plugin")
+	activity := NewActivityLog()
+	handler.SetActivityLog(activity)
+
+	req := httptest.NewRequest("GET", "/v1/plugin/config", nil)
+	req.Header.Set("X-Vault-Token", "root")
+	handler.HandleRequest(httptest.NewRecorder(), req)
+
+	data := getData(t, activity.HandleActivityMonthly, "/v1/sys/internal/counters/activity/monthly")
+	if data["non_entity_clients"] != float64(1) {
+		t.Errorf("non_entity_clients = %v, want 1", data["non_entity_clients"])
+	}
+}
@@ -0,0 +1,407 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// AuditStdout is the -audit-path value that writes audit entries to stdout
+const AuditStdout = "stdout"
+
+// hmacPrefix marks a value replaced by its HMAC, as in Vault's audit log
+const hmacPrefix = "hmac-sha256:"
+
+// AuditDevice records one JSON document per plugin request and response in the format
+// of Vault's audit devices, and hands it to its sink: a file, an HTTP endpoint or
+// syslog. With HMAC enabled, tokens, accessors and every string in request and response
+// data are replaced by their HMAC-SHA256 under a per-device key, as Vault does unless
+// log_raw is set.
+type AuditDevice struct {
+	name    string            // path the device is enabled at
+	kind    string            // file, http or syslog
+	options map[string]string // reported by sys/audit
+	mu      sync.Mutex
+	sink    AuditSink
+	hmacKey []byte // nil logs values raw
+}
+
+// NewAuditDevice opens a file audit device at the path "file", appending to a file,
+// or writing to stdout when path is AuditStdout
+func NewAuditDevice(path string, hmacValues bool) (*AuditDevice, error) {
+	sink, err := openAuditFile(path)
+	if err != nil {
+		return nil, err
+	}
+	options := map[string]string{"path": path, "hmac": strconv.FormatBool(hmacValues)}
+	return newAuditDevice("file", "file", options, sink, hmacValues)
+}
+
+// newAuditDevice creates a device writing to sink, generating its HMAC key if asked to
+func newAuditDevice(name, kind string, options map[string]string, sink AuditSink, hmacValues bool) (*AuditDevice, error) {
+	device := &AuditDevice{name: strings.Trim(name, "/"), kind: kind, options: options, sink: sink}
+	if hmacValues {
+		device.hmacKey = make([]byte, 32)
+		if _, err := rand.Read(device.hmacKey); err != nil {
+			sink.Close()
+			return nil, fmt.Errorf("failed to generate audit HMAC key: %w", err)
+		}
+	}
+	return device, nil
+}
+
+// newAuditDeviceWriter creates an audit device writing to w, for tests
+func newAuditDeviceWriter(w io.Writer, hmacKey []byte) *AuditDevice {
+	return &AuditDevice{name: "file", kind: "file", sink: &auditWriterSink{w: w}, hmacKey: hmacKey}
+}
+
+// Name returns the path the device is enabled at
+func (a *AuditDevice) Name() string {
+	return a.name
+}
+
+// Type returns the device type: file, http or syslog
+func (a *AuditDevice) Type() string {
+	return a.kind
+}
+
+// WritesStdout reports whether the device writes to stdout
+func (a *AuditDevice) WritesStdout() bool {
+	sink, ok := a.sink.(*auditWriterSink)
+	return ok && sink.w == os.Stdout
+}
+
+// Close closes the device's sink
+func (a *AuditDevice) Close() error {
+	return a.sink.Close()
+}
+
+// Hash returns the value the audit log records for s: its HMAC when HMAC is enabled,
+// otherwise s itself
+func (a *AuditDevice) Hash(s string) string {
+	if a.hmacKey == nil || s == "" {
+		return s
+	}
+	mac := hmac.New(sha256.New, a.hmacKey)
+	mac.Write([]byte(s))
+	return hmacPrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// hashValue replaces every string in a JSON-shaped value with its hash
+func (a *AuditDevice) hashValue(v interface{}) interface{} {
+	if a.hmacKey == nil || v == nil {
+		return v
+	}
+
+	// Round-trip through JSON so typed maps and slices from the plugin become generic
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var generic interface{}
+	if err := json.Unmarshal(raw, &generic); err != nil {
+		return v
+	}
+	return a.hashGeneric(generic)
+}
+
+func (a *AuditDevice) hashGeneric(v interface{}) interface{} {
+	switch value := v.(type) {
+	case string:
+		return a.Hash(value)
+	case map[string]interface{}:
+		for key, item := range value {
+			value[key] = a.hashGeneric(item)
+		}
+	case []interface{}:
+		for i, item := range value {
+			value[i] = a.hashGeneric(item)
+		}
+	}
+	return v
+}
+
+// auditAuth describes the client token of a request
+func (a *AuditDevice) auditAuth(clientToken string, token TokenEntry) map[string]interface{} {
+	auth := map[string]interface{}{
+		"client_token":   a.Hash(clientToken),
+		"accessor":       a.Hash(token.Accessor),
+		"display_name":   token.DisplayName,
+		"policies":       token.Policies,
+		"token_policies": token.Policies,
+		"metadata":       token.Metadata,
+		"entity_id":      token.EntityID,
+		"token_type":     "service",
+	}
+	if !token.IssueTime.IsZero() {
+		auth["token_issue_time"] = token.IssueTime.UTC().Format(time.RFC3339)
+	}
+	return auth
+}
+
+// auditRequest describes a plugin request
+func (a *AuditDevice) auditRequest(r *http.Request, req *logical.Request, mountPath string) map[string]interface{} {
+	mount := strings.Trim(mountPath, "/")
+	request := map[string]interface{}{
+		"id":                    req.ID,
+		"operation":             req.Operation,
+		"mount_point":           mount + "/",
+		"mount_type":            mount,
+		"client_token":          a.Hash(req.ClientToken),
+		"client_token_accessor": a.Hash(req.ClientTokenAccessor),
+		"namespace":             map[string]interface{}{"id": "root"},
+		"path":                  mount + "/" + req.Path,
+		"data":                  a.hashValue(req.Data),
+		"remote_address":        remoteHost(r.RemoteAddr),
+	}
+	if req.WrapInfo != nil {
+		request["wrap_ttl"] = int(req.WrapInfo.TTL.Seconds())
+	}
+	return request
+}
+
+// remoteHost strips the port from a remote address
+func remoteHost(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
+
+// LogRequest records a request before it is sent to the plugin
+func (a *AuditDevice) LogRequest(r *http.Request, req *logical.Request, token TokenEntry, mountPath string) error {
+	return a.write(map[string]interface{}{
+		"time":    time.Now().UTC().Format(time.RFC3339Nano),
+		"type":    "request",
+		"auth":    a.auditAuth(req.ClientToken, token),
+		"request": a.auditRequest(r, req, mountPath),
+	})
+}
+
+// LogResponse records the response returned to the client, given in the shape of
+// the HTTP response body, or the error the request failed with
+func (a *AuditDevice) LogResponse(r *http.Request, req *logical.Request, token TokenEntry, mountPath string, response map[string]interface{}, respErr error) error {
+	mount := strings.Trim(mountPath, "/")
+	audited := map[string]interface{}{
+		"mount_point": mount + "/",
+		"mount_type":  mount,
+	}
+	if data, ok := response["data"]; ok && data != nil {
+		audited["data"] = a.hashValue(data)
+	}
+	if leaseID, ok := response["lease_id"].(string); ok && leaseID != "" {
+		audited["secret"] = map[string]interface{}{"lease_id": leaseID}
+	}
+	if auth, ok := response["auth"].(map[string]interface{}); ok {
+		audited["auth"] = a.hashFields(auth, "client_token", "accessor")
+	}
+	if wrapInfo, ok := response["wrap_info"].(map[string]interface{}); ok {
+		audited["wrap_info"] = a.hashFields(wrapInfo, "token", "accessor")
+	}
+	if warnings, ok := response["warnings"]; ok && warnings != nil {
+		audited["warnings"] = warnings
+	}
+
+	entry := map[string]interface{}{
+		"time":     time.Now().UTC().Format(time.RFC3339Nano),
+		"type":     "response",
+		"auth":     a.auditAuth(req.ClientToken, token),
+		"request":  a.auditRequest(r, req, mountPath),
+		"response": audited,
+	}
+	if respErr != nil {
+		entry["error"] = respErr.Error()
+	}
+	return a.write(entry)
+}
+
+// hashFields copies m with the string values of keys replaced by their hashes
+func (a *AuditDevice) hashFields(m map[string]interface{}, keys ...string) map[string]interface{} {
+	hashed := make(map[string]interface{}, len(m))
+	for key, value := range m {
+		hashed[key] = value
+	}
+	for _, key := range keys {
+		if value, ok := m[key].(string); ok {
+			hashed[key] = a.Hash(value)
+		}
+	}
+	return hashed
+}
+
+// write hands one entry to the sink
+func (a *AuditDevice) write(entry map[string]interface{}) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err := a.sink.WriteEntry(line); err != nil {
+		return fmt.Errorf("audit device %s: %w", a.name, err)
+	}
+	return nil
+}
+
+// HandleHash answers a request to hash the input with this device's key, for finding
+// hashed values in its log
+func (a *AuditDevice) HandleHash(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodPut {
+		WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	var data struct {
+		Input string `json:"input"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		WriteError(w, http.StatusBadRequest, fmt.Sprintf("failed to parse JSON: %v", err))
+		return
+	}
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"request_id": newRequestID(),
+		"data":       map[string]interface{}{"hash": a.Hash(data.Input)},
+	})
+}
+
+// AuditBroker sends every audit entry to each enabled audit device, as Vault's audit
+// broker does. As in Vault, a request is refused when no device could record it.
+type AuditBroker struct {
+	devices []*AuditDevice // in the order they were enabled
+}
+
+// NewAuditBroker creates a broker without devices
+func NewAuditBroker() *AuditBroker {
+	return &AuditBroker{}
+}
+
+// Enable adds a device; its path must not be taken. Devices are enabled at startup,
+// before requests are served.
+func (b *AuditBroker) Enable(device *AuditDevice) error {
+	if device.name == "" {
+		return fmt.Errorf("audit device path is required")
+	}
+	if b.Device(device.name) != nil {
+		return fmt.Errorf("path already in use: an audit device is enabled at %s/", device.name)
+	}
+	b.devices = append(b.devices, device)
+	return nil
+}
+
+// Device returns the device enabled at path, or nil
+func (b *AuditBroker) Device(path string) *AuditDevice {
+	path = strings.Trim(path, "/")
+	for _, device := range b.devices {
+		if device.name == path {
+			return device
+		}
+	}
+	return nil
+}
+
+// Devices returns the enabled devices in order
+func (b *AuditBroker) Devices() []*AuditDevice {
+	return b.devices
+}
+
+// fanOut writes to every device, failing only when none of them succeeded
+func (b *AuditBroker) fanOut(write func(device *AuditDevice) error) error {
+	var errs []error
+	for _, device := range b.devices {
+		if err := write(device); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 && len(errs) == len(b.devices) {
+		return errors.Join(errs...)
+	}
+	return nil
+}
+
+// LogRequest records a request with every device
+func (b *AuditBroker) LogRequest(r *http.Request, req *logical.Request, token TokenEntry, mountPath string) error {
+	return b.fanOut(func(device *AuditDevice) error {
+		return device.LogRequest(r, req, token, mountPath)
+	})
+}
+
+// LogResponse records a response with every device
+func (b *AuditBroker) LogResponse(r *http.Request, req *logical.Request, token TokenEntry, mountPath string, response map[string]interface{}, respErr error) error {
+	return b.fanOut(func(device *AuditDevice) error {
+		return device.LogResponse(r, req, token, mountPath, response, respErr)
+	})
+}
+
+// Close closes every device
+func (b *AuditBroker) Close() error {
+	var errs []error
+	for _, device := range b.devices {
+		if err := device.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// HandleHash serves Vault's /v1/sys/audit-hash/<path>: POST {"input": ...} returns the
+// value the device enabled at path records for input
+func (b *AuditBroker) HandleHash(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/v1/sys/audit-hash/")
+	device := b.Device(path)
+	if device == nil {
+		WriteError(w, http.StatusBadRequest, fmt.Sprintf("unknown audit backend %s", path))
+		return
+	}
+	device.HandleHash(w, r)
+}
+
+// HandleList serves GET /v1/sys/audit, the enabled devices keyed by path as Vault
+// lists them
+func (b *AuditBroker) HandleList(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	devices := make(map[string]interface{}, len(b.devices))
+	for _, device := range b.devices {
+		options := device.options
+		if options == nil {
+			options = map[string]string{}
+		}
+		devices[device.name+"/"] = map[string]interface{}{
+			"type":        device.kind,
+			"path":        device.name + "/",
+			"description": "",
+			"options":     options,
+			"local":       false,
+		}
+	}
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"request_id": newRequestID(),
+		"data":       devices,
+	})
+}
+
+// SetAuditBroker makes the handler record its requests and responses with the
+// broker's audit devices
+func (h *Handler) SetAuditBroker(audit *AuditBroker) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.audit = audit
+}
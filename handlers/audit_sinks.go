@@ -0,0 +1,227 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log/syslog"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultAuditHTTPTimeout bounds each POST of an http audit device
+const defaultAuditHTTPTimeout = 5 * time.Second
+
+// AuditSink receives the entries of an audit device, one JSON document at a time
+type AuditSink interface {
+	WriteEntry(entry []byte) error
+	Close() error
+}
+
+// auditWriterSink writes entries as lines to a file or to stdout
+type auditWriterSink struct {
+	w      io.Writer
+	closer io.Closer // nil for stdout
+}
+
+func (s *auditWriterSink) WriteEntry(entry []byte) error {
+	_, err := s.w.Write(append(entry, '\n'))
+	return err
+}
+
+func (s *auditWriterSink) Close() error {
+	if s.closer == nil {
+		return nil
+	}
+	return s.closer.Close()
+}
+
+// openAuditFile opens a file sink appending to path, or writing to stdout when path
+// is AuditStdout
+func openAuditFile(path string) (*auditWriterSink, error) {
+	if path == AuditStdout {
+		return &auditWriterSink{w: os.Stdout}, nil
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	return &auditWriterSink{w: f, closer: f}, nil
+}
+
+// auditHTTPSink POSTs each entry to a URL, such as the HTTP input of a log shipper
+type auditHTTPSink struct {
+	url    string
+	header http.Header
+	client *http.Client
+}
+
+func (s *auditHTTPSink) WriteEntry(entry []byte) error {
+	req, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(entry))
+	if err != nil {
+		return err
+	}
+	for name, values := range s.header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s answered %s", s.url, resp.Status)
+	}
+	return nil
+}
+
+func (s *auditHTTPSink) Close() error {
+	s.client.CloseIdleConnections()
+	return nil
+}
+
+// auditSyslogSink sends each entry to syslog at the info level, as Vault's syslog
+// audit device does
+type auditSyslogSink struct {
+	w *syslog.Writer
+}
+
+func (s *auditSyslogSink) WriteEntry(entry []byte) error {
+	return s.w.Info(string(entry))
+}
+
+func (s *auditSyslogSink) Close() error {
+	return s.w.Close()
+}
+
+// syslogFacilities maps the facility names Vault's syslog device accepts to their values
+var syslogFacilities = map[string]syslog.Priority{
+	"KERN": syslog.LOG_KERN, "USER": syslog.LOG_USER, "MAIL": syslog.LOG_MAIL,
+	"DAEMON": syslog.LOG_DAEMON, "AUTH": syslog.LOG_AUTH, "SYSLOG": syslog.LOG_SYSLOG,
+	"LPR": syslog.LOG_LPR, "NEWS": syslog.LOG_NEWS, "UUCP": syslog.LOG_UUCP,
+	"CRON": syslog.LOG_CRON, "AUTHPRIV": syslog.LOG_AUTHPRIV, "FTP": syslog.LOG_FTP,
+	"LOCAL0": syslog.LOG_LOCAL0, "LOCAL1": syslog.LOG_LOCAL1, "LOCAL2": syslog.LOG_LOCAL2,
+	"LOCAL3": syslog.LOG_LOCAL3, "LOCAL4": syslog.LOG_LOCAL4, "LOCAL5": syslog.LOG_LOCAL5,
+	"LOCAL6": syslog.LOG_LOCAL6, "LOCAL7": syslog.LOG_LOCAL7,
+}
+
+// auditDeviceOptions lists the options each audit device type takes besides name and hmac
+var auditDeviceOptions = map[string][]string{
+	"file":   {"path"},
+	"http":   {"url", "header", "timeout"},
+	"syslog": {"facility", "tag", "network", "address"},
+}
+
+// ParseAuditDevice creates an audit device from a specification of the form
+// "<type>:<key>=<value>,...". Every type takes name, the path the device is enabled
+// at (its type by default), and hmac. The types and their options are:
+//
+//	file    path: a file to append to, or "stdout"
+//	http    url to POST each entry to; header "Name: value", repeatable; timeout
+//	syslog  facility (AUTH) and tag (vault); network and address of a remote server
+func ParseAuditDevice(spec string) (*AuditDevice, error) {
+	kind, rest, _ := strings.Cut(spec, ":")
+	allowed, ok := auditDeviceOptions[kind]
+	if !ok {
+		return nil, fmt.Errorf("unknown audit device type %q (expected file, http or syslog)", kind)
+	}
+	allowed = append([]string{"name", "hmac"}, allowed...)
+
+	options := make(map[string][]string)
+	if rest != "" {
+		for _, pair := range strings.Split(rest, ",") {
+			key, value, ok := strings.Cut(pair, "=")
+			key = strings.TrimSpace(key)
+			if !ok || !containsString(allowed, key) {
+				return nil, fmt.Errorf("invalid %s audit device option %q (expected %s)", kind, pair, strings.Join(allowed, ", "))
+			}
+			options[key] = append(options[key], strings.TrimSpace(value))
+		}
+	}
+	option := func(key, fallback string) string {
+		if values := options[key]; len(values) > 0 {
+			return values[len(values)-1]
+		}
+		return fallback
+	}
+
+	var hmacValues bool
+	if value := option("hmac", ""); value != "" {
+		var err error
+		if hmacValues, err = strconv.ParseBool(value); err != nil {
+			return nil, fmt.Errorf("invalid hmac %q", value)
+		}
+	}
+
+	var sink AuditSink
+	switch kind {
+	case "file":
+		path := option("path", "")
+		if path == "" {
+			return nil, fmt.Errorf("file audit device requires a path")
+		}
+		file, err := openAuditFile(path)
+		if err != nil {
+			return nil, err
+		}
+		sink = file
+	case "http":
+		url := option("url", "")
+		if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+			return nil, fmt.Errorf("http audit device requires an http(s) url")
+		}
+		timeout := defaultAuditHTTPTimeout
+		if value := option("timeout", ""); value != "" {
+			var err error
+			if timeout, err = time.ParseDuration(value); err != nil || timeout <= 0 {
+				return nil, fmt.Errorf("invalid timeout %q", value)
+			}
+		}
+		header := make(http.Header)
+		for _, line := range options["header"] {
+			name, value, ok := strings.Cut(line, ":")
+			if !ok || strings.TrimSpace(name) == "" {
+				return nil, fmt.Errorf("invalid header %q (expected \"Name: value\")", line)
+			}
+			header.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+		}
+		sink = &auditHTTPSink{url: url, header: header, client: &http.Client{Timeout: timeout}}
+	case "syslog":
+		facility, ok := syslogFacilities[strings.ToUpper(option("facility", "AUTH"))]
+		if !ok {
+			return nil, fmt.Errorf("unknown syslog facility %q", option("facility", ""))
+		}
+		w, err := syslog.Dial(option("network", ""), option("address", ""), facility|syslog.LOG_INFO, option("tag", "vault"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to syslog: %w", err)
+		}
+		sink = &auditSyslogSink{w: w}
+	}
+
+	reported := make(map[string]string)
+	for key := range options {
+		if key != "name" && key != "header" {
+			reported[key] = option(key, "")
+		}
+	}
+	if len(options["header"]) > 0 {
+		// Header values are often credentials, so only their names are reported
+		names := make([]string, 0, len(options["header"]))
+		for _, line := range options["header"] {
+			name, _, _ := strings.Cut(line, ":")
+			names = append(names, strings.TrimSpace(name))
+		}
+		sort.Strings(names)
+		reported["header"] = strings.Join(names, ",")
+	}
+	return newAuditDevice(option("name", kind), kind, reported, sink, hmacValues)
+}
@@ -0,0 +1,192 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestParseAuditDeviceErrors(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"socket:address=127.0.0.1:9090",
+		"file",
+		"file:path",
+		"file:path=/tmp/a.log,format=jsonx",
+		"file:path=/tmp/a.log,hmac=maybe",
+		"http:url=ftp://collector",
+		"http:url=http://collector,timeout=soon",
+		"http:url=http://collector,header=Authorization",
+		"syslog:facility=NOWHERE",
+	} {
+		if device, err := ParseAuditDevice(spec); err == nil {
+			device.Close()
+			t.Errorf("ParseAuditDevice(%q) should fail", spec)
+		}
+	}
+}
+
+// collector is an HTTP endpoint receiving audit entries
+type collector struct {
+	mu      sync.Mutex
+	entries []map[string]interface{}
+	auth    []string
+	status  int
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.status != 0 {
+		w.WriteHeader(c.status)
+		return
+	}
+	var entry map[string]interface{}
+	json.Unmarshal(body, &entry)
+	c.entries = append(c.entries, entry)
+	c.auth = append(c.auth, r.Header.Get("Authorization"))
+}
+
+func TestAuditHTTPDevice(t *testing.T) {
+	backend := funcBackend(func(ctx context.Context, req *logical.Request) (*logical.Response, error) {
+		return &logical.Response{Data: map[string]interface{}{"password": "s3cret"}}, nil
+	})
+	handler := NewHandler(backend, newMockStorage(), hclog.NewNullLogger(), "plugin")
+
+	sink := &collector{}
+	server := httptest.NewServer(sink)
+	defer server.Close()
+	shipped, err := ParseAuditDevice("http:name=ingest,url=" + server.URL + ",hmac=true,header=Authorization: Bearer t0ken")
+	if err != nil {
+		t.Fatalf("ParseAuditDevice failed: %v", err)
+	}
+	var log bytes.Buffer
+	broker := auditBrokerFor(t, newAuditDeviceWriter(&log, nil), shipped)
+	defer broker.Close()
+	handler.SetAuditBroker(broker)
+
+	handler.HandleRequest(httptest.NewRecorder(), httptest.NewRequest("GET", "/v1/plugin/creds/test", nil))
+
+	// Each device gets every entry, formatted with its own options
+	if len(sink.entries) != 2 || sink.auth[0] != "Bearer t0ken" {
+		t.Fatalf("collector received %d entries with %v, want 2 with the configured header", len(sink.entries), sink.auth)
+	}
+	data := sink.entries[1]["response"].(map[string]interface{})["data"].(map[string]interface{})
+	if data["password"] != shipped.Hash("s3cret") {
+		t.Errorf("http device should hash values, got %v", data["password"])
+	}
+	if raw := auditEntries(t, &log)[1]["response"].(map[string]interface{})["data"].(map[string]interface{}); raw["password"] != "s3cret" {
+		t.Errorf("file device should log values raw, got %v", raw["password"])
+	}
+
+	w := httptest.NewRecorder()
+	broker.HandleHash(w, httptest.NewRequest("POST", "/v1/sys/audit-hash/ingest", strings.NewReader(`{"input":"s3cret"}`)))
+	if !strings.Contains(w.Body.String(), shipped.Hash("s3cret")) {
+		t.Errorf("audit-hash/ingest = %s, want the http device's hash", w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	broker.HandleHash(w, httptest.NewRequest("POST", "/v1/sys/audit-hash/missing", strings.NewReader(`{"input":"s3cret"}`)))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("audit-hash of an unknown device = %d, want 400", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	broker.HandleList(w, httptest.NewRequest("GET", "/v1/sys/audit", nil))
+	var list struct {
+		Data map[string]struct {
+			Type    string            `json:"type"`
+			Options map[string]string `json:"options"`
+		} `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &list)
+	if list.Data["file/"].Type != "file" || list.Data["ingest/"].Type != "http" || list.Data["ingest/"].Options["header"] != "Authorization" {
+		t.Errorf("listed devices = %s", w.Body.String())
+	}
+
+	if err := broker.Enable(newAuditDeviceWriter(io.Discard, nil)); err == nil {
+		t.Error("enabling a second device at file/ should fail")
+	}
+}
+
+func TestAuditBrokerFailures(t *testing.T) {
+	calls := 0
+	backend := funcBackend(func(ctx context.Context, req *logical.Request) (*logical.Response, error) {
+		calls++
+		return nil, nil
+	})
+	handler := NewHandler(backend, newMockStorage(), hclog.NewNullLogger(), "plugin")
+
+	sink := &collector{status: http.StatusServiceUnavailable}
+	server := httptest.NewServer(sink)
+	defer server.Close()
+	failing, err := ParseAuditDevice("http:url=" + server.URL)
+	if err != nil {
+		t.Fatalf("ParseAuditDevice failed: %v", err)
+	}
+
+	// A request is served as long as one device records it
+	var log bytes.Buffer
+	handler.SetAuditBroker(auditBrokerFor(t, failing, newAuditDeviceWriter(&log, nil)))
+	w := httptest.NewRecorder()
+	handler.HandleRequest(w, httptest.NewRequest("GET", "/v1/plugin/config", nil))
+	if w.Code != http.StatusNotFound || calls != 1 || len(auditEntries(t, &log)) != 2 {
+		t.Errorf("with one working device: status %d, %d plugin calls, log %q", w.Code, calls, log.String())
+	}
+
+	// and refused when none can
+	handler.SetAuditBroker(auditBrokerFor(t, failing))
+	w = httptest.NewRecorder()
+	handler.HandleRequest(w, httptest.NewRequest("GET", "/v1/plugin/config", nil))
+	if w.Code != http.StatusInternalServerError || calls != 1 {
+		t.Errorf("without a working device: status %d, %d plugin calls; want 500 and no call", w.Code, calls)
+	}
+}
+
+func TestAuditSyslogDevice(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+	defer conn.Close()
+
+	device, err := ParseAuditDevice("syslog:network=udp,address=" + conn.LocalAddr().String() + ",facility=local0,tag=vault-test")
+	if err != nil {
+		t.Fatalf("ParseAuditDevice failed: %v", err)
+	}
+	defer device.Close()
+	if device.Name() != "syslog" || device.Type() != "syslog" {
+		t.Errorf("device = %s of type %s, want syslog/", device.Name(), device.Type())
+	}
+
+	req := &logical.Request{ID: "req-1", Operation: logical.ReadOperation, Path: "config"}
+	if err := device.LogRequest(httptest.NewRequest("GET", "/v1/plugin/config", nil), req, TokenEntry{}, "plugin"); err != nil {
+		t.Fatalf("LogRequest failed: %v", err)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	buf := make([]byte, 64*1024)
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("no syslog message: %v", err)
+	}
+	// local0.info is priority 16*8+6
+	message := string(buf[:n])
+	if !strings.HasPrefix(message, "<134>") || !strings.Contains(message, "vault-test") || !strings.Contains(message, `"path":"plugin/config"`) {
+		t.Errorf("syslog message = %q", message)
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// auditEntries decodes the lines of an audit log
+func auditEntries(t *testing.T, log *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+	var entries []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(log.String()), "\n") {
+		var entry map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("invalid audit line %q: %v", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// auditBrokerFor enables devices on a new broker
+func auditBrokerFor(t *testing.T, devices ...*AuditDevice) *AuditBroker {
+	t.Helper()
+	broker := NewAuditBroker()
+	for _, device := range devices {
+		if err := broker.Enable(device); err != nil {
+			t.Fatalf("Enable failed: %v", err)
+		}
+	}
+	return broker
+}
+
+func TestAuditLogsRequestAndResponse(t *testing.T) {
+	backend := funcBackend(func(ctx context.Context, req *logical.Request) (*logical.Response, error) {
+		return &logical.Response{Data: map[string]interface{}{"password": "s3cret"}}, nil
+	})
+	handler := NewHandler(backend, newMockStorage(), hclog.NewNullLogger(), "plugin")
+	tokens := NewTokenStore("root-token")
+	handler.SetTokenStore(tokens)
+	var log bytes.Buffer
+	handler.SetAuditBroker(auditBrokerFor(t, newAuditDeviceWriter(&log, nil)))
+
+	req := httptest.NewRequest("POST", "/v1/plugin/creds/test", strings.NewReader(`{"ttl":"1h"}`))
+	req.Header.Set("X-Vault-Token", "root-token")
+	handler.HandleRequest(httptest.NewRecorder(), req)
+
+	entries := auditEntries(t, &log)
+	if len(entries) != 2 || entries[0]["type"] != "request" || entries[1]["type"] != "response" {
+		t.Fatalf("expected a request and a response entry, got %v", entries)
+	}
+
+	request := entries[0]["request"].(map[string]interface{})
+	if request["path"] != "plugin/creds/test" || request["operation"] != "update" || request["mount_point"] != "plugin/" {
+		t.Errorf("unexpected request: %v", request)
+	}
+	if request["client_token"] != "root-token" {
+		t.Errorf("raw audit log should keep the token, got %v", request["client_token"])
+	}
+	auth := entries[0]["auth"].(map[string]interface{})
+	if auth["display_name"] != "root" {
+		t.Errorf("unexpected auth: %v", auth)
+	}
+
+	response := entries[1]["response"].(map[string]interface{})
+	if data := response["data"].(map[string]interface{}); data["password"] != "s3cret" {
+		t.Errorf("unexpected response data: %v", response)
+	}
+}
+
+func TestAuditHMAC(t *testing.T) {
+	backend := funcBackend(func(ctx context.Context, req *logical.Request) (*logical.Response, error) {
+		return &logical.Response{Data: map[string]interface{}{"password": "s3cret", "ttl": 3600}}, nil
+	})
+	handler := NewHandler(backend, newMockStorage(), hclog.NewNullLogger(), "plugin")
+	var log bytes.Buffer
+	audit := newAuditDeviceWriter(&log, []byte("key"))
+	handler.SetAuditBroker(auditBrokerFor(t, audit))
+
+	req := httptest.NewRequest("GET", "/v1/plugin/creds/test", nil)
+	req.Header.Set("X-Vault-Token", "some-token")
+	handler.HandleRequest(httptest.NewRecorder(), req)
+
+	entries := auditEntries(t, &log)
+	request := entries[1]["request"].(map[string]interface{})
+	if request["client_token"] != audit.Hash("some-token") || !strings.HasPrefix(audit.Hash("some-token"), hmacPrefix) {
+		t.Errorf("token should be hashed, got %v", request["client_token"])
+	}
+	data := entries[1]["response"].(map[string]interface{})["data"].(map[string]interface{})
+	if data["password"] != audit.Hash("s3cret") {
+		t.Errorf("string values should be hashed, got %v", data["password"])
+	}
+	if data["ttl"] != float64(3600) {
+		t.Errorf("non-string values should be kept, got %v", data["ttl"])
+	}
+
+	w := httptest.NewRecorder()
+	audit.HandleHash(w, httptest.NewRequest("POST", "/v1/sys/audit-hash/file", strings.NewReader(`{"input":"s3cret"}`)))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), audit.Hash("s3cret")) {
+		t.Errorf("audit-hash should return the logged value, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestAuditLogsErrors(t *testing.T) {
+	backend := funcBackend(func(ctx context.Context, req *logical.Request) (*logical.Response, error) {
+		return nil, errors.New("backend exploded")
+	})
+	handler := NewHandler(backend, newMockStorage(), hclog.NewNullLogger(), "plugin")
+	var log bytes.Buffer
+	handler.SetAuditBroker(auditBrokerFor(t, newAuditDeviceWriter(&log, nil)))
+
+	handler.HandleRequest(httptest.NewRecorder(), httptest.NewRequest("GET", "/v1/plugin/broken", nil))
+
+	entries := auditEntries(t, &log)
+	if len(entries) != 2 || entries[1]["error"] != "backend exploded" {
+		t.Errorf("response entry should carry the error, got %v", entries)
+	}
+}
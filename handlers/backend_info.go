@@ -0,0 +1,129 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// redactedConfigKeys are fragments of setup config keys whose values are credentials
+// and are not reported
+var redactedConfigKeys = []string{"password", "passwd", "secret", "token", "key", "credential", "private"}
+
+// BackendSetup is how the host set up the backend it serves
+type BackendSetup struct {
+	MultiplexID string            // ID of the backend instance in a multiplexed plugin; empty when not multiplexed
+	Config      map[string]string // the config the backend was set up with
+}
+
+// SetBackendSetup records how the backend was set up; nil when it is not running
+func (h *Handler) SetBackendSetup(setup *BackendSetup) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.setup = setup
+}
+
+// HandleBackendInfo serves GET /v1/sys/host/backend: what the mounted backend reports
+// of itself (its Type, SpecialPaths and plugin version) together with the protocol
+// it is served over, whether it is multiplexed and the config it was set up with,
+// credentials redacted
+func (h *Handler) HandleBackendInfo(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.writeVaultError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	h.mu.RLock()
+	backend := h.backend
+	conn := h.connection
+	setup := h.setup
+	pluginType := h.pluginType
+	h.mu.RUnlock()
+
+	if backend == nil {
+		h.writeVaultError(w, http.StatusServiceUnavailable, "plugin is not running")
+		return
+	}
+
+	data := map[string]interface{}{
+		"mount":  h.mountPath,
+		"plugin": pluginType,
+	}
+	backendType := logical.TypeUnknown
+	if b, ok := backend.(interface{ Type() logical.BackendType }); ok {
+		backendType = b.Type()
+	}
+	data["type"] = backendType.String()
+	data["type_code"] = int(backendType)
+
+	var paths *logical.Paths
+	if b, ok := backend.(interface{ SpecialPaths() *logical.Paths }); ok {
+		paths = b.SpecialPaths()
+	}
+	data["special_paths"] = specialPathsData(paths)
+
+	if b, ok := backend.(logical.PluginVersioner); ok {
+		data["plugin_version"] = b.PluginVersion().Version
+	}
+	if conn != nil {
+		data["protocol"] = conn.Protocol
+		data["protocol_version"] = conn.ProtocolVersion
+	}
+	data["multiplexed"] = false
+	if setup != nil {
+		data["multiplexed"] = setup.MultiplexID != ""
+		if setup.MultiplexID != "" {
+			data["multiplex_id"] = setup.MultiplexID
+		}
+		data["config"] = redactConfig(setup.Config)
+	}
+
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"request_id": newRequestID(),
+		"data":       data,
+	})
+}
+
+// specialPathsData lists special paths under the names Vault's API uses, with every
+// kind present so clients need not tell missing from empty
+func specialPathsData(paths *logical.Paths) map[string][]string {
+	if paths == nil {
+		paths = &logical.Paths{}
+	}
+	data := map[string][]string{
+		"root":                    paths.Root,
+		"unauthenticated":         paths.Unauthenticated,
+		"local_storage":           paths.LocalStorage,
+		"seal_wrap_storage":       paths.SealWrapStorage,
+		"write_forwarded_storage": paths.WriteForwardedStorage,
+		"binary":                  paths.Binary,
+		"limited":                 paths.Limited,
+		"allow_snapshot_read":     paths.AllowSnapshotRead,
+	}
+	for kind, list := range data {
+		if list == nil {
+			data[kind] = []string{}
+		}
+	}
+	return data
+}
+
+// redactConfig copies config with the values of credential-like keys replaced
+func redactConfig(config map[string]string) map[string]string {
+	redacted := make(map[string]string, len(config))
+	for key, value := range config {
+		lower := strings.ToLower(key)
+		for _, fragment := range redactedConfigKeys {
+			if strings.Contains(lower, fragment) {
+				value = "[redacted]"
+				break
+			}
+		}
+		redacted[key] = value
+	}
+	return redacted
+}
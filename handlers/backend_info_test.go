@@ -0,0 +1,87 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// describedBackend reports its type, special paths and version like a plugin backend
+type describedBackend struct {
+	mockBackend
+}
+
+func (describedBackend) Type() logical.BackendType { return logical.TypeCredential }
+
+func (describedBackend) SpecialPaths() *logical.Paths {
+	return &logical.Paths{Unauthenticated: []string{"login"}, SealWrapStorage: []string{"config"}}
+}
+
+func (describedBackend) PluginVersion() logical.PluginVersion {
+	return logical.PluginVersion{Version: "v1.2.3"}
+}
+
+func TestHandleBackendInfo(t *testing.T) {
+	handler := NewHandler(nil, newMockStorage(), hclog.NewNullLogger(), "auth/example")
+	get := func() (int, map[string]interface{}) {
+		w := httptest.NewRecorder()
+		handler.HandleBackendInfo(w, httptest.NewRequest(http.MethodGet, "/v1/sys/host/backend", nil))
+		var response struct {
+			Data map[string]interface{} `json:"data"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return w.Code, response.Data
+	}
+
+	if code, _ := get(); code != http.StatusServiceUnavailable {
+		t.Errorf("status without a plugin = %d, want 503", code)
+	}
+
+	handler.SetBackend(&describedBackend{})
+	handler.SetPluginType("vault-plugin-auth-example")
+	handler.SetPluginConnection(&PluginConnection{Protocol: "grpc", ProtocolVersion: 5})
+	handler.SetBackendSetup(&BackendSetup{MultiplexID: "abc", Config: map[string]string{"region": "eu", "client_secret": "s3cr3t", "API_Key": "k"}})
+
+	code, data := get()
+	if code != http.StatusOK {
+		t.Fatalf("status = %d", code)
+	}
+	for key, want := range map[string]interface{}{
+		"mount":            "auth/example",
+		"plugin":           "vault-plugin-auth-example",
+		"type":             "auth",
+		"type_code":        2.0,
+		"plugin_version":   "v1.2.3",
+		"protocol":         "grpc",
+		"protocol_version": 5.0,
+		"multiplexed":      true,
+		"multiplex_id":     "abc",
+	} {
+		if data[key] != want {
+			t.Errorf("%s = %v, want %v", key, data[key], want)
+		}
+	}
+	paths := data["special_paths"].(map[string]interface{})
+	if fmt.Sprint(paths["unauthenticated"], paths["seal_wrap_storage"], paths["root"]) != "[login] [config] []" {
+		t.Errorf("special_paths = %v", paths)
+	}
+	config := data["config"].(map[string]interface{})
+	if config["region"] != "eu" || config["client_secret"] != "[redacted]" || config["API_Key"] != "[redacted]" {
+		t.Errorf("config = %v, want credentials redacted", config)
+	}
+
+	// A backend that reports nothing of itself
+	handler.SetBackend(&mockBackend{})
+	handler.SetBackendSetup(&BackendSetup{})
+	if _, data := get(); data["type"] != "unknown" || data["multiplexed"] != false || data["plugin_version"] != nil {
+		t.Errorf("bare backend = %v", data)
+	}
+}
@@ -0,0 +1,210 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Breaker states, as reported in breaker metadata
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half-open"
+)
+
+// CircuitBreaker fails requests to a path fast once the plugin has failed a number of
+// consecutive requests to it, so clients can be tested against a persistently failing
+// plugin and fuzzing does not keep hammering a broken path. A failure is a backend
+// error answered with a 5xx status, a timeout included; any other answer resets the
+// count. After the cool-down one request is let through: its success closes the
+// breaker again, and its failure opens it for another cool-down.
+type CircuitBreaker struct {
+	threshold int
+	coolDown  time.Duration
+	now       func() time.Time
+
+	mu    sync.Mutex
+	paths map[string]*breakerPath
+}
+
+// breakerPath is the breaker state of one path
+type breakerPath struct {
+	failures  int // consecutive
+	trips     int
+	openedAt  time.Time
+	probing   bool // the request let through after the cool-down is in progress
+	lastError string
+}
+
+// BreakerStatus describes the breaker of a path, as fast-failed responses and
+// /v1/sys/host/breakers report it
+type BreakerStatus struct {
+	Path       string    `json:"path"`
+	State      string    `json:"state"`
+	Failures   int       `json:"consecutive_failures"`
+	Trips      int       `json:"trips"`
+	OpenedAt   time.Time `json:"opened_at,omitempty"`
+	RetryAfter int       `json:"retry_after,omitempty"` // seconds until a request is let through
+	LastError  string    `json:"last_error,omitempty"`
+}
+
+// NewCircuitBreaker creates a breaker that opens after threshold consecutive failures
+// of a path and lets a request through again after coolDown
+func NewCircuitBreaker(threshold int, coolDown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{
+		threshold: threshold,
+		coolDown:  coolDown,
+		now:       time.Now,
+		paths:     make(map[string]*breakerPath),
+	}
+}
+
+// allow reports whether a request to path may reach the plugin. When it may not, the
+// status describes the open breaker.
+func (b *CircuitBreaker) allow(path string) (BreakerStatus, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	state := b.paths[path]
+	if state == nil || state.failures < b.threshold {
+		return BreakerStatus{}, true
+	}
+	if !state.probing && b.now().Sub(state.openedAt) >= b.coolDown {
+		state.probing = true
+		return BreakerStatus{}, true
+	}
+	return b.status(path, state), false
+}
+
+// record counts the outcome of a request to path that reached the plugin
+func (b *CircuitBreaker) record(path string, failure error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	state := b.paths[path]
+	if failure == nil {
+		if state != nil {
+			state.failures = 0
+			state.probing = false
+		}
+		return
+	}
+	if state == nil {
+		state = &breakerPath{}
+		b.paths[path] = state
+	}
+	state.failures++
+	state.lastError = failure.Error()
+	if state.probing || state.failures == b.threshold {
+		state.trips++
+		state.openedAt = b.now()
+	}
+	state.probing = false
+}
+
+// status describes the breaker of a path; the caller holds b.mu
+func (b *CircuitBreaker) status(path string, state *breakerPath) BreakerStatus {
+	status := BreakerStatus{
+		Path:      path,
+		State:     BreakerClosed,
+		Failures:  state.failures,
+		Trips:     state.trips,
+		LastError: state.lastError,
+	}
+	if state.failures < b.threshold {
+		return status
+	}
+	status.OpenedAt = state.openedAt
+	remaining := b.coolDown - b.now().Sub(state.openedAt)
+	switch {
+	case state.probing:
+		status.State = BreakerHalfOpen
+	case remaining > 0:
+		status.State = BreakerOpen
+		status.RetryAfter = int(math.Ceil(remaining.Seconds()))
+	default:
+		status.State = BreakerHalfOpen
+	}
+	return status
+}
+
+// Statuses returns the breakers of the paths that failed since they last succeeded
+func (b *CircuitBreaker) Statuses() []BreakerStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	statuses := make([]BreakerStatus, 0, len(b.paths))
+	for path, state := range b.paths {
+		if state.failures > 0 {
+			statuses = append(statuses, b.status(path, state))
+		}
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Path < statuses[j].Path })
+	return statuses
+}
+
+// Reset closes every breaker
+func (b *CircuitBreaker) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.paths = make(map[string]*breakerPath)
+}
+
+// SetCircuitBreaker fails requests to paths that keep failing fast; nil disables it
+func (h *Handler) SetCircuitBreaker(breaker *CircuitBreaker) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.breaker = breaker
+}
+
+// err is the error a request failed fast by the breaker is audited with
+func (s BreakerStatus) err() error {
+	return fmt.Errorf("circuit breaker open for %s after %d consecutive failures: %s", s.Path, s.Failures, s.LastError)
+}
+
+// writeBreakerOpen responds with 503, Retry-After and the breaker's state
+func (h *Handler) writeBreakerOpen(w http.ResponseWriter, status BreakerStatus) {
+	h.logger.Debug("circuit breaker open", "path", status.Path, "failures", status.Failures)
+	if status.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(status.RetryAfter))
+	}
+	WriteJSON(w, http.StatusServiceUnavailable, map[string]interface{}{
+		"errors":          []string{status.err().Error()},
+		"circuit_breaker": status,
+	})
+}
+
+// HandleCircuitBreakers serves /v1/sys/host/breakers: GET lists the paths that are
+// failing and their breakers, DELETE closes every breaker
+func (h *Handler) HandleCircuitBreakers(w http.ResponseWriter, r *http.Request) {
+	h.mu.RLock()
+	breaker := h.breaker
+	h.mu.RUnlock()
+
+	if breaker == nil {
+		h.writeVaultError(w, http.StatusNotFound, "circuit breaker is not enabled")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		WriteJSON(w, http.StatusOK, map[string]interface{}{
+			"threshold": breaker.threshold,
+			"cool_down": breaker.coolDown.String(),
+			"breakers":  breaker.Statuses(),
+		})
+	case http.MethodDelete:
+		breaker.Reset()
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		h.writeVaultError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
@@ -0,0 +1,139 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// flakyBackend fails every request while failing is set
+type flakyBackend struct {
+	failing bool
+	calls   int
+}
+
+func (b *flakyBackend) HandleRequest(ctx context.Context, req *logical.Request) (*logical.Response, error) {
+	b.calls++
+	if b.failing {
+		return nil, errors.New("database connection refused")
+	}
+	return &logical.Response{Data: map[string]interface{}{"ok": true}}, nil
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	backend := &flakyBackend{failing: true}
+	handler := NewHandler(backend, newMockStorage(), hclog.NewNullLogger(), "plugin")
+	breaker := NewCircuitBreaker(3, 10*time.Second)
+	now := time.Now()
+	breaker.now = func() time.Time { return now }
+	handler.SetCircuitBreaker(breaker)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.HandleRequest(w, httptest.NewRequest("GET", "/v1/plugin/"+path, nil))
+		return w
+	}
+
+	for i := 0; i < 3; i++ {
+		if w := get("creds/app"); w.Code != http.StatusInternalServerError {
+			t.Fatalf("request %d: status = %d, want 500", i, w.Code)
+		}
+	}
+
+	// The breaker is open: the plugin is not called and the response says why
+	w := get("creds/app")
+	if w.Code != http.StatusServiceUnavailable || backend.calls != 3 {
+		t.Fatalf("status = %d after %d calls, want 503 after 3", w.Code, backend.calls)
+	}
+	if w.Header().Get("Retry-After") != "10" {
+		t.Errorf("Retry-After = %q, want 10", w.Header().Get("Retry-After"))
+	}
+	var response struct {
+		Errors  []string      `json:"errors"`
+		Breaker BreakerStatus `json:"circuit_breaker"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if response.Breaker.State != BreakerOpen || response.Breaker.Failures != 3 || response.Breaker.Path != "creds/app" ||
+		response.Breaker.LastError != "database connection refused" || len(response.Errors) != 1 {
+		t.Errorf("response = %+v", response)
+	}
+
+	// Other paths are unaffected
+	backend.failing = false
+	if w := get("config"); w.Code != http.StatusOK {
+		t.Errorf("other path status = %d, want 200", w.Code)
+	}
+
+	// After the cool-down one request is let through; it fails and reopens the breaker
+	backend.failing = true
+	now = now.Add(10 * time.Second)
+	if w := get("creds/app"); w.Code != http.StatusInternalServerError {
+		t.Fatalf("trial request status = %d, want 500", w.Code)
+	}
+	if w := get("creds/app"); w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status after failed trial = %d, want 503", w.Code)
+	}
+	statuses := breaker.Statuses()
+	if len(statuses) != 1 || statuses[0].Trips != 2 || statuses[0].State != BreakerOpen {
+		t.Errorf("statuses = %+v", statuses)
+	}
+
+	// A successful trial closes it
+	backend.failing = false
+	now = now.Add(10 * time.Second)
+	if w := get("creds/app"); w.Code != http.StatusOK {
+		t.Fatalf("trial request status = %d, want 200", w.Code)
+	}
+	if w := get("creds/app"); w.Code != http.StatusOK {
+		t.Errorf("status after successful trial = %d, want 200", w.Code)
+	}
+	if statuses := breaker.Statuses(); len(statuses) != 0 {
+		t.Errorf("statuses = %+v, want none", statuses)
+	}
+}
+
+func TestHandleCircuitBreakers(t *testing.T) {
+	handler := NewHandler(&flakyBackend{failing: true}, newMockStorage(), hclog.NewNullLogger(), "plugin")
+
+	w := httptest.NewRecorder()
+	handler.HandleCircuitBreakers(w, httptest.NewRequest("GET", "/v1/sys/host/breakers", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("status without a breaker = %d, want 404", w.Code)
+	}
+
+	handler.SetCircuitBreaker(NewCircuitBreaker(1, time.Minute))
+	handler.HandleRequest(httptest.NewRecorder(), httptest.NewRequest("GET", "/v1/plugin/creds/app", nil))
+
+	w = httptest.NewRecorder()
+	handler.HandleCircuitBreakers(w, httptest.NewRequest("GET", "/v1/sys/host/breakers", nil))
+	var listed struct {
+		Threshold int             `json:"threshold"`
+		Breakers  []BreakerStatus `json:"breakers"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&listed); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if listed.Threshold != 1 || len(listed.Breakers) != 1 || listed.Breakers[0].State != BreakerOpen {
+		t.Errorf("listed = %+v", listed)
+	}
+
+	w = httptest.NewRecorder()
+	handler.HandleCircuitBreakers(w, httptest.NewRequest("DELETE", "/v1/sys/host/breakers", nil))
+	w = httptest.NewRecorder()
+	handler.HandleRequest(w, httptest.NewRequest("GET", "/v1/plugin/creds/app", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("status after reset = %d, want the plugin's 500", w.Code)
+	}
+}
@@ -0,0 +1,228 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNoCheckpoint is returned when comparing with a checkpoint that was never taken
+var ErrNoCheckpoint = errors.New("no such checkpoint")
+
+// Checkpoint is a named snapshot of a mount's state taken between the phases of a
+// test: its storage entries, its leases and the tokens known to the host. Entries are
+// keyed "storage/<key>", "leases/<lease id>" and "tokens/<accessor>".
+type Checkpoint struct {
+	Name    string            `json:"name"`
+	Taken   time.Time         `json:"taken"`
+	Entries map[string]string `json:"-"`
+}
+
+// StateDifference is an entry that changed since a checkpoint
+type StateDifference struct {
+	Key    string `json:"key"`
+	Change string `json:"change"` // added, removed or changed
+	Before string `json:"before,omitempty"`
+	After  string `json:"after,omitempty"`
+}
+
+// Checkpoint captures the handler's current state under name
+func (h *Handler) Checkpoint(ctx context.Context, name string) (*Checkpoint, error) {
+	h.mu.RLock()
+	storage := h.storage
+	tokens := h.tokens
+	h.mu.RUnlock()
+
+	checkpoint := &Checkpoint{Name: name, Taken: time.Now().UTC(), Entries: make(map[string]string)}
+
+	keys, err := storage.List(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list storage: %w", err)
+	}
+	for _, key := range keys {
+		entry, err := storage.Get(ctx, key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read storage entry %q: %w", key, err)
+		}
+		if entry != nil {
+			checkpoint.Entries["storage/"+key] = string(entry.Value)
+		}
+	}
+
+	h.leases.Range(func(lease *LeaseInfo) bool {
+		checkpoint.Entries["leases/"+lease.LeaseID] = lease.Path
+		return true
+	})
+	for accessor, path := range tokens.Accessors() {
+		checkpoint.Entries["tokens/"+accessor] = path
+	}
+	return checkpoint, nil
+}
+
+// Compare lists the entries of current that differ from the checkpoint, sorted by key.
+// Entries matching an ignore pattern are skipped; a pattern matches a key as a
+// path.Match glob, or as a prefix when it ends in "/" (so "leases/" ignores all leases).
+func (c *Checkpoint) Compare(current *Checkpoint, ignore []string) []StateDifference {
+	keys := make([]string, 0, len(c.Entries)+len(current.Entries))
+	for key := range c.Entries {
+		keys = append(keys, key)
+	}
+	for key := range current.Entries {
+		if _, ok := c.Entries[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	differences := []StateDifference{}
+	for _, key := range keys {
+		if ignoredStateKey(key, ignore) {
+			continue
+		}
+		before, hadBefore := c.Entries[key]
+		after, hasAfter := current.Entries[key]
+		switch {
+		case !hadBefore:
+			differences = append(differences, StateDifference{Key: key, Change: "added", After: after})
+		case !hasAfter:
+			differences = append(differences, StateDifference{Key: key, Change: "removed", Before: before})
+		case before != after:
+			differences = append(differences, StateDifference{Key: key, Change: "changed", Before: before, After: after})
+		}
+	}
+	return differences
+}
+
+// ignoredStateKey reports whether a checkpoint key matches one of the ignore patterns
+func ignoredStateKey(key string, ignore []string) bool {
+	for _, pattern := range ignore {
+		if strings.HasSuffix(pattern, "/") && strings.HasPrefix(key, pattern) {
+			return true
+		}
+		if matched, _ := path.Match(pattern, key); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// Checkpoints holds the checkpoints taken during a test run
+type Checkpoints struct {
+	handler *Handler
+
+	mu     sync.Mutex
+	byName map[string]*Checkpoint
+}
+
+// NewCheckpoints creates an empty set of checkpoints of a handler's state
+func NewCheckpoints(handler *Handler) *Checkpoints {
+	return &Checkpoints{handler: handler, byName: make(map[string]*Checkpoint)}
+}
+
+// Take captures the current state under name, replacing an earlier checkpoint of that name
+func (c *Checkpoints) Take(ctx context.Context, name string) (*Checkpoint, error) {
+	if name == "" {
+		return nil, fmt.Errorf("a checkpoint name is required")
+	}
+	checkpoint, err := c.handler.Checkpoint(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.byName[name] = checkpoint
+	return checkpoint, nil
+}
+
+// Compare captures the current state and compares it with the named checkpoint
+func (c *Checkpoints) Compare(ctx context.Context, name string, ignore []string) ([]StateDifference, error) {
+	c.mu.Lock()
+	checkpoint, ok := c.byName[name]
+	c.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrNoCheckpoint, name)
+	}
+
+	current, err := c.handler.Checkpoint(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return checkpoint.Compare(current, ignore), nil
+}
+
+// Remove deletes a checkpoint and reports whether it existed
+func (c *Checkpoints) Remove(name string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.byName[name]
+	delete(c.byName, name)
+	return ok
+}
+
+// HandleCheckpoints manages checkpoints at /v1/sys/host/checkpoints/<name>:
+//
+//	PUT    - capture the current state under <name>
+//	GET    - compare the current state with <name>; repeat ?ignore= to skip keys
+//	DELETE - forget <name>
+//
+// The comparison answers 200 when nothing changed and 409 with the differences otherwise.
+func (c *Checkpoints) HandleCheckpoints(w http.ResponseWriter, r *http.Request) {
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/v1/sys/host/checkpoints"), "/")
+	if name == "" {
+		WriteError(w, http.StatusBadRequest, "a checkpoint name is required")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut, http.MethodPost:
+		checkpoint, err := c.Take(r.Context(), name)
+		if err != nil {
+			WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		WriteJSON(w, http.StatusOK, map[string]interface{}{
+			"name":    checkpoint.Name,
+			"taken":   checkpoint.Taken,
+			"entries": len(checkpoint.Entries),
+		})
+
+	case http.MethodGet:
+		differences, err := c.Compare(r.Context(), name, r.URL.Query()["ignore"])
+		if err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, ErrNoCheckpoint) {
+				status = http.StatusNotFound
+			}
+			WriteError(w, status, err.Error())
+			return
+		}
+		status := http.StatusOK
+		if len(differences) > 0 {
+			status = http.StatusConflict
+		}
+		WriteJSON(w, status, map[string]interface{}{
+			"checkpoint":  name,
+			"match":       len(differences) == 0,
+			"differences": differences,
+		})
+
+	case http.MethodDelete:
+		if !c.Remove(name) {
+			WriteError(w, http.StatusNotFound, fmt.Sprintf("%v: %q", ErrNoCheckpoint, name))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
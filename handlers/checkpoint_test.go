@@ -0,0 +1,110 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestCheckpointCompare(t *testing.T) {
+	ctx := context.Background()
+	storage := newMockStorage()
+	storage.Put(ctx, &logical.StorageEntry{Key: "config", Value: []byte(`{"url":"a"}`)})
+	storage.Put(ctx, &logical.StorageEntry{Key: "roles/old", Value: []byte(`{}`)})
+	handler := NewHandler(&mockBackend{}, storage, hclog.NewNullLogger(), "plugin")
+	handler.SetTokenStore(NewTokenStore("root"))
+	checkpoints := NewCheckpoints(handler)
+
+	if _, err := checkpoints.Take(ctx, "before"); err != nil {
+		t.Fatalf("Take failed: %v", err)
+	}
+	differences, err := checkpoints.Compare(ctx, "before", nil)
+	if err != nil || len(differences) != 0 {
+		t.Fatalf("unchanged state should match, got %v, %v", differences, err)
+	}
+
+	storage.Put(ctx, &logical.StorageEntry{Key: "config", Value: []byte(`{"url":"b"}`)})
+	storage.Delete(ctx, "roles/old")
+	storage.Put(ctx, &logical.StorageEntry{Key: "roles/new", Value: []byte(`{}`)})
+	handler.leases.Put(&LeaseInfo{LeaseID: "plugin/creds/test/abc", Path: "creds/test"})
+
+	differences, err = checkpoints.Compare(ctx, "before", []string{"leases/"})
+	if err != nil {
+		t.Fatalf("Compare failed: %v", err)
+	}
+	want := []StateDifference{
+		{Key: "storage/config", Change: "changed", Before: `{"url":"a"}`, After: `{"url":"b"}`},
+		{Key: "storage/roles/new", Change: "added", After: `{}`},
+		{Key: "storage/roles/old", Change: "removed", Before: `{}`},
+	}
+	if len(differences) != len(want) {
+		t.Fatalf("got differences %+v, want %+v", differences, want)
+	}
+	for i := range want {
+		if differences[i] != want[i] {
+			t.Errorf("difference %d: got %+v, want %+v", i, differences[i], want[i])
+		}
+	}
+
+	differences, _ = checkpoints.Compare(ctx, "before", []string{"leases/", "storage/*", "storage/roles/*"})
+	if len(differences) != 0 {
+		t.Errorf("ignored keys should not be reported, got %+v", differences)
+	}
+
+	if _, err := checkpoints.Compare(ctx, "missing", nil); err == nil {
+		t.Error("comparing with an unknown checkpoint should fail")
+	}
+}
+
+func TestHandleCheckpoints(t *testing.T) {
+	storage := newMockStorage()
+	handler := NewHandler(&mockBackend{}, storage, hclog.NewNullLogger(), "plugin")
+	checkpoints := NewCheckpoints(handler)
+
+	w := httptest.NewRecorder()
+	checkpoints.HandleCheckpoints(w, httptest.NewRequest("PUT", "/v1/sys/host/checkpoints/phase1", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	storage.Put(context.Background(), &logical.StorageEntry{Key: "config", Value: []byte("x")})
+
+	w = httptest.NewRecorder()
+	checkpoints.HandleCheckpoints(w, httptest.NewRequest("GET", "/v1/sys/host/checkpoints/phase1", nil))
+	if w.Code != http.StatusConflict {
+		t.Fatalf("expected 409, got %d: %s", w.Code, w.Body.String())
+	}
+	var result struct {
+		Match       bool              `json:"match"`
+		Differences []StateDifference `json:"differences"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &result)
+	if result.Match || len(result.Differences) != 1 || result.Differences[0].Key != "storage/config" {
+		t.Errorf("unexpected comparison: %s", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	checkpoints.HandleCheckpoints(w, httptest.NewRequest("GET", "/v1/sys/host/checkpoints/phase1?ignore=storage/config", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("expected 200 with the change ignored, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	checkpoints.HandleCheckpoints(w, httptest.NewRequest("DELETE", "/v1/sys/host/checkpoints/phase1", nil))
+	if w.Code != http.StatusNoContent {
+		t.Errorf("expected 204, got %d", w.Code)
+	}
+	w = httptest.NewRecorder()
+	checkpoints.HandleCheckpoints(w, httptest.NewRequest("GET", "/v1/sys/host/checkpoints/phase1", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for a removed checkpoint, got %d", w.Code)
+	}
+}
@@ -0,0 +1,180 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// maxClientPaths is the number of distinct paths counted per client
+	maxClientPaths = 200
+	// maxClientErrors is the number of recent failed requests kept per client
+	maxClientErrors = 20
+)
+
+// ClientRequest is a failed request of a client
+type ClientRequest struct {
+	Time   time.Time `json:"time"`
+	Method string    `json:"method"`
+	Path   string    `json:"path"`
+	Status int       `json:"status"`
+}
+
+// ClientStats describes one client fingerprint: the requests it sent, how many failed
+// and the paths it touched
+type ClientStats struct {
+	ID         string            `json:"id"`
+	UserAgent  string            `json:"user_agent"`
+	RemoteAddr string            `json:"remote_addr"`
+	Headers    map[string]string `json:"headers,omitempty"` // the fingerprint headers it sent
+	Requests   int               `json:"requests"`
+	Errors     int               `json:"errors"`
+	FirstSeen  time.Time         `json:"first_seen"`
+	LastSeen   time.Time         `json:"last_seen"`
+	Paths      map[string]int    `json:"paths"`
+	// PathsDropped counts requests to paths beyond the first maxClientPaths
+	PathsDropped int             `json:"paths_dropped,omitempty"`
+	LastErrors   []ClientRequest `json:"last_errors,omitempty"`
+}
+
+// ClientFingerprints tells apart the tools talking to the host. Each request is
+// attributed to a fingerprint of its User-Agent, remote address (without port) and
+// the values of any configured extra headers, such as a test run ID.
+type ClientFingerprints struct {
+	mu      sync.Mutex
+	headers []string
+	clients map[string]*ClientStats
+}
+
+// NewClientFingerprints creates an empty set of client statistics. headers are added
+// to the fingerprint in addition to User-Agent and remote address.
+func NewClientFingerprints(headers []string) *ClientFingerprints {
+	canonical := make([]string, 0, len(headers))
+	for _, header := range headers {
+		if header = strings.TrimSpace(header); header != "" {
+			canonical = append(canonical, http.CanonicalHeaderKey(header))
+		}
+	}
+	return &ClientFingerprints{headers: canonical, clients: make(map[string]*ClientStats)}
+}
+
+// Record attributes a request and the status it was answered with to its client
+func (c *ClientFingerprints) Record(r *http.Request, status int) {
+	userAgent := r.Header.Get("User-Agent")
+	addr := remoteHost(r.RemoteAddr)
+	parts := []string{userAgent, addr}
+	var headers map[string]string
+	for _, name := range c.headers {
+		value := r.Header.Get(name)
+		parts = append(parts, value)
+		if value != "" {
+			if headers == nil {
+				headers = make(map[string]string)
+			}
+			headers[name] = value
+		}
+	}
+	id := clientID(parts...)
+	path := strings.TrimPrefix(r.URL.Path, "/v1/")
+	now := time.Now().UTC()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	client, ok := c.clients[id]
+	if !ok {
+		client = &ClientStats{
+			ID:         id,
+			UserAgent:  userAgent,
+			RemoteAddr: addr,
+			Headers:    headers,
+			FirstSeen:  now,
+			Paths:      make(map[string]int),
+		}
+		c.clients[id] = client
+	}
+	client.Requests++
+	client.LastSeen = now
+	if _, ok := client.Paths[path]; ok || len(client.Paths) < maxClientPaths {
+		client.Paths[path]++
+	} else {
+		client.PathsDropped++
+	}
+
+	if status >= http.StatusBadRequest {
+		client.Errors++
+		client.LastErrors = append(client.LastErrors, ClientRequest{Time: now, Method: r.Method, Path: path, Status: status})
+		if len(client.LastErrors) > maxClientErrors {
+			client.LastErrors = client.LastErrors[len(client.LastErrors)-maxClientErrors:]
+		}
+	}
+}
+
+// Clients returns a copy of the statistics of every client, most recently seen first
+func (c *ClientFingerprints) Clients() []ClientStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	clients := make([]ClientStats, 0, len(c.clients))
+	for _, client := range c.clients {
+		copied := *client
+		copied.Paths = make(map[string]int, len(client.Paths))
+		for path, count := range client.Paths {
+			copied.Paths[path] = count
+		}
+		copied.LastErrors = append([]ClientRequest(nil), client.LastErrors...)
+		clients = append(clients, copied)
+	}
+	sort.Slice(clients, func(i, j int) bool {
+		if !clients[i].LastSeen.Equal(clients[j].LastSeen) {
+			return clients[i].LastSeen.After(clients[j].LastSeen)
+		}
+		return clients[i].ID < clients[j].ID
+	})
+	return clients
+}
+
+// HandleClients serves the client statistics at /v1/sys/host/clients:
+//
+//	GET    - every client, most recently seen first; ?user_agent= keeps clients whose
+//	         User-Agent contains the value
+//	DELETE - forget all clients
+func (c *ClientFingerprints) HandleClients(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		clients := c.Clients()
+		if filter := r.URL.Query().Get("user_agent"); filter != "" {
+			matching := clients[:0]
+			for _, client := range clients {
+				if strings.Contains(client.UserAgent, filter) {
+					matching = append(matching, client)
+				}
+			}
+			clients = matching
+		}
+		WriteJSON(w, http.StatusOK, map[string]interface{}{
+			"fingerprint_headers": append([]string{"User-Agent", "Remote-Addr"}, c.headers...),
+			"clients":             clients,
+		})
+	case http.MethodDelete:
+		c.mu.Lock()
+		c.clients = make(map[string]*ClientStats)
+		c.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// SetClientFingerprints makes the handler attribute its requests to client fingerprints
+func (h *Handler) SetClientFingerprints(clients *ClientFingerprints) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.clients = clients
+}
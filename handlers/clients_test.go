@@ -0,0 +1,108 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestClientFingerprints(t *testing.T) {
+	backend := funcBackend(func(ctx context.Context, req *logical.Request) (*logical.Response, error) {
+		if req.Path == "broken" {
+			return nil, errors.New("boom")
+		}
+		return &logical.Response{Data: map[string]interface{}{"ok": true}}, nil
+	})
+	handler := NewHandler(backend, newMockStorage(), hclog.NewNullLogger(), "plugin")
+	clients := NewClientFingerprints([]string{"x-test-run", " "})
+	handler.SetClientFingerprints(clients)
+
+	send := func(path, userAgent, addr, run string) {
+		req := httptest.NewRequest("GET", "/v1/plugin/"+path, nil)
+		req.Header.Set("User-Agent", userAgent)
+		req.RemoteAddr = addr
+		if run != "" {
+			req.Header.Set("X-Test-Run", run)
+		}
+		handler.HandleRequest(httptest.NewRecorder(), req)
+	}
+	send("config", "terraform/1.9", "10.0.0.1:5000", "")
+	send("config", "terraform/1.9", "10.0.0.1:5001", "") // another port is the same client
+	send("broken", "terraform/1.9", "10.0.0.1:5002", "")
+	send("config", "curl/8.0", "10.0.0.1:6000", "")
+	send("config", "curl/8.0", "10.0.0.1:6000", "run-2")
+
+	byAgent := make(map[string][]ClientStats)
+	for _, client := range clients.Clients() {
+		byAgent[client.UserAgent] = append(byAgent[client.UserAgent], client)
+	}
+
+	terraform := byAgent["terraform/1.9"]
+	if len(terraform) != 1 {
+		t.Fatalf("terraform clients = %+v, want one", terraform)
+	}
+	tf := terraform[0]
+	if tf.Requests != 3 || tf.Errors != 1 || tf.RemoteAddr != "10.0.0.1" {
+		t.Errorf("terraform client = %+v", tf)
+	}
+	if tf.Paths["plugin/config"] != 2 || tf.Paths["plugin/broken"] != 1 {
+		t.Errorf("terraform paths = %v", tf.Paths)
+	}
+	if len(tf.LastErrors) != 1 || tf.LastErrors[0].Path != "plugin/broken" || tf.LastErrors[0].Status != http.StatusInternalServerError {
+		t.Errorf("terraform errors = %+v", tf.LastErrors)
+	}
+
+	// The extra header splits curl into two clients
+	curl := byAgent["curl/8.0"]
+	if len(curl) != 2 {
+		t.Fatalf("curl clients = %+v, want two", curl)
+	}
+	for _, client := range curl {
+		if client.Requests != 1 {
+			t.Errorf("curl client = %+v", client)
+		}
+		if client.Headers["X-Test-Run"] != "" && client.Headers["X-Test-Run"] != "run-2" {
+			t.Errorf("curl headers = %v", client.Headers)
+		}
+	}
+}
+
+func TestHandleClients(t *testing.T) {
+	clients := NewClientFingerprints(nil)
+	for _, agent := range []string{"vault-cli", "go-http-client/1.1"} {
+		req := httptest.NewRequest("GET", "/v1/plugin/config", nil)
+		req.Header.Set("User-Agent", agent)
+		clients.Record(req, http.StatusOK)
+	}
+
+	w := httptest.NewRecorder()
+	clients.HandleClients(w, httptest.NewRequest("GET", "/v1/sys/host/clients?user_agent=vault", nil))
+	var body struct {
+		Headers []string      `json:"fingerprint_headers"`
+		Clients []ClientStats `json:"clients"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(body.Clients) != 1 || body.Clients[0].UserAgent != "vault-cli" {
+		t.Errorf("filtered clients = %+v", body.Clients)
+	}
+	if len(body.Headers) != 2 {
+		t.Errorf("fingerprint headers = %v", body.Headers)
+	}
+
+	w = httptest.NewRecorder()
+	clients.HandleClients(w, httptest.NewRequest("DELETE", "/v1/sys/host/clients", nil))
+	if w.Code != http.StatusNoContent || len(clients.Clients()) != 0 {
+		t.Errorf("DELETE = %d, %d clients left", w.Code, len(clients.Clients()))
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// Clock skews the timestamps the host reports to plugins, such as the issue time
+// of leases and tokens sent with renewals and revocations, to simulate clock drift
+// between the Vault node that issued a secret and the one the plugin runs on
+type Clock struct {
+	mu   sync.RWMutex
+	skew time.Duration
+}
+
+// NewClock creates a clock with the given skew; negative values report times earlier
+func NewClock(skew time.Duration) *Clock {
+	return &Clock{skew: skew}
+}
+
+// Skew returns the skew; a nil Clock has none
+func (c *Clock) Skew() time.Duration {
+	if c == nil {
+		return 0
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.skew
+}
+
+// SetSkew changes the skew
+func (c *Clock) SetSkew(skew time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.skew = skew
+}
+
+// Reported returns t as the plugin is told it; the zero time stays zero
+func (c *Clock) Reported(t time.Time) time.Time {
+	if t.IsZero() {
+		return t
+	}
+	return t.Add(c.Skew())
+}
+
+// HandleConfig reads (GET) or changes (PUT/POST {"skew": "-5m"}) the skew at
+// /v1/sys/host/clock
+func (c *Clock) HandleConfig(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		WriteJSON(w, http.StatusOK, map[string]interface{}{
+			"skew":         c.Skew().String(),
+			"skew_seconds": c.Skew().Seconds(),
+		})
+	case http.MethodPut, http.MethodPost:
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			WriteError(w, http.StatusBadRequest, fmt.Sprintf("failed to read body: %v", err))
+			return
+		}
+		var data struct {
+			Skew string `json:"skew"`
+		}
+		if err := json.Unmarshal(body, &data); err != nil {
+			WriteError(w, http.StatusBadRequest, fmt.Sprintf("failed to parse JSON: %v", err))
+			return
+		}
+		skew, err := time.ParseDuration(data.Skew)
+		if err != nil {
+			WriteError(w, http.StatusBadRequest, fmt.Sprintf("invalid skew %q", data.Skew))
+			return
+		}
+		c.SetSkew(skew)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// SetClock makes the handler report timestamps to the plugin through clock
+func (h *Handler) SetClock(clock *Clock) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.clock = clock
+}
+
+// Clock returns the handler's clock, or nil when timestamps are not skewed
+func (h *Handler) Clock() *Clock {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.clock
+}
+
+// reportedSecret copies a lease's secret for a renew or revoke request. As in Vault,
+// IssueTime is set to the lease's issue time, here as the plugin is told it.
+func (c *Clock) reportedSecret(lease *LeaseInfo) *logical.Secret {
+	if lease.Secret == nil {
+		return nil
+	}
+	secret := *lease.Secret
+	secret.IssueTime = c.Reported(lease.IssueTime)
+	return &secret
+}
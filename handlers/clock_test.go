@@ -0,0 +1,88 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestClockSkewsRenewAndRevoke(t *testing.T) {
+	received := make(map[logical.Operation]*logical.Request)
+	backend := funcBackend(func(ctx context.Context, req *logical.Request) (*logical.Response, error) {
+		received[req.Operation] = req
+		if req.Operation == logical.ReadOperation {
+			return &logical.Response{
+				Data:   map[string]interface{}{"password": "x"},
+				Secret: &logical.Secret{LeaseOptions: logical.LeaseOptions{TTL: time.Hour, Renewable: true}},
+			}, nil
+		}
+		return nil, nil
+	})
+	handler := NewHandler(backend, newMockStorage(), hclog.NewNullLogger(), "plugin")
+	handler.SetClock(NewClock(-10 * time.Minute))
+
+	w := httptest.NewRecorder()
+	handler.HandleRequest(w, httptest.NewRequest("GET", "/v1/plugin/creds/test", nil))
+	var resp struct {
+		LeaseID string `json:"lease_id"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	lease, _ := handler.leases.Get(resp.LeaseID)
+
+	handler.HandleLeaseRenew(httptest.NewRecorder(), httptest.NewRequest("PUT", "/v1/sys/leases/renew", strings.NewReader(`{"lease_id": "`+resp.LeaseID+`", "increment": 60}`)))
+	handler.HandleLeaseRevoke(httptest.NewRecorder(), httptest.NewRequest("PUT", "/v1/sys/leases/revoke", strings.NewReader(`{"lease_id": "`+resp.LeaseID+`"}`)))
+
+	want := lease.IssueTime.Add(-10 * time.Minute)
+	for _, op := range []logical.Operation{logical.RenewOperation, logical.RevokeOperation} {
+		req := received[op]
+		if req == nil {
+			t.Fatalf("plugin did not receive a %s request", op)
+		}
+		if issued, _ := req.Data["issue_time"].(time.Time); !issued.Equal(want) {
+			t.Errorf("%s: issue_time = %v, want %v", op, req.Data["issue_time"], want)
+		}
+		if !req.Secret.IssueTime.Equal(want) {
+			t.Errorf("%s: secret issue time = %v, want %v", op, req.Secret.IssueTime, want)
+		}
+	}
+	if !lease.Secret.IssueTime.IsZero() {
+		t.Error("the stored secret should not be modified")
+	}
+}
+
+func TestClockHandleConfig(t *testing.T) {
+	clock := NewClock(0)
+
+	w := httptest.NewRecorder()
+	clock.HandleConfig(w, httptest.NewRequest("PUT", "/v1/sys/host/clock", strings.NewReader(`{"skew": "90s"}`)))
+	if w.Code != http.StatusNoContent || clock.Skew() != 90*time.Second {
+		t.Fatalf("setting the skew failed: %d, skew %v", w.Code, clock.Skew())
+	}
+
+	w = httptest.NewRecorder()
+	clock.HandleConfig(w, httptest.NewRequest("PUT", "/v1/sys/host/clock", strings.NewReader(`{"skew": "soon"}`)))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("invalid skew should be rejected, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	clock.HandleConfig(w, httptest.NewRequest("GET", "/v1/sys/host/clock", nil))
+	if !strings.Contains(w.Body.String(), `"skew":"1m30s"`) {
+		t.Errorf("unexpected config: %s", w.Body.String())
+	}
+
+	var nilClock *Clock
+	if now := time.Now(); !nilClock.Reported(now).Equal(now) {
+		t.Error("a nil clock should not skew times")
+	}
+}
@@ -0,0 +1,413 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ResponseEncoder encodes plugin responses in a particular media type
+type ResponseEncoder interface {
+	// ContentType is the media type written to the Content-Type header
+	ContentType() string
+	// Encode writes v to w
+	Encode(w io.Writer, v interface{}) error
+}
+
+var (
+	encodersMu sync.RWMutex
+	encoders   = map[string]ResponseEncoder{}
+)
+
+func init() {
+	RegisterEncoder(jsonEncoder{})
+	RegisterEncoder(msgpackEncoder{})
+	RegisterEncoder(cborEncoder{})
+}
+
+// RegisterEncoder makes an encoder available for Accept header negotiation
+func RegisterEncoder(enc ResponseEncoder) {
+	encodersMu.Lock()
+	defer encodersMu.Unlock()
+	encoders[enc.ContentType()] = enc
+}
+
+// negotiateEncoder picks the first supported media type from the Accept header, defaulting to JSON
+func negotiateEncoder(r *http.Request) ResponseEncoder {
+	encodersMu.RLock()
+	defer encodersMu.RUnlock()
+
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		if enc, ok := encoders[mediaType]; ok {
+			return enc
+		}
+	}
+	return encoders["application/json"]
+}
+
+// SetCanonicalJSON makes JSON responses canonical: compact, sorted keys, no HTML escaping
+// and stable number formatting, so recorded fixtures can be compared byte for byte
+func (h *Handler) SetCanonicalJSON(enabled bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.canonicalJSON = enabled
+}
+
+// writeEncoded writes v with the encoder negotiated for the request
+func (h *Handler) writeEncoded(w http.ResponseWriter, r *http.Request, statusCode int, v interface{}) {
+	enc := negotiateEncoder(r)
+
+	h.mu.RLock()
+	canonical := h.canonicalJSON
+	h.mu.RUnlock()
+	if canonical && enc.ContentType() == "application/json" {
+		enc = canonicalJSONEncoder{}
+	}
+
+	w.Header().Set("Content-Type", enc.ContentType())
+	w.Header().Add("Vary", "Accept")
+	w.WriteHeader(statusCode)
+
+	if err := enc.Encode(w, v); err != nil {
+		h.logger.Error("failed to encode response", "content_type", enc.ContentType(), "error", err)
+	}
+}
+
+// jsonEncoder is the default encoder
+type jsonEncoder struct{}
+
+func (jsonEncoder) ContentType() string { return "application/json" }
+
+func (jsonEncoder) Encode(w io.Writer, v interface{}) error {
+	return json.NewEncoder(w).Encode(v)
+}
+
+// canonicalJSONEncoder writes JSON with sorted keys, no insignificant whitespace,
+// no HTML escaping and numbers in their shortest stable form
+type canonicalJSONEncoder struct{}
+
+func (canonicalJSONEncoder) ContentType() string { return "application/json" }
+
+func (canonicalJSONEncoder) Encode(w io.Writer, v interface{}) error {
+	value, err := normalize(v)
+	if err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+	if err := writeCanonicalJSON(bw, value); err != nil {
+		return err
+	}
+	bw.WriteByte('\n')
+	return bw.Flush()
+}
+
+// canonicalNumber formats a number so equal values always produce the same text
+func canonicalNumber(n json.Number) (string, error) {
+	if i, err := n.Int64(); err == nil {
+		return strconv.FormatInt(i, 10), nil
+	}
+	f, err := n.Float64()
+	if err != nil {
+		return "", err
+	}
+	if f == math.Trunc(f) && math.Abs(f) < 1e21 {
+		return strconv.FormatFloat(f, 'f', -1, 64), nil
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64), nil
+}
+
+func writeCanonicalJSON(w *bufio.Writer, v interface{}) error {
+	switch val := v.(type) {
+	case nil:
+		_, err := w.WriteString("null")
+		return err
+	case bool:
+		_, err := w.WriteString(strconv.FormatBool(val))
+		return err
+	case json.Number:
+		s, err := canonicalNumber(val)
+		if err != nil {
+			return err
+		}
+		_, err = w.WriteString(s)
+		return err
+	case string:
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(val); err != nil {
+			return err
+		}
+		_, err := w.Write(bytes.TrimSuffix(buf.Bytes(), []byte("\n")))
+		return err
+	case []interface{}:
+		w.WriteByte('[')
+		for i, item := range val {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			if err := writeCanonicalJSON(w, item); err != nil {
+				return err
+			}
+		}
+		return w.WriteByte(']')
+	case map[string]interface{}:
+		w.WriteByte('{')
+		for i, key := range sortedKeys(val) {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			if err := writeCanonicalJSON(w, key); err != nil {
+				return err
+			}
+			w.WriteByte(':')
+			if err := writeCanonicalJSON(w, val[key]); err != nil {
+				return err
+			}
+		}
+		return w.WriteByte('}')
+	default:
+		return fmt.Errorf("canonical json: unsupported type %T", v)
+	}
+}
+
+// normalize converts v into plain JSON values (maps, slices, strings, json.Number, bools, nil)
+// so binary encoders only need to handle a small set of types
+func normalize(v interface{}) (interface{}, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+
+	var out interface{}
+	if err := decoder.Decode(&out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// sortedKeys returns the keys of m in sorted order for deterministic output
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// msgpackEncoder encodes responses as MessagePack
+type msgpackEncoder struct{}
+
+func (msgpackEncoder) ContentType() string { return "application/msgpack" }
+
+func (msgpackEncoder) Encode(w io.Writer, v interface{}) error {
+	value, err := normalize(v)
+	if err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+	if err := writeMsgpack(bw, value); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+func writeMsgpack(w *bufio.Writer, v interface{}) error {
+	switch val := v.(type) {
+	case nil:
+		return w.WriteByte(0xc0)
+	case bool:
+		if val {
+			return w.WriteByte(0xc3)
+		}
+		return w.WriteByte(0xc2)
+	case json.Number:
+		if i, err := val.Int64(); err == nil {
+			switch {
+			case i >= 0 && i <= 127:
+				return w.WriteByte(byte(i))
+			case i < 0 && i >= -32:
+				return w.WriteByte(byte(int8(i)))
+			default:
+				w.WriteByte(0xd3)
+				return binary.Write(w, binary.BigEndian, i)
+			}
+		}
+		f, err := val.Float64()
+		if err != nil {
+			return err
+		}
+		w.WriteByte(0xcb)
+		return binary.Write(w, binary.BigEndian, math.Float64bits(f))
+	case string:
+		n := len(val)
+		switch {
+		case n < 32:
+			w.WriteByte(0xa0 | byte(n))
+		case n <= math.MaxUint8:
+			w.Write([]byte{0xd9, byte(n)})
+		case n <= math.MaxUint16:
+			w.WriteByte(0xda)
+			binary.Write(w, binary.BigEndian, uint16(n))
+		default:
+			w.WriteByte(0xdb)
+			binary.Write(w, binary.BigEndian, uint32(n))
+		}
+		_, err := w.WriteString(val)
+		return err
+	case []interface{}:
+		n := len(val)
+		switch {
+		case n < 16:
+			w.WriteByte(0x90 | byte(n))
+		case n <= math.MaxUint16:
+			w.WriteByte(0xdc)
+			binary.Write(w, binary.BigEndian, uint16(n))
+		default:
+			w.WriteByte(0xdd)
+			binary.Write(w, binary.BigEndian, uint32(n))
+		}
+		for _, item := range val {
+			if err := writeMsgpack(w, item); err != nil {
+				return err
+			}
+		}
+		return nil
+	case map[string]interface{}:
+		n := len(val)
+		switch {
+		case n < 16:
+			w.WriteByte(0x80 | byte(n))
+		case n <= math.MaxUint16:
+			w.WriteByte(0xde)
+			binary.Write(w, binary.BigEndian, uint16(n))
+		default:
+			w.WriteByte(0xdf)
+			binary.Write(w, binary.BigEndian, uint32(n))
+		}
+		for _, key := range sortedKeys(val) {
+			if err := writeMsgpack(w, key); err != nil {
+				return err
+			}
+			if err := writeMsgpack(w, val[key]); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("msgpack: unsupported type %T", v)
+	}
+}
+
+// cborEncoder encodes responses as CBOR (RFC 8949)
+type cborEncoder struct{}
+
+func (cborEncoder) ContentType() string { return "application/cbor" }
+
+func (cborEncoder) Encode(w io.Writer, v interface{}) error {
+	value, err := normalize(v)
+	if err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+	if err := writeCBOR(bw, value); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// writeCBORHead writes a CBOR major type with its argument
+func writeCBORHead(w *bufio.Writer, major byte, n uint64) {
+	major <<= 5
+	switch {
+	case n < 24:
+		w.WriteByte(major | byte(n))
+	case n <= math.MaxUint8:
+		w.Write([]byte{major | 24, byte(n)})
+	case n <= math.MaxUint16:
+		w.WriteByte(major | 25)
+		binary.Write(w, binary.BigEndian, uint16(n))
+	case n <= math.MaxUint32:
+		w.WriteByte(major | 26)
+		binary.Write(w, binary.BigEndian, uint32(n))
+	default:
+		w.WriteByte(major | 27)
+		binary.Write(w, binary.BigEndian, n)
+	}
+}
+
+func writeCBOR(w *bufio.Writer, v interface{}) error {
+	switch val := v.(type) {
+	case nil:
+		return w.WriteByte(0xf6)
+	case bool:
+		if val {
+			return w.WriteByte(0xf5)
+		}
+		return w.WriteByte(0xf4)
+	case json.Number:
+		if i, err := val.Int64(); err == nil {
+			if i >= 0 {
+				writeCBORHead(w, 0, uint64(i))
+			} else {
+				writeCBORHead(w, 1, uint64(-1-i))
+			}
+			return nil
+		}
+		f, err := val.Float64()
+		if err != nil {
+			return err
+		}
+		w.WriteByte(0xfb)
+		return binary.Write(w, binary.BigEndian, math.Float64bits(f))
+	case string:
+		writeCBORHead(w, 3, uint64(len(val)))
+		_, err := w.WriteString(val)
+		return err
+	case []interface{}:
+		writeCBORHead(w, 4, uint64(len(val)))
+		for _, item := range val {
+			if err := writeCBOR(w, item); err != nil {
+				return err
+			}
+		}
+		return nil
+	case map[string]interface{}:
+		writeCBORHead(w, 5, uint64(len(val)))
+		for _, key := range sortedKeys(val) {
+			if err := writeCBOR(w, key); err != nil {
+				return err
+			}
+			if err := writeCBOR(w, val[key]); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("cbor: unsupported type %T", v)
+	}
+}
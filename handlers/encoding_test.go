@@ -0,0 +1,128 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+func TestNegotiateEncoder(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{"", "application/json"},
+		{"*/*", "application/json"},
+		{"application/msgpack", "application/msgpack"},
+		{"text/html, application/cbor;q=0.9", "application/cbor"},
+		{"application/x-unknown", "application/json"},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/v1/plugin/test", nil)
+		req.Header.Set("Accept", tt.accept)
+		if got := negotiateEncoder(req).ContentType(); got != tt.want {
+			t.Errorf("Accept %q negotiated %s, want %s", tt.accept, got, tt.want)
+		}
+	}
+}
+
+func TestMsgpackEncoder(t *testing.T) {
+	var buf bytes.Buffer
+	value := map[string]interface{}{
+		"a": 1,
+		"b": []interface{}{true, nil, "x"},
+		"c": -100,
+		"d": 1.5,
+	}
+	if err := (msgpackEncoder{}).Encode(&buf, value); err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+
+	want := []byte{
+		0x84,
+		0xa1, 'a', 0x01,
+		0xa1, 'b', 0x93, 0xc3, 0xc0, 0xa1, 'x',
+		0xa1, 'c', 0xd3, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x9c,
+		0xa1, 'd', 0xcb, 0x3f, 0xf8, 0, 0, 0, 0, 0, 0,
+	}
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Errorf("msgpack = % x\nwant      % x", buf.Bytes(), want)
+	}
+}
+
+func TestCBOREncoder(t *testing.T) {
+	var buf bytes.Buffer
+	value := map[string]interface{}{
+		"a": 500,
+		"b": []interface{}{false, nil},
+		"c": -1,
+	}
+	if err := (cborEncoder{}).Encode(&buf, value); err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+
+	want := []byte{
+		0xa3,
+		0x61, 'a', 0x19, 0x01, 0xf4,
+		0x61, 'b', 0x82, 0xf4, 0xf6,
+		0x61, 'c', 0x20,
+	}
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Errorf("cbor = % x\nwant   % x", buf.Bytes(), want)
+	}
+}
+
+func TestCanonicalJSONEncoder(t *testing.T) {
+	var buf bytes.Buffer
+	value := map[string]interface{}{
+		"z":     "<a&b>",
+		"a":     []interface{}{1.0, 2.5, 1e21, int64(-3)},
+		"m":     map[string]interface{}{"y": true, "x": nil},
+		"float": 100.0,
+	}
+	if err := (canonicalJSONEncoder{}).Encode(&buf, value); err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+
+	want := `{"a":[1,2.5,1e+21,-3],"float":100,"m":{"x":null,"y":true},"z":"<a&b>"}` + "\n"
+	if buf.String() != want {
+		t.Errorf("canonical = %s\nwant        %s", buf.String(), want)
+	}
+}
+
+func TestHandleRequestNegotiatesEncoding(t *testing.T) {
+	handler := NewHandler(&mockBackend{}, newMockStorage(), hclog.NewNullLogger(), "plugin")
+
+	req := httptest.NewRequest("GET", "/v1/plugin/test", nil)
+	req.Header.Set("Accept", "application/msgpack")
+	w := httptest.NewRecorder()
+	handler.HandleRequest(w, req)
+
+	if ct := w.Header().Get("Content-Type"); ct != "application/msgpack" {
+		t.Errorf("Content-Type = %s, want application/msgpack", ct)
+	}
+	if w.Body.Len() == 0 || w.Body.Bytes()[0]&0xf0 != 0x80 {
+		t.Errorf("body does not start with a msgpack fixmap: % x", w.Body.Bytes())
+	}
+}
+
+func TestHandleRequestCanonicalJSON(t *testing.T) {
+	handler := NewHandler(&mockBackend{}, newMockStorage(), hclog.NewNullLogger(), "plugin")
+	handler.SetCanonicalJSON(true)
+
+	req := httptest.NewRequest("GET", "/v1/plugin/test", nil)
+	w := httptest.NewRecorder()
+	handler.HandleRequest(w, req)
+
+	body := w.Body.String()
+	if strings.Contains(body, " ") || !strings.HasPrefix(body, `{"auth":null,"data":{`) {
+		t.Errorf("response is not canonical JSON: %s", body)
+	}
+}
@@ -0,0 +1,136 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// encryptedExportMagic starts every encrypted export. It is followed by the scrypt
+// salt, the AES-GCM nonce and the sealed content.
+const encryptedExportMagic = "vault-plugin-host encrypted v1\n"
+
+const (
+	exportSaltSize = 16
+	// scrypt parameters recommended for interactive use; deriving a key takes well under
+	// a second, which is paid once per export
+	exportScryptN = 1 << 15
+	exportScryptR = 8
+	exportScryptP = 1
+)
+
+// errExportPassphrase is returned when an encrypted export cannot be opened
+var errExportPassphrase = errors.New("failed to decrypt: wrong passphrase or corrupted file")
+
+// exportKey derives the AES-256 key of an export from the passphrase
+func exportKey(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(passphrase), salt, exportScryptN, exportScryptR, exportScryptP, 32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// EncryptExport encrypts a snapshot or artifact with a passphrase, so fixtures that
+// contain generated credentials can be archived safely. The key is derived with
+// scrypt and the content sealed with AES-256-GCM.
+func EncryptExport(data []byte, passphrase string) ([]byte, error) {
+	if passphrase == "" {
+		return nil, fmt.Errorf("passphrase is required")
+	}
+	salt := make([]byte, exportSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	aead, err := exportKey(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, 0, len(encryptedExportMagic)+len(salt)+len(nonce)+len(data)+aead.Overhead())
+	out = append(out, encryptedExportMagic...)
+	out = append(out, salt...)
+	out = append(out, nonce...)
+	return aead.Seal(out, nonce, data, []byte(encryptedExportMagic)), nil
+}
+
+// IsEncryptedExport reports whether data was written by EncryptExport
+func IsEncryptedExport(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(encryptedExportMagic))
+}
+
+// DecryptExport opens data written by EncryptExport
+func DecryptExport(data []byte, passphrase string) ([]byte, error) {
+	if !IsEncryptedExport(data) {
+		return nil, fmt.Errorf("not an encrypted export")
+	}
+	if passphrase == "" {
+		return nil, fmt.Errorf("the file is encrypted and no passphrase is configured")
+	}
+	data = data[len(encryptedExportMagic):]
+	if len(data) < exportSaltSize {
+		return nil, errExportPassphrase
+	}
+	aead, err := exportKey(passphrase, data[:exportSaltSize])
+	if err != nil {
+		return nil, err
+	}
+	data = data[exportSaltSize:]
+	if len(data) < aead.NonceSize() {
+		return nil, errExportPassphrase
+	}
+	plaintext, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], []byte(encryptedExportMagic))
+	if err != nil {
+		return nil, errExportPassphrase
+	}
+	return plaintext, nil
+}
+
+// OpenExport reads r, decrypting it when it is an encrypted export; plain content
+// is returned as is
+func OpenExport(r io.Reader, passphrase string) (io.Reader, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if !IsEncryptedExport(data) {
+		return bytes.NewReader(data), nil
+	}
+	plaintext, err := DecryptExport(data, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(plaintext), nil
+}
+
+// SetExportPassphrase makes storage snapshots download encrypted with passphrase and
+// lets restores read snapshots encrypted with it; "" exports plain JSON
+func (h *Handler) SetExportPassphrase(passphrase string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.exportPassphrase = passphrase
+}
+
+// exportPassphraseValue returns the passphrase set by SetExportPassphrase
+func (h *Handler) exportPassphraseValue() string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.exportPassphrase
+}
@@ -0,0 +1,277 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// LoggedEvent is a plugin event as kept by an EventLog: its sequence number on the
+// bus, the mount that sent it, its metadata and the CloudEvent subscribers received
+type LoggedEvent struct {
+	Sequence uint64            `json:"sequence"`
+	Type     string            `json:"type"`
+	Time     time.Time         `json:"time"`
+	Mount    string            `json:"mount"`
+	Plugin   string            `json:"plugin"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+	Event    json.RawMessage   `json:"event"`
+}
+
+// EventQuery selects logged events. Every field that is set must match: Type may
+// contain "*" wildcards, From and To bound the time (inclusive), After skips the
+// events up to that sequence number, and each Metadata entry must equal the event's
+// metadata value.
+type EventQuery struct {
+	Type     string            `json:"type,omitempty"`
+	Mount    string            `json:"mount,omitempty"`
+	From     time.Time         `json:"from,omitempty"`
+	To       time.Time         `json:"to,omitempty"`
+	After    uint64            `json:"after,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// Matches reports whether a logged event satisfies the query
+func (q EventQuery) Matches(event LoggedEvent) bool {
+	if q.Type != "" && !eventTypeMatches(q.Type, event.Type) {
+		return false
+	}
+	if q.Mount != "" && strings.Trim(q.Mount, "/") != strings.Trim(event.Mount, "/") {
+		return false
+	}
+	if !q.From.IsZero() && event.Time.Before(q.From) {
+		return false
+	}
+	if !q.To.IsZero() && event.Time.After(q.To) {
+		return false
+	}
+	if event.Sequence <= q.After {
+		return false
+	}
+	for key, value := range q.Metadata {
+		if actual, ok := event.Metadata[key]; !ok || actual != value {
+			return false
+		}
+	}
+	return true
+}
+
+// EventLog keeps every event published on an EventBus in storage, one entry per event
+// keyed by its zero-padded sequence number, so scenarios can check after the fact
+// which events a plugin sent during a flow. Unlike the bus's replay buffer it is not
+// trimmed; it is emptied with Clear.
+type EventLog struct {
+	storage logical.Storage
+}
+
+// NewEventLog creates an event log kept in storage
+func NewEventLog(storage logical.Storage) *EventLog {
+	return &EventLog{storage: storage}
+}
+
+// eventLogKey is the storage key of the event with sequence number seq; keys sort in
+// sequence order
+func eventLogKey(seq uint64) string {
+	return fmt.Sprintf("%020d", seq)
+}
+
+// append stores an event
+func (l *EventLog) append(ctx context.Context, event LoggedEvent) error {
+	entry, err := logical.StorageEntryJSON(eventLogKey(event.Sequence), event)
+	if err != nil {
+		return err
+	}
+	return l.storage.Put(ctx, entry)
+}
+
+// keys returns the storage keys of the logged events in sequence order
+func (l *EventLog) keys(ctx context.Context) ([]string, error) {
+	keys, err := l.storage.List(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// last returns the highest sequence number in the log, or zero when it is empty
+func (l *EventLog) last(ctx context.Context) (uint64, error) {
+	keys, err := l.keys(ctx)
+	if err != nil || len(keys) == 0 {
+		return 0, err
+	}
+	return strconv.ParseUint(keys[len(keys)-1], 10, 64)
+}
+
+// Query returns the logged events matching q in sequence order
+func (l *EventLog) Query(ctx context.Context, q EventQuery) ([]LoggedEvent, error) {
+	keys, err := l.keys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	events := []LoggedEvent{}
+	for _, key := range keys {
+		if seq, err := strconv.ParseUint(key, 10, 64); err == nil && seq <= q.After {
+			continue
+		}
+		entry, err := l.storage.Get(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		if entry == nil {
+			continue
+		}
+		var event LoggedEvent
+		if err := entry.DecodeJSON(&event); err != nil {
+			return nil, fmt.Errorf("failed to decode event %s: %w", key, err)
+		}
+		if q.Matches(event) {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+// Clear removes every logged event and returns how many there were
+func (l *EventLog) Clear(ctx context.Context) (int, error) {
+	keys, err := l.keys(ctx)
+	if err != nil {
+		return 0, err
+	}
+	for _, key := range keys {
+		if err := l.storage.Delete(ctx, key); err != nil {
+			return 0, err
+		}
+	}
+	return len(keys), nil
+}
+
+// ParseEventQuery reads a query from URL parameters: type, mount, from and to
+// (RFC 3339), after (a sequence number) and metadata.<key>=<value>
+func ParseEventQuery(r *http.Request) (EventQuery, error) {
+	params := r.URL.Query()
+	q := EventQuery{Type: params.Get("type"), Mount: params.Get("mount")}
+	for name, bound := range map[string]*time.Time{"from": &q.From, "to": &q.To} {
+		if value := params.Get(name); value != "" {
+			t, err := time.Parse(time.RFC3339Nano, value)
+			if err != nil {
+				return q, fmt.Errorf("invalid %s time %q (expected RFC 3339)", name, value)
+			}
+			*bound = t
+		}
+	}
+	if value := params.Get("after"); value != "" {
+		after, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return q, fmt.Errorf("invalid event sequence number %q", value)
+		}
+		q.After = after
+	}
+	for name, values := range params {
+		if key, ok := strings.CutPrefix(name, "metadata."); ok && key != "" {
+			if q.Metadata == nil {
+				q.Metadata = make(map[string]string)
+			}
+			q.Metadata[key] = values[0]
+		}
+	}
+	return q, nil
+}
+
+// loggedEvent builds the log entry of an event published with sequence number seq
+func loggedEvent(seq uint64, at time.Time, received *logical.EventReceived, cloudEvent []byte) LoggedEvent {
+	event := LoggedEvent{
+		Sequence: seq,
+		Type:     received.EventType,
+		Time:     at,
+		Event:    cloudEvent,
+	}
+	if info := received.PluginInfo; info != nil {
+		event.Mount = info.MountPath
+		event.Plugin = info.Plugin
+	}
+	if metadata := received.Event.GetMetadata(); metadata != nil {
+		event.Metadata = make(map[string]string, len(metadata.Fields))
+		for key, value := range metadata.Fields {
+			if s, ok := value.AsInterface().(string); ok {
+				event.Metadata[key] = s
+				continue
+			}
+			raw, _ := json.Marshal(value.AsInterface())
+			event.Metadata[key] = string(raw)
+		}
+	}
+	return event
+}
+
+// SetEventLog makes the bus keep every event it publishes in eventLog. Sequence numbers
+// continue after the last logged event, so a log in file storage stays in order
+// across restarts.
+func (b *EventBus) SetEventLog(eventLog *EventLog) error {
+	last, err := eventLog.last(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to read event log: %w", err)
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.log = eventLog
+	if last > b.seq {
+		b.seq = last
+	}
+	return nil
+}
+
+// EventLog returns the bus's event log, or nil when events are not persisted
+func (b *EventBus) EventLog() *EventLog {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.log
+}
+
+// HandleLog serves /v1/sys/host/events/log. GET returns the logged events matching
+// the query parameters read by ParseEventQuery; DELETE empties the log, for instance
+// between scenarios.
+func (b *EventBus) HandleLog(w http.ResponseWriter, r *http.Request) {
+	eventLog := b.EventLog()
+	if eventLog == nil {
+		WriteError(w, http.StatusNotFound, "event persistence is not enabled")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		q, err := ParseEventQuery(r)
+		if err != nil {
+			WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		events, err := eventLog.Query(r.Context(), q)
+		if err != nil {
+			WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		WriteJSON(w, http.StatusOK, map[string]interface{}{
+			"events": events,
+			"count":  len(events),
+		})
+	case http.MethodDelete:
+		removed, err := eventLog.Clear(r.Context())
+		if err != nil {
+			WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		WriteJSON(w, http.StatusOK, map[string]interface{}{"removed": removed})
+	default:
+		WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
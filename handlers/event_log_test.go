@@ -0,0 +1,145 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// sendLoggedEvents publishes a kv write and delete on secret/ and a database rotation
+func sendLoggedEvents(t *testing.T, bus *EventBus) {
+	t.Helper()
+	ctx := context.Background()
+	kv := bus.Sender("secret", "kv")
+	if err := logical.SendEvent(ctx, kv, "kv-v2/data-write", logical.EventMetadataPath, "data/app"); err != nil {
+		t.Fatalf("SendEvent failed: %v", err)
+	}
+	if err := logical.SendEvent(ctx, kv, "kv-v2/data-delete", logical.EventMetadataPath, "data/app"); err != nil {
+		t.Fatalf("SendEvent failed: %v", err)
+	}
+	if err := logical.SendEvent(ctx, bus.Sender("database", "db"), "database/rotate", "name", "root"); err != nil {
+		t.Fatalf("SendEvent failed: %v", err)
+	}
+}
+
+func TestEventLogQuery(t *testing.T) {
+	bus := NewEventBus()
+	eventLog := NewEventLog(&logical.InmemStorage{})
+	if err := bus.SetEventLog(eventLog); err != nil {
+		t.Fatalf("SetEventLog failed: %v", err)
+	}
+	start := time.Now().UTC()
+	sendLoggedEvents(t, bus)
+
+	ctx := context.Background()
+	cases := []struct {
+		name  string
+		query EventQuery
+		want  []uint64
+	}{
+		{"all", EventQuery{}, []uint64{1, 2, 3}},
+		{"type", EventQuery{Type: "kv-v2/*"}, []uint64{1, 2}},
+		{"mount", EventQuery{Mount: "/database"}, []uint64{3}},
+		{"metadata", EventQuery{Metadata: map[string]string{"path": "secret/data/app"}}, []uint64{1, 2}},
+		{"metadata mismatch", EventQuery{Metadata: map[string]string{"name": "admin"}}, nil},
+		{"after", EventQuery{After: 2}, []uint64{3}},
+		{"from", EventQuery{From: start.Add(-time.Minute)}, []uint64{1, 2, 3}},
+		{"to", EventQuery{To: start.Add(-time.Minute)}, nil},
+	}
+	for _, c := range cases {
+		events, err := eventLog.Query(ctx, c.query)
+		if err != nil {
+			t.Fatalf("%s: Query failed: %v", c.name, err)
+		}
+		var got []uint64
+		for _, event := range events {
+			got = append(got, event.Sequence)
+		}
+		if len(got) != len(c.want) {
+			t.Errorf("%s: got events %v, want %v", c.name, got, c.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != c.want[i] {
+				t.Errorf("%s: got events %v, want %v", c.name, got, c.want)
+				break
+			}
+		}
+	}
+
+	events, _ := eventLog.Query(ctx, EventQuery{Type: "database/rotate"})
+	var event cloudEvent
+	if err := json.Unmarshal(events[0].Event, &event); err != nil {
+		t.Fatalf("invalid logged CloudEvent: %v", err)
+	}
+	if events[0].Mount != "database/" || events[0].Plugin != "db" || event.Sequence != 3 || event.Data.Event.Metadata["name"] != "root" {
+		t.Errorf("logged event = %+v, CloudEvent %+v", events[0], event)
+	}
+
+	// A bus opening an existing log continues its sequence
+	restarted := NewEventBus()
+	if err := restarted.SetEventLog(eventLog); err != nil {
+		t.Fatalf("SetEventLog failed: %v", err)
+	}
+	if err := logical.SendEvent(ctx, restarted.Sender("secret", "kv"), "kv-v2/data-write"); err != nil {
+		t.Fatalf("SendEvent failed: %v", err)
+	}
+	if events, _ := eventLog.Query(ctx, EventQuery{After: 3}); len(events) != 1 || events[0].Sequence != 4 {
+		t.Errorf("events after a restart = %+v, want sequence 4", events)
+	}
+}
+
+func TestEventBusHandleLog(t *testing.T) {
+	bus := NewEventBus()
+	w := httptest.NewRecorder()
+	bus.HandleLog(w, httptest.NewRequest("GET", "/v1/sys/host/events/log", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("without a log = %d, want 404", w.Code)
+	}
+
+	if err := bus.SetEventLog(NewEventLog(&logical.InmemStorage{})); err != nil {
+		t.Fatalf("SetEventLog failed: %v", err)
+	}
+	sendLoggedEvents(t, bus)
+
+	var body struct {
+		Events []LoggedEvent `json:"events"`
+		Count  int           `json:"count"`
+	}
+	w = httptest.NewRecorder()
+	bus.HandleLog(w, httptest.NewRequest("GET", "/v1/sys/host/events/log?type=kv*&metadata.path=secret/data/app&after=1", nil))
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("invalid response %s: %v", w.Body.String(), err)
+	}
+	if body.Count != 1 || body.Events[0].Type != "kv-v2/data-delete" {
+		t.Errorf("queried events = %+v", body)
+	}
+
+	for _, query := range []string{"from=yesterday", "after=-1"} {
+		w = httptest.NewRecorder()
+		bus.HandleLog(w, httptest.NewRequest("GET", "/v1/sys/host/events/log?"+query, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s = %d, want 400", query, w.Code)
+		}
+	}
+
+	w = httptest.NewRecorder()
+	bus.HandleLog(w, httptest.NewRequest("DELETE", "/v1/sys/host/events/log", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "{\"removed\":3}\n" {
+		t.Errorf("clear = %d %s", w.Code, w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	bus.HandleLog(w, httptest.NewRequest("GET", "/v1/sys/host/events/log", nil))
+	json.Unmarshal(w.Body.Bytes(), &body)
+	if body.Count != 0 {
+		t.Errorf("got %d events after clearing, want 0", body.Count)
+	}
+}
@@ -0,0 +1,414 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"golang.org/x/net/websocket"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// defaultEventReplay is the number of recent events kept for subscribers that ask for
+// events they missed
+const defaultEventReplay = 1024
+
+// EventSequenceHeader names the sequence number a subscriber resumes after, as
+// server-sent event clients send it when they reconnect
+const EventSequenceHeader = "Last-Event-ID"
+
+// bufferedEvent is an event kept for replay, with its sequence number
+type bufferedEvent struct {
+	seq       uint64
+	eventType string
+	message   []byte
+}
+
+// eventSubscriber is a client watching events whose type matches pattern. It reads
+// events from the bus's replay buffer, starting at sequence number next, and is woken
+// whenever an event is published.
+type eventSubscriber struct {
+	pattern string
+	next    uint64
+	wake    chan struct{}
+}
+
+// EventBus carries the events plugins send through their EventsSender to clients of
+// /v1/sys/events/subscribe, as Vault's event bus does. Events are delivered in the
+// CloudEvents JSON format of Vault's subscribe endpoint, numbered with a sequence. The
+// most recent events are kept, so subscribers can ask for events sent before they
+// connected, and a subscriber that falls behind catches up from the buffer instead of
+// blocking the plugin.
+type EventBus struct {
+	source string
+
+	mu          sync.Mutex
+	subscribers map[*eventSubscriber]bool
+	replay      []bufferedEvent // oldest first
+	replayLimit int
+	seq         uint64    // sequence number of the last event
+	log         *EventLog // nil unless events are persisted
+}
+
+// NewEventBus creates an event bus without subscribers
+func NewEventBus() *EventBus {
+	hostname, _ := os.Hostname()
+	return &EventBus{
+		source:      "vault://" + hostname,
+		subscribers: make(map[*eventSubscriber]bool),
+		replayLimit: defaultEventReplay,
+	}
+}
+
+// SetReplayLimit sets how many recent events are kept for replay; at least one is
+func (b *EventBus) SetReplayLimit(limit int) {
+	if limit < 1 {
+		limit = 1
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.replayLimit = limit
+	b.trimReplay()
+}
+
+// trimReplay drops the oldest events beyond the replay limit. The caller must hold b.mu.
+func (b *EventBus) trimReplay() {
+	if excess := len(b.replay) - b.replayLimit; excess > 0 {
+		b.replay = append([]bufferedEvent(nil), b.replay[excess:]...)
+	}
+}
+
+// eventTypeMatches matches an event type against a subscription pattern, in which
+// "*" stands for any characters (including "/"), as in Vault
+func eventTypeMatches(pattern, eventType string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == eventType
+	}
+	if !strings.HasPrefix(eventType, parts[0]) {
+		return false
+	}
+	rest := eventType[len(parts[0]):]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(rest, part)
+		if i < 0 {
+			return false
+		}
+		rest = rest[i+len(part):]
+	}
+	return strings.HasSuffix(rest, parts[len(parts)-1])
+}
+
+// Publish numbers an event, keeps it for replay and wakes the subscribers. Subscribers
+// that have fallen behind read it from the replay buffer later rather than block the
+// plugin. With an event log set, the event is also persisted.
+func (b *EventBus) Publish(received *logical.EventReceived) error {
+	data, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(received)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	now := time.Now().UTC()
+	b.mu.Lock()
+	cloudEvent, err := json.Marshal(map[string]interface{}{
+		"id":              received.Event.GetId(),
+		"source":          b.source,
+		"specversion":     "1.0",
+		"type":            received.EventType,
+		"data":            json.RawMessage(data),
+		"datacontenttype": "application/cloudevents",
+		"time":            now.Format(time.RFC3339Nano),
+		"sequence":        b.seq + 1,
+	})
+	if err != nil {
+		b.mu.Unlock()
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+	b.seq++
+	seq, eventLog := b.seq, b.log
+	b.replay = append(b.replay, bufferedEvent{seq: seq, eventType: received.EventType, message: cloudEvent})
+	b.trimReplay()
+
+	for subscriber := range b.subscribers {
+		select {
+		case subscriber.wake <- struct{}{}:
+		default: // already woken
+		}
+	}
+	b.mu.Unlock()
+
+	if eventLog != nil {
+		if err := eventLog.append(context.Background(), loggedEvent(seq, now, received, cloudEvent)); err != nil {
+			return fmt.Errorf("failed to persist event: %w", err)
+		}
+	}
+	return nil
+}
+
+// subscribe registers a subscriber that starts after sequence number since, or with
+// the next event when since is negative; the returned function removes it again
+func (b *EventBus) subscribe(pattern string, since int64) (*eventSubscriber, func()) {
+	subscriber := &eventSubscriber{pattern: pattern, wake: make(chan struct{}, 1)}
+	b.mu.Lock()
+	subscriber.next = b.seq + 1
+	if since >= 0 {
+		subscriber.next = uint64(since) + 1
+	}
+	b.subscribers[subscriber] = true
+	b.mu.Unlock()
+	return subscriber, func() {
+		b.mu.Lock()
+		delete(b.subscribers, subscriber)
+		b.mu.Unlock()
+	}
+}
+
+// pending returns the events a subscriber has not seen yet that match its pattern,
+// and how many it missed because they were dropped from the replay buffer first
+func (b *EventBus) pending(subscriber *eventSubscriber) ([]bufferedEvent, uint64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	events, missed, next := b.since(subscriber.pattern, subscriber.next)
+	subscriber.next = next
+	return events, missed
+}
+
+// since returns the buffered events from sequence number next on that match pattern,
+// the number of events from next on no longer buffered, and the sequence number after
+// the last event. The caller must hold b.mu.
+func (b *EventBus) since(pattern string, next uint64) ([]bufferedEvent, uint64, uint64) {
+	var events []bufferedEvent
+	var missed uint64
+	if len(b.replay) > 0 && b.replay[0].seq > next {
+		missed = b.replay[0].seq - next
+	}
+	for _, event := range b.replay {
+		if event.seq >= next && eventTypeMatches(pattern, event.eventType) {
+			events = append(events, event)
+		}
+	}
+	return events, missed, b.seq + 1
+}
+
+// Sender returns the EventsSender handed to the plugin mounted at mountPath
+func (b *EventBus) Sender(mountPath, plugin string) logical.EventSender {
+	mount := strings.Trim(mountPath, "/") + "/"
+	class := "secret"
+	if strings.HasPrefix(mount, "auth/") {
+		class = "auth"
+	}
+	return &mountEventSender{
+		bus: b,
+		info: &logical.EventPluginInfo{
+			MountClass:    class,
+			MountAccessor: class + "_" + clientID("mount", mount)[:8],
+			MountPath:     mount,
+			Plugin:        plugin,
+		},
+	}
+}
+
+// mountEventSender publishes the events of one mount
+type mountEventSender struct {
+	bus  *EventBus
+	info *logical.EventPluginInfo
+}
+
+// SendEvent implements logical.EventSender. As in Vault, the path and data_path
+// metadata are made absolute by prepending the mount path.
+func (s *mountEventSender) SendEvent(ctx context.Context, eventType logical.EventType, event *logical.EventData) error {
+	if event == nil {
+		return fmt.Errorf("event is required")
+	}
+	if event.Id == "" {
+		return fmt.Errorf("event ID is required")
+	}
+	if metadata := event.Metadata; metadata != nil {
+		for _, key := range []string{logical.EventMetadataPath, logical.EventMetadataDataPath} {
+			if value, ok := metadata.Fields[key]; ok && value.GetStringValue() != "" {
+				metadata.Fields[key] = structpb.NewStringValue(s.info.MountPath + strings.TrimPrefix(value.GetStringValue(), "/"))
+			}
+		}
+	}
+	return s.bus.Publish(&logical.EventReceived{
+		Event:      event,
+		Namespace:  "",
+		EventType:  string(eventType),
+		PluginInfo: s.info,
+	})
+}
+
+// HandleSubscribe streams events at /v1/sys/events/subscribe/<event type>, where the
+// event type may contain "*" wildcards. WebSocket clients (such as
+// "vault events subscribe") get one JSON message per event; other clients get a
+// server-sent event stream. A since query parameter, or the Last-Event-ID header of a
+// reconnecting server-sent event client, replays the buffered events after that
+// sequence number first.
+func (b *EventBus) HandleSubscribe(w http.ResponseWriter, r *http.Request) {
+	pattern := strings.TrimPrefix(r.URL.Path, "/v1/sys/events/subscribe/")
+	if pattern == "" || pattern == r.URL.Path {
+		WriteError(w, http.StatusBadRequest, "missing event type")
+		return
+	}
+	if r.Method != http.MethodGet {
+		WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	since, err := eventSince(r)
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		websocket.Server{Handler: func(conn *websocket.Conn) {
+			b.streamWebSocket(conn, pattern, since)
+		}}.ServeHTTP(w, r)
+		return
+	}
+	b.streamSSE(w, r, pattern, since)
+}
+
+// eventSince returns the sequence number a subscriber asked to resume after, or -1
+func eventSince(r *http.Request) (int64, error) {
+	value := r.URL.Query().Get("since")
+	if value == "" {
+		value = r.Header.Get(EventSequenceHeader)
+	}
+	if value == "" {
+		return -1, nil
+	}
+	since, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || since < 0 {
+		return 0, fmt.Errorf("invalid event sequence number %q", value)
+	}
+	return since, nil
+}
+
+// streamWebSocket writes events to a WebSocket until the client goes away
+func (b *EventBus) streamWebSocket(conn *websocket.Conn, pattern string, since int64) {
+	defer conn.Close()
+	subscriber, unsubscribe := b.subscribe(pattern, since)
+	defer unsubscribe()
+
+	// The client sends nothing; a failed read means it closed the connection
+	closed := make(chan struct{})
+	go func() {
+		var discard []byte
+		for websocket.Message.Receive(conn, &discard) == nil {
+		}
+		close(closed)
+	}()
+
+	for {
+		// Gaps show as jumps in the events' sequence numbers
+		events, _ := b.pending(subscriber)
+		for _, event := range events {
+			if err := websocket.Message.Send(conn, string(event.message)); err != nil {
+				return
+			}
+		}
+		select {
+		case <-closed:
+			return
+		case <-subscriber.wake:
+		}
+	}
+}
+
+// streamSSE writes events as server-sent events until the client goes away. Each
+// event carries its sequence number as the event ID, so a reconnecting client resumes
+// where it stopped; events dropped from the buffer before the client read them are
+// reported with a vault-event-gap event.
+func (b *EventBus) streamSSE(w http.ResponseWriter, r *http.Request, pattern string, since int64) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		WriteError(w, http.StatusInternalServerError, "streaming is not supported")
+		return
+	}
+	subscriber, unsubscribe := b.subscribe(pattern, since)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		events, missed := b.pending(subscriber)
+		if missed > 0 {
+			fmt.Fprintf(w, "event: vault-event-gap\ndata: {\"missed\":%d}\n\n", missed)
+		}
+		for _, event := range events {
+			fmt.Fprintf(w, "id: %d\nevent: vault-event\ndata: %s\n\n", event.seq, event.message)
+		}
+		flusher.Flush()
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-subscriber.wake:
+		}
+	}
+}
+
+// HandleReplay serves GET /v1/sys/host/events, the buffered events after the since
+// query parameter (all of them by default) whose type matches the type parameter
+// ("*" by default), for test clients that poll instead of subscribing
+func (b *EventBus) HandleReplay(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	since, err := eventSince(r)
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if since < 0 {
+		since = 0
+	}
+	pattern := r.URL.Query().Get("type")
+	if pattern == "" {
+		pattern = "*"
+	}
+
+	b.mu.Lock()
+	events, missed, next := b.since(pattern, uint64(since)+1)
+	b.mu.Unlock()
+
+	messages := make([]json.RawMessage, len(events))
+	for i, event := range events {
+		messages[i] = event.message
+	}
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"events": messages,
+		"missed": missed,
+		"last":   next - 1, // pass as since to get only newer events
+	})
+}
+
+// SetEventBus makes the handler's plugin send its events to bus
+func (h *Handler) SetEventBus(bus *EventBus) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.events = bus
+}
+
+// EventBus returns the handler's event bus, or nil when events are dropped
+func (h *Handler) EventBus() *EventBus {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.events
+}
@@ -0,0 +1,312 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"golang.org/x/net/websocket"
+)
+
+func TestEventTypeMatches(t *testing.T) {
+	cases := []struct {
+		pattern, eventType string
+		want               bool
+	}{
+		{"*", "kv-v2/data-write", true},
+		{"kv-v2/data-write", "kv-v2/data-write", true},
+		{"kv-v2/data-write", "kv-v2/data-delete", false},
+		{"kv-v2/*", "kv-v2/data-write", true},
+		{"kv*write", "kv-v2/data-write", true},
+		{"kv*write", "kv-v2/data-delete", false},
+		{"*delete", "kv-v2/data-write", false},
+		{"database/*/rotate", "database/role/rotate", true},
+	}
+	for _, c := range cases {
+		if got := eventTypeMatches(c.pattern, c.eventType); got != c.want {
+			t.Errorf("eventTypeMatches(%q, %q) = %v, want %v", c.pattern, c.eventType, got, c.want)
+		}
+	}
+}
+
+// cloudEvent is the part of a delivered event the tests look at
+type cloudEvent struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Sequence uint64 `json:"sequence"`
+	Data     struct {
+		EventType string `json:"event_type"`
+		Event     struct {
+			Metadata map[string]string `json:"metadata"`
+		} `json:"event"`
+		PluginInfo struct {
+			MountPath  string `json:"mount_path"`
+			MountClass string `json:"mount_class"`
+			Plugin     string `json:"plugin"`
+		} `json:"plugin_info"`
+	} `json:"data"`
+}
+
+// waitForSubscribers waits until n clients are subscribed to the bus
+func waitForSubscribers(t *testing.T, bus *EventBus, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		bus.mu.Lock()
+		count := len(bus.subscribers)
+		bus.mu.Unlock()
+		if count == n {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("expected %d subscribers", n)
+}
+
+func TestEventBusSSE(t *testing.T) {
+	bus := NewEventBus()
+	server := httptest.NewServer(http.HandlerFunc(bus.HandleSubscribe))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/v1/sys/events/subscribe/kv*")
+	if err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+	waitForSubscribers(t, bus, 1)
+
+	sender := bus.Sender("secret", "vault-plugin-kv")
+	ctx := context.Background()
+	if err := logical.SendEvent(ctx, sender, "database/rotate"); err != nil {
+		t.Fatalf("SendEvent failed: %v", err)
+	}
+	if err := logical.SendEvent(ctx, sender, "kv-v2/data-write", logical.EventMetadataPath, "data/app", logical.EventMetadataDataPath, "data/app"); err != nil {
+		t.Fatalf("SendEvent failed: %v", err)
+	}
+
+	// The non-matching event is skipped, so the first data line is the kv write
+	reader := bufio.NewReader(resp.Body)
+	var event cloudEvent
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("reading stream failed: %v", err)
+		}
+		if data, ok := strings.CutPrefix(line, "data: "); ok {
+			if err := json.Unmarshal([]byte(data), &event); err != nil {
+				t.Fatalf("invalid event %q: %v", data, err)
+			}
+			break
+		}
+	}
+	if event.Type != "kv-v2/data-write" || event.Data.EventType != "kv-v2/data-write" || event.ID == "" {
+		t.Errorf("event = %+v", event)
+	}
+	if event.Data.Event.Metadata["path"] != "secret/data/app" || event.Data.Event.Metadata["data_path"] != "secret/data/app" {
+		t.Errorf("metadata paths should be prefixed with the mount: %v", event.Data.Event.Metadata)
+	}
+	if info := event.Data.PluginInfo; info.MountPath != "secret/" || info.MountClass != "secret" || info.Plugin != "vault-plugin-kv" {
+		t.Errorf("plugin info = %+v", info)
+	}
+}
+
+func TestEventBusWebSocket(t *testing.T) {
+	bus := NewEventBus()
+	server := httptest.NewServer(http.HandlerFunc(bus.HandleSubscribe))
+	defer server.Close()
+
+	conn, err := websocket.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/v1/sys/events/subscribe/*", "", server.URL)
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	waitForSubscribers(t, bus, 1)
+
+	if err := logical.SendEvent(context.Background(), bus.Sender("auth/approle", "approle"), "approle/login"); err != nil {
+		t.Fatalf("SendEvent failed: %v", err)
+	}
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var message string
+	if err := websocket.Message.Receive(conn, &message); err != nil {
+		t.Fatalf("receive failed: %v", err)
+	}
+	var event cloudEvent
+	if err := json.Unmarshal([]byte(message), &event); err != nil {
+		t.Fatalf("invalid event %q: %v", message, err)
+	}
+	if event.Type != "approle/login" || event.Data.PluginInfo.MountClass != "auth" || event.Data.PluginInfo.MountPath != "auth/approle/" {
+		t.Errorf("event = %+v", event)
+	}
+
+	// Closing the socket unsubscribes
+	conn.Close()
+	waitForSubscribers(t, bus, 0)
+}
+
+// readSSE reads server-sent events until n data lines were seen, returning the
+// "field: value" lines
+func readSSE(t *testing.T, reader *bufio.Reader, n int) []string {
+	t.Helper()
+	var lines []string
+	for n > 0 {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("reading stream failed: %v", err)
+		}
+		if line = strings.TrimSpace(line); line == "" {
+			continue
+		}
+		lines = append(lines, line)
+		if strings.HasPrefix(line, "data: ") {
+			n--
+		}
+	}
+	return lines
+}
+
+func TestEventBusReplay(t *testing.T) {
+	bus := NewEventBus()
+	bus.SetReplayLimit(3)
+	server := httptest.NewServer(http.HandlerFunc(bus.HandleSubscribe))
+	defer server.Close()
+
+	sender := bus.Sender("secret", "kv")
+	ctx := context.Background()
+	for _, eventType := range []string{"kv/one", "kv/two", "db/three", "kv/four"} {
+		if err := logical.SendEvent(ctx, sender, eventType); err != nil {
+			t.Fatalf("SendEvent failed: %v", err)
+		}
+	}
+
+	// Event 1 is no longer buffered, so a subscriber resuming after 0 is told it missed it
+	resp, err := http.Get(server.URL + "/v1/sys/events/subscribe/kv*?since=0")
+	if err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+	defer resp.Body.Close()
+	reader := bufio.NewReader(resp.Body)
+	lines := readSSE(t, reader, 3)
+	want := []string{"event: vault-event-gap", `data: {"missed":1}`, "id: 2", "event: vault-event", "id: 4", "event: vault-event"}
+	var got []string
+	for _, line := range lines {
+		if !strings.HasPrefix(line, "data: {\"data") {
+			got = append(got, line)
+		}
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("replayed stream:\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	// Live events follow the replay
+	waitForSubscribers(t, bus, 1)
+	if err := logical.SendEvent(ctx, sender, "kv/five"); err != nil {
+		t.Fatalf("SendEvent failed: %v", err)
+	}
+	lines = readSSE(t, reader, 1)
+	var event cloudEvent
+	json.Unmarshal([]byte(strings.TrimPrefix(lines[len(lines)-1], "data: ")), &event)
+	if lines[0] != "id: 5" || event.Type != "kv/five" || event.Sequence != 5 {
+		t.Errorf("live event = %v", lines)
+	}
+
+	// A reconnecting client resumes after its Last-Event-ID
+	req, _ := http.NewRequest("GET", server.URL+"/v1/sys/events/subscribe/*", nil)
+	req.Header.Set(EventSequenceHeader, "3")
+	resumed, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+	defer resumed.Body.Close()
+	if lines := readSSE(t, bufio.NewReader(resumed.Body), 2); lines[0] != "id: 4" || lines[3] != "id: 5" {
+		t.Errorf("resumed stream = %v", lines)
+	}
+}
+
+func TestEventBusSlowSubscriber(t *testing.T) {
+	bus := NewEventBus()
+	subscriber, unsubscribe := bus.subscribe("*", -1)
+	defer unsubscribe()
+
+	sender := bus.Sender("secret", "kv")
+	for i := 0; i < defaultEventReplay+10; i++ {
+		if err := logical.SendEvent(context.Background(), sender, "kv/write"); err != nil {
+			t.Fatalf("SendEvent failed: %v", err)
+		}
+	}
+	// Publishing never blocks on the subscriber, which catches up from the buffer
+	events, missed := bus.pending(subscriber)
+	if len(events) != defaultEventReplay {
+		t.Fatalf("got %d events, want %d", len(events), defaultEventReplay)
+	}
+	if missed != 10 || events[0].seq != 11 {
+		t.Errorf("got %d events from %d, %d missed; want %d from 11, 10 missed", len(events), events[0].seq, missed, defaultEventReplay)
+	}
+	if events, missed := bus.pending(subscriber); len(events) != 0 || missed != 0 {
+		t.Errorf("a caught-up subscriber should have nothing pending, got %d events, %d missed", len(events), missed)
+	}
+}
+
+func TestEventBusHandleReplay(t *testing.T) {
+	bus := NewEventBus()
+	sender := bus.Sender("secret", "kv")
+	for _, eventType := range []string{"kv/one", "db/two", "kv/three"} {
+		logical.SendEvent(context.Background(), sender, eventType)
+	}
+
+	var body struct {
+		Events []cloudEvent `json:"events"`
+		Missed int          `json:"missed"`
+		Last   int          `json:"last"`
+	}
+	w := httptest.NewRecorder()
+	bus.HandleReplay(w, httptest.NewRequest("GET", "/v1/sys/host/events?type=kv*&since=1", nil))
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("invalid response %s: %v", w.Body.String(), err)
+	}
+	if len(body.Events) != 1 || body.Events[0].Type != "kv/three" || body.Events[0].Sequence != 3 || body.Last != 3 || body.Missed != 0 {
+		t.Errorf("replay = %+v", body)
+	}
+
+	w = httptest.NewRecorder()
+	bus.HandleReplay(w, httptest.NewRequest("GET", "/v1/sys/host/events", nil))
+	json.Unmarshal(w.Body.Bytes(), &body)
+	if len(body.Events) != 3 {
+		t.Errorf("got %d events, want all 3", len(body.Events))
+	}
+
+	w = httptest.NewRecorder()
+	bus.HandleReplay(w, httptest.NewRequest("GET", "/v1/sys/host/events?since=soon", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("invalid since = %d, want 400", w.Code)
+	}
+}
+
+func TestEventBusSubscribeErrors(t *testing.T) {
+	bus := NewEventBus()
+	w := httptest.NewRecorder()
+	bus.HandleSubscribe(w, httptest.NewRequest("GET", "/v1/sys/events/subscribe/", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("missing event type = %d, want 400", w.Code)
+	}
+	w = httptest.NewRecorder()
+	bus.HandleSubscribe(w, httptest.NewRequest("POST", "/v1/sys/events/subscribe/*", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST = %d, want 405", w.Code)
+	}
+
+	sender := bus.Sender("secret", "kv")
+	if err := sender.SendEvent(context.Background(), "kv/write", &logical.EventData{}); err == nil {
+		t.Error("an event without an ID should be rejected")
+	}
+}
@@ -0,0 +1,234 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RecordedExample is a captured request/response pair used to enrich the OpenAPI document
+type RecordedExample struct {
+	Method     string                 `json:"method"`
+	Path       string                 `json:"path"`
+	Request    map[string]interface{} `json:"request,omitempty"`
+	Response   map[string]interface{} `json:"response"`
+	StatusCode int                    `json:"status_code"`
+	RecordedAt time.Time              `json:"recorded_at"`
+}
+
+// ExampleRecorder captures real request/response pairs during a session
+type ExampleRecorder struct {
+	mu       sync.Mutex
+	examples []*RecordedExample
+	perPath  int
+}
+
+// NewExampleRecorder creates a recorder that keeps at most perPath examples
+// for each method and path combination (0 means unlimited)
+func NewExampleRecorder(perPath int) *ExampleRecorder {
+	return &ExampleRecorder{
+		perPath: perPath,
+	}
+}
+
+// Record stores a request/response pair
+func (r *ExampleRecorder) Record(method, path string, request, response map[string]interface{}, statusCode int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.perPath > 0 {
+		count := 0
+		for _, ex := range r.examples {
+			if ex.Method == method && ex.Path == path {
+				count++
+			}
+		}
+		if count >= r.perPath {
+			return
+		}
+	}
+
+	r.examples = append(r.examples, &RecordedExample{
+		Method:     method,
+		Path:       path,
+		Request:    request,
+		Response:   response,
+		StatusCode: statusCode,
+		RecordedAt: time.Now(),
+	})
+}
+
+// Examples returns a copy of all recorded examples
+func (r *ExampleRecorder) Examples() []*RecordedExample {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	result := make([]*RecordedExample, len(r.examples))
+	copy(result, r.examples)
+	return result
+}
+
+// Reset discards all recorded examples
+func (r *ExampleRecorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.examples = nil
+}
+
+// SetExampleRecorder enables example recording for plugin requests
+func (h *Handler) SetExampleRecorder(recorder *ExampleRecorder) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.examples = recorder
+}
+
+// HandleExamples lists (GET) or clears (DELETE) recorded examples at /v1/sys/host/examples.
+// With ?normalize=true the examples are listed with volatile values replaced, as by
+// /v1/sys/host/normalize.
+func (h *Handler) HandleExamples(w http.ResponseWriter, r *http.Request) {
+	h.mu.RLock()
+	recorder := h.examples
+	h.mu.RUnlock()
+
+	if recorder == nil {
+		h.writeVaultError(w, http.StatusNotFound, "example recording is not enabled")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		if r.URL.Query().Get("normalize") != "true" {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"examples": recorder.Examples(),
+			})
+			return
+		}
+		// Round-trip the examples so they are plain JSON values the normalizer can walk
+		data, _ := json.Marshal(recorder.Examples())
+		var examples interface{}
+		json.Unmarshal(data, &examples)
+		writeFixture(w, map[string]interface{}{
+			"examples": NewFixtureNormalizer(fixtureFieldsParam(r)...).Normalize(examples),
+		})
+	case http.MethodDelete:
+		recorder.Reset()
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		h.writeVaultError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// recordExample captures a plugin request/response pair if recording is enabled
+func (h *Handler) recordExample(method, path string, request, response map[string]interface{}, statusCode int) {
+	h.mu.RLock()
+	recorder := h.examples
+	h.mu.RUnlock()
+
+	if recorder == nil {
+		return
+	}
+	recorder.Record(method, path, request, response, statusCode)
+}
+
+var oasParamPattern = regexp.MustCompile(`\\\{[^}]+\\\}`)
+
+// oasPathMatcher converts an OpenAPI path template such as /creds/{name} into a regexp
+func oasPathMatcher(template string) (*regexp.Regexp, error) {
+	pattern := oasParamPattern.ReplaceAllString(regexp.QuoteMeta(strings.TrimPrefix(template, "/")), `[^/]+`)
+	return regexp.Compile("^" + pattern + "$")
+}
+
+// oasMethod maps an HTTP method onto the OpenAPI operation key Vault uses for it
+func oasMethod(method string) string {
+	switch method {
+	case http.MethodPost, http.MethodPut:
+		return "post"
+	case "LIST":
+		return "get"
+	default:
+		return strings.ToLower(method)
+	}
+}
+
+// mergeExamples adds recorded examples to the matching operations of an OpenAPI document map.
+// Paths in docMap must still be relative to the mount (as returned by the plugin).
+func mergeExamples(docMap map[string]interface{}, examples []*RecordedExample) {
+	paths, ok := docMap["paths"].(map[string]interface{})
+	if !ok || len(examples) == 0 {
+		return
+	}
+
+	matchers := make(map[string]*regexp.Regexp, len(paths))
+	for template := range paths {
+		if re, err := oasPathMatcher(template); err == nil {
+			matchers[template] = re
+		}
+	}
+
+	counters := make(map[string]int)
+	for _, ex := range examples {
+		for template, re := range matchers {
+			if !re.MatchString(ex.Path) {
+				continue
+			}
+
+			pathItem, ok := paths[template].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			op, ok := pathItem[oasMethod(ex.Method)].(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			key := template + " " + ex.Method
+			counters[key]++
+			name := fmt.Sprintf("recorded-%d", counters[key])
+			summary := fmt.Sprintf("%s /%s", ex.Method, ex.Path)
+
+			if len(ex.Request) > 0 {
+				requestBody := ensureMap(op, "requestBody")
+				content := ensureMap(requestBody, "content")
+				media := ensureMap(content, "application/json")
+				addExample(media, name, summary, ex.Request)
+			}
+
+			responses := ensureMap(op, "responses")
+			response := ensureMap(responses, fmt.Sprintf("%d", ex.StatusCode))
+			if _, ok := response["description"]; !ok {
+				response["description"] = http.StatusText(ex.StatusCode)
+			}
+			content := ensureMap(response, "content")
+			media := ensureMap(content, "application/json")
+			addExample(media, name, summary, ex.Response)
+			break
+		}
+	}
+}
+
+// ensureMap returns parent[key] as a map, creating it if missing
+func ensureMap(parent map[string]interface{}, key string) map[string]interface{} {
+	if m, ok := parent[key].(map[string]interface{}); ok {
+		return m
+	}
+	m := make(map[string]interface{})
+	parent[key] = m
+	return m
+}
+
+// addExample adds a named example to an OpenAPI media type object
+func addExample(media map[string]interface{}, name, summary string, value interface{}) {
+	examples := ensureMap(media, "examples")
+	examples[name] = map[string]interface{}{
+		"summary": summary,
+		"value":   value,
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+func TestExampleRecorderPerPathLimit(t *testing.T) {
+	recorder := NewExampleRecorder(2)
+
+	for i := 0; i < 5; i++ {
+		recorder.Record("GET", "creds/test", nil, map[string]interface{}{"i": i}, http.StatusOK)
+	}
+	recorder.Record("GET", "creds/other", nil, map[string]interface{}{}, http.StatusOK)
+
+	if got := len(recorder.Examples()); got != 3 {
+		t.Errorf("recorded %d examples, want 3", got)
+	}
+
+	recorder.Reset()
+	if got := len(recorder.Examples()); got != 0 {
+		t.Errorf("recorded %d examples after reset, want 0", got)
+	}
+}
+
+func TestMergeExamples(t *testing.T) {
+	docMap := map[string]interface{}{
+		"paths": map[string]interface{}{
+			"/creds/{name}": map[string]interface{}{
+				"get": map[string]interface{}{},
+			},
+			"/config": map[string]interface{}{
+				"post": map[string]interface{}{},
+			},
+		},
+	}
+
+	examples := []*RecordedExample{
+		{Method: "GET", Path: "creds/test", Response: map[string]interface{}{"data": "x"}, StatusCode: 200},
+		{Method: "PUT", Path: "config", Request: map[string]interface{}{"url": "y"}, Response: map[string]interface{}{}, StatusCode: 200},
+		{Method: "GET", Path: "unknown/path", Response: map[string]interface{}{}, StatusCode: 200},
+	}
+
+	mergeExamples(docMap, examples)
+
+	paths := docMap["paths"].(map[string]interface{})
+
+	get := paths["/creds/{name}"].(map[string]interface{})["get"].(map[string]interface{})
+	media := get["responses"].(map[string]interface{})["200"].(map[string]interface{})["content"].(map[string]interface{})["application/json"].(map[string]interface{})
+	if _, ok := media["examples"].(map[string]interface{})["recorded-1"]; !ok {
+		t.Error("expected recorded-1 response example on GET /creds/{name}")
+	}
+
+	post := paths["/config"].(map[string]interface{})["post"].(map[string]interface{})
+	reqMedia := post["requestBody"].(map[string]interface{})["content"].(map[string]interface{})["application/json"].(map[string]interface{})
+	if _, ok := reqMedia["examples"].(map[string]interface{})["recorded-1"]; !ok {
+		t.Error("expected recorded-1 request example on POST /config")
+	}
+}
+
+func TestHandleRequestRecordsExamples(t *testing.T) {
+	storage := newMockStorage()
+	logger := hclog.NewNullLogger()
+	handler := NewHandler(&mockBackend{}, storage, logger, "plugin")
+	handler.SetExampleRecorder(NewExampleRecorder(0))
+
+	req := httptest.NewRequest("POST", "/v1/plugin/config", bytes.NewBufferString(`{"key":"value"}`))
+	w := httptest.NewRecorder()
+	handler.HandleRequest(w, req)
+
+	req = httptest.NewRequest("GET", "/v1/sys/host/examples", nil)
+	w = httptest.NewRecorder()
+	handler.HandleExamples(w, req)
+
+	var response struct {
+		Examples []*RecordedExample `json:"examples"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	if len(response.Examples) != 1 {
+		t.Fatalf("got %d examples, want 1", len(response.Examples))
+	}
+	if response.Examples[0].Path != "config" || response.Examples[0].Request["key"] != "value" {
+		t.Errorf("unexpected example: %+v", response.Examples[0])
+	}
+}
+
+func TestHandleExamplesDisabled(t *testing.T) {
+	handler := NewHandler(nil, newMockStorage(), hclog.NewNullLogger(), "plugin")
+
+	req := httptest.NewRequest("GET", "/v1/sys/host/examples", nil)
+	w := httptest.NewRecorder()
+	handler.HandleExamples(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Status code = %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"vault-plugin-host/leases"
+)
+
+const (
+	// ExpirationCheckInterval is how often RunExpiration looks for lapsed leases
+	ExpirationCheckInterval = time.Second
+	// expirationRevokeTimeout bounds each revocation request sent for an expired lease
+	expirationRevokeTimeout = 30 * time.Second
+	// systemLeaseTTL is Vault's system default and max lease TTL, which mounts without
+	// lease TTLs of their own inherit
+	systemLeaseTTL = 768 * time.Hour
+)
+
+// SetLeaseTTLs sets the mount's default and max lease TTLs; zero inherits Vault's
+// system default of 32 days
+func (h *Handler) SetLeaseTTLs(defaultTTL, maxTTL time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.defaultLeaseTTL = defaultTTL
+	h.maxLeaseTTL = maxTTL
+}
+
+// leaseTTLs returns the mount's default and max lease TTLs
+func (h *Handler) leaseTTLs() (time.Duration, time.Duration) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	defaultTTL, maxTTL := h.defaultLeaseTTL, h.maxLeaseTTL
+	if defaultTTL == 0 {
+		defaultTTL = systemLeaseTTL
+	}
+	if maxTTL == 0 {
+		maxTTL = systemLeaseTTL
+	}
+	return defaultTTL, maxTTL
+}
+
+// calculateLeaseTTL returns the TTL of a lease issued at issued and extended at now,
+// as framework.CalculateTTL does: the increment the client asked for, else the
+// secret's TTL, else the mount default. The lease ends no later than the max TTL after
+// it was issued, where a secret's max TTL applies when it is below the mount's.
+func calculateLeaseTTL(increment, ttl, secretMaxTTL, defaultTTL, maxTTL time.Duration, issued, now time.Time) time.Duration {
+	switch {
+	case increment > 0:
+		ttl = increment
+	case ttl <= 0:
+		ttl = defaultTTL
+	}
+	if secretMaxTTL > 0 && secretMaxTTL < maxTTL {
+		maxTTL = secretMaxTTL
+	}
+	if end := issued.Add(maxTTL); now.Add(ttl).After(end) {
+		ttl = end.Sub(now)
+	}
+	if ttl < 0 {
+		ttl = 0
+	}
+	return ttl
+}
+
+// RunExpiration revokes expired leases every interval until stop is closed,
+// like Vault's expiration manager
+func (h *Handler) RunExpiration(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			h.RevokeExpired(now)
+		}
+	}
+}
+
+// RevokeExpired removes every lease that expired by now and sends the plugin a
+// revocation request for each; it returns the number of leases revoked. Leases
+// are kept while the plugin is not running so they can be revoked once it is back.
+func (h *Handler) RevokeExpired(now time.Time) int {
+	h.mu.RLock()
+	running := h.backend != nil
+	h.mu.RUnlock()
+	if !running {
+		return 0
+	}
+
+	expired := h.leases.TakeExpired(now)
+	for _, lease := range expired {
+		h.leaseStats.Ended(lease, leases.EndExpired, lease.ExpireTime)
+		ctx, cancel := context.WithTimeout(context.Background(), expirationRevokeTimeout)
+		if err := h.notifyRevoke(ctx, lease); err != nil {
+			h.logger.Error("plugin revocation of expired lease failed", "error", err, "lease_id", lease.LeaseID)
+		}
+		cancel()
+		h.logger.Info("lease expired and revoked", "lease_id", lease.LeaseID, "expire_time", lease.ExpireTime)
+	}
+	return len(expired)
+}
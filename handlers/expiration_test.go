@@ -0,0 +1,192 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestRevokeExpired(t *testing.T) {
+	var revoked []*logical.Request
+	backend := funcBackend(func(ctx context.Context, req *logical.Request) (*logical.Response, error) {
+		revoked = append(revoked, req)
+		return nil, nil
+	})
+
+	now := time.Now()
+	handler := NewHandler(nil, newMockStorage(), hclog.NewNullLogger(), "plugin")
+	handler.leases.Put(&LeaseInfo{LeaseID: "plugin/creds/a/1", Path: "creds/a", ExpireTime: now.Add(-time.Second)})
+	handler.leases.Put(&LeaseInfo{LeaseID: "plugin/creds/b/2", Path: "creds/b", ExpireTime: now.Add(time.Hour)})
+
+	// Expired leases wait for the plugin to be running
+	if n := handler.RevokeExpired(now); n != 0 {
+		t.Errorf("revoked %d leases without a backend, want 0", n)
+	}
+	if handler.leases.Len() != 2 {
+		t.Fatalf("leases = %d, want 2", handler.leases.Len())
+	}
+
+	handler.SetBackend(backend)
+	if n := handler.RevokeExpired(now); n != 1 {
+		t.Fatalf("revoked %d leases, want 1", n)
+	}
+
+	if len(revoked) != 1 {
+		t.Fatalf("plugin received %d revocations, want 1", len(revoked))
+	}
+	req := revoked[0]
+	if req.Operation != logical.RevokeOperation || req.Path != "creds/a" {
+		t.Errorf("request = %s %s, want revoke creds/a", req.Operation, req.Path)
+	}
+	if req.Data["lease_id"] != "plugin/creds/a/1" {
+		t.Errorf("lease_id = %v, want plugin/creds/a/1", req.Data["lease_id"])
+	}
+
+	if _, ok := handler.leases.Get("plugin/creds/a/1"); ok {
+		t.Error("expired lease is still stored")
+	}
+	if _, ok := handler.leases.Get("plugin/creds/b/2"); !ok {
+		t.Error("unexpired lease was removed")
+	}
+}
+
+func TestRunExpiration(t *testing.T) {
+	revoked := make(chan string, 1)
+	backend := funcBackend(func(ctx context.Context, req *logical.Request) (*logical.Response, error) {
+		revoked <- req.Data["lease_id"].(string)
+		return nil, nil
+	})
+
+	handler := NewHandler(backend, newMockStorage(), hclog.NewNullLogger(), "plugin")
+	handler.leases.Put(&LeaseInfo{LeaseID: "short", ExpireTime: time.Now().Add(20 * time.Millisecond)})
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go handler.RunExpiration(10*time.Millisecond, stop)
+
+	select {
+	case id := <-revoked:
+		if id != "short" {
+			t.Errorf("revoked %s, want short", id)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expired lease was not revoked")
+	}
+}
+
+func TestCalculateLeaseTTL(t *testing.T) {
+	issued := time.Now()
+	cases := []struct {
+		name                 string
+		increment, ttl, smax time.Duration
+		elapsed              time.Duration
+		want                 time.Duration
+	}{
+		{"mount default", 0, 0, 0, 0, 10 * time.Minute},
+		{"secret TTL", 0, 30 * time.Minute, 0, 0, 30 * time.Minute},
+		{"increment", 5 * time.Minute, 30 * time.Minute, 0, 0, 5 * time.Minute},
+		{"mount max", 0, 2 * time.Hour, 0, 0, time.Hour},
+		{"secret max", 0, 30 * time.Minute, 20 * time.Minute, 0, 20 * time.Minute},
+		{"max from issue", 0, 30 * time.Minute, 0, 45 * time.Minute, 15 * time.Minute},
+		{"past max", 0, 30 * time.Minute, 0, 2 * time.Hour, 0},
+	}
+	for _, c := range cases {
+		got := calculateLeaseTTL(c.increment, c.ttl, c.smax, 10*time.Minute, time.Hour, issued, issued.Add(c.elapsed))
+		if got != c.want {
+			t.Errorf("%s: TTL = %s, want %s", c.name, got, c.want)
+		}
+	}
+}
+
+func TestSecretLeases(t *testing.T) {
+	received := make(map[logical.Operation]*logical.Request)
+	backend := funcBackend(func(ctx context.Context, req *logical.Request) (*logical.Response, error) {
+		received[req.Operation] = req
+		switch {
+		case req.Path == "kv/app":
+			return &logical.Response{Data: map[string]interface{}{"k": "v"}}, nil
+		case req.Path == "static/app":
+			return &logical.Response{Data: map[string]interface{}{"k": "v"}, Secret: &logical.Secret{}}, nil
+		case req.Operation == logical.RenewOperation:
+			secret := *req.Secret
+			secret.TTL = 20 * time.Minute
+			secret.InternalData = map[string]interface{}{"username": "v-app", "renewed": true}
+			return &logical.Response{Secret: &secret}, nil
+		case req.Operation == logical.UpdateOperation:
+			return &logical.Response{
+				Data: map[string]interface{}{"username": "v-app"},
+				Secret: &logical.Secret{
+					LeaseOptions: logical.LeaseOptions{TTL: 2 * time.Hour, Renewable: true},
+					InternalData: map[string]interface{}{"username": "v-app"},
+				},
+			}, nil
+		}
+		return nil, nil
+	})
+	handler := NewHandler(backend, newMockStorage(), hclog.NewNullLogger(), "plugin")
+	handler.SetLeaseTTLs(10*time.Minute, time.Hour)
+
+	type leaseResponse struct {
+		LeaseID       string `json:"lease_id"`
+		LeaseDuration int    `json:"lease_duration"`
+		Renewable     bool   `json:"renewable"`
+	}
+	send := func(method, path string) leaseResponse {
+		w := httptest.NewRecorder()
+		handler.HandleRequest(w, httptest.NewRequest(method, "/v1/plugin/"+path, nil))
+		var resp leaseResponse
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return resp
+	}
+
+	// Reads without a secret are not leased
+	if resp := send("GET", "kv/app"); resp.LeaseID != "" || handler.leases.Len() != 0 {
+		t.Errorf("a read without a secret was leased: %+v", resp)
+	}
+
+	// A secret without a TTL gets the mount default, and is not renewable unless it says so
+	static := send("GET", "static/app")
+	if static.LeaseID == "" || static.LeaseDuration != 600 || static.Renewable {
+		t.Errorf("static secret lease = %+v, want 600s, not renewable", static)
+	}
+	w := httptest.NewRecorder()
+	handler.HandleLeaseRenew(w, httptest.NewRequest("PUT", "/v1/sys/leases/renew", strings.NewReader(`{"lease_id": "`+static.LeaseID+`"}`)))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("renewing a non-renewable lease = %d, want 400", w.Code)
+	}
+
+	// Writes that return a secret are leased, capped by the mount max TTL
+	creds := send("POST", "creds/app")
+	if creds.LeaseID == "" || creds.LeaseDuration != 3600 || !creds.Renewable {
+		t.Fatalf("creds lease = %+v, want 3600s, renewable", creds)
+	}
+
+	w = httptest.NewRecorder()
+	handler.HandleLeaseRenew(w, httptest.NewRequest("PUT", "/v1/sys/leases/renew", strings.NewReader(`{"lease_id": "`+creds.LeaseID+`", "increment": 300}`)))
+	var renewed leaseResponse
+	json.Unmarshal(w.Body.Bytes(), &renewed)
+	if w.Code != http.StatusOK || renewed.LeaseDuration != 1200 {
+		t.Errorf("renewal = %d %s, want the plugin's 1200s", w.Code, w.Body)
+	}
+	renewReq := received[logical.RenewOperation]
+	if renewReq.Secret.InternalData["username"] != "v-app" || renewReq.Secret.Increment != 5*time.Minute || renewReq.Secret.LeaseID != creds.LeaseID {
+		t.Errorf("renew request secret = %+v", renewReq.Secret)
+	}
+
+	// Revocation sees the InternalData the renewal returned
+	w = httptest.NewRecorder()
+	handler.HandleLeaseRevokeByPath(w, httptest.NewRequest("PUT", "/v1/sys/leases/revoke/"+creds.LeaseID, nil))
+	if revokeReq := received[logical.RevokeOperation]; revokeReq == nil || revokeReq.Secret.InternalData["renewed"] != true {
+		t.Errorf("revoke request = %+v", revokeReq)
+	}
+}
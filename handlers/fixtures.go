@@ -0,0 +1,178 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// maxNormalizeBytes bounds the body /v1/sys/host/normalize reads
+const maxNormalizeBytes = 32 << 20
+
+var (
+	// fixtureLeaseID matches a generated lease ID; the mount and path before the random
+	// suffix are kept
+	fixtureLeaseID = regexp.MustCompile(`^(\S+)/([0-9a-f]{24})$`)
+
+	// fixturePatterns are the volatile values replaced inside any string, by placeholder kind
+	fixturePatterns = []struct {
+		kind    string
+		pattern *regexp.Regexp
+	}{
+		{"token", regexp.MustCompile(`\bhvs\.[A-Za-z0-9]{24}\b`)},
+		{"uuid", regexp.MustCompile(`\b[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}\b`)},
+		{"timestamp", regexp.MustCompile(`\b\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:?\d{2})?`)},
+	}
+
+	// fixtureFields are keys whose string values are always replaced, such as random
+	// accessors that have no recognizable format
+	fixtureFields = []string{"accessor", "wrapped_accessor", "nonce"}
+)
+
+// FixtureNormalizer rewrites volatile values in captured responses (lease IDs, tokens,
+// UUIDs, timestamps and the values of chosen fields) with stable placeholders such as
+// <uuid-1>, so fixtures recorded in different runs compare equal. A value gets the same
+// placeholder wherever it appears, and placeholders are numbered in the order values are
+// first seen, so a fixture still shows which fields held the same value.
+type FixtureNormalizer struct {
+	fields       map[string]bool
+	placeholders map[string]string
+	counts       map[string]int
+}
+
+// NewFixtureNormalizer creates a normalizer that, besides the built-in patterns, replaces
+// the string values of the given field names
+func NewFixtureNormalizer(fields ...string) *FixtureNormalizer {
+	n := &FixtureNormalizer{
+		fields:       make(map[string]bool),
+		placeholders: make(map[string]string),
+		counts:       make(map[string]int),
+	}
+	for _, field := range fixtureFields {
+		n.fields[field] = true
+	}
+	for _, field := range fields {
+		if field = strings.TrimSpace(field); field != "" {
+			n.fields[field] = true
+		}
+	}
+	return n
+}
+
+// Normalize returns v, a decoded JSON value, with volatile values replaced. Object keys
+// are visited in sorted order so the numbering does not depend on map iteration.
+func (n *FixtureNormalizer) Normalize(v interface{}) interface{} {
+	return n.normalize("", v)
+}
+
+func (n *FixtureNormalizer) normalize(key string, v interface{}) interface{} {
+	switch value := v.(type) {
+	case string:
+		if n.fields[key] && value != "" {
+			return n.placeholder(key, value)
+		}
+		return n.normalizeString(value)
+	case map[string]interface{}:
+		keys := make([]string, 0, len(value))
+		for k := range value {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		result := make(map[string]interface{}, len(value))
+		for _, k := range keys {
+			result[k] = n.normalize(k, value[k])
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(value))
+		for i, item := range value {
+			result[i] = n.normalize(key, item)
+		}
+		return result
+	}
+	return v
+}
+
+// normalizeString replaces the volatile parts of a string
+func (n *FixtureNormalizer) normalizeString(s string) string {
+	if m := fixtureLeaseID.FindStringSubmatch(s); m != nil {
+		return m[1] + "/" + n.placeholder("lease", s)
+	}
+	for _, p := range fixturePatterns {
+		s = p.pattern.ReplaceAllStringFunc(s, func(match string) string {
+			return n.placeholder(p.kind, match)
+		})
+	}
+	return s
+}
+
+// placeholder returns the placeholder of a value, numbering it if it is new
+func (n *FixtureNormalizer) placeholder(kind, value string) string {
+	id := kind + "\x00" + value
+	if placeholder, ok := n.placeholders[id]; ok {
+		return placeholder
+	}
+	n.counts[kind]++
+	placeholder := fmt.Sprintf("<%s-%d>", kind, n.counts[kind])
+	n.placeholders[id] = placeholder
+	return placeholder
+}
+
+// NormalizeJSON normalizes a JSON document
+func (n *FixtureNormalizer) NormalizeJSON(data []byte) ([]byte, error) {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	return json.Marshal(n.Normalize(v))
+}
+
+// NormalizeFixture normalizes a decoded JSON value with the built-in rules
+func NormalizeFixture(v interface{}) interface{} {
+	return NewFixtureNormalizer().Normalize(v)
+}
+
+// writeFixture writes a normalized value as JSON, leaving the placeholders' angle
+// brackets unescaped so fixtures stay readable
+func writeFixture(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(v)
+}
+
+// fixtureFieldsParam returns the comma-separated field names of the fields query parameter
+func fixtureFieldsParam(r *http.Request) []string {
+	if fields := r.URL.Query().Get("fields"); fields != "" {
+		return strings.Split(fields, ",")
+	}
+	return nil
+}
+
+// HandleNormalize serves POST /v1/sys/host/normalize: the JSON body, such as a captured
+// response, is returned with volatile values replaced by placeholders. The fields query
+// parameter names further fields whose values are replaced.
+func HandleNormalize(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodPut {
+		WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	var body interface{}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxNormalizeBytes)).Decode(&body); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			WriteError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("body exceeds %d bytes", tooLarge.Limit))
+			return
+		}
+		WriteError(w, http.StatusBadRequest, fmt.Sprintf("invalid JSON body: %v", err))
+		return
+	}
+	writeFixture(w, NewFixtureNormalizer(fixtureFieldsParam(r)...).Normalize(body))
+}
@@ -0,0 +1,117 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+func TestFixtureNormalizer(t *testing.T) {
+	response := map[string]interface{}{
+		"request_id": "8f2c1a4e-0b3d-4c5e-9f6a-7b8c9d0e1f2a",
+		"lease_id":   "plugin/creds/readonly/a1b2c3d4e5f6a7b8c9d0e1f2",
+		"data": map[string]interface{}{
+			"username":   "v-readonly-8f2c1a4e-0b3d-4c5e-9f6a-7b8c9d0e1f2a",
+			"token":      "hvs.AbCdEfGhIjKlMnOpQrStUvWx",
+			"accessor":   "Zx81kLmNoPqRsTuVwXyZ0123",
+			"expires":    "2025-03-04T10:11:12.123456Z",
+			"created":    "2025-03-04T10:11:12Z",
+			"serials":    []interface{}{"keep-me", "2025-03-04 10:11:12+01:00"},
+			"ttl":        float64(3600),
+			"session_id": "abc",
+		},
+	}
+	want := map[string]interface{}{
+		"request_id": "<uuid-1>",
+		"lease_id":   "plugin/creds/readonly/<lease-1>",
+		"data": map[string]interface{}{
+			"username":   "v-readonly-<uuid-1>",
+			"token":      "<token-1>",
+			"accessor":   "<accessor-1>",
+			"expires":    "<timestamp-2>",
+			"created":    "<timestamp-1>",
+			"serials":    []interface{}{"keep-me", "<timestamp-3>"},
+			"ttl":        float64(3600),
+			"session_id": "<session_id-1>",
+		},
+	}
+
+	normalizer := NewFixtureNormalizer("session_id")
+	if got := normalizer.Normalize(response); !reflect.DeepEqual(got, want) {
+		t.Errorf("Normalize() =\n%v\nwant\n%v", got, want)
+	}
+	// A second response reuses the placeholders of values already seen
+	again := normalizer.Normalize(map[string]interface{}{"id": "8f2c1a4e-0b3d-4c5e-9f6a-7b8c9d0e1f2a", "other": "11111111-2222-3333-4444-555555555555"})
+	if !reflect.DeepEqual(again, map[string]interface{}{"id": "<uuid-1>", "other": "<uuid-2>"}) {
+		t.Errorf("placeholders should be stable across documents, got %v", again)
+	}
+
+	// Re-recorded responses normalize to the same fixture
+	first, err := NewFixtureNormalizer().NormalizeJSON([]byte(`{"a": "2024-01-01T00:00:00Z", "b": "2024-01-01T00:00:00Z", "c": "2024-01-02T00:00:00Z"}`))
+	if err != nil {
+		t.Fatalf("NormalizeJSON failed: %v", err)
+	}
+	second, _ := NewFixtureNormalizer().NormalizeJSON([]byte(`{"a": "2026-05-05T09:00:00Z", "b": "2026-05-05T09:00:00Z", "c": "2026-05-06T09:00:00Z"}`))
+	if !bytes.Equal(first, second) {
+		t.Errorf("re-recordings differ:\n%s\n%s", first, second)
+	}
+}
+
+func TestHandleNormalize(t *testing.T) {
+	req := httptest.NewRequest("POST", "/v1/sys/host/normalize?fields=name",
+		strings.NewReader(`{"request_id": "8f2c1a4e-0b3d-4c5e-9f6a-7b8c9d0e1f2a", "data": {"name": "x1"}}`))
+	w := httptest.NewRecorder()
+	HandleNormalize(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+	if got := strings.TrimSpace(w.Body.String()); got != `{"data":{"name":"<name-1>"},"request_id":"<uuid-1>"}` {
+		t.Errorf("body = %s", got)
+	}
+
+	for _, tc := range []struct {
+		method, body string
+		status       int
+	}{
+		{"GET", "", http.StatusMethodNotAllowed},
+		{"POST", "{", http.StatusBadRequest},
+	} {
+		w := httptest.NewRecorder()
+		HandleNormalize(w, httptest.NewRequest(tc.method, "/v1/sys/host/normalize", strings.NewReader(tc.body)))
+		if w.Code != tc.status {
+			t.Errorf("%s %q: status %d, want %d", tc.method, tc.body, w.Code, tc.status)
+		}
+	}
+}
+
+func TestHandleExamplesNormalized(t *testing.T) {
+	handler := NewHandler(&mockBackend{}, newMockStorage(), hclog.NewNullLogger(), "plugin")
+	recorder := NewExampleRecorder(0)
+	handler.SetExampleRecorder(recorder)
+	recorder.Record("GET", "creds/test", nil, map[string]interface{}{"request_id": "8f2c1a4e-0b3d-4c5e-9f6a-7b8c9d0e1f2a"}, http.StatusOK)
+
+	w := httptest.NewRecorder()
+	handler.HandleExamples(w, httptest.NewRequest("GET", "/v1/sys/host/examples?normalize=true", nil))
+	var response struct {
+		Examples []map[string]interface{} `json:"examples"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(response.Examples) != 1 {
+		t.Fatalf("got %d examples, want 1", len(response.Examples))
+	}
+	example := response.Examples[0]
+	if example["recorded_at"] != "<timestamp-1>" || example["response"].(map[string]interface{})["request_id"] != "<uuid-1>" {
+		t.Errorf("unexpected normalized example: %v", example)
+	}
+}
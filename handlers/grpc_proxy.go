@@ -0,0 +1,249 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// PluginConnection describes how the host reaches a plugin process, as go-plugin's
+// handshake line announced it
+type PluginConnection struct {
+	Protocol        string `json:"protocol"` // grpc or netrpc
+	ProtocolVersion int    `json:"protocol_version"`
+	Network         string `json:"network"`
+	Address         string `json:"address"`
+	AutoMTLS        bool   `json:"auto_mtls"`
+	Attached        bool   `json:"attached"` // the host attached to a plugin it did not launch
+	PID             int    `json:"pid,omitempty"`
+}
+
+// SetPluginConnection records how the host reaches the plugin; nil when it is not running
+func (h *Handler) SetPluginConnection(conn *PluginConnection) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.connection = conn
+}
+
+// HandlePluginConnection serves GET /v1/sys/plugin/connection with the plugin's
+// connection and, when a GRPCProxy publishes it, the address direct gRPC clients use
+func (h *Handler) HandlePluginConnection(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.writeVaultError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	h.mu.RLock()
+	conn := h.connection
+	proxyAddr := h.grpcProxyAddr
+	h.mu.RUnlock()
+
+	if conn == nil {
+		h.writeVaultError(w, http.StatusServiceUnavailable, "plugin is not running")
+		return
+	}
+	data := map[string]interface{}{
+		"protocol":         conn.Protocol,
+		"protocol_version": conn.ProtocolVersion,
+		"network":          conn.Network,
+		"address":          conn.Address,
+		"auto_mtls":        conn.AutoMTLS,
+		"attached":         conn.Attached,
+		"pid":              conn.PID,
+		"mount":            h.mountPath,
+		"proxy_address":    proxyAddr,
+	}
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"request_id": newRequestID(),
+		"data":       data,
+	})
+}
+
+// reservedGRPCServices are go-plugin's own services, which the host drives; a call to
+// GRPCController/Shutdown, for one, would stop the plugin behind the host's back
+var reservedGRPCServices = []string{"/plugin.GRPCController/", "/plugin.GRPCBroker/", "/plugin.GRPCStdio/"}
+
+// rawFrame is a gRPC message passed through undecoded
+type rawFrame struct {
+	data []byte
+}
+
+// rawCodec forwards messages as they are. It is named proto so calls keep the
+// content type the plugin expects.
+type rawCodec struct{}
+
+func (rawCodec) Marshal(v interface{}) ([]byte, error) {
+	frame, ok := v.(*rawFrame)
+	if !ok {
+		return nil, fmt.Errorf("unexpected message type %T", v)
+	}
+	return frame.data, nil
+}
+
+func (rawCodec) Unmarshal(data []byte, v interface{}) error {
+	frame, ok := v.(*rawFrame)
+	if !ok {
+		return fmt.Errorf("unexpected message type %T", v)
+	}
+	frame.data = append(frame.data[:0], data...)
+	return nil
+}
+
+func (rawCodec) Name() string { return "proto" }
+
+// GRPCProxy publishes a plugin's gRPC services on a TCP address without TLS, so tools
+// such as grpcurl can speak the backend protocol directly. Calls are forwarded over the
+// host's own connection, which the plugin secures with AutoMTLS, and follow restarts
+// of the plugin. When tokens are enforced, calls need the root token in x-vault-token
+// or an "authorization: Bearer" header.
+type GRPCProxy struct {
+	handler  *Handler
+	tokens   *TokenStore
+	server   *grpc.Server
+	listener net.Listener
+}
+
+// NewGRPCProxy creates a proxy for the plugin behind handler. tokens may be nil, in
+// which case calls are not checked.
+func NewGRPCProxy(handler *Handler, tokens *TokenStore) *GRPCProxy {
+	p := &GRPCProxy{handler: handler, tokens: tokens}
+	p.server = grpc.NewServer(
+		grpc.ForceServerCodec(rawCodec{}),
+		grpc.UnknownServiceHandler(p.forward),
+	)
+	return p
+}
+
+// Start listens on addr, such as 127.0.0.1:0, and serves in the background
+func (p *GRPCProxy) Start(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen for gRPC clients: %w", err)
+	}
+	p.listener = listener
+	p.handler.mu.Lock()
+	p.handler.grpcProxyAddr = listener.Addr().String()
+	p.handler.mu.Unlock()
+	go p.server.Serve(listener)
+	return nil
+}
+
+// Addr returns the address the proxy listens on
+func (p *GRPCProxy) Addr() string {
+	if p.listener == nil {
+		return ""
+	}
+	return p.listener.Addr().String()
+}
+
+// Close stops the proxy, ending the calls in progress
+func (p *GRPCProxy) Close() {
+	p.server.Stop()
+	p.handler.mu.Lock()
+	p.handler.grpcProxyAddr = ""
+	p.handler.mu.Unlock()
+}
+
+// authorize checks the token of an incoming call
+func (p *GRPCProxy) authorize(md metadata.MD) error {
+	if p.tokens == nil || !p.tokens.Enforced() {
+		return nil
+	}
+	token := ""
+	if values := md.Get("x-vault-token"); len(values) > 0 {
+		token = values[0]
+	} else if values := md.Get("authorization"); len(values) > 0 {
+		token = strings.TrimPrefix(values[0], "Bearer ")
+	}
+	entry, ok := p.tokens.Lookup(token)
+	if !ok || !containsString(entry.Policies, "root") {
+		return status.Error(codes.PermissionDenied, "permission denied")
+	}
+	return nil
+}
+
+// forward relays a call of any method to the plugin and its responses back
+func (p *GRPCProxy) forward(_ interface{}, stream grpc.ServerStream) error {
+	method, ok := grpc.MethodFromServerStream(stream)
+	if !ok {
+		return status.Error(codes.Internal, "no method in call")
+	}
+	for _, prefix := range reservedGRPCServices {
+		if strings.HasPrefix(method, prefix) {
+			return status.Errorf(codes.PermissionDenied, "%s is reserved for the host", strings.Trim(prefix, "/"))
+		}
+	}
+
+	md, _ := metadata.FromIncomingContext(stream.Context())
+	if err := p.authorize(md); err != nil {
+		return err
+	}
+	md = md.Copy()
+	md.Delete("x-vault-token")
+	md.Delete("authorization")
+
+	p.handler.mu.RLock()
+	conn := p.handler.grpcConn
+	p.handler.mu.RUnlock()
+	if conn == nil {
+		return status.Error(codes.Unavailable, "plugin is not connected over gRPC")
+	}
+
+	ctx, cancel := context.WithCancel(metadata.NewOutgoingContext(stream.Context(), md))
+	defer cancel()
+	upstream, err := conn.NewStream(ctx, &grpc.StreamDesc{ServerStreams: true, ClientStreams: true}, method, grpc.ForceCodec(rawCodec{}))
+	if err != nil {
+		return err
+	}
+
+	// Client messages go up until the client is done; a failure ends the call
+	go func() {
+		for {
+			frame := &rawFrame{}
+			if err := stream.RecvMsg(frame); err != nil {
+				if errors.Is(err, io.EOF) {
+					upstream.CloseSend()
+				} else {
+					cancel()
+				}
+				return
+			}
+			if err := upstream.SendMsg(frame); err != nil {
+				cancel()
+				return
+			}
+		}
+	}()
+
+	for sentHeader := false; ; {
+		frame := &rawFrame{}
+		if err := upstream.RecvMsg(frame); err != nil {
+			stream.SetTrailer(upstream.Trailer())
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		if !sentHeader {
+			if header, err := upstream.Header(); err == nil {
+				stream.SendHeader(header)
+			}
+			sentHeader = true
+		}
+		if err := stream.SendMsg(frame); err != nil {
+			return err
+		}
+	}
+}
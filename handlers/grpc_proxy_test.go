@@ -0,0 +1,115 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+func TestGRPCProxy(t *testing.T) {
+	// The plugin's side: a server reached over an in-memory connection
+	listener := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer()
+	healthpb.RegisterHealthServer(server, health.NewServer())
+	go server.Serve(listener)
+	defer server.Stop()
+	pluginConn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	defer pluginConn.Close()
+
+	handler := NewHandler(&mockBackend{}, newMockStorage(), hclog.NewNullLogger(), "plugin")
+	tokens := NewTokenStore("root-token")
+	tokens.Enforce(true)
+	proxy := NewGRPCProxy(handler, tokens)
+	if err := proxy.Start("127.0.0.1:0"); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer proxy.Close()
+
+	conn, err := grpc.NewClient(proxy.Addr(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Failed to dial the proxy: %v", err)
+	}
+	defer conn.Close()
+	client := healthpb.NewHealthClient(conn)
+	withToken := metadata.AppendToOutgoingContext(context.Background(), "x-vault-token", "root-token")
+
+	if _, err := client.Check(withToken, &healthpb.HealthCheckRequest{}); status.Code(err) != codes.Unavailable {
+		t.Errorf("call without a plugin connection = %v, want Unavailable", err)
+	}
+	handler.SetGRPCConn(pluginConn)
+
+	resp, err := client.Check(withToken, &healthpb.HealthCheckRequest{})
+	if err != nil || resp.Status != healthpb.HealthCheckResponse_SERVING {
+		t.Fatalf("Check through the proxy = %v, %v", resp, err)
+	}
+	bearer := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer root-token")
+	if _, err := client.Check(bearer, &healthpb.HealthCheckRequest{}); err != nil {
+		t.Errorf("Check with a bearer token failed: %v", err)
+	}
+	if _, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{}); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Check without a token = %v, want PermissionDenied", err)
+	}
+
+	// Errors of the plugin come back as they are
+	if _, err := client.Check(withToken, &healthpb.HealthCheckRequest{Service: "missing"}); status.Code(err) != codes.NotFound {
+		t.Errorf("Check of an unknown service = %v, want NotFound", err)
+	}
+
+	// go-plugin's own services stay with the host
+	err = conn.Invoke(withToken, "/plugin.GRPCController/Shutdown", &emptypb.Empty{}, &emptypb.Empty{})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("GRPCController/Shutdown = %v, want PermissionDenied", err)
+	}
+}
+
+func TestHandlePluginConnection(t *testing.T) {
+	handler := NewHandler(&mockBackend{}, newMockStorage(), hclog.NewNullLogger(), "plugin")
+
+	w := httptest.NewRecorder()
+	handler.HandlePluginConnection(w, httptest.NewRequest("GET", "/v1/sys/plugin/connection", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("status without a plugin = %d, want 503", w.Code)
+	}
+
+	handler.SetPluginConnection(&PluginConnection{Protocol: "grpc", ProtocolVersion: 5, Network: "unix", Address: "/tmp/plugin.sock", AutoMTLS: true, PID: 42})
+	proxy := NewGRPCProxy(handler, nil)
+	if err := proxy.Start("127.0.0.1:0"); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer proxy.Close()
+
+	w = httptest.NewRecorder()
+	handler.HandlePluginConnection(w, httptest.NewRequest("GET", "/v1/sys/plugin/connection", nil))
+	var response struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	data := response.Data
+	if w.Code != http.StatusOK || data["protocol"] != "grpc" || data["protocol_version"] != float64(5) || data["address"] != "/tmp/plugin.sock" || data["proxy_address"] != proxy.Addr() {
+		t.Errorf("connection = %d, %s", w.Code, w.Body.String())
+	}
+}
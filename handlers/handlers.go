@@ -4,6 +4,7 @@
 package handlers
 
 import (
+	"bufio"
 	"context"
 	"crypto/rand"
 	"encoding/hex"
@@ -11,14 +12,18 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"vault-plugin-host/leases"
+
 	"github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/logical"
+	"google.golang.org/grpc"
 )
 
 // PluginBackend interface defines the methods needed from the plugin
@@ -34,37 +39,80 @@ type StorageView interface {
 	Delete(ctx context.Context, key string) error
 }
 
+const (
+	// StorageNextHeader carries the cursor for the next page of /v1/sys/storage
+	StorageNextHeader = "X-Vault-Host-Storage-Next"
+	// storageFlushInterval is the number of storage entries written between flushes
+	storageFlushInterval = 500
+)
+
 // LeaseInfo stores lease information
-type LeaseInfo struct {
-	LeaseID    string                 `json:"lease_id"`
-	Path       string                 `json:"path"`
-	Data       map[string]interface{} `json:"data"`
-	Secret     *logical.Secret        `json:"secret"`
-	IssueTime  time.Time              `json:"issue_time"`
-	ExpireTime time.Time              `json:"expire_time"`
-	Duration   time.Duration          `json:"duration"`
-	Renewable  bool                   `json:"renewable"`
-}
+type LeaseInfo = leases.Lease
 
 // Handler manages HTTP requests and forwards them to the plugin
 type Handler struct {
-	backend   PluginBackend
-	storage   StorageView
-	logger    hclog.Logger
-	mountPath string
-	mu        sync.RWMutex
-	leases    map[string]*LeaseInfo // lease storage
-	leaseMu   sync.RWMutex          // separate mutex for lease operations
+	backend     PluginBackend
+	storage     StorageView
+	logger      hclog.Logger
+	mountPath   string
+	mu          sync.RWMutex
+	leases      *leases.Manager     // lease storage
+	leaseStats  *leases.Stats       // lease TTL, renewal and lifetime analytics
+	examples    *ExampleRecorder    // optional request/response recorder for OpenAPI examples
+	breaker     *CircuitBreaker     // optional fast failure of paths that keep failing
+	activity    *ActivityLog        // optional request and client counters
+	clients     *ClientFingerprints // optional per-client statistics
+	traceOutput io.Writer           // destination of per-request trace logs
+	tokens      *TokenStore         // optional token check for plugin requests
+	wraps       *WrapStore          // optional response wrapping
+	replication *Replication        // optional replication state; secondaries reject writes
+	faults      *StorageFaults      // optional latency and errors injected into plugin storage
+	logs        *PluginLogs         // what the plugin process writes to stdout and stderr
+	audit       *AuditBroker        // optional audit devices recording plugin requests and responses
+	clock       *Clock              // optional skew of timestamps reported to the plugin
+	passwords   *PasswordPolicies   // optional password policies for the plugin's system view
+	events      *EventBus           // optional bus for the events the plugin sends
+
+	unauthPaths   []string // the plugin's unauthenticated special paths, loaded on first use
+	unauthLoaded  bool
+	backendEpoch  int               // counts SetBackend calls, so paths of a replaced backend are not kept
+	grpcConn      *grpc.ClientConn  // raw plugin connection for the debug RPC console
+	connection    *PluginConnection // how the host reaches the plugin, for sys/plugin/connection
+	setup         *BackendSetup     // how the host set up the backend, for sys/host/backend
+	grpcProxyAddr string            // where a GRPCProxy publishes the plugin's gRPC services
+	pprofAddr     string            // plugin pprof listener address for the profiling proxy
+
+	requestTimeout   time.Duration     // default deadline for plugin requests (0 means none)
+	defaultLeaseTTL  time.Duration     // mount lease TTL for secrets without one (0 inherits the system default)
+	maxLeaseTTL      time.Duration     // mount cap on lease lifetimes (0 inherits the system default)
+	responseWrapTTL  time.Duration     // wrap TTL of responses whose request asks for none (0 leaves them unwrapped)
+	maxWrapTTL       time.Duration     // cap on the wrap TTL a request may ask for (0 means none)
+	canonicalJSON    bool              // write JSON responses in canonical form
+	operationHeaders bool              // report the operation and its cost in response headers
+	exportPassphrase string            // encrypts storage snapshot downloads when set
+	pluginType       string            // mount type reported by sys/mounts, the plugin's name
+	mountOptions     map[string]string // mount options reported by sys/mounts, such as a KV version
+
+	passthroughHeaders []string     // request headers passed to the plugin
+	responseHeaders    []string     // plugin response headers sent on to the client
+	restarts           RestartStats // crashes and automatic restarts of the plugin
+	storageLeaks       LeakReport   // storage left behind by revoked leases
+
+	inflight   map[uint64]*InflightCall // backend requests currently in progress
+	inflightMu sync.Mutex
+	nextCallID uint64
 }
 
 // NewHandler creates a new HTTP handler
 func NewHandler(backend PluginBackend, storage StorageView, logger hclog.Logger, mountPath string) *Handler {
 	return &Handler{
-		backend:   backend,
-		storage:   storage,
-		logger:    logger,
-		mountPath: mountPath,
-		leases:    make(map[string]*LeaseInfo),
+		backend:    backend,
+		storage:    storage,
+		logger:     logger,
+		mountPath:  mountPath,
+		leases:     leases.NewManager(),
+		leaseStats: leases.NewStats(),
+		inflight:   make(map[uint64]*InflightCall),
 	}
 }
 
@@ -73,6 +121,66 @@ func (h *Handler) SetBackend(backend PluginBackend) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 	h.backend = backend
+	h.unauthLoaded = false
+	h.backendEpoch++
+}
+
+// SetActivityLog sets the log that counts requests and clients; it may be shared by several mounts
+func (h *Handler) SetActivityLog(activity *ActivityLog) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.activity = activity
+}
+
+// SetStorage replaces the storage handed to the plugin; it must be called before serving requests
+func (h *Handler) SetStorage(storage StorageView) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.storage = storage
+}
+
+// StorageEntry reads one entry of the plugin's storage; it is nil when the key is unset
+func (h *Handler) StorageEntry(ctx context.Context, key string) (*logical.StorageEntry, error) {
+	h.mu.RLock()
+	storage := h.storage
+	h.mu.RUnlock()
+	return storage.Get(ctx, key)
+}
+
+// SetPluginType sets the type reported for the mount, as Vault reports a catalog
+// plugin's name
+func (h *Handler) SetPluginType(pluginType string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.pluginType = pluginType
+}
+
+// SetMountOptions sets the options reported for the mount, such as {"version": "2"},
+// which clients like Vault Agent read to tell a KV v2 mount from a KV v1 one
+func (h *Handler) SetMountOptions(options map[string]string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.mountOptions = options
+}
+
+// MountOptions returns the options reported for the mount, nil when none were set
+func (h *Handler) MountOptions() map[string]string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if len(h.mountOptions) == 0 {
+		return nil
+	}
+	return h.mountOptions
+}
+
+// PluginType returns the type reported for the mount, "plugin" when none was set
+func (h *Handler) PluginType() string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if h.pluginType == "" {
+		return "plugin"
+	}
+	return h.pluginType
 }
 
 // HandleRequest handles an HTTP request and forwards it to the plugin
@@ -84,8 +192,51 @@ func (h *Handler) HandleRequest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	backend := h.backend
+	activity := h.activity
+	tokens := h.tokens
+	wraps := h.wraps
+	responseWrapTTL, maxWrapTTL := h.responseWrapTTL, h.maxWrapTTL
+	replication := h.replication
+	faults := h.faults
+	audit := h.audit
+	clients := h.clients
+	passthroughHeaders := h.passthroughHeaders
+	responseHeaders := h.responseHeaders
+	breaker := h.breaker
+	operationHeaders := h.operationHeaders
 	h.mu.RUnlock()
 
+	if clients != nil {
+		sw := &statusWriter{ResponseWriter: w}
+		w = sw
+		defer func() {
+			status := sw.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			clients.Record(r, status)
+		}()
+	}
+
+	trace := newRequestTrace(time.Now())
+
+	// A request can ask for trace-level logging of just itself
+	traceLog, traceID := h.traceLogger(r)
+	if traceLog != nil {
+		trace.logger = traceLog
+		w.Header().Set(TraceIDHeader, traceID)
+		sw := &statusWriter{ResponseWriter: w}
+		w = sw
+		defer traceFinished(traceLog, sw, trace, trace.start)
+		traceLog.Trace("request received", "method", r.Method, "path", r.URL.Path)
+	}
+
+	timeout, err := h.timeoutFor(r)
+	if err != nil {
+		h.writeVaultError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	// Parse the request
 	var requestData map[string]interface{}
 	if r.Method == http.MethodPost || r.Method == http.MethodPut {
@@ -129,15 +280,16 @@ func (h *Handler) HandleRequest(w http.ResponseWriter, r *http.Request) {
 		path = strings.TrimSuffix(path, "/rotate")
 	} else {
 		// Standard HTTP method-based operations
-		switch r.Method {
-		case http.MethodGet:
+		switch {
+		case isListRequest(r):
+			operation = logical.ListOperation
+			path = listPath(path)
+		case r.Method == http.MethodGet:
 			operation = logical.ReadOperation
-		case http.MethodPost, http.MethodPut:
+		case r.Method == http.MethodPost, r.Method == http.MethodPut:
 			operation = logical.UpdateOperation
-		case http.MethodDelete:
+		case r.Method == http.MethodDelete:
 			operation = logical.DeleteOperation
-		case "LIST":
-			operation = logical.ListOperation
 		default:
 			h.writeVaultError(w, http.StatusMethodNotAllowed, "unsupported method")
 			return
@@ -146,79 +298,210 @@ func (h *Handler) HandleRequest(w http.ResponseWriter, r *http.Request) {
 
 	h.logger.Debug("handling request", "method", r.Method, "path", path, "operation", operation)
 
+	// Validate a requested wrap TTL before the plugin sees the request
+	var wrapTTL time.Duration
+	if header := r.Header.Get(WrapTTLHeader); header != "" {
+		if wraps == nil {
+			h.writeVaultError(w, http.StatusBadRequest, "response wrapping is not enabled")
+			return
+		}
+		ttl, err := parseTTL(header)
+		if err != nil || ttl <= 0 {
+			h.writeVaultError(w, http.StatusBadRequest, fmt.Sprintf("error parsing wrap TTL %q", header))
+			return
+		}
+		if maxWrapTTL > 0 && ttl > maxWrapTTL {
+			h.writeVaultError(w, http.StatusBadRequest, fmt.Sprintf("wrap TTL %s exceeds the mount's max wrap TTL %s", ttl, maxWrapTTL))
+			return
+		}
+		wrapTTL = ttl
+	} else if wraps != nil {
+		// The mount wraps responses by default, as a Vault policy's min_wrapping_ttl forces
+		wrapTTL = responseWrapTTL
+	}
+
+	// Check the client token unless the plugin marks the path as unauthenticated
+	clientToken := requestToken(r)
+	var token TokenEntry
+	if tokens != nil {
+		var valid bool
+		token, valid = tokens.Use(clientToken)
+		if !valid && tokens.Enforced() && !h.unauthenticated(path) {
+			h.writeVaultError(w, http.StatusForbidden, "permission denied")
+			return
+		}
+	}
+
 	// Create logical request
 	req := &logical.Request{
-		Operation: operation,
-		Path:      path,
-		Storage:   h.storage,
-		Data:      requestData,
+		ID:                       traceID,
+		Operation:                operation,
+		Path:                     path,
+		Storage:                  &tracedStorage{storage: replication.Storage(faults.Storage(h.storage)), trace: trace},
+		Data:                     requestData,
+		ClientToken:              clientToken,
+		ClientTokenAccessor:      token.Accessor,
+		EntityID:                 token.EntityID,
+		ClientTokenRemainingUses: token.NumUses,
+		Headers:                  filteredHeaders(r.Header, passthroughHeaders, deniedPassthroughHeaders),
+		Connection:               requestConnection(r),
+	}
+	if wrapTTL > 0 {
+		req.WrapInfo = &logical.RequestWrapInfo{TTL: wrapTTL}
+	}
+	if clientToken != "" {
+		req.ClientTokenSource = logical.ClientTokenFromVaultHeader
+		if r.Header.Get("X-Vault-Token") == "" {
+			req.ClientTokenSource = logical.ClientTokenFromAuthzHeader
+		}
+	}
+	if traceLog != nil {
+		traceLog.Trace("dispatching to plugin", "operation", operation, "path", path, "data", requestData)
+	}
+
+	// Audit the request now and the response on the way out, whichever way that is
+	response := make(map[string]interface{})
+	if audit != nil {
+		if auditErr := audit.LogRequest(r, req, token, h.mountPath); auditErr != nil {
+			// As in Vault, a request no audit device could record is not served
+			h.logger.Error("failed to audit request", "error", auditErr, "path", path)
+			h.writeVaultError(w, http.StatusInternalServerError, "failed to audit request")
+			return
+		}
+		defer func() {
+			if auditErr := audit.LogResponse(r, req, token, h.mountPath, response, err); auditErr != nil {
+				h.logger.Error("failed to audit response", "error", auditErr, "path", path)
+			}
+		}()
+	}
+
+	// A path that keeps failing is failed fast until its breaker's cool-down passes
+	if breaker != nil {
+		if status, allowed := breaker.allow(path); !allowed {
+			err = status.err()
+			h.writeBreakerOpen(w, status)
+			return
+		}
 	}
 
 	// Handle the request
 	ctx := context.Background()
-	resp, err := backend.HandleRequest(ctx, req)
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	resp, err := h.callWithDeadline(ctx, backend, req, trace)
+	if operationHeaders {
+		setOperationHeaders(w.Header(), operation, trace)
+	}
+	if activity != nil {
+		activity.Record(h.mountPath+"/", r, resp)
+	}
 
 	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			if breaker != nil {
+				breaker.record(path, fmt.Errorf("request timed out after %s", timeout))
+			}
+			h.writeTimeout(w, timeout, req, trace)
+			return
+		}
+
 		h.logger.Error("request failed", "error", err)
 
-		// Check if it's a permission denied error
-		if err == logical.ErrPermissionDenied {
-			h.writeVaultError(w, http.StatusForbidden, "permission denied")
+		// Writes on a secondary fail as they would against a Vault secondary
+		if isReadOnlyError(err) {
+			if breaker != nil {
+				breaker.record(path, nil)
+			}
+			h.writeReadOnly(w, r, replication)
 			return
 		}
 
-		h.writeVaultError(w, http.StatusInternalServerError, err.Error())
+		status, mapped := errorStatus(req, resp, err)
+		if breaker != nil {
+			if status >= http.StatusInternalServerError {
+				breaker.record(path, mapped)
+			} else {
+				breaker.record(path, nil)
+			}
+		}
+		h.writeVaultError(w, status, mapped.Error())
 		return
 	}
+	if breaker != nil {
+		breaker.record(path, nil)
+	}
 
-	// Build response
-	response := make(map[string]interface{})
+	// Only the response headers the mount allows reach the client
+	if resp != nil {
+		for name, values := range filteredHeaders(resp.Headers, responseHeaders, nil) {
+			for _, value := range values {
+				w.Header().Add(name, value)
+			}
+		}
+	}
 
+	if h.writeStatusResponse(w, req, resp) {
+		return
+	}
+
+	// Build response
 	if resp != nil {
 		if resp.Auth != nil {
-			authData := map[string]interface{}{
-				"client_token":   "mock-token-" + time.Now().Format("20060102150405"),
-				"accessor":       "mock-accessor",
-				"policies":       resp.Auth.Policies,
-				"metadata":       resp.Auth.Metadata,
-				"lease_duration": int(resp.Auth.TTL.Seconds()),
-				"renewable":      resp.Auth.Renewable,
+			if tokens != nil {
+				// Mint a real token that later requests and auth/token/* endpoints accept
+				issuedToken, issued := tokens.Issue(resp.Auth, strings.Trim(h.mountPath, "/")+"/"+path, h)
+				response["auth"] = authResponse(issuedToken, issued)
+			} else {
+				response["auth"] = map[string]interface{}{
+					"client_token":   "mock-token-" + time.Now().Format("20060102150405"),
+					"accessor":       "mock-accessor",
+					"policies":       resp.Auth.Policies,
+					"metadata":       resp.Auth.Metadata,
+					"lease_duration": int(resp.Auth.TTL.Seconds()),
+					"renewable":      resp.Auth.Renewable,
+				}
 			}
-			response["auth"] = authData
 		}
 
-		if resp.Data != nil {
+		if operation == logical.ListOperation {
+			response["data"] = listData(resp.Data)
+		} else if resp.Data != nil {
 			response["data"] = resp.Data
+		}
 
-			// Generate lease for read operations that return data
-			if operation == logical.ReadOperation {
-				leaseID := h.generateLeaseID(path)
-				leaseDuration := 24 * time.Hour // Default 24 hour lease
-				if resp.Secret != nil && resp.Secret.TTL > 0 {
-					leaseDuration = resp.Secret.TTL
-				}
-
-				// Store lease information
-				leaseInfo := &LeaseInfo{
-					LeaseID:    leaseID,
-					Path:       path,
-					Data:       resp.Data,
-					Secret:     resp.Secret, // Store the secret for renewal/revocation
-					IssueTime:  time.Now(),
-					ExpireTime: time.Now().Add(leaseDuration),
-					Duration:   leaseDuration,
-					Renewable:  true,
-				}
+		// As in Vault, only responses carrying a secret are leased
+		if resp.Secret != nil {
+			defaultTTL, maxTTL := h.leaseTTLs()
+			issued := time.Now()
+			leaseID := h.generateLeaseID(path)
+			leaseDuration := calculateLeaseTTL(0, resp.Secret.TTL, resp.Secret.MaxTTL, defaultTTL, maxTTL, issued, issued)
+
+			// Store lease information; the secret keeps its InternalData for renewal and revocation
+			secret := *resp.Secret
+			secret.LeaseID = leaseID
+			secret.TTL = leaseDuration
+			leaseInfo := &LeaseInfo{
+				LeaseID:    leaseID,
+				Path:       path,
+				Data:       resp.Data,
+				Secret:     &secret,
+				IssueTime:  issued,
+				ExpireTime: issued.Add(leaseDuration),
+				Duration:   leaseDuration,
+				Renewable:  secret.Renewable,
+				Writes:     trace.writtenKeys(),
+			}
 
-				h.leaseMu.Lock()
-				h.leases[leaseID] = leaseInfo
-				h.leaseMu.Unlock()
+			h.leases.Put(leaseInfo)
+			h.leaseStats.Issued(leaseInfo)
 
-				// Add lease information to response (matching Vault format)
-				response["lease_id"] = leaseID
-				response["lease_duration"] = int(leaseDuration.Seconds())
-				response["renewable"] = true
-			}
+			// Add lease information to response (matching Vault format)
+			response["lease_id"] = leaseID
+			response["lease_duration"] = int(leaseDuration.Seconds())
+			response["renewable"] = secret.Renewable
 		}
 
 		// Only include warnings if they exist
@@ -229,20 +512,46 @@ func (h *Handler) HandleRequest(w http.ResponseWriter, r *http.Request) {
 		// Add standard Vault response fields
 		response["request_id"] = h.generateRequestID()
 		response["wrap_info"] = nil
-		response["auth"] = nil
+		if _, ok := response["auth"]; !ok {
+			response["auth"] = nil
+		}
 		// Add mount type from the mount path (the part before the first slash)
 		response["mount_type"] = strings.TrimPrefix(h.mountPath, "/")
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(response)
+	if wrapTTL > 0 && resp != nil {
+		// Return a wrapping token in place of the response, as Vault does
+		info, err := wraps.Wrap(context.Background(), response, wrapTTL, strings.Trim(h.mountPath, "/")+"/"+path)
+		if err != nil {
+			h.writeVaultError(w, http.StatusInternalServerError, fmt.Sprintf("failed to wrap response: %v", err))
+			return
+		}
+		wrapped := map[string]interface{}{
+			"request_id":     response["request_id"],
+			"lease_id":       "",
+			"renewable":      false,
+			"lease_duration": 0,
+			"data":           nil,
+			"wrap_info":      wrapInfoResponse(info),
+			"warnings":       nil,
+			"auth":           nil,
+		}
+		h.writeEncoded(w, r, http.StatusOK, wrapped)
+		h.recordExample(r.Method, path, requestData, response, http.StatusOK)
+		response = wrapped // the audit log records the response the client received
+		return
+	}
+
+	h.writeEncoded(w, r, http.StatusOK, response)
+
+	h.recordExample(r.Method, path, requestData, response, http.StatusOK)
 }
 
 // HandleHealth handles health check requests
 func (h *Handler) HandleHealth(w http.ResponseWriter, r *http.Request) {
 	h.mu.RLock()
 	running := h.backend != nil
+	restarts := h.restarts
 	h.mu.RUnlock()
 
 	// Get storage entry count
@@ -256,39 +565,105 @@ func (h *Handler) HandleHealth(w http.ResponseWriter, r *http.Request) {
 	status := map[string]interface{}{
 		"plugin_running":  running,
 		"storage_entries": entryCount,
+		"restarts":        restarts,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(status)
 }
 
-// HandleStorage shows storage contents
+// HandleStorage streams storage contents as a JSON array of key/value objects.
+//
+// Entries are sorted by key and written as they are read, so large datasets are never
+// held in memory as a whole. The optional query parameters page through the results:
+//
+//	prefix - only include keys with this prefix
+//	after  - only include keys sorting after this key
+//	limit  - return at most this many entries
+//
+// When a page is truncated by limit the last key is returned in the StorageNextHeader header.
 func (h *Handler) HandleStorage(w http.ResponseWriter, r *http.Request) {
-	ctx := context.Background()
-	keys, err := h.storage.List(ctx, "")
+	query := r.URL.Query()
+
+	limit := 0
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			h.writeVaultError(w, http.StatusBadRequest, fmt.Sprintf("invalid limit %q", v))
+			return
+		}
+		limit = n
+	}
+
+	ctx := r.Context()
+	keys, err := h.storage.List(ctx, query.Get("prefix"))
 	if err != nil {
 		h.writeVaultError(w, http.StatusInternalServerError, fmt.Sprintf("failed to list storage: %v", err))
 		return
 	}
+	sort.Strings(keys)
+
+	if after := query.Get("after"); after != "" {
+		keys = keys[sort.Search(len(keys), func(i int) bool { return keys[i] > after }):]
+	}
+	if limit > 0 && len(keys) > limit {
+		keys = keys[:limit]
+		w.Header().Set(StorageNextHeader, keys[limit-1])
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := h.streamStorage(ctx, w, keys); err != nil {
+		h.logger.Warn("storage stream aborted", "error", err)
+	}
+}
+
+// streamStorage writes the entries for keys as a JSON array, flushing periodically
+func (h *Handler) streamStorage(ctx context.Context, w http.ResponseWriter, keys []string) error {
+	flusher, _ := w.(http.Flusher)
+	bw := bufio.NewWriter(w)
 
-	// Return array of key-value objects for frontend
-	data := make([]map[string]string, 0, len(keys))
+	bw.WriteByte('[')
+	written := 0
 	for _, key := range keys {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
 		entry, err := h.storage.Get(ctx, key)
 		if err != nil {
 			h.logger.Warn("failed to get storage entry", "key", key, "error", err)
 			continue
 		}
-		if entry != nil {
-			data = append(data, map[string]string{
-				"key":   key,
-				"value": string(entry.Value),
-			})
+		if entry == nil {
+			continue
 		}
-	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(data)
+		item, err := json.Marshal(map[string]string{
+			"key":   key,
+			"value": string(entry.Value),
+		})
+		if err != nil {
+			return err
+		}
+		if written > 0 {
+			bw.WriteByte(',')
+		}
+		bw.Write(item)
+		written++
+
+		if written%storageFlushInterval == 0 {
+			if err := bw.Flush(); err != nil {
+				return err
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+	}
+	bw.WriteString("]\n")
+	return bw.Flush()
 }
 
 // HandleOpenAPI returns the OpenAPI document from the plugin with corrected paths
@@ -332,6 +707,14 @@ func (h *Handler) HandleOpenAPI(w http.ResponseWriter, r *http.Request, oasDoc i
 		}
 	}
 
+	// Merge recorded examples while paths are still relative to the mount
+	h.mu.RLock()
+	recorder := h.examples
+	h.mu.RUnlock()
+	if recorder != nil {
+		mergeExamples(docMap, recorder.Examples())
+	}
+
 	// Fix the paths to include /v1/{mount} prefix
 	if paths, ok := docMap["paths"].(map[string]interface{}); ok {
 		newPaths := make(map[string]interface{})
@@ -360,6 +743,11 @@ func (h *Handler) generateLeaseID(path string) string {
 
 // generateRequestID generates a unique request ID in UUID format
 func (h *Handler) generateRequestID() string {
+	return newRequestID()
+}
+
+// newRequestID returns a random UUID-formatted request ID
+func newRequestID() string {
 	bytes := make([]byte, 16)
 	rand.Read(bytes)
 	// Format as UUID: xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx
@@ -369,12 +757,22 @@ func (h *Handler) generateRequestID() string {
 
 // writeVaultError writes an error response in Vault's JSON format
 func (h *Handler) writeVaultError(w http.ResponseWriter, statusCode int, message string) {
+	WriteError(w, statusCode, message)
+}
+
+// WriteError writes an error response in Vault's JSON format
+func WriteError(w http.ResponseWriter, statusCode int, message string) {
 	errorResponse := map[string]interface{}{
 		"errors": []string{message},
 	}
+	WriteJSON(w, statusCode, errorResponse)
+}
+
+// WriteJSON writes v as a JSON response with the given status code
+func WriteJSON(w http.ResponseWriter, statusCode int, v interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
-	json.NewEncoder(w).Encode(errorResponse)
+	json.NewEncoder(w).Encode(v)
 }
 
 // HandleLeaseRenew handles lease renewal requests at /v1/sys/leases/renew
@@ -422,14 +820,7 @@ func (h *Handler) HandleLeaseRenew(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	// Default increment is the original lease duration
-	if increment == 0 {
-		increment = 24 * time.Hour
-	}
-
-	h.leaseMu.Lock()
-	leaseInfo, exists := h.leases[leaseID]
-	h.leaseMu.Unlock()
+	leaseInfo, exists := h.leases.Get(leaseID)
 
 	if !exists {
 		h.writeVaultError(w, http.StatusNotFound, "lease not found")
@@ -448,49 +839,83 @@ func (h *Handler) HandleLeaseRenew(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Calculate new expiration time but don't update yet
-	newExpireTime := time.Now().Add(increment)
-
 	// Notify plugin backend about lease renewal
 	h.mu.RLock()
 	backend := h.backend
+	clock := h.clock
 	h.mu.RUnlock()
 
+	var secret logical.Secret
+	if leaseInfo.Secret != nil {
+		secret = *leaseInfo.Secret
+	}
+	renewIncrement := increment
 	if backend != nil {
-		// Create renewal request for the plugin
+		// Create renewal request for the plugin, which decides the new TTL as in Vault
+		reported := clock.reportedSecret(leaseInfo)
+		if reported != nil {
+			reported.Increment = increment
+		}
 		renewReq := &logical.Request{
 			Operation: logical.RenewOperation,
 			Path:      leaseInfo.Path,
 			Storage:   h.storage,
-			Secret:    leaseInfo.Secret, // Include the secret
+			Secret:    reported, // Include the secret with its InternalData
 			Data: map[string]interface{}{
 				"lease_id":   leaseID,
 				"increment":  int(increment.Seconds()),
-				"issue_time": leaseInfo.IssueTime,
+				"issue_time": clock.Reported(leaseInfo.IssueTime),
 			},
 		}
 
 		ctx := context.Background()
-		if _, err := backend.HandleRequest(ctx, renewReq); err != nil {
+		resp, err := h.callBackend(ctx, backend, renewReq)
+		if err != nil {
 			h.logger.Error("plugin renewal notification failed", "error", err, "lease_id", leaseID)
 			h.writeVaultError(w, http.StatusInternalServerError, fmt.Sprintf("failed to renew lease: %v", err))
 			return
 		}
+		if resp != nil && resp.IsError() {
+			h.writeVaultError(w, http.StatusBadRequest, resp.Error().Error())
+			return
+		}
+		if resp != nil && resp.Secret != nil {
+			// The plugin's secret replaces the lease's, along with its InternalData
+			secret = *resp.Secret
+			secret.LeaseID = leaseID
+			renewIncrement = 0
+		}
 	}
 
+	now := time.Now()
+	defaultTTL, maxTTL := h.leaseTTLs()
+	ttl := calculateLeaseTTL(renewIncrement, secret.TTL, secret.MaxTTL, defaultTTL, maxTTL, leaseInfo.IssueTime, now)
+	secret.TTL = ttl
+	renewable := leaseInfo.Secret == nil || secret.Renewable
+
 	// Plugin succeeded, now update the lease
-	h.leaseMu.Lock()
-	h.leases[leaseID].ExpireTime = newExpireTime
-	h.leases[leaseID].Duration = increment
-	h.leaseMu.Unlock()
+	if _, ok := h.leases.Update(leaseID, func(lease *LeaseInfo) {
+		lease.ExpireTime = now.Add(ttl)
+		lease.LastRenewal = now
+		lease.Renewals++
+		lease.Duration = ttl
+		lease.Renewable = renewable
+		if lease.Secret != nil {
+			lease.Secret = &secret
+		}
+	}); !ok {
+		h.writeVaultError(w, http.StatusNotFound, "lease not found")
+		return
+	}
+	h.leaseStats.Renewed()
 
-	h.logger.Info("lease renewed", "lease_id", leaseID, "increment", increment, "new_expire_time", newExpireTime)
+	h.logger.Info("lease renewed", "lease_id", leaseID, "increment", increment, "new_expire_time", now.Add(ttl))
 
 	// Build response
 	response := map[string]interface{}{
 		"lease_id":       leaseID,
-		"lease_duration": int(increment.Seconds()),
-		"renewable":      true,
+		"lease_duration": int(ttl.Seconds()),
+		"renewable":      renewable,
 		"data":           leaseInfo.Data,
 	}
 
@@ -528,47 +953,51 @@ func (h *Handler) HandleLeaseRevoke(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.leaseMu.Lock()
-	leaseInfo, exists := h.leases[leaseID]
-	if exists {
-		delete(h.leases, leaseID)
-	}
-	h.leaseMu.Unlock()
+	leaseInfo, exists := h.leases.Take(leaseID)
 
 	if !exists {
 		h.writeVaultError(w, http.StatusNotFound, "lease not found")
 		return
 	}
+	h.leaseStats.Ended(leaseInfo, leases.EndRevoked, time.Now())
+
+	// Notify plugin backend about lease revocation; the response is sent even if this fails
+	if err := h.notifyRevoke(context.Background(), leaseInfo); err != nil {
+		h.logger.Error("plugin revocation notification failed", "error", err, "lease_id", leaseID)
+	}
+
+	h.logger.Info("lease revoked", "lease_id", leaseID)
 
-	// Notify plugin backend about lease revocation
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// notifyRevoke sends a revocation request for a lease to the plugin, if one is running
+func (h *Handler) notifyRevoke(ctx context.Context, leaseInfo *LeaseInfo) error {
 	h.mu.RLock()
 	backend := h.backend
+	clock := h.clock
 	h.mu.RUnlock()
 
-	if backend != nil {
-		// Create revocation request for the plugin
-		revokeReq := &logical.Request{
-			Operation: logical.RevokeOperation,
-			Path:      leaseInfo.Path,
-			Storage:   h.storage,
-			Secret:    leaseInfo.Secret, // Include the secret
-			Data: map[string]interface{}{
-				"lease_id":   leaseID,
-				"issue_time": leaseInfo.IssueTime,
-				"data":       leaseInfo.Data,
-			},
-		}
-
-		ctx := context.Background()
-		if _, err := backend.HandleRequest(ctx, revokeReq); err != nil {
-			h.logger.Error("plugin revocation notification failed", "error", err, "lease_id", leaseID)
-			// Continue with the response even if plugin notification fails
-		}
+	if backend == nil {
+		return nil
 	}
 
-	h.logger.Info("lease revoked", "lease_id", leaseID)
+	trace := newRequestTrace(time.Now())
+	revokeReq := &logical.Request{
+		Operation: logical.RevokeOperation,
+		Path:      leaseInfo.Path,
+		Storage:   &tracedStorage{storage: h.storage, trace: trace},
+		Secret:    clock.reportedSecret(leaseInfo), // Include the secret
+		Data: map[string]interface{}{
+			"lease_id":   leaseInfo.LeaseID,
+			"issue_time": clock.Reported(leaseInfo.IssueTime),
+			"data":       leaseInfo.Data,
+		},
+	}
 
-	w.WriteHeader(http.StatusNoContent)
+	_, err := h.callBackend(ctx, backend, revokeReq)
+	h.checkRevokedStorage(ctx, leaseInfo, trace, err)
+	return err
 }
 
 // HandleLeaseRevokeByPath handles lease revocation requests at /v1/sys/leases/revoke/<lease_id>
@@ -588,42 +1017,17 @@ func (h *Handler) HandleLeaseRevokeByPath(w http.ResponseWriter, r *http.Request
 
 	leaseID := path
 
-	h.leaseMu.Lock()
-	leaseInfo, exists := h.leases[leaseID]
-	if exists {
-		delete(h.leases, leaseID)
-	}
-	h.leaseMu.Unlock()
+	leaseInfo, exists := h.leases.Take(leaseID)
 
 	if !exists {
 		h.writeVaultError(w, http.StatusNotFound, "lease not found")
 		return
 	}
+	h.leaseStats.Ended(leaseInfo, leases.EndRevoked, time.Now())
 
-	// Notify plugin backend about lease revocation
-	h.mu.RLock()
-	backend := h.backend
-	h.mu.RUnlock()
-
-	if backend != nil {
-		// Create revocation request for the plugin
-		revokeReq := &logical.Request{
-			Operation: logical.RevokeOperation,
-			Path:      leaseInfo.Path,
-			Storage:   h.storage,
-			Secret:    leaseInfo.Secret, // Include the secret
-			Data: map[string]interface{}{
-				"lease_id":   leaseID,
-				"issue_time": leaseInfo.IssueTime,
-				"data":       leaseInfo.Data,
-			},
-		}
-
-		ctx := context.Background()
-		if _, err := backend.HandleRequest(ctx, revokeReq); err != nil {
-			h.logger.Error("plugin revocation notification failed", "error", err, "lease_id", leaseID)
-			// Continue with the response even if plugin notification fails
-		}
+	// Notify plugin backend about lease revocation; the response is sent even if this fails
+	if err := h.notifyRevoke(context.Background(), leaseInfo); err != nil {
+		h.logger.Error("plugin revocation notification failed", "error", err, "lease_id", leaseID)
 	}
 
 	h.logger.Info("lease revoked", "lease_id", leaseID)
@@ -9,6 +9,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/hashicorp/go-hclog"
@@ -57,18 +58,13 @@ func TestHandleStorageEmpty(t *testing.T) {
 		t.Errorf("Status code = %d, want %d", w.Code, http.StatusOK)
 	}
 
-	var response map[string]interface{}
+	var response []map[string]string
 	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
 		t.Fatalf("Failed to decode response: %v", err)
 	}
 
-	storageData, ok := response["storage"].(map[string]interface{})
-	if !ok {
-		t.Fatal("Response missing 'storage' field")
-	}
-
-	if len(storageData) != 0 {
-		t.Errorf("storage should be empty, got %d entries", len(storageData))
+	if len(response) != 0 {
+		t.Errorf("storage should be empty, got %d entries", len(response))
 	}
 }
 
@@ -96,22 +92,75 @@ func TestHandleStorageWithData(t *testing.T) {
 		t.Errorf("Status code = %d, want %d", w.Code, http.StatusOK)
 	}
 
-	var response map[string]interface{}
+	var response []map[string]string
 	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
 		t.Fatalf("Failed to decode response: %v", err)
 	}
 
-	storageData, ok := response["storage"].(map[string]interface{})
-	if !ok {
-		t.Fatal("Response missing 'storage' field")
+	if len(response) != 1 {
+		t.Fatalf("storage should have 1 entry, got %d", len(response))
 	}
 
-	if len(storageData) != 1 {
-		t.Errorf("storage should have 1 entry, got %d", len(storageData))
+	if response[0]["key"] != "test-key" || response[0]["value"] != "test-value" {
+		t.Errorf("storage[0] = %v, want test-key=test-value", response[0])
 	}
+}
+
+func TestHandleStoragePagination(t *testing.T) {
+	storage := newMockStorage()
+	logger := hclog.NewNullLogger()
+	handler := NewHandler(nil, storage, logger, "plugin")
+
+	ctx := context.Background()
+	for _, key := range []string{"b", "a", "config/x", "c", "config/y"} {
+		storage.Put(ctx, &logical.StorageEntry{Key: key, Value: []byte(key)})
+	}
+
+	fetch := func(query string) ([]string, string) {
+		req := httptest.NewRequest("GET", "/v1/sys/storage"+query, nil)
+		w := httptest.NewRecorder()
+		handler.HandleStorage(w, req)
 
-	if val, ok := storageData["test-key"].(string); !ok || val != "test-value" {
-		t.Errorf("storage[test-key] = %v, want test-value", val)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: status code = %d, want %d", query, w.Code, http.StatusOK)
+		}
+
+		var response []map[string]string
+		if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+			t.Fatalf("%s: failed to decode response: %v", query, err)
+		}
+		keys := make([]string, 0, len(response))
+		for _, item := range response {
+			keys = append(keys, item["key"])
+		}
+		return keys, w.Header().Get(StorageNextHeader)
+	}
+
+	var all []string
+	query := "?limit=2"
+	for {
+		keys, next := fetch(query)
+		all = append(all, keys...)
+		if next == "" {
+			break
+		}
+		query = "?limit=2&after=" + next
+	}
+
+	want := []string{"a", "b", "c", "config/x", "config/y"}
+	if strings.Join(all, ",") != strings.Join(want, ",") {
+		t.Errorf("paged keys = %v, want %v", all, want)
+	}
+
+	if keys, _ := fetch("?prefix=config/"); strings.Join(keys, ",") != "config/x,config/y" {
+		t.Errorf("prefix keys = %v", keys)
+	}
+
+	req := httptest.NewRequest("GET", "/v1/sys/storage?limit=abc", nil)
+	w := httptest.NewRecorder()
+	handler.HandleStorage(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("invalid limit: status code = %d, want %d", w.Code, http.StatusBadRequest)
 	}
 }
 
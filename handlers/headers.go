@@ -0,0 +1,61 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// deniedPassthroughHeaders are never passed to the plugin, even when listed, as in Vault
+var deniedPassthroughHeaders = []string{"X-Vault-Token"}
+
+// SetHeaderPassthrough sets the request headers passed to the plugin in
+// logical.Request.Headers and the headers of the plugin's responses sent on to the
+// client, like a Vault mount's passthrough_request_headers and allowed_response_headers
+func (h *Handler) SetHeaderPassthrough(request, response []string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.passthroughHeaders = request
+	h.responseHeaders = response
+}
+
+// filteredHeaders returns the headers whose names are in allowed and not in denied,
+// comparing names case-insensitively; nil when none are left
+func filteredHeaders(headers map[string][]string, allowed, denied []string) map[string][]string {
+	var filtered map[string][]string
+	for name, values := range headers {
+		if !containsHeader(allowed, name) || containsHeader(denied, name) {
+			continue
+		}
+		if filtered == nil {
+			filtered = make(map[string][]string)
+		}
+		filtered[name] = append([]string(nil), values...)
+	}
+	return filtered
+}
+
+func containsHeader(names []string, name string) bool {
+	for _, n := range names {
+		if strings.EqualFold(strings.TrimSpace(n), name) {
+			return true
+		}
+	}
+	return false
+}
+
+// requestConnection describes the client connection of r for logical.Request.Connection
+func requestConnection(r *http.Request) *logical.Connection {
+	conn := &logical.Connection{RemoteAddr: r.RemoteAddr, ConnState: r.TLS}
+	if host, port, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		conn.RemoteAddr = host
+		conn.RemotePort, _ = strconv.Atoi(port)
+	}
+	return conn
+}
@@ -0,0 +1,95 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestHeaderPassthrough(t *testing.T) {
+	var seen *logical.Request
+	backend := funcBackend(func(ctx context.Context, req *logical.Request) (*logical.Response, error) {
+		seen = req
+		return &logical.Response{
+			Data: map[string]interface{}{"ok": true},
+			Headers: map[string][]string{
+				"X-Plugin-Trace": {"abc"},
+				"X-Internal":     {"secret"},
+			},
+		}, nil
+	})
+	handler := NewHandler(backend, newMockStorage(), hclog.NewNullLogger(), "plugin")
+	store := NewTokenStore("root-token")
+	handler.SetTokenStore(store)
+
+	do := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/v1/plugin/config", nil)
+		req.RemoteAddr = "192.0.2.10:53124"
+		req.Header.Set("X-Tenant", "acme")
+		req.Header.Set("X-Other", "ignored")
+		req.Header.Set("X-Vault-Token", "root-token")
+		w := httptest.NewRecorder()
+		handler.HandleRequest(w, req)
+		return w
+	}
+
+	// Without passthrough the plugin sees no headers and sends none back
+	w := do()
+	if seen.Headers != nil || w.Header().Get("X-Plugin-Trace") != "" {
+		t.Errorf("headers passed without passthrough: request %v, response %v", seen.Headers, w.Header())
+	}
+	if seen.Connection == nil || seen.Connection.RemoteAddr != "192.0.2.10" || seen.Connection.RemotePort != 53124 {
+		t.Errorf("Connection = %+v, want 192.0.2.10:53124", seen.Connection)
+	}
+
+	// The token header is never passed, even when listed
+	handler.SetHeaderPassthrough([]string{"x-tenant", "X-Vault-Token"}, []string{"x-plugin-trace"})
+	w = do()
+	if want := map[string][]string{"X-Tenant": {"acme"}}; !reflect.DeepEqual(seen.Headers, want) {
+		t.Errorf("request headers = %v, want %v", seen.Headers, want)
+	}
+	if w.Header().Get("X-Plugin-Trace") != "abc" || w.Header().Get("X-Internal") != "" {
+		t.Errorf("response headers = %v, want only X-Plugin-Trace", w.Header())
+	}
+	if w.Code != http.StatusOK {
+		t.Errorf("status = %d, want 200", w.Code)
+	}
+}
+
+func TestHandleRequestTokenUses(t *testing.T) {
+	var seen *logical.Request
+	backend := funcBackend(func(ctx context.Context, req *logical.Request) (*logical.Response, error) {
+		seen = req
+		return &logical.Response{Data: map[string]interface{}{"ok": true}}, nil
+	})
+	handler := NewHandler(backend, newMockStorage(), hclog.NewNullLogger(), "plugin")
+	store := NewTokenStore("root-token")
+	store.Enforce(true)
+	handler.SetTokenStore(store)
+	token, _ := store.Issue(&logical.Auth{NumUses: 2}, "auth/token/create", nil)
+
+	for i, want := range []int{1, tokenRevocationPending} {
+		req := httptest.NewRequest("GET", "/v1/plugin/config", nil)
+		req.Header.Set("X-Vault-Token", token)
+		w := httptest.NewRecorder()
+		handler.HandleRequest(w, req)
+		if w.Code != http.StatusOK || seen.ClientTokenRemainingUses != want {
+			t.Errorf("use %d: status %d, remaining uses %d, want %d", i+1, w.Code, seen.ClientTokenRemainingUses, want)
+		}
+	}
+	req := httptest.NewRequest("GET", "/v1/plugin/config", nil)
+	req.Header.Set("X-Vault-Token", token)
+	w := httptest.NewRecorder()
+	handler.HandleRequest(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("exhausted token: status %d, want 403", w.Code)
+	}
+}
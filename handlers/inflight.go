@@ -0,0 +1,75 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"context"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// InflightCall describes a backend request that has not returned yet
+type InflightCall struct {
+	ID        uint64            `json:"id"`
+	Operation logical.Operation `json:"operation"`
+	Path      string            `json:"path"`
+	Started   time.Time         `json:"started"`
+
+	trace *requestTrace // of the request, when its storage is traced
+}
+
+// callBackend forwards a request to the plugin while tracking it as in flight
+func (h *Handler) callBackend(ctx context.Context, backend PluginBackend, req *logical.Request) (*logical.Response, error) {
+	call := &InflightCall{
+		ID:        atomic.AddUint64(&h.nextCallID, 1),
+		Operation: req.Operation,
+		Path:      req.Path,
+		Started:   time.Now(),
+	}
+	if storage, ok := req.Storage.(*tracedStorage); ok {
+		call.trace = storage.trace
+	}
+
+	h.inflightMu.Lock()
+	h.inflight[call.ID] = call
+	h.inflightMu.Unlock()
+
+	defer func() {
+		h.inflightMu.Lock()
+		delete(h.inflight, call.ID)
+		h.inflightMu.Unlock()
+	}()
+
+	return backend.HandleRequest(ctx, req)
+}
+
+// soleCallTrace returns the trace of the backend request in flight when it is the only
+// one, or nil
+func (h *Handler) soleCallTrace() *requestTrace {
+	h.inflightMu.Lock()
+	defer h.inflightMu.Unlock()
+	if len(h.inflight) != 1 {
+		return nil
+	}
+	for _, call := range h.inflight {
+		return call.trace
+	}
+	return nil
+}
+
+// InflightCalls returns the backend requests currently in progress, oldest first
+func (h *Handler) InflightCalls() []InflightCall {
+	h.inflightMu.Lock()
+	calls := make([]InflightCall, 0, len(h.inflight))
+	for _, call := range h.inflight {
+		calls = append(calls, *call)
+	}
+	h.inflightMu.Unlock()
+
+	sort.Slice(calls, func(i, j int) bool { return calls[i].Started.Before(calls[j].Started) })
+	return calls
+}
@@ -0,0 +1,104 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// maxStorageLeaks is the number of leaking revocations kept
+const maxStorageLeaks = 100
+
+// StorageLeak is a revoked lease whose storage was not cleaned up: keys the plugin
+// wrote while issuing the secret that still exist after the plugin handled the revoke
+type StorageLeak struct {
+	LeaseID     string    `json:"lease_id"`
+	Path        string    `json:"path"`
+	RevokedAt   time.Time `json:"revoked_at"`
+	Written     []string  `json:"written"`           // keys written when the lease was issued
+	Deleted     []string  `json:"deleted,omitempty"` // keys the revoke deleted
+	Remaining   []string  `json:"remaining"`         // written keys still in storage
+	RevokeError string    `json:"revoke_error,omitempty"`
+}
+
+// LeakReport counts revocations checked for leftover storage and keeps the ones
+// that left some behind
+type LeakReport struct {
+	Checked int           `json:"checked"`
+	Leaking int           `json:"leaking"`
+	Leaks   []StorageLeak `json:"leaks"`
+}
+
+// checkRevokedStorage compares the keys written when a lease was issued with storage
+// after the plugin handled its revocation. Keys that survive are probable leaks in the
+// plugin's revoke path, though shared state the issuing request also updated (a
+// counter, say) is reported too.
+func (h *Handler) checkRevokedStorage(ctx context.Context, lease *LeaseInfo, trace *requestTrace, revokeErr error) {
+	if len(lease.Writes) == 0 {
+		return
+	}
+
+	var remaining []string
+	for _, key := range lease.Writes {
+		entry, err := h.storage.Get(ctx, key)
+		if err == nil && entry != nil {
+			remaining = append(remaining, key)
+		}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.storageLeaks.Checked++
+	if len(remaining) == 0 {
+		return
+	}
+
+	leak := StorageLeak{
+		LeaseID:   lease.LeaseID,
+		Path:      lease.Path,
+		RevokedAt: time.Now().UTC(),
+		Written:   lease.Writes,
+		Deleted:   trace.deletedKeys(),
+		Remaining: remaining,
+	}
+	if revokeErr != nil {
+		leak.RevokeError = revokeErr.Error()
+	}
+	h.logger.Warn("revoked lease left storage behind", "lease_id", lease.LeaseID, "keys", remaining)
+
+	h.storageLeaks.Leaking++
+	h.storageLeaks.Leaks = append(h.storageLeaks.Leaks, leak)
+	if len(h.storageLeaks.Leaks) > maxStorageLeaks {
+		h.storageLeaks.Leaks = h.storageLeaks.Leaks[len(h.storageLeaks.Leaks)-maxStorageLeaks:]
+	}
+}
+
+// StorageLeaks returns the revocations checked for leftover storage so far
+func (h *Handler) StorageLeaks() LeakReport {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	report := h.storageLeaks
+	report.Leaks = append([]StorageLeak{}, report.Leaks...)
+	return report
+}
+
+// HandleStorageLeaks serves the storage leak report at /v1/sys/host/leases/leaks:
+//
+//	GET    - revocations checked and the ones that left storage behind
+//	DELETE - reset the report
+func (h *Handler) HandleStorageLeaks(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		WriteJSON(w, http.StatusOK, h.StorageLeaks())
+	case http.MethodDelete:
+		h.mu.Lock()
+		h.storageLeaks = LeakReport{}
+		h.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		h.writeVaultError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
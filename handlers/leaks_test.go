@@ -0,0 +1,90 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// credsBackend stores a record for each credential it issues and deletes it on
+// revocation only when cleanup is set
+func credsBackend(cleanup bool) funcBackend {
+	return func(ctx context.Context, req *logical.Request) (*logical.Response, error) {
+		switch req.Operation {
+		case logical.ReadOperation:
+			req.Storage.Put(ctx, &logical.StorageEntry{Key: "users/" + req.Path, Value: []byte("{}")})
+			req.Storage.Put(ctx, &logical.StorageEntry{Key: "wal/1", Value: []byte("{}")})
+			req.Storage.Delete(ctx, "wal/1")
+			return &logical.Response{
+				Data:   map[string]interface{}{"username": req.Path},
+				Secret: &logical.Secret{},
+			}, nil
+		case logical.RevokeOperation:
+			if cleanup {
+				req.Storage.Delete(ctx, "users/"+req.Path)
+			}
+		}
+		return nil, nil
+	}
+}
+
+// issueAndRevoke reads a credential and revokes its lease
+func issueAndRevoke(t *testing.T, handler *Handler, path string) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	handler.HandleRequest(w, httptest.NewRequest("GET", "/v1/plugin/"+path, nil))
+	var resp struct {
+		LeaseID string `json:"lease_id"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp.LeaseID == "" {
+		t.Fatalf("expected a lease, got %s", w.Body.String())
+	}
+
+	body, _ := json.Marshal(map[string]string{"lease_id": resp.LeaseID})
+	w = httptest.NewRecorder()
+	handler.HandleLeaseRevoke(w, httptest.NewRequest("PUT", "/v1/sys/leases/revoke", bytes.NewReader(body)))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("revoke failed: %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestRevokeDetectsStorageLeak(t *testing.T) {
+	handler := NewHandler(credsBackend(false), newMockStorage(), hclog.NewNullLogger(), "plugin")
+	issueAndRevoke(t, handler, "creds/test")
+
+	report := handler.StorageLeaks()
+	if report.Checked != 1 || report.Leaking != 1 || len(report.Leaks) != 1 {
+		t.Fatalf("expected one leaking revocation, got %+v", report)
+	}
+	leak := report.Leaks[0]
+	if leak.Path != "creds/test" || !reflect.DeepEqual(leak.Remaining, []string{"users/creds/test"}) {
+		t.Errorf("unexpected leak: %+v", leak)
+	}
+	if !reflect.DeepEqual(leak.Written, []string{"users/creds/test"}) {
+		t.Errorf("keys deleted by the issuing request should not count as written, got %v", leak.Written)
+	}
+}
+
+func TestRevokeWithCleanupIsNotALeak(t *testing.T) {
+	handler := NewHandler(credsBackend(true), newMockStorage(), hclog.NewNullLogger(), "plugin")
+	issueAndRevoke(t, handler, "creds/test")
+
+	w := httptest.NewRecorder()
+	handler.HandleStorageLeaks(w, httptest.NewRequest("GET", "/v1/sys/host/leases/leaks", nil))
+	var report LeakReport
+	json.Unmarshal(w.Body.Bytes(), &report)
+	if report.Checked != 1 || report.Leaking != 0 || len(report.Leaks) != 0 {
+		t.Errorf("a revoke that cleans up should not be reported, got %s", w.Body.String())
+	}
+}
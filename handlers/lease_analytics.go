@@ -0,0 +1,35 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"net/http"
+
+	"vault-plugin-host/leases"
+)
+
+// LeaseAnalytics is the lease analytics report with the number of leases still active
+type LeaseAnalytics struct {
+	leases.Analytics
+	Active int `json:"active"`
+}
+
+// HandleLeaseAnalytics serves lease statistics for the session at /v1/sys/host/leases/analytics:
+//
+//	GET    - issued TTLs, renewal counts and lifetimes of ended leases as histograms
+//	DELETE - reset the statistics
+func (h *Handler) HandleLeaseAnalytics(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		WriteJSON(w, http.StatusOK, LeaseAnalytics{
+			Analytics: h.leaseStats.Analytics(),
+			Active:    h.leases.Len(),
+		})
+	case http.MethodDelete:
+		h.leaseStats.Reset()
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		h.writeVaultError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
@@ -0,0 +1,72 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestHandleLeaseAnalytics(t *testing.T) {
+	backend := funcBackend(func(ctx context.Context, req *logical.Request) (*logical.Response, error) {
+		return &logical.Response{
+			Data:   map[string]interface{}{"username": "v-token"},
+			Secret: &logical.Secret{LeaseOptions: logical.LeaseOptions{TTL: time.Hour, Renewable: true}},
+		}, nil
+	})
+	handler := NewHandler(backend, newMockStorage(), hclog.NewNullLogger(), "plugin")
+
+	// Issue two leases, renew one and revoke the other
+	var leaseIDs []string
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		handler.HandleRequest(w, httptest.NewRequest("GET", "/v1/plugin/creds/test", nil))
+		var response struct {
+			LeaseID string `json:"lease_id"`
+		}
+		json.NewDecoder(w.Body).Decode(&response)
+		leaseIDs = append(leaseIDs, response.LeaseID)
+	}
+
+	w := httptest.NewRecorder()
+	handler.HandleLeaseRenew(w, httptest.NewRequest("PUT", "/v1/sys/leases/renew", strings.NewReader(`{"lease_id": "`+leaseIDs[0]+`", "increment": 60}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("renew failed: %d %s", w.Code, w.Body)
+	}
+	w = httptest.NewRecorder()
+	handler.HandleLeaseRevokeByPath(w, httptest.NewRequest("PUT", "/v1/sys/leases/revoke/"+leaseIDs[1], nil))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("revoke failed: %d %s", w.Code, w.Body)
+	}
+
+	w = httptest.NewRecorder()
+	handler.HandleLeaseAnalytics(w, httptest.NewRequest("GET", "/v1/sys/host/leases/analytics", nil))
+	var analytics LeaseAnalytics
+	if err := json.NewDecoder(w.Body).Decode(&analytics); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if analytics.Issued != 2 || analytics.Renewals != 1 || analytics.Active != 1 || analytics.Ended["revoked"] != 1 {
+		t.Errorf("unexpected analytics %+v", analytics)
+	}
+	if analytics.TTL.Count != 2 || analytics.Lifetime.Count != 1 {
+		t.Errorf("unexpected TTL or lifetime summaries %+v / %+v", analytics.TTL, analytics.Lifetime)
+	}
+
+	w = httptest.NewRecorder()
+	handler.HandleLeaseAnalytics(w, httptest.NewRequest("DELETE", "/v1/sys/host/leases/analytics", nil))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("reset: status = %d, want 204", w.Code)
+	}
+	if a := handler.leaseStats.Analytics(); a.Issued != 0 {
+		t.Errorf("issued = %d after reset, want 0", a.Issued)
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// HandleLeaseLookup serves /v1/sys/leases/lookup like Vault:
+//
+//	PUT  /v1/sys/leases/lookup           - details of the lease named by lease_id
+//	LIST /v1/sys/leases/lookup/<prefix>  - lease ID segments below prefix
+func (h *Handler) HandleLeaseLookup(w http.ResponseWriter, r *http.Request) {
+	if r.Method == "LIST" || (r.Method == http.MethodGet && r.URL.Query().Get("list") == "true") {
+		prefix := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/v1/sys/leases/lookup"), "/")
+		h.listLeases(w, prefix)
+		return
+	}
+	if r.Method != http.MethodPut && r.Method != http.MethodPost {
+		h.writeVaultError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		h.writeVaultError(w, http.StatusBadRequest, fmt.Sprintf("failed to read body: %v", err))
+		return
+	}
+
+	var requestData map[string]interface{}
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &requestData); err != nil {
+			h.writeVaultError(w, http.StatusBadRequest, fmt.Sprintf("failed to parse JSON: %v", err))
+			return
+		}
+	}
+
+	leaseID, ok := requestData["lease_id"].(string)
+	if !ok || leaseID == "" {
+		h.writeVaultError(w, http.StatusBadRequest, "missing lease ID")
+		return
+	}
+
+	leaseInfo, exists := h.leases.Get(leaseID)
+	if !exists {
+		h.writeVaultError(w, http.StatusBadRequest, "invalid lease")
+		return
+	}
+
+	ttl := time.Until(leaseInfo.ExpireTime)
+	if ttl < 0 {
+		ttl = 0
+	}
+	var lastRenewal interface{}
+	if !leaseInfo.LastRenewal.IsZero() {
+		lastRenewal = leaseInfo.LastRenewal
+	}
+
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"lease_id":       "",
+		"renewable":      false,
+		"lease_duration": 0,
+		"data": map[string]interface{}{
+			"id":           leaseInfo.LeaseID,
+			"issue_time":   leaseInfo.IssueTime,
+			"expire_time":  leaseInfo.ExpireTime,
+			"last_renewal": lastRenewal,
+			"renewable":    leaseInfo.Renewable,
+			"ttl":          int(ttl.Seconds()),
+		},
+	})
+}
+
+// listLeases writes the next lease ID segment of every lease under prefix, with a
+// trailing slash for segments that have more below them
+func (h *Handler) listLeases(w http.ResponseWriter, prefix string) {
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+
+	seen := make(map[string]bool)
+	h.leases.Range(func(lease *LeaseInfo) bool {
+		rest, ok := strings.CutPrefix(lease.LeaseID, prefix)
+		if !ok || rest == "" {
+			return true
+		}
+		if i := strings.Index(rest, "/"); i >= 0 {
+			rest = rest[:i+1]
+		}
+		seen[rest] = true
+		return true
+	})
+
+	// Vault answers an empty LIST with a 404 and no error messages
+	if len(seen) == 0 {
+		WriteJSON(w, http.StatusNotFound, map[string][]string{"errors": {}})
+		return
+	}
+
+	keys := make([]string, 0, len(seen))
+	for key := range seen {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"data": map[string]interface{}{"keys": keys},
+	})
+}
@@ -0,0 +1,101 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+func newLeaseHandler() *Handler {
+	handler := NewHandler(nil, newMockStorage(), hclog.NewNullLogger(), "plugin")
+	now := time.Now()
+	for _, id := range []string{"plugin/creds/a/1", "plugin/creds/a/2", "plugin/creds/b/3", "other/login/4"} {
+		handler.leases.Put(&LeaseInfo{LeaseID: id, IssueTime: now, ExpireTime: now.Add(time.Hour), Renewable: true})
+	}
+	return handler
+}
+
+func TestHandleLeaseLookup(t *testing.T) {
+	handler := newLeaseHandler()
+
+	req := httptest.NewRequest("PUT", "/v1/sys/leases/lookup", strings.NewReader(`{"lease_id": "plugin/creds/a/1"}`))
+	w := httptest.NewRecorder()
+	handler.HandleLeaseLookup(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Status code = %d, want 200: %s", w.Code, w.Body)
+	}
+
+	var response struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if response.Data["id"] != "plugin/creds/a/1" || response.Data["renewable"] != true {
+		t.Errorf("unexpected lease data %v", response.Data)
+	}
+	if ttl, _ := response.Data["ttl"].(float64); ttl < 3590 || ttl > 3600 {
+		t.Errorf("ttl = %v, want about 3600", response.Data["ttl"])
+	}
+	if response.Data["last_renewal"] != nil {
+		t.Errorf("last_renewal = %v, want null for a lease that was never renewed", response.Data["last_renewal"])
+	}
+
+	for body, want := range map[string]int{
+		`{"lease_id": "plugin/creds/a/missing"}`: http.StatusBadRequest,
+		`{}`:                                     http.StatusBadRequest,
+	} {
+		w := httptest.NewRecorder()
+		handler.HandleLeaseLookup(w, httptest.NewRequest("PUT", "/v1/sys/leases/lookup", strings.NewReader(body)))
+		if w.Code != want {
+			t.Errorf("lookup %s: status = %d, want %d", body, w.Code, want)
+		}
+	}
+}
+
+func TestHandleLeaseLookupList(t *testing.T) {
+	handler := newLeaseHandler()
+
+	tests := []struct {
+		method string
+		path   string
+		want   []string
+	}{
+		{"LIST", "/v1/sys/leases/lookup/", []string{"other/", "plugin/"}},
+		{"LIST", "/v1/sys/leases/lookup/plugin/creds", []string{"a/", "b/"}},
+		{"GET", "/v1/sys/leases/lookup/plugin/creds/a/?list=true", []string{"1", "2"}},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		handler.HandleLeaseLookup(w, httptest.NewRequest(tt.method, tt.path, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s %s: status = %d, want 200", tt.method, tt.path, w.Code)
+		}
+
+		var response struct {
+			Data struct {
+				Keys []string `json:"keys"`
+			} `json:"data"`
+		}
+		if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if strings.Join(response.Data.Keys, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("%s %s: keys = %v, want %v", tt.method, tt.path, response.Data.Keys, tt.want)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	handler.HandleLeaseLookup(w, httptest.NewRequest("LIST", "/v1/sys/leases/lookup/missing/", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("empty list: status = %d, want 404", w.Code)
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// isListRequest reports whether r is a list: the LIST method, or GET with list=true
+// as sent by clients that cannot use custom methods
+func isListRequest(r *http.Request) bool {
+	if r.Method == "LIST" {
+		return true
+	}
+	if r.Method != http.MethodGet {
+		return false
+	}
+	list, _ := strconv.ParseBool(r.URL.Query().Get("list"))
+	return list
+}
+
+// listPath gives a list path the trailing slash Vault adds before routing, so
+// "roles" and "roles/" reach the same plugin path. The mount root stays empty.
+func listPath(path string) string {
+	if path == "" || strings.HasSuffix(path, "/") {
+		return path
+	}
+	return path + "/"
+}
+
+// listData shapes the data of a list response as {"keys": [...]}, keeping key_info.
+// Plugins that return their entries as top-level data fields instead of under
+// "keys" get the field names as keys.
+func listData(data map[string]interface{}) map[string]interface{} {
+	if _, ok := data["keys"]; ok {
+		return data
+	}
+
+	keys := []string{}
+	for _, key := range sortedKeys(data) {
+		if key != "key_info" {
+			keys = append(keys, key)
+		}
+	}
+	shaped := map[string]interface{}{"keys": keys}
+	if info, ok := data["key_info"]; ok {
+		shaped["key_info"] = info
+	}
+	return shaped
+}
+
+// listKeysEmpty reports whether list data holds no keys, which Vault answers with 404
+func listKeysEmpty(data map[string]interface{}) bool {
+	switch keys := data["keys"].(type) {
+	case []string:
+		return len(keys) == 0
+	case []interface{}:
+		return len(keys) == 0
+	default:
+		return keys == nil
+	}
+}
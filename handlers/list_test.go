@@ -0,0 +1,91 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestListRequests(t *testing.T) {
+	var got *logical.Request
+	backend := funcBackend(func(ctx context.Context, req *logical.Request) (*logical.Response, error) {
+		got = req
+		switch req.Path {
+		case "roles/":
+			return logical.ListResponse([]string{"dev", "prod"}), nil
+		case "groups/":
+			// Entries as top-level data fields rather than under "keys"
+			return &logical.Response{Data: map[string]interface{}{"admins": true, "devs": true}}, nil
+		}
+		return nil, nil
+	})
+	handler := NewHandler(backend, newMockStorage(), hclog.NewNullLogger(), "plugin")
+
+	tests := []struct {
+		method, url string
+		operation   logical.Operation
+		path        string
+		keys        []interface{}
+	}{
+		{"LIST", "/v1/plugin/roles", logical.ListOperation, "roles/", []interface{}{"dev", "prod"}},
+		{"LIST", "/v1/plugin/roles/", logical.ListOperation, "roles/", []interface{}{"dev", "prod"}},
+		{"GET", "/v1/plugin/roles?list=true", logical.ListOperation, "roles/", []interface{}{"dev", "prod"}},
+		{"GET", "/v1/plugin/groups/?list=1", logical.ListOperation, "groups/", []interface{}{"admins", "devs"}},
+		{"GET", "/v1/plugin/roles?list=false", logical.ReadOperation, "roles", nil},
+		{"POST", "/v1/plugin/roles?list=true", logical.UpdateOperation, "roles", nil},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		handler.HandleRequest(w, httptest.NewRequest(tt.method, tt.url, nil))
+		if got == nil || got.Operation != tt.operation || got.Path != tt.path {
+			t.Errorf("%s %s: plugin got %+v, want %s on %q", tt.method, tt.url, got, tt.operation, tt.path)
+			continue
+		}
+		if tt.keys == nil {
+			continue
+		}
+
+		var resp struct {
+			Data struct {
+				Keys []interface{} `json:"keys"`
+			} `json:"data"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		if !reflect.DeepEqual(resp.Data.Keys, tt.keys) {
+			t.Errorf("%s %s: keys = %v, want %v", tt.method, tt.url, resp.Data.Keys, tt.keys)
+		}
+	}
+}
+
+func TestListDataKeepsKeyInfo(t *testing.T) {
+	info := map[string]interface{}{"dev": map[string]interface{}{"ttl": 60}}
+	data := listData(map[string]interface{}{"dev": true, "key_info": info})
+	if !reflect.DeepEqual(data["keys"], []string{"dev"}) || !reflect.DeepEqual(data["key_info"], info) {
+		t.Errorf("listData = %v", data)
+	}
+	if data := listData(nil); !listKeysEmpty(data) {
+		t.Errorf("listData(nil) = %v, want no keys", data)
+	}
+}
+
+func TestEmptyListNotFound(t *testing.T) {
+	backend := funcBackend(func(ctx context.Context, req *logical.Request) (*logical.Response, error) {
+		return logical.ListResponse(nil), nil
+	})
+	handler := NewHandler(backend, newMockStorage(), hclog.NewNullLogger(), "plugin")
+
+	w := httptest.NewRecorder()
+	handler.HandleRequest(w, httptest.NewRequest("LIST", "/v1/plugin/roles", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("empty list: status = %d, want 404", w.Code)
+	}
+}
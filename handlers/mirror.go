@@ -0,0 +1,378 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// maxMirrorsInFlight bounds the mirrored requests running at once; requests
+	// beyond it are dropped rather than queued, so mirroring never slows the source
+	maxMirrorsInFlight = 16
+	// maxDivergences is the number of divergences kept per mirror
+	maxDivergences = 100
+	// maxDivergentFields is the number of differing fields listed per divergence
+	maxDivergentFields = 20
+)
+
+// volatileFields differ between any two responses and are ignored when comparing
+var volatileFields = []string{"request_id", "lease_id", "mount_type", "wrap_info"}
+
+// Divergence is a mirrored request whose response differed from the source's
+type Divergence struct {
+	Time          time.Time   `json:"time"`
+	Method        string      `json:"method"`
+	Path          string      `json:"path"` // relative to the mount
+	Fields        []string    `json:"fields,omitempty"`
+	SourceStatus  int         `json:"source_status"`
+	MirrorStatus  int         `json:"mirror_status"`
+	SourceBody    interface{} `json:"source_body,omitempty"`
+	MirrorBody    interface{} `json:"mirror_body,omitempty"`
+	MirrorFailure string      `json:"mirror_failure,omitempty"`
+}
+
+// MirrorStats counts the outcomes of mirrored requests
+type MirrorStats struct {
+	Mirrored int `json:"mirrored"`
+	Matched  int `json:"matched"`
+	Diverged int `json:"diverged"`
+	Failed   int `json:"failed"`  // the mirror mount was missing
+	Dropped  int `json:"dropped"` // too many mirrored requests were in flight
+
+	SourceLatency time.Duration `json:"-"`
+	MirrorLatency time.Duration `json:"-"`
+}
+
+// Mirror copies every request to one mount asynchronously to another mount, for
+// example a candidate build of the plugin, and records where the responses differ.
+// Clients only ever see the source mount's responses.
+type Mirror struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+
+	slots chan struct{}
+
+	mu          sync.Mutex
+	stats       MirrorStats
+	divergences []Divergence
+}
+
+// newMirror creates a mirror from one mount path to another
+func newMirror(from, to string) *Mirror {
+	return &Mirror{From: from, To: to, slots: make(chan struct{}, maxMirrorsInFlight)}
+}
+
+// Stats returns the mirror's counters
+func (m *Mirror) Stats() MirrorStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.stats
+}
+
+// Divergences returns the recorded divergences, oldest first
+func (m *Mirror) Divergences() []Divergence {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	result := make([]Divergence, len(m.divergences))
+	copy(result, m.divergences)
+	return result
+}
+
+// summary describes the mirror for the admin API
+func (m *Mirror) summary(withDivergences bool) map[string]interface{} {
+	stats := m.Stats()
+	summary := map[string]interface{}{
+		"from":  m.From,
+		"to":    m.To,
+		"stats": stats,
+	}
+	if stats.Mirrored > 0 {
+		summary["source_avg_latency"] = (stats.SourceLatency / time.Duration(stats.Mirrored)).String()
+		summary["mirror_avg_latency"] = (stats.MirrorLatency / time.Duration(stats.Mirrored)).String()
+	}
+	if withDivergences {
+		summary["divergences"] = m.Divergences()
+	}
+	return summary
+}
+
+// captureWriter passes a response through while keeping a copy of it
+type captureWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (c *captureWriter) WriteHeader(status int) {
+	if c.status == 0 {
+		c.status = status
+	}
+	c.ResponseWriter.WriteHeader(status)
+}
+
+func (c *captureWriter) Write(p []byte) (int, error) {
+	if c.status == 0 {
+		c.status = http.StatusOK
+	}
+	c.body.Write(p)
+	return c.ResponseWriter.Write(p)
+}
+
+// serveMirrored serves a request from its mount and replays it against the mirror
+// once the client has its response
+func (rt *Router) serveMirrored(w http.ResponseWriter, r *http.Request, entry *mountEntry, mirror *Mirror) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, fmt.Sprintf("failed to read body: %v", err))
+		return
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	capture := &captureWriter{ResponseWriter: w}
+	start := time.Now()
+	entry.handler.HandleRequest(capture, r)
+	sourceLatency := time.Since(start)
+
+	select {
+	case mirror.slots <- struct{}{}:
+	default:
+		mirror.mu.Lock()
+		mirror.stats.Dropped++
+		mirror.mu.Unlock()
+		return
+	}
+
+	rest := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/v1/"+entry.path), "/")
+	replay := httptest.NewRequest(r.Method, "/v1/"+mirror.To+"/"+rest, bytes.NewReader(body))
+	replay.URL.RawQuery = r.URL.RawQuery
+	replay.Header = r.Header.Clone()
+	source := &httptest.ResponseRecorder{Code: capture.status, Body: &capture.body}
+
+	go func() {
+		defer func() { <-mirror.slots }()
+		rt.replayMirrored(mirror, replay, rest, source, sourceLatency)
+	}()
+}
+
+// replayMirrored sends a copy of a request to the mirror mount and compares responses
+func (rt *Router) replayMirrored(mirror *Mirror, replay *http.Request, rest string, source *httptest.ResponseRecorder, sourceLatency time.Duration) {
+	divergence := Divergence{
+		Time:         time.Now().UTC(),
+		Method:       replay.Method,
+		Path:         rest,
+		SourceStatus: source.Code,
+	}
+
+	handler, ok := rt.Lookup(mirror.To)
+	if !ok {
+		divergence.MirrorFailure = fmt.Sprintf("no mount at %s/", mirror.To)
+		mirror.record(&divergence, sourceLatency, 0)
+		return
+	}
+
+	recorder := httptest.NewRecorder()
+	start := time.Now()
+	handler.HandleRequest(recorder, replay)
+	mirrorLatency := time.Since(start)
+
+	divergence.MirrorStatus = recorder.Code
+	sourceBody := normalizedBody(source.Body.Bytes())
+	mirrorBody := normalizedBody(recorder.Body.Bytes())
+	if source.Code == recorder.Code && reflect.DeepEqual(sourceBody, mirrorBody) {
+		mirror.record(nil, sourceLatency, mirrorLatency)
+		return
+	}
+
+	divergence.Fields = diffFields("", sourceBody, mirrorBody, nil)
+	divergence.SourceBody = sourceBody
+	divergence.MirrorBody = mirrorBody
+	mirror.record(&divergence, sourceLatency, mirrorLatency)
+}
+
+// record counts a mirrored request; divergence is nil when the responses matched
+func (m *Mirror) record(divergence *Divergence, sourceLatency, mirrorLatency time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.stats.Mirrored++
+	m.stats.SourceLatency += sourceLatency
+	m.stats.MirrorLatency += mirrorLatency
+	switch {
+	case divergence == nil:
+		m.stats.Matched++
+		return
+	case divergence.MirrorFailure != "":
+		m.stats.Failed++
+	default:
+		m.stats.Diverged++
+	}
+
+	m.divergences = append(m.divergences, *divergence)
+	if len(m.divergences) > maxDivergences {
+		m.divergences = m.divergences[len(m.divergences)-maxDivergences:]
+	}
+}
+
+// normalizedBody decodes a JSON response without the fields that always differ
+func normalizedBody(body []byte) interface{} {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil
+	}
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return string(body)
+	}
+	if object, ok := value.(map[string]interface{}); ok {
+		for _, field := range volatileFields {
+			delete(object, field)
+		}
+		if auth, ok := object["auth"].(map[string]interface{}); ok {
+			delete(auth, "client_token")
+			delete(auth, "accessor")
+		}
+	}
+	return value
+}
+
+// diffFields appends the dotted paths at which two JSON values differ
+func diffFields(prefix string, a, b interface{}, fields []string) []string {
+	if len(fields) >= maxDivergentFields || reflect.DeepEqual(a, b) {
+		return fields
+	}
+	objectA, okA := a.(map[string]interface{})
+	objectB, okB := b.(map[string]interface{})
+	if !okA || !okB {
+		if prefix == "" {
+			prefix = "."
+		}
+		return append(fields, prefix)
+	}
+
+	keys := make(map[string]bool)
+	for key := range objectA {
+		keys[key] = true
+	}
+	for key := range objectB {
+		keys[key] = true
+	}
+	sorted := make([]string, 0, len(keys))
+	for key := range keys {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+
+	for _, key := range sorted {
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+		fields = diffFields(path, objectA[key], objectB[key], fields)
+	}
+	return fields
+}
+
+// AddMirror mirrors requests to the mount at from onto the mount at to, replacing
+// any mirror from the same mount. The mounts need not exist yet.
+func (rt *Router) AddMirror(from, to string) (*Mirror, error) {
+	from, to = normalizeMount(from), normalizeMount(to)
+	if from == "" || to == "" {
+		return nil, fmt.Errorf("both a source and a mirror mount are required")
+	}
+	if from == to {
+		return nil, fmt.Errorf("a mount cannot be mirrored onto itself")
+	}
+
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	mirror := newMirror(from, to)
+	rt.mirrors[from] = mirror
+	return mirror, nil
+}
+
+// RemoveMirror stops mirroring the mount at from
+func (rt *Router) RemoveMirror(from string) bool {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	from = normalizeMount(from)
+	_, ok := rt.mirrors[from]
+	delete(rt.mirrors, from)
+	return ok
+}
+
+// mirrorFor returns the mirror of a mount, or nil
+func (rt *Router) mirrorFor(path string) *Mirror {
+	rt.mu.RLock()
+	defer rt.mu.RUnlock()
+	return rt.mirrors[path]
+}
+
+// HandleMirrors manages request mirrors at /v1/sys/host/mirrors:
+//
+//	GET    /v1/sys/host/mirrors          - list mirrors and their divergence counts
+//	GET    /v1/sys/host/mirrors/<from>   - one mirror with its recorded divergences
+//	POST   /v1/sys/host/mirrors/<from>   - mirror <from> onto {"to": "<mount>"}
+//	DELETE /v1/sys/host/mirrors/<from>   - stop mirroring
+func (rt *Router) HandleMirrors(w http.ResponseWriter, r *http.Request) {
+	from := normalizeMount(strings.TrimPrefix(r.URL.Path, "/v1/sys/host/mirrors"))
+
+	switch {
+	case from == "" && r.Method == http.MethodGet:
+		rt.mu.RLock()
+		mirrors := make([]*Mirror, 0, len(rt.mirrors))
+		for _, mirror := range rt.mirrors {
+			mirrors = append(mirrors, mirror)
+		}
+		rt.mu.RUnlock()
+		sort.Slice(mirrors, func(i, j int) bool { return mirrors[i].From < mirrors[j].From })
+
+		summaries := make([]map[string]interface{}, 0, len(mirrors))
+		for _, mirror := range mirrors {
+			summaries = append(summaries, mirror.summary(false))
+		}
+		WriteJSON(w, http.StatusOK, map[string]interface{}{"mirrors": summaries})
+
+	case from != "" && r.Method == http.MethodGet:
+		mirror := rt.mirrorFor(from)
+		if mirror == nil {
+			WriteError(w, http.StatusNotFound, fmt.Sprintf("no mirror from %s/", from))
+			return
+		}
+		WriteJSON(w, http.StatusOK, mirror.summary(true))
+
+	case from != "" && (r.Method == http.MethodPost || r.Method == http.MethodPut):
+		var data struct {
+			To string `json:"to"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+			WriteError(w, http.StatusBadRequest, fmt.Sprintf("failed to parse JSON: %v", err))
+			return
+		}
+		if _, err := rt.AddMirror(from, data.To); err != nil {
+			WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	case from != "" && r.Method == http.MethodDelete:
+		if !rt.RemoveMirror(from) {
+			WriteError(w, http.StatusNotFound, fmt.Sprintf("no mirror from %s/", from))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// waitMirrored waits until a mirror has replayed n requests
+func waitMirrored(t *testing.T, mirror *Mirror, n int) MirrorStats {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		stats := mirror.Stats()
+		if stats.Mirrored+stats.Dropped >= n {
+			return stats
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("only %d of %d requests were mirrored", stats.Mirrored, n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRouterMirrorRecordsDivergence(t *testing.T) {
+	router := NewRouter()
+	source := funcBackend(func(ctx context.Context, req *logical.Request) (*logical.Response, error) {
+		return &logical.Response{Data: map[string]interface{}{"path": req.Path, "version": 1}}, nil
+	})
+	var candidateBody map[string]interface{}
+	candidate := funcBackend(func(ctx context.Context, req *logical.Request) (*logical.Response, error) {
+		candidateBody = req.Data
+		version := 1
+		if req.Path == "changed" {
+			version = 2
+		}
+		return &logical.Response{Data: map[string]interface{}{"path": req.Path, "version": version}}, nil
+	})
+	router.Mount("plugin", NewHandler(source, newMockStorage(), hclog.NewNullLogger(), "plugin"), nil)
+	router.Mount("candidate", NewHandler(candidate, newMockStorage(), hclog.NewNullLogger(), "candidate"), nil)
+	mirror, err := router.AddMirror("plugin", "candidate")
+	if err != nil {
+		t.Fatalf("AddMirror failed: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/v1/plugin/same", bytes.NewBufferString(`{"name":"x"}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected the source response, got %d: %s", w.Code, w.Body.String())
+	}
+	waitMirrored(t, mirror, 1)
+	if candidateBody["name"] != "x" {
+		t.Errorf("mirror should receive the request body, got %v", candidateBody)
+	}
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/v1/plugin/changed", nil))
+	stats := waitMirrored(t, mirror, 2)
+	if stats.Matched != 1 || stats.Diverged != 1 {
+		t.Errorf("expected 1 matched and 1 diverged, got %+v", stats)
+	}
+
+	divergences := mirror.Divergences()
+	if len(divergences) != 1 {
+		t.Fatalf("expected 1 divergence, got %d", len(divergences))
+	}
+	if divergences[0].Path != "changed" || !reflect.DeepEqual(divergences[0].Fields, []string{"data.version"}) {
+		t.Errorf("unexpected divergence: %+v", divergences[0])
+	}
+}
+
+func TestRouterMirrorMissingMount(t *testing.T) {
+	router := NewRouter()
+	router.Mount("plugin", NewHandler(&mockBackend{}, newMockStorage(), hclog.NewNullLogger(), "plugin"), nil)
+	mirror, _ := router.AddMirror("plugin", "candidate")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/v1/plugin/foo", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("source response should not be affected, got %d", w.Code)
+	}
+	if stats := waitMirrored(t, mirror, 1); stats.Failed != 1 {
+		t.Errorf("expected a failed mirror, got %+v", stats)
+	}
+}
+
+func TestNormalizedBodyIgnoresVolatileFields(t *testing.T) {
+	a := normalizedBody([]byte(`{"request_id":"1","lease_id":"plugin/creds/a","data":{"k":"v"},"auth":{"client_token":"a","policies":["p"]}}`))
+	b := normalizedBody([]byte(`{"request_id":"2","lease_id":"candidate/creds/b","data":{"k":"v"},"auth":{"client_token":"b","policies":["p"]}}`))
+	if !reflect.DeepEqual(a, b) {
+		t.Errorf("bodies should match after normalizing: %v != %v", a, b)
+	}
+}
+
+func TestHandleMirrors(t *testing.T) {
+	router := NewRouter()
+
+	w := httptest.NewRecorder()
+	router.HandleMirrors(w, httptest.NewRequest("POST", "/v1/sys/host/mirrors/plugin", bytes.NewBufferString(`{"to":"plugin"}`)))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("mirroring a mount onto itself should fail, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	router.HandleMirrors(w, httptest.NewRequest("POST", "/v1/sys/host/mirrors/plugin", bytes.NewBufferString(`{"to":"candidate"}`)))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	router.HandleMirrors(w, httptest.NewRequest("GET", "/v1/sys/host/mirrors", nil))
+	var list struct {
+		Mirrors []struct {
+			From string `json:"from"`
+			To   string `json:"to"`
+		} `json:"mirrors"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &list)
+	if len(list.Mirrors) != 1 || list.Mirrors[0].From != "plugin" || list.Mirrors[0].To != "candidate" {
+		t.Errorf("unexpected mirrors: %s", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	router.HandleMirrors(w, httptest.NewRequest("DELETE", "/v1/sys/host/mirrors/plugin", nil))
+	if w.Code != http.StatusNoContent {
+		t.Errorf("expected 204, got %d", w.Code)
+	}
+	w = httptest.NewRecorder()
+	router.HandleMirrors(w, httptest.NewRequest("GET", "/v1/sys/host/mirrors/plugin", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("removed mirror should not be found, got %d", w.Code)
+	}
+}
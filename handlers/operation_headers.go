@@ -0,0 +1,38 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	// OperationHeader names the logical operation a request was dispatched to the plugin as
+	OperationHeader = "X-Vault-Host-Operation"
+	// BackendDurationHeader is how long the plugin spent on the request, in milliseconds
+	BackendDurationHeader = "X-Vault-Host-Backend-Duration-Ms"
+	// StorageOpsHeader is how many storage calls the plugin made for the request
+	StorageOpsHeader = "X-Vault-Host-Storage-Ops"
+)
+
+// SetOperationHeaders makes responses of requests the plugin handled report the logical
+// operation and what it cost, so client-side tests can assert on them without the logs
+func (h *Handler) SetOperationHeaders(enabled bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.operationHeaders = enabled
+}
+
+// setOperationHeaders reports operation and the cost recorded on trace in header. A
+// request that timed out is reported with what the plugin has spent on it so far.
+func setOperationHeaders(header http.Header, operation logical.Operation, trace *requestTrace) {
+	backend, storageOps := trace.cost()
+	header.Set(OperationHeader, string(operation))
+	header.Set(BackendDurationHeader, strconv.FormatFloat(float64(backend)/float64(time.Millisecond), 'f', 3, 64))
+	header.Set(StorageOpsHeader, strconv.Itoa(storageOps))
+}
@@ -0,0 +1,72 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"context"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestOperationHeaders(t *testing.T) {
+	backend := funcBackend(func(ctx context.Context, req *logical.Request) (*logical.Response, error) {
+		req.Storage.Get(ctx, "config")
+		req.Storage.Put(ctx, &logical.StorageEntry{Key: "config", Value: []byte("{}")})
+		time.Sleep(5 * time.Millisecond)
+		return &logical.Response{Data: map[string]interface{}{"ok": true}}, nil
+	})
+	handler := NewHandler(backend, newMockStorage(), hclog.NewNullLogger(), "plugin")
+
+	w := httptest.NewRecorder()
+	handler.HandleRequest(w, httptest.NewRequest("POST", "/v1/plugin/config", strings.NewReader(`{"a":1}`)))
+	if w.Header().Get(OperationHeader) != "" {
+		t.Fatal("operation headers should only be sent when enabled")
+	}
+
+	handler.SetOperationHeaders(true)
+	w = httptest.NewRecorder()
+	handler.HandleRequest(w, httptest.NewRequest("POST", "/v1/plugin/config", strings.NewReader(`{"a":1}`)))
+	if op := w.Header().Get(OperationHeader); op != "update" {
+		t.Errorf("%s = %q, want update", OperationHeader, op)
+	}
+	if ops := w.Header().Get(StorageOpsHeader); ops != "2" {
+		t.Errorf("%s = %q, want 2", StorageOpsHeader, ops)
+	}
+	if ms, err := strconv.ParseFloat(w.Header().Get(BackendDurationHeader), 64); err != nil || ms < 5 {
+		t.Errorf("%s = %q, want at least 5ms", BackendDurationHeader, w.Header().Get(BackendDurationHeader))
+	}
+
+	w = httptest.NewRecorder()
+	handler.HandleRequest(w, httptest.NewRequest("LIST", "/v1/plugin/", nil))
+	if op := w.Header().Get(OperationHeader); op != "list" {
+		t.Errorf("%s of a LIST = %q, want list", OperationHeader, op)
+	}
+
+	// A plugin served over gRPC stores through the storage it was set up with
+	var setupStorage logical.Storage
+	handler.SetBackend(funcBackend(func(ctx context.Context, req *logical.Request) (*logical.Response, error) {
+		setupStorage.Get(ctx, "config")
+		return nil, nil
+	}))
+	setupStorage = handler.CallStorage(newMockStorage())
+	w = httptest.NewRecorder()
+	handler.HandleRequest(w, httptest.NewRequest("GET", "/v1/plugin/config", nil))
+	if ops := w.Header().Get(StorageOpsHeader); ops != "1" {
+		t.Errorf("%s of a call through the setup storage = %q, want 1", StorageOpsHeader, ops)
+	}
+	setupStorage.Get(context.Background(), "config") // outside a request, recorded on none
+
+	// A request the plugin never saw reports nothing
+	w = httptest.NewRecorder()
+	handler.HandleRequest(w, httptest.NewRequest("PATCH", "/v1/plugin/config", nil))
+	if w.Header().Get(OperationHeader) != "" {
+		t.Error("a rejected request should not report an operation")
+	}
+}
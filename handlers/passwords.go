@@ -0,0 +1,375 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/hashicorp/hcl"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// passwordPolicyPrefix is the storage prefix of password policies
+const passwordPolicyPrefix = "password_policy/"
+
+// maxPasswordAttempts bounds the passwords generated while looking for one that
+// satisfies every rule of a policy
+const maxPasswordAttempts = 1000
+
+// errPasswordPolicyNotFound is Vault's error for generating from an unknown policy
+var errPasswordPolicyNotFound = errors.New("password policy not found")
+
+// CharsetRule is a "charset" rule of a password policy: the characters it adds to the
+// policy's character set, and how many of them a password must contain
+type CharsetRule struct {
+	Charset  []rune
+	MinChars int
+}
+
+// PasswordPolicy is a parsed password policy in Vault's format:
+//
+//	length = 20
+//	rule "charset" {
+//	  charset   = "abcdefghijklmnopqrstuvwxyz"
+//	  min-chars = 1
+//	}
+//
+// Passwords are drawn from the union of the rules' charsets and must contain at
+// least min-chars characters of each rule.
+type PasswordPolicy struct {
+	Length  int
+	Rules   []CharsetRule
+	charset []rune
+}
+
+// ParsePasswordPolicy parses a policy written in HCL or JSON
+func ParsePasswordPolicy(text string) (*PasswordPolicy, error) {
+	var raw map[string]interface{}
+	if err := hcl.Decode(&raw, text); err != nil {
+		return nil, fmt.Errorf("failed to parse policy: %w", err)
+	}
+
+	policy := &PasswordPolicy{}
+	for key, value := range raw {
+		switch key {
+		case "length":
+			length, ok := value.(int)
+			if !ok {
+				return nil, fmt.Errorf("length must be an integer")
+			}
+			policy.Length = length
+		case "rule":
+			rules, _ := value.([]map[string]interface{})
+			for _, rule := range rules {
+				for ruleType, body := range rule {
+					if ruleType != "charset" {
+						return nil, fmt.Errorf("unrecognized rule type %q", ruleType)
+					}
+					blocks, _ := body.([]map[string]interface{})
+					for _, block := range blocks {
+						parsed, err := parseCharsetRule(block)
+						if err != nil {
+							return nil, err
+						}
+						policy.Rules = append(policy.Rules, parsed)
+					}
+				}
+			}
+		default:
+			return nil, fmt.Errorf("unrecognized policy field %q", key)
+		}
+	}
+
+	seen := make(map[rune]bool)
+	minChars := 0
+	for _, rule := range policy.Rules {
+		minChars += rule.MinChars
+		for _, r := range rule.Charset {
+			if !seen[r] {
+				seen[r] = true
+				policy.charset = append(policy.charset, r)
+			}
+		}
+	}
+
+	switch {
+	case policy.Length <= 0:
+		return nil, fmt.Errorf("length must be > 0")
+	case len(policy.charset) == 0:
+		return nil, fmt.Errorf("no charset specified")
+	case minChars > policy.Length:
+		return nil, fmt.Errorf("rules require at least %d characters but length is %d", minChars, policy.Length)
+	}
+	return policy, nil
+}
+
+// parseCharsetRule parses the body of a charset rule
+func parseCharsetRule(block map[string]interface{}) (CharsetRule, error) {
+	var rule CharsetRule
+	for key, value := range block {
+		switch key {
+		case "charset":
+			charset, ok := value.(string)
+			if !ok {
+				return rule, fmt.Errorf("charset must be a string")
+			}
+			rule.Charset = []rune(charset)
+		case "min-chars", "min_chars":
+			minChars, ok := value.(int)
+			if !ok || minChars < 0 {
+				return rule, fmt.Errorf("min-chars must be a non-negative integer")
+			}
+			rule.MinChars = minChars
+		default:
+			return rule, fmt.Errorf("unrecognized charset rule field %q", key)
+		}
+	}
+	if len(rule.Charset) == 0 {
+		return rule, fmt.Errorf("charset rule requires a charset")
+	}
+	for _, r := range rule.Charset {
+		if r > unicode.MaxASCII || !unicode.IsPrint(r) {
+			return rule, fmt.Errorf("charset contains the non-printable or non-ASCII character %q", r)
+		}
+	}
+	return rule, nil
+}
+
+// Generate returns a random password that satisfies the policy
+func (p *PasswordPolicy) Generate() (string, error) {
+	size := big.NewInt(int64(len(p.charset)))
+	password := make([]rune, p.Length)
+	for attempt := 0; attempt < maxPasswordAttempts; attempt++ {
+		for i := range password {
+			idx, err := rand.Int(rand.Reader, size)
+			if err != nil {
+				return "", fmt.Errorf("failed to generate password: %w", err)
+			}
+			password[i] = p.charset[idx.Int64()]
+		}
+		if p.satisfied(password) {
+			return string(password), nil
+		}
+	}
+	return "", fmt.Errorf("unable to generate a password satisfying the policy in %d attempts", maxPasswordAttempts)
+}
+
+// satisfied reports whether password meets the min-chars of every rule
+func (p *PasswordPolicy) satisfied(password []rune) bool {
+	for _, rule := range p.Rules {
+		count := 0
+		for _, r := range password {
+			for _, c := range rule.Charset {
+				if r == c {
+					count++
+					break
+				}
+			}
+		}
+		if count < rule.MinChars {
+			return false
+		}
+	}
+	return true
+}
+
+// PasswordPolicies emulates Vault's password policies at /v1/sys/policies/password,
+// which plugins use through GeneratePasswordFromPolicy on the system view
+type PasswordPolicies struct {
+	storage logical.Storage
+}
+
+// NewPasswordPolicies creates a password policy store that keeps policies in storage
+func NewPasswordPolicies(storage logical.Storage) *PasswordPolicies {
+	return &PasswordPolicies{storage: storage}
+}
+
+// Set validates and stores a policy under name
+func (s *PasswordPolicies) Set(ctx context.Context, name, text string) error {
+	if _, err := ParsePasswordPolicy(text); err != nil {
+		return err
+	}
+	return s.storage.Put(ctx, &logical.StorageEntry{Key: passwordPolicyPrefix + name, Value: []byte(text)})
+}
+
+// Get returns the text of a policy, or "" when there is none
+func (s *PasswordPolicies) Get(ctx context.Context, name string) (string, error) {
+	entry, err := s.storage.Get(ctx, passwordPolicyPrefix+name)
+	if err != nil || entry == nil {
+		return "", err
+	}
+	return string(entry.Value), nil
+}
+
+// Delete removes a policy
+func (s *PasswordPolicies) Delete(ctx context.Context, name string) error {
+	return s.storage.Delete(ctx, passwordPolicyPrefix+name)
+}
+
+// List returns the policy names, sorted
+func (s *PasswordPolicies) List(ctx context.Context) ([]string, error) {
+	keys, err := s.storage.List(ctx, passwordPolicyPrefix)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(keys))
+	for _, key := range keys {
+		names = append(names, strings.TrimPrefix(key, passwordPolicyPrefix))
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Generate returns a password from the named policy
+func (s *PasswordPolicies) Generate(ctx context.Context, name string) (string, error) {
+	text, err := s.Get(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	if text == "" {
+		return "", errPasswordPolicyNotFound
+	}
+	policy, err := ParsePasswordPolicy(text)
+	if err != nil {
+		return "", err
+	}
+	return policy.Generate()
+}
+
+// HandlePolicies serves Vault's password policy endpoints:
+//
+//	LIST        /v1/sys/policies/password                 - policy names
+//	PUT/POST    /v1/sys/policies/password/<name>          - store {"policy": "<HCL or JSON>"}
+//	GET         /v1/sys/policies/password/<name>          - the policy text
+//	DELETE      /v1/sys/policies/password/<name>          - remove the policy
+//	GET         /v1/sys/policies/password/<name>/generate - a password from the policy
+func (s *PasswordPolicies) HandlePolicies(w http.ResponseWriter, r *http.Request) {
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/v1/sys/policies/password"), "/")
+	if name == "" {
+		if !isListRequest(r) {
+			WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		names, err := s.List(r.Context())
+		if err != nil {
+			WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if len(names) == 0 {
+			WriteError(w, http.StatusNotFound, "")
+			return
+		}
+		writePasswordPolicyResponse(w, map[string]interface{}{"keys": names})
+		return
+	}
+
+	if generateName, ok := strings.CutSuffix(name, "/generate"); ok {
+		if r.Method != http.MethodGet {
+			WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		password, err := s.Generate(r.Context(), generateName)
+		if errors.Is(err, errPasswordPolicyNotFound) {
+			WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err != nil {
+			WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writePasswordPolicyResponse(w, map[string]interface{}{"password": password})
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		text, err := s.Get(r.Context(), name)
+		if err != nil {
+			WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if text == "" {
+			WriteError(w, http.StatusNotFound, "")
+			return
+		}
+		writePasswordPolicyResponse(w, map[string]interface{}{"policy": text})
+
+	case http.MethodPut, http.MethodPost:
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			WriteError(w, http.StatusBadRequest, fmt.Sprintf("failed to read body: %v", err))
+			return
+		}
+		var data struct {
+			Policy string `json:"policy"`
+		}
+		if err := json.Unmarshal(body, &data); err != nil {
+			WriteError(w, http.StatusBadRequest, fmt.Sprintf("failed to parse JSON: %v", err))
+			return
+		}
+		if data.Policy == "" {
+			WriteError(w, http.StatusBadRequest, "missing policy")
+			return
+		}
+		// Like Vault, accept the policy base64 encoded
+		text := data.Policy
+		if decoded, err := base64.StdEncoding.DecodeString(text); err == nil {
+			text = string(decoded)
+		}
+		if err := s.Set(r.Context(), name, text); err != nil {
+			WriteError(w, http.StatusBadRequest, fmt.Sprintf("invalid password policy: %v", err))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	case http.MethodDelete:
+		if err := s.Delete(r.Context(), name); err != nil {
+			WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// writePasswordPolicyResponse writes data in Vault's response envelope
+func writePasswordPolicyResponse(w http.ResponseWriter, data map[string]interface{}) {
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"request_id":     newRequestID(),
+		"lease_id":       "",
+		"renewable":      false,
+		"lease_duration": 0,
+		"data":           data,
+		"wrap_info":      nil,
+		"warnings":       nil,
+		"auth":           nil,
+	})
+}
+
+// SetPasswordPolicies makes the policies available to the plugin's system view
+func (h *Handler) SetPasswordPolicies(policies *PasswordPolicies) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.passwords = policies
+}
+
+// PasswordPolicies returns the handler's password policies, or nil when there are none
+func (h *Handler) PasswordPolicies() *PasswordPolicies {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.passwords
+}
@@ -0,0 +1,169 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testPasswordPolicy = `
+length = 24
+rule "charset" {
+  charset   = "abcdefghijklmnopqrstuvwxyz"
+  min-chars = 1
+}
+rule "charset" {
+  charset   = "0123456789"
+  min-chars = 4
+}
+rule "charset" {
+  charset   = "!@#"
+  min-chars = 2
+}
+`
+
+func TestParsePasswordPolicy(t *testing.T) {
+	policy, err := ParsePasswordPolicy(testPasswordPolicy)
+	if err != nil {
+		t.Fatalf("ParsePasswordPolicy failed: %v", err)
+	}
+	if policy.Length != 24 || len(policy.Rules) != 3 || len(policy.charset) != 39 {
+		t.Errorf("policy = length %d, %d rules, %d chars", policy.Length, len(policy.Rules), len(policy.charset))
+	}
+
+	jsonPolicy := `{"length": 8, "rule": [{"charset": {"charset": "ab", "min-chars": 1}}]}`
+	if policy, err := ParsePasswordPolicy(jsonPolicy); err != nil || policy.Length != 8 || policy.Rules[0].MinChars != 1 {
+		t.Errorf("JSON policy = %+v, %v", policy, err)
+	}
+
+	for name, text := range map[string]string{
+		"no length":       `rule "charset" { charset = "abc" }`,
+		"no charset":      `length = 10`,
+		"unknown rule":    `length = 10` + "\n" + `rule "charset-min" { charset = "abc" }`,
+		"unknown field":   `length = 10` + "\n" + `size = 3`,
+		"too many chars":  `length = 4` + "\n" + `rule "charset" { charset = "abc" min-chars = 5 }`,
+		"non-ASCII chars": `length = 4` + "\n" + `rule "charset" { charset = "ab€" }`,
+		"not HCL":         `length = `,
+	} {
+		if _, err := ParsePasswordPolicy(text); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestPasswordPolicyGenerate(t *testing.T) {
+	policy, err := ParsePasswordPolicy(testPasswordPolicy)
+	if err != nil {
+		t.Fatalf("ParsePasswordPolicy failed: %v", err)
+	}
+	for i := 0; i < 50; i++ {
+		password, err := policy.Generate()
+		if err != nil {
+			t.Fatalf("Generate failed: %v", err)
+		}
+		if len(password) != 24 {
+			t.Fatalf("password %q has length %d, want 24", password, len(password))
+		}
+		digits, symbols := 0, 0
+		for _, r := range password {
+			switch {
+			case strings.ContainsRune("0123456789", r):
+				digits++
+			case strings.ContainsRune("!@#", r):
+				symbols++
+			case r < 'a' || r > 'z':
+				t.Fatalf("password %q contains %q outside the charset", password, r)
+			}
+		}
+		if digits < 4 || symbols < 2 {
+			t.Fatalf("password %q has %d digits and %d symbols", password, digits, symbols)
+		}
+	}
+}
+
+func TestPasswordPolicies(t *testing.T) {
+	ctx := context.Background()
+	policies := NewPasswordPolicies(newMockStorage())
+
+	if _, err := policies.Generate(ctx, "missing"); err != errPasswordPolicyNotFound {
+		t.Errorf("Generate(missing) error = %v, want %v", err, errPasswordPolicyNotFound)
+	}
+	if err := policies.Set(ctx, "bad", `length = 0`); err == nil {
+		t.Error("Set should reject an invalid policy")
+	}
+	if err := policies.Set(ctx, "db", testPasswordPolicy); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if password, err := policies.Generate(ctx, "db"); err != nil || len(password) != 24 {
+		t.Errorf("Generate = %q, %v", password, err)
+	}
+	if names, err := policies.List(ctx); err != nil || len(names) != 1 || names[0] != "db" {
+		t.Errorf("List = %v, %v", names, err)
+	}
+	if err := policies.Delete(ctx, "db"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if text, _ := policies.Get(ctx, "db"); text != "" {
+		t.Errorf("policy still present after delete: %q", text)
+	}
+}
+
+func TestHandlePasswordPolicies(t *testing.T) {
+	policies := NewPasswordPolicies(newMockStorage())
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		policies.HandlePolicies(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return w
+	}
+	data := func(w *httptest.ResponseRecorder) map[string]interface{} {
+		var response struct {
+			Data map[string]interface{} `json:"data"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return response.Data
+	}
+
+	body, _ := json.Marshal(map[string]string{"policy": testPasswordPolicy})
+	if w := serve(http.MethodPut, "/v1/sys/policies/password/db", string(body)); w.Code != http.StatusNoContent {
+		t.Fatalf("PUT = %d: %s", w.Code, w.Body)
+	}
+	encoded, _ := json.Marshal(map[string]string{"policy": base64.StdEncoding.EncodeToString([]byte(`length = 6` + "\n" + `rule "charset" { charset = "xyz" }`))})
+	if w := serve(http.MethodPost, "/v1/sys/policies/password/short", string(encoded)); w.Code != http.StatusNoContent {
+		t.Fatalf("POST base64 = %d: %s", w.Code, w.Body)
+	}
+	if w := serve(http.MethodPut, "/v1/sys/policies/password/bad", `{"policy": "length = 5"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("PUT invalid = %d, want 400", w.Code)
+	}
+
+	w := serve(http.MethodGet, "/v1/sys/policies/password/db", "")
+	if w.Code != http.StatusOK || data(w)["policy"] != testPasswordPolicy {
+		t.Errorf("GET = %d: %s", w.Code, w.Body)
+	}
+
+	w = serve("LIST", "/v1/sys/policies/password", "")
+	if keys, _ := data(w)["keys"].([]interface{}); len(keys) != 2 || keys[0] != "db" || keys[1] != "short" {
+		t.Errorf("LIST = %d: %s", w.Code, w.Body)
+	}
+
+	w = serve(http.MethodGet, "/v1/sys/policies/password/short/generate", "")
+	if password, _ := data(w)["password"].(string); len(password) != 6 || strings.Trim(password, "xyz") != "" {
+		t.Errorf("generate = %d: %s", w.Code, w.Body)
+	}
+	if w := serve(http.MethodGet, "/v1/sys/policies/password/missing/generate", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("generate missing = %d, want 400", w.Code)
+	}
+
+	if w := serve(http.MethodDelete, "/v1/sys/policies/password/db", ""); w.Code != http.StatusNoContent {
+		t.Errorf("DELETE = %d", w.Code)
+	}
+	if w := serve(http.MethodGet, "/v1/sys/policies/password/db", ""); w.Code != http.StatusNotFound {
+		t.Errorf("GET deleted = %d, want 404", w.Code)
+	}
+}
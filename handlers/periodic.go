@@ -0,0 +1,186 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// Periodic sends the rollback request Vault's rollback manager issues on every tick.
+// framework.Backend runs its PeriodicFunc and WAL rollbacks in response; plugins that
+// define neither report ErrUnsupportedOperation, which is not treated as a failure.
+func (h *Handler) Periodic(ctx context.Context) error {
+	return h.rollback(ctx, false)
+}
+
+// rollback sends a rollback request on the mount root. With immediate set the request
+// carries Vault's "immediate" flag, which makes framework.Backend roll back every WAL
+// entry regardless of its WALRollbackMinAge.
+func (h *Handler) rollback(ctx context.Context, immediate bool) error {
+	h.mu.RLock()
+	backend := h.backend
+	storage := h.storage
+	h.mu.RUnlock()
+
+	if backend == nil {
+		return fmt.Errorf("plugin not started")
+	}
+
+	req := &logical.Request{
+		Operation:  logical.RollbackOperation,
+		Path:       "",
+		Storage:    storage,
+		MountPoint: h.mountPath + "/",
+	}
+	if immediate {
+		req.Data = map[string]interface{}{"immediate": true}
+	}
+
+	resp, err := h.callBackend(ctx, backend, req)
+	if errors.Is(err, logical.ErrUnsupportedOperation) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if resp != nil && resp.IsError() {
+		return resp.Error()
+	}
+	return nil
+}
+
+// WALEntry is a write-ahead log entry the plugin keeps in storage until a rollback
+// either completes or undoes the operation it guards
+type WALEntry struct {
+	ID        string      `json:"id"`
+	Kind      string      `json:"kind"`
+	Data      interface{} `json:"data"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+// WALEntries returns the plugin's WAL entries, oldest first
+func (h *Handler) WALEntries(ctx context.Context) ([]WALEntry, error) {
+	h.mu.RLock()
+	storage := h.storage
+	h.mu.RUnlock()
+
+	ids, err := framework.ListWAL(ctx, storage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list WAL entries: %w", err)
+	}
+	entries := make([]WALEntry, 0, len(ids))
+	for _, id := range ids {
+		entry, err := framework.GetWAL(ctx, storage, id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read WAL entry %q: %w", id, err)
+		}
+		if entry == nil {
+			continue
+		}
+		entries = append(entries, WALEntry{
+			ID:        id,
+			Kind:      entry.Kind,
+			Data:      entry.Data,
+			CreatedAt: time.Unix(entry.CreatedAt, 0).UTC(),
+		})
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if !entries[i].CreatedAt.Equal(entries[j].CreatedAt) {
+			return entries[i].CreatedAt.Before(entries[j].CreatedAt)
+		}
+		return entries[i].ID < entries[j].ID
+	})
+	return entries, nil
+}
+
+// RollbackResult is the outcome of a rollback pass triggered through the API
+type RollbackResult struct {
+	Immediate  bool       `json:"immediate"`
+	RolledBack []WALEntry `json:"rolled_back"` // entries the plugin removed during the pass
+	Remaining  []WALEntry `json:"remaining"`
+	Error      string     `json:"error,omitempty"` // the plugin's rollback error, if any
+}
+
+// Rollback runs a rollback pass now and reports which WAL entries it cleared
+func (h *Handler) Rollback(ctx context.Context, immediate bool) (*RollbackResult, error) {
+	before, err := h.WALEntries(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &RollbackResult{Immediate: immediate, RolledBack: []WALEntry{}}
+	if err := h.rollback(ctx, immediate); err != nil {
+		result.Error = err.Error()
+	}
+
+	if result.Remaining, err = h.WALEntries(ctx); err != nil {
+		return nil, err
+	}
+	remaining := make(map[string]bool, len(result.Remaining))
+	for _, entry := range result.Remaining {
+		remaining[entry.ID] = true
+	}
+	for _, entry := range before {
+		if !remaining[entry.ID] {
+			result.RolledBack = append(result.RolledBack, entry)
+		}
+	}
+	return result, nil
+}
+
+// HandleRollback drives the plugin's WAL recovery at /v1/sys/test/rollback:
+//
+//	GET       - the pending WAL entries
+//	POST/PUT  - run a rollback pass now; entries are rolled back regardless of age
+//	            unless ?immediate=false, which applies the plugin's WALRollbackMinAge
+//	            as the periodic pass does
+func (h *Handler) HandleRollback(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		entries, err := h.WALEntries(r.Context())
+		if err != nil {
+			h.writeVaultError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		WriteJSON(w, http.StatusOK, map[string]interface{}{"wal_entries": entries})
+
+	case http.MethodPost, http.MethodPut:
+		immediate := true
+		if v := r.URL.Query().Get("immediate"); v != "" {
+			parsed, err := strconv.ParseBool(v)
+			if err != nil {
+				h.writeVaultError(w, http.StatusBadRequest, fmt.Sprintf("invalid immediate %q", v))
+				return
+			}
+			immediate = parsed
+		}
+		h.mu.RLock()
+		backend := h.backend
+		h.mu.RUnlock()
+		if backend == nil {
+			h.writeVaultError(w, http.StatusServiceUnavailable, "plugin not started")
+			return
+		}
+
+		result, err := h.Rollback(r.Context(), immediate)
+		if err != nil {
+			h.writeVaultError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		h.logger.Info("rollback pass triggered", "immediate", immediate, "rolled_back", len(result.RolledBack), "remaining", len(result.Remaining))
+		WriteJSON(w, http.StatusOK, result)
+
+	default:
+		h.writeVaultError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
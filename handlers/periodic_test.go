@@ -0,0 +1,164 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// funcBackend answers every request with the given function
+type funcBackend func(ctx context.Context, req *logical.Request) (*logical.Response, error)
+
+func (f funcBackend) HandleRequest(ctx context.Context, req *logical.Request) (*logical.Response, error) {
+	return f(ctx, req)
+}
+
+func TestPeriodic(t *testing.T) {
+	var got *logical.Request
+	backend := funcBackend(func(ctx context.Context, req *logical.Request) (*logical.Response, error) {
+		got = req
+		return nil, nil
+	})
+
+	storage := newMockStorage()
+	handler := NewHandler(backend, storage, hclog.NewNullLogger(), "plugin")
+	if err := handler.Periodic(context.Background()); err != nil {
+		t.Fatalf("Periodic failed: %v", err)
+	}
+
+	if got == nil {
+		t.Fatal("backend did not receive a request")
+	}
+	if got.Operation != logical.RollbackOperation || got.Path != "" {
+		t.Errorf("request = %s %q, want rollback on the mount root", got.Operation, got.Path)
+	}
+	if got.MountPoint != "plugin/" {
+		t.Errorf("mount point = %q, want plugin/", got.MountPoint)
+	}
+	if got.Storage != storage {
+		t.Error("request should carry the handler's storage")
+	}
+}
+
+func TestPeriodicErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		resp    *logical.Response
+		err     error
+		wantErr bool
+	}{
+		{name: "unsupported", err: logical.ErrUnsupportedOperation},
+		{name: "backend error", err: errors.New("boom"), wantErr: true},
+		{name: "error response", resp: logical.ErrorResponse("rollback failed"), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := funcBackend(func(ctx context.Context, req *logical.Request) (*logical.Response, error) {
+				return tt.resp, tt.err
+			})
+			handler := NewHandler(backend, newMockStorage(), hclog.NewNullLogger(), "plugin")
+
+			err := handler.Periodic(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Periodic error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	handler := NewHandler(nil, newMockStorage(), hclog.NewNullLogger(), "plugin")
+	if err := handler.Periodic(context.Background()); err == nil {
+		t.Error("Periodic should fail when the plugin is not started")
+	}
+}
+
+func TestRollback(t *testing.T) {
+	ctx := context.Background()
+	var rolledBack []string
+	backend := &framework.Backend{
+		WALRollbackMinAge: time.Hour,
+		WALRollback: func(ctx context.Context, req *logical.Request, kind string, data interface{}) error {
+			if kind == "broken" {
+				return errors.New("cannot undo")
+			}
+			rolledBack = append(rolledBack, kind)
+			return nil
+		},
+	}
+	storage := newMockStorage()
+	handler := NewHandler(backend, storage, hclog.NewNullLogger(), "plugin")
+
+	for _, kind := range []string{"user", "broken"} {
+		if _, err := framework.PutWAL(ctx, storage, kind, map[string]interface{}{"name": kind}); err != nil {
+			t.Fatalf("PutWAL failed: %v", err)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	handler.HandleRollback(w, httptest.NewRequest(http.MethodGet, "/v1/sys/test/rollback", nil))
+	var listing struct {
+		Entries []WALEntry `json:"wal_entries"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&listing); err != nil || len(listing.Entries) != 2 {
+		t.Fatalf("listing = %+v, %v", listing.Entries, err)
+	}
+
+	// The periodic pass leaves entries younger than WALRollbackMinAge alone
+	if err := handler.Periodic(ctx); err != nil {
+		t.Fatalf("Periodic failed: %v", err)
+	}
+	if len(rolledBack) != 0 {
+		t.Fatalf("periodic pass rolled back young entries: %v", rolledBack)
+	}
+
+	w = httptest.NewRecorder()
+	handler.HandleRollback(w, httptest.NewRequest(http.MethodPost, "/v1/sys/test/rollback?immediate=false", nil))
+	var result RollbackResult
+	if err := json.NewDecoder(w.Body).Decode(&result); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("rollback: status %d, %v", w.Code, err)
+	}
+	if len(result.RolledBack) != 0 || len(result.Remaining) != 2 {
+		t.Errorf("non-immediate rollback = %+v", result)
+	}
+
+	w = httptest.NewRecorder()
+	handler.HandleRollback(w, httptest.NewRequest(http.MethodPost, "/v1/sys/test/rollback", nil))
+	result = RollbackResult{}
+	if err := json.NewDecoder(w.Body).Decode(&result); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("rollback: status %d, %v", w.Code, err)
+	}
+	if !result.Immediate || len(result.RolledBack) != 1 || result.RolledBack[0].Kind != "user" {
+		t.Errorf("rolled back = %+v", result.RolledBack)
+	}
+	if len(result.Remaining) != 1 || result.Remaining[0].Kind != "broken" || !strings.Contains(result.Error, "cannot undo") {
+		t.Errorf("remaining = %+v, error %q", result.Remaining, result.Error)
+	}
+	if len(rolledBack) != 1 {
+		t.Errorf("plugin rolled back %v", rolledBack)
+	}
+
+	w = httptest.NewRecorder()
+	handler.HandleRollback(w, httptest.NewRequest(http.MethodPost, "/v1/sys/test/rollback?immediate=maybe", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("invalid immediate: status %d", w.Code)
+	}
+
+	stopped := NewHandler(nil, newMockStorage(), hclog.NewNullLogger(), "plugin")
+	w = httptest.NewRecorder()
+	stopped.HandleRollback(w, httptest.NewRequest(http.MethodPost, "/v1/sys/test/rollback", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("plugin not started: status %d", w.Code)
+	}
+}
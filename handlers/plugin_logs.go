@@ -0,0 +1,305 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+// maxPluginLogLine is how long a line may grow before it is cut, so a plugin writing
+// without newlines cannot make the host buffer without bound
+const maxPluginLogLine = 64 << 10
+
+// PluginLogLine is one line a plugin wrote to its stdout or stderr
+type PluginLogLine struct {
+	Seq    uint64    `json:"seq"`
+	Time   time.Time `json:"time"`
+	Stream string    `json:"stream"` // "stdout" or "stderr"
+	Line   string    `json:"line"`
+}
+
+// PluginLogs collects what a plugin process writes to stdout and stderr: each line
+// goes to the host logger and into a buffer of the last lines, which followers of
+// /v1/sys/plugin/logs are streamed from. It outlives restarts of the process.
+type PluginLogs struct {
+	logger hclog.Logger
+	max    int
+
+	mu    sync.Mutex
+	lines []PluginLogLine // the last max lines, oldest first
+	next  uint64          // sequence number of the next line
+	wake  chan struct{}   // closed when a line is added
+}
+
+// NewPluginLogs creates a collector logging through logger and keeping max lines
+func NewPluginLogs(logger hclog.Logger, max int) *PluginLogs {
+	if max < 1 {
+		max = 1
+	}
+	return &PluginLogs{logger: logger, max: max, next: 1, wake: make(chan struct{})}
+}
+
+// Writer returns a writer for one stream of the plugin, splitting it into lines. A nil
+// collector discards what is written.
+func (l *PluginLogs) Writer(stream string) io.Writer {
+	if l == nil {
+		return io.Discard
+	}
+	return &pluginLogWriter{logs: l, stream: stream, log: true}
+}
+
+// Keeper returns a writer that keeps the lines of one stream without logging them, for
+// output that is logged elsewhere, as go-plugin logs the stderr of the plugins it
+// launches. A nil collector discards what is written.
+func (l *PluginLogs) Keeper(stream string) io.Writer {
+	if l == nil {
+		return io.Discard
+	}
+	return &pluginLogWriter{logs: l, stream: stream}
+}
+
+type pluginLogWriter struct {
+	logs    *PluginLogs
+	stream  string
+	log     bool // log lines as well as keeping them
+	mu      sync.Mutex
+	partial []byte
+}
+
+func (w *pluginLogWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.partial = append(w.partial, p...)
+	for {
+		i := bytes.IndexByte(w.partial, '\n')
+		if i < 0 {
+			break
+		}
+		w.logs.add(w.stream, strings.TrimSuffix(string(w.partial[:i]), "\r"), w.log)
+		w.partial = w.partial[i+1:]
+	}
+	if len(w.partial) >= maxPluginLogLine {
+		w.logs.add(w.stream, string(w.partial), w.log)
+		w.partial = nil
+	}
+	return len(p), nil
+}
+
+// add keeps a line and, with log set, logs it. A line that is an hclog JSON entry, as
+// go-plugin plugins write their logs, is logged at its own level with its fields.
+func (l *PluginLogs) add(stream, line string, log bool) {
+	if line == "" {
+		return
+	}
+	if log {
+		l.log(stream, line)
+	}
+
+	l.mu.Lock()
+	l.lines = append(l.lines, PluginLogLine{Seq: l.next, Time: time.Now(), Stream: stream, Line: line})
+	if len(l.lines) > l.max {
+		l.lines = l.lines[len(l.lines)-l.max:]
+	}
+	l.next++
+	close(l.wake)
+	l.wake = make(chan struct{})
+	l.mu.Unlock()
+}
+
+func (l *PluginLogs) log(stream, line string) {
+	var entry map[string]interface{}
+	if strings.HasPrefix(line, "{") && json.Unmarshal([]byte(line), &entry) == nil {
+		if message, ok := entry["@message"].(string); ok {
+			level := hclog.Info
+			if name, ok := entry["@level"].(string); ok {
+				if parsed := hclog.LevelFromString(name); parsed != hclog.NoLevel {
+					level = parsed
+				}
+			}
+			keys := make([]string, 0, len(entry))
+			for key := range entry {
+				if !strings.HasPrefix(key, "@") {
+					keys = append(keys, key)
+				}
+			}
+			sort.Strings(keys)
+			args := []interface{}{"stream", stream}
+			for _, key := range keys {
+				args = append(args, key, entry[key])
+			}
+			l.logger.Log(level, message, args...)
+			return
+		}
+	}
+	l.logger.Info(line, "stream", stream)
+}
+
+// since returns the kept lines after seq, how many lines after seq are no longer
+// kept, and a channel closed when the next line arrives
+func (l *PluginLogs) since(seq uint64) ([]PluginLogLine, uint64, <-chan struct{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	first := l.next - uint64(len(l.lines))
+	var missed uint64
+	if seq+1 < first {
+		missed = first - seq - 1
+	}
+	start := 0
+	if seq >= first {
+		start = int(seq - first + 1)
+	}
+	if start > len(l.lines) {
+		start = len(l.lines)
+	}
+	return append([]PluginLogLine(nil), l.lines[start:]...), missed, l.wake
+}
+
+// Lines returns the kept lines, oldest first
+func (l *PluginLogs) Lines() []PluginLogLine {
+	lines, _, _ := l.since(0)
+	return lines
+}
+
+// HandlePluginLogs serves GET /v1/sys/plugin/logs: the lines the plugin wrote after
+// the since query parameter (all kept lines by default), only the last of them with
+// lines=N, only one stream with stream=stdout or stream=stderr. With follow=true the
+// lines are streamed as server-sent events as they are written, each with its
+// sequence number as the event ID so a reconnecting client resumes where it stopped.
+func (h *Handler) HandlePluginLogs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	logs := h.PluginLogs()
+	if logs == nil {
+		WriteError(w, http.StatusNotFound, "plugin output is not captured")
+		return
+	}
+
+	// Without a position the client asked for whatever is kept, so nothing is missed
+	query := r.URL.Query()
+	var since uint64
+	resume := true
+	if value := query.Get("since"); value != "" {
+		parsed, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			WriteError(w, http.StatusBadRequest, fmt.Sprintf("invalid since %q", value))
+			return
+		}
+		since = parsed
+	} else if value := r.Header.Get("Last-Event-ID"); value != "" {
+		since, _ = strconv.ParseUint(value, 10, 64)
+	} else {
+		resume = false
+	}
+	last := -1
+	if value := query.Get("lines"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			WriteError(w, http.StatusBadRequest, fmt.Sprintf("invalid lines %q", value))
+			return
+		}
+		last = n
+	}
+	stream := query.Get("stream")
+	if stream != "" && stream != "stdout" && stream != "stderr" {
+		WriteError(w, http.StatusBadRequest, "stream must be stdout or stderr")
+		return
+	}
+
+	all, missed, wake := logs.since(since)
+	if !resume {
+		missed = 0
+	}
+	lines := filterPluginLogLines(all, stream)
+	if last >= 0 && len(lines) > last {
+		lines = lines[len(lines)-last:]
+	}
+
+	if query.Get("follow") != "true" {
+		next := since
+		if len(all) > 0 {
+			next = all[len(all)-1].Seq
+		}
+		WriteJSON(w, http.StatusOK, map[string]interface{}{
+			"lines":  append([]PluginLogLine{}, lines...),
+			"missed": missed,
+			"last":   next, // pass as since to get only newer lines
+		})
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		WriteError(w, http.StatusInternalServerError, "streaming is not supported")
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	for {
+		if missed > 0 {
+			fmt.Fprintf(w, "event: plugin-log-gap\ndata: {\"missed\":%d}\n\n", missed)
+		}
+		for _, line := range lines {
+			data, _ := json.Marshal(line)
+			fmt.Fprintf(w, "id: %d\nevent: plugin-log\ndata: %s\n\n", line.Seq, data)
+		}
+		flusher.Flush()
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-wake:
+		}
+		// Lines of the other stream move the position on too
+		if len(all) > 0 {
+			since = all[len(all)-1].Seq
+		}
+		all, missed, wake = logs.since(since)
+		lines = filterPluginLogLines(all, stream)
+	}
+}
+
+// filterPluginLogLines returns the lines of stream, or all lines for an empty stream
+func filterPluginLogLines(lines []PluginLogLine, stream string) []PluginLogLine {
+	if stream == "" {
+		return lines
+	}
+	var filtered []PluginLogLine
+	for _, line := range lines {
+		if line.Stream == stream {
+			filtered = append(filtered, line)
+		}
+	}
+	return filtered
+}
+
+// SetPluginLogs makes the handler serve the plugin output that logs collects
+func (h *Handler) SetPluginLogs(logs *PluginLogs) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.logs = logs
+}
+
+// PluginLogs returns the handler's plugin output collector, or nil when none is set
+func (h *Handler) PluginLogs() *PluginLogs {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.logs
+}
@@ -0,0 +1,148 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+func TestPluginLogsWriter(t *testing.T) {
+	var output bytes.Buffer
+	logs := NewPluginLogs(hclog.New(&hclog.LoggerOptions{Output: &output, Level: hclog.Info}), 3)
+	stderr := logs.Writer("stderr")
+
+	// Lines may arrive in pieces
+	fmt.Fprint(stderr, "panic: boom\ngorou")
+	fmt.Fprint(stderr, "tine 1 [running]:\r\n")
+	fmt.Fprint(logs.Writer("stdout"), `{"@level":"warn","@message":"lease expired","@timestamp":"2025-01-01T00:00:00Z","lease":"abc"}`+"\n")
+	fmt.Fprint(stderr, `{"@level":"debug","@message":"too chatty"}`+"\n")
+
+	lines := logs.Lines()
+	if len(lines) != 3 {
+		t.Fatalf("kept %d lines, want the last 3", len(lines))
+	}
+	if lines[0].Line != "goroutine 1 [running]:" || lines[0].Seq != 2 || lines[1].Stream != "stdout" {
+		t.Errorf("unexpected lines %+v", lines)
+	}
+
+	logged := output.String()
+	if !strings.Contains(logged, "[INFO]  panic: boom: stream=stderr") {
+		t.Errorf("a plain line should be logged at info, got %q", logged)
+	}
+	if !strings.Contains(logged, "[WARN]  lease expired: stream=stdout lease=abc") {
+		t.Errorf("an hclog entry should be logged at its level with its fields, got %q", logged)
+	}
+	if strings.Contains(logged, "too chatty") {
+		t.Error("an hclog entry below the logger's level should not be logged")
+	}
+
+	// Kept output that is logged elsewhere
+	output.Reset()
+	fmt.Fprintln(logs.Keeper("stderr"), "logged by go-plugin")
+	if lines := logs.Lines(); lines[len(lines)-1].Line != "logged by go-plugin" || output.Len() != 0 {
+		t.Errorf("Keeper should keep the line without logging it, kept %+v, logged %q", lines, output.String())
+	}
+
+	var none *PluginLogs
+	fmt.Fprintln(none.Writer("stdout"), "discarded")
+	fmt.Fprintln(none.Keeper("stderr"), "discarded")
+}
+
+func TestHandlePluginLogs(t *testing.T) {
+	h := NewHandler(nil, newMockStorage(), hclog.NewNullLogger(), "plugin")
+	logs := NewPluginLogs(hclog.NewNullLogger(), 3)
+	h.SetPluginLogs(logs)
+	for i := 1; i <= 5; i++ {
+		stream := "stdout"
+		if i%2 == 0 {
+			stream = "stderr"
+		}
+		fmt.Fprintf(logs.Writer(stream), "line %d\n", i)
+	}
+
+	get := func(query string) (int, map[string]interface{}) {
+		w := httptest.NewRecorder()
+		h.HandlePluginLogs(w, httptest.NewRequest(http.MethodGet, "/v1/sys/plugin/logs"+query, nil))
+		var body map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &body)
+		return w.Code, body
+	}
+	texts := func(body map[string]interface{}) []string {
+		var texts []string
+		for _, line := range body["lines"].([]interface{}) {
+			texts = append(texts, line.(map[string]interface{})["line"].(string))
+		}
+		return texts
+	}
+
+	if code, body := get(""); code != http.StatusOK || fmt.Sprint(texts(body)) != "[line 3 line 4 line 5]" || body["missed"] != 0.0 || body["last"] != 5.0 {
+		t.Errorf("GET = %d %v", code, body)
+	}
+	if _, body := get("?since=1"); body["missed"] != 1.0 {
+		t.Errorf("lines after 1 dropped from the buffer should be reported missed, got %v", body["missed"])
+	}
+	if _, body := get("?lines=1&stream=stdout"); fmt.Sprint(texts(body)) != "[line 5]" {
+		t.Errorf("last stdout line = %v", texts(body))
+	}
+	if _, body := get("?since=5"); len(texts(body)) != 0 || body["last"] != 5.0 {
+		t.Errorf("GET since the last line = %v", body)
+	}
+	for _, query := range []string{"?since=x", "?lines=-1", "?stream=both"} {
+		if code, _ := get(query); code != http.StatusBadRequest {
+			t.Errorf("GET %s = %d, want 400", query, code)
+		}
+	}
+
+	h.SetPluginLogs(nil)
+	if code, _ := get(""); code != http.StatusNotFound {
+		t.Errorf("GET without captured output = %d, want 404", code)
+	}
+}
+
+func TestHandlePluginLogsFollow(t *testing.T) {
+	h := NewHandler(nil, newMockStorage(), hclog.NewNullLogger(), "plugin")
+	logs := NewPluginLogs(hclog.NewNullLogger(), 10)
+	h.SetPluginLogs(logs)
+	fmt.Fprintln(logs.Writer("stderr"), "before")
+
+	server := httptest.NewServer(http.HandlerFunc(h.HandlePluginLogs))
+	defer server.Close()
+	resp, err := http.Get(server.URL + "/v1/sys/plugin/logs?follow=true&stream=stderr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		fmt.Fprintln(logs.Writer("stdout"), "other stream")
+		fmt.Fprintln(logs.Writer("stderr"), "after")
+	}()
+
+	var events []string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() && len(events) < 2 {
+		if data, ok := strings.CutPrefix(scanner.Text(), "data: "); ok {
+			var line PluginLogLine
+			json.Unmarshal([]byte(data), &line)
+			events = append(events, fmt.Sprintf("%d %s", line.Seq, line.Line))
+		}
+	}
+	if fmt.Sprint(events) != "[1 before 3 after]" {
+		t.Errorf("streamed %v", events)
+	}
+}
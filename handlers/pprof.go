@@ -0,0 +1,47 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+)
+
+// pprofPrefix is the host path under which the plugin's pprof endpoints are proxied
+const pprofPrefix = "/v1/sys/host/plugin/pprof"
+
+// SetPprofAddr sets the host:port on which the plugin serves net/http/pprof (empty disables the proxy)
+func (h *Handler) SetPprofAddr(addr string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.pprofAddr = addr
+}
+
+// HandlePluginPprof proxies /v1/sys/host/plugin/pprof/* to the plugin's /debug/pprof/*
+func (h *Handler) HandlePluginPprof(w http.ResponseWriter, r *http.Request) {
+	h.mu.RLock()
+	addr := h.pprofAddr
+	h.mu.RUnlock()
+
+	if addr == "" {
+		h.writeVaultError(w, http.StatusNotFound, "plugin pprof is not enabled")
+		return
+	}
+
+	target := &url.URL{Scheme: "http", Host: addr}
+	proxy := &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.SetURL(target)
+			pr.Out.URL.Path = "/debug/pprof/" + strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, pprofPrefix), "/")
+			pr.Out.URL.RawPath = ""
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			h.logger.Warn("plugin pprof proxy failed", "addr", addr, "error", err)
+			h.writeVaultError(w, http.StatusBadGateway, "plugin pprof unavailable: "+err.Error())
+		},
+	}
+	proxy.ServeHTTP(w, r)
+}
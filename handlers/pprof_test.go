@@ -0,0 +1,51 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+func TestHandlePluginPprofProxy(t *testing.T) {
+	var gotPath string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		w.Write([]byte("profile"))
+	}))
+	defer upstream.Close()
+
+	handler := NewHandler(nil, newMockStorage(), hclog.NewNullLogger(), "plugin")
+	handler.SetPprofAddr(strings.TrimPrefix(upstream.URL, "http://"))
+
+	req := httptest.NewRequest("GET", "/v1/sys/host/plugin/pprof/heap?gc=1", nil)
+	w := httptest.NewRecorder()
+	handler.HandlePluginPprof(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Status code = %d, want %d", w.Code, http.StatusOK)
+	}
+	if gotPath != "/debug/pprof/heap" {
+		t.Errorf("upstream path = %s, want /debug/pprof/heap", gotPath)
+	}
+	if w.Body.String() != "profile" {
+		t.Errorf("body = %q, want profile", w.Body.String())
+	}
+}
+
+func TestHandlePluginPprofDisabled(t *testing.T) {
+	handler := NewHandler(nil, newMockStorage(), hclog.NewNullLogger(), "plugin")
+
+	req := httptest.NewRequest("GET", "/v1/sys/host/plugin/pprof/heap", nil)
+	w := httptest.NewRecorder()
+	handler.HandlePluginPprof(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Status code = %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
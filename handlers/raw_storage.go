@@ -0,0 +1,157 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// maxRawStorageBytes bounds the body of a raw storage write
+const maxRawStorageBytes = 32 << 20
+
+// HandleRawStorage serves /v1/sys/storage/raw/<key>, reading and changing single
+// entries of plugin storage like Vault's sys/raw:
+//
+//	GET    /v1/sys/storage/raw/<key>     - the entry's value; ?encoding=base64 for binary values
+//	PUT    /v1/sys/storage/raw/<key>     - write {"value": ..., "encoding": "base64"?}
+//	DELETE /v1/sys/storage/raw/<key>     - delete the entry
+//	LIST   /v1/sys/storage/raw/<prefix>  - key segments below prefix
+//
+// Writes bypass the plugin, so they can leave entries the plugin does not expect.
+func (h *Handler) HandleRawStorage(w http.ResponseWriter, r *http.Request) {
+	key := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/v1/sys/storage/raw"), "/")
+	if isListRequest(r) {
+		h.listRawStorage(w, r, listPath(key))
+		return
+	}
+	if key == "" {
+		h.writeVaultError(w, http.StatusBadRequest, "missing storage key")
+		return
+	}
+
+	ctx := r.Context()
+	switch r.Method {
+	case http.MethodGet:
+		encoding := r.URL.Query().Get("encoding")
+		if encoding != "" && encoding != "base64" {
+			h.writeVaultError(w, http.StatusBadRequest, fmt.Sprintf("invalid encoding %q", encoding))
+			return
+		}
+		entry, err := h.storage.Get(ctx, key)
+		if err != nil {
+			h.writeVaultError(w, http.StatusInternalServerError, fmt.Sprintf("failed to read storage: %v", err))
+			return
+		}
+		if entry == nil {
+			WriteJSON(w, http.StatusNotFound, map[string][]string{"errors": {}})
+			return
+		}
+		value := string(entry.Value)
+		if encoding == "base64" {
+			value = base64.StdEncoding.EncodeToString(entry.Value)
+		}
+		WriteJSON(w, http.StatusOK, map[string]interface{}{
+			"request_id": newRequestID(),
+			"data":       map[string]interface{}{"value": value},
+		})
+
+	case http.MethodPut, http.MethodPost:
+		var body struct {
+			Value    *string `json:"value"`
+			Encoding string  `json:"encoding"`
+		}
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRawStorageBytes)).Decode(&body); err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				h.writeVaultError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("value exceeds %d bytes", tooLarge.Limit))
+				return
+			}
+			h.writeVaultError(w, http.StatusBadRequest, fmt.Sprintf("failed to parse JSON: %v", err))
+			return
+		}
+		if body.Value == nil {
+			h.writeVaultError(w, http.StatusBadRequest, "missing value")
+			return
+		}
+		value := []byte(*body.Value)
+		switch body.Encoding {
+		case "":
+		case "base64":
+			decoded, err := base64.StdEncoding.DecodeString(*body.Value)
+			if err != nil {
+				h.writeVaultError(w, http.StatusBadRequest, fmt.Sprintf("invalid base64 value: %v", err))
+				return
+			}
+			value = decoded
+		default:
+			h.writeVaultError(w, http.StatusBadRequest, fmt.Sprintf("invalid encoding %q", body.Encoding))
+			return
+		}
+		if err := h.storage.Put(ctx, &logical.StorageEntry{Key: key, Value: value}); err != nil {
+			h.writeVaultError(w, http.StatusInternalServerError, fmt.Sprintf("failed to write storage: %v", err))
+			return
+		}
+		h.logger.Info("raw storage entry written", "key", key, "bytes", len(value))
+		w.WriteHeader(http.StatusNoContent)
+
+	case http.MethodDelete:
+		if err := h.storage.Delete(ctx, key); err != nil {
+			h.writeVaultError(w, http.StatusInternalServerError, fmt.Sprintf("failed to delete storage: %v", err))
+			return
+		}
+		h.logger.Info("raw storage entry deleted", "key", key)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		h.writeVaultError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// listRawStorage writes the next key segment of every storage key under prefix, with
+// a trailing slash for segments that have more below them
+func (h *Handler) listRawStorage(w http.ResponseWriter, r *http.Request, prefix string) {
+	keys, err := h.storage.List(r.Context(), prefix)
+	if err != nil {
+		h.writeVaultError(w, http.StatusInternalServerError, fmt.Sprintf("failed to list storage: %v", err))
+		return
+	}
+
+	seen := make(map[string]bool)
+	for _, key := range keys {
+		// Host storage lists every key below the prefix, in full
+		rest, ok := strings.CutPrefix(key, prefix)
+		if !ok || rest == "" {
+			continue
+		}
+		if i := strings.Index(rest, "/"); i >= 0 {
+			rest = rest[:i+1]
+		}
+		seen[rest] = true
+	}
+
+	// Vault answers an empty LIST with a 404 and no error messages
+	if len(seen) == 0 {
+		WriteJSON(w, http.StatusNotFound, map[string][]string{"errors": {}})
+		return
+	}
+
+	segments := make([]string, 0, len(seen))
+	for segment := range seen {
+		segments = append(segments, segment)
+	}
+	sort.Strings(segments)
+
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"request_id": newRequestID(),
+		"data":       map[string]interface{}{"keys": segments},
+	})
+}
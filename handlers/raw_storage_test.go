@@ -0,0 +1,134 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestHandleRawStorage(t *testing.T) {
+	storage := newMockStorage()
+	handler := NewHandler(nil, storage, hclog.NewNullLogger(), "plugin")
+	ctx := context.Background()
+	for key, value := range map[string]string{"config": `{"url":"a"}`, "roles/dev": "{}", "roles/prod": "{}", "roles/team/ops": "{}"} {
+		storage.Put(ctx, &logical.StorageEntry{Key: key, Value: []byte(value)})
+	}
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.HandleRawStorage(w, httptest.NewRequest(method, "/v1/sys/storage/raw/"+path, strings.NewReader(body)))
+		return w
+	}
+	value := func(w *httptest.ResponseRecorder) string {
+		var resp struct {
+			Data struct {
+				Value string `json:"value"`
+			} `json:"data"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return resp.Data.Value
+	}
+
+	if w := send("GET", "config", ""); w.Code != http.StatusOK || value(w) != `{"url":"a"}` {
+		t.Errorf("read = %d %s", w.Code, w.Body)
+	}
+	if w := send("GET", "missing", ""); w.Code != http.StatusNotFound {
+		t.Errorf("read of a missing key = %d, want 404", w.Code)
+	}
+
+	// Corrupt an entry on purpose, then write a binary value
+	if w := send("PUT", "config", `{"value": "{not json"}`); w.Code != http.StatusNoContent {
+		t.Fatalf("write = %d %s", w.Code, w.Body)
+	}
+	if entry, _ := storage.Get(ctx, "config"); string(entry.Value) != "{not json" {
+		t.Errorf("stored value = %q", entry.Value)
+	}
+	if w := send("POST", "blob", `{"value": "AP8=", "encoding": "base64"}`); w.Code != http.StatusNoContent {
+		t.Fatalf("base64 write = %d %s", w.Code, w.Body)
+	}
+	if entry, _ := storage.Get(ctx, "blob"); string(entry.Value) != "\x00\xff" {
+		t.Errorf("stored binary value = %q", entry.Value)
+	}
+	if w := send("GET", "blob?encoding=base64", ""); value(w) != "AP8=" {
+		t.Errorf("base64 read = %s", w.Body)
+	}
+
+	for _, c := range []struct{ method, path, body string }{
+		{"PUT", "config", `{}`},
+		{"PUT", "config", `{"value": "x", "encoding": "hex"}`},
+		{"PUT", "config", `{"value": "%%%", "encoding": "base64"}`},
+		{"PUT", "", `{"value": "x"}`},
+		{"GET", "config?encoding=hex", ""},
+	} {
+		if w := send(c.method, c.path, c.body); w.Code != http.StatusBadRequest {
+			t.Errorf("%s %s %s = %d, want 400", c.method, c.path, c.body, w.Code)
+		}
+	}
+
+	for _, c := range []struct {
+		method, path string
+		want         []string
+	}{
+		{"LIST", "", []string{"blob", "config", "roles/"}},
+		{"LIST", "roles", []string{"dev", "prod", "team/"}},
+		{"GET", "roles/?list=true", []string{"dev", "prod", "team/"}},
+	} {
+		w := send(c.method, c.path, "")
+		var resp struct {
+			Data struct {
+				Keys []string `json:"keys"`
+			} `json:"data"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		if strings.Join(resp.Data.Keys, ",") != strings.Join(c.want, ",") {
+			t.Errorf("%s %s = %v, want %v", c.method, c.path, resp.Data.Keys, c.want)
+		}
+	}
+
+	if w := send("DELETE", "roles/dev", ""); w.Code != http.StatusNoContent {
+		t.Errorf("delete = %d", w.Code)
+	}
+	if entry, _ := storage.Get(ctx, "roles/dev"); entry != nil {
+		t.Error("deleted entry is still stored")
+	}
+	if w := send("LIST", "missing/", ""); w.Code != http.StatusNotFound {
+		t.Errorf("empty list = %d, want 404", w.Code)
+	}
+}
+
+func TestHandleRawStorageRequiresRoot(t *testing.T) {
+	storage := newMockStorage()
+	handler := NewHandler(nil, storage, hclog.NewNullLogger(), "plugin")
+	store := NewTokenStore("root-token")
+	store.Enforce(true)
+	guarded := store.RequireRoot(handler.HandleRawStorage)
+
+	put := func(token string) int {
+		req := httptest.NewRequest(http.MethodPut, "/v1/sys/storage/raw/config", strings.NewReader(`{"value": "changed"}`))
+		if token != "" {
+			req.Header.Set("X-Vault-Token", token)
+		}
+		w := httptest.NewRecorder()
+		guarded(w, req)
+		return w.Code
+	}
+
+	if code := put(""); code != http.StatusForbidden {
+		t.Errorf("unauthenticated PUT: status = %d, want 403", code)
+	}
+	if entry, _ := storage.Get(context.Background(), "config"); entry != nil {
+		t.Error("an unauthenticated PUT should not write the entry")
+	}
+	if code := put("root-token"); code != http.StatusNoContent {
+		t.Errorf("PUT with the root token: status = %d, want 204", code)
+	}
+}
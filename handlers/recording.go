@@ -0,0 +1,241 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxRecordingLine bounds the size of a single recorded request
+const maxRecordingLine = 64 << 20
+
+// unrecordedHeaders carry credentials, which are never written to a recording
+var unrecordedHeaders = []string{"X-Vault-Token", "Authorization", "Cookie", "Proxy-Authorization"}
+
+// RecordedRequest is one line of a recording: a plugin request as the client sent it
+// and the response it got. Bodies are kept as JSON when they are JSON, else as strings.
+type RecordedRequest struct {
+	Time     time.Time       `json:"time"`
+	Method   string          `json:"method"`
+	Path     string          `json:"path"`
+	Query    string          `json:"query,omitempty"`
+	Header   http.Header     `json:"header,omitempty"`
+	Body     json.RawMessage `json:"body,omitempty"`
+	Status   int             `json:"status"`
+	Response json.RawMessage `json:"response,omitempty"`
+	Duration string          `json:"duration"`
+}
+
+// rawJSON returns b as a JSON value: itself when it is JSON, else a JSON string
+func rawJSON(b []byte) json.RawMessage {
+	b = bytes.TrimSpace(b)
+	if len(b) == 0 {
+		return nil
+	}
+	if json.Valid(b) {
+		return json.RawMessage(b)
+	}
+	encoded, _ := json.Marshal(string(b))
+	return encoded
+}
+
+// rawBytes reverses rawJSON
+func rawBytes(raw json.RawMessage) []byte {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return []byte(s)
+	}
+	return raw
+}
+
+// RequestRecorder appends every plugin request a router serves, with its response,
+// to a file of newline-delimited JSON that ReplayRecording can run again
+type RequestRecorder struct {
+	mu     sync.Mutex
+	w      io.Writer
+	closer io.Closer
+}
+
+// NewRequestRecorder creates a recorder appending to the file at path. The file is
+// only readable by its owner since responses may hold credentials.
+func NewRequestRecorder(path string) (*RequestRecorder, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open recording: %w", err)
+	}
+	return &RequestRecorder{w: f, closer: f}, nil
+}
+
+// newRequestRecorderWriter creates a recorder writing to w, for tests
+func newRequestRecorderWriter(w io.Writer) *RequestRecorder {
+	return &RequestRecorder{w: w}
+}
+
+// Close closes the recording file
+func (rec *RequestRecorder) Close() error {
+	if rec.closer == nil {
+		return nil
+	}
+	return rec.closer.Close()
+}
+
+// serve passes a request to next and records it with the response the client got
+func (rec *RequestRecorder) serve(w http.ResponseWriter, r *http.Request, next func(http.ResponseWriter, *http.Request)) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, fmt.Sprintf("failed to read body: %v", err))
+		return
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	header := r.Header.Clone()
+	for _, name := range unrecordedHeaders {
+		header.Del(name)
+	}
+	header.Del("Content-Length")
+	if len(header) == 0 {
+		header = nil
+	}
+
+	capture := &captureWriter{ResponseWriter: w}
+	start := time.Now()
+	next(capture, r)
+
+	status := capture.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	line, err := json.Marshal(RecordedRequest{
+		Time:     start.UTC(),
+		Method:   r.Method,
+		Path:     r.URL.Path,
+		Query:    r.URL.RawQuery,
+		Header:   header,
+		Body:     rawJSON(body),
+		Status:   status,
+		Response: rawJSON(capture.body.Bytes()),
+		Duration: time.Since(start).String(),
+	})
+	if err != nil {
+		return
+	}
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	rec.w.Write(append(line, '\n'))
+}
+
+// SetRecorder makes the router record every request it sends to a mount; nil stops
+// recording
+func (rt *Router) SetRecorder(rec *RequestRecorder) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	rt.recorder = rec
+}
+
+// ReplayDivergence is a replayed request whose response differed from the recorded one
+type ReplayDivergence struct {
+	Line           int         `json:"line"`
+	Method         string      `json:"method"`
+	Path           string      `json:"path"`
+	Fields         []string    `json:"fields,omitempty"`
+	RecordedStatus int         `json:"recorded_status"`
+	Status         int         `json:"status"`
+	Recorded       interface{} `json:"recorded,omitempty"`
+	Replayed       interface{} `json:"replayed,omitempty"`
+}
+
+// ReplayOptions adjust how ReplayRecording sends and compares requests
+type ReplayOptions struct {
+	// Token is sent as X-Vault-Token with every request, since recordings hold none
+	Token string
+	// Ignore lists dotted response fields, such as "data.password", left out of the
+	// comparison along with everything below them
+	Ignore []string
+}
+
+// ReplayRecording sends every request of a recording to handler in order and calls
+// report with each one whose status or response differs from the recorded one. The
+// fields that differ in every response, such as request and lease IDs, are not
+// compared. It returns the number of requests replayed.
+func ReplayRecording(handler http.Handler, in io.Reader, opts ReplayOptions, report func(ReplayDivergence) error) (int, error) {
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxRecordingLine)
+
+	replayed, line := 0, 0
+	for scanner.Scan() {
+		line++
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var recorded RecordedRequest
+		if err := json.Unmarshal(scanner.Bytes(), &recorded); err != nil {
+			return replayed, fmt.Errorf("line %d: invalid recorded request: %w", line, err)
+		}
+
+		req := httptest.NewRequest(recorded.Method, recorded.Path, bytes.NewReader(rawBytes(recorded.Body)))
+		req.URL.RawQuery = recorded.Query
+		for name, values := range recorded.Header {
+			req.Header[name] = values
+		}
+		if opts.Token != "" {
+			req.Header.Set("X-Vault-Token", opts.Token)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		replayed++
+
+		want := normalizedBody(rawBytes(recorded.Response))
+		got := normalizedBody(w.Body.Bytes())
+		for _, field := range opts.Ignore {
+			want = withoutField(want, field)
+			got = withoutField(got, field)
+		}
+		if w.Code == recorded.Status && reflect.DeepEqual(want, got) {
+			continue
+		}
+		divergence := ReplayDivergence{
+			Line:           line,
+			Method:         recorded.Method,
+			Path:           recorded.Path,
+			Fields:         diffFields("", want, got, nil),
+			RecordedStatus: recorded.Status,
+			Status:         w.Code,
+			Recorded:       want,
+			Replayed:       got,
+		}
+		if err := report(divergence); err != nil {
+			return replayed, err
+		}
+	}
+	return replayed, scanner.Err()
+}
+
+// withoutField removes a dotted field from a decoded JSON value
+func withoutField(v interface{}, field string) interface{} {
+	object, ok := v.(map[string]interface{})
+	if !ok {
+		return v
+	}
+	key, rest, nested := strings.Cut(field, ".")
+	if !nested {
+		delete(object, key)
+		return object
+	}
+	if child, ok := object[key]; ok {
+		object[key] = withoutField(child, rest)
+	}
+	return object
+}
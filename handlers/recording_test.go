@@ -0,0 +1,97 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// versionedBackend answers like successive builds of a plugin: version 2 renames a field
+type versionedBackend struct {
+	version int
+}
+
+func (b *versionedBackend) HandleRequest(ctx context.Context, req *logical.Request) (*logical.Response, error) {
+	data := map[string]interface{}{"path": req.Path, "password": "p-" + newRequestID()}
+	if b.version > 1 {
+		data["username_v2"] = req.Data["username"]
+	} else {
+		data["username"] = req.Data["username"]
+	}
+	return &logical.Response{Data: data}, nil
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	backend := &versionedBackend{version: 1}
+	router := NewRouter()
+	router.Mount("plugin", NewHandler(backend, newMockStorage(), hclog.NewNullLogger(), "plugin"), nil)
+	router.HandleFunc("/v1/sys/health", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	var recording bytes.Buffer
+	router.SetRecorder(newRequestRecorderWriter(&recording))
+	for _, req := range []*http.Request{
+		httptest.NewRequest("PUT", "/v1/plugin/creds/web", strings.NewReader(`{"username":"alice"}`)),
+		httptest.NewRequest("GET", "/v1/plugin/config?verbose=true", nil),
+		httptest.NewRequest("GET", "/v1/sys/health", nil),
+	} {
+		req.Header.Set("X-Vault-Token", "s3cret-token")
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	router.SetRecorder(nil)
+
+	// Only plugin requests are recorded, without their tokens
+	lines := strings.Split(strings.TrimSpace(recording.String()), "\n")
+	if len(lines) != 2 || strings.Contains(recording.String(), "s3cret-token") {
+		t.Fatalf("recording = %s", recording.String())
+	}
+	var first RecordedRequest
+	json.Unmarshal([]byte(lines[0]), &first)
+	if first.Method != "PUT" || first.Path != "/v1/plugin/creds/web" || first.Status != http.StatusOK || string(first.Body) != `{"username":"alice"}` {
+		t.Errorf("first recorded request = %+v", first)
+	}
+
+	replay := func(opts ReplayOptions) (int, []ReplayDivergence) {
+		var divergences []ReplayDivergence
+		replayed, err := ReplayRecording(router, strings.NewReader(recording.String()), opts, func(d ReplayDivergence) error {
+			divergences = append(divergences, d)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("ReplayRecording failed: %v", err)
+		}
+		return replayed, divergences
+	}
+
+	// Generated values differ on every run, so they are ignored
+	if replayed, divergences := replay(ReplayOptions{Ignore: []string{"data.password"}}); replayed != 2 || len(divergences) != 0 {
+		t.Fatalf("replay against the same build: %d replayed, divergences %+v", replayed, divergences)
+	}
+	if _, divergences := replay(ReplayOptions{}); len(divergences) != 2 {
+		t.Errorf("replay without ignoring the password: %d divergences, want 2", len(divergences))
+	}
+
+	backend.version = 2
+	_, divergences := replay(ReplayOptions{Ignore: []string{"data.password"}})
+	if len(divergences) != 2 {
+		t.Fatalf("replay against the new build: divergences %+v, want 2", divergences)
+	}
+	if d := divergences[0]; d.Line != 1 || d.Path != "/v1/plugin/creds/web" || strings.Join(d.Fields, ",") != "data.username,data.username_v2" {
+		t.Errorf("divergence = %+v", d)
+	}
+
+	if _, err := ReplayRecording(router, strings.NewReader("{not json\n"), ReplayOptions{}, func(ReplayDivergence) error { return nil }); err == nil {
+		t.Error("replaying an invalid recording should fail")
+	}
+}
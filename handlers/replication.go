@@ -0,0 +1,256 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/vault/sdk/helper/consts"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// replicationStates names the replication states accepted by ParseReplicationState
+var replicationStates = map[string]consts.ReplicationState{
+	"perf-primary":   consts.ReplicationPerformancePrimary,
+	"perf-secondary": consts.ReplicationPerformanceSecondary,
+	"dr-primary":     consts.ReplicationDRPrimary,
+	"dr-secondary":   consts.ReplicationDRSecondary,
+	"perf-standby":   consts.ReplicationPerformanceStandby,
+	"perfstandby":    consts.ReplicationPerformanceStandby, // as StateStrings reports it
+}
+
+// ParseReplicationState parses a comma-separated list of perf-primary, perf-secondary,
+// dr-primary, dr-secondary and perf-standby. An empty string or "disabled" means no
+// replication.
+func ParseReplicationState(s string) (consts.ReplicationState, error) {
+	var state consts.ReplicationState
+	if s == "" || s == "disabled" {
+		return state, nil
+	}
+	for _, name := range strings.Split(s, ",") {
+		flag, ok := replicationStates[strings.TrimSpace(name)]
+		if !ok {
+			return 0, fmt.Errorf("unknown replication state %q (expected perf-primary, perf-secondary, dr-primary, dr-secondary or perf-standby)", name)
+		}
+		state.AddState(flag)
+	}
+	return state, nil
+}
+
+// Replication is the simulated replication state of the host. On a secondary or a
+// performance standby, plugin storage is read-only and writes fail with Vault's
+// read-only error, which is answered with a redirect to the primary (or the active
+// node of a standby) when its address is known.
+type Replication struct {
+	mu      sync.RWMutex
+	state   consts.ReplicationState
+	primary string
+}
+
+// NewReplication creates a replication state with replication disabled
+func NewReplication() *Replication {
+	return &Replication{}
+}
+
+// Set changes the replication state and the primary's address (empty for none)
+func (r *Replication) Set(state consts.ReplicationState, primary string) error {
+	if primary != "" {
+		u, err := url.Parse(primary)
+		if err != nil || u.Host == "" {
+			return fmt.Errorf("invalid primary address %q", primary)
+		}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.state = state
+	r.primary = strings.TrimSuffix(primary, "/")
+	return nil
+}
+
+// State returns the replication state; a nil Replication is disabled
+func (r *Replication) State() consts.ReplicationState {
+	if r == nil {
+		return consts.ReplicationUnknown
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.state
+}
+
+// Primary returns the address writes are redirected to on a secondary
+func (r *Replication) Primary() string {
+	if r == nil {
+		return ""
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.primary
+}
+
+// Secondary reports whether the host is a performance or DR secondary
+func (r *Replication) Secondary() bool {
+	return r.State().HasState(consts.ReplicationPerformanceSecondary | consts.ReplicationDRSecondary)
+}
+
+// Standby reports whether the host is a performance standby
+func (r *Replication) Standby() bool {
+	return r.State().HasState(consts.ReplicationPerformanceStandby)
+}
+
+// ReadOnly reports whether plugin storage rejects writes: on a secondary, and on a
+// performance standby, which forwards writes to the active node
+func (r *Replication) ReadOnly() bool {
+	return r.Secondary() || r.Standby()
+}
+
+// Storage wraps plugin storage so writes fail with logical.ErrReadOnly while the host
+// is read-only, as the storage of replicated mounts does on Vault secondaries and
+// standbys
+func (r *Replication) Storage(storage logical.Storage) logical.Storage {
+	if r == nil {
+		return storage
+	}
+	return &replicatedStorage{Storage: storage, replication: r}
+}
+
+type replicatedStorage struct {
+	logical.Storage
+	replication *Replication
+}
+
+func (s *replicatedStorage) Put(ctx context.Context, entry *logical.StorageEntry) error {
+	if s.replication.ReadOnly() {
+		return logical.ErrReadOnly
+	}
+	return s.Storage.Put(ctx, entry)
+}
+
+func (s *replicatedStorage) Delete(ctx context.Context, key string) error {
+	if s.replication.ReadOnly() {
+		return logical.ErrReadOnly
+	}
+	return s.Storage.Delete(ctx, key)
+}
+
+// isReadOnlyError reports whether a plugin error is Vault's read-only error. The
+// error crosses the plugin's gRPC connection, so it is matched by message.
+func isReadOnlyError(err error) bool {
+	return err != nil && strings.Contains(err.Error(), logical.ErrReadOnly.Error())
+}
+
+// writeReadOnly answers a write rejected on a secondary or standby with Vault's
+// read-only error, redirecting it to the same path on the primary when the primary's
+// address is known
+func (h *Handler) writeReadOnly(w http.ResponseWriter, r *http.Request, replication *Replication) {
+	if primary := replication.Primary(); primary != "" {
+		w.Header().Set("Location", primary+r.URL.RequestURI())
+		h.writeVaultError(w, http.StatusTemporaryRedirect, logical.ErrReadOnly.Error())
+		return
+	}
+	status, _ := logical.RespondErrorCommon(&logical.Request{}, nil, logical.ErrReadOnly)
+	h.writeVaultError(w, status, logical.ErrReadOnly.Error())
+}
+
+// replicationMode describes one kind of replication as Vault's status endpoint does
+func replicationMode(state consts.ReplicationState, primary, secondary consts.ReplicationState) string {
+	switch {
+	case state.HasState(primary):
+		return "primary"
+	case state.HasState(secondary):
+		return "secondary"
+	}
+	return "disabled"
+}
+
+// HandleStatus serves Vault's /v1/sys/replication/status for clients that check
+// whether they are talking to a secondary
+func (r *Replication) HandleStatus(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	state := r.State()
+	primary := r.Primary()
+	status := func(mode string) map[string]interface{} {
+		data := map[string]interface{}{"mode": mode}
+		if mode == "secondary" && primary != "" {
+			data["primaries"] = []string{primary}
+		}
+		return data
+	}
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"request_id": newRequestID(),
+		"data": map[string]interface{}{
+			"dr":          status(replicationMode(state, consts.ReplicationDRPrimary, consts.ReplicationDRSecondary)),
+			"performance": status(replicationMode(state, consts.ReplicationPerformancePrimary, consts.ReplicationPerformanceSecondary)),
+		},
+	})
+}
+
+// HandleConfig reads (GET) or changes (PUT/POST {"state", "primary_addr"}) the
+// simulated replication state at /v1/sys/host/replication and
+// /v1/sys/test/replication-state
+func (r *Replication) HandleConfig(w http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet:
+		states := r.State().StateStrings()
+		if states == nil {
+			states = []string{}
+		}
+		WriteJSON(w, http.StatusOK, map[string]interface{}{
+			"state":        states,
+			"secondary":    r.Secondary(),
+			"standby":      r.Standby(),
+			"read_only":    r.ReadOnly(),
+			"primary_addr": r.Primary(),
+		})
+	case http.MethodPut, http.MethodPost:
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			WriteError(w, http.StatusBadRequest, fmt.Sprintf("failed to read body: %v", err))
+			return
+		}
+		var data struct {
+			State   string `json:"state"`
+			Primary string `json:"primary_addr"`
+		}
+		if err := json.Unmarshal(body, &data); err != nil {
+			WriteError(w, http.StatusBadRequest, fmt.Sprintf("failed to parse JSON: %v", err))
+			return
+		}
+		state, err := ParseReplicationState(data.State)
+		if err == nil {
+			err = r.Set(state, data.Primary)
+		}
+		if err != nil {
+			WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// SetReplication makes the handler reject writes while replication is a secondary
+func (h *Handler) SetReplication(replication *Replication) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.replication = replication
+}
+
+// Replication returns the handler's replication state, or nil when none is set
+func (h *Handler) Replication() *Replication {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.replication
+}
@@ -0,0 +1,129 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/sdk/helper/consts"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestParseReplicationState(t *testing.T) {
+	state, err := ParseReplicationState("dr-primary, perf-secondary")
+	if err != nil || !state.HasState(consts.ReplicationDRPrimary) || !state.HasState(consts.ReplicationPerformanceSecondary) {
+		t.Errorf("ParseReplicationState = %v, %v", state.StateStrings(), err)
+	}
+	if state, err := ParseReplicationState("disabled"); err != nil || state != consts.ReplicationUnknown {
+		t.Errorf("disabled = %v, %v", state, err)
+	}
+	for _, name := range []string{"perf-standby", "perfstandby"} {
+		if state, err := ParseReplicationState(name); err != nil || !state.HasState(consts.ReplicationPerformanceStandby) {
+			t.Errorf("%s = %v, %v", name, state.StateStrings(), err)
+		}
+	}
+	if _, err := ParseReplicationState("tertiary"); err == nil {
+		t.Error("an unknown state should be rejected")
+	}
+}
+
+func TestSecondaryRejectsWrites(t *testing.T) {
+	// The plugin writes to storage on updates and reads it back on reads
+	backend := funcBackend(func(ctx context.Context, req *logical.Request) (*logical.Response, error) {
+		if req.Operation == logical.UpdateOperation {
+			if err := req.Storage.Put(ctx, &logical.StorageEntry{Key: req.Path, Value: []byte("v")}); err != nil {
+				return nil, err
+			}
+			return nil, nil
+		}
+		entry, err := req.Storage.Get(ctx, req.Path)
+		if err != nil || entry == nil {
+			return nil, err
+		}
+		return &logical.Response{Data: map[string]interface{}{"value": string(entry.Value)}}, nil
+	})
+	handler := NewHandler(backend, newMockStorage(), hclog.NewNullLogger(), "plugin")
+	replication := NewReplication()
+	handler.SetReplication(replication)
+
+	do := func(method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.HandleRequest(w, httptest.NewRequest(method, path, strings.NewReader(`{}`)))
+		return w
+	}
+
+	if w := do("PUT", "/v1/plugin/config"); w.Code != http.StatusNoContent {
+		t.Fatalf("write on a primary: status = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	replication.Set(consts.ReplicationPerformanceSecondary, "")
+	w := do("PUT", "/v1/plugin/config")
+	if w.Code != http.StatusInternalServerError || !strings.Contains(w.Body.String(), logical.ErrReadOnly.Error()) {
+		t.Errorf("write on a secondary: status = %d, body = %s", w.Code, w.Body.String())
+	}
+	if w := do("GET", "/v1/plugin/config"); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"value":"v"`) {
+		t.Errorf("read on a secondary: status = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	// A performance standby forwards writes, so its storage is read-only too
+	replication.Set(consts.ReplicationPerformanceStandby, "")
+	if w := do("PUT", "/v1/plugin/config"); w.Code != http.StatusInternalServerError || !strings.Contains(w.Body.String(), logical.ErrReadOnly.Error()) {
+		t.Errorf("write on a standby: status = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	replication.Set(consts.ReplicationDRSecondary, "https://primary.example.com:8200/")
+	w = do("PUT", "/v1/plugin/config?x=1")
+	if w.Code != http.StatusTemporaryRedirect || w.Header().Get("Location") != "https://primary.example.com:8200/v1/plugin/config?x=1" {
+		t.Errorf("write with a known primary: status = %d, Location = %q", w.Code, w.Header().Get("Location"))
+	}
+}
+
+func TestReplicationEndpoints(t *testing.T) {
+	replication := NewReplication()
+
+	w := httptest.NewRecorder()
+	replication.HandleConfig(w, httptest.NewRequest("PUT", "/v1/sys/host/replication",
+		strings.NewReader(`{"state": "perf-secondary", "primary_addr": "http://primary:8300"}`)))
+	if w.Code != http.StatusNoContent || !replication.Secondary() {
+		t.Fatalf("config: status = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	replication.HandleConfig(w, httptest.NewRequest("POST", "/v1/sys/test/replication-state", strings.NewReader(`{"state": "perf-standby"}`)))
+	if w.Code != http.StatusNoContent || !replication.Standby() || replication.Secondary() || !replication.ReadOnly() {
+		t.Errorf("standby: status = %d, body = %s", w.Code, w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	replication.HandleConfig(w, httptest.NewRequest("GET", "/v1/sys/test/replication-state", nil))
+	if !strings.Contains(w.Body.String(), `"read_only":true`) || !strings.Contains(w.Body.String(), `"perfstandby"`) {
+		t.Errorf("standby config = %s", w.Body.String())
+	}
+	replication.HandleConfig(httptest.NewRecorder(), httptest.NewRequest("PUT", "/v1/sys/host/replication",
+		strings.NewReader(`{"state": "perf-secondary", "primary_addr": "http://primary:8300"}`)))
+
+	w = httptest.NewRecorder()
+	replication.HandleConfig(w, httptest.NewRequest("PUT", "/v1/sys/host/replication", strings.NewReader(`{"state": "nope"}`)))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("invalid state: status = %d, want 400", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	replication.HandleStatus(w, httptest.NewRequest("GET", "/v1/sys/replication/status", nil))
+	var status struct {
+		Data struct {
+			DR          map[string]interface{} `json:"dr"`
+			Performance map[string]interface{} `json:"performance"`
+		} `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &status)
+	if status.Data.DR["mode"] != "disabled" || status.Data.Performance["mode"] != "secondary" {
+		t.Errorf("status = %s", w.Body.String())
+	}
+}
@@ -0,0 +1,41 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import "time"
+
+// RestartStats counts plugin crashes and the automatic restarts that followed them
+type RestartStats struct {
+	Crashes     int        `json:"crashes"`
+	Restarts    int        `json:"restarts"`
+	LastCrash   *time.Time `json:"last_crash,omitempty"`
+	LastError   string     `json:"last_error,omitempty"`
+	LastRestart *time.Time `json:"last_restart,omitempty"`
+}
+
+// RecordCrash counts a plugin crash detected by the host's supervisor
+func (h *Handler) RecordCrash(err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	now := time.Now().UTC()
+	h.restarts.Crashes++
+	h.restarts.LastCrash = &now
+	h.restarts.LastError = err.Error()
+}
+
+// RecordRestart counts a successful automatic restart after a crash
+func (h *Handler) RecordRestart() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	now := time.Now().UTC()
+	h.restarts.Restarts++
+	h.restarts.LastRestart = &now
+}
+
+// RestartStats returns the plugin's crash and restart counts
+func (h *Handler) RestartStats() RestartStats {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.restarts
+}
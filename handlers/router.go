@@ -0,0 +1,347 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// MountFactory creates a handler for a new mount from the options posted to /v1/sys/mounts/<path>.
+// The returned cleanup function is called when the mount is removed.
+type MountFactory func(path string, options map[string]interface{}) (*Handler, func(), error)
+
+// mountEntry is a mounted plugin handler
+type mountEntry struct {
+	path    string
+	handler *Handler
+	cleanup func()
+}
+
+// Router dispatches /v1/<mount>/ requests to the handler of the longest matching mount
+// and everything else to registered system routes. Mounts can be added and removed at runtime.
+type Router struct {
+	mu       sync.RWMutex
+	mounts   map[string]*mountEntry
+	mirrors  map[string]*Mirror
+	recorder *RequestRecorder // records the requests sent to mounts when set
+	mux      *http.ServeMux
+	factory  MountFactory
+}
+
+// NewRouter creates an empty router
+func NewRouter() *Router {
+	return &Router{
+		mounts:  make(map[string]*mountEntry),
+		mirrors: make(map[string]*Mirror),
+		mux:     http.NewServeMux(),
+	}
+}
+
+// normalizeMount strips surrounding slashes from a mount path
+func normalizeMount(path string) string {
+	return strings.Trim(path, "/")
+}
+
+// Mount registers a handler under /v1/<path>/
+func (rt *Router) Mount(path string, handler *Handler, cleanup func()) error {
+	path = normalizeMount(path)
+	if path == "" {
+		return fmt.Errorf("mount path is required")
+	}
+	if path == "sys" || strings.HasPrefix(path, "sys/") {
+		return fmt.Errorf("mount path %q is reserved", path)
+	}
+
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	if _, exists := rt.mounts[path]; exists {
+		return fmt.Errorf("path is already in use at %s/", path)
+	}
+	rt.mounts[path] = &mountEntry{path: path, handler: handler, cleanup: cleanup}
+	return nil
+}
+
+// Unmount removes a mount and runs its cleanup function
+func (rt *Router) Unmount(path string) error {
+	path = normalizeMount(path)
+
+	rt.mu.Lock()
+	entry, exists := rt.mounts[path]
+	delete(rt.mounts, path)
+	rt.mu.Unlock()
+
+	if !exists {
+		return fmt.Errorf("no mount at %s/", path)
+	}
+	if entry.cleanup != nil {
+		entry.cleanup()
+	}
+	return nil
+}
+
+// Mounts returns the mounted paths in sorted order
+func (rt *Router) Mounts() []string {
+	rt.mu.RLock()
+	defer rt.mu.RUnlock()
+
+	paths := make([]string, 0, len(rt.mounts))
+	for path := range rt.mounts {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// Lookup returns the handler mounted at exactly path
+func (rt *Router) Lookup(path string) (*Handler, bool) {
+	rt.mu.RLock()
+	defer rt.mu.RUnlock()
+
+	entry, ok := rt.mounts[normalizeMount(path)]
+	if !ok {
+		return nil, false
+	}
+	return entry.handler, true
+}
+
+// MountFor returns the handler of the longest mount containing path, relative to
+// /v1/, such as the mount a lease ID belongs to
+func (rt *Router) MountFor(path string) (*Handler, bool) {
+	entry := rt.mountFor(strings.TrimPrefix(path, "/"))
+	if entry == nil {
+		return nil, false
+	}
+	return entry.handler, true
+}
+
+// match finds the mount for a request path using the longest matching mount
+func (rt *Router) match(urlPath string) (*mountEntry, bool) {
+	if !strings.HasPrefix(urlPath, "/v1/") {
+		return nil, false
+	}
+	best := rt.mountFor(strings.TrimPrefix(urlPath, "/v1/"))
+	if best == nil {
+		return nil, false
+	}
+	return best, true
+}
+
+// mountFor returns the longest mount containing a path relative to /v1/, or nil
+func (rt *Router) mountFor(rest string) *mountEntry {
+	rt.mu.RLock()
+	defer rt.mu.RUnlock()
+
+	var best *mountEntry
+	for path, entry := range rt.mounts {
+		if rest == path || strings.HasPrefix(rest, path+"/") {
+			if best == nil || len(path) > len(best.path) {
+				best = entry
+			}
+		}
+	}
+	return best
+}
+
+// SetMountFactory enables creating mounts through POST /v1/sys/mounts/<path>
+func (rt *Router) SetMountFactory(factory MountFactory) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	rt.factory = factory
+}
+
+// Handle registers a system route
+func (rt *Router) Handle(pattern string, handler http.Handler) {
+	rt.mux.Handle(pattern, handler)
+}
+
+// HandleFunc registers a system route
+func (rt *Router) HandleFunc(pattern string, handler http.HandlerFunc) {
+	rt.mux.HandleFunc(pattern, handler)
+}
+
+// ServeHTTP implements http.Handler
+func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if entry, ok := rt.match(r.URL.Path); ok {
+		rt.mu.RLock()
+		recorder := rt.recorder
+		rt.mu.RUnlock()
+		if recorder != nil {
+			recorder.serve(w, r, func(w http.ResponseWriter, r *http.Request) {
+				rt.serveMount(w, r, entry)
+			})
+			return
+		}
+		rt.serveMount(w, r, entry)
+		return
+	}
+	rt.mux.ServeHTTP(w, r)
+}
+
+// serveMount serves a request from its mount, mirroring it when the mount is mirrored
+func (rt *Router) serveMount(w http.ResponseWriter, r *http.Request, entry *mountEntry) {
+	if mirror := rt.mirrorFor(entry.path); mirror != nil {
+		rt.serveMirrored(w, r, entry, mirror)
+		return
+	}
+	entry.handler.HandleRequest(w, r)
+}
+
+// HandleMounts manages mounts at /v1/sys/mounts:
+//
+//	GET    /v1/sys/mounts              - list mounts
+//	GET    /v1/sys/mounts/<path>       - read a mount
+//	GET    /v1/sys/mounts/<path>/tune  - read a mount's lease TTLs
+//	POST   /v1/sys/mounts/<path>       - create a mount via the mount factory
+//	DELETE /v1/sys/mounts/<path>       - remove a mount
+func (rt *Router) HandleMounts(w http.ResponseWriter, r *http.Request) {
+	path := normalizeMount(strings.TrimPrefix(r.URL.Path, "/v1/sys/mounts"))
+
+	switch {
+	case path == "" && r.Method == http.MethodGet:
+		mounts := make(map[string]interface{})
+		for _, mount := range rt.Mounts() {
+			handler, ok := rt.Lookup(mount)
+			if !ok {
+				continue
+			}
+			mounts[mount+"/"] = mountOutput(handler)
+		}
+		WriteJSON(w, http.StatusOK, map[string]interface{}{"data": mounts})
+
+	case path != "" && r.Method == http.MethodGet:
+		mount, tune := path, false
+		if _, ok := rt.Lookup(mount); !ok {
+			mount, tune = strings.CutSuffix(path, "/tune")
+		}
+		handler, ok := rt.Lookup(mount)
+		if !ok {
+			WriteError(w, http.StatusBadRequest, fmt.Sprintf("no mount at %s/", mount))
+			return
+		}
+		data := mountOutput(handler)
+		if tune {
+			data = data["config"].(map[string]interface{})
+			data["description"] = ""
+			data["options"] = handler.MountOptions()
+		}
+		WriteJSON(w, http.StatusOK, map[string]interface{}{
+			"request_id":     newRequestID(),
+			"lease_id":       "",
+			"renewable":      false,
+			"lease_duration": 0,
+			"data":           data,
+			"wrap_info":      nil,
+			"warnings":       nil,
+			"auth":           nil,
+		})
+
+	case path != "" && (r.Method == http.MethodPost || r.Method == http.MethodPut):
+		rt.mu.RLock()
+		factory := rt.factory
+		rt.mu.RUnlock()
+
+		if factory == nil {
+			WriteError(w, http.StatusNotImplemented, "creating mounts at runtime is not supported")
+			return
+		}
+
+		var options map[string]interface{}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			WriteError(w, http.StatusBadRequest, fmt.Sprintf("failed to read body: %v", err))
+			return
+		}
+		if len(body) > 0 {
+			if err := json.Unmarshal(body, &options); err != nil {
+				WriteError(w, http.StatusBadRequest, fmt.Sprintf("failed to parse JSON: %v", err))
+				return
+			}
+		}
+
+		if _, exists := rt.Lookup(path); exists {
+			WriteError(w, http.StatusBadRequest, fmt.Sprintf("path is already in use at %s/", path))
+			return
+		}
+
+		handler, cleanup, err := factory(path, options)
+		if err != nil {
+			WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err := rt.Mount(path, handler, cleanup); err != nil {
+			if cleanup != nil {
+				cleanup()
+			}
+			WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	case path != "" && r.Method == http.MethodDelete:
+		if err := rt.Unmount(path); err != nil {
+			WriteError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// mountOutput describes a mount as Vault's sys/mounts does. The lease TTLs are the
+// ones in effect, so a mount inheriting the system default reports 32 days.
+func mountOutput(handler *Handler) map[string]interface{} {
+	defaultTTL, maxTTL := handler.leaseTTLs()
+	return map[string]interface{}{
+		"type":        handler.PluginType(),
+		"description": "",
+		"options":     handler.MountOptions(),
+		"config": map[string]interface{}{
+			"default_lease_ttl": int(defaultTTL.Seconds()),
+			"max_lease_ttl":     int(maxTTL.Seconds()),
+			"force_no_cache":    false,
+		},
+		"local":     false,
+		"seal_wrap": false,
+	}
+}
+
+// HandleUIMounts serves /v1/sys/internal/ui/mounts/<path> with the mount that serves
+// path, or 404 when no mount does. The Terraform provider, the vault CLI and Vault
+// Agent use it to tell KV version 2 mounts, whose options set version 2, from other
+// engines.
+func (rt *Router) HandleUIMounts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/v1/sys/internal/ui/mounts"), "/")
+	entry := rt.mountFor(path)
+	if entry == nil {
+		WriteJSON(w, http.StatusNotFound, map[string]interface{}{"errors": []string{}})
+		return
+	}
+
+	data := mountOutput(entry.handler)
+	data["path"] = entry.path + "/"
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"request_id":     newRequestID(),
+		"lease_id":       "",
+		"renewable":      false,
+		"lease_duration": 0,
+		"data":           data,
+		"wrap_info":      nil,
+		"warnings":       nil,
+		"auth":           nil,
+	})
+}
@@ -0,0 +1,204 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+func TestRouterLongestMountMatch(t *testing.T) {
+	router := NewRouter()
+	short := &mockBackend{}
+	long := &mockBackend{}
+
+	if err := router.Mount("auth", NewHandler(short, newMockStorage(), hclog.NewNullLogger(), "auth"), nil); err != nil {
+		t.Fatalf("Mount failed: %v", err)
+	}
+	if err := router.Mount("auth/userpass", NewHandler(long, newMockStorage(), hclog.NewNullLogger(), "auth/userpass"), nil); err != nil {
+		t.Fatalf("Mount failed: %v", err)
+	}
+
+	req := httptest.NewRequest("GET", "/v1/auth/userpass/users/bob", nil)
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	if !long.called || short.called {
+		t.Error("request should be routed to the longest matching mount")
+	}
+}
+
+func TestRouterMountValidation(t *testing.T) {
+	router := NewRouter()
+	handler := NewHandler(nil, newMockStorage(), hclog.NewNullLogger(), "plugin")
+
+	if err := router.Mount("sys", handler, nil); err == nil {
+		t.Error("mounting at sys should fail")
+	}
+	if err := router.Mount("/plugin/", handler, nil); err != nil {
+		t.Fatalf("Mount failed: %v", err)
+	}
+	if err := router.Mount("plugin", handler, nil); err == nil {
+		t.Error("duplicate mount should fail")
+	}
+}
+
+func TestRouterUnmountRunsCleanup(t *testing.T) {
+	router := NewRouter()
+	cleaned := false
+	router.Mount("plugin", NewHandler(&mockBackend{}, newMockStorage(), hclog.NewNullLogger(), "plugin"), func() {
+		cleaned = true
+	})
+	router.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	req := httptest.NewRequest("DELETE", "/v1/sys/mounts/plugin", nil)
+	w := httptest.NewRecorder()
+	router.HandleMounts(w, req)
+
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("Status code = %d, want %d", w.Code, http.StatusNoContent)
+	}
+	if !cleaned {
+		t.Error("cleanup was not called on unmount")
+	}
+
+	req = httptest.NewRequest("GET", "/v1/plugin/test", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Status code after unmount = %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestRouterHandleMountsCreate(t *testing.T) {
+	router := NewRouter()
+
+	req := httptest.NewRequest("POST", "/v1/sys/mounts/extra", bytes.NewBufferString(`{"plugin":"/bin/x"}`))
+	w := httptest.NewRecorder()
+	router.HandleMounts(w, req)
+	if w.Code != http.StatusNotImplemented {
+		t.Errorf("Status code without factory = %d, want %d", w.Code, http.StatusNotImplemented)
+	}
+
+	backend := &mockBackend{}
+	router.SetMountFactory(func(path string, options map[string]interface{}) (*Handler, func(), error) {
+		if options["plugin"] != "/bin/x" {
+			return nil, nil, fmt.Errorf("unexpected options %v", options)
+		}
+		handler := NewHandler(backend, newMockStorage(), hclog.NewNullLogger(), path)
+		handler.SetPluginType("x")
+		return handler, nil, nil
+	})
+
+	req = httptest.NewRequest("POST", "/v1/sys/mounts/extra", bytes.NewBufferString(`{"plugin":"/bin/x"}`))
+	w = httptest.NewRecorder()
+	router.HandleMounts(w, req)
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("Status code = %d, want %d: %s", w.Code, http.StatusNoContent, w.Body.String())
+	}
+
+	req = httptest.NewRequest("GET", "/v1/sys/mounts", nil)
+	w = httptest.NewRecorder()
+	router.HandleMounts(w, req)
+
+	var response struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if mount, ok := response.Data["extra/"].(map[string]interface{}); !ok || mount["type"] != "x" {
+		t.Errorf("extra/ missing from mounts or not typed by its plugin: %v", response.Data)
+	}
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/v1/extra/foo", nil))
+	if !backend.called {
+		t.Error("request was not routed to the new mount")
+	}
+}
+
+func TestRouterHandleMountsRead(t *testing.T) {
+	router := NewRouter()
+	kv := NewHandler(&mockBackend{}, newMockStorage(), hclog.NewNullLogger(), "secret")
+	kv.SetPluginType("kv")
+	kv.SetMountOptions(map[string]string{"version": "2"})
+	kv.SetLeaseTTLs(time.Hour, 24*time.Hour)
+	router.Mount("secret", kv, nil)
+
+	read := func(path string) (int, map[string]interface{}) {
+		w := httptest.NewRecorder()
+		router.HandleMounts(w, httptest.NewRequest("GET", path, nil))
+		var response struct {
+			Data map[string]interface{} `json:"data"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return w.Code, response.Data
+	}
+
+	// The vault api's GetMount and MountConfig decode these
+	code, mount := read("/v1/sys/mounts/secret")
+	config, _ := mount["config"].(map[string]interface{})
+	if code != http.StatusOK || mount["type"] != "kv" || config["default_lease_ttl"] != float64(3600) || config["max_lease_ttl"] != float64(86400) {
+		t.Errorf("GET sys/mounts/secret = %d, %v", code, mount)
+	}
+	code, tune := read("/v1/sys/mounts/secret/tune")
+	if options, _ := tune["options"].(map[string]interface{}); code != http.StatusOK || tune["default_lease_ttl"] != float64(3600) || options["version"] != "2" {
+		t.Errorf("GET sys/mounts/secret/tune = %d, %v", code, tune)
+	}
+	if code, _ := read("/v1/sys/mounts/missing"); code != http.StatusBadRequest {
+		t.Errorf("GET of an unknown mount = %d, want 400", code)
+	}
+
+	// Mounts without lease TTLs of their own report the system default
+	router.Mount("plugin", NewHandler(&mockBackend{}, newMockStorage(), hclog.NewNullLogger(), "plugin"), nil)
+	_, mounts := read("/v1/sys/mounts")
+	plugin, _ := mounts["plugin/"].(map[string]interface{})
+	if config, _ := plugin["config"].(map[string]interface{}); config["max_lease_ttl"] != float64(768*3600) {
+		t.Errorf("plugin/ in sys/mounts = %v", plugin)
+	}
+}
+
+func TestHandleUIMounts(t *testing.T) {
+	router := NewRouter()
+	router.Mount("plugin", NewHandler(nil, newMockStorage(), hclog.NewNullLogger(), "plugin"), nil)
+
+	w := httptest.NewRecorder()
+	router.HandleUIMounts(w, httptest.NewRequest("GET", "/v1/sys/internal/ui/mounts/plugin/roles/dev", nil))
+	var mount struct {
+		Data struct {
+			Path    string      `json:"path"`
+			Options interface{} `json:"options"`
+		} `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &mount)
+	if w.Code != http.StatusOK || mount.Data.Path != "plugin/" || mount.Data.Options != nil {
+		t.Errorf("status = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	router.HandleUIMounts(w, httptest.NewRequest("GET", "/v1/sys/internal/ui/mounts/secret/data/x", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("unmounted path: status = %d, want 404", w.Code)
+	}
+
+	// A KV v2 mount reports its version, which Vault Agent templates rely on
+	kv := NewHandler(nil, newMockStorage(), hclog.NewNullLogger(), "secret")
+	kv.SetMountOptions(map[string]string{"version": "2"})
+	router.Mount("secret", kv, nil)
+	w = httptest.NewRecorder()
+	router.HandleUIMounts(w, httptest.NewRequest("GET", "/v1/sys/internal/ui/mounts/secret/data/x", nil))
+	mount.Data.Options = nil
+	json.Unmarshal(w.Body.Bytes(), &mount)
+	if options, _ := mount.Data.Options.(map[string]interface{}); w.Code != http.StatusOK || options["version"] != "2" {
+		t.Errorf("KV v2 mount: status = %d, body = %s", w.Code, w.Body.String())
+	}
+}
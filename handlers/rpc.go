@@ -0,0 +1,229 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"google.golang.org/grpc"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// rpcTimeout bounds reflection and direct RPC calls made from the debug console
+const rpcTimeout = 10 * time.Second
+
+// GRPCService describes a gRPC service exposed by the plugin process
+type GRPCService struct {
+	Name    string   `json:"name"`
+	Methods []string `json:"methods,omitempty"`
+}
+
+// rpcMethods maps console method names onto the backend calls that issue the corresponding RPCs
+var rpcMethods = map[string]func(ctx context.Context, backend PluginBackend) (interface{}, error){
+	"special-paths": func(ctx context.Context, backend PluginBackend) (interface{}, error) {
+		b, ok := backend.(interface{ SpecialPaths() *logical.Paths })
+		if !ok {
+			return nil, fmt.Errorf("backend does not support SpecialPaths")
+		}
+		return b.SpecialPaths(), nil
+	},
+	"type": func(ctx context.Context, backend PluginBackend) (interface{}, error) {
+		b, ok := backend.(interface{ Type() logical.BackendType })
+		if !ok {
+			return nil, fmt.Errorf("backend does not support Type")
+		}
+		backendType := b.Type()
+		return map[string]interface{}{
+			"type": backendType.String(),
+			"code": int(backendType),
+		}, nil
+	},
+	"version": func(ctx context.Context, backend PluginBackend) (interface{}, error) {
+		b, ok := backend.(logical.PluginVersioner)
+		if !ok {
+			return nil, fmt.Errorf("backend does not support PluginVersion")
+		}
+		return b.PluginVersion(), nil
+	},
+}
+
+// RPCMethodNames returns the names of the RPCs that can be invoked directly
+func RPCMethodNames() []string {
+	names := make([]string, 0, len(rpcMethods))
+	for name := range rpcMethods {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SetGRPCConn sets the raw gRPC connection to the plugin (nil when not connected via gRPC)
+func (h *Handler) SetGRPCConn(conn *grpc.ClientConn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.grpcConn = conn
+}
+
+// InvokeRPC invokes one of the low-level backend RPCs by console name
+func (h *Handler) InvokeRPC(ctx context.Context, name string) (interface{}, error) {
+	h.mu.RLock()
+	backend := h.backend
+	h.mu.RUnlock()
+
+	if backend == nil {
+		return nil, fmt.Errorf("plugin not started")
+	}
+
+	call, ok := rpcMethods[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown rpc %q (available: %s)", name, strings.Join(RPCMethodNames(), ", "))
+	}
+	return call(ctx, backend)
+}
+
+// ListGRPCServices lists the gRPC services and methods of the plugin using server reflection
+func (h *Handler) ListGRPCServices(ctx context.Context) ([]GRPCService, error) {
+	h.mu.RLock()
+	conn := h.grpcConn
+	h.mu.RUnlock()
+
+	if conn == nil {
+		return nil, fmt.Errorf("no gRPC connection to plugin")
+	}
+
+	stream, err := reflectionpb.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open reflection stream: %w", err)
+	}
+	defer stream.CloseSend()
+
+	if err := stream.Send(&reflectionpb.ServerReflectionRequest{
+		MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{},
+	}); err != nil {
+		return nil, fmt.Errorf("failed to list services: %w", err)
+	}
+	resp, err := stream.Recv()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list services: %w", err)
+	}
+
+	listResp := resp.GetListServicesResponse()
+	if listResp == nil {
+		return nil, fmt.Errorf("unexpected reflection response: %v", resp.GetErrorResponse())
+	}
+
+	services := make([]GRPCService, 0, len(listResp.Service))
+	for _, svc := range listResp.Service {
+		service := GRPCService{Name: svc.Name}
+
+		// Method lookup is best effort; the service name alone is still useful
+		if err := stream.Send(&reflectionpb.ServerReflectionRequest{
+			MessageRequest: &reflectionpb.ServerReflectionRequest_FileContainingSymbol{
+				FileContainingSymbol: svc.Name,
+			},
+		}); err == nil {
+			if fileResp, err := stream.Recv(); err == nil {
+				service.Methods = serviceMethods(fileResp.GetFileDescriptorResponse(), svc.Name)
+			}
+		}
+
+		services = append(services, service)
+	}
+
+	sort.Slice(services, func(i, j int) bool { return services[i].Name < services[j].Name })
+	return services, nil
+}
+
+// serviceMethods extracts the method names of a fully-qualified service from reflection file descriptors
+func serviceMethods(resp *reflectionpb.FileDescriptorResponse, serviceName string) []string {
+	if resp == nil {
+		return nil
+	}
+
+	for _, raw := range resp.FileDescriptorProto {
+		var fd descriptorpb.FileDescriptorProto
+		if err := proto.Unmarshal(raw, &fd); err != nil {
+			continue
+		}
+		for _, svc := range fd.Service {
+			fullName := svc.GetName()
+			if fd.GetPackage() != "" {
+				fullName = fd.GetPackage() + "." + fullName
+			}
+			if fullName != serviceName {
+				continue
+			}
+
+			methods := make([]string, 0, len(svc.Method))
+			for _, m := range svc.Method {
+				methods = append(methods, m.GetName())
+			}
+			return methods
+		}
+	}
+	return nil
+}
+
+// HandleRPC serves the debug RPC console:
+//
+//	GET  /v1/sys/host/rpc          - list raw gRPC services and invokable RPCs
+//	POST /v1/sys/host/rpc/<name>   - invoke an RPC (special-paths, type, version)
+func (h *Handler) HandleRPC(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), rpcTimeout)
+	defer cancel()
+
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/v1/sys/host/rpc"), "/")
+
+	if name == "" {
+		if r.Method != http.MethodGet {
+			h.writeVaultError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		response := map[string]interface{}{
+			"rpcs": RPCMethodNames(),
+		}
+		services, err := h.ListGRPCServices(ctx)
+		if err != nil {
+			response["services_error"] = err.Error()
+		} else {
+			response["services"] = services
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	if r.Method != http.MethodGet && r.Method != http.MethodPost && r.Method != http.MethodPut {
+		h.writeVaultError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	if _, ok := rpcMethods[name]; !ok {
+		h.writeVaultError(w, http.StatusNotFound, fmt.Sprintf("unknown rpc %q", name))
+		return
+	}
+
+	result, err := h.InvokeRPC(ctx, name)
+	if err != nil {
+		h.writeVaultError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"rpc":    name,
+		"result": result,
+	})
+}
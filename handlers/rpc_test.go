@@ -0,0 +1,125 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/sdk/logical"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// rpcBackend implements the optional backend methods used by the RPC console
+type rpcBackend struct {
+	mockBackend
+}
+
+func (b *rpcBackend) SpecialPaths() *logical.Paths {
+	return &logical.Paths{Unauthenticated: []string{"login"}}
+}
+
+func (b *rpcBackend) Type() logical.BackendType {
+	return logical.TypeCredential
+}
+
+func (b *rpcBackend) PluginVersion() logical.PluginVersion {
+	return logical.PluginVersion{Version: "v1.2.3"}
+}
+
+func TestHandleRPCInvoke(t *testing.T) {
+	handler := NewHandler(&rpcBackend{}, newMockStorage(), hclog.NewNullLogger(), "plugin")
+
+	req := httptest.NewRequest("POST", "/v1/sys/host/rpc/type", nil)
+	w := httptest.NewRecorder()
+	handler.HandleRPC(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Status code = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+
+	var response map[string]interface{}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	result, ok := response["result"].(map[string]interface{})
+	if !ok || result["type"] != logical.TypeCredential.String() {
+		t.Errorf("unexpected result: %v", response["result"])
+	}
+}
+
+func TestHandleRPCUnsupportedBackend(t *testing.T) {
+	handler := NewHandler(&mockBackend{}, newMockStorage(), hclog.NewNullLogger(), "plugin")
+
+	req := httptest.NewRequest("POST", "/v1/sys/host/rpc/special-paths", nil)
+	w := httptest.NewRecorder()
+	handler.HandleRPC(w, req)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Status code = %d, want %d", w.Code, http.StatusServiceUnavailable)
+	}
+}
+
+func TestHandleRPCUnknown(t *testing.T) {
+	handler := NewHandler(&rpcBackend{}, newMockStorage(), hclog.NewNullLogger(), "plugin")
+
+	req := httptest.NewRequest("POST", "/v1/sys/host/rpc/bogus", nil)
+	w := httptest.NewRecorder()
+	handler.HandleRPC(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Status code = %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestListGRPCServices(t *testing.T) {
+	listener := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer()
+	healthpb.RegisterHealthServer(server, health.NewServer())
+	reflection.Register(server)
+	go server.Serve(listener)
+	defer server.Stop()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	defer conn.Close()
+
+	handler := NewHandler(&rpcBackend{}, newMockStorage(), hclog.NewNullLogger(), "plugin")
+	handler.SetGRPCConn(conn)
+
+	services, err := handler.ListGRPCServices(context.Background())
+	if err != nil {
+		t.Fatalf("ListGRPCServices failed: %v", err)
+	}
+
+	var found bool
+	for _, svc := range services {
+		if svc.Name == "grpc.health.v1.Health" {
+			found = true
+			if len(svc.Methods) == 0 {
+				t.Error("expected methods for grpc.health.v1.Health")
+			}
+		}
+	}
+	if !found {
+		t.Errorf("grpc.health.v1.Health not listed in %v", services)
+	}
+}
@@ -0,0 +1,52 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"net/http"
+)
+
+// VaultVersion is the Vault version reported by sys/seal-status. Clients such as the
+// Terraform provider parse it to decide which features to use.
+const VaultVersion = "1.20.0"
+
+// OpenBaoVersion is the version sys/seal-status reports when the host follows OpenBao
+const OpenBaoVersion = "2.2.0"
+
+// HandleSealStatus serves /v1/sys/seal-status for clients that check the server
+// before using it. The host is always initialized and unsealed.
+func HandleSealStatus(w http.ResponseWriter, r *http.Request) {
+	SealStatusHandler(VaultVersion)(w, r)
+}
+
+// SealStatusHandler returns a sys/seal-status handler that reports version, as a host
+// standing in for OpenBao rather than Vault does
+func SealStatusHandler(version string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		writeSealStatus(w, version)
+	}
+}
+
+func writeSealStatus(w http.ResponseWriter, version string) {
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"type":          "shamir",
+		"initialized":   true,
+		"sealed":        false,
+		"t":             1,
+		"n":             1,
+		"progress":      0,
+		"nonce":         "",
+		"version":       version,
+		"build_date":    "",
+		"migration":     false,
+		"cluster_name":  "vault-plugin-host",
+		"cluster_id":    "test-cluster",
+		"recovery_seal": false,
+		"storage_type":  "inmem",
+	})
+}
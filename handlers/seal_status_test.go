@@ -0,0 +1,32 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandleSealStatus(t *testing.T) {
+	w := httptest.NewRecorder()
+	HandleSealStatus(w, httptest.NewRequest("GET", "/v1/sys/seal-status", nil))
+
+	var status struct {
+		Sealed  bool   `json:"sealed"`
+		Version string `json:"version"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &status)
+	if w.Code != http.StatusOK || status.Sealed || status.Version != VaultVersion {
+		t.Errorf("status = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	SealStatusHandler(OpenBaoVersion)(w, httptest.NewRequest("GET", "/v1/sys/seal-status", nil))
+	json.Unmarshal(w.Body.Bytes(), &status)
+	if w.Code != http.StatusOK || status.Version != OpenBaoVersion {
+		t.Errorf("OpenBao status = %d, body = %s", w.Code, w.Body.String())
+	}
+}
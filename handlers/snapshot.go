@@ -0,0 +1,271 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	// snapshotVersion is the format version written to storage snapshots
+	snapshotVersion = 1
+	// maxRestoreBytes bounds the snapshot body /v1/sys/storage/restore reads
+	maxRestoreBytes = 256 << 20
+)
+
+// SnapshotEntry is one storage entry of a snapshot. Values are kept as bytes, so they
+// are base64 in the JSON form and binary values survive the round trip.
+type SnapshotEntry struct {
+	Key   string `json:"key"`
+	Value []byte `json:"value"`
+}
+
+// StorageSnapshot is the full content of a mount's storage
+type StorageSnapshot struct {
+	Version int             `json:"version"`
+	Mount   string          `json:"mount"`
+	Created time.Time       `json:"created"`
+	Entries []SnapshotEntry `json:"entries"`
+}
+
+// ReadStorageSnapshot decodes a snapshot written by Snapshot
+func ReadStorageSnapshot(r io.Reader) (*StorageSnapshot, error) {
+	var snapshot StorageSnapshot
+	if err := json.NewDecoder(r).Decode(&snapshot); err != nil {
+		return nil, fmt.Errorf("failed to parse snapshot: %w", err)
+	}
+	if snapshot.Version != snapshotVersion {
+		return nil, fmt.Errorf("unsupported snapshot version %d (expected %d)", snapshot.Version, snapshotVersion)
+	}
+	for _, entry := range snapshot.Entries {
+		if entry.Key == "" {
+			return nil, fmt.Errorf("snapshot contains an entry without a key")
+		}
+	}
+	return &snapshot, nil
+}
+
+// snapshotKeys lists the storage keys of the mount in the order a snapshot holds them
+func (h *Handler) snapshotKeys(ctx context.Context) ([]string, error) {
+	h.mu.RLock()
+	storage := h.storage
+	h.mu.RUnlock()
+
+	keys, err := storage.List(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list storage: %w", err)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// writeSnapshot writes a snapshot of the entries for keys to w. Like streamStorage,
+// entries are written as they are read and flushed periodically, so a large storage is
+// never held in memory as a whole.
+func (h *Handler) writeSnapshot(ctx context.Context, w io.Writer, keys []string) error {
+	h.mu.RLock()
+	storage := h.storage
+	h.mu.RUnlock()
+
+	flusher, _ := w.(http.Flusher)
+	bw := bufio.NewWriter(w)
+
+	// The header is a snapshot without entries, left open for the entries array
+	header, err := json.Marshal(struct {
+		Version int       `json:"version"`
+		Mount   string    `json:"mount"`
+		Created time.Time `json:"created"`
+	}{snapshotVersion, strings.Trim(h.mountPath, "/"), time.Now().UTC()})
+	if err != nil {
+		return err
+	}
+	bw.Write(header[:len(header)-1])
+	bw.WriteString(`,"entries":[`)
+
+	written := 0
+	for _, key := range keys {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		entry, err := storage.Get(ctx, key)
+		if err != nil {
+			return fmt.Errorf("failed to read storage entry %q: %w", key, err)
+		}
+		if entry == nil {
+			continue
+		}
+
+		item, err := json.Marshal(SnapshotEntry{Key: key, Value: entry.Value})
+		if err != nil {
+			return err
+		}
+		if written > 0 {
+			bw.WriteByte(',')
+		}
+		bw.Write(item)
+		written++
+
+		if written%storageFlushInterval == 0 {
+			if err := bw.Flush(); err != nil {
+				return err
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+	}
+	bw.WriteString("]}\n")
+	return bw.Flush()
+}
+
+// Restore writes the entries of a snapshot to storage. Unless merge is set, entries
+// not in the snapshot are deleted so storage ends up exactly as snapshotted.
+//
+// Storage that can replace its contents at once, as the host's storage does, gets the
+// whole snapshot to swap in. Otherwise entries are written before stale ones are
+// deleted, so a failed restore never leaves storage emptied.
+func (h *Handler) Restore(ctx context.Context, snapshot *StorageSnapshot, merge bool) error {
+	h.mu.RLock()
+	storage := h.storage
+	h.mu.RUnlock()
+
+	entries := make([]*logical.StorageEntry, len(snapshot.Entries))
+	keep := make(map[string]bool, len(snapshot.Entries))
+	for i, entry := range snapshot.Entries {
+		if entry.Key == "" {
+			return fmt.Errorf("snapshot contains an entry without a key")
+		}
+		entries[i] = &logical.StorageEntry{Key: entry.Key, Value: entry.Value}
+		keep[entry.Key] = true
+	}
+
+	if restorer, ok := storage.(interface {
+		RestoreEntries(ctx context.Context, entries []*logical.StorageEntry, merge bool) error
+	}); ok {
+		return restorer.RestoreEntries(ctx, entries, merge)
+	}
+
+	for _, entry := range entries {
+		if err := storage.Put(ctx, entry); err != nil {
+			return fmt.Errorf("failed to write storage entry %q: %w", entry.Key, err)
+		}
+	}
+	if merge {
+		return nil
+	}
+	keys, err := storage.List(ctx, "")
+	if err != nil {
+		return fmt.Errorf("failed to list storage: %w", err)
+	}
+	for _, key := range keys {
+		if keep[key] {
+			continue
+		}
+		if err := storage.Delete(ctx, key); err != nil {
+			return fmt.Errorf("failed to delete storage entry %q: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// HandleSnapshot returns all storage entries at /v1/sys/storage/snapshot as a JSON
+// snapshot that /v1/sys/storage/restore and -seed-storage accept. With an export
+// passphrase set, the snapshot is encrypted with it.
+func (h *Handler) HandleSnapshot(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodPut && r.Method != http.MethodGet {
+		h.writeVaultError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	keys, err := h.snapshotKeys(r.Context())
+	if err != nil {
+		h.writeVaultError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	filename := strings.ReplaceAll(strings.Trim(h.mountPath, "/"), "/", "-") + "-storage.json"
+	passphrase := h.exportPassphraseValue()
+	if passphrase == "" {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+		w.WriteHeader(http.StatusOK)
+		if err := h.writeSnapshot(r.Context(), w, keys); err != nil {
+			h.logger.Warn("storage snapshot aborted", "error", err)
+		}
+		return
+	}
+
+	// Encryption needs the whole plaintext, so an encrypted snapshot is built in memory
+	var data bytes.Buffer
+	if err := h.writeSnapshot(r.Context(), &data, keys); err != nil {
+		h.writeVaultError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	encrypted, err := EncryptExport(data.Bytes(), passphrase)
+	if err != nil {
+		h.writeVaultError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.enc"`, filename))
+	w.WriteHeader(http.StatusOK)
+	w.Write(encrypted)
+}
+
+// HandleRestore loads a snapshot posted to /v1/sys/storage/restore, replacing the
+// current storage, or adding to it with ?merge=true. Encrypted snapshots are opened
+// with the export passphrase.
+func (h *Handler) HandleRestore(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodPut {
+		h.writeVaultError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	merge := false
+	if v := r.URL.Query().Get("merge"); v != "" {
+		parsed, err := strconv.ParseBool(v)
+		if err != nil {
+			h.writeVaultError(w, http.StatusBadRequest, fmt.Sprintf("invalid merge %q", v))
+			return
+		}
+		merge = parsed
+	}
+
+	body, err := OpenExport(http.MaxBytesReader(w, r.Body, maxRestoreBytes), h.exportPassphraseValue())
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			h.writeVaultError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("snapshot exceeds %d bytes", tooLarge.Limit))
+			return
+		}
+		h.writeVaultError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	snapshot, err := ReadStorageSnapshot(body)
+	if err != nil {
+		h.writeVaultError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := h.Restore(r.Context(), snapshot, merge); err != nil {
+		h.writeVaultError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.logger.Info("storage restored from snapshot", "entries", len(snapshot.Entries), "merge", merge)
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"restored": len(snapshot.Entries),
+		"merge":    merge,
+	})
+}
@@ -0,0 +1,148 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestStorageSnapshotRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	source := newMockStorage()
+	source.Put(ctx, &logical.StorageEntry{Key: "config", Value: []byte(`{"url":"a"}`)})
+	source.Put(ctx, &logical.StorageEntry{Key: "roles/binary", Value: []byte{0xff, 0x00, 0xfe}})
+	handler := NewHandler(nil, source, hclog.NewNullLogger(), "plugin")
+
+	w := httptest.NewRecorder()
+	handler.HandleSnapshot(w, httptest.NewRequest("POST", "/v1/sys/storage/snapshot", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("snapshot failed: %d %s", w.Code, w.Body.String())
+	}
+	snapshot := w.Body.Bytes()
+	if parsed, err := ReadStorageSnapshot(bytes.NewReader(snapshot)); err != nil || len(parsed.Entries) != 2 || parsed.Mount != "plugin" {
+		t.Fatalf("streamed snapshot = %+v, %v", parsed, err)
+	}
+
+	target := newMockStorage()
+	target.Put(ctx, &logical.StorageEntry{Key: "stale", Value: []byte("x")})
+	restored := NewHandler(nil, target, hclog.NewNullLogger(), "plugin")
+
+	w = httptest.NewRecorder()
+	restored.HandleRestore(w, httptest.NewRequest("POST", "/v1/sys/storage/restore", bytes.NewReader(snapshot)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("restore failed: %d %s", w.Code, w.Body.String())
+	}
+
+	if entry, _ := target.Get(ctx, "roles/binary"); entry == nil || !bytes.Equal(entry.Value, []byte{0xff, 0x00, 0xfe}) {
+		t.Errorf("binary value should survive the round trip, got %v", entry)
+	}
+	if entry, _ := target.Get(ctx, "stale"); entry != nil {
+		t.Error("restore should remove entries missing from the snapshot")
+	}
+
+	target.Put(ctx, &logical.StorageEntry{Key: "extra", Value: []byte("x")})
+	w = httptest.NewRecorder()
+	restored.HandleRestore(w, httptest.NewRequest("POST", "/v1/sys/storage/restore?merge=true", bytes.NewReader(snapshot)))
+	if entry, _ := target.Get(ctx, "extra"); w.Code != http.StatusOK || entry == nil {
+		t.Errorf("merge should keep other entries, got %d", w.Code)
+	}
+}
+
+func TestReadStorageSnapshotValidation(t *testing.T) {
+	for _, input := range []string{
+		`not json`,
+		`{"version": 2, "entries": []}`,
+		`{"version": 1, "entries": [{"value": "eA=="}]}`,
+	} {
+		if _, err := ReadStorageSnapshot(strings.NewReader(input)); err == nil {
+			t.Errorf("snapshot %s should be rejected", input)
+		}
+	}
+}
+
+func TestEncryptedStorageSnapshot(t *testing.T) {
+	ctx := context.Background()
+	source := newMockStorage()
+	source.Put(ctx, &logical.StorageEntry{Key: "creds/admin", Value: []byte("s3cret-password")})
+	handler := NewHandler(nil, source, hclog.NewNullLogger(), "plugin")
+	handler.SetExportPassphrase("correct horse")
+
+	w := httptest.NewRecorder()
+	handler.HandleSnapshot(w, httptest.NewRequest("POST", "/v1/sys/storage/snapshot", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("snapshot failed: %d %s", w.Code, w.Body.String())
+	}
+	snapshot := w.Body.Bytes()
+	if !IsEncryptedExport(snapshot) || bytes.Contains(snapshot, []byte("creds/admin")) {
+		t.Fatal("snapshot should be encrypted")
+	}
+	if got := w.Header().Get("Content-Disposition"); !strings.Contains(got, "plugin-storage.json.enc") {
+		t.Errorf("Content-Disposition = %q", got)
+	}
+
+	for name, passphrase := range map[string]string{"no passphrase": "", "wrong passphrase": "wrong"} {
+		restored := NewHandler(nil, newMockStorage(), hclog.NewNullLogger(), "plugin")
+		restored.SetExportPassphrase(passphrase)
+		w = httptest.NewRecorder()
+		restored.HandleRestore(w, httptest.NewRequest("POST", "/v1/sys/storage/restore", bytes.NewReader(snapshot)))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", name, w.Code)
+		}
+	}
+
+	target := newMockStorage()
+	restored := NewHandler(nil, target, hclog.NewNullLogger(), "plugin")
+	restored.SetExportPassphrase("correct horse")
+	w = httptest.NewRecorder()
+	restored.HandleRestore(w, httptest.NewRequest("POST", "/v1/sys/storage/restore", bytes.NewReader(snapshot)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("restore failed: %d %s", w.Code, w.Body.String())
+	}
+	if entry, _ := target.Get(ctx, "creds/admin"); entry == nil || string(entry.Value) != "s3cret-password" {
+		t.Errorf("restored entry = %v", entry)
+	}
+
+	// A plain snapshot is still accepted with a passphrase configured
+	plain := `{"version": 1, "mount": "plugin", "entries": [{"key": "config", "value": "eA=="}]}`
+	w = httptest.NewRecorder()
+	restored.HandleRestore(w, httptest.NewRequest("POST", "/v1/sys/storage/restore", strings.NewReader(plain)))
+	if w.Code != http.StatusOK {
+		t.Errorf("plain restore failed: %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestEncryptExport(t *testing.T) {
+	data := []byte("fixture")
+	first, err := EncryptExport(data, "passphrase")
+	if err != nil {
+		t.Fatalf("EncryptExport failed: %v", err)
+	}
+	second, _ := EncryptExport(data, "passphrase")
+	if bytes.Equal(first, second) {
+		t.Error("encrypting twice should use a fresh salt and nonce")
+	}
+	if plaintext, err := DecryptExport(first, "passphrase"); err != nil || !bytes.Equal(plaintext, data) {
+		t.Fatalf("DecryptExport = %q, %v", plaintext, err)
+	}
+
+	tampered := append([]byte(nil), first...)
+	tampered[len(tampered)-1] ^= 1
+	if _, err := DecryptExport(tampered, "passphrase"); err == nil {
+		t.Error("tampered content should not decrypt")
+	}
+	if _, err := DecryptExport(first[:len(encryptedExportMagic)+4], "passphrase"); err == nil {
+		t.Error("truncated content should not decrypt")
+	}
+	if _, err := EncryptExport(data, ""); err == nil {
+		t.Error("an empty passphrase should be rejected")
+	}
+}
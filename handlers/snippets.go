@@ -0,0 +1,253 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// SnippetRequest is a request to turn into client code. Path is relative to /v1/
+// and may carry ?list=true, as the Web UI sends it.
+type SnippetRequest struct {
+	Method string                 `json:"method"`
+	Path   string                 `json:"path"`
+	Data   map[string]interface{} `json:"data,omitempty"`
+}
+
+// Snippets are equivalent ways of making a request outside the host
+type Snippets struct {
+	CLI       string `json:"vault_cli"`
+	Go        string `json:"go"`
+	Terraform string `json:"terraform"`
+}
+
+// GenerateSnippets renders a request as a vault CLI command, a Go program using the
+// Vault API client and a Terraform block. addr, when set, is the Vault address to use.
+func GenerateSnippets(addr string, req SnippetRequest) Snippets {
+	method := strings.ToUpper(req.Method)
+	path := strings.Trim(req.Path, "/")
+	path = strings.TrimPrefix(path, "v1/")
+	if i := strings.Index(path, "?"); i >= 0 {
+		if query, _ := url.ParseQuery(path[i+1:]); query.Get("list") == "true" {
+			method = "LIST"
+		}
+		path = strings.TrimSuffix(path[:i], "/")
+	}
+
+	return Snippets{
+		CLI:       cliSnippet(addr, method, path, req.Data),
+		Go:        goSnippet(addr, method, path, req.Data),
+		Terraform: terraformSnippet(method, path, req.Data),
+	}
+}
+
+func cliSnippet(addr, method, path string, data map[string]interface{}) string {
+	var b strings.Builder
+	if addr != "" {
+		fmt.Fprintf(&b, "export VAULT_ADDR=%s\n", shellQuote(addr))
+	}
+
+	switch method {
+	case http.MethodGet:
+		fmt.Fprintf(&b, "vault read %s", shellQuote(path))
+	case "LIST":
+		fmt.Fprintf(&b, "vault list %s", shellQuote(path))
+	case http.MethodDelete:
+		fmt.Fprintf(&b, "vault delete %s", shellQuote(path))
+	default:
+		if len(data) == 0 {
+			fmt.Fprintf(&b, "vault write -f %s", shellQuote(path))
+			break
+		}
+		if !flatData(data) {
+			// Nested values cannot be given as key=value pairs, so pass JSON on stdin
+			body, _ := json.MarshalIndent(data, "", "  ")
+			fmt.Fprintf(&b, "vault write %s - <<'EOF'\n%s\nEOF", shellQuote(path), body)
+			break
+		}
+		fmt.Fprintf(&b, "vault write %s", shellQuote(path))
+		for _, key := range sortedKeys(data) {
+			value, ok := data[key].(string)
+			if !ok {
+				encoded, _ := json.Marshal(data[key])
+				value = string(encoded)
+			}
+			fmt.Fprintf(&b, " \\\n    %s", shellQuote(key+"="+value))
+		}
+	}
+	return b.String()
+}
+
+func goSnippet(addr, method, path string, data map[string]interface{}) string {
+	var call string
+	switch method {
+	case http.MethodGet:
+		call = fmt.Sprintf("client.Logical().Read(%q)", path)
+	case "LIST":
+		call = fmt.Sprintf("client.Logical().List(%q)", path)
+	case http.MethodDelete:
+		call = fmt.Sprintf("client.Logical().Delete(%q)", path)
+	default:
+		if data == nil {
+			data = map[string]interface{}{}
+		}
+		call = fmt.Sprintf("client.Logical().Write(%q, %s)", path, goLiteral(data, "\t"))
+	}
+
+	var b strings.Builder
+	b.WriteString("package main\n\n")
+	b.WriteString("import (\n\t\"fmt\"\n\t\"log\"\n\n\t\"github.com/hashicorp/vault/api\"\n)\n\n")
+	b.WriteString("func main() {\n")
+	b.WriteString("\t// The client reads VAULT_TOKEN (and VAULT_ADDR unless set here) from the environment\n")
+	b.WriteString("\tconfig := api.DefaultConfig()\n")
+	if addr != "" {
+		fmt.Fprintf(&b, "\tconfig.Address = %q\n", addr)
+	}
+	b.WriteString("\tclient, err := api.NewClient(config)\n")
+	b.WriteString("\tif err != nil {\n\t\tlog.Fatal(err)\n\t}\n\n")
+	fmt.Fprintf(&b, "\tsecret, err := %s\n", call)
+	b.WriteString("\tif err != nil {\n\t\tlog.Fatal(err)\n\t}\n")
+	b.WriteString("\tfmt.Println(secret)\n")
+	b.WriteString("}\n")
+	return b.String()
+}
+
+var terraformNameInvalid = regexp.MustCompile(`[^A-Za-z0-9_]+`)
+
+func terraformSnippet(method, path string, data map[string]interface{}) string {
+	name := strings.Trim(terraformNameInvalid.ReplaceAllString(path, "_"), "_")
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "endpoint_" + name
+	}
+
+	switch method {
+	case http.MethodGet:
+		return fmt.Sprintf("data \"vault_generic_secret\" %q {\n  path = %q\n}\n", name, path)
+	case "LIST", http.MethodDelete:
+		return fmt.Sprintf("# %s %s has no Terraform equivalent: vault_generic_endpoint manages writes\n", method, path)
+	}
+
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	body, _ := json.MarshalIndent(data, "  ", "  ")
+	return fmt.Sprintf("resource \"vault_generic_endpoint\" %q {\n  path      = %q\n  data_json = <<-EOT\n  %s\n  EOT\n}\n", name, path, body)
+}
+
+// goLiteral renders a JSON value as a Go literal of map[string]interface{}, []interface{}
+// and basic types, indented for a statement at the given indentation
+func goLiteral(value interface{}, indent string) string {
+	switch v := value.(type) {
+	case nil:
+		return "nil"
+	case string:
+		return strconv.Quote(v)
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case json.Number:
+		return v.String()
+	case map[string]interface{}:
+		var b strings.Builder
+		b.WriteString("map[string]interface{}{\n")
+		for _, key := range sortedKeys(v) {
+			fmt.Fprintf(&b, "%s\t%q: %s,\n", indent, key, goLiteral(v[key], indent+"\t"))
+		}
+		b.WriteString(indent + "}")
+		return b.String()
+	case []interface{}:
+		var b strings.Builder
+		b.WriteString("[]interface{}{\n")
+		for _, item := range v {
+			fmt.Fprintf(&b, "%s\t%s,\n", indent, goLiteral(item, indent+"\t"))
+		}
+		b.WriteString(indent + "}")
+		return b.String()
+	default:
+		return fmt.Sprintf("%#v", v)
+	}
+}
+
+// flatData reports whether every value can be written as a key=value CLI argument
+func flatData(data map[string]interface{}) bool {
+	for _, value := range data {
+		switch value.(type) {
+		case map[string]interface{}, []interface{}:
+			return false
+		}
+	}
+	return true
+}
+
+var shellSafe = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./-]+$`)
+
+// shellQuote quotes s for a POSIX shell when it contains special characters
+func shellQuote(s string) string {
+	if shellSafe.MatchString(s) {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// HandleSnippets serves "copy as" snippets at /v1/sys/host/snippets:
+//
+//	GET  - snippets for every recorded example (requires -record-examples)
+//	POST - snippets for the SnippetRequest in the body
+func (h *Handler) HandleSnippets(w http.ResponseWriter, r *http.Request) {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	addr := scheme + "://" + r.Host
+
+	switch r.Method {
+	case http.MethodGet:
+		h.mu.RLock()
+		recorder := h.examples
+		h.mu.RUnlock()
+		if recorder == nil {
+			h.writeVaultError(w, http.StatusNotFound, "example recording is not enabled")
+			return
+		}
+
+		mount := strings.Trim(h.mountPath, "/")
+		results := []map[string]interface{}{}
+		for _, ex := range recorder.Examples() {
+			req := SnippetRequest{Method: ex.Method, Path: mount + "/" + ex.Path, Data: ex.Request}
+			results = append(results, map[string]interface{}{
+				"method":      ex.Method,
+				"path":        req.Path,
+				"recorded_at": ex.RecordedAt,
+				"snippets":    GenerateSnippets(addr, req),
+			})
+		}
+		WriteJSON(w, http.StatusOK, map[string]interface{}{"requests": results})
+	case http.MethodPost, http.MethodPut:
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			h.writeVaultError(w, http.StatusBadRequest, fmt.Sprintf("failed to read body: %v", err))
+			return
+		}
+		var req SnippetRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			h.writeVaultError(w, http.StatusBadRequest, fmt.Sprintf("failed to parse JSON: %v", err))
+			return
+		}
+		if req.Method == "" || strings.Trim(req.Path, "/") == "" {
+			h.writeVaultError(w, http.StatusBadRequest, "method and path are required")
+			return
+		}
+		WriteJSON(w, http.StatusOK, GenerateSnippets(addr, req))
+	default:
+		h.writeVaultError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+func TestGenerateSnippetsWrite(t *testing.T) {
+	snippets := GenerateSnippets("http://localhost:8300", SnippetRequest{
+		Method: "POST",
+		Path:   "/v1/plugin/roles/dev",
+		Data:   map[string]interface{}{"ttl": "1h", "max_uses": float64(3), "name": "it's"},
+	})
+
+	for _, want := range []string{
+		"export VAULT_ADDR=http://localhost:8300",
+		"vault write plugin/roles/dev",
+		"max_uses=3",
+		`'name=it'\''s'`,
+		"ttl=1h",
+	} {
+		if !strings.Contains(snippets.CLI, want) {
+			t.Errorf("CLI snippet is missing %q:\n%s", want, snippets.CLI)
+		}
+	}
+
+	for _, want := range []string{
+		`config.Address = "http://localhost:8300"`,
+		`client.Logical().Write("plugin/roles/dev", map[string]interface{}{`,
+		`"max_uses": 3,`,
+		`"ttl": "1h",`,
+	} {
+		if !strings.Contains(snippets.Go, want) {
+			t.Errorf("Go snippet is missing %q:\n%s", want, snippets.Go)
+		}
+	}
+
+	for _, want := range []string{
+		`resource "vault_generic_endpoint" "plugin_roles_dev"`,
+		`path      = "plugin/roles/dev"`,
+		`"ttl": "1h"`,
+	} {
+		if !strings.Contains(snippets.Terraform, want) {
+			t.Errorf("Terraform snippet is missing %q:\n%s", want, snippets.Terraform)
+		}
+	}
+}
+
+func TestGenerateSnippetsOperations(t *testing.T) {
+	tests := []struct {
+		req           SnippetRequest
+		cli, goClient string
+	}{
+		{SnippetRequest{Method: "GET", Path: "plugin/config"}, "vault read plugin/config", `Logical().Read("plugin/config")`},
+		{SnippetRequest{Method: "GET", Path: "plugin/roles/?list=true"}, "vault list plugin/roles", `Logical().List("plugin/roles")`},
+		{SnippetRequest{Method: "LIST", Path: "plugin/roles"}, "vault list plugin/roles", `Logical().List("plugin/roles")`},
+		{SnippetRequest{Method: "DELETE", Path: "plugin/roles/dev"}, "vault delete plugin/roles/dev", `Logical().Delete("plugin/roles/dev")`},
+		{SnippetRequest{Method: "POST", Path: "plugin/rotate"}, "vault write -f plugin/rotate", `Logical().Write("plugin/rotate", map[string]interface{}{`},
+		{
+			SnippetRequest{Method: "PUT", Path: "plugin/config", Data: map[string]interface{}{"urls": []interface{}{"a", "b"}}},
+			"vault write plugin/config - <<'EOF'",
+			`"urls": []interface{}{`,
+		},
+	}
+	for _, tt := range tests {
+		snippets := GenerateSnippets("", tt.req)
+		if !strings.Contains(snippets.CLI, tt.cli) {
+			t.Errorf("%s %s: CLI = %q, want %q", tt.req.Method, tt.req.Path, snippets.CLI, tt.cli)
+		}
+		if !strings.Contains(snippets.Go, tt.goClient) {
+			t.Errorf("%s %s: Go snippet is missing %q:\n%s", tt.req.Method, tt.req.Path, tt.goClient, snippets.Go)
+		}
+	}
+}
+
+func TestHandleSnippets(t *testing.T) {
+	handler := NewHandler(nil, newMockStorage(), hclog.NewNullLogger(), "plugin")
+
+	w := httptest.NewRecorder()
+	handler.HandleSnippets(w, httptest.NewRequest("GET", "/v1/sys/host/snippets", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("GET without recording: status = %d, want 404", w.Code)
+	}
+
+	handler.SetExampleRecorder(NewExampleRecorder(0))
+	handler.recordExample("PUT", "config", map[string]interface{}{"ttl": "1h"}, nil, http.StatusOK)
+
+	w = httptest.NewRecorder()
+	handler.HandleSnippets(w, httptest.NewRequest("GET", "/v1/sys/host/snippets", nil))
+	var listed struct {
+		Requests []struct {
+			Path     string   `json:"path"`
+			Snippets Snippets `json:"snippets"`
+		} `json:"requests"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &listed)
+	if len(listed.Requests) != 1 || listed.Requests[0].Path != "plugin/config" {
+		t.Fatalf("unexpected recorded snippets: %s", w.Body.String())
+	}
+	if !strings.Contains(listed.Requests[0].Snippets.CLI, "vault write plugin/config") {
+		t.Errorf("CLI = %q", listed.Requests[0].Snippets.CLI)
+	}
+
+	w = httptest.NewRecorder()
+	handler.HandleSnippets(w, httptest.NewRequest("POST", "/v1/sys/host/snippets", strings.NewReader(`{"method":"GET","path":"plugin/config"}`)))
+	var snippets Snippets
+	json.Unmarshal(w.Body.Bytes(), &snippets)
+	if w.Code != http.StatusOK || !strings.Contains(snippets.CLI, "vault read plugin/config") {
+		t.Errorf("POST: status = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	handler.HandleSnippets(w, httptest.NewRequest("POST", "/v1/sys/host/snippets", strings.NewReader(`{}`)))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("POST without a path: status = %d, want 400", w.Code)
+	}
+}
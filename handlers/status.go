@@ -0,0 +1,130 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// RawErrorHeader explains why a raw plugin response could not be written, as in Vault
+const RawErrorHeader = "X-Vault-Raw-Error"
+
+// writeStatusResponse writes the responses that Vault answers with a status other than
+// 200 and the plugin's response as JSON, and reports whether it did: 404 for a read
+// that returns nothing or a list without keys, 204 for any other empty response, 4xx
+// for error responses and the plugin's own status for raw responses. Other responses
+// are left to the caller.
+func (h *Handler) writeStatusResponse(w http.ResponseWriter, req *logical.Request, resp *logical.Response) bool {
+	switch {
+	case resp == nil && req.Operation == logical.ReadOperation,
+		req.Operation == logical.ListOperation && (resp == nil || !resp.IsError() && listKeysEmpty(listData(resp.Data))):
+		WriteJSON(w, http.StatusNotFound, map[string]interface{}{"errors": []string{}})
+	case resp == nil:
+		w.WriteHeader(http.StatusNoContent)
+	case resp.IsError():
+		status, err := logical.RespondErrorCommon(req, resp, nil)
+		if err == nil {
+			err = resp.Error()
+		}
+		if status == 0 {
+			status = http.StatusBadRequest
+		}
+		logical.AdjustErrorStatusCode(&status, err)
+		h.writeVaultError(w, status, err.Error())
+	case resp.Data != nil && resp.Data[logical.HTTPStatusCode] != nil:
+		writeRawResponse(w, resp)
+	default:
+		return false
+	}
+	return true
+}
+
+// errorStatus returns the status Vault answers an error from the plugin with, and the
+// error to report: 403 for permission denied, 400 for invalid requests or when the
+// plugin also returned an error response, the code of a logical.CodedError and 500 for
+// other errors
+func errorStatus(req *logical.Request, resp *logical.Response, err error) (int, error) {
+	status, mapped := logical.RespondErrorCommon(req, resp, err)
+	if mapped == nil {
+		mapped = err
+	}
+	logical.AdjustErrorStatusCode(&status, mapped)
+	return status, mapped
+}
+
+// writeRawResponse writes a response whose data sets logical.HTTPStatusCode as Vault
+// does: with that status, logical.HTTPContentType and the logical.HTTPRawBody bytes
+// instead of the JSON envelope. Raw bytes reach the host base64-encoded when they
+// cross the plugin's gRPC connection.
+func writeRawResponse(w http.ResponseWriter, resp *logical.Response) {
+	fail := func(reason string) {
+		w.Header().Set(RawErrorHeader, reason)
+		WriteJSON(w, http.StatusInternalServerError, map[string]interface{}{"errors": []string{}})
+	}
+	if resp.Secret != nil || resp.Auth != nil {
+		fail("raw responses cannot contain secrets or auth")
+		return
+	}
+
+	var status int
+	switch v := resp.Data[logical.HTTPStatusCode].(type) {
+	case int:
+		status = v
+	case float64:
+		status = int(v)
+	case json.Number:
+		n, err := v.Int64()
+		if err != nil {
+			fail("cannot decode status code")
+			return
+		}
+		status = int(n)
+	default:
+		fail("cannot decode status code")
+		return
+	}
+
+	nonEmpty := status != http.StatusNoContent
+	contentType, hasType := resp.Data[logical.HTTPContentType].(string)
+	if _, set := resp.Data[logical.HTTPContentType]; set && !hasType {
+		fail("cannot decode content type")
+		return
+	}
+	if !hasType && nonEmpty {
+		fail("no content type given")
+		return
+	}
+
+	var body []byte
+	if nonEmpty {
+		switch v := resp.Data[logical.HTTPRawBody].(type) {
+		case nil:
+		case []byte:
+			body = v
+		case string:
+			// The body may or may not still be base64-encoded; Vault tries both too
+			if decoded, err := base64.StdEncoding.DecodeString(v); err == nil {
+				body = decoded
+			} else {
+				body = []byte(v)
+			}
+		default:
+			fail("cannot decode body")
+			return
+		}
+	}
+
+	if contentType != "" {
+		w.Header().Set("Content-Type", contentType)
+	}
+	if cacheControl, ok := resp.Data[logical.HTTPCacheControlHeader].(string); ok {
+		w.Header().Set("Cache-Control", cacheControl)
+	}
+	w.WriteHeader(status)
+	w.Write(body)
+}
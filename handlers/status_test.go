@@ -0,0 +1,134 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestStatusResponses(t *testing.T) {
+	backend := funcBackend(func(ctx context.Context, req *logical.Request) (*logical.Response, error) {
+		switch req.Path {
+		case "invalid":
+			return logical.ErrorResponse("name is required"), nil
+		case "config":
+			return &logical.Response{Data: map[string]interface{}{"url": "https://example.com"}}, nil
+		case "denied":
+			return nil, logical.ErrPermissionDenied
+		case "bad":
+			return nil, fmt.Errorf("role %q: %w", "x", logical.ErrInvalidRequest)
+		case "unsupported":
+			return nil, logical.ErrUnsupportedOperation
+		case "coded":
+			return nil, logical.CodedError(http.StatusConflict, "already exists")
+		case "broken":
+			return nil, errors.New("database unreachable")
+		case "error-with-response":
+			return logical.ErrorResponse("bad input"), logical.ErrInvalidRequest
+		}
+		return nil, nil
+	})
+	handler := NewHandler(backend, newMockStorage(), hclog.NewNullLogger(), "plugin")
+
+	tests := []struct {
+		method, path string
+		status       int
+		body         string
+	}{
+		{"GET", "/v1/plugin/missing", http.StatusNotFound, `{"errors":[]}`},
+		{"PUT", "/v1/plugin/missing", http.StatusNoContent, ""},
+		{"DELETE", "/v1/plugin/missing", http.StatusNoContent, ""},
+		{"PUT", "/v1/plugin/invalid", http.StatusBadRequest, "name is required"},
+		{"GET", "/v1/plugin/config", http.StatusOK, "https://example.com"},
+		{"GET", "/v1/plugin/denied", http.StatusForbidden, "permission denied"},
+		{"GET", "/v1/plugin/bad", http.StatusBadRequest, "invalid request"},
+		{"GET", "/v1/plugin/unsupported", http.StatusMethodNotAllowed, "unsupported operation"},
+		{"PUT", "/v1/plugin/coded", http.StatusConflict, "already exists"},
+		{"GET", "/v1/plugin/broken", http.StatusInternalServerError, "database unreachable"},
+		{"PUT", "/v1/plugin/error-with-response", http.StatusBadRequest, "bad input"},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		handler.HandleRequest(w, httptest.NewRequest(tt.method, tt.path, nil))
+		if w.Code != tt.status || !strings.Contains(w.Body.String(), tt.body) {
+			t.Errorf("%s %s: status = %d, body = %q; want %d with %q", tt.method, tt.path, w.Code, w.Body.String(), tt.status, tt.body)
+		}
+	}
+}
+
+func TestRawResponses(t *testing.T) {
+	responses := map[string]map[string]interface{}{
+		// As the plugin builds it
+		"ca": {
+			logical.HTTPStatusCode:         200,
+			logical.HTTPContentType:        "application/pkix-cert",
+			logical.HTTPRawBody:            []byte("-----BEGIN CERTIFICATE-----"),
+			logical.HTTPCacheControlHeader: "max-age=60",
+		},
+		// As it arrives over gRPC, with a JSON number and base64 bytes
+		"crl": {
+			logical.HTTPStatusCode:  json.Number("202"),
+			logical.HTTPContentType: "text/plain",
+			logical.HTTPRawBody:     "aGVsbG8=",
+		},
+		"empty":      {logical.HTTPStatusCode: float64(204)},
+		"no-type":    {logical.HTTPStatusCode: 200, logical.HTTPRawBody: []byte("x")},
+		"bad-status": {logical.HTTPStatusCode: "teapot", logical.HTTPContentType: "text/plain"},
+	}
+	backend := funcBackend(func(ctx context.Context, req *logical.Request) (*logical.Response, error) {
+		resp := &logical.Response{Data: responses[req.Path]}
+		if req.Path == "secret" {
+			resp = &logical.Response{
+				Data:   map[string]interface{}{logical.HTTPStatusCode: 200, logical.HTTPContentType: "text/plain"},
+				Secret: &logical.Secret{},
+			}
+		}
+		return resp, nil
+	})
+	handler := NewHandler(backend, newMockStorage(), hclog.NewNullLogger(), "plugin")
+
+	tests := []struct {
+		path, contentType, body, rawError string
+		status                            int
+	}{
+		{"ca", "application/pkix-cert", "-----BEGIN CERTIFICATE-----", "", http.StatusOK},
+		{"crl", "text/plain", "hello", "", http.StatusAccepted},
+		{"empty", "", "", "", http.StatusNoContent},
+		{"no-type", "", "", "no content type given", http.StatusInternalServerError},
+		{"bad-status", "", "", "cannot decode status code", http.StatusInternalServerError},
+		{"secret", "", "", "raw responses cannot contain secrets or auth", http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		handler.HandleRequest(w, httptest.NewRequest("GET", "/v1/plugin/"+tt.path, nil))
+		if w.Code != tt.status || w.Header().Get(RawErrorHeader) != tt.rawError {
+			t.Errorf("%s: status = %d, raw error %q; want %d, %q", tt.path, w.Code, w.Header().Get(RawErrorHeader), tt.status, tt.rawError)
+			continue
+		}
+		if tt.rawError != "" {
+			continue
+		}
+		if got := w.Header().Get("Content-Type"); tt.contentType != "" && got != tt.contentType {
+			t.Errorf("%s: Content-Type = %q, want %q", tt.path, got, tt.contentType)
+		}
+		if w.Body.String() != tt.body {
+			t.Errorf("%s: body = %q, want %q", tt.path, w.Body.String(), tt.body)
+		}
+	}
+	w := httptest.NewRecorder()
+	handler.HandleRequest(w, httptest.NewRequest("GET", "/v1/plugin/ca", nil))
+	if w.Header().Get("Cache-Control") != "max-age=60" {
+		t.Errorf("Cache-Control = %q, want max-age=60", w.Header().Get("Cache-Control"))
+	}
+}
@@ -0,0 +1,203 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// StorageFaultConfig describes the faults injected into plugin storage
+type StorageFaultConfig struct {
+	LatencyMS    int      `json:"latency_ms"`    // delay before every storage call
+	ErrorRate    float64  `json:"error_rate"`    // percent of storage calls that fail
+	FailPrefixes []string `json:"fail_prefixes"` // calls on keys with these prefixes always fail
+	ReadOnly     bool     `json:"read_only"`     // writes fail with logical.ErrReadOnly
+}
+
+// StorageFaults injects latency and errors into the storage the plugins see, so their
+// retry and error paths can be exercised without changing their code. Host endpoints
+// that read storage directly are not affected.
+type StorageFaults struct {
+	mu     sync.RWMutex
+	config StorageFaultConfig
+
+	randMu sync.Mutex
+	chance func() float64 // in [0, 100)
+
+	delayed, failed, readOnly atomic.Uint64
+}
+
+// NewStorageFaults creates a fault injector that injects nothing
+func NewStorageFaults() *StorageFaults {
+	return &StorageFaults{chance: func() float64 { return rand.Float64() * 100 }}
+}
+
+// Set replaces the injected faults
+func (f *StorageFaults) Set(config StorageFaultConfig) error {
+	if config.LatencyMS < 0 {
+		return fmt.Errorf("latency_ms must not be negative")
+	}
+	if config.ErrorRate < 0 || config.ErrorRate > 100 {
+		return fmt.Errorf("error_rate must be a percentage between 0 and 100")
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.config = config
+	return nil
+}
+
+// Config returns the injected faults
+func (f *StorageFaults) Config() StorageFaultConfig {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.config
+}
+
+// inject delays a storage call and returns the error it fails with, if any
+func (f *StorageFaults) inject(ctx context.Context, op, key string, write bool) error {
+	config := f.Config()
+
+	if config.LatencyMS > 0 {
+		f.delayed.Add(1)
+		timer := time.NewTimer(time.Duration(config.LatencyMS) * time.Millisecond)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+
+	if write && config.ReadOnly {
+		f.readOnly.Add(1)
+		return logical.ErrReadOnly
+	}
+	for _, prefix := range config.FailPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			f.failed.Add(1)
+			return fmt.Errorf("injected storage fault: %s %s", op, key)
+		}
+	}
+	if config.ErrorRate > 0 {
+		f.randMu.Lock()
+		roll := f.chance()
+		f.randMu.Unlock()
+		if roll < config.ErrorRate {
+			f.failed.Add(1)
+			return fmt.Errorf("injected storage fault: %s %s", op, key)
+		}
+	}
+	return nil
+}
+
+// Storage wraps plugin storage so its calls suffer the injected faults
+func (f *StorageFaults) Storage(storage logical.Storage) logical.Storage {
+	if f == nil {
+		return storage
+	}
+	return &faultyStorage{storage: storage, faults: f}
+}
+
+type faultyStorage struct {
+	storage logical.Storage
+	faults  *StorageFaults
+}
+
+func (s *faultyStorage) List(ctx context.Context, prefix string) ([]string, error) {
+	if err := s.faults.inject(ctx, "list", prefix, false); err != nil {
+		return nil, err
+	}
+	return s.storage.List(ctx, prefix)
+}
+
+func (s *faultyStorage) Get(ctx context.Context, key string) (*logical.StorageEntry, error) {
+	if err := s.faults.inject(ctx, "get", key, false); err != nil {
+		return nil, err
+	}
+	return s.storage.Get(ctx, key)
+}
+
+func (s *faultyStorage) Put(ctx context.Context, entry *logical.StorageEntry) error {
+	if err := s.faults.inject(ctx, "put", entry.Key, true); err != nil {
+		return err
+	}
+	return s.storage.Put(ctx, entry)
+}
+
+func (s *faultyStorage) Delete(ctx context.Context, key string) error {
+	if err := s.faults.inject(ctx, "delete", key, true); err != nil {
+		return err
+	}
+	return s.storage.Delete(ctx, key)
+}
+
+// HandleConfig reads (GET) or replaces (PUT/POST {"latency_ms", "error_rate",
+// "fail_prefixes", "read_only"}) the injected storage faults at
+// /v1/sys/test/storage-faults; DELETE stops injecting faults
+func (f *StorageFaults) HandleConfig(w http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet:
+		config := f.Config()
+		if config.FailPrefixes == nil {
+			config.FailPrefixes = []string{}
+		}
+		WriteJSON(w, http.StatusOK, map[string]interface{}{
+			"latency_ms":    config.LatencyMS,
+			"error_rate":    config.ErrorRate,
+			"fail_prefixes": config.FailPrefixes,
+			"read_only":     config.ReadOnly,
+			"injected": map[string]interface{}{
+				"delayed":   f.delayed.Load(),
+				"failed":    f.failed.Load(),
+				"read_only": f.readOnly.Load(),
+			},
+		})
+	case http.MethodPut, http.MethodPost:
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			WriteError(w, http.StatusBadRequest, fmt.Sprintf("failed to read body: %v", err))
+			return
+		}
+		var config StorageFaultConfig
+		if err := json.Unmarshal(body, &config); err != nil {
+			WriteError(w, http.StatusBadRequest, fmt.Sprintf("failed to parse JSON: %v", err))
+			return
+		}
+		if err := f.Set(config); err != nil {
+			WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodDelete:
+		f.Set(StorageFaultConfig{})
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// SetStorageFaults makes the storage the handler gives the plugin suffer injected faults
+func (h *Handler) SetStorageFaults(faults *StorageFaults) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.faults = faults
+}
+
+// StorageFaults returns the handler's storage fault injector, or nil when none is set
+func (h *Handler) StorageFaults() *StorageFaults {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.faults
+}
@@ -0,0 +1,121 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestStorageFaults(t *testing.T) {
+	ctx := context.Background()
+	faults := NewStorageFaults()
+	storage := faults.Storage(newMockStorage())
+
+	if err := storage.Put(ctx, &logical.StorageEntry{Key: "config", Value: []byte("x")}); err != nil {
+		t.Fatalf("Put without faults failed: %v", err)
+	}
+
+	faults.Set(StorageFaultConfig{FailPrefixes: []string{"creds/"}, ReadOnly: true})
+	if _, err := storage.Get(ctx, "creds/a"); err == nil || !strings.Contains(err.Error(), "injected storage fault: get creds/a") {
+		t.Errorf("Get of a failing prefix = %v", err)
+	}
+	if entry, err := storage.Get(ctx, "config"); err != nil || entry == nil {
+		t.Errorf("Get of another key = %v, %v", entry, err)
+	}
+	if err := storage.Delete(ctx, "config"); !errors.Is(err, logical.ErrReadOnly) {
+		t.Errorf("Delete while read-only = %v, want ErrReadOnly", err)
+	}
+
+	// Every call below the error rate fails
+	rolls := []float64{10, 60}
+	faults.chance = func() float64 { roll := rolls[0]; rolls = rolls[1:]; return roll }
+	faults.Set(StorageFaultConfig{ErrorRate: 50})
+	if _, err := storage.List(ctx, ""); err == nil {
+		t.Error("List with a roll below the error rate should fail")
+	}
+	if _, err := storage.List(ctx, ""); err != nil {
+		t.Errorf("List with a roll above the error rate failed: %v", err)
+	}
+
+	// Latency delays calls but gives way to the caller's deadline
+	faults.Set(StorageFaultConfig{LatencyMS: 20})
+	start := time.Now()
+	if _, err := storage.Get(ctx, "config"); err != nil || time.Since(start) < 20*time.Millisecond {
+		t.Errorf("delayed Get = %v after %s", err, time.Since(start))
+	}
+	short, cancel := context.WithTimeout(ctx, time.Millisecond)
+	defer cancel()
+	if _, err := storage.Get(short, "config"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Get past its deadline = %v", err)
+	}
+
+	if got := faults.failed.Load(); got != 2 {
+		t.Errorf("failed = %d, want 2", got)
+	}
+	if faults.Set(StorageFaultConfig{ErrorRate: 101}) == nil || faults.Set(StorageFaultConfig{LatencyMS: -1}) == nil {
+		t.Error("Set should refuse an error rate above 100 and negative latency")
+	}
+}
+
+func TestStorageFaultsHandleConfig(t *testing.T) {
+	faults := NewStorageFaults()
+
+	req := httptest.NewRequest("PUT", "/v1/sys/test/storage-faults", strings.NewReader(`{"latency_ms": 5, "fail_prefixes": ["roles/"]}`))
+	w := httptest.NewRecorder()
+	faults.HandleConfig(w, req)
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("PUT status = %d: %s", w.Code, w.Body.String())
+	}
+	if config := faults.Config(); config.LatencyMS != 5 || len(config.FailPrefixes) != 1 {
+		t.Errorf("config = %+v", config)
+	}
+
+	w = httptest.NewRecorder()
+	faults.HandleConfig(w, httptest.NewRequest("PUT", "/v1/sys/test/storage-faults", strings.NewReader(`{"error_rate": 150}`)))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("PUT of an invalid error rate: status = %d", w.Code)
+	}
+
+	// Faults reach the storage of plugin requests
+	backend := &capturingBackend{}
+	handler := NewHandler(backend, newMockStorage(), hclog.NewNullLogger(), "plugin")
+	handler.SetStorageFaults(faults)
+	handler.HandleRequest(httptest.NewRecorder(), httptest.NewRequest("GET", "/v1/plugin/roles/a", nil))
+	if backend.err == nil {
+		t.Error("the plugin's storage should fail on roles/")
+	}
+
+	w = httptest.NewRecorder()
+	faults.HandleConfig(w, httptest.NewRequest("DELETE", "/v1/sys/test/storage-faults", nil))
+	w = httptest.NewRecorder()
+	faults.HandleConfig(w, httptest.NewRequest("GET", "/v1/sys/test/storage-faults", nil))
+	var config struct {
+		LatencyMS int            `json:"latency_ms"`
+		Injected  map[string]int `json:"injected"`
+	}
+	json.NewDecoder(w.Body).Decode(&config)
+	if config.LatencyMS != 0 || config.Injected["failed"] != 1 {
+		t.Errorf("GET after DELETE = %+v", config)
+	}
+}
+
+// capturingBackend reads the request path from storage and keeps the error
+type capturingBackend struct {
+	err error
+}
+
+func (b *capturingBackend) HandleRequest(ctx context.Context, req *logical.Request) (*logical.Response, error) {
+	_, b.err = req.Storage.Get(ctx, req.Path)
+	return nil, b.err
+}
@@ -0,0 +1,145 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+// TidyResult counts what tidying removed
+type TidyResult struct {
+	WrappedResponses int `json:"wrapped_responses"` // expired wrapped responses, with their cubbyholes
+	Cubbyholes       int `json:"cubbyholes"`        // cubbyholes without a wrapped response, or with one that cannot be decoded
+	Tokens           int `json:"tokens"`            // expired tokens
+}
+
+// TidyStats counts the tidy passes, what they removed in all and what the last one left
+type TidyStats struct {
+	Interval             string     `json:"interval,omitempty"` // of the background tidy; empty when it is off
+	Runs                 int        `json:"runs"`
+	Errors               int        `json:"errors"`
+	LastRun              *time.Time `json:"last_run,omitempty"`
+	LastDuration         string     `json:"last_duration,omitempty"`
+	LastError            string     `json:"last_error,omitempty"`
+	Removed              TidyResult `json:"removed"`
+	LiveWrappedResponses int        `json:"live_wrapped_responses"`
+	LiveTokens           int        `json:"live_tokens"`
+}
+
+// Tidier removes the expired wrapped responses, cubbyholes and tokens that short-lived
+// test artifacts leave behind, like Vault's tidy operations, so a host that serves a
+// soak run for days does not grow without bound
+type Tidier struct {
+	wraps  *WrapStore
+	tokens *TokenStore
+	logger hclog.Logger
+
+	pass  sync.Mutex // one pass at a time
+	mu    sync.Mutex
+	stats TidyStats
+}
+
+// NewTidier creates a tidier of the wrap store and the token store
+func NewTidier(wraps *WrapStore, tokens *TokenStore, logger hclog.Logger) *Tidier {
+	return &Tidier{wraps: wraps, tokens: tokens, logger: logger}
+}
+
+// Run tidies every interval until stop is closed
+func (t *Tidier) Run(interval time.Duration, stop <-chan struct{}) {
+	t.mu.Lock()
+	t.stats.Interval = interval.String()
+	t.mu.Unlock()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			t.Tidy(context.Background(), now)
+		}
+	}
+}
+
+// Tidy removes what expired by now and returns what it removed
+func (t *Tidier) Tidy(ctx context.Context, now time.Time) (TidyResult, error) {
+	t.pass.Lock()
+	defer t.pass.Unlock()
+
+	start := time.Now()
+	var result TidyResult
+	var liveWraps int
+	var err error
+	if t.wraps != nil {
+		result.WrappedResponses, result.Cubbyholes, liveWraps, err = t.wraps.Tidy(ctx, now)
+	}
+	var liveTokens int
+	result.Tokens, liveTokens = t.tokens.Tidy(now)
+
+	t.mu.Lock()
+	ran := start.UTC()
+	t.stats.Runs++
+	t.stats.LastRun = &ran
+	t.stats.LastDuration = time.Since(start).String()
+	t.stats.Removed.WrappedResponses += result.WrappedResponses
+	t.stats.Removed.Cubbyholes += result.Cubbyholes
+	t.stats.Removed.Tokens += result.Tokens
+	t.stats.LiveTokens = liveTokens
+	if err != nil {
+		t.stats.Errors++
+		t.stats.LastError = err.Error()
+	} else {
+		t.stats.LiveWrappedResponses = liveWraps
+	}
+	t.mu.Unlock()
+
+	if err != nil {
+		t.logger.Error("failed to tidy wrapped responses", "error", err)
+	}
+	if result != (TidyResult{}) {
+		t.logger.Debug("tidied expired artifacts", "wrapped_responses", result.WrappedResponses, "cubbyholes", result.Cubbyholes, "tokens", result.Tokens)
+	}
+	return result, err
+}
+
+// Stats returns the counters of the tidy passes so far
+func (t *Tidier) Stats() TidyStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.stats
+}
+
+// HandleTidy serves /v1/sys/host/tidy: GET reports the counters, and POST or PUT runs a
+// pass now and reports what it removed along with them
+func (t *Tidier) HandleTidy(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		WriteJSON(w, http.StatusOK, map[string]interface{}{
+			"request_id": newRequestID(),
+			"data":       t.Stats(),
+		})
+	case http.MethodPost, http.MethodPut:
+		result, err := t.Tidy(r.Context(), time.Now())
+		if err != nil {
+			WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		WriteJSON(w, http.StatusOK, map[string]interface{}{
+			"request_id": newRequestID(),
+			"data": map[string]interface{}{
+				"tidied": result,
+				"stats":  t.Stats(),
+			},
+		})
+	default:
+		WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestTidier(t *testing.T) {
+	ctx := context.Background()
+	storage := newMockStorage()
+	wraps := NewWrapStore(storage)
+	tokens := NewTokenStore("root-token")
+
+	short, _ := wraps.Wrap(ctx, map[string]interface{}{"data": "a"}, time.Minute, "plugin/creds")
+	long, _ := wraps.Wrap(ctx, map[string]interface{}{"data": "b"}, time.Hour, "plugin/creds")
+	storage.Put(ctx, &logical.StorageEntry{Key: "cubbyhole/hvs.orphan/extra", Value: []byte("{}")})
+	storage.Put(ctx, &logical.StorageEntry{Key: cubbyholeKey("hvs.corrupt"), Value: []byte("{not json")})
+	tokens.Issue(&logical.Auth{LeaseOptions: logical.LeaseOptions{TTL: time.Minute}}, "plugin/login", nil)
+	tokens.Issue(&logical.Auth{}, "plugin/login", nil)
+
+	tidier := NewTidier(wraps, tokens, hclog.NewNullLogger())
+	result, err := tidier.Tidy(ctx, time.Now().Add(10*time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != (TidyResult{WrappedResponses: 1, Cubbyholes: 2, Tokens: 1}) {
+		t.Errorf("tidied %+v", result)
+	}
+	if _, err := wraps.Lookup(ctx, long.Token); err != nil {
+		t.Errorf("a wrapped response that has not expired should be kept: %v", err)
+	}
+	if entry, _ := storage.Get(ctx, cubbyholeKey(short.Token)); entry != nil {
+		t.Error("the expired wrapped response should be deleted")
+	}
+	if keys, _ := storage.List(ctx, "cubbyhole/hvs.orphan/"); len(keys) != 0 {
+		t.Errorf("the orphan cubbyhole should be deleted, left %v", keys)
+	}
+	if entry, _ := storage.Get(ctx, cubbyholeKey("hvs.corrupt")); entry != nil {
+		t.Error("a wrapped response that cannot be decoded should be deleted")
+	}
+	if _, ok := tokens.Lookup("root-token"); !ok {
+		t.Error("the root token should never be tidied")
+	}
+
+	// A second pass finds nothing more
+	tidier.Tidy(ctx, time.Now().Add(10*time.Minute))
+	stats := tidier.Stats()
+	if stats.Runs != 2 || stats.Removed != result || stats.LiveWrappedResponses != 1 || stats.LiveTokens != 2 || stats.LastRun == nil {
+		t.Errorf("stats = %+v", stats)
+	}
+}
+
+func TestHandleTidy(t *testing.T) {
+	tidier := NewTidier(NewWrapStore(newMockStorage()), nil, hclog.NewNullLogger())
+	serve := func(method string) (int, map[string]interface{}) {
+		w := httptest.NewRecorder()
+		tidier.HandleTidy(w, httptest.NewRequest(method, "/v1/sys/host/tidy", nil))
+		var response struct {
+			Data map[string]interface{} `json:"data"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return w.Code, response.Data
+	}
+
+	if code, data := serve(http.MethodGet); code != http.StatusOK || data["runs"] != 0.0 {
+		t.Errorf("GET = %d %v", code, data)
+	}
+	code, data := serve(http.MethodPost)
+	if code != http.StatusOK || data["tidied"] == nil || data["stats"].(map[string]interface{})["runs"] != 1.0 {
+		t.Errorf("POST = %d %v", code, data)
+	}
+	if code, _ := serve(http.MethodDelete); code != http.StatusMethodNotAllowed {
+		t.Errorf("DELETE = %d, want 405", code)
+	}
+}
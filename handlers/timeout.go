@@ -0,0 +1,85 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// RequestTimeoutHeader lets a client override the request deadline (Go duration, e.g. "2s")
+const RequestTimeoutHeader = "X-Vault-Host-Request-Timeout"
+
+// backendResult carries the outcome of an asynchronous backend call
+type backendResult struct {
+	resp *logical.Response
+	err  error
+}
+
+// SetRequestTimeout sets the default deadline for plugin requests (0 disables it)
+func (h *Handler) SetRequestTimeout(timeout time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.requestTimeout = timeout
+}
+
+// timeoutFor returns the deadline to apply to a request, honouring the override header
+func (h *Handler) timeoutFor(r *http.Request) (time.Duration, error) {
+	if value := r.Header.Get(RequestTimeoutHeader); value != "" {
+		timeout, err := time.ParseDuration(value)
+		if err != nil || timeout < 0 {
+			return 0, fmt.Errorf("invalid %s header: %q", RequestTimeoutHeader, value)
+		}
+		return timeout, nil
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.requestTimeout, nil
+}
+
+// callWithDeadline forwards a request to the plugin and stops waiting once ctx expires,
+// so a stuck plugin cannot hold the HTTP request forever
+func (h *Handler) callWithDeadline(ctx context.Context, backend PluginBackend, req *logical.Request, trace *requestTrace) (*logical.Response, error) {
+	trace.backendStarted()
+
+	if _, ok := ctx.Deadline(); !ok {
+		resp, err := h.callBackend(ctx, backend, req)
+		trace.backendFinished()
+		return resp, err
+	}
+
+	done := make(chan backendResult, 1)
+	go func() {
+		resp, err := h.callBackend(ctx, backend, req)
+		done <- backendResult{resp: resp, err: err}
+	}()
+
+	select {
+	case result := <-done:
+		trace.backendFinished()
+		return result.resp, result.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// writeTimeout responds with 504 and a breakdown of where the request spent its time
+func (h *Handler) writeTimeout(w http.ResponseWriter, timeout time.Duration, req *logical.Request, trace *requestTrace) {
+	diagnostics := trace.Summary()
+	diagnostics["timeout"] = timeout.String()
+	diagnostics["operation"] = req.Operation
+	diagnostics["path"] = req.Path
+
+	h.logger.Warn("request timed out", "path", req.Path, "operation", req.Operation, "timeout", timeout)
+
+	WriteJSON(w, http.StatusGatewayTimeout, map[string]interface{}{
+		"errors":      []string{fmt.Sprintf("request timed out after %s", timeout)},
+		"diagnostics": diagnostics,
+	})
+}
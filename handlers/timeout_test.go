@@ -0,0 +1,88 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// stuckBackend reads from storage and then never returns until released
+type stuckBackend struct {
+	release chan struct{}
+}
+
+func (b *stuckBackend) HandleRequest(ctx context.Context, req *logical.Request) (*logical.Response, error) {
+	req.Storage.Get(ctx, "config")
+	<-b.release
+	return nil, nil
+}
+
+func TestHandleRequestTimeout(t *testing.T) {
+	backend := &stuckBackend{release: make(chan struct{})}
+	defer close(backend.release)
+
+	handler := NewHandler(backend, newMockStorage(), hclog.NewNullLogger(), "plugin")
+	handler.SetRequestTimeout(50 * time.Millisecond)
+
+	req := httptest.NewRequest("GET", "/v1/plugin/slow", nil)
+	w := httptest.NewRecorder()
+	handler.HandleRequest(w, req)
+
+	if w.Code != http.StatusGatewayTimeout {
+		t.Fatalf("Status code = %d, want %d", w.Code, http.StatusGatewayTimeout)
+	}
+
+	var response struct {
+		Errors      []string               `json:"errors"`
+		Diagnostics map[string]interface{} `json:"diagnostics"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	if response.Diagnostics["path"] != "slow" {
+		t.Errorf("diagnostics path = %v, want slow", response.Diagnostics["path"])
+	}
+	if response.Diagnostics["backend_running"] != true {
+		t.Error("diagnostics should report the backend call as still running")
+	}
+
+	storage, ok := response.Diagnostics["storage"].(map[string]interface{})
+	if !ok {
+		t.Fatal("diagnostics missing storage breakdown")
+	}
+	if calls, _ := storage["calls"].(float64); calls != 1 {
+		t.Errorf("storage calls = %v, want 1", storage["calls"])
+	}
+}
+
+func TestHandleRequestTimeoutHeader(t *testing.T) {
+	handler := NewHandler(&mockBackend{}, newMockStorage(), hclog.NewNullLogger(), "plugin")
+
+	req := httptest.NewRequest("GET", "/v1/plugin/test", nil)
+	req.Header.Set(RequestTimeoutHeader, "not-a-duration")
+	w := httptest.NewRecorder()
+	handler.HandleRequest(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Status code = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+
+	req = httptest.NewRequest("GET", "/v1/plugin/test", nil)
+	req.Header.Set(RequestTimeoutHeader, "5s")
+	w = httptest.NewRecorder()
+	handler.HandleRequest(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Status code = %d, want %d", w.Code, http.StatusOK)
+	}
+}
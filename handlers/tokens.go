@@ -0,0 +1,792 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// tokenAlphabet is the character set of generated tokens and accessors
+const tokenAlphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// TokenEntry describes a token accepted by the host
+type TokenEntry struct {
+	Accessor    string            `json:"accessor"`
+	Policies    []string          `json:"policies"`
+	DisplayName string            `json:"display_name"`
+	EntityID    string            `json:"entity_id,omitempty"`
+	Metadata    map[string]string `json:"meta,omitempty"`
+	Path        string            `json:"path"` // login path that issued the token
+	Renewable   bool              `json:"renewable"`
+	TTL         time.Duration     `json:"ttl"`
+	MaxTTL      time.Duration     `json:"explicit_max_ttl"`
+	IssueTime   time.Time         `json:"issue_time"`
+	ExpireTime  time.Time         `json:"expire_time,omitempty"` // zero for tokens that never expire
+	NumUses     int               `json:"num_uses"`              // plugin requests left; 0 for unlimited tokens
+
+	creationTTL time.Duration
+	auth        *logical.Auth // the plugin's Auth, sent back to it on renewal
+	issuer      *Handler      // handler of the mount that issued the token
+}
+
+// TokenStore holds the root token and the tokens issued by plugin logins. When the
+// store is enforced, plugin requests must carry one of these tokens in X-Vault-Token.
+type TokenStore struct {
+	mu      sync.RWMutex
+	root    string
+	enforce bool
+	tokens  map[string]*TokenEntry
+}
+
+// NewTokenStore creates a token store with the given root token, generating one if empty
+func NewTokenStore(root string) *TokenStore {
+	if root == "" {
+		root = "hvs." + randomString(24)
+	}
+	return &TokenStore{
+		root: root,
+		tokens: map[string]*TokenEntry{
+			root: {
+				Accessor:    randomString(24),
+				Policies:    []string{"root"},
+				DisplayName: "root",
+				Path:        "auth/token/root",
+				IssueTime:   time.Now(),
+			},
+		},
+	}
+}
+
+// RootToken returns the root token
+func (s *TokenStore) RootToken() string {
+	return s.root
+}
+
+// Enforce sets whether plugin requests without a valid token are rejected
+func (s *TokenStore) Enforce(enforce bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.enforce = enforce
+}
+
+// Enforced reports whether plugin requests must carry a valid token
+func (s *TokenStore) Enforced() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.enforce
+}
+
+// Issue creates a token for a successful login at path. issuer, when set, is asked
+// to renew the token through the plugin.
+func (s *TokenStore) Issue(auth *logical.Auth, path string, issuer *Handler) (string, TokenEntry) {
+	token := "hvs." + randomString(24)
+
+	// Vault attaches the default policy to tokens unless they opt out or are root tokens
+	policies := append([]string(nil), auth.Policies...)
+	if !auth.NoDefaultPolicy && !containsString(policies, "default") && !containsString(policies, "root") {
+		policies = append(policies, "default")
+	}
+
+	entry := &TokenEntry{
+		Accessor:    randomString(24),
+		Policies:    policies,
+		DisplayName: auth.DisplayName,
+		EntityID:    auth.EntityID,
+		Metadata:    auth.Metadata,
+		Path:        path,
+		Renewable:   auth.Renewable,
+		TTL:         auth.TTL,
+		MaxTTL:      auth.MaxTTL,
+		NumUses:     auth.NumUses,
+		IssueTime:   time.Now(),
+		creationTTL: auth.TTL,
+		auth:        auth,
+		issuer:      issuer,
+	}
+	if auth.TTL > 0 {
+		entry.ExpireTime = entry.IssueTime.Add(auth.TTL)
+	}
+
+	s.mu.Lock()
+	s.prune(entry.IssueTime)
+	s.tokens[token] = entry
+	s.mu.Unlock()
+	return token, *entry
+}
+
+// prune removes the tokens that expired before now, so a long-running host does not
+// keep every token it ever issued. The caller must hold s.mu.
+func (s *TokenStore) prune(now time.Time) {
+	for token, entry := range s.tokens {
+		if !entry.ExpireTime.IsZero() && now.After(entry.ExpireTime) {
+			delete(s.tokens, token)
+		}
+	}
+}
+
+// Tidy removes the tokens that expired by now, which are otherwise only pruned when a
+// token is issued, and returns how many it removed and how many are left; a nil store
+// has none
+func (s *TokenStore) Tidy(now time.Time) (removed, live int) {
+	if s == nil {
+		return 0, 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	before := len(s.tokens)
+	s.prune(now)
+	return before - len(s.tokens), len(s.tokens)
+}
+
+// Lookup returns the entry of a valid, unexpired token
+func (s *TokenStore) Lookup(token string) (TokenEntry, bool) {
+	if token == "" {
+		return TokenEntry{}, false
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entry, ok := s.tokens[token]
+	if !ok || (!entry.ExpireTime.IsZero() && time.Now().After(entry.ExpireTime)) {
+		return TokenEntry{}, false
+	}
+	return *entry, true
+}
+
+// tokenRevocationPending is the NumUses that Use reports for the last use of a token,
+// as Vault reports it to plugins in ClientTokenRemainingUses
+const tokenRevocationPending = -1
+
+// Use looks up a token for a plugin request, counting the request against a
+// use-limited token. The returned entry has the uses left after this request; a token
+// used for the last time is removed and reported with tokenRevocationPending.
+func (s *TokenStore) Use(token string) (TokenEntry, bool) {
+	if token == "" {
+		return TokenEntry{}, false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.tokens[token]
+	if !ok || (!entry.ExpireTime.IsZero() && time.Now().After(entry.ExpireTime)) {
+		return TokenEntry{}, false
+	}
+	if entry.NumUses == 0 {
+		return *entry, true
+	}
+	entry.NumUses--
+	if entry.NumUses > 0 {
+		return *entry, true
+	}
+	delete(s.tokens, token)
+	used := *entry
+	used.NumUses = tokenRevocationPending
+	return used, true
+}
+
+// Accessors returns the accessors of the valid, unexpired tokens with the login path
+// that issued each; a nil store has none
+func (s *TokenStore) Accessors() map[string]string {
+	accessors := make(map[string]string)
+	if s == nil {
+		return accessors
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+	for _, entry := range s.tokens {
+		if entry.ExpireTime.IsZero() || now.Before(entry.ExpireTime) {
+			accessors[entry.Accessor] = entry.Path
+		}
+	}
+	return accessors
+}
+
+// lookupAccessor returns the token and entry of a valid, unexpired token by its
+// accessor. The caller must hold s.mu.
+func (s *TokenStore) lookupAccessor(accessor string) (string, *TokenEntry, bool) {
+	if accessor == "" {
+		return "", nil, false
+	}
+	now := time.Now()
+	for token, entry := range s.tokens {
+		if entry.Accessor == accessor && (entry.ExpireTime.IsZero() || now.Before(entry.ExpireTime)) {
+			return token, entry, true
+		}
+	}
+	return "", nil, false
+}
+
+// LookupAccessor returns the entry of the valid, unexpired token with the accessor
+func (s *TokenStore) LookupAccessor(accessor string) (TokenEntry, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, entry, ok := s.lookupAccessor(accessor)
+	if !ok {
+		return TokenEntry{}, false
+	}
+	return *entry, true
+}
+
+// RevokeAccessor removes the token with the accessor and reports whether it existed
+func (s *TokenStore) RevokeAccessor(accessor string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token, _, ok := s.lookupAccessor(accessor)
+	if ok {
+		delete(s.tokens, token)
+	}
+	return ok
+}
+
+// RequireRootToWrite is RequireRoot for requests that change something; reads and lists
+// pass without a token
+func (s *TokenStore) RequireRootToWrite(next http.HandlerFunc) http.HandlerFunc {
+	guarded := s.RequireRoot(next)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet || r.Method == "LIST" {
+			next(w, r)
+			return
+		}
+		guarded(w, r)
+	}
+}
+
+// RequireRoot guards host administration endpoints, such as mount management: when the
+// store is enforced, requests must carry a valid token with the root policy
+func (s *TokenStore) RequireRoot(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.Enforced() {
+			entry, ok := s.Lookup(requestToken(r))
+			if !ok || !containsString(entry.Policies, "root") {
+				WriteError(w, http.StatusForbidden, "permission denied")
+				return
+			}
+		}
+		next(w, r)
+	}
+}
+
+// Revoke removes a token and reports whether it existed
+func (s *TokenStore) Revoke(token string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.tokens[token]
+	delete(s.tokens, token)
+	return ok
+}
+
+// Renew extends a token through the plugin that issued it. increment is the TTL the
+// client asked for (0 keeps the plugin's choice). The new TTL is capped by the max TTL.
+func (s *TokenStore) Renew(ctx context.Context, token string, increment time.Duration) (TokenEntry, error) {
+	entry, ok := s.Lookup(token)
+	if !ok {
+		return TokenEntry{}, logical.ErrPermissionDenied
+	}
+	if !entry.Renewable {
+		return TokenEntry{}, fmt.Errorf("lease is not renewable")
+	}
+
+	auth := *entry.auth
+	auth.Increment = increment
+	auth.IssueTime = entry.IssueTime
+	auth.ClientToken = token
+	auth.Accessor = entry.Accessor
+	if entry.issuer != nil {
+		renewed, err := entry.issuer.renewAuth(ctx, entry.Path, &auth)
+		if err != nil {
+			return TokenEntry{}, err
+		}
+		auth = *renewed
+	}
+
+	ttl := auth.TTL
+	if ttl == 0 {
+		ttl = increment
+	}
+	if ttl == 0 {
+		ttl = entry.TTL
+	}
+
+	now := time.Now()
+	expire := now.Add(ttl)
+	if entry.MaxTTL > 0 && expire.After(entry.IssueTime.Add(entry.MaxTTL)) {
+		expire = entry.IssueTime.Add(entry.MaxTTL)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, ok := s.tokens[token]
+	if !ok {
+		return TokenEntry{}, logical.ErrPermissionDenied
+	}
+	stored.TTL = expire.Sub(now).Round(time.Second)
+	if ttl > 0 {
+		stored.ExpireTime = expire
+	}
+	stored.auth = &auth
+	return *stored, nil
+}
+
+// randomString returns n random characters from tokenAlphabet
+func randomString(n int) string {
+	size := big.NewInt(int64(len(tokenAlphabet)))
+	b := make([]byte, n)
+	for i := range b {
+		idx, _ := rand.Int(rand.Reader, size)
+		b[i] = tokenAlphabet[idx.Int64()]
+	}
+	return string(b)
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// authResponse builds the auth block of a login or token renewal response
+func authResponse(token string, entry TokenEntry) map[string]interface{} {
+	return map[string]interface{}{
+		"client_token":   token,
+		"accessor":       entry.Accessor,
+		"policies":       entry.Policies,
+		"token_policies": entry.Policies,
+		"metadata":       entry.Metadata,
+		"lease_duration": int(entry.TTL.Seconds()),
+		"renewable":      entry.Renewable,
+		"entity_id":      entry.EntityID,
+		"token_type":     "service",
+		"orphan":         true,
+	}
+}
+
+// tokenLookupResponse builds the response of a token lookup. Lookups by accessor pass
+// an empty token, since Vault does not reveal the token an accessor belongs to.
+func tokenLookupResponse(token string, entry TokenEntry) map[string]interface{} {
+	var expireTime interface{}
+	ttl := 0
+	if !entry.ExpireTime.IsZero() {
+		expireTime = entry.ExpireTime.Format(time.RFC3339Nano)
+		ttl = int(time.Until(entry.ExpireTime).Seconds())
+	}
+
+	return map[string]interface{}{
+		"request_id":     newRequestID(),
+		"lease_id":       "",
+		"renewable":      false,
+		"lease_duration": 0,
+		"data": map[string]interface{}{
+			"accessor":         entry.Accessor,
+			"creation_time":    entry.IssueTime.Unix(),
+			"creation_ttl":     int(entry.creationTTL.Seconds()),
+			"display_name":     entry.DisplayName,
+			"entity_id":        entry.EntityID,
+			"expire_time":      expireTime,
+			"explicit_max_ttl": int(entry.MaxTTL.Seconds()),
+			"id":               token,
+			"issue_time":       entry.IssueTime.Format(time.RFC3339Nano),
+			"meta":             entry.Metadata,
+			"num_uses":         entry.NumUses,
+			"orphan":           true,
+			"path":             entry.Path,
+			"policies":         entry.Policies,
+			"renewable":        entry.Renewable,
+			"ttl":              ttl,
+			"type":             "service",
+		},
+		"wrap_info": nil,
+		"warnings":  nil,
+		"auth":      nil,
+	}
+}
+
+// HandleLookupSelf serves /v1/auth/token/lookup-self with the details of the calling token
+func (s *TokenStore) HandleLookupSelf(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost && r.Method != http.MethodPut {
+		WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	token := requestToken(r)
+	entry, ok := s.Lookup(token)
+	if !ok {
+		WriteError(w, http.StatusForbidden, "permission denied")
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, tokenLookupResponse(token, entry))
+}
+
+// HandleCreate serves /v1/auth/token/create, creating a token from the calling token
+// like Vault's token store. Without policies the new token gets the caller's; as in
+// Vault, only a root token may give a child policies it does not have itself.
+func (s *TokenStore) HandleCreate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodPut {
+		WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	parent, ok := s.Lookup(requestToken(r))
+	if !ok {
+		WriteError(w, http.StatusForbidden, "permission denied")
+		return
+	}
+
+	var body struct {
+		Policies        []string          `json:"policies"`
+		TTL             interface{}       `json:"ttl"`
+		ExplicitMaxTTL  interface{}       `json:"explicit_max_ttl"`
+		DisplayName     string            `json:"display_name"`
+		Renewable       *bool             `json:"renewable"`
+		NoDefaultPolicy bool              `json:"no_default_policy"`
+		Meta            map[string]string `json:"meta"`
+		NumUses         int               `json:"num_uses"`
+	}
+	if data, err := io.ReadAll(r.Body); err == nil && len(data) > 0 {
+		if err := json.Unmarshal(data, &body); err != nil {
+			WriteError(w, http.StatusBadRequest, fmt.Sprintf("failed to parse JSON: %v", err))
+			return
+		}
+	}
+	ttl, err := parseTTL(body.TTL)
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	maxTTL, err := parseTTL(body.ExplicitMaxTTL)
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if body.NumUses < 0 {
+		WriteError(w, http.StatusBadRequest, "number of uses cannot be negative")
+		return
+	}
+
+	auth := &logical.Auth{
+		NumUses:         body.NumUses,
+		Policies:        body.Policies,
+		DisplayName:     "token",
+		Metadata:        body.Meta,
+		NoDefaultPolicy: body.NoDefaultPolicy,
+		EntityID:        parent.EntityID,
+		LeaseOptions: logical.LeaseOptions{
+			TTL:       ttl,
+			MaxTTL:    maxTTL,
+			Renewable: body.Renewable == nil || *body.Renewable,
+		},
+	}
+	if body.DisplayName != "" {
+		auth.DisplayName = "token-" + body.DisplayName
+	}
+	if len(auth.Policies) == 0 {
+		auth.Policies = parent.Policies
+	}
+	if !containsString(parent.Policies, "root") {
+		for _, policy := range auth.Policies {
+			if policy != "default" && !containsString(parent.Policies, policy) {
+				WriteError(w, http.StatusBadRequest, "child policies must be subset of parent")
+				return
+			}
+		}
+	}
+
+	token, entry := s.Issue(auth, "auth/token/create", nil)
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"request_id":     newRequestID(),
+		"lease_id":       "",
+		"renewable":      false,
+		"lease_duration": 0,
+		"data":           nil,
+		"wrap_info":      nil,
+		"warnings":       nil,
+		"auth":           authResponse(token, entry),
+	})
+}
+
+// HandleRenewSelf serves /v1/auth/token/renew-self, renewing the calling token through
+// the plugin that issued it. The optional increment is a duration or a number of seconds.
+func (s *TokenStore) HandleRenewSelf(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodPut {
+		WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var body struct {
+		Increment interface{} `json:"increment"`
+	}
+	if data, err := io.ReadAll(r.Body); err == nil && len(data) > 0 {
+		if err := json.Unmarshal(data, &body); err != nil {
+			WriteError(w, http.StatusBadRequest, fmt.Sprintf("failed to parse JSON: %v", err))
+			return
+		}
+	}
+	increment, err := parseTTL(body.Increment)
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	token := requestToken(r)
+	entry, err := s.Renew(r.Context(), token, increment)
+	if err == logical.ErrPermissionDenied {
+		WriteError(w, http.StatusForbidden, "permission denied")
+		return
+	}
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"request_id":     newRequestID(),
+		"lease_id":       "",
+		"renewable":      false,
+		"lease_duration": 0,
+		"data":           nil,
+		"wrap_info":      nil,
+		"warnings":       nil,
+		"auth":           authResponse(token, entry),
+	})
+}
+
+// HandleRevokeSelf serves /v1/auth/token/revoke-self, revoking the calling token
+func (s *TokenStore) HandleRevokeSelf(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodPut {
+		WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	token := requestToken(r)
+	if _, ok := s.Lookup(token); !ok || !s.Revoke(token) {
+		WriteError(w, http.StatusForbidden, "permission denied")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// readAccessor reads the accessor from the body of an accessor endpoint request
+func readAccessor(r *http.Request) (string, error) {
+	var body struct {
+		Accessor string `json:"accessor"`
+	}
+	if data, err := io.ReadAll(r.Body); err == nil && len(data) > 0 {
+		if err := json.Unmarshal(data, &body); err != nil {
+			return "", fmt.Errorf("failed to parse JSON: %v", err)
+		}
+	}
+	if body.Accessor == "" {
+		return "", fmt.Errorf("missing accessor")
+	}
+	return body.Accessor, nil
+}
+
+// HandleLookupAccessor serves /v1/auth/token/lookup-accessor with the details of the
+// token whose accessor is given as {"accessor": ...}. As in Vault, the token itself is
+// not returned.
+func (s *TokenStore) HandleLookupAccessor(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodPut {
+		WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	accessor, err := readAccessor(r)
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	entry, ok := s.LookupAccessor(accessor)
+	if !ok {
+		WriteError(w, http.StatusBadRequest, "invalid accessor")
+		return
+	}
+	WriteJSON(w, http.StatusOK, tokenLookupResponse("", entry))
+}
+
+// HandleRevokeAccessor serves /v1/auth/token/revoke-accessor, revoking the token whose
+// accessor is given as {"accessor": ...}
+func (s *TokenStore) HandleRevokeAccessor(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodPut {
+		WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	accessor, err := readAccessor(r)
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !s.RevokeAccessor(accessor) {
+		WriteError(w, http.StatusBadRequest, "invalid accessor")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleListAccessors serves LIST /v1/auth/token/accessors with the accessors of every
+// valid token, sorted
+func (s *TokenStore) HandleListAccessors(w http.ResponseWriter, r *http.Request) {
+	if !isListRequest(r) {
+		WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	accessors := s.Accessors()
+	keys := make([]string, 0, len(accessors))
+	for accessor := range accessors {
+		keys = append(keys, accessor)
+	}
+	sort.Strings(keys)
+
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"request_id": newRequestID(),
+		"data":       map[string]interface{}{"keys": keys},
+	})
+}
+
+// parseTTL reads a TTL given as seconds or a duration string
+func parseTTL(value interface{}) (time.Duration, error) {
+	switch v := value.(type) {
+	case nil:
+		return 0, nil
+	case float64:
+		return time.Duration(v) * time.Second, nil
+	case string:
+		if v == "" {
+			return 0, nil
+		}
+		if d, err := time.ParseDuration(v); err == nil {
+			return d, nil
+		}
+		if d, err := time.ParseDuration(v + "s"); err == nil {
+			return d, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid TTL %v", value)
+}
+
+// SetTokenStore makes the handler issue tokens from the store for plugin logins and,
+// when the store is enforced, require one of its tokens on plugin requests
+func (h *Handler) SetTokenStore(tokens *TokenStore) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.tokens = tokens
+}
+
+// renewAuth asks the plugin to renew a token it issued at path, as Vault does for
+// tokens created by auth methods
+func (h *Handler) renewAuth(ctx context.Context, path string, auth *logical.Auth) (*logical.Auth, error) {
+	h.mu.RLock()
+	backend := h.backend
+	clock := h.clock
+	h.mu.RUnlock()
+	if backend == nil {
+		return nil, fmt.Errorf("plugin not initialized")
+	}
+	auth.IssueTime = clock.Reported(auth.IssueTime)
+
+	req := &logical.Request{
+		Operation:           logical.RenewOperation,
+		Path:                strings.TrimPrefix(path, strings.Trim(h.mountPath, "/")+"/"),
+		Storage:             h.storage,
+		Auth:                auth,
+		ClientToken:         auth.ClientToken,
+		ClientTokenAccessor: auth.Accessor,
+		EntityID:            auth.EntityID,
+	}
+	resp, err := h.callBackend(ctx, backend, req)
+	if err != nil {
+		return nil, err
+	}
+	if resp != nil && resp.IsError() {
+		return nil, resp.Error()
+	}
+	if resp == nil || resp.Auth == nil {
+		return nil, fmt.Errorf("plugin did not return auth information on renewal")
+	}
+	return resp.Auth, nil
+}
+
+// unauthenticated reports whether the plugin lists path as reachable without a token.
+// The paths are asked of the plugin once per backend, outside the handler's lock, as
+// that is a call to the plugin process.
+func (h *Handler) unauthenticated(path string) bool {
+	h.mu.RLock()
+	backend, loaded, patterns, epoch := h.backend, h.unauthLoaded, h.unauthPaths, h.backendEpoch
+	h.mu.RUnlock()
+
+	if !loaded {
+		patterns = nil
+		if b, ok := backend.(interface{ SpecialPaths() *logical.Paths }); ok {
+			if paths := b.SpecialPaths(); paths != nil {
+				patterns = paths.Unauthenticated
+			}
+		}
+		h.mu.Lock()
+		// Keep them unless the backend was replaced meanwhile
+		if h.backendEpoch == epoch {
+			h.unauthLoaded = true
+			h.unauthPaths = patterns
+		}
+		h.mu.Unlock()
+	}
+
+	for _, pattern := range patterns {
+		if matchSpecialPath(pattern, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchSpecialPath matches a path against a special path pattern, where a trailing "*"
+// matches any suffix and a "+" segment matches exactly one segment
+func matchSpecialPath(pattern, path string) bool {
+	prefix := strings.HasSuffix(pattern, "*")
+	pattern = strings.TrimSuffix(pattern, "*")
+
+	patternSegs := strings.Split(pattern, "/")
+	pathSegs := strings.Split(path, "/")
+	if len(pathSegs) < len(patternSegs) || (!prefix && len(pathSegs) != len(patternSegs)) {
+		return false
+	}
+
+	last := len(patternSegs) - 1
+	for i, seg := range patternSegs {
+		switch {
+		case seg == "+":
+		case i == last && prefix:
+			if !strings.HasPrefix(pathSegs[i], seg) {
+				return false
+			}
+		case seg != pathSegs[i]:
+			return false
+		}
+	}
+	return true
+}
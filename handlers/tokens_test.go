@@ -0,0 +1,474 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// specialPathsBackend is a funcBackend that declares special paths
+type specialPathsBackend struct {
+	funcBackend
+	paths *logical.Paths
+}
+
+func (b specialPathsBackend) SpecialPaths() *logical.Paths {
+	return b.paths
+}
+
+func TestTokenStore(t *testing.T) {
+	store := NewTokenStore("")
+	root := store.RootToken()
+	if !strings.HasPrefix(root, "hvs.") {
+		t.Errorf("generated root token = %q, want an hvs. token", root)
+	}
+	if entry, ok := store.Lookup(root); !ok || entry.Policies[0] != "root" {
+		t.Errorf("root token lookup = %+v, %v", entry, ok)
+	}
+	if _, ok := store.Lookup("unknown"); ok {
+		t.Error("unknown token should be rejected")
+	}
+
+	token, entry := store.Issue(&logical.Auth{Policies: []string{"dev"}, EntityID: "entity-1"}, "plugin/login", nil)
+	if found, ok := store.Lookup(token); !ok || found.Accessor != entry.Accessor || found.EntityID != "entity-1" {
+		t.Errorf("issued token lookup = %+v, %v", found, ok)
+	}
+
+	if len(entry.Policies) != 2 || entry.Policies[1] != "default" {
+		t.Errorf("policies = %v, want dev and default", entry.Policies)
+	}
+
+	expired, _ := store.Issue(&logical.Auth{LeaseOptions: logical.LeaseOptions{TTL: time.Nanosecond}}, "plugin/login", nil)
+	time.Sleep(time.Millisecond)
+	if _, ok := store.Lookup(expired); ok {
+		t.Error("expired token should be rejected")
+	}
+
+	// Issuing prunes the tokens that have expired
+	store.Issue(&logical.Auth{}, "plugin/login", nil)
+	if _, ok := store.tokens[expired]; ok {
+		t.Error("expired token was not pruned")
+	}
+}
+
+func TestMatchSpecialPath(t *testing.T) {
+	tests := []struct {
+		pattern, path string
+		want          bool
+	}{
+		{"login", "login", true},
+		{"login", "login/user", false},
+		{"login*", "login/user", true},
+		{"login/*", "login", false},
+		{"role/+/login", "role/dev/login", true},
+		{"role/+/login", "role/dev/other", false},
+		{"role/+/log*", "role/dev/login/x", true},
+	}
+	for _, tt := range tests {
+		if got := matchSpecialPath(tt.pattern, tt.path); got != tt.want {
+			t.Errorf("matchSpecialPath(%q, %q) = %v, want %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestHandleRequestToken(t *testing.T) {
+	var seen *logical.Request
+	backend := specialPathsBackend{
+		funcBackend: func(ctx context.Context, req *logical.Request) (*logical.Response, error) {
+			seen = req
+			if req.Path == "login" {
+				return &logical.Response{Auth: &logical.Auth{Policies: []string{"dev"}}}, nil
+			}
+			return &logical.Response{Data: map[string]interface{}{"ok": true}}, nil
+		},
+		paths: &logical.Paths{Unauthenticated: []string{"login"}},
+	}
+	handler := NewHandler(backend, newMockStorage(), hclog.NewNullLogger(), "plugin")
+	store := NewTokenStore("root-token")
+	store.Enforce(true)
+	handler.SetTokenStore(store)
+
+	do := func(method, path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		if token != "" {
+			req.Header.Set("X-Vault-Token", token)
+		}
+		w := httptest.NewRecorder()
+		handler.HandleRequest(w, req)
+		return w
+	}
+
+	for _, token := range []string{"", "wrong"} {
+		seen = nil
+		w := do("GET", "/v1/plugin/config", token)
+		if w.Code != http.StatusForbidden || seen != nil {
+			t.Fatalf("token %q: status = %d, want 403 without calling the plugin", token, w.Code)
+		}
+		if !strings.Contains(w.Body.String(), "permission denied") {
+			t.Errorf("unexpected error body: %s", w.Body.String())
+		}
+	}
+
+	w := do("GET", "/v1/plugin/config", "root-token")
+	if w.Code != http.StatusOK {
+		t.Fatalf("root token: status = %d, want 200", w.Code)
+	}
+	root, _ := store.Lookup("root-token")
+	if seen.ClientToken != "root-token" || seen.ClientTokenAccessor != root.Accessor {
+		t.Errorf("plugin saw token %q accessor %q", seen.ClientToken, seen.ClientTokenAccessor)
+	}
+
+	// Login is unauthenticated and issues a token that is then accepted
+	w = do("PUT", "/v1/plugin/login", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("login: status = %d, want 200", w.Code)
+	}
+	var login struct {
+		Auth struct {
+			ClientToken string `json:"client_token"`
+			Accessor    string `json:"accessor"`
+		} `json:"auth"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &login)
+	if login.Auth.ClientToken == "" {
+		t.Fatalf("login returned no token: %s", w.Body.String())
+	}
+
+	if w := do("GET", "/v1/plugin/config", login.Auth.ClientToken); w.Code != http.StatusOK {
+		t.Errorf("issued token: status = %d, want 200", w.Code)
+	}
+	if seen.ClientTokenAccessor != login.Auth.Accessor {
+		t.Errorf("accessor = %q, want %q", seen.ClientTokenAccessor, login.Auth.Accessor)
+	}
+}
+
+// slowPathsBackend answers SpecialPaths once released, as a plugin busy elsewhere would
+type slowPathsBackend struct {
+	funcBackend
+	asked   chan struct{}
+	release chan struct{}
+}
+
+func (b slowPathsBackend) SpecialPaths() *logical.Paths {
+	close(b.asked)
+	<-b.release
+	return &logical.Paths{Unauthenticated: []string{"login"}}
+}
+
+func TestUnauthenticatedOutsideLock(t *testing.T) {
+	backend := slowPathsBackend{
+		funcBackend: func(ctx context.Context, req *logical.Request) (*logical.Response, error) {
+			return &logical.Response{}, nil
+		},
+		asked:   make(chan struct{}),
+		release: make(chan struct{}),
+	}
+	handler := NewHandler(backend, newMockStorage(), hclog.NewNullLogger(), "plugin")
+	store := NewTokenStore("root-token")
+	store.Enforce(true)
+	handler.SetTokenStore(store)
+
+	done := make(chan int)
+	go func() {
+		w := httptest.NewRecorder()
+		handler.HandleRequest(w, httptest.NewRequest("PUT", "/v1/plugin/login", nil))
+		done <- w.Code
+	}()
+	<-backend.asked
+
+	// Requests that need the handler's lock go on while the plugin is asked
+	changed := make(chan struct{})
+	go func() {
+		handler.SetPluginType("other")
+		close(changed)
+	}()
+	select {
+	case <-changed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the handler's lock was held while the plugin was asked for its special paths")
+	}
+
+	close(backend.release)
+	if code := <-done; code != http.StatusOK {
+		t.Errorf("login: status = %d, want 200", code)
+	}
+}
+
+func TestTokenSelfEndpoints(t *testing.T) {
+	var renewReq *logical.Request
+	backend := funcBackend(func(ctx context.Context, req *logical.Request) (*logical.Response, error) {
+		if req.Operation == logical.RenewOperation {
+			renewReq = req
+			auth := *req.Auth
+			auth.TTL = req.Auth.Increment
+			return &logical.Response{Auth: &auth}, nil
+		}
+		return &logical.Response{Auth: &logical.Auth{
+			Policies:     []string{"dev"},
+			DisplayName:  "userpass-alice",
+			Metadata:     map[string]string{"username": "alice"},
+			LeaseOptions: logical.LeaseOptions{TTL: time.Hour, MaxTTL: 2 * time.Hour, Renewable: true},
+		}}, nil
+	})
+	handler := NewHandler(backend, newMockStorage(), hclog.NewNullLogger(), "plugin")
+	store := NewTokenStore("")
+	handler.SetTokenStore(store)
+
+	w := httptest.NewRecorder()
+	handler.HandleRequest(w, httptest.NewRequest("PUT", "/v1/plugin/login/alice", strings.NewReader(`{"password":"x"}`)))
+	var login struct {
+		Auth struct {
+			ClientToken   string `json:"client_token"`
+			LeaseDuration int    `json:"lease_duration"`
+		} `json:"auth"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &login)
+	token := login.Auth.ClientToken
+	if token == "" || login.Auth.LeaseDuration != 3600 {
+		t.Fatalf("unexpected login response: %s", w.Body.String())
+	}
+
+	call := func(handle http.HandlerFunc, method, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/v1/auth/token/self", strings.NewReader(body))
+		req.Header.Set("X-Vault-Token", token)
+		w := httptest.NewRecorder()
+		handle(w, req)
+		return w
+	}
+
+	w = call(store.HandleLookupSelf, "GET", token, "")
+	var lookup struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &lookup)
+	if w.Code != http.StatusOK || lookup.Data["path"] != "plugin/login/alice" || lookup.Data["display_name"] != "userpass-alice" {
+		t.Fatalf("lookup-self: status = %d, body = %s", w.Code, w.Body.String())
+	}
+	if call(store.HandleLookupSelf, "GET", "bogus", "").Code != http.StatusForbidden {
+		t.Error("lookup-self with an unknown token should be forbidden")
+	}
+
+	// Renewal goes through the plugin with the original auth and the requested increment
+	w = call(store.HandleRenewSelf, "PUT", token, `{"increment":"90m"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("renew-self: status = %d, body = %s", w.Code, w.Body.String())
+	}
+	if renewReq == nil || renewReq.Path != "login/alice" || renewReq.Auth.Metadata["username"] != "alice" {
+		t.Fatalf("plugin renew request = %+v", renewReq)
+	}
+	if renewReq.Auth.Increment != 90*time.Minute {
+		t.Errorf("increment = %v, want 90m", renewReq.Auth.Increment)
+	}
+	entry, _ := store.Lookup(token)
+	if entry.TTL != 90*time.Minute {
+		t.Errorf("renewed TTL = %v, want 90m", entry.TTL)
+	}
+
+	// The max TTL caps the renewal
+	call(store.HandleRenewSelf, "PUT", token, `{"increment":"5h"}`)
+	entry, _ = store.Lookup(token)
+	if entry.ExpireTime.After(entry.IssueTime.Add(2 * time.Hour)) {
+		t.Errorf("expire time %v is past the max TTL", entry.ExpireTime)
+	}
+
+	if w := call(store.HandleRenewSelf, "PUT", store.RootToken(), ""); w.Code != http.StatusBadRequest {
+		t.Errorf("renewing the root token: status = %d, want 400", w.Code)
+	}
+
+	if w := call(store.HandleRevokeSelf, "PUT", token, ""); w.Code != http.StatusNoContent {
+		t.Fatalf("revoke-self: status = %d", w.Code)
+	}
+	if _, ok := store.Lookup(token); ok {
+		t.Error("revoked token is still valid")
+	}
+}
+
+func TestTokenAccessorEndpoints(t *testing.T) {
+	store := NewTokenStore("")
+	token, issued := store.Issue(&logical.Auth{
+		Policies:     []string{"dev"},
+		DisplayName:  "approle",
+		LeaseOptions: logical.LeaseOptions{TTL: time.Hour},
+	}, "plugin/login", nil)
+
+	call := func(handle http.HandlerFunc, method, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handle(w, httptest.NewRequest(method, "/v1/auth/token/accessor", strings.NewReader(body)))
+		return w
+	}
+
+	w := call(store.HandleListAccessors, "LIST", "")
+	var list struct {
+		Data struct {
+			Keys []string `json:"keys"`
+		} `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &list)
+	root, _ := store.Lookup(store.RootToken())
+	if w.Code != http.StatusOK || len(list.Data.Keys) != 2 || !containsString(list.Data.Keys, root.Accessor) || !containsString(list.Data.Keys, issued.Accessor) {
+		t.Fatalf("accessors: status = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	// A lookup by accessor has the token's details but not the token
+	w = call(store.HandleLookupAccessor, "POST", `{"accessor":"`+issued.Accessor+`"}`)
+	var lookup struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &lookup)
+	if w.Code != http.StatusOK || lookup.Data["id"] != "" || lookup.Data["display_name"] != "approle" || lookup.Data["path"] != "plugin/login" {
+		t.Fatalf("lookup-accessor: status = %d, body = %s", w.Code, w.Body.String())
+	}
+	for _, body := range []string{"", `{"accessor":"bogus"}`} {
+		if w := call(store.HandleLookupAccessor, "POST", body); w.Code != http.StatusBadRequest {
+			t.Errorf("lookup-accessor with %q: status = %d, want 400", body, w.Code)
+		}
+	}
+
+	if w := call(store.HandleRevokeAccessor, "POST", `{"accessor":"`+issued.Accessor+`"}`); w.Code != http.StatusNoContent {
+		t.Fatalf("revoke-accessor: status = %d, body = %s", w.Code, w.Body.String())
+	}
+	if _, ok := store.Lookup(token); ok {
+		t.Error("token revoked by accessor is still valid")
+	}
+	if w := call(store.HandleRevokeAccessor, "POST", `{"accessor":"`+issued.Accessor+`"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("revoking a revoked accessor: status = %d, want 400", w.Code)
+	}
+}
+
+func TestRequireRoot(t *testing.T) {
+	store := NewTokenStore("")
+	guarded := store.RequireRoot(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	call := func(token string) int {
+		req := httptest.NewRequest("POST", "/v1/sys/mounts/extra", nil)
+		if token != "" {
+			req.Header.Set("X-Vault-Token", token)
+		}
+		w := httptest.NewRecorder()
+		guarded(w, req)
+		return w.Code
+	}
+
+	if code := call(""); code != http.StatusNoContent {
+		t.Errorf("without -token: status = %d, want 204", code)
+	}
+
+	store.Enforce(true)
+	login, _ := store.Issue(&logical.Auth{Policies: []string{"dev"}}, "plugin/login", nil)
+	for name, token := range map[string]string{"no token": "", "unknown token": "bogus", "login token": login} {
+		if code := call(token); code != http.StatusForbidden {
+			t.Errorf("%s: status = %d, want 403", name, code)
+		}
+	}
+	if code := call(store.RootToken()); code != http.StatusNoContent {
+		t.Errorf("root token: status = %d, want 204", code)
+	}
+}
+
+func TestRequireRootToWrite(t *testing.T) {
+	store := NewTokenStore("")
+	store.Enforce(true)
+	guarded := store.RequireRootToWrite(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	for method, want := range map[string]int{
+		http.MethodGet:    http.StatusNoContent,
+		"LIST":            http.StatusNoContent,
+		http.MethodPut:    http.StatusForbidden,
+		http.MethodPost:   http.StatusForbidden,
+		http.MethodDelete: http.StatusForbidden,
+	} {
+		w := httptest.NewRecorder()
+		guarded(w, httptest.NewRequest(method, "/v1/sys/policies/password/db", nil))
+		if w.Code != want {
+			t.Errorf("%s without a token: status = %d, want %d", method, w.Code, want)
+		}
+	}
+}
+
+func TestTokenUses(t *testing.T) {
+	store := NewTokenStore("root-token")
+	token, entry := store.Issue(&logical.Auth{NumUses: 2}, "auth/token/create", nil)
+	if entry.NumUses != 2 {
+		t.Fatalf("NumUses = %d, want 2", entry.NumUses)
+	}
+
+	for i, want := range []int{1, tokenRevocationPending} {
+		used, ok := store.Use(token)
+		if !ok || used.NumUses != want {
+			t.Errorf("use %d: NumUses = %d (valid %v), want %d", i+1, used.NumUses, ok, want)
+		}
+	}
+	if _, ok := store.Use(token); ok {
+		t.Error("a token should be revoked after its last use")
+	}
+	// Unlimited tokens are not counted
+	for i := 0; i < 3; i++ {
+		if root, ok := store.Use("root-token"); !ok || root.NumUses != 0 {
+			t.Fatalf("root token: NumUses = %d (valid %v)", root.NumUses, ok)
+		}
+	}
+}
+
+func TestTokenCreate(t *testing.T) {
+	store := NewTokenStore("root-token")
+
+	create := func(token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/v1/auth/token/create", strings.NewReader(body))
+		req.Header.Set("X-Vault-Token", token)
+		w := httptest.NewRecorder()
+		store.HandleCreate(w, req)
+		return w
+	}
+
+	if w := create("bogus", "{}"); w.Code != http.StatusForbidden {
+		t.Errorf("create with an unknown token: status = %d, want 403", w.Code)
+	}
+
+	// The Terraform provider creates a short-lived child token on startup
+	w := create("root-token", `{"display_name":"terraform","ttl":"20m","policies":["dev"]}`)
+	var created struct {
+		Auth struct {
+			ClientToken   string   `json:"client_token"`
+			Policies      []string `json:"policies"`
+			LeaseDuration int      `json:"lease_duration"`
+			Renewable     bool     `json:"renewable"`
+		} `json:"auth"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &created)
+	if w.Code != http.StatusOK || created.Auth.LeaseDuration != 1200 || !created.Auth.Renewable {
+		t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+	}
+	entry, ok := store.Lookup(created.Auth.ClientToken)
+	if !ok || entry.DisplayName != "token-terraform" || strings.Join(entry.Policies, ",") != "dev,default" {
+		t.Errorf("created token = %+v, %v", entry, ok)
+	}
+
+	// Without policies a child of the root token is a root token
+	json.Unmarshal(create("root-token", "{}").Body.Bytes(), &created)
+	if strings.Join(created.Auth.Policies, ",") != "root" {
+		t.Errorf("child policies = %v, want [root]", created.Auth.Policies)
+	}
+
+	// Only a root token may grant policies it does not have itself
+	dev, _ := store.Issue(&logical.Auth{Policies: []string{"dev"}}, "plugin/login", nil)
+	if w := create(dev, `{"policies":["dev","default"]}`); w.Code != http.StatusOK {
+		t.Errorf("a subset of the parent's policies: status = %d, body = %s", w.Code, w.Body.String())
+	}
+	for _, body := range []string{`{"policies":["root"]}`, `{"policies":["dev","admin"]}`} {
+		if w := create(dev, body); w.Code != http.StatusBadRequest {
+			t.Errorf("%s from a dev token: status = %d, want 400", body, w.Code)
+		}
+	}
+}
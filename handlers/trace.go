@@ -0,0 +1,275 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// StorageOp is a single storage callback made by the plugin while serving a request
+type StorageOp struct {
+	Op       string        `json:"op"`
+	Key      string        `json:"key"`
+	Start    time.Duration `json:"start"` // offset from the start of the request
+	Duration time.Duration `json:"duration"`
+	Done     bool          `json:"done"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// requestTrace records where time is spent while a request is handled
+type requestTrace struct {
+	mu           sync.Mutex
+	start        time.Time
+	backendStart time.Time
+	backendEnd   time.Time
+	storageOps   []*StorageOp
+	logger       hclog.Logger // set for requests that asked for trace logging
+}
+
+// newRequestTrace starts a trace at the given time
+func newRequestTrace(start time.Time) *requestTrace {
+	return &requestTrace{start: start}
+}
+
+// backendStarted marks the moment the request is handed to the plugin
+func (t *requestTrace) backendStarted() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.backendStart = time.Now()
+	if t.logger != nil {
+		t.logger.Trace("gRPC HandleRequest started", "queue", t.backendStart.Sub(t.start))
+	}
+}
+
+// backendFinished marks the moment the plugin returned
+func (t *requestTrace) backendFinished() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.backendEnd = time.Now()
+	if t.logger != nil {
+		t.logger.Trace("gRPC HandleRequest finished", "duration", t.backendEnd.Sub(t.backendStart))
+	}
+}
+
+// beginStorage records the start of a storage callback
+func (t *requestTrace) beginStorage(op, key string) *StorageOp {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	entry := &StorageOp{Op: op, Key: key, Start: time.Since(t.start)}
+	t.storageOps = append(t.storageOps, entry)
+	if t.logger != nil {
+		t.logger.Trace("storage callback started", "op", op, "key", key)
+	}
+	return entry
+}
+
+// endStorage records the completion of a storage callback
+func (t *requestTrace) endStorage(entry *StorageOp, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	entry.Duration = time.Since(t.start) - entry.Start
+	entry.Done = true
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	if t.logger != nil {
+		args := []interface{}{"op", entry.Op, "key", entry.Key, "duration", entry.Duration}
+		if err != nil {
+			args = append(args, "error", err)
+		}
+		t.logger.Trace("storage callback finished", args...)
+	}
+}
+
+// Summary describes the time spent in each phase of the request so far
+func (t *requestTrace) Summary() map[string]interface{} {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	summary := map[string]interface{}{
+		"elapsed": now.Sub(t.start).String(),
+	}
+
+	if t.backendStart.IsZero() {
+		summary["queue"] = now.Sub(t.start).String()
+		return summary
+	}
+	summary["queue"] = t.backendStart.Sub(t.start).String()
+
+	backendEnd := t.backendEnd
+	if backendEnd.IsZero() {
+		backendEnd = now
+		summary["backend_running"] = true
+	}
+	backend := backendEnd.Sub(t.backendStart)
+
+	var storageTotal time.Duration
+	ops := make([]StorageOp, 0, len(t.storageOps))
+	var pending []StorageOp
+	for _, op := range t.storageOps {
+		copied := *op
+		if !op.Done {
+			copied.Duration = now.Sub(t.start) - op.Start
+			pending = append(pending, copied)
+		}
+		storageTotal += copied.Duration
+		ops = append(ops, copied)
+	}
+
+	summary["backend"] = backend.String()
+	summary["grpc"] = (backend - storageTotal).String()
+	summary["storage"] = map[string]interface{}{
+		"calls":       len(ops),
+		"total":       storageTotal.String(),
+		"ops":         ops,
+		"in_progress": pending,
+	}
+	return summary
+}
+
+// cost returns how long the plugin has spent on the request so far and how many
+// storage calls it made
+func (t *requestTrace) cost() (time.Duration, int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.backendStart.IsZero() {
+		return 0, len(t.storageOps)
+	}
+	end := t.backendEnd
+	if end.IsZero() {
+		end = time.Now()
+	}
+	return end.Sub(t.backendStart), len(t.storageOps)
+}
+
+// writtenKeys returns the keys the request stored and did not delete again, sorted
+func (t *requestTrace) writtenKeys() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	written := make(map[string]bool)
+	for _, op := range t.storageOps {
+		if op.Error != "" {
+			continue
+		}
+		switch op.Op {
+		case "put":
+			written[op.Key] = true
+		case "delete":
+			delete(written, op.Key)
+		}
+	}
+	keys := make([]string, 0, len(written))
+	for key := range written {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// deletedKeys returns the keys the request deleted, sorted
+func (t *requestTrace) deletedKeys() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	deleted := make(map[string]bool)
+	for _, op := range t.storageOps {
+		if op.Op == "delete" && op.Error == "" {
+			deleted[op.Key] = true
+		}
+	}
+	keys := make([]string, 0, len(deleted))
+	for key := range deleted {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// tracedStorage records plugin storage callbacks on a request trace
+type tracedStorage struct {
+	storage StorageView
+	trace   *requestTrace
+}
+
+var _ logical.Storage = (*tracedStorage)(nil)
+
+func (s *tracedStorage) List(ctx context.Context, prefix string) ([]string, error) {
+	op := s.trace.beginStorage("list", prefix)
+	keys, err := s.storage.List(ctx, prefix)
+	s.trace.endStorage(op, err)
+	return keys, err
+}
+
+func (s *tracedStorage) Get(ctx context.Context, key string) (*logical.StorageEntry, error) {
+	op := s.trace.beginStorage("get", key)
+	entry, err := s.storage.Get(ctx, key)
+	s.trace.endStorage(op, err)
+	return entry, err
+}
+
+func (s *tracedStorage) Put(ctx context.Context, entry *logical.StorageEntry) error {
+	op := s.trace.beginStorage("put", entry.Key)
+	err := s.storage.Put(ctx, entry)
+	s.trace.endStorage(op, err)
+	return err
+}
+
+func (s *tracedStorage) Delete(ctx context.Context, key string) error {
+	op := s.trace.beginStorage("delete", key)
+	err := s.storage.Delete(ctx, key)
+	s.trace.endStorage(op, err)
+	return err
+}
+
+// callStorage traces the storage calls a plugin makes through the storage it was set up
+// with. A plugin served over gRPC uses that storage in place of the request's, and its
+// calls carry nothing naming the request they are made for, so they are recorded on the
+// trace of the request in flight while there is only one.
+type callStorage struct {
+	storage StorageView
+	handler *Handler
+}
+
+var _ logical.Storage = (*callStorage)(nil)
+
+// CallStorage wraps the storage a plugin backend is set up with, so the storage calls
+// of a plugin process are traced with the request that made them
+func (h *Handler) CallStorage(storage StorageView) logical.Storage {
+	return &callStorage{storage: storage, handler: h}
+}
+
+// traced returns the storage to make a call through
+func (s *callStorage) traced() StorageView {
+	if trace := s.handler.soleCallTrace(); trace != nil {
+		return &tracedStorage{storage: s.storage, trace: trace}
+	}
+	return s.storage
+}
+
+func (s *callStorage) List(ctx context.Context, prefix string) ([]string, error) {
+	return s.traced().List(ctx, prefix)
+}
+
+func (s *callStorage) Get(ctx context.Context, key string) (*logical.StorageEntry, error) {
+	return s.traced().Get(ctx, key)
+}
+
+func (s *callStorage) Put(ctx context.Context, entry *logical.StorageEntry) error {
+	return s.traced().Put(ctx, entry)
+}
+
+func (s *callStorage) Delete(ctx context.Context, key string) error {
+	return s.traced().Delete(ctx, key)
+}
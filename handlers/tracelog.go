@@ -0,0 +1,85 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+const (
+	// TraceHeader enables trace-level logging for a single request when set to true
+	TraceHeader = "X-Vault-Trace"
+	// TraceIDHeader carries the ID that tags the trace log lines of a request
+	TraceIDHeader = "X-Vault-Trace-Id"
+)
+
+// SetTraceOutput sets where per-request trace logs are written (os.Stderr by default)
+func (h *Handler) SetTraceOutput(output io.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.traceOutput = output
+}
+
+// traceLogger returns a trace-level logger and trace ID for a request that asked for one
+// with TraceHeader, or nil. The logger is independent of the host's log level so only
+// the traced request becomes verbose.
+func (h *Handler) traceLogger(r *http.Request) (hclog.Logger, string) {
+	if enabled, _ := strconv.ParseBool(r.Header.Get(TraceHeader)); !enabled {
+		return nil, ""
+	}
+
+	h.mu.RLock()
+	output := h.traceOutput
+	h.mu.RUnlock()
+	if output == nil {
+		output = os.Stderr
+	}
+
+	id := make([]byte, 8)
+	rand.Read(id)
+	traceID := hex.EncodeToString(id)
+
+	logger := hclog.New(&hclog.LoggerOptions{
+		Name:   h.logger.Name(),
+		Level:  hclog.Trace,
+		Output: output,
+	}).Named("trace").With("trace_id", traceID)
+	return logger, traceID
+}
+
+// statusWriter remembers the status code written to a response
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// traceFinished logs the outcome of a traced request
+func traceFinished(logger hclog.Logger, w *statusWriter, trace *requestTrace, start time.Time) {
+	status := w.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	summary := trace.Summary()
+	storage, _ := summary["storage"].(map[string]interface{})
+	logger.Trace("request finished",
+		"status", status,
+		"elapsed", time.Since(start),
+		"backend", summary["backend"],
+		"grpc", summary["grpc"],
+		"storage_calls", storage["calls"],
+		"storage", storage["total"])
+}
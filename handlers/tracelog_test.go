@@ -0,0 +1,68 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestTraceHeader(t *testing.T) {
+	var requestID string
+	backend := funcBackend(func(ctx context.Context, req *logical.Request) (*logical.Response, error) {
+		requestID = req.ID
+		req.Storage.Get(ctx, "config")
+		return &logical.Response{Data: map[string]interface{}{"ok": true}}, nil
+	})
+
+	var output bytes.Buffer
+	logger := hclog.New(&hclog.LoggerOptions{Level: hclog.Info, Output: &output})
+	handler := NewHandler(backend, newMockStorage(), logger, "plugin")
+	handler.SetTraceOutput(&output)
+
+	// An ordinary request logs nothing at info level
+	w := httptest.NewRecorder()
+	handler.HandleRequest(w, httptest.NewRequest("GET", "/v1/plugin/config", nil))
+	if w.Header().Get(TraceIDHeader) != "" || output.Len() != 0 {
+		t.Fatalf("untraced request produced trace output: %q", output.String())
+	}
+
+	req := httptest.NewRequest("GET", "/v1/plugin/config", nil)
+	req.Header.Set(TraceHeader, "true")
+	w = httptest.NewRecorder()
+	handler.HandleRequest(w, req)
+
+	traceID := w.Header().Get(TraceIDHeader)
+	if traceID == "" {
+		t.Fatal("traced request did not return a trace ID")
+	}
+	if requestID != traceID {
+		t.Errorf("plugin request ID = %q, want the trace ID %q", requestID, traceID)
+	}
+	if w.Code != http.StatusOK {
+		t.Errorf("status = %d, want 200", w.Code)
+	}
+
+	logs := output.String()
+	for _, want := range []string{
+		"request received",
+		"gRPC HandleRequest started",
+		"storage callback finished",
+		"op=get key=config",
+		"gRPC HandleRequest finished",
+		"request finished",
+		"trace_id=" + traceID,
+	} {
+		if !strings.Contains(logs, want) {
+			t.Errorf("trace log is missing %q:\n%s", want, logs)
+		}
+	}
+}
@@ -0,0 +1,343 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/helper/wrapping"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// WrapTTLHeader asks for the response of a request to be wrapped for the given TTL
+const WrapTTLHeader = "X-Vault-Wrap-TTL"
+
+// errInvalidWrappingToken is Vault's error for unknown, used or expired wrapping tokens
+var errInvalidWrappingToken = errors.New("wrapping token is not valid or does not exist")
+
+// wrappedResponse is the cubbyhole entry of a wrapping token
+type wrappedResponse struct {
+	Info     wrapping.ResponseWrapInfo `json:"info"`
+	Response map[string]interface{}    `json:"response"`
+}
+
+// WrapStore emulates Vault's response wrapping. Each wrapping token owns a cubbyhole
+// entry in storage that holds the wrapped response until it is unwrapped once or the
+// token's TTL runs out.
+type WrapStore struct {
+	storage logical.Storage
+
+	mu sync.Mutex // held while a token is consumed, so it is consumed once
+}
+
+// NewWrapStore creates a wrap store that keeps wrapped responses in storage
+func NewWrapStore(storage logical.Storage) *WrapStore {
+	return &WrapStore{storage: storage}
+}
+
+// cubbyholeKey is the storage key of a wrapping token's cubbyhole
+func cubbyholeKey(token string) string {
+	return "cubbyhole/" + token + "/response"
+}
+
+// Wrap stores response under a new wrapping token valid for ttl
+func (s *WrapStore) Wrap(ctx context.Context, response map[string]interface{}, ttl time.Duration, creationPath string) (*wrapping.ResponseWrapInfo, error) {
+	if ttl <= 0 {
+		return nil, fmt.Errorf("wrap TTL must be positive")
+	}
+
+	entry := wrappedResponse{
+		Info: wrapping.ResponseWrapInfo{
+			TTL:          ttl,
+			Token:        "hvs." + randomString(24),
+			Accessor:     randomString(24),
+			CreationTime: time.Now().UTC(),
+			CreationPath: creationPath,
+		},
+		Response: response,
+	}
+	value, err := json.Marshal(entry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode wrapped response: %w", err)
+	}
+	if err := s.storage.Put(ctx, &logical.StorageEntry{Key: cubbyholeKey(entry.Info.Token), Value: value}); err != nil {
+		return nil, err
+	}
+	return &entry.Info, nil
+}
+
+// Lookup returns the wrap info of a valid wrapping token without consuming it
+func (s *WrapStore) Lookup(ctx context.Context, token string) (*wrapping.ResponseWrapInfo, error) {
+	entry, err := s.load(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	return &entry.Info, nil
+}
+
+// Rewrap moves a wrapped response to a new wrapping token with the same TTL and
+// creation path, invalidating the old token, as Vault's sys/wrapping/rewrap does
+func (s *WrapStore) Rewrap(ctx context.Context, token string) (*wrapping.ResponseWrapInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, err := s.load(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	info, err := s.Wrap(ctx, entry.Response, entry.Info.TTL, entry.Info.CreationPath)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.storage.Delete(ctx, cubbyholeKey(token)); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// Unwrap returns the wrapped response and invalidates the wrapping token
+func (s *WrapStore) Unwrap(ctx context.Context, token string) (map[string]interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, err := s.load(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.storage.Delete(ctx, cubbyholeKey(token)); err != nil {
+		return nil, err
+	}
+	return entry.Response, nil
+}
+
+// load reads the cubbyhole of a token, deleting it once the token has expired
+func (s *WrapStore) load(ctx context.Context, token string) (*wrappedResponse, error) {
+	if token == "" {
+		return nil, errInvalidWrappingToken
+	}
+	stored, err := s.storage.Get(ctx, cubbyholeKey(token))
+	if err != nil {
+		return nil, err
+	}
+	if stored == nil {
+		return nil, errInvalidWrappingToken
+	}
+
+	var entry wrappedResponse
+	if err := json.Unmarshal(stored.Value, &entry); err != nil {
+		return nil, fmt.Errorf("failed to decode wrapped response: %w", err)
+	}
+	if time.Now().After(entry.Info.CreationTime.Add(entry.Info.TTL)) {
+		s.storage.Delete(ctx, cubbyholeKey(token))
+		return nil, errInvalidWrappingToken
+	}
+	return &entry, nil
+}
+
+// Tidy deletes the cubbyholes of the wrapping tokens that expired by now, which are
+// otherwise only deleted when the token is next used, and of tokens whose wrapped
+// response is gone or cannot be decoded, since no unwrap could return it. It returns
+// how many wrapped responses and other cubbyholes it removed and how many wrapped
+// responses are left.
+func (s *WrapStore) Tidy(ctx context.Context, now time.Time) (expired, orphaned, live int, err error) {
+	keys, err := s.storage.List(ctx, "cubbyhole/")
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	cubbyholes := make(map[string][]string)
+	for _, key := range keys {
+		if strings.HasSuffix(key, "/") {
+			continue
+		}
+		token, _, ok := strings.Cut(strings.TrimPrefix(key, "cubbyhole/"), "/")
+		if !ok {
+			continue
+		}
+		cubbyholes[token] = append(cubbyholes[token], key)
+	}
+
+	for token, keys := range cubbyholes {
+		stored, err := s.storage.Get(ctx, cubbyholeKey(token))
+		if err != nil {
+			return expired, orphaned, live, err
+		}
+		var entry wrappedResponse
+		switch {
+		case stored == nil || json.Unmarshal(stored.Value, &entry) != nil:
+			orphaned++
+		case now.After(entry.Info.CreationTime.Add(entry.Info.TTL)):
+			expired++
+		default:
+			live++
+			continue
+		}
+		for _, key := range keys {
+			if err := s.storage.Delete(ctx, key); err != nil {
+				return expired, orphaned, live, err
+			}
+		}
+	}
+	return expired, orphaned, live, nil
+}
+
+// wrapInfoResponse is the wrap_info block returned in place of a wrapped response
+func wrapInfoResponse(info *wrapping.ResponseWrapInfo) map[string]interface{} {
+	return map[string]interface{}{
+		"token":            info.Token,
+		"accessor":         info.Accessor,
+		"ttl":              int(info.TTL.Seconds()),
+		"creation_time":    info.CreationTime.Format(time.RFC3339Nano),
+		"creation_path":    info.CreationPath,
+		"wrapped_accessor": info.WrappedAccessor,
+	}
+}
+
+// wrapRequestToken returns the wrapping token of an unwrap or lookup request: the
+// "token" body parameter, or else the request's own token
+func wrapRequestToken(r *http.Request) (string, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read body: %v", err)
+	}
+	var data struct {
+		Token string `json:"token"`
+	}
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &data); err != nil {
+			return "", fmt.Errorf("failed to parse JSON: %v", err)
+		}
+	}
+	if data.Token != "" {
+		return data.Token, nil
+	}
+	return requestToken(r), nil
+}
+
+// HandleUnwrap serves /v1/sys/wrapping/unwrap, returning a wrapped response once
+func (s *WrapStore) HandleUnwrap(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodPut {
+		WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	token, err := wrapRequestToken(r)
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response, err := s.Unwrap(r.Context(), token)
+	if err == errInvalidWrappingToken {
+		WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	response["request_id"] = newRequestID()
+	WriteJSON(w, http.StatusOK, response)
+}
+
+// HandleLookup serves /v1/sys/wrapping/lookup with the details of a wrapping token
+func (s *WrapStore) HandleLookup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodPut {
+		WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	token, err := wrapRequestToken(r)
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	info, err := s.Lookup(r.Context(), token)
+	if err == errInvalidWrappingToken {
+		WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"request_id":     newRequestID(),
+		"lease_id":       "",
+		"renewable":      false,
+		"lease_duration": 0,
+		"data": map[string]interface{}{
+			"creation_path": info.CreationPath,
+			"creation_time": info.CreationTime.Format(time.RFC3339Nano),
+			"creation_ttl":  int(info.TTL.Seconds()),
+		},
+		"wrap_info": nil,
+		"warnings":  nil,
+		"auth":      nil,
+	})
+}
+
+// HandleRewrap serves /v1/sys/wrapping/rewrap, exchanging a wrapping token for a new
+// one that wraps the same response
+func (s *WrapStore) HandleRewrap(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodPut {
+		WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	token, err := wrapRequestToken(r)
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	info, err := s.Rewrap(r.Context(), token)
+	if err == errInvalidWrappingToken {
+		WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"request_id":     newRequestID(),
+		"lease_id":       "",
+		"renewable":      false,
+		"lease_duration": 0,
+		"data":           nil,
+		"wrap_info":      wrapInfoResponse(info),
+		"warnings":       nil,
+		"auth":           nil,
+	})
+}
+
+// SetWrapStore enables response wrapping with X-Vault-Wrap-TTL and for the plugin's
+// system view
+func (h *Handler) SetWrapStore(wraps *WrapStore) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.wraps = wraps
+}
+
+// SetWrapTTLs sets the wrap TTL of the mount's responses when a request asks for none,
+// and the largest wrap TTL a request may ask for; zero disables either
+func (h *Handler) SetWrapTTLs(responseTTL, maxTTL time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.responseWrapTTL = responseTTL
+	h.maxWrapTTL = maxTTL
+}
+
+// WrapStore returns the handler's wrap store, or nil when wrapping is disabled
+func (h *Handler) WrapStore() *WrapStore {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.wraps
+}
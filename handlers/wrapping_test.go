@@ -0,0 +1,240 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestWrapStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewWrapStore(newMockStorage())
+
+	info, err := store.Wrap(ctx, map[string]interface{}{"data": "secret"}, time.Minute, "plugin/creds")
+	if err != nil {
+		t.Fatalf("Wrap failed: %v", err)
+	}
+	if found, err := store.Lookup(ctx, info.Token); err != nil || found.CreationPath != "plugin/creds" {
+		t.Fatalf("Lookup = %+v, %v", found, err)
+	}
+
+	response, err := store.Unwrap(ctx, info.Token)
+	if err != nil || response["data"] != "secret" {
+		t.Fatalf("Unwrap = %v, %v", response, err)
+	}
+	if _, err := store.Unwrap(ctx, info.Token); err != errInvalidWrappingToken {
+		t.Errorf("second unwrap error = %v, want %v", err, errInvalidWrappingToken)
+	}
+
+	expired, _ := store.Wrap(ctx, map[string]interface{}{}, time.Nanosecond, "plugin/creds")
+	time.Sleep(time.Millisecond)
+	if _, err := store.Lookup(ctx, expired.Token); err != errInvalidWrappingToken {
+		t.Errorf("expired token lookup error = %v, want %v", err, errInvalidWrappingToken)
+	}
+}
+
+// slowStorage answers reads late, so concurrent callers all read before any of them writes
+type slowStorage struct {
+	logical.Storage
+}
+
+func (s slowStorage) Get(ctx context.Context, key string) (*logical.StorageEntry, error) {
+	entry, err := s.Storage.Get(ctx, key)
+	time.Sleep(10 * time.Millisecond)
+	return entry, err
+}
+
+func TestWrapStoreConcurrentUnwrap(t *testing.T) {
+	ctx := context.Background()
+	store := NewWrapStore(slowStorage{&logical.InmemStorage{}})
+	info, err := store.Wrap(ctx, map[string]interface{}{"data": "secret"}, time.Minute, "plugin/creds")
+	if err != nil {
+		t.Fatalf("Wrap failed: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	var successes atomic.Int32
+	start := make(chan struct{})
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			if _, err := store.Unwrap(ctx, info.Token); err == nil {
+				successes.Add(1)
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+	if n := successes.Load(); n != 1 {
+		t.Errorf("%d concurrent unwraps of one token succeeded, want exactly 1", n)
+	}
+}
+
+func TestHandleRequestWrapTTL(t *testing.T) {
+	var wrapInfo *logical.RequestWrapInfo
+	backend := funcBackend(func(ctx context.Context, req *logical.Request) (*logical.Response, error) {
+		wrapInfo = req.WrapInfo
+		return &logical.Response{Data: map[string]interface{}{"password": "hunter2"}}, nil
+	})
+	handler := NewHandler(backend, newMockStorage(), hclog.NewNullLogger(), "plugin")
+
+	request := func(wrapTTL string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/v1/plugin/creds", strings.NewReader(`{}`))
+		req.Header.Set(WrapTTLHeader, wrapTTL)
+		w := httptest.NewRecorder()
+		handler.HandleRequest(w, req)
+		return w
+	}
+
+	if w := request("5m"); w.Code != http.StatusBadRequest {
+		t.Errorf("wrapping without a wrap store: status = %d, want 400", w.Code)
+	}
+
+	wraps := NewWrapStore(newMockStorage())
+	handler.SetWrapStore(wraps)
+
+	if w := request("soon"); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid wrap TTL: status = %d, want 400", w.Code)
+	}
+
+	w := request("300")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+	}
+	if wrapInfo == nil || wrapInfo.TTL != 5*time.Minute {
+		t.Errorf("plugin saw wrap info %+v, want a 5m TTL", wrapInfo)
+	}
+
+	var wrapped struct {
+		Data     interface{} `json:"data"`
+		WrapInfo struct {
+			Token        string `json:"token"`
+			TTL          int    `json:"ttl"`
+			CreationPath string `json:"creation_path"`
+		} `json:"wrap_info"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &wrapped)
+	if wrapped.Data != nil || wrapped.WrapInfo.Token == "" || wrapped.WrapInfo.TTL != 300 || wrapped.WrapInfo.CreationPath != "plugin/creds" {
+		t.Fatalf("unexpected wrapped response: %s", w.Body.String())
+	}
+
+	// Lookup by body token, then unwrap with the token as X-Vault-Token
+	w = httptest.NewRecorder()
+	wraps.HandleLookup(w, httptest.NewRequest("POST", "/v1/sys/wrapping/lookup", strings.NewReader(`{"token":"`+wrapped.WrapInfo.Token+`"}`)))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"creation_ttl":300`) {
+		t.Errorf("lookup: status = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	unwrap := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/v1/sys/wrapping/unwrap", nil)
+		req.Header.Set("X-Vault-Token", wrapped.WrapInfo.Token)
+		w := httptest.NewRecorder()
+		wraps.HandleUnwrap(w, req)
+		return w
+	}
+	w = unwrap()
+	var unwrapped struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &unwrapped)
+	if w.Code != http.StatusOK || unwrapped.Data["password"] != "hunter2" {
+		t.Fatalf("unwrap: status = %d, body = %s", w.Code, w.Body.String())
+	}
+	if w := unwrap(); w.Code != http.StatusBadRequest {
+		t.Errorf("second unwrap: status = %d, want 400", w.Code)
+	}
+}
+
+func TestWrapStoreRewrap(t *testing.T) {
+	ctx := context.Background()
+	store := NewWrapStore(newMockStorage())
+
+	original, _ := store.Wrap(ctx, map[string]interface{}{"data": "secret"}, time.Hour, "plugin/creds")
+	w := httptest.NewRecorder()
+	store.HandleRewrap(w, httptest.NewRequest("POST", "/v1/sys/wrapping/rewrap", strings.NewReader(`{"token":"`+original.Token+`"}`)))
+	var rewrapped struct {
+		WrapInfo struct {
+			Token        string `json:"token"`
+			TTL          int    `json:"ttl"`
+			CreationPath string `json:"creation_path"`
+		} `json:"wrap_info"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &rewrapped)
+	if w.Code != http.StatusOK || rewrapped.WrapInfo.Token == original.Token || rewrapped.WrapInfo.TTL != 3600 || rewrapped.WrapInfo.CreationPath != "plugin/creds" {
+		t.Fatalf("rewrap: status = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	// The old token is spent and the new one unwraps the same response
+	if _, err := store.Lookup(ctx, original.Token); err != errInvalidWrappingToken {
+		t.Errorf("lookup of the rewrapped token error = %v, want %v", err, errInvalidWrappingToken)
+	}
+	if response, err := store.Unwrap(ctx, rewrapped.WrapInfo.Token); err != nil || response["data"] != "secret" {
+		t.Errorf("Unwrap = %v, %v", response, err)
+	}
+
+	w = httptest.NewRecorder()
+	store.HandleRewrap(w, httptest.NewRequest("POST", "/v1/sys/wrapping/rewrap", strings.NewReader(`{"token":"`+original.Token+`"}`)))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("rewrapping a spent token: status = %d, want 400", w.Code)
+	}
+}
+
+func TestMountWrapTTLs(t *testing.T) {
+	var wrapInfo *logical.RequestWrapInfo
+	backend := funcBackend(func(ctx context.Context, req *logical.Request) (*logical.Response, error) {
+		wrapInfo = req.WrapInfo
+		return &logical.Response{Data: map[string]interface{}{"password": "hunter2"}}, nil
+	})
+	handler := NewHandler(backend, newMockStorage(), hclog.NewNullLogger(), "plugin")
+	handler.SetWrapStore(NewWrapStore(newMockStorage()))
+	handler.SetWrapTTLs(2*time.Minute, 10*time.Minute)
+
+	request := func(wrapTTL string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/v1/plugin/creds", nil)
+		if wrapTTL != "" {
+			req.Header.Set(WrapTTLHeader, wrapTTL)
+		}
+		w := httptest.NewRecorder()
+		handler.HandleRequest(w, req)
+		return w
+	}
+	wrappedTTL := func(w *httptest.ResponseRecorder) int {
+		var wrapped struct {
+			WrapInfo struct {
+				TTL int `json:"ttl"`
+			} `json:"wrap_info"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &wrapped)
+		return wrapped.WrapInfo.TTL
+	}
+
+	// Responses are wrapped for the mount's TTL unless the request asks for another
+	if w := request(""); w.Code != http.StatusOK || wrappedTTL(w) != 120 || wrapInfo == nil || wrapInfo.TTL != 2*time.Minute {
+		t.Errorf("default wrap: status = %d, plugin saw %+v, body = %s", w.Code, wrapInfo, w.Body.String())
+	}
+	if w := request("5m"); w.Code != http.StatusOK || wrappedTTL(w) != 300 {
+		t.Errorf("requested wrap: status = %d, body = %s", w.Code, w.Body.String())
+	}
+	if w := request("1h"); w.Code != http.StatusBadRequest {
+		t.Errorf("wrap TTL above the max: status = %d, want 400", w.Code)
+	}
+
+	handler.SetWrapTTLs(0, 0)
+	if w := request(""); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "hunter2") {
+		t.Errorf("without a default wrap TTL: status = %d, body = %s", w.Code, w.Body.String())
+	}
+}
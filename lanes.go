@@ -0,0 +1,258 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"vault-plugin-host/handlers"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+// LaneHeader names the lane that served a request
+const LaneHeader = "X-Vault-Host-Lane"
+
+// requestLanes classifies requests into priority lanes, each with its own concurrency
+// budget, so a flood of one kind of request (say lease renewals in a load test) queues
+// in its lane instead of starving the rest. Requests that match no lane are not limited.
+type requestLanes struct {
+	lanes   []*lane
+	wait    time.Duration // how long a request queues for a slot before it gets 503
+	logger  hclog.Logger
+	statsMu sync.Mutex
+}
+
+// lane is one priority lane: the requests its patterns match and the slots they share
+type lane struct {
+	name     string
+	limit    int
+	patterns []lanePattern
+	slots    chan struct{}
+
+	// guarded by requestLanes.statsMu
+	waiting  int
+	served   uint64
+	rejected uint64
+	maxWait  time.Duration
+}
+
+// lanePattern matches requests by method (optional) and by the path after /v1/
+type lanePattern struct {
+	method string // empty matches every method
+	path   string // empty matches every path
+}
+
+// LaneStats reports a lane at /v1/sys/host/lanes
+type LaneStats struct {
+	Name     string   `json:"name"`
+	Limit    int      `json:"limit"`
+	Patterns []string `json:"patterns"`
+	InFlight int      `json:"in_flight"`
+	Waiting  int      `json:"waiting"`
+	Served   uint64   `json:"served"`
+	Rejected uint64   `json:"rejected"`
+	MaxWait  string   `json:"max_wait"`
+}
+
+// parseLanes parses -lane values of the form name:limit=pattern,pattern. A pattern is
+// a path below /v1/, matched as a path.Match glob or as a prefix when it ends in "/",
+// optionally preceded by a method and a space ("LIST kv/"); a method alone matches
+// every path. It returns nil when no lane is given.
+func parseLanes(specs []string, wait time.Duration, logger hclog.Logger) (*requestLanes, error) {
+	if len(specs) == 0 {
+		return nil, nil
+	}
+	lanes := &requestLanes{wait: wait, logger: logger}
+	names := make(map[string]bool)
+	for _, spec := range specs {
+		head, patterns, ok := strings.Cut(spec, "=")
+		name, limitText, hasLimit := strings.Cut(head, ":")
+		name = strings.TrimSpace(name)
+		if !ok || !hasLimit || name == "" {
+			return nil, fmt.Errorf("invalid lane %q: expected name:limit=pattern,...", spec)
+		}
+		limit, err := strconv.Atoi(strings.TrimSpace(limitText))
+		if err != nil || limit < 1 {
+			return nil, fmt.Errorf("invalid lane %q: the limit must be a positive number", spec)
+		}
+		if names[name] {
+			return nil, fmt.Errorf("lane %s is given twice", name)
+		}
+		names[name] = true
+
+		l := &lane{name: name, limit: limit, slots: make(chan struct{}, limit)}
+		for _, text := range strings.Split(patterns, ",") {
+			text = strings.TrimSpace(text)
+			if text == "" {
+				continue
+			}
+			var p lanePattern
+			if method, rest, ok := strings.Cut(text, " "); ok {
+				p = lanePattern{method: strings.ToUpper(method), path: strings.TrimSpace(rest)}
+			} else if strings.ToUpper(text) == text && !strings.ContainsAny(text, "/*") {
+				p = lanePattern{method: text}
+			} else {
+				p = lanePattern{path: text}
+			}
+			p.path = strings.TrimPrefix(p.path, "/v1/")
+			if _, err := path.Match(p.path, ""); err != nil {
+				return nil, fmt.Errorf("invalid lane %q: bad pattern %q", spec, text)
+			}
+			l.patterns = append(l.patterns, p)
+		}
+		if len(l.patterns) == 0 {
+			return nil, fmt.Errorf("invalid lane %q: no patterns", spec)
+		}
+		lanes.lanes = append(lanes.lanes, l)
+	}
+	return lanes, nil
+}
+
+// String is the pattern as given to -lane
+func (p lanePattern) String() string {
+	return strings.TrimSpace(p.method + " " + p.path)
+}
+
+// matches reports whether a request with method to the path after /v1/ is in the lane
+func (p lanePattern) matches(method, reqPath string) bool {
+	if p.method != "" && p.method != method {
+		return false
+	}
+	if p.path == "" {
+		return true
+	}
+	if strings.HasSuffix(p.path, "/") {
+		return strings.HasPrefix(reqPath, p.path)
+	}
+	matched, _ := path.Match(p.path, reqPath)
+	return matched
+}
+
+// laneFor returns the first lane whose patterns match r, or nil
+func (ls *requestLanes) laneFor(r *http.Request) *lane {
+	method := r.Method
+	if method == http.MethodGet && r.URL.Query().Get("list") == "true" {
+		method = "LIST"
+	}
+	reqPath := strings.TrimPrefix(r.URL.Path, "/v1/")
+	for _, l := range ls.lanes {
+		for _, p := range l.patterns {
+			if p.matches(method, reqPath) {
+				return l
+			}
+		}
+	}
+	return nil
+}
+
+// wrap queues each request for a slot of its lane. A request that waits longer than
+// the lane wait gets 503 with Retry-After, as Vault answers when it is overloaded.
+func (ls *requestLanes) wrap(next http.Handler) http.Handler {
+	if ls == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		l := ls.laneFor(r)
+		if l == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		start := time.Now()
+		select {
+		case l.slots <- struct{}{}:
+		default:
+			ls.statsMu.Lock()
+			l.waiting++
+			ls.statsMu.Unlock()
+
+			timer := time.NewTimer(ls.wait)
+			var acquired bool
+			select {
+			case l.slots <- struct{}{}:
+				acquired = true
+			case <-timer.C:
+			case <-r.Context().Done():
+			}
+			timer.Stop()
+
+			ls.statsMu.Lock()
+			l.waiting--
+			if !acquired {
+				l.rejected++
+			}
+			ls.statsMu.Unlock()
+
+			if !acquired {
+				if r.Context().Err() == nil {
+					ls.logger.Warn("request rejected by a full lane", "lane", l.name, "method", r.Method, "path", r.URL.Path)
+					w.Header().Set(LaneHeader, l.name)
+					w.Header().Set("Retry-After", "1")
+					handlers.WriteError(w, http.StatusServiceUnavailable, fmt.Sprintf("lane %s is full: %d requests in flight, none finished within %s", l.name, l.limit, ls.wait))
+				}
+				return
+			}
+		}
+		defer func() { <-l.slots }()
+
+		waited := time.Since(start)
+		ls.statsMu.Lock()
+		l.served++
+		if waited > l.maxWait {
+			l.maxWait = waited
+		}
+		ls.statsMu.Unlock()
+
+		w.Header().Set(LaneHeader, l.name)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Stats returns the state of every lane, in the order the lanes were given
+func (ls *requestLanes) Stats() []LaneStats {
+	ls.statsMu.Lock()
+	defer ls.statsMu.Unlock()
+
+	stats := make([]LaneStats, len(ls.lanes))
+	for i, l := range ls.lanes {
+		patterns := make([]string, len(l.patterns))
+		for j, p := range l.patterns {
+			patterns[j] = p.String()
+		}
+		stats[i] = LaneStats{
+			Name:     l.name,
+			Limit:    l.limit,
+			Patterns: patterns,
+			InFlight: len(l.slots),
+			Waiting:  l.waiting,
+			Served:   l.served,
+			Rejected: l.rejected,
+			MaxWait:  l.maxWait.String(),
+		}
+	}
+	return stats
+}
+
+// HandleStats serves GET /v1/sys/host/lanes
+func (ls *requestLanes) HandleStats(w http.ResponseWriter, r *http.Request) {
+	if ls == nil {
+		handlers.WriteError(w, http.StatusNotFound, "no request lanes configured")
+		return
+	}
+	if r.Method != http.MethodGet {
+		handlers.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	handlers.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"wait":  ls.wait.String(),
+		"lanes": ls.Stats(),
+	})
+}
@@ -0,0 +1,106 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+func TestParseLanes(t *testing.T) {
+	lanes, err := parseLanes([]string{"health:2=sys/health, */renew", "bulk:1=LIST,GET kv/export/"}, time.Second, hclog.NewNullLogger())
+	if err != nil {
+		t.Fatalf("parseLanes failed: %v", err)
+	}
+	for _, tc := range []struct {
+		method, target, lane string
+	}{
+		{"GET", "/v1/sys/health", "health"},
+		{"PUT", "/v1/kv/renew", "health"},
+		{"LIST", "/v1/kv/", "bulk"},
+		{"GET", "/v1/kv/roles?list=true", "bulk"},
+		{"GET", "/v1/kv/export/all", "bulk"},
+		{"PUT", "/v1/kv/export/all", ""},
+		{"GET", "/v1/kv/config", ""},
+	} {
+		l := lanes.laneFor(httptest.NewRequest(tc.method, tc.target, nil))
+		if (l == nil && tc.lane != "") || (l != nil && l.name != tc.lane) {
+			t.Errorf("%s %s: lane = %v, want %q", tc.method, tc.target, l, tc.lane)
+		}
+	}
+
+	if lanes, err := parseLanes(nil, time.Second, hclog.NewNullLogger()); lanes != nil || err != nil {
+		t.Errorf("parseLanes(nil) = %v, %v", lanes, err)
+	}
+	for _, spec := range []string{"health=sys/health", "health:0=sys/health", "health:x=sys/health", "health:1=", ":1=sys/health", "a:1=[x"} {
+		if _, err := parseLanes([]string{spec}, time.Second, hclog.NewNullLogger()); err == nil {
+			t.Errorf("parseLanes(%q) should fail", spec)
+		}
+	}
+	if _, err := parseLanes([]string{"a:1=x", "a:2=y"}, time.Second, hclog.NewNullLogger()); err == nil {
+		t.Error("parseLanes should refuse a lane given twice")
+	}
+}
+
+func TestRequestLanesWrap(t *testing.T) {
+	lanes, _ := parseLanes([]string{"renewals:1=sys/leases/renew"}, 50*time.Millisecond, hclog.NewNullLogger())
+
+	release := make(chan struct{})
+	started := make(chan struct{}, 1)
+	handler := lanes.wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/sys/leases/renew" {
+			started <- struct{}{}
+			<-release
+		}
+	}))
+
+	first := make(chan *httptest.ResponseRecorder)
+	go func() {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("PUT", "/v1/sys/leases/renew", nil))
+		first <- w
+	}()
+	<-started
+
+	// The lane is full: another renewal waits, then gets 503
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("PUT", "/v1/sys/leases/renew", nil))
+	if w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") == "" || w.Header().Get(LaneHeader) != "renewals" {
+		t.Errorf("second renewal: status %d, headers %v", w.Code, w.Header())
+	}
+
+	// Requests outside the lane are not held up
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/v1/kv/config", nil))
+	if w.Code != http.StatusOK || w.Header().Get(LaneHeader) != "" {
+		t.Errorf("unlaned request: status %d, headers %v", w.Code, w.Header())
+	}
+
+	// A renewal queued when the slot frees up is served
+	queued := make(chan *httptest.ResponseRecorder)
+	go func() {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("PUT", "/v1/sys/leases/renew", nil))
+		queued <- w
+	}()
+	time.Sleep(10 * time.Millisecond)
+	release <- struct{}{}
+	if w := <-first; w.Code != http.StatusOK {
+		t.Errorf("first renewal: status %d", w.Code)
+	}
+	<-started
+	release <- struct{}{}
+	if w := <-queued; w.Code != http.StatusOK {
+		t.Errorf("queued renewal: status %d", w.Code)
+	}
+
+	stats := lanes.Stats()
+	if len(stats) != 1 || stats[0].Served != 2 || stats[0].Rejected != 1 || stats[0].InFlight != 0 || stats[0].Waiting != 0 {
+		t.Errorf("stats = %+v", stats)
+	}
+}
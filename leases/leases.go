@@ -0,0 +1,164 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package leases tracks secret leases issued by the plugin host.
+package leases
+
+import (
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// DefaultShards is the number of shards used by NewManager
+const DefaultShards = 32
+
+// Lease stores lease information
+type Lease struct {
+	LeaseID     string                 `json:"lease_id"`
+	Path        string                 `json:"path"`
+	Data        map[string]interface{} `json:"data"`
+	Secret      *logical.Secret        `json:"secret"`
+	IssueTime   time.Time              `json:"issue_time"`
+	ExpireTime  time.Time              `json:"expire_time"`
+	LastRenewal time.Time              `json:"last_renewal"` // zero until the lease is first renewed
+	Renewals    int                    `json:"renewals"`
+	Duration    time.Duration          `json:"duration"`
+	Renewable   bool                   `json:"renewable"`
+	Writes      []string               `json:"writes,omitempty"` // storage keys written by the request that issued the lease
+}
+
+// shard is one independently locked slice of the lease map
+type shard struct {
+	mu     sync.RWMutex
+	leases map[string]*Lease
+}
+
+// Manager stores leases in independently locked shards so that concurrent
+// lease creation under load does not serialize on a single mutex
+type Manager struct {
+	shards []*shard
+}
+
+// NewManager creates a lease manager with DefaultShards shards
+func NewManager() *Manager {
+	return NewShardedManager(DefaultShards)
+}
+
+// NewShardedManager creates a lease manager with the given number of shards
+func NewShardedManager(n int) *Manager {
+	if n < 1 {
+		n = 1
+	}
+
+	m := &Manager{shards: make([]*shard, n)}
+	for i := range m.shards {
+		m.shards[i] = &shard{leases: make(map[string]*Lease)}
+	}
+	return m
+}
+
+// shardFor returns the shard responsible for a lease ID
+func (m *Manager) shardFor(leaseID string) *shard {
+	h := fnv.New32a()
+	h.Write([]byte(leaseID))
+	return m.shards[h.Sum32()%uint32(len(m.shards))]
+}
+
+// Put stores a lease, replacing any lease with the same ID
+func (m *Manager) Put(lease *Lease) {
+	s := m.shardFor(lease.LeaseID)
+	s.mu.Lock()
+	s.leases[lease.LeaseID] = lease
+	s.mu.Unlock()
+}
+
+// Get returns a copy of the lease with the given ID
+func (m *Manager) Get(leaseID string) (*Lease, bool) {
+	s := m.shardFor(leaseID)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	lease, ok := s.leases[leaseID]
+	if !ok {
+		return nil, false
+	}
+	copied := *lease
+	return &copied, true
+}
+
+// Update applies fn to the stored lease under the shard lock and returns a copy of the result
+func (m *Manager) Update(leaseID string, fn func(lease *Lease)) (*Lease, bool) {
+	s := m.shardFor(leaseID)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	lease, ok := s.leases[leaseID]
+	if !ok {
+		return nil, false
+	}
+	fn(lease)
+	copied := *lease
+	return &copied, true
+}
+
+// Take removes the lease with the given ID and returns it
+func (m *Manager) Take(leaseID string) (*Lease, bool) {
+	s := m.shardFor(leaseID)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	lease, ok := s.leases[leaseID]
+	if ok {
+		delete(s.leases, leaseID)
+	}
+	return lease, ok
+}
+
+// TakeExpired removes and returns every lease whose expiry is not after now
+func (m *Manager) TakeExpired(now time.Time) []*Lease {
+	var expired []*Lease
+	for _, s := range m.shards {
+		s.mu.Lock()
+		for id, lease := range s.leases {
+			if !lease.ExpireTime.After(now) {
+				expired = append(expired, lease)
+				delete(s.leases, id)
+			}
+		}
+		s.mu.Unlock()
+	}
+	return expired
+}
+
+// Len returns the number of stored leases
+func (m *Manager) Len() int {
+	n := 0
+	for _, s := range m.shards {
+		s.mu.RLock()
+		n += len(s.leases)
+		s.mu.RUnlock()
+	}
+	return n
+}
+
+// Range calls fn with a copy of every lease until fn returns false.
+// Shards are locked one at a time, so the view is not a point-in-time snapshot.
+func (m *Manager) Range(fn func(lease *Lease) bool) {
+	for _, s := range m.shards {
+		s.mu.RLock()
+		copies := make([]Lease, 0, len(s.leases))
+		for _, lease := range s.leases {
+			copies = append(copies, *lease)
+		}
+		s.mu.RUnlock()
+
+		for i := range copies {
+			if !fn(&copies[i]) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,154 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package leases
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestManagerPutGetTake(t *testing.T) {
+	m := NewManager()
+	m.Put(&Lease{LeaseID: "plugin/creds/a/1", Path: "creds/a"})
+
+	lease, ok := m.Get("plugin/creds/a/1")
+	if !ok || lease.Path != "creds/a" {
+		t.Fatalf("Get = %v, %v", lease, ok)
+	}
+
+	// Get returns a copy that does not alias the stored lease
+	lease.Path = "mutated"
+	if again, _ := m.Get("plugin/creds/a/1"); again.Path != "creds/a" {
+		t.Error("mutating a returned lease changed the stored lease")
+	}
+
+	if _, ok := m.Take("plugin/creds/a/1"); !ok {
+		t.Error("Take did not find the lease")
+	}
+	if _, ok := m.Get("plugin/creds/a/1"); ok {
+		t.Error("lease still present after Take")
+	}
+	if m.Len() != 0 {
+		t.Errorf("Len = %d, want 0", m.Len())
+	}
+}
+
+func TestManagerUpdate(t *testing.T) {
+	m := NewManager()
+	m.Put(&Lease{LeaseID: "id", Duration: time.Minute})
+
+	updated, ok := m.Update("id", func(l *Lease) { l.Duration = time.Hour })
+	if !ok || updated.Duration != time.Hour {
+		t.Errorf("Update = %v, %v", updated, ok)
+	}
+	if _, ok := m.Update("missing", func(l *Lease) {}); ok {
+		t.Error("Update of a missing lease should report false")
+	}
+}
+
+func TestManagerTakeExpired(t *testing.T) {
+	now := time.Now()
+	m := NewShardedManager(4)
+	m.Put(&Lease{LeaseID: "expired", ExpireTime: now.Add(-time.Minute)})
+	m.Put(&Lease{LeaseID: "due", ExpireTime: now})
+	m.Put(&Lease{LeaseID: "live", ExpireTime: now.Add(time.Minute)})
+
+	expired := m.TakeExpired(now)
+	if len(expired) != 2 {
+		t.Fatalf("TakeExpired returned %d leases, want 2", len(expired))
+	}
+	for _, lease := range expired {
+		if lease.LeaseID == "live" {
+			t.Error("TakeExpired returned a lease that has not expired")
+		}
+	}
+	if m.Len() != 1 {
+		t.Errorf("Len = %d, want 1", m.Len())
+	}
+	if _, ok := m.Get("live"); !ok {
+		t.Error("unexpired lease was removed")
+	}
+}
+
+func TestManagerRange(t *testing.T) {
+	m := NewShardedManager(4)
+	for i := 0; i < 100; i++ {
+		m.Put(&Lease{LeaseID: fmt.Sprintf("lease-%d", i)})
+	}
+
+	count := 0
+	m.Range(func(*Lease) bool {
+		count++
+		return true
+	})
+	if count != 100 {
+		t.Errorf("Range visited %d leases, want 100", count)
+	}
+
+	count = 0
+	m.Range(func(*Lease) bool {
+		count++
+		return count < 10
+	})
+	if count != 10 {
+		t.Errorf("Range did not stop early: visited %d", count)
+	}
+}
+
+func TestManagerConcurrentAccess(t *testing.T) {
+	m := NewManager()
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				id := fmt.Sprintf("lease-%d-%d", g, i)
+				m.Put(&Lease{LeaseID: id})
+				m.Update(id, func(l *Lease) { l.Renewable = true })
+				m.Get(id)
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	if m.Len() != 8000 {
+		t.Errorf("Len = %d, want 8000", m.Len())
+	}
+}
+
+func benchmarkPut(b *testing.B, shards int) {
+	m := NewShardedManager(shards)
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			m.Put(&Lease{LeaseID: fmt.Sprintf("plugin/creds/%p/%d", pb, i)})
+			i++
+		}
+	})
+}
+
+func BenchmarkPutSingleShard(b *testing.B) { benchmarkPut(b, 1) }
+func BenchmarkPutSharded(b *testing.B)     { benchmarkPut(b, DefaultShards) }
+
+func BenchmarkGetSharded(b *testing.B) {
+	m := NewManager()
+	ids := make([]string, 10000)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("plugin/creds/test/%d", i)
+		m.Put(&Lease{LeaseID: ids[i]})
+	}
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			m.Get(ids[i%len(ids)])
+			i++
+		}
+	})
+}
@@ -0,0 +1,193 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package leases
+
+import (
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Reasons a lease ends
+const (
+	EndRevoked = "revoked"
+	EndExpired = "expired"
+)
+
+// DurationBuckets are the upper bounds of the TTL and lifetime histograms; longer
+// durations fall in a final unbounded bucket
+var DurationBuckets = []time.Duration{
+	time.Minute,
+	5 * time.Minute,
+	15 * time.Minute,
+	time.Hour,
+	4 * time.Hour,
+	12 * time.Hour,
+	24 * time.Hour,
+	7 * 24 * time.Hour,
+}
+
+// RenewalBuckets are the upper bounds of the renewals-per-lease histogram
+var RenewalBuckets = []int{0, 1, 2, 5, 10}
+
+// Bucket is one histogram bucket; Le is its inclusive upper bound, or "+Inf"
+type Bucket struct {
+	Le    string `json:"le"`
+	Count int    `json:"count"`
+}
+
+// DurationSummary describes a set of durations
+type DurationSummary struct {
+	Count     int      `json:"count"`
+	Min       string   `json:"min,omitempty"`
+	Max       string   `json:"max,omitempty"`
+	Mean      string   `json:"mean,omitempty"`
+	Histogram []Bucket `json:"histogram"`
+}
+
+// Analytics summarizes the leases seen during a session
+type Analytics struct {
+	Issued   int            `json:"issued"`
+	Renewals int            `json:"renewals"`
+	Ended    map[string]int `json:"ended"`
+	// TTL covers the TTL each lease was issued with, Lifetime how long ended leases lived
+	TTL      DurationSummary `json:"ttl"`
+	Lifetime DurationSummary `json:"lifetime"`
+	// RenewalsPerLease counts ended leases by how often they were renewed
+	RenewalsPerLease []Bucket `json:"renewals_per_lease"`
+}
+
+// durations accumulates a duration histogram
+type durations struct {
+	count    int
+	sum      time.Duration
+	min, max time.Duration
+	buckets  []int
+}
+
+func newDurations() durations {
+	return durations{buckets: make([]int, len(DurationBuckets)+1)}
+}
+
+func (d *durations) add(v time.Duration) {
+	if d.count == 0 || v < d.min {
+		d.min = v
+	}
+	if v > d.max {
+		d.max = v
+	}
+	d.count++
+	d.sum += v
+
+	i := 0
+	for i < len(DurationBuckets) && v > DurationBuckets[i] {
+		i++
+	}
+	d.buckets[i]++
+}
+
+func (d *durations) summary() DurationSummary {
+	s := DurationSummary{Count: d.count, Histogram: make([]Bucket, len(d.buckets))}
+	for i, count := range d.buckets {
+		le := "+Inf"
+		if i < len(DurationBuckets) {
+			le = DurationBuckets[i].String()
+		}
+		s.Histogram[i] = Bucket{Le: le, Count: count}
+	}
+	if d.count > 0 {
+		s.Min = d.min.String()
+		s.Max = d.max.String()
+		s.Mean = (d.sum / time.Duration(d.count)).Round(time.Millisecond).String()
+	}
+	return s
+}
+
+// Stats records lease TTLs, renewals and lifetimes for analytics
+type Stats struct {
+	mu       sync.Mutex
+	issued   int
+	renewals int
+	ended    map[string]int
+	ttl      durations
+	lifetime durations
+	renewed  []int
+}
+
+// NewStats creates empty lease statistics
+func NewStats() *Stats {
+	s := &Stats{}
+	s.Reset()
+	return s
+}
+
+// Reset discards all recorded statistics
+func (s *Stats) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.issued = 0
+	s.renewals = 0
+	s.ended = make(map[string]int)
+	s.ttl = newDurations()
+	s.lifetime = newDurations()
+	s.renewed = make([]int, len(RenewalBuckets)+1)
+}
+
+// Issued records a newly issued lease
+func (s *Stats) Issued(lease *Lease) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.issued++
+	s.ttl.add(lease.Duration)
+}
+
+// Renewed records a lease renewal
+func (s *Stats) Renewed() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.renewals++
+}
+
+// Ended records a lease that was revoked or expired at the given time
+func (s *Stats) Ended(lease *Lease, reason string, at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.ended[reason]++
+	s.lifetime.add(at.Sub(lease.IssueTime))
+
+	i := 0
+	for i < len(RenewalBuckets) && lease.Renewals > RenewalBuckets[i] {
+		i++
+	}
+	s.renewed[i]++
+}
+
+// Analytics returns a summary of the recorded statistics
+func (s *Stats) Analytics() Analytics {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a := Analytics{
+		Issued:           s.issued,
+		Renewals:         s.renewals,
+		Ended:            make(map[string]int, len(s.ended)),
+		TTL:              s.ttl.summary(),
+		Lifetime:         s.lifetime.summary(),
+		RenewalsPerLease: make([]Bucket, len(s.renewed)),
+	}
+	for reason, count := range s.ended {
+		a.Ended[reason] = count
+	}
+	for i, count := range s.renewed {
+		le := "+Inf"
+		if i < len(RenewalBuckets) {
+			le = strconv.Itoa(RenewalBuckets[i])
+		}
+		a.RenewalsPerLease[i] = Bucket{Le: le, Count: count}
+	}
+	return a
+}
@@ -0,0 +1,64 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package leases
+
+import (
+	"testing"
+	"time"
+)
+
+func bucketCount(buckets []Bucket, le string) int {
+	for _, b := range buckets {
+		if b.Le == le {
+			return b.Count
+		}
+	}
+	return -1
+}
+
+func TestStats(t *testing.T) {
+	s := NewStats()
+	issued := time.Now()
+
+	short := &Lease{LeaseID: "short", Duration: 30 * time.Second, IssueTime: issued}
+	long := &Lease{LeaseID: "long", Duration: 24 * time.Hour, IssueTime: issued}
+	huge := &Lease{LeaseID: "huge", Duration: 30 * 24 * time.Hour, IssueTime: issued}
+	for _, lease := range []*Lease{short, long, huge} {
+		s.Issued(lease)
+	}
+
+	long.Renewals = 3
+	s.Renewed()
+	s.Renewed()
+	s.Renewed()
+	s.Ended(short, EndExpired, issued.Add(30*time.Second))
+	s.Ended(long, EndRevoked, issued.Add(2*time.Hour))
+
+	a := s.Analytics()
+	if a.Issued != 3 || a.Renewals != 3 {
+		t.Errorf("issued = %d, renewals = %d, want 3 and 3", a.Issued, a.Renewals)
+	}
+	if a.Ended[EndExpired] != 1 || a.Ended[EndRevoked] != 1 {
+		t.Errorf("ended = %v", a.Ended)
+	}
+
+	if a.TTL.Count != 3 || a.TTL.Min != "30s" || a.TTL.Max != "720h0m0s" {
+		t.Errorf("unexpected TTL summary %+v", a.TTL)
+	}
+	if bucketCount(a.TTL.Histogram, "1m0s") != 1 || bucketCount(a.TTL.Histogram, "24h0m0s") != 1 || bucketCount(a.TTL.Histogram, "+Inf") != 1 {
+		t.Errorf("unexpected TTL histogram %+v", a.TTL.Histogram)
+	}
+
+	if a.Lifetime.Count != 2 || bucketCount(a.Lifetime.Histogram, "4h0m0s") != 1 {
+		t.Errorf("unexpected lifetime summary %+v", a.Lifetime)
+	}
+	if bucketCount(a.RenewalsPerLease, "0") != 1 || bucketCount(a.RenewalsPerLease, "5") != 1 {
+		t.Errorf("unexpected renewals histogram %+v", a.RenewalsPerLease)
+	}
+
+	s.Reset()
+	if a := s.Analytics(); a.Issued != 0 || a.TTL.Count != 0 || a.TTL.Min != "" {
+		t.Errorf("Reset left statistics behind: %+v", a)
+	}
+}
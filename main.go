@@ -5,41 +5,318 @@ package main
 
 import (
 	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
 	"embed"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"io/fs"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strings"
+	"sync"
 	"syscall"
+	"time"
+
+	"vault-plugin-host/egress"
+	"vault-plugin-host/federation"
+	"vault-plugin-host/handlers"
+	"vault-plugin-host/mockcloud"
+	"vault-plugin-host/mockdb"
+	"vault-plugin-host/mockidp"
+	"vault-plugin-host/mockldap"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/go-plugin"
+	bolt "go.etcd.io/bbolt"
+	"golang.org/x/net/netutil"
 )
 
 //go:embed web
 var webFS embed.FS
 
+// exportPassphraseEnv supplies -export-passphrase without putting it on the command line
+const exportPassphraseEnv = "VAULT_PLUGIN_HOST_EXPORT_PASSPHRASE"
+
 var (
-	pluginPath   = flag.String("plugin", "", "Path to plugin binary")
-	port         = flag.String("port", "8300", "HTTP server port")
-	mount        = flag.String("mount", "plugin", "Mount path for the plugin (under /v1/)")
-	verbose      = flag.Bool("v", false, "Enable verbose logging")
-	attach       = flag.Bool("attach", false, "Enable attach mode (reads plugin attach string from stdin or prompts)")
-	pluginConfig = flag.String("config", "", "Plugin configuration options in JSON format or key=value pairs separated by commas")
+	pluginPaths    = repeatedFlag("plugin", "Path to plugin binary; repeat together with -mount to host several plugins")
+	port           = flag.String("port", "8300", "HTTP server port")
+	mountPaths     = repeatedFlag("mount", "Mount path (under /v1/) for the -plugin in the same position (default \"plugin\")")
+	configFile     = flag.String("config-file", "", "HCL, JSON or YAML file of host settings (listener, storage, system view, audit) and mount blocks; flags on the command line take precedence")
+	mountsFile     = flag.String("mounts", "", "JSON file of additional mounts, mapping each path to {\"plugin\": ..., \"config\": ..., \"system_view\": ..., \"sha256\": ...}")
+	pluginDir      = flag.String("plugin-dir", "", "Directory of plugin binaries that POST /v1/sys/mounts may launch, in addition to the plugins given at startup")
+	verbose        = flag.Bool("v", false, "Enable verbose logging")
+	attach         = flag.Bool("attach", false, "Enable attach mode (reads plugin attach string from stdin or prompts)")
+	pluginConfig   = flag.String("config", "", "Plugin configuration options in JSON format or key=value pairs separated by commas")
+	mountOptions   = flag.String("mount-options", "", "Options sys/mounts reports for the -plugin mount, in JSON format or key=value pairs separated by commas (version=2 marks a KV v2 plugin)")
+	genSmoke       = flag.String("gen-smoke", "", "Print a smoke test of every plugin path, derived from its OpenAPI document, as a shell script ('sh') or Ansible tasks ('ansible') and exit")
+	clientHeaders  = flag.String("fingerprint-headers", "", "Comma-separated request headers that, with User-Agent and remote address, tell clients apart in /v1/sys/host/clients (e.g. X-Test-Run)")
+	robustness     = flag.String("robustness", "", "Exercise every templated plugin path with odd parameter values (percent-encoding, unicode, very long segments, path traversal), print a report ('text' or 'json') of panics, server errors and inconsistent routing and exit")
+	rpcCall        = flag.String("rpc", "", "Invoke a low-level backend RPC (services, special-paths, type, version), print the result as JSON and exit")
+	pluginGRPC     = flag.String("plugin-grpc", "", "Publish the -plugin mount's gRPC services on this host:port without TLS, for direct clients such as grpcurl; calls need the root token when -token is set")
+	pluginEnvSpecs = repeatedFlag("plugin-env", "Set a variable in the environment of plugin processes, as KEY=VALUE, or pass on the host's value of KEY; repeatable")
+	pluginArgs     = flag.String("plugin-args", "", "Arguments plugin processes are started with, split into words as a shell would (quotes and backslashes, no expansion)")
+	pluginUser     = flag.String("plugin-user", "", "Run plugins as this user (name or uid, optionally :group); the host must run as root (Linux only)")
+	pluginWorkDir  = flag.String("plugin-workdir", "", "Working directory of plugin processes, instead of the host's")
+	pluginClearEnv = flag.Bool("plugin-clear-env", false, "Start plugins with only the variables the host sets for them, instead of the host's environment")
+	pluginAppArmor = flag.String("plugin-apparmor", "", "Confine plugins by this loaded AppArmor profile (Linux only)")
+	pluginSeccomp  = flag.Bool("plugin-seccomp", false, "Deny plugins syscalls they have no business making, such as ptrace, mount and module loading (Linux only)")
+	pluginSHA256   = flag.String("plugin-sha256", "", "Hex SHA256 the -plugin binary must have, as registered in Vault's plugin catalog; checked before every launch")
+	pluginSums     = flag.String("plugin-sha256sums", "", "Checksums file (sha256sum format) that must list the SHA256 of every plugin binary before it is launched")
+	cosignKey      = flag.String("plugin-cosign-key", "", "Cosign public key that must have signed every plugin binary, in <binary>.sig (cosign sign-blob --key)")
+	cosignIdentity = flag.String("plugin-cosign-identity", "", "Certificate identity of the keyless cosign signature every plugin binary must carry in <binary>.bundle; needs the cosign CLI")
+	cosignIssuer   = flag.String("plugin-cosign-issuer", "", "OIDC issuer of -plugin-cosign-identity, such as https://token.actions.githubusercontent.com")
+	downloadURL    = flag.String("plugin-download-url", "", "URL template ({{name}}, {{version}}, {{os}}, {{arch}}) of the artifacts of plugins registered with download=true, extracted into -plugin-dir")
+	downloadSums   = flag.String("plugin-download-sums", "", "URL template of the SHA256SUMS file each -plugin-download-url artifact must be listed in")
+	downloadOCI    = flag.String("plugin-download-registry", "", "OCI repository prefix plugins registered with download=true are pulled from, as <prefix>/<name>:<version>")
+	pluginLogLines = flag.Int("plugin-log-lines", defaultPluginLogLines, "How many lines of plugin stdout and stderr to keep for /v1/sys/plugin/logs")
+	pluginPprof    = flag.String("plugin-pprof", "", "Proxy the plugin's pprof endpoints: 'auto' passes a free address via VAULT_PLUGIN_PPROF_ADDR, or give the host:port the plugin already serves pprof on")
+	hangThreshold  = flag.Duration("hang-threshold", 0, "Capture a goroutine dump (SIGQUIT) from the plugin when a backend call runs longer than this (0 disables the watchdog)")
+	hangRestart    = flag.Bool("hang-restart", false, "Restart the plugin after capturing a hang dump")
+	watchPlugin    = flag.Bool("watch", false, "Reload the plugin whenever its binary changes on disk")
+	autoRestart    = flag.Bool("auto-restart", true, "Restart the plugin with exponential backoff when its process dies")
+	multiplex      = flag.Bool("multiplex", false, "Serve every mount of the same plugin binary from one process when the plugin supports multiplexing, as Vault does")
+	periodicEvery  = flag.Duration("periodic-interval", defaultPeriodicInterval, "How often to send the rollback request that runs the plugin's PeriodicFunc (0 disables it)")
+	requestTimeout = flag.Duration("request-timeout", 0, "Deadline for plugin requests; expired requests return 504 with timing diagnostics (0 disables)")
+	breakerAfter   = flag.Int("breaker-threshold", 0, "Fail requests to a path fast with 503 after this many consecutive backend errors on it (0 disables the circuit breaker)")
+	breakerCool    = flag.Duration("breaker-cooldown", 30*time.Second, "How long a circuit breaker stays open before a request to its path is let through again")
+	eventReplay    = flag.Int("event-replay", 1024, "Number of recent plugin events kept for subscribers that ask for events sent before they connected")
+	persistEvents  = flag.Bool("persist-events", false, "Keep every plugin event in storage, queryable at /v1/sys/host/events/log")
+	recordExamples = flag.Int("record-examples", 0, "Record up to N request/response pairs per path as OpenAPI examples (0 disables recording)")
+	readTimeout    = flag.Duration("read-timeout", 0, "HTTP server read timeout (0 means no timeout)")
+	writeTimeout   = flag.Duration("write-timeout", 0, "HTTP server write timeout (0 means no timeout)")
+	idleTimeout    = flag.Duration("idle-timeout", 0, "How long idle keep-alive connections are kept open (0 uses the read timeout)")
+	drainTimeout   = flag.Duration("shutdown-timeout", 30*time.Second, "How long to wait on SIGINT/SIGTERM for in-flight requests to finish before stopping the plugins")
+	maxHeaderBytes = flag.Int("max-header-bytes", http.DefaultMaxHeaderBytes, "Maximum size of request headers in bytes")
+	maxConns       = flag.Int("max-conns", 0, "Maximum number of simultaneous client connections (0 means unlimited)")
+	laneWait       = flag.Duration("lane-wait", 30*time.Second, "How long a request waits for a slot in its full -lane before it gets 503")
+	rootToken      = flag.String("token", "", "Require this token in X-Vault-Token on plugin requests; 'auto' generates a root token and prints it at startup")
+	allowIPs       = flag.String("allow-ips", "", "Comma-separated IP addresses and CIDR ranges allowed to reach the host; requests from other addresses get 403")
+	localOnly      = flag.Bool("local-only", false, "Listen on 127.0.0.1 only and reject requests that do not come from a loopback address")
+	tlsCert        = flag.String("tls-cert", "", "PEM certificate for serving HTTPS (requires -tls-key)")
+	tlsKey         = flag.String("tls-key", "", "PEM private key for -tls-cert")
+	tlsClientCA    = flag.String("tls-client-ca", "", "PEM CA bundle; when set, clients must present a certificate signed by it (mutual TLS)")
+	artifactsDir   = flag.String("artifacts-dir", "", "Directory passed to the plugin via VAULT_PLUGIN_ARTIFACTS_DIR for debug files (default: a temporary directory removed on exit)")
+	mockIdP        = flag.Bool("mock-idp", false, "Serve a mock OAuth2/OIDC identity provider under /mock-idp/")
+	mockIdPClaims  = flag.String("mock-idp-claims", "", "Extra claims (JSON object) added to every token issued by the mock identity provider")
+	mockLDAP       = flag.String("mock-ldap", "", "Serve a mock LDAP directory on this address (e.g. 127.0.0.1:3890)")
+	mockLDAPData   = flag.String("mock-ldap-fixtures", "", "JSON file with users, groups and entries for the mock LDAP directory")
+	mockDB         = flag.String("mock-db", "", "Serve a mock PostgreSQL database on this address for database secrets engine testing (e.g. 127.0.0.1:5432)")
+	mockDBData     = flag.String("mock-db-fixtures", "", "JSON file with the initial roles of the mock database")
+	mockCloud      = flag.Bool("mock-cloud", false, "Serve mock AWS STS/instance metadata and GCP metadata endpoints")
+	mockCloudData  = flag.String("mock-cloud-config", "", "JSON file with identities and canned responses for the mock cloud endpoints")
+	egressMode     = flag.String("egress", "", "Route plugin HTTP(S) traffic through a recording proxy: 'record' (forward and record) or 'replay' (answer from the cassette only)")
+	egressCassette = flag.String("egress-cassette", "", "Cassette file replayed by the egress proxy; in record mode newly recorded traffic is saved to it on exit")
+	egressPolicy   = flag.String("egress-policy", "", "JSON file of allow/deny rules for plugin outbound destinations; violations are blocked and reported (implies -egress=record when -egress is not set)")
+	storageType    = flag.String("storage", "inmem", "Storage backend for plugin data: 'inmem', 'file' (a file per entry), 'bbolt' (an embedded database) or 'vault' (a KV mount of a Vault server)")
+	storagePath    = flag.String("storage-path", "", "Directory for -storage=file or -storage=bbolt")
+	vaultStoreAddr = flag.String("storage-vault-addr", "", "Address of the Vault server for -storage=vault (defaults to VAULT_ADDR)")
+	vaultStoreTok  = flag.String("storage-vault-token", "", "Token for -storage=vault (defaults to VAULT_TOKEN)")
+	vaultStorePath = flag.String("storage-vault-path", "secret/vault-plugin-host", "KV path below which -storage=vault keeps the plugin's storage entries")
+	postStart      = flag.String("post-start-check", "", "Once the plugin is initialized and the host serves it, run this command (given VAULT_ADDR, VAULT_TOKEN and "+postStartMountEnv+") or GET this /v1/ path with the root token; the host exits with status 1 if it fails")
+	postStartWait  = flag.Duration("post-start-timeout", time.Minute, "How long the -post-start-check may take before it counts as failed")
+	waitFor        = flag.String("wait-for", "", "Comma-separated dependencies of the plugin to wait for before launching it: tcp://host:port, or an http:// or https:// URL answering below 400")
+	waitTimeout    = flag.Duration("wait-for-timeout", 2*time.Minute, "How long to wait for the -wait-for dependencies before giving up")
+	storageView    = flag.Bool("storage-view", false, "Store the data of all mounts in one storage, each mount below a logical/<uuid>/ prefix as in Vault, so plugins that use relative keys or assume they own the storage root fail as they would there")
+	seedStorage    = flag.String("seed-storage", "", "Load a storage snapshot (from /v1/sys/storage/snapshot) into plugin storage before the plugin starts")
+	exportPass     = flag.String("export-passphrase", "", "Encrypt storage snapshot and artifact downloads with this passphrase, and decrypt encrypted snapshots given to /v1/sys/storage/restore and -seed-storage (default: $"+exportPassphraseEnv+")")
+	decryptFile    = flag.String("decrypt", "", "Decrypt a snapshot or artifact encrypted with -export-passphrase to stdout and exit")
+	scaffold       = flag.String("scaffold", "", "Write a starter scenario suite for a plugin archetype ('kv', 'dynamic-creds' or 'pki-like') to -scaffold-dir and exit")
+	scaffoldDir    = flag.String("scaffold-dir", "scenarios", "Directory -scaffold writes the scenario suite to")
+	replState      = flag.String("replication-state", "", "Simulated replication state: perf-primary, perf-secondary, dr-primary, dr-secondary or perf-standby (comma-separated to combine); secondaries and standbys reject writes")
+	clockSkew      = flag.Duration("clock-skew", 0, "Skew the timestamps reported to the plugin (lease and token issue times, wrapping creation times) by this duration, e.g. -5m, to simulate clock drift between Vault nodes")
+	defaultTTL     = flag.Duration("default-lease-ttl", 30*time.Second, "Default lease TTL the system view reports to the plugin, as for a tuned mount")
+	maxTTL         = flag.Duration("max-lease-ttl", 60*time.Minute, "Max lease TTL the system view reports to the plugin")
+	mlock          = flag.Bool("mlock", false, "Report mlock as enabled to the plugin")
+	localMount     = flag.Bool("local-mount", false, "Report the mount as local (not replicated) to the plugin")
+	clusterID      = flag.String("cluster-id", "test-cluster", "Cluster ID reported to the plugin")
+	flavorName     = flag.String("flavor", "vault", "Server whose plugin contract the host follows: vault, or openbao for plugins built against the OpenBao SDK (BAO_ handshake variables, OpenBao version)")
+	vaultVersion   = flag.String("vault-version", "test-version", "Vault version string reported to the plugin")
+	passHeaders    = flag.String("passthrough-request-headers", "", "Comma-separated request headers passed to the plugin in the request's Headers, like a mount's passthrough_request_headers")
+	allowHeaders   = flag.String("allowed-response-headers", "", "Comma-separated headers of plugin responses sent on to the client, like a mount's allowed_response_headers")
+	wrapTTL        = flag.Duration("response-wrap-ttl", 0, "Wrap every plugin response for this TTL unless the request sets X-Vault-Wrap-TTL")
+	maxWrapTTL     = flag.Duration("max-response-wrap-ttl", 0, "Reject plugin requests asking for a longer X-Vault-Wrap-TTL than this")
+	tidyInterval   = flag.Duration("tidy-interval", time.Minute, "How often expired wrapped responses, their cubbyholes and expired tokens are removed in the background (0 disables it; POST /v1/sys/host/tidy still runs a pass)")
+	replPrimary    = flag.String("replication-primary", "", "Primary address that writes rejected on a secondary are redirected to")
+	canonicalJSON  = flag.Bool("canonical-json", false, "Write JSON responses in canonical form (sorted keys, compact, stable number formatting) for diff-based tests")
+	opHeaders      = flag.Bool("operation-headers", false, "Report the logical operation, backend duration and storage call count of plugin responses in X-Vault-Host-* headers")
+	auditPath      = flag.String("audit-path", "", "Write an audit log of plugin requests and responses in Vault's audit JSON format to this file, or to stdout with 'stdout'")
+	auditHMAC      = flag.Bool("audit-hmac", false, "HMAC tokens and string values in the audit log, as Vault does unless log_raw is set")
+	auditSpecs     = repeatedFlag("audit-device", "Enable an audit device, as type:key=value,... with type file (path), http (url, header, timeout) or syslog (facility, tag, network, address), each also taking name and hmac; repeatable")
+	pipeline       = flag.Bool("pipeline", false, "Read newline-delimited JSON requests from stdin and write JSON responses to stdout instead of serving HTTP")
+	pipelineVarsIn = repeatedFlag("var", "Set a pipeline or scenario variable referenced as {{ name }} in request lines, as name=value; repeatable")
+	pipelineMatrix = flag.String("matrix", "", "Run the pipeline once per row of this CSV (with a header row) or JSON table, setting the row's columns as variables")
+	peers          = repeatedFlag("peer", "Register another host instance as a federation peer, as name=address or address; repeatable")
+	mirrors        = repeatedFlag("mirror", "Mirror every request to one mount asynchronously to another and record divergences, as from=to; repeatable")
+	laneSpecs      = repeatedFlag("lane", "Give the requests matching some paths a priority lane with its own concurrency budget, as name:limit=pattern,... where a pattern is a path glob, a prefix ending in /, or either preceded by a method (\"LIST kv/\"); repeatable")
+	recordPath     = flag.String("record", "", "Append every plugin request and its response to this newline-delimited JSON file, for -replay")
+	replayPath     = flag.String("replay", "", "Send the requests of a -record file to the plugins, print the responses that differ from the recorded ones as JSON lines and exit")
+	replayIgnore   = flag.String("replay-ignore", "", "Comma-separated dotted response fields, such as data.password, that -replay does not compare")
 
 	attachString *string
+
+	// egressEnv routes plugins launched for additional mounts through the egress proxy
+	egressEnv []string
+
+	// activityLog counts requests and clients across all mounts for sys/internal/counters
+	activityLog = handlers.NewActivityLog()
+
+	// eventBus carries the events plugins of all mounts send to /v1/sys/events/subscribe
+	eventBus = handlers.NewEventBus()
+
+	// clientFingerprints attributes the requests of all mounts to the clients that sent them
+	clientFingerprints *handlers.ClientFingerprints
+
+	// wrapStore holds wrapped responses of all mounts, like Vault's cubbyholes
+	wrapStore = handlers.NewWrapStore(NewInMemoryStorage())
+
+	// passwordPolicies holds the password policies plugins of all mounts generate from
+	passwordPolicies = handlers.NewPasswordPolicies(NewInMemoryStorage())
+
+	// replication is the simulated replication state of all mounts
+	replication = handlers.NewReplication()
+
+	// clock skews the timestamps all mounts report to their plugins
+	clock = handlers.NewClock(0)
+
+	// storageFaults injects latency and errors into the storage of all mounts' plugins
+	storageFaults = handlers.NewStorageFaults()
+
+	// sharedStorage holds the data of all mounts below their prefixes with -storage-view
+	sharedStorage Storage
+
+	// boltDB is the database of -storage=bbolt, holding a bucket per storage
+	boltDB *bolt.DB
+
+	// serverFlavor is the server, Vault or OpenBao, all mounts launch their plugins as
+	serverFlavor = hostFlavors["vault"]
+
+	// sandbox restricts the processes of all mounts' plugins (nil for none)
+	sandbox *pluginSandbox
+
+	// verifier checks every plugin binary before it is launched (nil for none)
+	verifier *pluginVerifier
+
+	// downloader fetches plugins registered with download=true into -plugin-dir (nil
+	// for none)
+	downloader *pluginDownloader
+
+	// pluginEnv and pluginArgv are the -plugin-env variables and -plugin-args words
+	// every plugin process is started with
+	pluginEnv, pluginArgv []string
+
+	// systemViewConfig is the mount tuning reported to plugins, from flags; additional
+	// mounts can override it with a "system_view" object
+	systemViewConfig = DefaultSystemViewConfig()
+
+	// artifactsPath is the artifact directory; additional mounts get a subdirectory of it
+	artifactsPath string
+
+	// auditBroker records the requests and responses of all mounts with the audit devices
+	// of -audit-path and -audit-device
+	auditBroker *handlers.AuditBroker
+
+	// tokenStore issues tokens for plugin logins on all mounts and checks them when -token is set
+	tokenStore *handlers.TokenStore
 )
 
 func main() {
+	// The launcher of a sandboxed plugin takes no host flags
+	if len(os.Args) > 1 && os.Args[1] == sandboxExecArg {
+		os.Exit(runSandboxExec(os.Args[2:]))
+	}
+
 	flag.Parse()
+	var scenarioFiles []string
+	if flag.Arg(0) == "test" {
+		if scenarioFiles = subcommandArgs(); len(scenarioFiles) == 0 {
+			log.Fatalf("Usage: vault-plugin-host [flags] test <scenario file>... [flags]")
+		}
+	}
+	var configMounts map[string]map[string]interface{}
+	if *configFile != "" {
+		config, err := loadHostConfig(*configFile)
+		if err != nil {
+			log.Fatalf("Failed to load config file: %v", err)
+		}
+		if configMounts, err = config.apply(flag.CommandLine); err != nil {
+			log.Fatalf("Failed to apply config file %s: %v", *configFile, err)
+		}
+	}
+	if *exportPass == "" {
+		*exportPass = os.Getenv(exportPassphraseEnv)
+	}
+	if *decryptFile != "" {
+		os.Exit(runDecryptCommand(*decryptFile, *exportPass))
+	}
+	if *scaffold != "" {
+		os.Exit(runScaffoldCommand(*scaffold, *scaffoldDir))
+	}
+	if flag.Arg(0) == "probe" {
+		target := subcommandArgs()
+		if len(target) == 0 && len(*pluginPaths) > 0 {
+			target = (*pluginPaths)[:1]
+		}
+		if len(target) != 1 {
+			log.Fatalf("Usage: vault-plugin-host [flags] probe <plugin binary> [flags]")
+		}
+		flavor, err := parseHostFlavor(*flavorName)
+		if err != nil {
+			log.Fatalf("Invalid -flavor: %v", err)
+		}
+		os.Exit(runProbeCommand(target[0], flavor))
+	}
 
 	var absPath string
 	var err error
 
+	// In pipeline mode stdout carries responses only, so everything else goes to stderr.
+	// The same goes for a generated smoke test, so it can be redirected to a file, and
+	// for the results of the test subcommand.
+	console := io.Writer(os.Stdout)
+	if *pipeline && *attach {
+		log.Fatalf("-pipeline cannot be combined with -attach since both read from stdin")
+	}
+	if *watchPlugin && *attach {
+		log.Fatalf("-watch cannot be combined with -attach since the host does not launch the plugin")
+	}
+	if *pipelineMatrix != "" && !*pipeline {
+		log.Fatalf("-matrix requires -pipeline")
+	}
+	if *pipeline && *auditPath == handlers.AuditStdout {
+		log.Fatalf("-audit-path=stdout cannot be combined with -pipeline since stdout carries the responses")
+	}
+	if *pipeline && (*recordPath != "" || *replayPath != "") {
+		log.Fatalf("-record and -replay cannot be combined with -pipeline since pipeline requests bypass the router")
+	}
+	if *recordPath != "" && *recordPath == *replayPath {
+		log.Fatalf("-record and -replay cannot use the same file")
+	}
+	if len(scenarioFiles) > 0 && (*pipeline || *recordPath != "" || *replayPath != "") {
+		log.Fatalf("test cannot be combined with -pipeline, -record or -replay")
+	}
+	if *pipeline || *genSmoke != "" || *robustness != "" || *replayPath != "" || len(scenarioFiles) > 0 {
+		console = os.Stderr
+		logOutput = os.Stderr
+	}
+
+	specs, err := resolveMounts(*pluginPaths, *mountPaths, *attach)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	primary := specs[0]
+
 	// Check if -attach flag was provided
 	if *attach {
-		fmt.Print("Enter plugin attach string (format: 1|4|unix|/path/to/socket|grpc|): ")
+		fmt.Fprint(console, "Enter plugin attach string (format: 1|4|unix|/path/to/socket|grpc|): ")
 
 		reader := bufio.NewReader(os.Stdin)
 
@@ -50,16 +327,10 @@ func main() {
 		}
 		if value != "" {
 			attachString = &value
-			fmt.Printf("Using attach config: %s\n", value)
+			fmt.Fprintf(console, "Using attach config: %s\n", value)
 		}
 	} else {
-		// Determine plugin path
-		path := *pluginPath
-		if path == "" {
-			log.Fatalf("Plugin path required when not in attach mode. Use -plugin flag to specify the plugin binary.")
-		}
-
-		absPath, err = filepath.Abs(path)
+		absPath, err = filepath.Abs(primary.plugin)
 		if err != nil {
 			log.Fatalf("Failed to resolve plugin path: %v", err)
 		}
@@ -80,38 +351,342 @@ func main() {
 	}
 
 	if len(config) > 0 {
-		fmt.Printf("Plugin config: %v\n", config)
+		fmt.Fprintf(console, "Plugin config: %v\n", config)
 	}
 
-	fmt.Printf("Plugin: %s\n", absPath)
-	fmt.Printf("Starting HTTP server on port %s...\n\n", *port)
+	fmt.Fprintf(console, "Plugin: %s\n", absPath)
+	if !*pipeline {
+		fmt.Fprintf(console, "Starting HTTP server on port %s...\n\n", *port)
+	}
 
-	host, err := NewPluginHost(absPath, *verbose, config, *mount)
+	host, err := NewPluginHost(absPath, *verbose, config, primary.path)
 	if err != nil {
 		log.Fatalf("Failed to create plugin host: %v", err)
 	}
+	options, err := parsePluginConfig(*mountOptions)
+	if err != nil {
+		log.Fatalf("Failed to parse mount options: %v", err)
+	}
+	host.handler.SetMountOptions(options)
+
+	if attachString != nil {
+		host.attach = *attachString
+	}
+	if *pluginSHA256 != "" {
+		if *attach {
+			log.Fatalf("-plugin-sha256 cannot be combined with -attach, since an attached plugin is not launched by the host")
+		}
+		digest, err := parseSHA256Digest(*pluginSHA256)
+		if err != nil {
+			log.Fatalf("Invalid -plugin-sha256: %v", err)
+		}
+		host.SetSHA256(digest)
+	}
+	host.pprofAddr = *pluginPprof
+
+	// The event log is kept beside the plugin data, so it survives restarts with it
+	eventLog := handlers.NewEventLog(NewInMemoryStorage())
+	switch *storageType {
+	case "inmem":
+	case "file", "bbolt":
+		if *storagePath == "" {
+			log.Fatalf("-storage-path is required with -storage=%s", *storageType)
+		}
+		storage, location, err := openPersistentStorage("")
+		if err != nil {
+			log.Fatalf("Failed to open %s storage: %v", *storageType, err)
+		}
+		host.SetStorage(storage)
+		fmt.Fprintf(console, "Storage: %s\n", location)
+
+		// Wrapped responses stay valid across restarts along with the plugin data
+		cubbyhole, _, err := openPersistentStorage("cubbyhole")
+		if err != nil {
+			log.Fatalf("Failed to open %s storage: %v", *storageType, err)
+		}
+		wrapStore = handlers.NewWrapStore(cubbyhole)
+
+		policies, _, err := openPersistentStorage("password_policies")
+		if err != nil {
+			log.Fatalf("Failed to open %s storage: %v", *storageType, err)
+		}
+		passwordPolicies = handlers.NewPasswordPolicies(policies)
+
+		if *persistEvents {
+			events, _, err := openPersistentStorage("events")
+			if err != nil {
+				log.Fatalf("Failed to open %s storage: %v", *storageType, err)
+			}
+			eventLog = handlers.NewEventLog(events)
+		}
+	case "vault":
+		// Only the plugin's data lives in Vault; the host's own state stays in memory
+		addr, token := *vaultStoreAddr, *vaultStoreTok
+		if addr == "" {
+			addr = os.Getenv("VAULT_ADDR")
+		}
+		if token == "" {
+			token = os.Getenv("VAULT_TOKEN")
+		}
+		storage, err := NewVaultStorage(addr, token, *vaultStorePath)
+		if err != nil {
+			log.Fatalf("Failed to open vault storage: %v", err)
+		}
+		host.SetStorage(storage)
+		fmt.Fprintf(console, "Storage: %s\n", storage.Path())
+	default:
+		log.Fatalf("Unknown storage backend %q (expected inmem, file, bbolt or vault)", *storageType)
+	}
+	if *storageView {
+		sharedStorage = host.storage
+		host.SetStorageView(sharedStorage)
+		fmt.Fprintf(console, "Storage view: logical/%s/\n", host.backendUUID)
+	}
+	if *seedStorage != "" {
+		n, err := seedHostStorage(host, *seedStorage, *exportPass)
+		if err != nil {
+			log.Fatalf("Failed to seed storage: %v", err)
+		}
+		fmt.Fprintf(console, "Seeded storage with %d entries from %s\n", n, *seedStorage)
+	}
+
+	artifacts, removeArtifacts, err := openArtifactDir(*artifactsDir)
+	if err != nil {
+		log.Fatalf("Failed to prepare artifact directory: %v", err)
+	}
+	defer removeArtifacts()
+	artifacts.SetExportPassphrase(*exportPass)
+	artifactsPath = artifacts.Path()
+	host.artifactsDir = artifactsPath
+	fmt.Fprintf(console, "Plugin artifacts: %s\n", artifacts.Path())
+	proxy, finishEgress, err := startEgressProxy(*egressMode, *egressCassette, *egressPolicy, host.logger.Named("egress"))
+	if err != nil {
+		log.Fatalf("Failed to start egress proxy: %v", err)
+	}
+	defer finishEgress()
+	if proxy != nil {
+		fmt.Fprintf(console, "Egress proxy: http://%s (%s mode, traffic at /v1/sys/host/egress)\n", proxy.Addr(), proxy.Mode())
+	}
+	systemViewConfig = SystemViewConfig{
+		DefaultLeaseTTL: *defaultTTL,
+		MaxLeaseTTL:     *maxTTL,
+		Mlock:           *mlock,
+		LocalMount:      *localMount,
+		ClusterID:       *clusterID,
+		VaultVersion:    *vaultVersion,
+
+		PassthroughRequestHeaders: splitList(*passHeaders),
+		AllowedResponseHeaders:    splitList(*allowHeaders),
+		ResponseWrapTTL:           *wrapTTL,
+		MaxResponseWrapTTL:        *maxWrapTTL,
+	}
+	if err := systemViewConfig.Validate(); err != nil {
+		log.Fatalf("Invalid system view settings: %v", err)
+	}
+	eventBus.SetReplayLimit(*eventReplay)
+	if *persistEvents {
+		if err := eventBus.SetEventLog(eventLog); err != nil {
+			log.Fatalf("Failed to open event log: %v", err)
+		}
+	}
+	clientFingerprints = handlers.NewClientFingerprints(strings.Split(*clientHeaders, ","))
+
+	if *auditPath != "" || len(*auditSpecs) > 0 {
+		auditBroker = handlers.NewAuditBroker()
+		defer auditBroker.Close()
+		if *auditPath != "" {
+			device, err := handlers.NewAuditDevice(*auditPath, *auditHMAC)
+			if err != nil {
+				log.Fatalf("Failed to open audit device: %v", err)
+			}
+			auditBroker.Enable(device)
+			fmt.Fprintf(console, "Audit log: %s\n", *auditPath)
+		}
+		for _, spec := range *auditSpecs {
+			device, err := handlers.ParseAuditDevice(spec)
+			if err != nil {
+				log.Fatalf("Failed to open audit device: %v", err)
+			}
+			if err := auditBroker.Enable(device); err != nil {
+				device.Close()
+				log.Fatalf("Failed to enable audit device: %v", err)
+			}
+			if *pipeline && device.WritesStdout() {
+				log.Fatalf("An audit device writing to stdout cannot be combined with -pipeline since stdout carries the responses")
+			}
+			fmt.Fprintf(console, "Audit device: %s/ (%s)\n", device.Name(), device.Type())
+		}
+	}
+
+	state, err := handlers.ParseReplicationState(*replState)
+	if err == nil {
+		err = replication.Set(state, *replPrimary)
+	}
+	if err != nil {
+		log.Fatalf("Invalid replication settings: %v", err)
+	}
+	if replication.ReadOnly() {
+		fmt.Fprintf(console, "Replication: %s (plugin writes are rejected)\n", strings.Join(state.StateStrings(), ", "))
+	}
+
+	clock.SetSkew(*clockSkew)
+	if *clockSkew != 0 {
+		fmt.Fprintf(console, "Clock skew: %s (timestamps reported to the plugin are shifted)\n", *clockSkew)
+	}
+
+	if serverFlavor, err = parseHostFlavor(*flavorName); err != nil {
+		log.Fatalf("Invalid -flavor: %v", err)
+	}
+	if serverFlavor.name != "vault" {
+		fmt.Fprintf(console, "Flavor: %s %s\n", serverFlavor.name, serverFlavor.version)
+	}
+
+	sandbox, err = newPluginSandbox(*pluginUser, *pluginWorkDir, *pluginClearEnv, *pluginAppArmor, *pluginSeccomp)
+	if err != nil {
+		log.Fatalf("Invalid plugin sandbox: %v", err)
+	}
+	if sandbox != nil {
+		fmt.Fprintf(console, "Plugin sandbox: %s\n", sandbox)
+	}
+	verifier, err = newPluginVerifier(*pluginSums, *cosignKey, *cosignIdentity, *cosignIssuer)
+	if err != nil {
+		log.Fatalf("Invalid plugin verification: %v", err)
+	}
+	if verifier != nil {
+		fmt.Fprintf(console, "Plugin verification: %s\n", verifier)
+	}
+	downloader, err = newPluginDownloader(*downloadURL, *downloadSums, *downloadOCI, *pluginDir)
+	if err != nil {
+		log.Fatalf("Invalid plugin downloads: %v", err)
+	}
+	if downloader != nil {
+		fmt.Fprintf(console, "Plugin downloads: %s\n", downloader)
+	}
+	if pluginEnv, err = parsePluginEnv(*pluginEnvSpecs); err != nil {
+		log.Fatal(err)
+	}
+	if pluginArgv, err = splitPluginArgs(*pluginArgs); err != nil {
+		log.Fatal(err)
+	}
+	if len(pluginArgv) > 0 {
+		fmt.Fprintf(console, "Plugin arguments: %q\n", pluginArgv)
+	}
 
+	if *recordExamples > 0 {
+		fmt.Fprintf(console, "Recording up to %d OpenAPI examples per path\n", *recordExamples)
+	}
+	if *breakerAfter > 0 {
+		fmt.Fprintf(console, "Circuit breaker: open after %d consecutive failures, cool-down %s\n", *breakerAfter, *breakerCool)
+	}
+
+	if *waitFor != "" {
+		deps, err := parseDependencies(*waitFor)
+		if err != nil {
+			log.Fatalf("Invalid -wait-for: %v", err)
+		}
+		fmt.Fprintf(console, "Waiting for %d dependencies (up to %s)\n", len(deps), *waitTimeout)
+		if err := waitForDependencies(deps, *waitTimeout, dependencyPollInterval, console); err != nil {
+			log.Fatalf("Failed to start plugin: %v", err)
+		}
+	}
+
+	configureMount(host, systemViewConfig)
 	if err := host.Start(); err != nil {
 		log.Fatalf("Failed to start plugin: %v", err)
 	}
 	defer host.Stop()
+	if *autoRestart && attachString == nil {
+		defer superviseHost(host)()
+	}
 
-	// Setup signal handling for graceful shutdown
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
-	go func() {
-		<-sigChan
-		fmt.Println("\nReceived interrupt signal, shutting down...")
-		host.Stop()
-		os.Exit(0)
-	}()
+	if *rpcCall != "" {
+		code := runRPCCommand(host, *rpcCall)
+		finishEgress()
+		removeArtifacts()
+		os.Exit(code)
+	}
+
+	if *genSmoke != "" {
+		code := runSmokeCommand(host, *genSmoke)
+		finishEgress()
+		removeArtifacts()
+		os.Exit(code)
+	}
+
+	if *robustness != "" {
+		code := runRobustnessCommand(host, *robustness)
+		finishEgress()
+		removeArtifacts()
+		os.Exit(code)
+	}
+
+	if len(scenarioFiles) > 0 {
+		code := runTestCommand(host, scenarioFiles, *pipelineVarsIn)
+		finishEgress()
+		removeArtifacts()
+		os.Exit(code)
+	}
+
+	if *pipeline {
+		vars, err := parsePipelineVars(*pipelineVarsIn)
+		if err != nil {
+			host.Stop()
+			log.Fatalf("Pipeline failed: %v", err)
+		}
+		if *pipelineMatrix != "" {
+			rows, err := loadPipelineMatrix(*pipelineMatrix)
+			if err != nil {
+				host.Stop()
+				log.Fatalf("Pipeline failed: %v", err)
+			}
+			summary, err := runPipelineMatrix(host.handler, host.mountPath, vars, rows, os.Stdin, os.Stdout)
+			if err != nil {
+				host.Stop()
+				log.Fatalf("Pipeline failed: %v", err)
+			}
+			fmt.Fprintf(console, "Matrix: %d of %d rows passed\n", summary.Passed, summary.Rows)
+			if len(summary.Failed) > 0 {
+				host.Stop()
+				finishEgress()
+				removeArtifacts()
+				os.Exit(1)
+			}
+			return
+		}
+		if err := runPipeline(host.handler, host.mountPath, vars, os.Stdin, os.Stdout); err != nil {
+			host.Stop()
+			log.Fatalf("Pipeline failed: %v", err)
+		}
+		return
+	}
+
+	if *rootToken == "" || *rootToken == "auto" {
+		tokenStore = handlers.NewTokenStore("")
+	} else {
+		tokenStore = handlers.NewTokenStore(*rootToken)
+	}
+	host.handler.SetTokenStore(tokenStore)
+	if *rootToken != "" {
+		tokenStore.Enforce(true)
+		fmt.Fprintf(console, "Root token: %s\n", tokenStore.RootToken())
+	}
+
+	var grpcProxy *handlers.GRPCProxy
+	if *pluginGRPC != "" {
+		grpcProxy = handlers.NewGRPCProxy(host.handler, tokenStore)
+		if err := grpcProxy.Start(*pluginGRPC); err != nil {
+			host.Stop()
+			log.Fatalf("Failed to publish the plugin's gRPC services: %v", err)
+		}
+		fmt.Fprintf(console, "Plugin gRPC services: %s\n", grpcProxy.Addr())
+	}
 
 	// CORS middleware
 	corsMiddleware := func(next http.HandlerFunc) http.HandlerFunc {
 		return func(w http.ResponseWriter, r *http.Request) {
 			w.Header().Set("Access-Control-Allow-Origin", "*")
 			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Vault-Token, X-Vault-Wrap-TTL")
 
 			// Handle preflight requests
 			if r.Method == "OPTIONS" {
@@ -123,40 +698,846 @@ func main() {
 		}
 	}
 
-	// Setup HTTP handlers with CORS
-	mountPath := "/v1/" + *mount + "/"
-	http.HandleFunc(mountPath, corsMiddleware(host.handler.HandleRequest))
-	http.HandleFunc("/v1/sys/health", corsMiddleware(host.handler.HandleHealth))
-	http.HandleFunc("/v1/sys/storage", corsMiddleware(host.handler.HandleStorage))
-	http.HandleFunc("/v1/sys/leases/renew", corsMiddleware(host.handler.HandleLeaseRenew))
-	http.HandleFunc("/v1/sys/leases/revoke", corsMiddleware(host.handler.HandleLeaseRevoke))
-	http.HandleFunc("/v1/sys/leases/revoke/", corsMiddleware(host.handler.HandleLeaseRevokeByPath))
-	http.HandleFunc("/v1/sys/plugins/catalog/openapi", corsMiddleware(func(w http.ResponseWriter, r *http.Request) {
+	if *watchPlugin {
+		stopWatch, err := watchPluginBinary(host)
+		if err != nil {
+			log.Fatalf("Failed to watch plugin binary: %v", err)
+		}
+		defer stopWatch()
+		fmt.Fprintf(console, "Watching %s for changes\n", host.pluginPath)
+	}
+
+	var watchdog *Watchdog
+	if *hangThreshold > 0 {
+		watchdog = NewWatchdog(host, *hangThreshold, *hangRestart)
+		stopWatchdog := make(chan struct{})
+		defer close(stopWatchdog)
+		go watchdog.Run(stopWatchdog)
+		fmt.Fprintf(console, "Hang watchdog enabled (threshold %s, restart %v)\n", *hangThreshold, *hangRestart)
+	}
+
+	// Revoke leases in the background once they expire
+	stopExpiration := make(chan struct{})
+	defer close(stopExpiration)
+	go host.handler.RunExpiration(handlers.ExpirationCheckInterval, stopExpiration)
+
+	// Remove expired wrapped responses and tokens that nothing looks up again
+	tidier := handlers.NewTidier(wrapStore, tokenStore, host.logger.Named("tidy"))
+	if *tidyInterval > 0 {
+		stopTidy := make(chan struct{})
+		defer close(stopTidy)
+		go tidier.Run(*tidyInterval, stopTidy)
+	}
+
+	// Setup HTTP routes; plugin mounts are resolved by the router so they can change at runtime
+	router := handlers.NewRouter()
+	if err := router.Mount(primary.path, host.handler, nil); err != nil {
+		log.Fatalf("Failed to mount plugin: %v", err)
+	}
+	// Additional mounts from repeated -plugin/-mount flags and the -mounts file
+	extraPaths := make([]string, 0, len(specs)-1)
+	extraOptions := make(map[string]map[string]interface{})
+	for _, spec := range specs[1:] {
+		extraPaths = append(extraPaths, spec.path)
+		extraOptions[spec.path] = map[string]interface{}{"plugin": spec.plugin}
+		for key, value := range configMounts[spec.path] {
+			extraOptions[spec.path][key] = value
+		}
+	}
+	if *mountsFile != "" {
+		paths, options, err := loadMountsFile(*mountsFile)
+		if err != nil {
+			log.Fatalf("Failed to load mounts file: %v", err)
+		}
+		for _, path := range paths {
+			extraPaths = append(extraPaths, path)
+			extraOptions[path] = options[path]
+		}
+	}
+	for _, path := range extraPaths {
+		handler, cleanup, err := newMountedPlugin(path, extraOptions[path], *verbose)
+		if err != nil {
+			log.Fatalf("Failed to start plugin for mount %s/: %v", path, err)
+		}
+		if err := router.Mount(path, handler, cleanup); err != nil {
+			cleanup()
+			log.Fatalf("Failed to mount plugin: %v", err)
+		}
+		fmt.Fprintf(console, "Mounted %v at /v1/%s/\n", extraOptions[path]["plugin"], strings.Trim(path, "/"))
+	}
+
+	// Mounts created at runtime may only launch binaries from the plugin catalog
+	catalogPlugins := []string{primary.plugin}
+	for _, path := range extraPaths {
+		if plugin, ok := extraOptions[path]["plugin"].(string); ok {
+			catalogPlugins = append(catalogPlugins, plugin)
+		}
+	}
+	catalog, err := newPluginCatalog(catalogPlugins, *pluginDir)
+	if err != nil {
+		log.Fatalf("Failed to build plugin catalog: %v", err)
+	}
+	router.SetMountFactory(func(path string, options map[string]interface{}) (*handlers.Handler, func(), error) {
+		binary, _ := options["plugin"].(string)
+		resolved, err := catalog.resolve(binary)
+		if err != nil {
+			return nil, nil, err
+		}
+		mountOptions := map[string]interface{}{"plugin": resolved}
+		for key, value := range options {
+			if key != "plugin" {
+				mountOptions[key] = value
+			}
+		}
+		return newMountedPlugin(path, mountOptions, *verbose)
+	})
+
+	router.HandleFunc("/v1/sys/health", host.handler.HandleHealth)
+	router.HandleFunc("/v1/sys/storage", host.handler.HandleStorage)
+	router.HandleFunc("/v1/sys/storage/snapshot", tokenStore.RequireRoot(host.handler.HandleSnapshot))
+	router.HandleFunc("/v1/sys/storage/restore", tokenStore.RequireRoot(host.handler.HandleRestore))
+	router.HandleFunc("/v1/sys/storage/raw/", tokenStore.RequireRoot(forMount(router, host.handler, (*handlers.Handler).HandleRawStorage)))
+	router.HandleFunc("/v1/sys/test/rollback", tokenStore.RequireRoot(host.handler.HandleRollback))
+	router.HandleFunc("/v1/sys/test/storage-faults", tokenStore.RequireRoot(storageFaults.HandleConfig))
+	router.HandleFunc("/v1/sys/mounts", tokenStore.RequireRoot(router.HandleMounts))
+	router.HandleFunc("/v1/sys/mounts/", tokenStore.RequireRoot(router.HandleMounts))
+	router.HandleFunc("/v1/sys/internal/ui/mounts/", router.HandleUIMounts)
+	router.HandleFunc("/v1/sys/seal-status", handlers.SealStatusHandler(serverFlavor.version))
+	router.HandleFunc("/v1/sys/replication/status", replication.HandleStatus)
+	if auditBroker != nil {
+		router.HandleFunc("/v1/sys/audit-hash/", auditBroker.HandleHash)
+		router.HandleFunc("/v1/sys/audit", tokenStore.RequireRoot(auditBroker.HandleList))
+	}
+	router.HandleFunc("/v1/sys/host/replication", tokenStore.RequireRoot(replication.HandleConfig))
+	router.HandleFunc("/v1/sys/test/replication-state", tokenStore.RequireRoot(replication.HandleConfig))
+	router.HandleFunc("/v1/sys/host/clock", tokenStore.RequireRoot(clock.HandleConfig))
+	router.HandleFunc("/v1/sys/leases/lookup", forLease(router, host.handler, (*handlers.Handler).HandleLeaseLookup))
+	router.HandleFunc("/v1/sys/leases/lookup/", forLease(router, host.handler, (*handlers.Handler).HandleLeaseLookup))
+	router.HandleFunc("/v1/sys/leases/renew", forLease(router, host.handler, (*handlers.Handler).HandleLeaseRenew))
+	router.HandleFunc("/v1/sys/host/leases/analytics", host.handler.HandleLeaseAnalytics)
+	router.HandleFunc("/v1/sys/host/leases/leaks", host.handler.HandleStorageLeaks)
+	router.HandleFunc("/v1/sys/host/checkpoints/", handlers.NewCheckpoints(host.handler).HandleCheckpoints)
+	router.HandleFunc("/v1/sys/internal/counters/requests", activityLog.HandleRequests)
+	router.HandleFunc("/v1/sys/host/clients", clientFingerprints.HandleClients)
+	router.HandleFunc("/v1/sys/internal/counters/activity", activityLog.HandleActivity)
+	router.HandleFunc("/v1/sys/internal/counters/activity/monthly", activityLog.HandleActivityMonthly)
+	router.HandleFunc("/v1/sys/internal/counters/config", activityLog.HandleConfig)
+	router.HandleFunc("/v1/sys/leases/revoke", forLease(router, host.handler, (*handlers.Handler).HandleLeaseRevoke))
+	router.HandleFunc("/v1/sys/leases/revoke/", forLease(router, host.handler, (*handlers.Handler).HandleLeaseRevokeByPath))
+	router.HandleFunc("/v1/sys/host/rpc", host.handler.HandleRPC)
+	router.HandleFunc("/v1/sys/host/rpc/", host.handler.HandleRPC)
+	router.HandleFunc("/v1/sys/host/plugin/pprof/", forMount(router, host.handler, (*handlers.Handler).HandlePluginPprof))
+	router.HandleFunc("/v1/sys/host/backend", forMount(router, host.handler, (*handlers.Handler).HandleBackendInfo))
+	router.HandleFunc("/v1/sys/plugin/connection", tokenStore.RequireRoot(forMount(router, host.handler, (*handlers.Handler).HandlePluginConnection)))
+	router.HandleFunc("/v1/sys/plugin/logs", tokenStore.RequireRoot(forMount(router, host.handler, (*handlers.Handler).HandlePluginLogs)))
+	router.HandleFunc("/v1/sys/host/artifacts", artifacts.HandleArtifacts)
+	router.HandleFunc("/v1/sys/host/artifacts/", artifacts.HandleArtifacts)
+
+	if *mockIdP {
+		provider, err := mockidp.New("http://localhost:" + *port + "/mock-idp")
+		if err != nil {
+			log.Fatalf("Failed to start mock identity provider: %v", err)
+		}
+		if *mockIdPClaims != "" {
+			var claims map[string]interface{}
+			if err := json.Unmarshal([]byte(*mockIdPClaims), &claims); err != nil {
+				log.Fatalf("Failed to parse -mock-idp-claims: %v", err)
+			}
+			provider.SetClaims(claims)
+		}
+		router.Handle("/mock-idp/", http.StripPrefix("/mock-idp", provider))
+		fmt.Fprintf(console, "Mock identity provider: %s/.well-known/openid-configuration\n", provider.Issuer())
+	}
+
+	if *mockLDAP != "" {
+		var fixtures *mockldap.Fixtures
+		if *mockLDAPData != "" {
+			if fixtures, err = mockldap.LoadFixtures(*mockLDAPData); err != nil {
+				log.Fatalf("Failed to load mock LDAP fixtures: %v", err)
+			}
+		}
+		directory, err := mockldap.NewDirectory(fixtures)
+		if err != nil {
+			log.Fatalf("Failed to build mock LDAP directory: %v", err)
+		}
+		ldapAddr, err := mockldap.NewServer(directory, host.logger.Named("mock-ldap")).ListenAndServe(*mockLDAP)
+		if err != nil {
+			log.Fatalf("Failed to start mock LDAP server: %v", err)
+		}
+		fmt.Fprintf(console, "Mock LDAP server: ldap://%s\n", ldapAddr)
+	}
+
+	if *mockCloud {
+		var config *mockcloud.Config
+		if *mockCloudData != "" {
+			if config, err = mockcloud.LoadConfig(*mockCloudData); err != nil {
+				log.Fatalf("Failed to load mock cloud config: %v", err)
+			}
+		}
+		cloud, err := mockcloud.New(config)
+		if err != nil {
+			log.Fatalf("Failed to create mock cloud endpoints: %v", err)
+		}
+		for _, path := range mockcloud.Paths {
+			router.Handle(path, cloud)
+		}
+		base := "http://localhost:" + *port
+		fmt.Fprintf(console, "Mock AWS STS: %s/mock-cloud/sts, IMDS: %s (AWS_EC2_METADATA_SERVICE_ENDPOINT)\n", base, base)
+		fmt.Fprintf(console, "Mock GCP metadata: localhost:%s (GCE_METADATA_HOST)\n", *port)
+	}
+
+	if *mockDB != "" {
+		var fixtures *mockdb.Fixtures
+		if *mockDBData != "" {
+			if fixtures, err = mockdb.LoadFixtures(*mockDBData); err != nil {
+				log.Fatalf("Failed to load mock database fixtures: %v", err)
+			}
+		}
+		engine := mockdb.NewEngine(fixtures)
+		dbAddr, err := mockdb.NewServer(engine, host.logger.Named("mock-db")).ListenAndServe(*mockDB)
+		if err != nil {
+			log.Fatalf("Failed to start mock database: %v", err)
+		}
+		router.HandleFunc("/v1/sys/host/mock-db", func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet {
+				handlers.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+				return
+			}
+			handlers.WriteJSON(w, http.StatusOK, map[string]interface{}{
+				"roles":      engine.Roles(),
+				"statements": engine.Statements(),
+			})
+		})
+		fmt.Fprintf(console, "Mock database: postgres://%s (inspect at /v1/sys/host/mock-db)\n", dbAddr)
+	}
+	router.HandleFunc("/v1/sys/host/examples", forMount(router, host.handler, (*handlers.Handler).HandleExamples))
+	router.HandleFunc("/v1/sys/host/breakers", forMount(router, host.handler, (*handlers.Handler).HandleCircuitBreakers))
+	router.HandleFunc("/v1/sys/host/normalize", handlers.HandleNormalize)
+	router.HandleFunc("/v1/sys/host/snippets", forMount(router, host.handler, (*handlers.Handler).HandleSnippets))
+	router.HandleFunc("/v1/sys/wrapping/unwrap", wrapStore.HandleUnwrap)
+	router.HandleFunc("/v1/sys/wrapping/lookup", wrapStore.HandleLookup)
+	router.HandleFunc("/v1/sys/wrapping/rewrap", wrapStore.HandleRewrap)
+	router.HandleFunc("/v1/sys/events/subscribe/", eventBus.HandleSubscribe)
+	router.HandleFunc("/v1/sys/host/events", eventBus.HandleReplay)
+	router.HandleFunc("/v1/sys/host/events/log", eventBus.HandleLog)
+	router.HandleFunc("/v1/sys/policies/password", passwordPolicies.HandlePolicies)
+	router.HandleFunc("/v1/sys/policies/password/", tokenStore.RequireRootToWrite(passwordPolicies.HandlePolicies))
+	router.HandleFunc("/v1/auth/token/create", tokenStore.HandleCreate)
+	router.HandleFunc("/v1/auth/token/lookup-self", tokenStore.HandleLookupSelf)
+	router.HandleFunc("/v1/auth/token/renew-self", tokenStore.HandleRenewSelf)
+	router.HandleFunc("/v1/auth/token/revoke-self", tokenStore.HandleRevokeSelf)
+	router.HandleFunc("/v1/auth/token/lookup-accessor", tokenStore.RequireRoot(tokenStore.HandleLookupAccessor))
+	router.HandleFunc("/v1/auth/token/revoke-accessor", tokenStore.RequireRoot(tokenStore.HandleRevokeAccessor))
+	router.HandleFunc("/v1/auth/token/accessors", tokenStore.RequireRoot(tokenStore.HandleListAccessors))
+	router.HandleFunc("/v1/auth/token/accessors/", tokenStore.RequireRoot(tokenStore.HandleListAccessors))
+	router.HandleFunc("/v1/sys/host/storage", host.storage.HandleStats)
+	router.HandleFunc("/v1/sys/host/tidy", tokenStore.RequireRoot(tidier.HandleTidy))
+	if proxy != nil {
+		router.HandleFunc("/v1/sys/host/egress", proxy.HandleInteractions)
+	}
+	router.HandleFunc("/v1/sys/plugins/catalog/", tokenStore.RequireRoot(downloader.HandleCatalog))
+	router.HandleFunc("/v1/sys/plugins/catalog/openapi", func(w http.ResponseWriter, r *http.Request) {
 		host.handler.HandleOpenAPI(w, r, host.GetOpenAPIDoc())
-	}))
+	})
+	if watchdog != nil {
+		router.HandleFunc("/v1/sys/host/hangs", watchdog.HandleHangs)
+	}
+
+	// Requests in a lane queue for its slots; the rest are not limited
+	lanes, err := parseLanes(*laneSpecs, *laneWait, host.logger.Named("lanes"))
+	if err != nil {
+		log.Fatalf("Invalid -lane: %v", err)
+	}
+	router.HandleFunc("/v1/sys/host/lanes", lanes.HandleStats)
+	if lanes != nil {
+		for _, l := range lanes.Stats() {
+			fmt.Fprintf(console, "Request lane %s: %d at a time for %s\n", l.Name, l.Limit, strings.Join(l.Patterns, ", "))
+		}
+	}
+
+	// Other host instances whose mounts, health and requests this one can combine
+	fed := federation.New(router)
+	for _, spec := range *peers {
+		name, address, err := federation.ParsePeer(spec)
+		if err == nil {
+			err = fed.Register(name, address, "")
+		}
+		if err != nil {
+			log.Fatalf("Invalid -peer %q: %v", spec, err)
+		}
+		fmt.Fprintf(console, "Federation peer %s: %s\n", name, address)
+	}
+	router.Handle(federation.PathPrefix+"/", fed)
+
+	// Mounts whose requests are replayed against a second mount, such as a candidate build
+	for _, spec := range *mirrors {
+		from, to, found := strings.Cut(spec, "=")
+		if !found {
+			log.Fatalf("Invalid -mirror %q: expected from=to", spec)
+		}
+		if _, err := router.AddMirror(from, to); err != nil {
+			log.Fatalf("Invalid -mirror %q: %v", spec, err)
+		}
+		fmt.Fprintf(console, "Mirroring requests to %s/ onto %s/\n", strings.Trim(from, "/"), strings.Trim(to, "/"))
+	}
+	router.HandleFunc("/v1/sys/host/mirrors", router.HandleMirrors)
+	router.HandleFunc("/v1/sys/host/mirrors/", router.HandleMirrors)
 
 	// Serve embedded web UI
 	webContentFS, err := fs.Sub(webFS, "web")
 	if err == nil {
-		http.Handle("/ui/", http.StripPrefix("/ui/", http.FileServer(http.FS(webContentFS))))
+		router.Handle("/ui/", http.StripPrefix("/ui/", http.FileServer(http.FS(webContentFS))))
 	}
 
 	// Root handler with usage info
-	http.HandleFunc("/", corsMiddleware(func(w http.ResponseWriter, r *http.Request) {
+	router.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		if r.URL.Path == "/" {
 			w.Header().Set("Content-Type", "text/plain")
 			fmt.Fprint(w, host.GetUsageInfo(*port))
-		} else {
-			host.handler.HandleRequest(w, r)
+			return
 		}
-	}))
+		handlers.WriteError(w, http.StatusNotFound, fmt.Sprintf("no handler for route %q", r.URL.Path))
+	})
 
-	addr := ":" + *port
+	if *recordPath != "" {
+		recorder, err := handlers.NewRequestRecorder(*recordPath)
+		if err != nil {
+			log.Fatalf("Failed to start recording: %v", err)
+		}
+		defer recorder.Close()
+		router.SetRecorder(recorder)
+		fmt.Fprintf(console, "Recording plugin requests to %s\n", *recordPath)
+	}
+
+	if *replayPath != "" {
+		code := runReplayCommand(router, *replayPath, splitList(*replayIgnore), console)
+		for _, path := range router.Mounts() {
+			router.Unmount(path)
+		}
+		host.Stop()
+		finishEgress()
+		removeArtifacts()
+		os.Exit(code)
+	}
+
+	tlsConfig, err := loadServerTLS(*tlsCert, *tlsKey, *tlsClientCA)
+	if err != nil {
+		log.Fatalf("Failed to configure TLS: %v", err)
+	}
+	scheme := "http"
+	if tlsConfig != nil {
+		scheme = "https"
+	}
+
+	sources, err := newSourceFilter(*allowIPs, *localOnly, host.logger.Named("access"))
+	if err != nil {
+		log.Fatalf("Invalid -allow-ips: %v", err)
+	}
+
+	addr := listenAddr(*port, *localOnly)
 	fmt.Printf("Server ready! Try:\n")
-	fmt.Printf("  curl http://localhost:%s/ \n", *port)
-	fmt.Printf("  curl http://localhost:%s/ui/ (GUI)\n", *port)
+	fmt.Printf("  curl %s://localhost:%s/ \n", scheme, *port)
+	fmt.Printf("  curl %s://localhost:%s/ui/ (GUI)\n", scheme, *port)
+	if tlsConfig != nil {
+		fmt.Printf("  VAULT_ADDR=https://localhost:%s VAULT_CACERT=/path/to/ca.pem vault status\n", *port)
+	}
+	if *localOnly {
+		fmt.Printf("Accepting requests from loopback addresses only\n")
+	}
+	if *allowIPs != "" {
+		fmt.Printf("Accepting requests only from %s\n", *allowIPs)
+	}
 
-	if err := http.ListenAndServe(addr, nil); err != nil {
+	server := &http.Server{
+		Addr:           addr,
+		Handler:        sources.wrap(corsMiddleware(lanes.wrap(router).ServeHTTP)),
+		ReadTimeout:    *readTimeout,
+		WriteTimeout:   *writeTimeout,
+		IdleTimeout:    *idleTimeout,
+		MaxHeaderBytes: *maxHeaderBytes,
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
 		log.Fatalf("Server failed: %v", err)
 	}
+	if *maxConns > 0 {
+		listener = netutil.LimitListener(listener, *maxConns)
+	}
+	if tlsConfig != nil {
+		listener = tls.NewListener(listener, tlsConfig)
+	}
+
+	// On SIGINT/SIGTERM stop accepting connections and let in-flight requests finish
+	// before the plugins are stopped; a second signal stops waiting
+	drained := make(chan struct{})
+	sigChan := make(chan os.Signal, 2)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	postStartFailed := make(chan struct{})
+	go func() {
+		defer close(drained)
+		select {
+		case <-sigChan:
+			fmt.Printf("\nReceived interrupt signal, draining in-flight requests (up to %s)...\n", *drainTimeout)
+		case <-postStartFailed:
+			fmt.Printf("Draining in-flight requests (up to %s)...\n", *drainTimeout)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), *drainTimeout)
+		defer cancel()
+		go func() {
+			select {
+			case <-sigChan:
+				fmt.Println("Received second interrupt signal, shutting down now")
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+		if err := server.Shutdown(ctx); err != nil {
+			fmt.Printf("Requests still in flight were cut off: %v\n", err)
+			server.Close()
+		}
+	}()
+
+	// A failed post-start check shuts the host down as a signal does, then exits with 1
+	if *postStart != "" {
+		check := &postStartCheck{
+			check:   *postStart,
+			handler: router,
+			addr:    fmt.Sprintf("%s://localhost:%s", scheme, *port),
+			token:   tokenStore.RootToken(),
+			mount:   host.mountPath,
+			timeout: *postStartWait,
+			out:     os.Stderr,
+		}
+		go func() {
+			if err := check.run(); err != nil {
+				fmt.Fprintf(os.Stderr, "Post-start check failed: %v\n", err)
+				close(postStartFailed)
+				return
+			}
+			fmt.Printf("Post-start check passed: %s\n", *postStart)
+		}()
+	}
+
+	if err := server.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+		log.Fatalf("Server failed: %v", err)
+	}
+	<-drained
+	fmt.Println("Shutting down...")
+	if grpcProxy != nil {
+		grpcProxy.Close()
+	}
+	for _, path := range router.Mounts() {
+		router.Unmount(path)
+	}
+	// End any plugin process go-plugin launched that a mount left behind
+	plugin.CleanupClients()
+	select {
+	case <-postStartFailed:
+		host.Stop()
+		finishEgress()
+		removeArtifacts()
+		os.Exit(1)
+	default:
+	}
+}
+
+// newMountedPlugin launches an additional plugin for a mount created through /v1/sys/mounts.
+// Options: "plugin" (path to the binary, required), "config" (map or string of plugin config),
+// "options" (map or string of mount options), "system_view" (mount tuning) and "sha256"
+// (the SHA256 the binary must have).
+func newMountedPlugin(path string, options map[string]interface{}, verbose bool) (*handlers.Handler, func(), error) {
+	binary, _ := options["plugin"].(string)
+	if binary == "" {
+		return nil, nil, fmt.Errorf("plugin is required")
+	}
+
+	absPath, err := filepath.Abs(binary)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to resolve plugin path: %w", err)
+	}
+	if _, err := os.Stat(absPath); err != nil {
+		return nil, nil, fmt.Errorf("plugin binary not found at path: %s", absPath)
+	}
+
+	var config map[string]string
+	switch v := options["config"].(type) {
+	case nil:
+	case string:
+		if config, err = parsePluginConfig(v); err != nil {
+			return nil, nil, err
+		}
+	case map[string]interface{}:
+		config = make(map[string]string, len(v))
+		for key, value := range v {
+			config[key] = fmt.Sprintf("%v", value)
+		}
+	default:
+		return nil, nil, fmt.Errorf("config must be an object or a string")
+	}
+
+	var mountOptions map[string]string
+	switch v := options["options"].(type) {
+	case nil:
+	case string:
+		if mountOptions, err = parsePluginConfig(v); err != nil {
+			return nil, nil, err
+		}
+	case map[string]interface{}:
+		mountOptions = make(map[string]string, len(v))
+		for key, value := range v {
+			mountOptions[key] = fmt.Sprintf("%v", value)
+		}
+	default:
+		return nil, nil, fmt.Errorf("options must be an object or a string")
+	}
+
+	tuning := systemViewConfig
+	switch v := options["system_view"].(type) {
+	case nil:
+	case map[string]interface{}:
+		if tuning, err = tuning.withOverrides(v); err != nil {
+			return nil, nil, err
+		}
+	default:
+		return nil, nil, fmt.Errorf("system_view must be an object")
+	}
+
+	var digest string
+	switch v := options["sha256"].(type) {
+	case nil:
+	case string:
+		if digest, err = parseSHA256Digest(v); err != nil {
+			return nil, nil, fmt.Errorf("invalid sha256: %w", err)
+		}
+	default:
+		return nil, nil, fmt.Errorf("sha256 must be a string")
+	}
+
+	host, err := NewPluginHost(absPath, verbose, config, path)
+	if err != nil {
+		return nil, nil, err
+	}
+	host.SetSHA256(digest)
+	// An explicit -plugin-pprof address belongs to the primary plugin, so other mounts
+	// are always given an address of their own
+	if *pluginPprof != "" {
+		host.pprofAddr = "auto"
+	}
+	if artifactsPath != "" {
+		dir := filepath.Join(artifactsPath, "mounts", filepath.FromSlash(strings.Trim(path, "/")))
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, nil, fmt.Errorf("failed to create artifact directory: %w", err)
+		}
+		host.artifactsDir = dir
+	}
+	if sharedStorage != nil {
+		host.SetStorageView(sharedStorage)
+	} else if *storageType == "file" || *storageType == "bbolt" {
+		// Each mount keeps its own storage view beside the primary plugin's
+		storage, _, err := openPersistentStorage("mounts/" + strings.Trim(path, "/"))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open %s storage: %w", *storageType, err)
+		}
+		host.SetStorage(storage)
+	}
+	configureMount(host, tuning)
+	host.handler.SetMountOptions(mountOptions)
+	if err := host.Start(); err != nil {
+		return nil, nil, fmt.Errorf("failed to start plugin: %w", err)
+	}
+
+	stopExpiration := make(chan struct{})
+	go host.handler.RunExpiration(handlers.ExpirationCheckInterval, stopExpiration)
+	stops := []func(){func() { close(stopExpiration) }}
+	if *watchPlugin {
+		stopWatch, err := watchPluginBinary(host)
+		if err != nil {
+			host.Stop()
+			return nil, nil, err
+		}
+		stops = append(stops, stopWatch)
+	}
+	if *autoRestart {
+		stops = append(stops, superviseHost(host))
+	}
+	return host.handler, func() {
+		for _, stop := range stops {
+			stop()
+		}
+		host.Stop()
+	}, nil
+}
+
+// configureMount applies the flags and shared state that every mount gets, so the
+// primary mount and those added later are set up alike
+func configureMount(host *PluginHost, tuning SystemViewConfig) {
+	host.SetPeriodicInterval(*periodicEvery)
+	host.SetMultiplex(*multiplex)
+	host.SetFlavor(serverFlavor)
+	host.SetSandbox(sandbox)
+	host.SetVerifier(verifier)
+	host.SetDownloader(downloader)
+	host.SetPluginLogLines(*pluginLogLines)
+	host.SetSystemViewConfig(tuning)
+	host.handler.SetLeaseTTLs(tuning.DefaultLeaseTTL, tuning.MaxLeaseTTL)
+	host.handler.SetWrapTTLs(tuning.ResponseWrapTTL, tuning.MaxResponseWrapTTL)
+	host.handler.SetHeaderPassthrough(tuning.PassthroughRequestHeaders, tuning.AllowedResponseHeaders)
+	host.env = append(host.env, pluginEnv...)
+	host.env = append(host.env, egressEnv...)
+	host.args = pluginArgv
+	host.handler.SetActivityLog(activityLog)
+	host.handler.SetClientFingerprints(clientFingerprints)
+	host.handler.SetTokenStore(tokenStore)
+	host.handler.SetWrapStore(wrapStore)
+	host.handler.SetPasswordPolicies(passwordPolicies)
+	host.handler.SetEventBus(eventBus)
+	host.handler.SetRequestTimeout(*requestTimeout)
+	host.handler.SetCanonicalJSON(*canonicalJSON)
+	host.handler.SetOperationHeaders(*opHeaders)
+	host.handler.SetExportPassphrase(*exportPass)
+	host.handler.SetReplication(replication)
+	host.handler.SetAuditBroker(auditBroker)
+	host.handler.SetClock(clock)
+	host.handler.SetStorageFaults(storageFaults)
+	if *recordExamples > 0 {
+		host.handler.SetExampleRecorder(handlers.NewExampleRecorder(*recordExamples))
+	}
+	if *breakerAfter > 0 {
+		host.handler.SetCircuitBreaker(handlers.NewCircuitBreaker(*breakerAfter, *breakerCool))
+	}
+}
+
+// subcommandArgs returns the arguments given to a subcommand such as test. Flags may
+// follow them, as in "test scenarios.yaml -plugin ./my-plugin", and are parsed here.
+func subcommandArgs() []string {
+	var files []string
+	args := flag.Args()[1:]
+	for len(args) > 0 {
+		if strings.HasPrefix(args[0], "-") {
+			flag.CommandLine.Parse(args)
+			args = flag.Args()
+			continue
+		}
+		files = append(files, args[0])
+		args = args[1:]
+	}
+	return files
+}
+
+// openPersistentStorage opens the storage named name, such as cubbyhole, below
+// -storage-path: a directory for -storage=file, and a bucket of one database for
+// -storage=bbolt, where the plugin's own storage is the bucket plugin. It returns the
+// storage and where it is kept.
+func openPersistentStorage(name string) (Storage, string, error) {
+	if *storageType == "bbolt" {
+		if boltDB == nil {
+			if err := os.MkdirAll(*storagePath, 0o700); err != nil {
+				return nil, "", fmt.Errorf("failed to create storage directory: %w", err)
+			}
+			db, err := OpenBoltDB(filepath.Join(*storagePath, boltStorageFile))
+			if err != nil {
+				return nil, "", err
+			}
+			boltDB = db
+		}
+		if name == "" {
+			name = "plugin"
+		}
+		storage, err := NewBoltStorage(boltDB, name)
+		if err != nil {
+			return nil, "", err
+		}
+		return storage, storage.Path(), nil
+	}
+	storage, err := NewFileStorage(filepath.Join(*storagePath, filepath.FromSlash(name)))
+	if err != nil {
+		return nil, "", err
+	}
+	return storage, storage.Dir(), nil
+}
+
+// forMount serves a per-mount host endpoint for the mount named by the mount query
+// parameter, or for the primary mount without one
+func forMount(router *handlers.Router, primary *handlers.Handler, handle func(*handlers.Handler, http.ResponseWriter, *http.Request)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handler := primary
+		if mount := r.URL.Query().Get("mount"); mount != "" {
+			var ok bool
+			if handler, ok = router.Lookup(mount); !ok {
+				handlers.WriteError(w, http.StatusNotFound, fmt.Sprintf("no mount at %s/", strings.Trim(mount, "/")))
+				return
+			}
+		}
+		handle(handler, w, r)
+	}
+}
+
+// forLease serves a lease endpoint from the mount that issued the lease, found from the
+// mount prefix of the lease ID in the body or of the prefix after the endpoint's path,
+// so clients such as Vault Agent can renew leases of every mount. Leases of no mount
+// go to primary, which answers as Vault does for unknown leases.
+func forLease(router *handlers.Router, primary *handlers.Handler, handle func(*handlers.Handler, http.ResponseWriter, *http.Request)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		_, id, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/v1/sys/leases/"), "/")
+		if id == "" && r.Body != nil {
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				handlers.WriteError(w, http.StatusBadRequest, fmt.Sprintf("failed to read body: %v", err))
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			var data struct {
+				LeaseID string `json:"lease_id"`
+			}
+			json.Unmarshal(body, &data)
+			id = data.LeaseID
+		}
+
+		handler := primary
+		if mounted, ok := router.MountFor(id); ok {
+			handler = mounted
+		}
+		handle(handler, w, r)
+	}
+}
+
+// superviseHost restarts host's plugin whenever its process dies, until the returned
+// function is called
+func superviseHost(host *PluginHost) func() {
+	stop := make(chan struct{})
+	go NewSupervisor(host).Run(stop)
+	return func() { close(stop) }
+}
+
+// watchPluginBinary reloads host's plugin whenever its binary changes, until the
+// returned function is called
+func watchPluginBinary(host *PluginHost) (func(), error) {
+	watcher, err := NewPluginWatcher(host)
+	if err != nil {
+		return nil, err
+	}
+	stop := make(chan struct{})
+	go watcher.Run(stop)
+	return func() { close(stop) }, nil
+}
+
+// startEgressProxy starts the egress proxy for a non-empty mode or policy and sets
+// egressEnv. The returned function saves recorded traffic to the cassette (in record
+// mode), reports policy violations and removes the CA bundle; it is safe to call more
+// than once.
+func startEgressProxy(mode, cassettePath, policyPath string, logger hclog.Logger) (*egress.Proxy, func(), error) {
+	if mode == "" && policyPath != "" {
+		mode = string(egress.ModeRecord)
+	}
+	if mode == "" {
+		return nil, func() {}, nil
+	}
+	parsed, err := egress.ParseMode(mode)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var cassette *egress.Cassette
+	if cassettePath != "" {
+		cassette, err = egress.LoadCassette(cassettePath)
+		// A missing cassette is fine when recording a new one
+		if err != nil && !(os.IsNotExist(err) && parsed == egress.ModeRecord) {
+			return nil, nil, err
+		}
+	} else if parsed == egress.ModeReplay {
+		return nil, nil, fmt.Errorf("-egress=replay requires -egress-cassette")
+	}
+
+	proxy, err := egress.New(parsed, cassette, logger)
+	if err != nil {
+		return nil, nil, err
+	}
+	if policyPath != "" {
+		policy, err := egress.LoadPolicy(policyPath)
+		if err != nil {
+			return nil, nil, err
+		}
+		proxy.SetPolicy(policy)
+	}
+	addr, err := proxy.ListenAndServe("127.0.0.1:0")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	bundle, err := os.CreateTemp("", "vault-plugin-host-egress-*.pem")
+	if err != nil {
+		return nil, nil, err
+	}
+	bundle.Close()
+	if err := proxy.CA().WriteTrustBundle(bundle.Name()); err != nil {
+		os.Remove(bundle.Name())
+		return nil, nil, err
+	}
+	egressEnv = egress.Env(addr, bundle.Name())
+
+	var once sync.Once
+	finish := func() {
+		once.Do(func() {
+			os.Remove(bundle.Name())
+			if parsed == egress.ModeRecord && cassettePath != "" {
+				if err := proxy.Cassette().Save(cassettePath); err != nil {
+					logger.Error("failed to save egress cassette", "path", cassettePath, "error", err)
+				}
+			}
+			if violations := proxy.Violations(); len(violations) > 0 {
+				fmt.Fprintf(os.Stderr, "Egress policy violations (%d):\n", len(violations))
+				for _, v := range violations {
+					fmt.Fprintf(os.Stderr, "  %s %s: %s\n", v.Method, v.URL, v.Reason)
+				}
+			}
+		})
+	}
+	return proxy, finish, nil
+}
+
+// openArtifactDir prepares the plugin artifact directory. Without an explicit directory a
+// temporary one is created, and the returned function removes it again.
+func openArtifactDir(dir string) (*ArtifactDir, func(), error) {
+	remove := func() {}
+	if dir == "" {
+		tmp, err := os.MkdirTemp("", "vault-plugin-artifacts-")
+		if err != nil {
+			return nil, nil, err
+		}
+		dir = tmp
+		remove = func() { os.RemoveAll(tmp) }
+	}
+
+	artifacts, err := NewArtifactDir(dir)
+	if err != nil {
+		remove()
+		return nil, nil, err
+	}
+	return artifacts, remove, nil
+}
+
+// runRPCCommand invokes a debug RPC against the started plugin and prints the result
+func runRPCCommand(host *PluginHost, name string) int {
+	defer host.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var result interface{}
+	var err error
+	if name == "services" {
+		result, err = host.handler.ListGRPCServices(ctx)
+	} else {
+		result, err = host.handler.InvokeRPC(ctx, name)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "RPC %s failed: %v\n", name, err)
+		return 1
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(result)
+	return 0
 }
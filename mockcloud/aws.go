@@ -0,0 +1,405 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package mockcloud
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// IMDSv2 session token headers
+const (
+	imdsTokenHeader    = "X-aws-ec2-metadata-token"
+	imdsTokenTTLHeader = "X-aws-ec2-metadata-token-ttl-seconds"
+	imdsMaxTokenTTL    = 21600
+)
+
+// stsNamespace is the XML namespace of STS responses
+const stsNamespace = "https://sts.amazonaws.com/doc/2011-06-15/"
+
+// awsCredentials returns the configured credentials, or fresh random ones
+func (m *Mock) awsCredentials(ttl time.Duration) stsCredentials {
+	aws := m.config.AWS
+	creds := stsCredentials{
+		AccessKeyID:     aws.AccessKeyID,
+		SecretAccessKey: aws.SecretAccessKey,
+		SessionToken:    aws.SessionToken,
+		Expiration:      time.Now().Add(ttl).UTC().Format(time.RFC3339),
+	}
+	if creds.AccessKeyID == "" {
+		creds.AccessKeyID = "ASIA" + randomID(upperAlnum, 16)
+	}
+	if creds.SecretAccessKey == "" {
+		creds.SecretAccessKey = randomID(base64Like, 40)
+	}
+	if creds.SessionToken == "" {
+		creds.SessionToken = randomID(base64Like, 356)
+	}
+	return creds
+}
+
+// handleIMDS serves the EC2 instance metadata service. IMDSv2 session tokens are
+// issued and checked when presented; requests without a token are allowed as IMDSv1.
+func (m *Mock) handleIMDS(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/latest/api/token" {
+		m.handleIMDSToken(w, r)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if token := r.Header.Get(imdsTokenHeader); token != "" && !m.validIMDSToken(token) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	tree, err := m.imdsTree()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	body, ok := lookupTree(tree, strings.TrimPrefix(r.URL.Path, "/latest/"))
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain")
+	w.Write([]byte(body))
+}
+
+func (m *Mock) handleIMDSToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	ttl, err := strconv.Atoi(r.Header.Get(imdsTokenTTLHeader))
+	if err != nil || ttl < 1 || ttl > imdsMaxTokenTTL {
+		http.Error(w, "invalid "+imdsTokenTTLHeader, http.StatusBadRequest)
+		return
+	}
+
+	token := randomID(base64Like, 56)
+	m.mu.Lock()
+	if m.imdsTokens == nil {
+		m.imdsTokens = make(map[string]time.Time)
+	}
+	now := time.Now()
+	for t, expires := range m.imdsTokens {
+		if now.After(expires) {
+			delete(m.imdsTokens, t)
+		}
+	}
+	m.imdsTokens[token] = now.Add(time.Duration(ttl) * time.Second)
+	m.mu.Unlock()
+
+	w.Header().Set(imdsTokenTTLHeader, strconv.Itoa(ttl))
+	w.Header().Set("Content-Type", "text/plain")
+	w.Write([]byte(token))
+}
+
+func (m *Mock) validIMDSToken(token string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	expires, ok := m.imdsTokens[token]
+	return ok && time.Now().Before(expires)
+}
+
+// imdsTree returns the metadata leaves keyed by path below /latest/
+func (m *Mock) imdsTree() (map[string]string, error) {
+	aws := m.config.AWS
+	creds := m.awsCredentials(credentialTTL)
+	now := time.Now().UTC().Format(time.RFC3339)
+
+	credentials, err := json.Marshal(map[string]string{
+		"Code":            "Success",
+		"LastUpdated":     now,
+		"Type":            "AWS-HMAC",
+		"AccessKeyId":     creds.AccessKeyID,
+		"SecretAccessKey": creds.SecretAccessKey,
+		"Token":           creds.SessionToken,
+		"Expiration":      creds.Expiration,
+	})
+	if err != nil {
+		return nil, err
+	}
+	info, err := json.Marshal(map[string]string{
+		"Code":               "Success",
+		"LastUpdated":        now,
+		"InstanceProfileArn": "arn:aws:iam::" + aws.AccountID + ":instance-profile/" + aws.RoleName,
+		"InstanceProfileId":  "AIPAEXAMPLEPROFILE0001",
+	})
+	if err != nil {
+		return nil, err
+	}
+	document, err := json.MarshalIndent(map[string]string{
+		"accountId":        aws.AccountID,
+		"architecture":     "x86_64",
+		"availabilityZone": aws.AvailabilityZone,
+		"imageId":          aws.AMIID,
+		"instanceId":       aws.InstanceID,
+		"instanceType":     aws.InstanceType,
+		"pendingTime":      now,
+		"privateIp":        aws.PrivateIP,
+		"region":           aws.Region,
+		"version":          "2017-09-30",
+	}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]string{
+		"meta-data/ami-id":                                   aws.AMIID,
+		"meta-data/instance-id":                              aws.InstanceID,
+		"meta-data/instance-type":                            aws.InstanceType,
+		"meta-data/local-ipv4":                               aws.PrivateIP,
+		"meta-data/hostname":                                 "ip-" + strings.ReplaceAll(aws.PrivateIP, ".", "-") + ".ec2.internal",
+		"meta-data/placement/availability-zone":              aws.AvailabilityZone,
+		"meta-data/placement/region":                         aws.Region,
+		"meta-data/iam/info":                                 string(info),
+		"meta-data/iam/security-credentials/" + aws.RoleName: string(credentials),
+		"dynamic/instance-identity/document":                 string(document),
+	}, nil
+}
+
+// lookupTree returns a leaf value, or the listing of a directory with child directories
+// suffixed by "/", as the metadata services do
+func lookupTree(tree map[string]string, path string) (string, bool) {
+	if value, ok := tree[path]; ok {
+		return value, true
+	}
+
+	prefix := path
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	children := make(map[string]bool)
+	for key := range tree {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		child := strings.TrimPrefix(key, prefix)
+		if i := strings.Index(child, "/"); i >= 0 {
+			child = child[:i+1]
+		}
+		children[child] = true
+	}
+	if len(children) == 0 {
+		return "", false
+	}
+
+	names := make([]string, 0, len(children))
+	for name := range children {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, "\n"), true
+}
+
+// STS response shapes
+type stsCredentials struct {
+	AccessKeyID     string `xml:"AccessKeyId"`
+	SecretAccessKey string `xml:"SecretAccessKey"`
+	SessionToken    string `xml:"SessionToken"`
+	Expiration      string `xml:"Expiration"`
+}
+
+type stsAssumedRoleUser struct {
+	AssumedRoleID string `xml:"AssumedRoleId"`
+	Arn           string `xml:"Arn"`
+}
+
+type stsFederatedUser struct {
+	FederatedUserID string `xml:"FederatedUserId"`
+	Arn             string `xml:"Arn"`
+}
+
+type stsResult struct {
+	Arn                         string              `xml:"Arn,omitempty"`
+	UserID                      string              `xml:"UserId,omitempty"`
+	Account                     string              `xml:"Account,omitempty"`
+	Credentials                 *stsCredentials     `xml:"Credentials,omitempty"`
+	AssumedRoleUser             *stsAssumedRoleUser `xml:"AssumedRoleUser,omitempty"`
+	FederatedUser               *stsFederatedUser   `xml:"FederatedUser,omitempty"`
+	SubjectFromWebIdentityToken string              `xml:"SubjectFromWebIdentityToken,omitempty"`
+	Audience                    string              `xml:"Audience,omitempty"`
+	PackedPolicySize            *int                `xml:"PackedPolicySize,omitempty"`
+}
+
+type stsResponseMetadata struct {
+	RequestID string `xml:"RequestId"`
+}
+
+type stsError struct {
+	XMLName   xml.Name `xml:"ErrorResponse"`
+	Xmlns     string   `xml:"xmlns,attr"`
+	Type      string   `xml:"Error>Type"`
+	Code      string   `xml:"Error>Code"`
+	Message   string   `xml:"Error>Message"`
+	RequestID string   `xml:"RequestId"`
+}
+
+// handleSTS serves the STS query API actions used by the AWS auth and secrets plugins
+func (m *Mock) handleSTS(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeSTSError(w, http.StatusBadRequest, "MalformedInput", err.Error())
+		return
+	}
+	action := r.Form.Get("Action")
+	if m.serveCanned(w, "/mock-cloud/sts/"+action) {
+		return
+	}
+
+	aws := m.config.AWS
+	ttl := credentialTTL
+	if s := r.Form.Get("DurationSeconds"); s != "" {
+		seconds, err := strconv.Atoi(s)
+		if err != nil || seconds < 900 || seconds > 129600 {
+			writeSTSError(w, http.StatusBadRequest, "ValidationError", "DurationSeconds must be between 900 and 129600")
+			return
+		}
+		ttl = time.Duration(seconds) * time.Second
+	}
+
+	var result stsResult
+	switch action {
+	case "GetCallerIdentity":
+		result = stsResult{Arn: aws.CallerARN, UserID: aws.UserID, Account: aws.AccountID}
+
+	case "GetSessionToken":
+		creds := m.awsCredentials(ttl)
+		result = stsResult{Credentials: &creds}
+
+	case "AssumeRole", "AssumeRoleWithWebIdentity":
+		roleARN := r.Form.Get("RoleArn")
+		session := r.Form.Get("RoleSessionName")
+		if roleARN == "" || session == "" {
+			writeSTSError(w, http.StatusBadRequest, "ValidationError", "RoleArn and RoleSessionName are required")
+			return
+		}
+		roleName := roleARN[strings.LastIndex(roleARN, "/")+1:]
+		creds := m.awsCredentials(ttl)
+		packed := 6
+		result = stsResult{
+			Credentials: &creds,
+			AssumedRoleUser: &stsAssumedRoleUser{
+				AssumedRoleID: "AROA" + randomID(upperAlnum, 17) + ":" + session,
+				Arn:           "arn:aws:sts::" + aws.AccountID + ":assumed-role/" + roleName + "/" + session,
+			},
+			PackedPolicySize: &packed,
+		}
+		if action == "AssumeRoleWithWebIdentity" {
+			token := r.Form.Get("WebIdentityToken")
+			if token == "" {
+				writeSTSError(w, http.StatusBadRequest, "ValidationError", "WebIdentityToken is required")
+				return
+			}
+			result.PackedPolicySize = nil
+			result.SubjectFromWebIdentityToken, result.Audience = tokenSubject(token)
+		}
+
+	case "GetFederationToken":
+		name := r.Form.Get("Name")
+		if name == "" {
+			writeSTSError(w, http.StatusBadRequest, "ValidationError", "Name is required")
+			return
+		}
+		creds := m.awsCredentials(ttl)
+		result = stsResult{
+			Credentials: &creds,
+			FederatedUser: &stsFederatedUser{
+				FederatedUserID: aws.AccountID + ":" + name,
+				Arn:             "arn:aws:sts::" + aws.AccountID + ":federated-user/" + name,
+			},
+		}
+
+	default:
+		writeSTSError(w, http.StatusBadRequest, "InvalidAction", fmt.Sprintf("Could not find operation %s for version 2011-06-15", action))
+		return
+	}
+
+	writeSTSResponse(w, action, result)
+}
+
+// tokenSubject returns the unverified sub and aud claims of a JWT
+func tokenSubject(token string) (string, string) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", ""
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", ""
+	}
+	var claims struct {
+		Subject  string      `json:"sub"`
+		Audience interface{} `json:"aud"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return "", ""
+	}
+	switch aud := claims.Audience.(type) {
+	case string:
+		return claims.Subject, aud
+	case []interface{}:
+		if len(aud) > 0 {
+			return claims.Subject, fmt.Sprint(aud[0])
+		}
+	}
+	return claims.Subject, ""
+}
+
+// writeSTSResponse wraps a result in the <Action>Response/<Action>Result envelope
+func writeSTSResponse(w http.ResponseWriter, action string, result stsResult) {
+	envelope := struct {
+		XMLName          xml.Name
+		Xmlns            string              `xml:"xmlns,attr"`
+		Result           interface{}         `xml:",any"`
+		ResponseMetadata stsResponseMetadata `xml:"ResponseMetadata"`
+	}{
+		XMLName: xml.Name{Local: action + "Response"},
+		Xmlns:   stsNamespace,
+		Result: struct {
+			XMLName xml.Name
+			stsResult
+		}{XMLName: xml.Name{Local: action + "Result"}, stsResult: result},
+		ResponseMetadata: stsResponseMetadata{RequestID: newRequestID()},
+	}
+
+	data, err := xml.Marshal(envelope)
+	if err != nil {
+		writeSTSError(w, http.StatusInternalServerError, "InternalFailure", err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "text/xml")
+	w.Write([]byte(xml.Header))
+	w.Write(data)
+}
+
+func writeSTSError(w http.ResponseWriter, status int, code, message string) {
+	errType := "Sender"
+	if status >= 500 {
+		errType = "Receiver"
+	}
+	data, _ := xml.Marshal(stsError{Xmlns: stsNamespace, Type: errType, Code: code, Message: message, RequestID: newRequestID()})
+	w.Header().Set("Content-Type", "text/xml")
+	w.WriteHeader(status)
+	w.Write([]byte(xml.Header))
+	w.Write(data)
+}
+
+// newRequestID returns a UUID-shaped request ID
+func newRequestID() string {
+	const hex = "0123456789abcdef"
+	id := randomID(hex, 32)
+	return id[:8] + "-" + id[8:12] + "-" + id[12:16] + "-" + id[16:20] + "-" + id[20:]
+}
@@ -0,0 +1,124 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package mockcloud
+
+import (
+	"net/http"
+	"strings"
+)
+
+// gcpIssuer is the issuer of GCP identity tokens
+const gcpIssuer = "https://accounts.google.com"
+
+// gcpMetadataPrefix is the path of version 1 of the compute metadata API
+const gcpMetadataPrefix = "/computeMetadata/v1/"
+
+// handleGCPMetadata serves the compute metadata server. As on GCE, requests must carry
+// Metadata-Flavor: Google.
+func (m *Mock) handleGCPMetadata(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Metadata-Flavor", "Google")
+	if r.Header.Get("Metadata-Flavor") != "Google" {
+		http.Error(w, "Missing required header: Metadata-Flavor", http.StatusForbidden)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !strings.HasPrefix(r.URL.Path, gcpMetadataPrefix) {
+		http.NotFound(w, r)
+		return
+	}
+	path := strings.TrimPrefix(r.URL.Path, gcpMetadataPrefix)
+
+	gcp := m.config.GCP
+	if rest, ok := strings.CutPrefix(path, "instance/service-accounts/"); ok {
+		account, leaf, _ := strings.Cut(rest, "/")
+		if account == "default" || account == gcp.ServiceAccount {
+			switch leaf {
+			case "token":
+				m.handleGCPToken(w)
+				return
+			case "identity":
+				m.handleGCPIdentity(w, r)
+				return
+			}
+		}
+	}
+
+	body, ok := lookupTree(m.gcpTree(), path)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/text")
+	w.Write([]byte(body))
+}
+
+// gcpTree returns the static metadata leaves keyed by path below /computeMetadata/v1/
+func (m *Mock) gcpTree() map[string]string {
+	gcp := m.config.GCP
+	tree := map[string]string{
+		"project/project-id":         gcp.ProjectID,
+		"project/numeric-project-id": gcp.NumericProjectID,
+		"instance/id":                gcp.InstanceID,
+		"instance/name":              gcp.InstanceName,
+		"instance/hostname":          gcp.InstanceName + "." + gcp.Zone + ".c." + gcp.ProjectID + ".internal",
+		"instance/zone":              "projects/" + gcp.NumericProjectID + "/zones/" + gcp.Zone,
+	}
+	for _, account := range []string{"default", gcp.ServiceAccount} {
+		prefix := "instance/service-accounts/" + account + "/"
+		tree[prefix+"email"] = gcp.ServiceAccount
+		tree[prefix+"aliases"] = "default"
+		tree[prefix+"scopes"] = strings.Join(gcp.Scopes, "\n")
+	}
+	return tree
+}
+
+func (m *Mock) handleGCPToken(w http.ResponseWriter) {
+	token := m.config.GCP.AccessToken
+	if token == "" {
+		token = "ya29.mock-" + randomID(base64Like[:62], 64)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": token,
+		"expires_in":   int(credentialTTL.Seconds()) - 1,
+		"token_type":   "Bearer",
+	})
+}
+
+// handleGCPIdentity issues an identity token signed by the key served at /mock-cloud/gcp/keys
+func (m *Mock) handleGCPIdentity(w http.ResponseWriter, r *http.Request) {
+	audience := r.URL.Query().Get("audience")
+	if audience == "" {
+		http.Error(w, "non-empty audience parameter required", http.StatusBadRequest)
+		return
+	}
+
+	gcp := m.config.GCP
+	claims := map[string]interface{}{
+		"azp":            gcp.ServiceAccount,
+		"email":          gcp.ServiceAccount,
+		"email_verified": true,
+	}
+	if r.URL.Query().Get("format") == "full" {
+		claims["google"] = map[string]interface{}{
+			"compute_engine": map[string]interface{}{
+				"project_id":     gcp.ProjectID,
+				"project_number": gcp.NumericProjectID,
+				"zone":           gcp.Zone,
+				"instance_id":    gcp.InstanceID,
+				"instance_name":  gcp.InstanceName,
+			},
+		}
+	}
+
+	token, err := m.signer.IssueToken(audience, gcp.ServiceAccount, credentialTTL, claims)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html")
+	w.Write([]byte(token))
+}
@@ -0,0 +1,280 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package mockcloud serves mock AWS (STS, EC2 instance metadata) and GCP (compute
+// metadata) endpoints, so cloud auth and secrets plugins can complete their flows
+// offline. Identities and credentials come from a Config, and any endpoint can be
+// replaced by a canned response.
+package mockcloud
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"vault-plugin-host/mockidp"
+)
+
+// AWSConfig is the identity reported by the AWS endpoints
+type AWSConfig struct {
+	AccountID        string `json:"account_id"`
+	Region           string `json:"region"`
+	AvailabilityZone string `json:"availability_zone"`
+	InstanceID       string `json:"instance_id"`
+	InstanceType     string `json:"instance_type"`
+	AMIID            string `json:"ami_id"`
+	PrivateIP        string `json:"private_ip"`
+	// RoleName is the instance profile role whose credentials IMDS hands out
+	RoleName string `json:"role_name"`
+	// CallerARN and UserID are returned by sts:GetCallerIdentity
+	CallerARN string `json:"caller_arn"`
+	UserID    string `json:"user_id"`
+	// Static credentials; random ones are generated per request when empty
+	AccessKeyID     string `json:"access_key_id"`
+	SecretAccessKey string `json:"secret_access_key"`
+	SessionToken    string `json:"session_token"`
+}
+
+// GCPConfig is the identity reported by the GCP metadata server
+type GCPConfig struct {
+	ProjectID        string   `json:"project_id"`
+	NumericProjectID string   `json:"numeric_project_id"`
+	Zone             string   `json:"zone"`
+	InstanceID       string   `json:"instance_id"`
+	InstanceName     string   `json:"instance_name"`
+	ServiceAccount   string   `json:"service_account"`
+	Scopes           []string `json:"scopes"`
+	// AccessToken is returned by the token endpoint; a random one is generated when empty
+	AccessToken string `json:"access_token"`
+}
+
+// Response is a canned reply for an endpoint. A JSON string body is written as-is;
+// any other JSON value is written as application/json.
+type Response struct {
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers"`
+	Body    json.RawMessage   `json:"body"`
+}
+
+// Config describes the mock cloud
+type Config struct {
+	AWS AWSConfig `json:"aws"`
+	GCP GCPConfig `json:"gcp"`
+	// Responses replace endpoints by request path. STS actions are keyed as
+	// /mock-cloud/sts/<Action>.
+	Responses map[string]Response `json:"responses"`
+}
+
+// LoadConfig reads a config from a JSON file
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var config Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse mock cloud config: %w", err)
+	}
+	return &config, nil
+}
+
+// setDefault assigns def to *s when it is empty
+func setDefault(s *string, def string) {
+	if *s == "" {
+		*s = def
+	}
+}
+
+// applyDefaults fills in a plausible identity for every unset field
+func (c *Config) applyDefaults() {
+	aws := &c.AWS
+	setDefault(&aws.AccountID, "123456789012")
+	setDefault(&aws.Region, "us-east-1")
+	setDefault(&aws.AvailabilityZone, aws.Region+"a")
+	setDefault(&aws.InstanceID, "i-0123456789abcdef0")
+	setDefault(&aws.InstanceType, "t3.micro")
+	setDefault(&aws.AMIID, "ami-0123456789abcdef0")
+	setDefault(&aws.PrivateIP, "10.0.0.10")
+	setDefault(&aws.RoleName, "vault-test-role")
+	setDefault(&aws.CallerARN, "arn:aws:iam::"+aws.AccountID+":role/"+aws.RoleName)
+	setDefault(&aws.UserID, "AROAEXAMPLEROLEID0001")
+
+	gcp := &c.GCP
+	setDefault(&gcp.ProjectID, "vault-test-project")
+	setDefault(&gcp.NumericProjectID, "123456789012")
+	setDefault(&gcp.Zone, "us-central1-a")
+	setDefault(&gcp.InstanceID, "1234567890123456789")
+	setDefault(&gcp.InstanceName, "vault-test-instance")
+	setDefault(&gcp.ServiceAccount, "vault-test@"+gcp.ProjectID+".iam.gserviceaccount.com")
+	if len(gcp.Scopes) == 0 {
+		gcp.Scopes = []string{"https://www.googleapis.com/auth/cloud-platform"}
+	}
+}
+
+// credentialTTL is the lifetime of generated credentials and tokens
+const credentialTTL = time.Hour
+
+// Mock serves the cloud endpoints
+type Mock struct {
+	config Config
+	// signer issues GCP identity tokens
+	signer *mockidp.Provider
+
+	mu         sync.Mutex
+	responses  map[string]Response
+	imdsTokens map[string]time.Time // IMDSv2 session tokens and their expiry
+}
+
+// New creates a mock from config; a nil config uses defaults throughout
+func New(config *Config) (*Mock, error) {
+	m := &Mock{responses: make(map[string]Response)}
+	if config != nil {
+		m.config = *config
+	}
+	m.config.applyDefaults()
+	m.SetResponses(m.config.Responses)
+
+	signer, err := mockidp.New(gcpIssuer)
+	if err != nil {
+		return nil, err
+	}
+	m.signer = signer
+	return m, nil
+}
+
+// Config returns the effective configuration, with defaults applied
+func (m *Mock) Config() Config {
+	config := m.config
+	config.Responses = m.Responses()
+	return config
+}
+
+// SetResponses replaces the canned responses
+func (m *Mock) SetResponses(responses map[string]Response) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.responses = make(map[string]Response, len(responses))
+	for k, v := range responses {
+		m.responses[k] = v
+	}
+}
+
+// Responses returns the canned responses
+func (m *Mock) Responses() map[string]Response {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	responses := make(map[string]Response, len(m.responses))
+	for k, v := range m.responses {
+		responses[k] = v
+	}
+	return responses
+}
+
+// Paths under which the mock must be registered on the host router
+var Paths = []string{"/latest/", "/computeMetadata/", "/mock-cloud/"}
+
+// ServeHTTP serves, at the host root:
+//
+//	PUT, GET /latest/...                  - EC2 instance metadata (IMDSv1 and v2)
+//	GET      /computeMetadata/v1/...      - GCP compute metadata
+//	GET, POST /mock-cloud/sts             - AWS STS query API
+//	GET      /mock-cloud/gcp/keys         - JWKS verifying GCP identity tokens
+//	GET, PUT /mock-cloud/responses        - canned responses
+func (m *Mock) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/mock-cloud/responses" {
+		m.handleResponses(w, r)
+		return
+	}
+	if m.serveCanned(w, r.URL.Path) {
+		return
+	}
+
+	switch path := r.URL.Path; {
+	case strings.HasPrefix(path, "/latest/"):
+		m.handleIMDS(w, r)
+	case strings.HasPrefix(path, "/computeMetadata/"):
+		m.handleGCPMetadata(w, r)
+	case strings.TrimSuffix(path, "/") == "/mock-cloud/sts":
+		m.handleSTS(w, r)
+	case path == "/mock-cloud/gcp/keys":
+		r.URL.Path = "/keys"
+		m.signer.ServeHTTP(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// serveCanned writes the canned response for key, if one is configured
+func (m *Mock) serveCanned(w http.ResponseWriter, key string) bool {
+	m.mu.Lock()
+	resp, ok := m.responses[key]
+	m.mu.Unlock()
+	if !ok {
+		return false
+	}
+
+	for k, v := range resp.Headers {
+		w.Header().Set(k, v)
+	}
+	body := []byte(resp.Body)
+	var text string
+	if err := json.Unmarshal(resp.Body, &text); err == nil {
+		body = []byte(text)
+	} else if len(body) > 0 && w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "application/json")
+	}
+
+	status := resp.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	w.WriteHeader(status)
+	w.Write(body)
+	return true
+}
+
+func (m *Mock) handleResponses(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, m.Responses())
+	case http.MethodPut, http.MethodPost:
+		var responses map[string]Response
+		if err := json.NewDecoder(r.Body).Decode(&responses); err != nil {
+			http.Error(w, fmt.Sprintf("failed to parse JSON: %v", err), http.StatusBadRequest)
+			return
+		}
+		m.SetResponses(responses)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// randomID returns n random characters from alphabet
+func randomID(alphabet string, n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	for i := range b {
+		b[i] = alphabet[int(b[i])%len(alphabet)]
+	}
+	return string(b)
+}
+
+const (
+	upperAlnum = "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	base64Like = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+)
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
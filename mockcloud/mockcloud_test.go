@@ -0,0 +1,191 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package mockcloud
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func newTestMock(t *testing.T, config *Config) *Mock {
+	t.Helper()
+
+	m, err := New(config)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	return m
+}
+
+func do(m *Mock, req *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, req)
+	return w
+}
+
+func TestIMDS(t *testing.T) {
+	m := newTestMock(t, &Config{AWS: AWSConfig{RoleName: "app", AccessKeyID: "AKIDTEST"}})
+
+	// IMDSv2 token
+	req := httptest.NewRequest(http.MethodPut, "/latest/api/token", nil)
+	req.Header.Set(imdsTokenTTLHeader, "60")
+	w := do(m, req)
+	if w.Code != http.StatusOK || w.Body.Len() == 0 {
+		t.Fatalf("token request failed: %d %s", w.Code, w.Body)
+	}
+	token := w.Body.String()
+
+	get := func(path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if token != "" {
+			req.Header.Set(imdsTokenHeader, token)
+		}
+		return do(m, req)
+	}
+
+	if w := get("/latest/meta-data/iam/security-credentials/", token); w.Body.String() != "app" {
+		t.Fatalf("expected role listing, got %q", w.Body)
+	}
+	w = get("/latest/meta-data/iam/security-credentials/app", token)
+	var creds map[string]string
+	if err := json.Unmarshal(w.Body.Bytes(), &creds); err != nil {
+		t.Fatalf("invalid credentials: %v", err)
+	}
+	if creds["AccessKeyId"] != "AKIDTEST" || creds["SecretAccessKey"] == "" || creds["Code"] != "Success" {
+		t.Fatalf("unexpected credentials: %v", creds)
+	}
+
+	if w := get("/latest/meta-data/placement/", ""); w.Body.String() != "availability-zone\nregion" {
+		t.Fatalf("unexpected listing: %q", w.Body)
+	}
+	if w := get("/latest/meta-data/instance-id", "bogus"); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected invalid token to be rejected, got %d", w.Code)
+	}
+	if w := get("/latest/meta-data/nope", ""); w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", w.Code)
+	}
+
+	var doc map[string]string
+	json.Unmarshal(get("/latest/dynamic/instance-identity/document", "").Body.Bytes(), &doc)
+	if doc["accountId"] != "123456789012" || doc["region"] != "us-east-1" {
+		t.Fatalf("unexpected identity document: %v", doc)
+	}
+}
+
+func TestSTS(t *testing.T) {
+	m := newTestMock(t, nil)
+
+	post := func(form url.Values) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/mock-cloud/sts", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return do(m, req)
+	}
+
+	w := post(url.Values{"Action": {"GetCallerIdentity"}, "Version": {"2011-06-15"}})
+	var identity struct {
+		XMLName xml.Name `xml:"GetCallerIdentityResponse"`
+		Result  struct {
+			Arn     string `xml:"Arn"`
+			Account string `xml:"Account"`
+		} `xml:"GetCallerIdentityResult"`
+		RequestID string `xml:"ResponseMetadata>RequestId"`
+	}
+	if err := xml.Unmarshal(w.Body.Bytes(), &identity); err != nil {
+		t.Fatalf("invalid response %s: %v", w.Body, err)
+	}
+	if identity.Result.Account != "123456789012" || !strings.HasPrefix(identity.Result.Arn, "arn:aws:iam::123456789012:role/") || identity.RequestID == "" {
+		t.Fatalf("unexpected identity: %+v", identity)
+	}
+
+	w = post(url.Values{"Action": {"AssumeRole"}, "RoleArn": {"arn:aws:iam::123456789012:role/deploy"}, "RoleSessionName": {"vault"}})
+	var assumed struct {
+		Result struct {
+			AccessKeyID string `xml:"Credentials>AccessKeyId"`
+			Arn         string `xml:"AssumedRoleUser>Arn"`
+		} `xml:"AssumeRoleResult"`
+	}
+	if err := xml.Unmarshal(w.Body.Bytes(), &assumed); err != nil {
+		t.Fatalf("invalid response %s: %v", w.Body, err)
+	}
+	if !strings.HasPrefix(assumed.Result.AccessKeyID, "ASIA") || assumed.Result.Arn != "arn:aws:sts::123456789012:assumed-role/deploy/vault" {
+		t.Fatalf("unexpected assume role result: %+v", assumed)
+	}
+
+	if w := post(url.Values{"Action": {"DeleteEverything"}}); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "InvalidAction") {
+		t.Fatalf("expected InvalidAction, got %d %s", w.Code, w.Body)
+	}
+}
+
+func TestGCPMetadata(t *testing.T) {
+	m := newTestMock(t, &Config{GCP: GCPConfig{ProjectID: "demo"}})
+
+	get := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Metadata-Flavor", "Google")
+		return do(m, req)
+	}
+
+	if w := do(m, httptest.NewRequest(http.MethodGet, "/computeMetadata/v1/project/project-id", nil)); w.Code != http.StatusForbidden {
+		t.Fatalf("expected missing Metadata-Flavor to be rejected, got %d", w.Code)
+	}
+	if w := get("/computeMetadata/v1/project/project-id"); w.Body.String() != "demo" || w.Header().Get("Metadata-Flavor") != "Google" {
+		t.Fatalf("unexpected project id %q", w.Body)
+	}
+	if w := get("/computeMetadata/v1/instance/service-accounts/default/email"); w.Body.String() != "vault-test@demo.iam.gserviceaccount.com" {
+		t.Fatalf("unexpected email %q", w.Body)
+	}
+
+	var token map[string]interface{}
+	json.Unmarshal(get("/computeMetadata/v1/instance/service-accounts/default/token").Body.Bytes(), &token)
+	if token["token_type"] != "Bearer" || token["access_token"] == "" {
+		t.Fatalf("unexpected token: %v", token)
+	}
+
+	w := get("/computeMetadata/v1/instance/service-accounts/default/identity?audience=vault/my-role&format=full")
+	parts := strings.Split(w.Body.String(), ".")
+	if len(parts) != 3 {
+		t.Fatalf("expected a JWT, got %q", w.Body)
+	}
+	payload, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	var claims map[string]interface{}
+	json.Unmarshal(payload, &claims)
+	if claims["aud"] != "vault/my-role" || claims["iss"] != gcpIssuer || claims["google"] == nil {
+		t.Fatalf("unexpected claims: %v", claims)
+	}
+
+	if w := get("/computeMetadata/v1/instance/service-accounts/default/identity"); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected missing audience to fail, got %d", w.Code)
+	}
+}
+
+func TestCannedResponses(t *testing.T) {
+	m := newTestMock(t, &Config{Responses: map[string]Response{
+		"/latest/meta-data/instance-id": {Body: json.RawMessage(`"i-canned"`)},
+	}})
+
+	if w := do(m, httptest.NewRequest(http.MethodGet, "/latest/meta-data/instance-id", nil)); w.Body.String() != "i-canned" {
+		t.Fatalf("expected canned body, got %q", w.Body)
+	}
+
+	body := `{"/mock-cloud/sts/GetCallerIdentity": {"status": 403, "body": "<ErrorResponse/>", "headers": {"Content-Type": "text/xml"}}}`
+	if w := do(m, httptest.NewRequest(http.MethodPut, "/mock-cloud/responses", strings.NewReader(body))); w.Code != http.StatusNoContent {
+		t.Fatalf("setting responses failed: %d", w.Code)
+	}
+
+	w := do(m, httptest.NewRequest(http.MethodGet, "/mock-cloud/sts?Action=GetCallerIdentity", nil))
+	if w.Code != http.StatusForbidden || w.Body.String() != "<ErrorResponse/>" || w.Header().Get("Content-Type") != "text/xml" {
+		t.Fatalf("unexpected canned STS response: %d %q", w.Code, w.Body)
+	}
+
+	// Replacing the responses drops the earlier ones
+	if w := do(m, httptest.NewRequest(http.MethodGet, "/latest/meta-data/instance-id", nil)); w.Body.String() != "i-0123456789abcdef0" {
+		t.Fatalf("expected default instance id, got %q", w.Body)
+	}
+}
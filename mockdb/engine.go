@@ -0,0 +1,381 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package mockdb
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Role is a database login tracked by the mock
+type Role struct {
+	Name       string     `json:"name"`
+	Password   string     `json:"password,omitempty"`
+	Login      bool       `json:"login"`
+	ValidUntil *time.Time `json:"valid_until,omitempty"`
+	Grants     []string   `json:"grants,omitempty"`
+	Created    time.Time  `json:"created"`
+}
+
+// expired reports whether the role's VALID UNTIL has passed
+func (r *Role) expired(now time.Time) bool {
+	return r.ValidUntil != nil && now.After(*r.ValidUntil)
+}
+
+// Statement is an executed SQL statement kept for inspection
+type Statement struct {
+	Time  time.Time `json:"time"`
+	User  string    `json:"user"`
+	SQL   string    `json:"sql"`
+	Error string    `json:"error,omitempty"`
+}
+
+// Fixtures describes the initial database state
+type Fixtures struct {
+	// Roles that exist at startup; the first login role is the usual admin for the plugin connection
+	Roles []Role `json:"roles"`
+}
+
+// LoadFixtures reads fixtures from a JSON file
+func LoadFixtures(path string) (*Fixtures, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var fixtures Fixtures
+	if err := json.Unmarshal(data, &fixtures); err != nil {
+		return nil, fmt.Errorf("failed to parse database fixtures: %w", err)
+	}
+	return &fixtures, nil
+}
+
+// maxStatements is the number of executed statements retained
+const maxStatements = 1000
+
+// Engine interprets the role management SQL that database secrets engines issue.
+// It is not transactional: statements take effect immediately even inside BEGIN/ROLLBACK.
+type Engine struct {
+	mu         sync.Mutex
+	roles      map[string]*Role
+	statements []Statement
+}
+
+// NewEngine creates an engine from fixtures; without fixtures a postgres/postgres admin exists
+func NewEngine(fixtures *Fixtures) *Engine {
+	e := &Engine{roles: make(map[string]*Role)}
+	if fixtures == nil || len(fixtures.Roles) == 0 {
+		fixtures = &Fixtures{Roles: []Role{{Name: "postgres", Password: "postgres", Login: true}}}
+	}
+	for _, role := range fixtures.Roles {
+		role := role
+		if role.Created.IsZero() {
+			role.Created = time.Now()
+		}
+		e.roles[role.Name] = &role
+	}
+	return e
+}
+
+// Authenticate checks a login role's password and expiry
+func (e *Engine) Authenticate(user, password string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	role, ok := e.roles[user]
+	if !ok || !role.Login || role.Password == "" || role.Password != password {
+		return fmt.Errorf("password authentication failed for user %q", user)
+	}
+	if role.expired(time.Now()) {
+		return fmt.Errorf("password authentication failed for user %q: role expired", user)
+	}
+	return nil
+}
+
+// Roles returns the current roles sorted by name
+func (e *Engine) Roles() []Role {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	roles := make([]Role, 0, len(e.roles))
+	for _, role := range e.roles {
+		copied := *role
+		copied.Grants = append([]string(nil), role.Grants...)
+		roles = append(roles, copied)
+	}
+	sort.Slice(roles, func(i, j int) bool { return roles[i].Name < roles[j].Name })
+	return roles
+}
+
+// Statements returns the executed statements, oldest first
+func (e *Engine) Statements() []Statement {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]Statement(nil), e.statements...)
+}
+
+// Column types the engine can return
+const (
+	typeBool = "bool"
+	typeText = "text"
+)
+
+// Result is the outcome of one statement
+type Result struct {
+	Tag     string     // command tag, e.g. "CREATE ROLE" or "SELECT 1"
+	Columns []Column   // nil for statements that return no rows
+	Rows    [][]string // text values; nil entries are not supported
+}
+
+// Column describes a result column
+type Column struct {
+	Name string
+	Type string
+}
+
+// SQLError is a statement failure carrying a SQLSTATE code
+type SQLError struct {
+	Code    string
+	Message string
+}
+
+func (e *SQLError) Error() string { return e.Message }
+
+var (
+	identifier = `("(?:[^"]|"")+"|[A-Za-z_][\w$]*)`
+
+	createRoleRe = regexp.MustCompile(`(?is)^CREATE\s+(ROLE|USER)\s+` + identifier + `(.*)$`)
+	alterRoleRe  = regexp.MustCompile(`(?is)^ALTER\s+(?:ROLE|USER)\s+` + identifier + `(.*)$`)
+	dropRoleRe   = regexp.MustCompile(`(?is)^DROP\s+(?:ROLE|USER)\s+(IF\s+EXISTS\s+)?(.+)$`)
+	grantRe      = regexp.MustCompile(`(?is)^GRANT\s+(.+?)\s+TO\s+(.+)$`)
+	passwordRe   = regexp.MustCompile(`(?is)\bPASSWORD\s+'((?:[^']|'')*)'`)
+	validUntilRe = regexp.MustCompile(`(?is)\bVALID\s+UNTIL\s+'((?:[^']|'')*)'`)
+	noLoginRe    = regexp.MustCompile(`(?i)\bNOLOGIN\b`)
+	loginRe      = regexp.MustCompile(`(?i)\bLOGIN\b`)
+	roleExistsRe = regexp.MustCompile(`(?is)^SELECT\s+exists\s*\(\s*SELECT\s+.*\bFROM\s+pg_roles\s+WHERE\s+rolname\s*=\s*(\$\d+|'(?:[^']|'')*')`)
+	paramRe      = regexp.MustCompile(`\$(\d+)`)
+)
+
+// unquoteIdent removes identifier quoting
+func unquoteIdent(s string) string {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, `"`) && strings.HasSuffix(s, `"`) && len(s) >= 2 {
+		return strings.ReplaceAll(s[1:len(s)-1], `""`, `"`)
+	}
+	return strings.ToLower(s)
+}
+
+// unquoteLiteral removes string literal quoting
+func unquoteLiteral(s string) string {
+	return strings.ReplaceAll(s, "''", "'")
+}
+
+// validUntilLayouts are the timestamp formats accepted in VALID UNTIL
+var validUntilLayouts = []string{
+	"2006-01-02 15:04:05-0700",
+	"2006-01-02 15:04:05-07:00",
+	"2006-01-02 15:04:05-07",
+	"2006-01-02 15:04:05.999999999-07:00",
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// parseValidUntil parses a VALID UNTIL literal; "infinity" and unparseable values mean no expiry
+func parseValidUntil(s string) *time.Time {
+	for _, layout := range validUntilLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return &t
+		}
+	}
+	return nil
+}
+
+// Exec runs one statement with positional parameters
+func (e *Engine) Exec(user, query string, params []string) (*Result, error) {
+	query = strings.TrimSpace(substituteParams(query, params))
+
+	res, err := e.exec(query)
+
+	e.mu.Lock()
+	stmt := Statement{Time: time.Now(), User: user, SQL: query}
+	if err != nil {
+		stmt.Error = err.Error()
+	}
+	e.statements = append(e.statements, stmt)
+	if len(e.statements) > maxStatements {
+		e.statements = e.statements[len(e.statements)-maxStatements:]
+	}
+	e.mu.Unlock()
+
+	return res, err
+}
+
+// substituteParams replaces $n placeholders with quoted literals
+func substituteParams(query string, params []string) string {
+	return paramRe.ReplaceAllStringFunc(query, func(m string) string {
+		var n int
+		fmt.Sscanf(m[1:], "%d", &n)
+		if n < 1 || n > len(params) {
+			return m
+		}
+		return "'" + strings.ReplaceAll(params[n-1], "'", "''") + "'"
+	})
+}
+
+func (e *Engine) exec(query string) (*Result, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if m := createRoleRe.FindStringSubmatch(query); m != nil {
+		name := unquoteIdent(m[2])
+		if _, exists := e.roles[name]; exists {
+			return nil, &SQLError{"42710", fmt.Sprintf("role %q already exists", name)}
+		}
+		role := &Role{Name: name, Login: strings.EqualFold(m[1], "USER"), Created: time.Now()}
+		applyRoleOptions(role, m[3])
+		e.roles[name] = role
+		return &Result{Tag: "CREATE ROLE"}, nil
+	}
+
+	if m := alterRoleRe.FindStringSubmatch(query); m != nil {
+		name := unquoteIdent(m[1])
+		role, exists := e.roles[name]
+		if !exists {
+			return nil, &SQLError{"42704", fmt.Sprintf("role %q does not exist", name)}
+		}
+		applyRoleOptions(role, m[2])
+		return &Result{Tag: "ALTER ROLE"}, nil
+	}
+
+	if m := dropRoleRe.FindStringSubmatch(query); m != nil {
+		ifExists := m[1] != ""
+		for _, raw := range strings.Split(m[2], ",") {
+			name := unquoteIdent(raw)
+			if _, exists := e.roles[name]; !exists && !ifExists {
+				return nil, &SQLError{"42704", fmt.Sprintf("role %q does not exist", name)}
+			}
+			delete(e.roles, name)
+		}
+		return &Result{Tag: "DROP ROLE"}, nil
+	}
+
+	if m := grantRe.FindStringSubmatch(query); m != nil {
+		for _, raw := range strings.Split(m[2], ",") {
+			name := unquoteIdent(strings.Fields(raw + " ")[0])
+			if role, exists := e.roles[name]; exists {
+				role.Grants = append(role.Grants, strings.TrimSpace(m[1]))
+			}
+		}
+		return &Result{Tag: "GRANT"}, nil
+	}
+
+	if m := roleExistsRe.FindStringSubmatch(query); m != nil {
+		name := unquoteLiteral(strings.Trim(m[1], "'"))
+		_, exists := e.roles[name]
+		value := "f"
+		if exists {
+			value = "t"
+		}
+		return &Result{Tag: "SELECT 1", Columns: describe(query), Rows: [][]string{{value}}}, nil
+	}
+
+	keyword := strings.ToUpper(strings.Fields(query + " ")[0])
+	switch keyword {
+	case "SELECT", "WITH", "SHOW":
+		// Catalog lookups during revocation find nothing to clean up
+		return &Result{Tag: "SELECT 0", Columns: describe(query)}, nil
+	case "BEGIN", "START":
+		return &Result{Tag: "BEGIN"}, nil
+	case "COMMIT", "END":
+		return &Result{Tag: "COMMIT"}, nil
+	case "ROLLBACK", "ABORT":
+		return &Result{Tag: "ROLLBACK"}, nil
+	case "REVOKE", "REASSIGN", "SET", "RESET", "DO", "COMMENT", "DISCARD", "DEALLOCATE":
+		return &Result{Tag: keyword}, nil
+	case "DROP", "CREATE", "ALTER":
+		words := strings.Fields(strings.ToUpper(query))
+		if len(words) > 1 {
+			return &Result{Tag: keyword + " " + words[1]}, nil
+		}
+		return &Result{Tag: keyword}, nil
+	default:
+		return nil, &SQLError{"0A000", fmt.Sprintf("mock database does not support %q", keyword)}
+	}
+}
+
+// describe returns the columns a statement produces without executing it, or nil if it returns no rows
+func describe(query string) []Column {
+	if roleExistsRe.MatchString(query) {
+		return []Column{{Name: "exists", Type: typeBool}}
+	}
+	switch strings.ToUpper(strings.Fields(query + " ")[0]) {
+	case "SELECT", "WITH", "SHOW":
+		return []Column{{Name: "?column?", Type: typeText}}
+	}
+	return nil
+}
+
+// applyRoleOptions updates a role from the option clause of CREATE/ALTER ROLE
+func applyRoleOptions(role *Role, options string) {
+	if m := passwordRe.FindStringSubmatch(options); m != nil {
+		role.Password = unquoteLiteral(m[1])
+	}
+	if m := validUntilRe.FindStringSubmatch(options); m != nil {
+		role.ValidUntil = parseValidUntil(unquoteLiteral(m[1]))
+	}
+	// Strip literals so a password containing LOGIN can't flip the flag
+	bare := passwordRe.ReplaceAllString(options, "")
+	switch {
+	case noLoginRe.MatchString(bare):
+		role.Login = false
+	case loginRe.MatchString(bare):
+		role.Login = true
+	}
+}
+
+// splitStatements splits a simple query on semicolons outside quotes and comments.
+// Comment-only statements are dropped.
+func splitStatements(query string) []string {
+	var statements []string
+	var current strings.Builder
+	var quote byte
+
+	flush := func() {
+		if s := strings.TrimSpace(current.String()); s != "" {
+			statements = append(statements, s)
+		}
+		current.Reset()
+	}
+
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case quote != 0:
+			current.WriteByte(c)
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+			current.WriteByte(c)
+		case c == '-' && i+1 < len(query) && query[i+1] == '-':
+			for i < len(query) && query[i] != '\n' {
+				i++
+			}
+		case c == ';':
+			flush()
+		default:
+			current.WriteByte(c)
+		}
+	}
+	flush()
+	return statements
+}
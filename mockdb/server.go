@@ -0,0 +1,593 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package mockdb implements a small PostgreSQL wire-protocol server backed by an in-memory
+// role table, so database secrets engine flows (create role, issue credentials, revoke) can
+// be exercised with the stock postgresql-database-plugin and no real database.
+//
+// The server speaks protocol 3.0 with cleartext password authentication and supports both
+// the simple and extended query protocols. It only understands the role management SQL that
+// database plugins issue; see Engine.
+package mockdb
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"strconv"
+	"sync"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+// Startup request codes
+const (
+	protocolVersion = 196608
+	sslRequestCode  = 80877103
+	gssRequestCode  = 80877104
+	cancelCode      = 80877102
+)
+
+// maxMessageSize bounds a single frontend message
+const maxMessageSize = 1 << 24
+
+// Type OIDs and sizes of the columns the engine returns
+var columnTypes = map[string]struct {
+	oid  uint32
+	size int16
+}{
+	typeBool: {16, 1},
+	typeText: {25, -1},
+}
+
+// textOID is the parameter type advertised for every placeholder
+const textOID = 25
+
+// Server serves an Engine over the PostgreSQL wire protocol
+type Server struct {
+	engine *Engine
+	logger hclog.Logger
+
+	mu       sync.Mutex
+	listener net.Listener
+	conns    map[net.Conn]struct{}
+	nextPID  uint32
+}
+
+// NewServer creates a server for the engine
+func NewServer(engine *Engine, logger hclog.Logger) *Server {
+	return &Server{
+		engine: engine,
+		logger: logger,
+		conns:  make(map[net.Conn]struct{}),
+	}
+}
+
+// Engine returns the engine served by the server
+func (s *Server) Engine() *Engine {
+	return s.engine
+}
+
+// ListenAndServe listens on addr and serves connections in the background
+func (s *Server) ListenAndServe(addr string) (net.Addr, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	go s.Serve(listener)
+	return listener.Addr(), nil
+}
+
+// Serve accepts connections until the listener is closed
+func (s *Server) Serve(listener net.Listener) error {
+	s.mu.Lock()
+	s.listener = listener
+	s.mu.Unlock()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+
+		s.mu.Lock()
+		s.conns[conn] = struct{}{}
+		s.nextPID++
+		pid := s.nextPID
+		s.mu.Unlock()
+
+		go s.serveConn(conn, pid)
+	}
+}
+
+// Close stops the listener and closes open connections
+func (s *Server) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for conn := range s.conns {
+		conn.Close()
+	}
+	if s.listener != nil {
+		return s.listener.Close()
+	}
+	return nil
+}
+
+// portal is a bound statement ready to execute
+type portal struct {
+	query         string
+	params        []string
+	resultFormats []int16
+}
+
+// session is the state of one client connection
+type session struct {
+	r    *bufio.Reader
+	w    *bufio.Writer
+	user string
+
+	txStatus   byte // 'I' idle, 'T' in transaction, 'E' failed transaction
+	statements map[string]string
+	portals    map[string]*portal
+	skipToSync bool // set after an extended-protocol error
+}
+
+func (s *Server) serveConn(conn net.Conn, pid uint32) {
+	defer func() {
+		conn.Close()
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+	}()
+
+	sess := &session{
+		r:          bufio.NewReader(conn),
+		w:          bufio.NewWriter(conn),
+		txStatus:   'I',
+		statements: make(map[string]string),
+		portals:    make(map[string]*portal),
+	}
+
+	if err := s.startup(sess, pid); err != nil {
+		if !errors.Is(err, io.EOF) {
+			s.logger.Debug("postgres startup failed", "remote", conn.RemoteAddr(), "error", err)
+		}
+		return
+	}
+
+	for {
+		typ, body, err := readMessage(sess.r)
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				s.logger.Debug("postgres connection closed", "remote", conn.RemoteAddr(), "error", err)
+			}
+			return
+		}
+		if typ == 'X' {
+			return
+		}
+		if err := s.handle(sess, typ, body); err != nil {
+			s.logger.Debug("postgres write failed", "remote", conn.RemoteAddr(), "error", err)
+			return
+		}
+	}
+}
+
+// startup negotiates the connection and authenticates the user
+func (s *Server) startup(sess *session, pid uint32) error {
+	for {
+		var header [8]byte
+		if _, err := io.ReadFull(sess.r, header[:]); err != nil {
+			return err
+		}
+		length := binary.BigEndian.Uint32(header[:4])
+		if length < 8 || length > maxMessageSize {
+			return fmt.Errorf("invalid startup message length %d", length)
+		}
+		body := make([]byte, length-8)
+		if _, err := io.ReadFull(sess.r, body); err != nil {
+			return err
+		}
+
+		switch code := binary.BigEndian.Uint32(header[4:]); code {
+		case sslRequestCode, gssRequestCode:
+			// Encryption is not supported; the client continues in plaintext
+			if err := sess.w.WriteByte('N'); err != nil {
+				return err
+			}
+			if err := sess.w.Flush(); err != nil {
+				return err
+			}
+			continue
+		case cancelCode:
+			return io.EOF
+		case protocolVersion:
+			params := make(map[string]string)
+			fields := splitCStrings(body)
+			for i := 0; i+1 < len(fields); i += 2 {
+				params[fields[i]] = fields[i+1]
+			}
+			sess.user = params["user"]
+		default:
+			sess.fatal("0A000", fmt.Sprintf("unsupported frontend protocol %d", code))
+			return sess.w.Flush()
+		}
+		break
+	}
+
+	// Request a cleartext password
+	sess.send('R', uint32be(3))
+	if err := sess.w.Flush(); err != nil {
+		return err
+	}
+	typ, body, err := readMessage(sess.r)
+	if err != nil {
+		return err
+	}
+	if typ != 'p' {
+		sess.fatal("08P01", "expected password message")
+		return sess.w.Flush()
+	}
+	password := ""
+	if fields := splitCStrings(body); len(fields) > 0 {
+		password = fields[0]
+	}
+	if err := s.engine.Authenticate(sess.user, password); err != nil {
+		sess.fatal("28P01", err.Error())
+		sess.w.Flush()
+		return err
+	}
+
+	sess.send('R', uint32be(0))
+	for _, kv := range [][2]string{
+		{"server_version", "16.0 (vault-plugin-host mock)"},
+		{"server_encoding", "UTF8"},
+		{"client_encoding", "UTF8"},
+		{"DateStyle", "ISO, MDY"},
+		{"TimeZone", "UTC"},
+		{"integer_datetimes", "on"},
+		{"standard_conforming_strings", "on"},
+	} {
+		sess.send('S', cstring(kv[0]), cstring(kv[1]))
+	}
+	sess.send('K', uint32be(pid), uint32be(pid))
+	sess.ready()
+	return sess.w.Flush()
+}
+
+func (s *Server) handle(sess *session, typ byte, body []byte) error {
+	// After an error in the extended protocol everything up to Sync is discarded
+	if sess.skipToSync && typ != 'S' {
+		return nil
+	}
+
+	switch typ {
+	case 'Q':
+		s.simpleQuery(sess, firstCString(body))
+		sess.ready()
+		return sess.w.Flush()
+
+	case 'P':
+		fields := splitCStrings(body)
+		if len(fields) < 2 {
+			sess.extendedError("08P01", "malformed parse message")
+			return nil
+		}
+		sess.statements[fields[0]] = fields[1]
+		sess.send('1')
+
+	case 'B':
+		if err := parseBind(sess, body); err != nil {
+			sess.extendedError("08P01", err.Error())
+			return nil
+		}
+		sess.send('2')
+
+	case 'D':
+		if len(body) < 2 {
+			sess.extendedError("08P01", "malformed describe message")
+			return nil
+		}
+		name := firstCString(body[1:])
+		switch body[0] {
+		case 'S':
+			query, ok := sess.statements[name]
+			if !ok {
+				sess.extendedError("26000", fmt.Sprintf("prepared statement %q does not exist", name))
+				return nil
+			}
+			sess.send('t', parameterDescription(query))
+			sess.rowDescription(describe(query), nil)
+		case 'P':
+			p, ok := sess.portals[name]
+			if !ok {
+				sess.extendedError("34000", fmt.Sprintf("portal %q does not exist", name))
+				return nil
+			}
+			sess.rowDescription(describe(p.query), p.resultFormats)
+		default:
+			sess.extendedError("08P01", "malformed describe message")
+		}
+
+	case 'E':
+		name := firstCString(body)
+		p, ok := sess.portals[name]
+		if !ok {
+			sess.extendedError("34000", fmt.Sprintf("portal %q does not exist", name))
+			return nil
+		}
+		if len(splitStatements(p.query)) == 0 {
+			sess.send('I')
+			return nil
+		}
+		res, err := s.exec(sess, p.query, p.params)
+		if err != nil {
+			sess.extendedError(errorCode(err), err.Error())
+			return nil
+		}
+		sess.dataRows(res, p.resultFormats)
+		sess.send('C', cstring(res.Tag))
+
+	case 'C':
+		if len(body) < 2 {
+			sess.extendedError("08P01", "malformed close message")
+			return nil
+		}
+		name := firstCString(body[1:])
+		if body[0] == 'S' {
+			delete(sess.statements, name)
+		} else {
+			delete(sess.portals, name)
+		}
+		sess.send('3')
+
+	case 'S':
+		sess.skipToSync = false
+		delete(sess.portals, "")
+		sess.ready()
+		return sess.w.Flush()
+
+	case 'H':
+		return sess.w.Flush()
+
+	default:
+		sess.errorResponse("08P01", fmt.Sprintf("unsupported message type %q", typ))
+		sess.ready()
+		return sess.w.Flush()
+	}
+	return nil
+}
+
+// simpleQuery runs each statement of a simple query, stopping at the first error
+func (s *Server) simpleQuery(sess *session, query string) {
+	statements := splitStatements(query)
+	if len(statements) == 0 {
+		sess.send('I')
+		return
+	}
+	for _, stmt := range statements {
+		res, err := s.exec(sess, stmt, nil)
+		if err != nil {
+			sess.errorResponse(errorCode(err), err.Error())
+			return
+		}
+		sess.rowDescription(res.Columns, nil)
+		sess.dataRows(res, nil)
+		sess.send('C', cstring(res.Tag))
+	}
+}
+
+// exec runs a statement and tracks the transaction status
+func (s *Server) exec(sess *session, query string, params []string) (*Result, error) {
+	if sess.txStatus == 'E' {
+		if tag := txTag(query); tag != "COMMIT" && tag != "ROLLBACK" {
+			return nil, &SQLError{"25P02", "current transaction is aborted, commands ignored until end of transaction block"}
+		}
+	}
+
+	res, err := s.engine.Exec(sess.user, query, params)
+	if err != nil {
+		if sess.txStatus == 'T' {
+			sess.txStatus = 'E'
+		}
+		return nil, err
+	}
+
+	switch res.Tag {
+	case "BEGIN":
+		sess.txStatus = 'T'
+	case "COMMIT":
+		if sess.txStatus == 'E' {
+			res.Tag = "ROLLBACK"
+		}
+		sess.txStatus = 'I'
+	case "ROLLBACK":
+		sess.txStatus = 'I'
+	}
+	return res, nil
+}
+
+var txTagRe = regexp.MustCompile(`(?i)^\s*(COMMIT|END|ROLLBACK|ABORT)\b`)
+
+// txTag returns COMMIT or ROLLBACK for statements that end a transaction
+func txTag(query string) string {
+	m := txTagRe.FindStringSubmatch(query)
+	if m == nil {
+		return ""
+	}
+	switch m[1][0] | 0x20 {
+	case 'c', 'e':
+		return "COMMIT"
+	default:
+		return "ROLLBACK"
+	}
+}
+
+// parseBind decodes a Bind message into a named portal
+func parseBind(sess *session, body []byte) error {
+	r := &reader{buf: body}
+	portalName := r.cstring()
+	stmtName := r.cstring()
+	query, ok := sess.statements[stmtName]
+	if !ok {
+		return fmt.Errorf("prepared statement %q does not exist", stmtName)
+	}
+
+	// Parameter format codes are ignored: every parameter is declared as text, whose
+	// binary representation is the same bytes
+	for n := r.int16(); n > 0; n-- {
+		r.int16()
+	}
+
+	p := &portal{query: query}
+	for n := r.int16(); n > 0; n-- {
+		size := int32(r.uint32())
+		if size < 0 {
+			p.params = append(p.params, "")
+			continue
+		}
+		p.params = append(p.params, string(r.bytes(int(size))))
+	}
+	for n := r.int16(); n > 0; n-- {
+		p.resultFormats = append(p.resultFormats, r.int16())
+	}
+	if r.err != nil {
+		return fmt.Errorf("malformed bind message")
+	}
+
+	sess.portals[portalName] = p
+	return nil
+}
+
+// parameterDescription declares every $n placeholder in a query as text
+func parameterDescription(query string) []byte {
+	count := 0
+	for _, m := range paramRe.FindAllStringSubmatch(query, -1) {
+		if n, err := strconv.Atoi(m[1]); err == nil && n > count {
+			count = n
+		}
+	}
+	buf := uint16be(uint16(count))
+	for i := 0; i < count; i++ {
+		buf = append(buf, uint32be(textOID)...)
+	}
+	return buf
+}
+
+// formatFor returns the requested format code of a result column
+func formatFor(formats []int16, i int) int16 {
+	switch len(formats) {
+	case 0:
+		return 0
+	case 1:
+		return formats[0]
+	default:
+		if i < len(formats) {
+			return formats[i]
+		}
+		return 0
+	}
+}
+
+// rowDescription sends RowDescription, or NoData when there are no columns
+func (sess *session) rowDescription(columns []Column, formats []int16) {
+	if columns == nil {
+		sess.send('n')
+		return
+	}
+	buf := uint16be(uint16(len(columns)))
+	for i, col := range columns {
+		typ := columnTypes[col.Type]
+		buf = append(buf, cstring(col.Name)...)
+		buf = append(buf, uint32be(0)...)
+		buf = append(buf, uint16be(0)...)
+		buf = append(buf, uint32be(typ.oid)...)
+		buf = append(buf, uint16be(uint16(typ.size))...)
+		buf = append(buf, uint32be(0xffffffff)...)
+		buf = append(buf, uint16be(uint16(formatFor(formats, i)))...)
+	}
+	sess.send('T', buf)
+}
+
+// dataRows sends a DataRow per result row in the requested formats
+func (sess *session) dataRows(res *Result, formats []int16) {
+	for _, row := range res.Rows {
+		buf := uint16be(uint16(len(row)))
+		for i, value := range row {
+			data := []byte(value)
+			if formatFor(formats, i) == 1 && i < len(res.Columns) && res.Columns[i].Type == typeBool {
+				data = []byte{0}
+				if value == "t" {
+					data[0] = 1
+				}
+			}
+			buf = append(buf, uint32be(uint32(len(data)))...)
+			buf = append(buf, data...)
+		}
+		sess.send('D', buf)
+	}
+}
+
+// errorCode returns the SQLSTATE for an error
+func errorCode(err error) string {
+	var sqlErr *SQLError
+	if errors.As(err, &sqlErr) {
+		return sqlErr.Code
+	}
+	return "XX000"
+}
+
+// send buffers a backend message
+func (sess *session) send(typ byte, parts ...[]byte) {
+	size := 4
+	for _, part := range parts {
+		size += len(part)
+	}
+	sess.w.WriteByte(typ)
+	sess.w.Write(uint32be(uint32(size)))
+	for _, part := range parts {
+		sess.w.Write(part)
+	}
+}
+
+// ready sends ReadyForQuery with the transaction status
+func (sess *session) ready() {
+	sess.send('Z', []byte{sess.txStatus})
+}
+
+func (sess *session) errorWithSeverity(severity, code, message string) {
+	var buf []byte
+	for _, field := range []struct {
+		typ   byte
+		value string
+	}{{'S', severity}, {'V', severity}, {'C', code}, {'M', message}} {
+		buf = append(buf, field.typ)
+		buf = append(buf, cstring(field.value)...)
+	}
+	buf = append(buf, 0)
+	sess.send('E', buf)
+}
+
+// errorResponse sends an ERROR
+func (sess *session) errorResponse(code, message string) {
+	sess.errorWithSeverity("ERROR", code, message)
+}
+
+// extendedError sends an ERROR and discards messages until the next Sync
+func (sess *session) extendedError(code, message string) {
+	sess.errorResponse(code, message)
+	sess.skipToSync = true
+}
+
+// fatal sends a FATAL error; the connection is closed afterwards
+func (sess *session) fatal(code, message string) {
+	sess.errorWithSeverity("FATAL", code, message)
+}
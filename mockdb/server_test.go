@@ -0,0 +1,178 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package mockdb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	_ "github.com/jackc/pgx/v4/stdlib"
+)
+
+func startServer(t *testing.T) (*Server, string) {
+	t.Helper()
+
+	server := NewServer(NewEngine(nil), hclog.NewNullLogger())
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Serve(listener)
+	t.Cleanup(func() { server.Close() })
+	return server, listener.Addr().String()
+}
+
+func open(t *testing.T, addr, user, password string) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("pgx", fmt.Sprintf("postgres://%s:%s@%s/postgres?sslmode=disable", user, password, addr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestServerRoleLifecycle(t *testing.T) {
+	server, addr := startServer(t)
+	ctx := context.Background()
+	db := open(t, addr, "postgres", "postgres")
+
+	if err := db.PingContext(ctx); err != nil {
+		t.Fatalf("ping: %v", err)
+	}
+
+	// Credential creation as the postgresql plugin performs it
+	expiry := time.Now().Add(time.Hour).Format("2006-01-02 15:04:05-0700")
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, stmt := range []string{
+		fmt.Sprintf(`CREATE ROLE "v-token-app-abc" WITH LOGIN PASSWORD 's3cr;et' VALID UNTIL '%s'`, expiry),
+		`GRANT SELECT ON ALL TABLES IN SCHEMA public TO "v-token-app-abc"`,
+	} {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The issued credentials work
+	if err := open(t, addr, "v-token-app-abc", "s3cr;et").PingContext(ctx); err != nil {
+		t.Fatalf("login with issued credentials: %v", err)
+	}
+	if err := open(t, addr, "v-token-app-abc", "wrong").PingContext(ctx); err == nil {
+		t.Fatal("login with wrong password succeeded")
+	}
+
+	var exists bool
+	if err := db.QueryRowContext(ctx, "SELECT exists (SELECT rolname FROM pg_roles WHERE rolname=$1);", "v-token-app-abc").Scan(&exists); err != nil {
+		t.Fatal(err)
+	}
+	if !exists {
+		t.Fatal("expected role to exist")
+	}
+
+	// Catalog lookups made during revocation return no rows
+	rows, err := db.QueryContext(ctx, "SELECT DISTINCT table_schema FROM information_schema.role_column_grants WHERE grantee=$1;", "v-token-app-abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rows.Next() {
+		t.Fatal("expected no grants")
+	}
+	rows.Close()
+
+	if _, err := db.ExecContext(ctx, `REVOKE ALL PRIVILEGES ON ALL TABLES IN SCHEMA public FROM "v-token-app-abc"; DROP ROLE IF EXISTS "v-token-app-abc";`); err != nil {
+		t.Fatalf("revoke: %v", err)
+	}
+	if err := db.QueryRowContext(ctx, "SELECT exists (SELECT rolname FROM pg_roles WHERE rolname=$1);", "v-token-app-abc").Scan(&exists); err != nil {
+		t.Fatal(err)
+	}
+	if exists {
+		t.Fatal("expected role to be dropped")
+	}
+
+	roles := server.Engine().Roles()
+	if len(roles) != 1 || roles[0].Name != "postgres" {
+		t.Fatalf("unexpected roles after revoke: %+v", roles)
+	}
+	if len(server.Engine().Statements()) == 0 {
+		t.Fatal("expected statements to be recorded")
+	}
+}
+
+func TestServerErrors(t *testing.T) {
+	_, addr := startServer(t)
+	ctx := context.Background()
+	db := open(t, addr, "postgres", "postgres")
+
+	if _, err := db.ExecContext(ctx, `DROP ROLE "missing"`); err == nil {
+		t.Fatal("expected dropping a missing role to fail")
+	}
+	if _, err := db.ExecContext(ctx, `CREATE ROLE "postgres"`); err == nil {
+		t.Fatal("expected creating a duplicate role to fail")
+	}
+
+	// A failed statement aborts the transaction until rollback
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.ExecContext(ctx, `ALTER ROLE "missing" PASSWORD 'x'`); err == nil {
+		t.Fatal("expected altering a missing role to fail")
+	}
+	if _, err := tx.ExecContext(ctx, `CREATE ROLE "other"`); err == nil {
+		t.Fatal("expected statements in an aborted transaction to fail")
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.PingContext(ctx); err != nil {
+		t.Fatalf("connection unusable after rollback: %v", err)
+	}
+}
+
+func TestEngineExpiry(t *testing.T) {
+	engine := NewEngine(&Fixtures{Roles: []Role{{Name: "admin", Password: "pw", Login: true}}})
+
+	if _, err := engine.Exec("admin", `CREATE USER old PASSWORD 'pw' VALID UNTIL '2000-01-01 00:00:00+0000'`, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.Authenticate("old", "pw"); err == nil {
+		t.Fatal("expected expired role to be rejected")
+	}
+
+	if _, err := engine.Exec("admin", `ALTER USER old VALID UNTIL 'infinity'`, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.Authenticate("old", "pw"); err != nil {
+		t.Fatalf("expected renewed role to authenticate: %v", err)
+	}
+
+	if _, err := engine.Exec("admin", `CREATE ROLE nologin_role PASSWORD 'LOGIN'`, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.Authenticate("nologin_role", "LOGIN"); err == nil {
+		t.Fatal("expected role without LOGIN to be rejected")
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	got := splitStatements("-- ping\nCREATE ROLE \"a;b\" PASSWORD 'c;d'; ;GRANT x TO \"a;b\"")
+	if len(got) != 2 || got[0] != `CREATE ROLE "a;b" PASSWORD 'c;d'` || got[1] != `GRANT x TO "a;b"` {
+		t.Fatalf("unexpected split: %q", got)
+	}
+	if got := splitStatements("-- ping"); len(got) != 0 {
+		t.Fatalf("expected comment-only query to be empty, got %q", got)
+	}
+}
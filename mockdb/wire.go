@@ -0,0 +1,122 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package mockdb
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// readMessage reads a typed frontend message
+func readMessage(r *bufio.Reader) (byte, []byte, error) {
+	typ, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	var header [4]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, nil, err
+	}
+	length := binary.BigEndian.Uint32(header[:])
+	if length < 4 || length > maxMessageSize {
+		return 0, nil, fmt.Errorf("invalid message length %d", length)
+	}
+	body := make([]byte, length-4)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return 0, nil, err
+	}
+	return typ, body, nil
+}
+
+// splitCStrings splits a sequence of null-terminated strings
+func splitCStrings(b []byte) []string {
+	var fields []string
+	for len(b) > 0 {
+		i := bytes.IndexByte(b, 0)
+		if i < 0 {
+			break
+		}
+		if i == 0 && len(fields)%2 == 0 && len(b) == 1 {
+			break // trailing terminator of a key/value list
+		}
+		fields = append(fields, string(b[:i]))
+		b = b[i+1:]
+	}
+	return fields
+}
+
+// firstCString returns the null-terminated string at the start of b
+func firstCString(b []byte) string {
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		return string(b[:i])
+	}
+	return string(b)
+}
+
+func cstring(s string) []byte {
+	return append([]byte(s), 0)
+}
+
+func uint32be(v uint32) []byte {
+	return binary.BigEndian.AppendUint32(nil, v)
+}
+
+func uint16be(v uint16) []byte {
+	return binary.BigEndian.AppendUint16(nil, v)
+}
+
+var errShortMessage = errors.New("message too short")
+
+// reader decodes message bodies, recording the first error instead of returning it from every call
+type reader struct {
+	buf []byte
+	err error
+}
+
+func (r *reader) bytes(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || n > len(r.buf) {
+		r.err = errShortMessage
+		return nil
+	}
+	b := r.buf[:n]
+	r.buf = r.buf[n:]
+	return b
+}
+
+func (r *reader) int16() int16 {
+	b := r.bytes(2)
+	if b == nil {
+		return 0
+	}
+	return int16(binary.BigEndian.Uint16(b))
+}
+
+func (r *reader) uint32() uint32 {
+	b := r.bytes(4)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint32(b)
+}
+
+func (r *reader) cstring() string {
+	if r.err != nil {
+		return ""
+	}
+	i := bytes.IndexByte(r.buf, 0)
+	if i < 0 {
+		r.err = errShortMessage
+		return ""
+	}
+	s := string(r.buf[:i])
+	r.buf = r.buf[i+1:]
+	return s
+}
@@ -0,0 +1,381 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package mockidp implements a minimal OAuth2/OIDC identity provider so auth plugins
+// that validate JWTs can be exercised offline.
+package mockidp
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultAudience is used when a token request does not name a client
+	DefaultAudience = "vault"
+	// DefaultSubject is used when a token request does not name a subject
+	DefaultSubject = "test-user"
+	// DefaultTTL is the lifetime of issued tokens
+	DefaultTTL = time.Hour
+)
+
+// authCode is a pending authorization code from the authorize endpoint
+type authCode struct {
+	clientID string
+	nonce    string
+	expires  time.Time
+}
+
+// Provider is a mock OIDC provider serving discovery, JWKS, authorize, token and userinfo endpoints
+type Provider struct {
+	issuer string
+	key    *rsa.PrivateKey
+	keyID  string
+
+	mu     sync.Mutex
+	claims map[string]interface{} // extra claims added to every token
+	codes  map[string]authCode
+}
+
+// New creates a provider with a fresh signing key. issuer is the externally visible
+// base URL of the provider, e.g. http://localhost:8300/mock-idp
+func New(issuer string) (*Provider, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate signing key: %w", err)
+	}
+
+	sum := sha256.Sum256(key.PublicKey.N.Bytes())
+	return &Provider{
+		issuer: strings.TrimSuffix(issuer, "/"),
+		key:    key,
+		keyID:  hex.EncodeToString(sum[:8]),
+		claims: make(map[string]interface{}),
+		codes:  make(map[string]authCode),
+	}, nil
+}
+
+// Issuer returns the issuer URL placed in tokens and the discovery document
+func (p *Provider) Issuer() string {
+	return p.issuer
+}
+
+// PublicKey returns the key that verifies issued tokens
+func (p *Provider) PublicKey() *rsa.PublicKey {
+	return &p.key.PublicKey
+}
+
+// SetClaims replaces the extra claims added to every issued token
+func (p *Provider) SetClaims(claims map[string]interface{}) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.claims = make(map[string]interface{}, len(claims))
+	for k, v := range claims {
+		p.claims[k] = v
+	}
+}
+
+// Claims returns the extra claims added to every issued token
+func (p *Provider) Claims() map[string]interface{} {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	claims := make(map[string]interface{}, len(p.claims))
+	for k, v := range p.claims {
+		claims[k] = v
+	}
+	return claims
+}
+
+// IssueToken signs an RS256 JWT. Standard claims are filled in from the provider,
+// then the configured claims and finally overrides are applied on top.
+func (p *Provider) IssueToken(audience, subject string, ttl time.Duration, overrides map[string]interface{}) (string, error) {
+	if audience == "" {
+		audience = DefaultAudience
+	}
+	if subject == "" {
+		subject = DefaultSubject
+	}
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+
+	now := time.Now()
+	claims := map[string]interface{}{
+		"iss": p.issuer,
+		"aud": audience,
+		"sub": subject,
+		"iat": now.Unix(),
+		"nbf": now.Unix(),
+		"exp": now.Add(ttl).Unix(),
+	}
+	for k, v := range p.Claims() {
+		claims[k] = v
+	}
+	for k, v := range overrides {
+		claims[k] = v
+	}
+
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": p.keyID})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := b64(header) + "." + b64(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, p.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return signingInput + "." + b64(signature), nil
+}
+
+// b64 is unpadded base64url as used by JOSE
+func b64(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// ServeHTTP serves the provider endpoints relative to the mount prefix stripped by the caller:
+//
+//	GET      /.well-known/openid-configuration - discovery document
+//	GET      /keys                             - JWKS
+//	GET      /authorize                        - authorization code flow (redirects immediately)
+//	POST     /token                            - token endpoint
+//	GET      /userinfo                         - claims of the bearer token's subject
+//	GET, PUT /claims                           - extra claims added to every token
+func (p *Provider) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch strings.TrimSuffix(r.URL.Path, "/") {
+	case "/.well-known/openid-configuration":
+		p.handleDiscovery(w, r)
+	case "/keys":
+		p.handleKeys(w, r)
+	case "/authorize":
+		p.handleAuthorize(w, r)
+	case "/token":
+		p.handleToken(w, r)
+	case "/userinfo":
+		p.handleUserinfo(w, r)
+	case "/claims":
+		p.handleClaims(w, r)
+	default:
+		writeError(w, http.StatusNotFound, "not_found", fmt.Sprintf("no mock IdP endpoint at %s", r.URL.Path))
+	}
+}
+
+func (p *Provider) handleDiscovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                p.issuer,
+		"authorization_endpoint":                p.issuer + "/authorize",
+		"token_endpoint":                        p.issuer + "/token",
+		"userinfo_endpoint":                     p.issuer + "/userinfo",
+		"jwks_uri":                              p.issuer + "/keys",
+		"response_types_supported":              []string{"code", "id_token"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"grant_types_supported":                 []string{"authorization_code", "client_credentials"},
+		"scopes_supported":                      []string{"openid", "profile", "email", "groups"},
+		"token_endpoint_auth_methods_supported": []string{"client_secret_basic", "client_secret_post", "none"},
+	})
+}
+
+func (p *Provider) handleKeys(w http.ResponseWriter, r *http.Request) {
+	pub := p.key.PublicKey
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"kid": p.keyID,
+			"n":   b64(pub.N.Bytes()),
+			"e":   b64(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	})
+}
+
+// handleAuthorize approves every request and redirects straight back with a code
+func (p *Provider) handleAuthorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	redirectURI, err := url.Parse(query.Get("redirect_uri"))
+	if err != nil || redirectURI.String() == "" {
+		writeError(w, http.StatusBadRequest, "invalid_request", "redirect_uri is required")
+		return
+	}
+
+	code := randomString()
+	p.mu.Lock()
+	p.codes[code] = authCode{
+		clientID: query.Get("client_id"),
+		nonce:    query.Get("nonce"),
+		expires:  time.Now().Add(5 * time.Minute),
+	}
+	p.mu.Unlock()
+
+	values := redirectURI.Query()
+	values.Set("code", code)
+	if state := query.Get("state"); state != "" {
+		values.Set("state", state)
+	}
+	redirectURI.RawQuery = values.Encode()
+	http.Redirect(w, r, redirectURI.String(), http.StatusFound)
+}
+
+// tokenRequest is the JSON form of a token request with explicit claims
+type tokenRequest struct {
+	Audience string                 `json:"audience"`
+	Subject  string                 `json:"subject"`
+	TTL      string                 `json:"ttl"`
+	Claims   map[string]interface{} `json:"claims"`
+}
+
+// handleToken accepts standard form-encoded OAuth2 requests (authorization_code and
+// client_credentials) as well as a JSON body naming the claims to issue
+func (p *Provider) handleToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "invalid_request", "method not allowed")
+		return
+	}
+
+	var req tokenRequest
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_request", fmt.Sprintf("failed to parse JSON: %v", err))
+			return
+		}
+	} else {
+		if err := r.ParseForm(); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_request", err.Error())
+			return
+		}
+
+		clientID := r.PostForm.Get("client_id")
+		if user, _, ok := r.BasicAuth(); ok {
+			clientID = user
+		}
+		req.Audience = clientID
+		req.Subject = clientID
+
+		switch grant := r.PostForm.Get("grant_type"); grant {
+		case "client_credentials":
+		case "authorization_code":
+			p.mu.Lock()
+			code, ok := p.codes[r.PostForm.Get("code")]
+			delete(p.codes, r.PostForm.Get("code"))
+			p.mu.Unlock()
+
+			if !ok || time.Now().After(code.expires) {
+				writeError(w, http.StatusBadRequest, "invalid_grant", "unknown or expired code")
+				return
+			}
+			req.Subject = ""
+			if code.clientID != "" {
+				req.Audience = code.clientID
+			}
+			if code.nonce != "" {
+				req.Claims = map[string]interface{}{"nonce": code.nonce}
+			}
+		default:
+			writeError(w, http.StatusBadRequest, "unsupported_grant_type", fmt.Sprintf("unsupported grant_type %q", grant))
+			return
+		}
+	}
+
+	ttl := DefaultTTL
+	if req.TTL != "" {
+		parsed, err := time.ParseDuration(req.TTL)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_request", fmt.Sprintf("invalid ttl %q", req.TTL))
+			return
+		}
+		ttl = parsed
+	}
+
+	token, err := p.IssueToken(req.Audience, req.Subject, ttl, req.Claims)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": token,
+		"id_token":     token,
+		"token_type":   "Bearer",
+		"expires_in":   int(ttl.Seconds()),
+	})
+}
+
+// handleUserinfo returns the claims of the presented bearer token without verifying it again
+func (p *Provider) handleUserinfo(w http.ResponseWriter, r *http.Request) {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		writeError(w, http.StatusUnauthorized, "invalid_token", "bearer token required")
+		return
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "invalid_token", "malformed token")
+		return
+	}
+	var claims map[string]interface{}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		writeError(w, http.StatusUnauthorized, "invalid_token", "malformed token")
+		return
+	}
+	writeJSON(w, http.StatusOK, claims)
+}
+
+func (p *Provider) handleClaims(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, p.Claims())
+	case http.MethodPut, http.MethodPost:
+		var claims map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&claims); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_request", fmt.Sprintf("failed to parse JSON: %v", err))
+			return
+		}
+		p.SetClaims(claims)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "invalid_request", "method not allowed")
+	}
+}
+
+// randomString returns a random URL-safe identifier
+func randomString() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return b64(b)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes an OAuth2 style error response
+func writeError(w http.ResponseWriter, status int, code, description string) {
+	writeJSON(w, status, map[string]string{
+		"error":             code,
+		"error_description": description,
+	})
+}
@@ -0,0 +1,145 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package mockidp
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// verifyToken checks an RS256 token against a JWKS key and returns its claims
+func verifyToken(t *testing.T, token string, jwk map[string]string) map[string]interface{} {
+	t.Helper()
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		t.Fatalf("token has %d parts", len(parts))
+	}
+
+	n, _ := base64.RawURLEncoding.DecodeString(jwk["n"])
+	e, _ := base64.RawURLEncoding.DecodeString(jwk["e"])
+	pub := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+
+	signature, _ := base64.RawURLEncoding.DecodeString(parts[2])
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], signature); err != nil {
+		t.Fatalf("signature does not verify against JWKS: %v", err)
+	}
+
+	payload, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	var claims map[string]interface{}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		t.Fatalf("invalid payload: %v", err)
+	}
+	return claims
+}
+
+func newTestProvider(t *testing.T) (*Provider, map[string]string) {
+	t.Helper()
+
+	p, err := New("http://localhost:8300/mock-idp/")
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	p.ServeHTTP(w, httptest.NewRequest("GET", "/keys", nil))
+	var jwks struct {
+		Keys []map[string]string `json:"keys"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&jwks); err != nil || len(jwks.Keys) != 1 {
+		t.Fatalf("bad JWKS: %v %s", err, w.Body.String())
+	}
+	return p, jwks.Keys[0]
+}
+
+func TestDiscovery(t *testing.T) {
+	p, _ := newTestProvider(t)
+
+	w := httptest.NewRecorder()
+	p.ServeHTTP(w, httptest.NewRequest("GET", "/.well-known/openid-configuration", nil))
+
+	var doc map[string]interface{}
+	json.NewDecoder(w.Body).Decode(&doc)
+	if doc["issuer"] != "http://localhost:8300/mock-idp" || doc["jwks_uri"] != "http://localhost:8300/mock-idp/keys" {
+		t.Errorf("discovery = %v", doc)
+	}
+}
+
+func TestTokenWithConfiguredClaims(t *testing.T) {
+	p, jwk := newTestProvider(t)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("PUT", "/claims", strings.NewReader(`{"groups": ["admins"], "email": "a@example.com"}`))
+	p.ServeHTTP(w, req)
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("PUT /claims status = %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest("POST", "/token", strings.NewReader(`{"audience": "my-role", "subject": "alice", "claims": {"email": "alice@example.com"}}`))
+	req.Header.Set("Content-Type", "application/json")
+	p.ServeHTTP(w, req)
+
+	var resp map[string]interface{}
+	json.NewDecoder(w.Body).Decode(&resp)
+	claims := verifyToken(t, resp["id_token"].(string), jwk)
+
+	if claims["iss"] != "http://localhost:8300/mock-idp" || claims["aud"] != "my-role" || claims["sub"] != "alice" {
+		t.Errorf("standard claims = %v", claims)
+	}
+	if claims["email"] != "alice@example.com" {
+		t.Errorf("override not applied: %v", claims["email"])
+	}
+	if groups, _ := claims["groups"].([]interface{}); len(groups) != 1 || groups[0] != "admins" {
+		t.Errorf("configured claim not applied: %v", claims["groups"])
+	}
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	p, jwk := newTestProvider(t)
+
+	w := httptest.NewRecorder()
+	p.ServeHTTP(w, httptest.NewRequest("GET", "/authorize?client_id=vault&state=xyz&nonce=n-1&redirect_uri="+
+		url.QueryEscape("http://localhost:8250/oidc/callback"), nil))
+	if w.Code != http.StatusFound {
+		t.Fatalf("authorize status = %d", w.Code)
+	}
+
+	location, _ := url.Parse(w.Header().Get("Location"))
+	if location.Query().Get("state") != "xyz" || location.Query().Get("code") == "" {
+		t.Fatalf("redirect = %s", location)
+	}
+
+	form := url.Values{"grant_type": {"authorization_code"}, "code": {location.Query().Get("code")}}
+	exchange := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/token", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		p.ServeHTTP(w, req)
+		return w
+	}
+
+	w = exchange()
+	var resp map[string]interface{}
+	json.NewDecoder(w.Body).Decode(&resp)
+	claims := verifyToken(t, resp["id_token"].(string), jwk)
+	if claims["nonce"] != "n-1" || claims["aud"] != "vault" || claims["sub"] != DefaultSubject {
+		t.Errorf("claims = %v", claims)
+	}
+
+	// Codes are single use
+	if w := exchange(); w.Code != http.StatusBadRequest {
+		t.Errorf("reused code status = %d, want 400", w.Code)
+	}
+}
@@ -0,0 +1,222 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package mockldap
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// BER tag classes and flags
+const (
+	classUniversal   = 0x00
+	classApplication = 0x40
+	classContext     = 0x80
+	constructed      = 0x20
+)
+
+// Universal tags used by LDAP
+const (
+	tagBoolean     = 0x01
+	tagInteger     = 0x02
+	tagOctetString = 0x04
+	tagEnumerated  = 0x0a
+	tagSequence    = 0x10 | constructed
+	tagSet         = 0x11 | constructed
+)
+
+// maxMessageSize bounds a single incoming LDAP message
+const maxMessageSize = 16 << 20
+
+// packet is a decoded BER element. Constructed elements have children, primitive ones a value.
+type packet struct {
+	tag      byte
+	value    []byte
+	children []*packet
+}
+
+func (p *packet) constructed() bool {
+	return p.tag&constructed != 0
+}
+
+// child returns the i-th child or an error if it does not exist
+func (p *packet) child(i int) (*packet, error) {
+	if i >= len(p.children) {
+		return nil, fmt.Errorf("ber: element 0x%02x has %d children, want at least %d", p.tag, len(p.children), i+1)
+	}
+	return p.children[i], nil
+}
+
+// str returns the value as a string
+func (p *packet) str() string {
+	return string(p.value)
+}
+
+// int returns the value as a signed integer
+func (p *packet) int() int64 {
+	var n int64
+	for i, b := range p.value {
+		if i == 0 && b&0x80 != 0 {
+			n = -1
+		}
+		n = n<<8 | int64(b)
+	}
+	return n
+}
+
+// readPacket reads one BER element
+func readPacket(r *bufio.Reader) (*packet, error) {
+	tag, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	if tag&0x1f == 0x1f {
+		return nil, errors.New("ber: multi-byte tags are not supported")
+	}
+
+	length, err := readLength(r)
+	if err != nil {
+		return nil, err
+	}
+	if length > maxMessageSize {
+		return nil, fmt.Errorf("ber: element of %d bytes exceeds limit", length)
+	}
+
+	value := make([]byte, length)
+	if _, err := io.ReadFull(r, value); err != nil {
+		return nil, err
+	}
+	return decode(tag, value)
+}
+
+// readLength reads a definite BER length
+func readLength(r *bufio.Reader) (int, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	if b&0x80 == 0 {
+		return int(b), nil
+	}
+
+	n := int(b & 0x7f)
+	if n == 0 || n > 4 {
+		return 0, errors.New("ber: unsupported length encoding")
+	}
+	length := 0
+	for i := 0; i < n; i++ {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		length = length<<8 | int(b)
+	}
+	return length, nil
+}
+
+// decode parses the contents of an element, recursing into constructed ones
+func decode(tag byte, value []byte) (*packet, error) {
+	p := &packet{tag: tag}
+	if !p.constructed() {
+		p.value = value
+		return p, nil
+	}
+
+	for len(value) > 0 {
+		childTag := value[0]
+		if childTag&0x1f == 0x1f {
+			return nil, errors.New("ber: multi-byte tags are not supported")
+		}
+		length, header, err := parseLength(value[1:])
+		if err != nil {
+			return nil, err
+		}
+		start := 1 + header
+		if start+length > len(value) {
+			return nil, errors.New("ber: element overruns its parent")
+		}
+
+		child, err := decode(childTag, value[start:start+length])
+		if err != nil {
+			return nil, err
+		}
+		p.children = append(p.children, child)
+		value = value[start+length:]
+	}
+	return p, nil
+}
+
+// parseLength decodes a length from a byte slice and returns it with the number of bytes used
+func parseLength(b []byte) (int, int, error) {
+	if len(b) == 0 {
+		return 0, 0, errors.New("ber: truncated length")
+	}
+	if b[0]&0x80 == 0 {
+		return int(b[0]), 1, nil
+	}
+
+	n := int(b[0] & 0x7f)
+	if n == 0 || n > 4 || len(b) < 1+n {
+		return 0, 0, errors.New("ber: unsupported length encoding")
+	}
+	length := 0
+	for _, c := range b[1 : 1+n] {
+		length = length<<8 | int(c)
+	}
+	return length, 1 + n, nil
+}
+
+// encode serialises a packet
+func (p *packet) encode() []byte {
+	body := p.value
+	if p.constructed() {
+		body = nil
+		for _, child := range p.children {
+			body = append(body, child.encode()...)
+		}
+	}
+
+	out := []byte{p.tag}
+	out = append(out, encodeLength(len(body))...)
+	return append(out, body...)
+}
+
+func encodeLength(n int) []byte {
+	if n < 0x80 {
+		return []byte{byte(n)}
+	}
+	var b []byte
+	for ; n > 0; n >>= 8 {
+		b = append([]byte{byte(n)}, b...)
+	}
+	return append([]byte{0x80 | byte(len(b))}, b...)
+}
+
+// Constructors for the element types LDAP responses use
+
+func newConstructed(tag byte, children ...*packet) *packet {
+	return &packet{tag: tag | constructed, children: children}
+}
+
+func newString(tag byte, s string) *packet {
+	return &packet{tag: tag, value: []byte(s)}
+}
+
+func newInt(tag byte, n int64) *packet {
+	var b []byte
+	for {
+		b = append([]byte{byte(n)}, b...)
+		if (n >= -128 && n < 128) || len(b) == 8 {
+			break
+		}
+		n >>= 8
+	}
+	// Keep the sign bit consistent with the value
+	for len(b) > 1 && ((b[0] == 0 && b[1]&0x80 == 0) || (b[0] == 0xff && b[1]&0x80 != 0)) {
+		b = b[1:]
+	}
+	return &packet{tag: tag, value: b}
+}
@@ -0,0 +1,308 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package mockldap
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Entry is a directory object
+type Entry struct {
+	DN         string              `json:"dn"`
+	Attributes map[string][]string `json:"attributes"`
+}
+
+// get returns the values of an attribute, matching its name case-insensitively
+func (e *Entry) get(name string) []string {
+	for attr, values := range e.Attributes {
+		if strings.EqualFold(attr, name) {
+			return values
+		}
+	}
+	return nil
+}
+
+// set replaces the values of an attribute, keeping the existing spelling of its name
+func (e *Entry) set(name string, values []string) {
+	for attr := range e.Attributes {
+		if strings.EqualFold(attr, name) {
+			name = attr
+			break
+		}
+	}
+	if len(values) == 0 {
+		delete(e.Attributes, name)
+		return
+	}
+	e.Attributes[name] = values
+}
+
+// clone returns a deep copy of the entry
+func (e *Entry) clone() *Entry {
+	c := &Entry{DN: e.DN, Attributes: make(map[string][]string, len(e.Attributes))}
+	for k, v := range e.Attributes {
+		c.Attributes[k] = append([]string(nil), v...)
+	}
+	return c
+}
+
+// User is a convenience fixture that expands to an inetOrgPerson entry
+type User struct {
+	Username   string              `json:"username"`
+	Password   string              `json:"password"`
+	Groups     []string            `json:"groups"`
+	Attributes map[string][]string `json:"attributes"`
+}
+
+// Group is a convenience fixture that expands to a groupOfNames entry
+type Group struct {
+	Name       string              `json:"name"`
+	Attributes map[string][]string `json:"attributes"`
+}
+
+// Fixtures describes the initial directory contents
+type Fixtures struct {
+	BaseDN  string   `json:"base_dn"`
+	Users   []User   `json:"users"`
+	Groups  []Group  `json:"groups"`
+	Entries []*Entry `json:"entries"`
+}
+
+// LoadFixtures reads fixtures from a JSON file
+func LoadFixtures(path string) (*Fixtures, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var fixtures Fixtures
+	if err := json.Unmarshal(data, &fixtures); err != nil {
+		return nil, fmt.Errorf("failed to parse LDAP fixtures: %w", err)
+	}
+	return &fixtures, nil
+}
+
+// Directory is an in-memory set of entries keyed by normalised DN
+type Directory struct {
+	mu      sync.RWMutex
+	entries map[string]*Entry
+}
+
+// NewDirectory builds a directory from fixtures. Users are created under ou=users and groups
+// under ou=groups of the base DN, with member, memberUid and memberOf kept in sync.
+func NewDirectory(fixtures *Fixtures) (*Directory, error) {
+	d := &Directory{entries: make(map[string]*Entry)}
+	if fixtures == nil {
+		fixtures = &Fixtures{}
+	}
+
+	base := fixtures.BaseDN
+	if base == "" {
+		base = "dc=example,dc=com"
+	}
+	usersDN := "ou=users," + base
+	groupsDN := "ou=groups," + base
+
+	rdn := strings.SplitN(strings.SplitN(base, ",", 2)[0], "=", 2)
+	baseEntry := &Entry{DN: base, Attributes: map[string][]string{"objectClass": {"top", "domain"}}}
+	if len(rdn) == 2 {
+		baseEntry.Attributes[rdn[0]] = []string{rdn[1]}
+	}
+	d.put(baseEntry)
+	d.put(&Entry{DN: usersDN, Attributes: map[string][]string{"objectClass": {"top", "organizationalUnit"}, "ou": {"users"}}})
+	d.put(&Entry{DN: groupsDN, Attributes: map[string][]string{"objectClass": {"top", "organizationalUnit"}, "ou": {"groups"}}})
+
+	groups := make(map[string]*Entry)
+	groupEntry := func(name string) *Entry {
+		if g, ok := groups[strings.ToLower(name)]; ok {
+			return g
+		}
+		g := &Entry{
+			DN: "cn=" + name + "," + groupsDN,
+			Attributes: map[string][]string{
+				"objectClass": {"top", "groupOfNames", "posixGroup"},
+				"cn":          {name},
+			},
+		}
+		groups[strings.ToLower(name)] = g
+		return g
+	}
+
+	for _, group := range fixtures.Groups {
+		if group.Name == "" {
+			return nil, fmt.Errorf("group fixture without a name")
+		}
+		g := groupEntry(group.Name)
+		for k, v := range group.Attributes {
+			g.Attributes[k] = v
+		}
+	}
+
+	for _, user := range fixtures.Users {
+		if user.Username == "" {
+			return nil, fmt.Errorf("user fixture without a username")
+		}
+		dn := "cn=" + user.Username + "," + usersDN
+		entry := &Entry{
+			DN: dn,
+			Attributes: map[string][]string{
+				"objectClass": {"top", "person", "organizationalPerson", "inetOrgPerson"},
+				"cn":          {user.Username},
+				"uid":         {user.Username},
+				"sn":          {user.Username},
+			},
+		}
+		if user.Password != "" {
+			entry.Attributes["userPassword"] = []string{user.Password}
+		}
+		for _, name := range user.Groups {
+			g := groupEntry(name)
+			g.Attributes["member"] = append(g.Attributes["member"], dn)
+			g.Attributes["memberUid"] = append(g.Attributes["memberUid"], user.Username)
+			entry.Attributes["memberOf"] = append(entry.Attributes["memberOf"], g.DN)
+		}
+		for k, v := range user.Attributes {
+			entry.Attributes[k] = v
+		}
+		d.put(entry)
+	}
+
+	for _, g := range groups {
+		d.put(g)
+	}
+	for _, entry := range fixtures.Entries {
+		if entry.DN == "" {
+			return nil, fmt.Errorf("entry fixture without a dn")
+		}
+		if entry.Attributes == nil {
+			entry.Attributes = make(map[string][]string)
+		}
+		d.put(entry)
+	}
+	return d, nil
+}
+
+// normalizeDN lowercases a DN and removes spaces around separators for comparisons
+func normalizeDN(dn string) string {
+	parts := strings.Split(dn, ",")
+	for i, part := range parts {
+		kv := strings.SplitN(part, "=", 2)
+		for j := range kv {
+			kv[j] = strings.TrimSpace(kv[j])
+		}
+		parts[i] = strings.Join(kv, "=")
+	}
+	return strings.ToLower(strings.Join(parts, ","))
+}
+
+// put stores an entry without locking; used while building the directory
+func (d *Directory) put(entry *Entry) {
+	d.entries[normalizeDN(entry.DN)] = entry
+}
+
+// Get returns a copy of the entry with the given DN
+func (d *Directory) Get(dn string) (*Entry, bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	entry, ok := d.entries[normalizeDN(dn)]
+	if !ok {
+		return nil, false
+	}
+	return entry.clone(), true
+}
+
+// Add creates an entry; it fails if the DN already exists
+func (d *Directory) Add(entry *Entry) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	key := normalizeDN(entry.DN)
+	if _, exists := d.entries[key]; exists {
+		return errAlreadyExists
+	}
+	d.entries[key] = entry.clone()
+	return nil
+}
+
+// Delete removes an entry
+func (d *Directory) Delete(dn string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	key := normalizeDN(dn)
+	if _, exists := d.entries[key]; !exists {
+		return errNoSuchObject
+	}
+	delete(d.entries, key)
+	return nil
+}
+
+// Modify applies fn to an entry under the directory lock
+func (d *Directory) Modify(dn string, fn func(entry *Entry) error) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	entry, exists := d.entries[normalizeDN(dn)]
+	if !exists {
+		return errNoSuchObject
+	}
+	updated := entry.clone()
+	if err := fn(updated); err != nil {
+		return err
+	}
+	d.entries[normalizeDN(dn)] = updated
+	return nil
+}
+
+// Search scopes
+const (
+	scopeBase    = 0
+	scopeOne     = 1
+	scopeSubtree = 2
+)
+
+// search returns copies of entries under base matching the scope and filter, sorted by DN
+func (d *Directory) search(base string, scope int64, match func(*Entry) bool) ([]*Entry, bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	baseKey := normalizeDN(base)
+	if _, exists := d.entries[baseKey]; !exists && baseKey != "" {
+		return nil, false
+	}
+
+	var results []*Entry
+	for key, entry := range d.entries {
+		if !inScope(key, baseKey, scope) {
+			continue
+		}
+		if match(entry) {
+			results = append(results, entry.clone())
+		}
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].DN < results[j].DN })
+	return results, true
+}
+
+// inScope reports whether dn falls within the search scope rooted at base
+func inScope(dn, base string, scope int64) bool {
+	switch scope {
+	case scopeBase:
+		return dn == base
+	case scopeOne:
+		parent := ""
+		if i := strings.Index(dn, ","); i >= 0 {
+			parent = dn[i+1:]
+		}
+		return parent == base
+	default:
+		return base == "" || dn == base || strings.HasSuffix(dn, ","+base)
+	}
+}
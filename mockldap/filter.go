@@ -0,0 +1,146 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package mockldap
+
+import "strings"
+
+// Search filter choices (RFC 4511 section 4.5.1)
+const (
+	filterAnd            = classContext | constructed | 0
+	filterOr             = classContext | constructed | 1
+	filterNot            = classContext | constructed | 2
+	filterEquality       = classContext | constructed | 3
+	filterSubstrings     = classContext | constructed | 4
+	filterGreaterOrEqual = classContext | constructed | 5
+	filterLessOrEqual    = classContext | constructed | 6
+	filterPresent        = classContext | 7
+	filterApprox         = classContext | constructed | 8
+)
+
+// Substring filter parts
+const (
+	substringInitial = classContext | 0
+	substringAny     = classContext | 1
+	substringFinal   = classContext | 2
+)
+
+// matchFilter evaluates a BER-encoded search filter against an entry.
+// Values are compared case-insensitively, as with caseIgnoreMatch.
+func matchFilter(entry *Entry, filter *packet) bool {
+	switch filter.tag {
+	case filterAnd:
+		for _, child := range filter.children {
+			if !matchFilter(entry, child) {
+				return false
+			}
+		}
+		return true
+
+	case filterOr:
+		for _, child := range filter.children {
+			if matchFilter(entry, child) {
+				return true
+			}
+		}
+		return false
+
+	case filterNot:
+		return len(filter.children) == 1 && !matchFilter(entry, filter.children[0])
+
+	case filterPresent:
+		if strings.EqualFold(filter.str(), "objectClass") {
+			return true
+		}
+		return len(entry.get(filter.str())) > 0
+
+	case filterEquality, filterApprox:
+		return matchValues(entry, filter, func(value, assertion string) bool {
+			return value == assertion
+		})
+
+	case filterGreaterOrEqual:
+		return matchValues(entry, filter, func(value, assertion string) bool {
+			return value >= assertion
+		})
+
+	case filterLessOrEqual:
+		return matchValues(entry, filter, func(value, assertion string) bool {
+			return value <= assertion
+		})
+
+	case filterSubstrings:
+		if len(filter.children) < 2 {
+			return false
+		}
+		for _, value := range entry.get(filter.children[0].str()) {
+			if matchSubstrings(strings.ToLower(value), filter.children[1].children) {
+				return true
+			}
+		}
+		return false
+
+	default:
+		// Extensible match and anything unknown never match
+		return false
+	}
+}
+
+// matchValues applies cmp to each lowercased value of the filter's attribute
+func matchValues(entry *Entry, filter *packet, cmp func(value, assertion string) bool) bool {
+	if len(filter.children) < 2 {
+		return false
+	}
+	attr := filter.children[0].str()
+	assertion := strings.ToLower(filter.children[1].str())
+
+	values := entry.get(attr)
+	if strings.EqualFold(attr, "distinguishedName") || strings.EqualFold(attr, "entryDN") {
+		values = []string{entry.DN}
+	}
+	for _, value := range values {
+		value = strings.ToLower(value)
+		if isDNAttribute(attr) {
+			value, assertion = normalizeDN(value), normalizeDN(assertion)
+		}
+		if cmp(value, assertion) {
+			return true
+		}
+	}
+	return false
+}
+
+// isDNAttribute reports whether an attribute holds DNs, which compare after normalisation
+func isDNAttribute(attr string) bool {
+	switch strings.ToLower(attr) {
+	case "member", "uniquemember", "memberof", "distinguishedname", "entrydn", "manager":
+		return true
+	}
+	return false
+}
+
+// matchSubstrings checks a lowercased value against initial/any/final parts in order
+func matchSubstrings(value string, parts []*packet) bool {
+	for _, part := range parts {
+		s := strings.ToLower(part.str())
+		switch part.tag {
+		case substringInitial:
+			if !strings.HasPrefix(value, s) {
+				return false
+			}
+			value = value[len(s):]
+		case substringAny:
+			i := strings.Index(value, s)
+			if i < 0 {
+				return false
+			}
+			value = value[i+len(s):]
+		case substringFinal:
+			if !strings.HasSuffix(value, s) {
+				return false
+			}
+			value = ""
+		}
+	}
+	return true
+}
@@ -0,0 +1,408 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package mockldap implements a small LDAPv3 server backed by in-memory fixtures, so
+// LDAP auth and secrets plugins can be tested end-to-end without a real directory.
+//
+// Supported operations are simple bind, search (all filter types except extensible
+// match), add, delete, modify and unbind. Other requests are rejected as unwilling to perform.
+package mockldap
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+// LDAP protocol operation tags
+const (
+	opBindRequest     = classApplication | constructed | 0
+	opBindResponse    = classApplication | constructed | 1
+	opUnbindRequest   = classApplication | 2
+	opSearchRequest   = classApplication | constructed | 3
+	opSearchEntry     = classApplication | constructed | 4
+	opSearchDone      = classApplication | constructed | 5
+	opModifyRequest   = classApplication | constructed | 6
+	opModifyResponse  = classApplication | constructed | 7
+	opAddRequest      = classApplication | constructed | 8
+	opAddResponse     = classApplication | constructed | 9
+	opDelRequest      = classApplication | 10
+	opDelResponse     = classApplication | constructed | 11
+	opAbandonRequest  = classApplication | 16
+	opExtendedRequest = classApplication | constructed | 23
+	opExtendedResp    = classApplication | constructed | 24
+)
+
+// LDAP result codes
+const (
+	resultSuccess            = 0
+	resultProtocolError      = 2
+	resultNoSuchObject       = 32
+	resultInvalidCredentials = 49
+	resultInsufficientAccess = 50
+	resultUnwillingToPerform = 53
+	resultEntryAlreadyExists = 68
+)
+
+// ldapError is an operation failure carrying an LDAP result code
+type ldapError struct {
+	code    int64
+	message string
+}
+
+func (e *ldapError) Error() string { return e.message }
+
+var (
+	errNoSuchObject  = &ldapError{resultNoSuchObject, "no such object"}
+	errAlreadyExists = &ldapError{resultEntryAlreadyExists, "entry already exists"}
+)
+
+// Server serves a Directory over LDAP
+type Server struct {
+	directory *Directory
+	logger    hclog.Logger
+
+	mu       sync.Mutex
+	listener net.Listener
+	conns    map[net.Conn]struct{}
+}
+
+// NewServer creates a server for the directory
+func NewServer(directory *Directory, logger hclog.Logger) *Server {
+	return &Server{
+		directory: directory,
+		logger:    logger,
+		conns:     make(map[net.Conn]struct{}),
+	}
+}
+
+// Directory returns the directory served by the server
+func (s *Server) Directory() *Directory {
+	return s.directory
+}
+
+// ListenAndServe listens on addr and serves connections in the background
+func (s *Server) ListenAndServe(addr string) (net.Addr, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	go s.Serve(listener)
+	return listener.Addr(), nil
+}
+
+// Serve accepts connections until the listener is closed
+func (s *Server) Serve(listener net.Listener) error {
+	s.mu.Lock()
+	s.listener = listener
+	s.mu.Unlock()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+
+		s.mu.Lock()
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+
+		go s.serveConn(conn)
+	}
+}
+
+// Close stops the listener and closes open connections
+func (s *Server) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for conn := range s.conns {
+		conn.Close()
+	}
+	if s.listener != nil {
+		return s.listener.Close()
+	}
+	return nil
+}
+
+// session is the state of one client connection
+type session struct {
+	conn    net.Conn
+	boundDN string
+	writeMu sync.Mutex
+}
+
+func (s *Server) serveConn(conn net.Conn) {
+	defer func() {
+		conn.Close()
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+	}()
+
+	sess := &session{conn: conn}
+	reader := bufio.NewReader(conn)
+	for {
+		msg, err := readPacket(reader)
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				s.logger.Debug("ldap connection closed", "remote", conn.RemoteAddr(), "error", err)
+			}
+			return
+		}
+
+		if msg.tag != tagSequence || len(msg.children) < 2 {
+			s.logger.Debug("malformed ldap message", "remote", conn.RemoteAddr())
+			return
+		}
+		id := msg.children[0].int()
+		op := msg.children[1]
+
+		if op.tag == opUnbindRequest {
+			return
+		}
+		if err := s.handle(sess, id, op); err != nil {
+			s.logger.Debug("ldap write failed", "remote", conn.RemoteAddr(), "error", err)
+			return
+		}
+	}
+}
+
+// reply writes an LDAPMessage with the given protocol op
+func (sess *session) reply(id int64, op *packet) error {
+	msg := newConstructed(tagSequence, newInt(tagInteger, id), op)
+
+	sess.writeMu.Lock()
+	defer sess.writeMu.Unlock()
+	_, err := sess.conn.Write(msg.encode())
+	return err
+}
+
+// result builds an LDAPResult-shaped response
+func result(tag byte, code int64, message string) *packet {
+	return newConstructed(tag,
+		newInt(tagEnumerated, code),
+		newString(tagOctetString, ""),
+		newString(tagOctetString, message),
+	)
+}
+
+// resultFor maps an error to a result response
+func resultFor(tag byte, err error) *packet {
+	if err == nil {
+		return result(tag, resultSuccess, "")
+	}
+	var lerr *ldapError
+	if errors.As(err, &lerr) {
+		return result(tag, lerr.code, lerr.message)
+	}
+	return result(tag, resultProtocolError, err.Error())
+}
+
+func (s *Server) handle(sess *session, id int64, op *packet) error {
+	switch op.tag {
+	case opBindRequest:
+		return sess.reply(id, resultFor(opBindResponse, s.bind(sess, op)))
+	case opSearchRequest:
+		return s.search(sess, id, op)
+	case opAddRequest:
+		return sess.reply(id, resultFor(opAddResponse, s.requireBind(sess, func() error { return s.add(op) })))
+	case opDelRequest:
+		return sess.reply(id, resultFor(opDelResponse, s.requireBind(sess, func() error { return s.directory.Delete(op.str()) })))
+	case opModifyRequest:
+		return sess.reply(id, resultFor(opModifyResponse, s.requireBind(sess, func() error { return s.modify(op) })))
+	case opAbandonRequest:
+		return nil
+	case opExtendedRequest:
+		return sess.reply(id, result(opExtendedResp, resultUnwillingToPerform, "extended operations are not supported"))
+	default:
+		s.logger.Debug("unsupported ldap operation", "tag", fmt.Sprintf("0x%02x", op.tag))
+		// Responses are application tag + 1 for the operations this server does not implement
+		return sess.reply(id, result(op.tag+1|constructed, resultUnwillingToPerform, "operation not supported"))
+	}
+}
+
+// requireBind runs fn only for authenticated sessions
+func (s *Server) requireBind(sess *session, fn func() error) error {
+	if sess.boundDN == "" {
+		return &ldapError{resultInsufficientAccess, "bind required"}
+	}
+	return fn()
+}
+
+func (s *Server) bind(sess *session, op *packet) error {
+	if len(op.children) < 3 {
+		return &ldapError{resultProtocolError, "malformed bind request"}
+	}
+	name := op.children[1].str()
+	auth := op.children[2]
+	if auth.tag != classContext|0 {
+		return &ldapError{resultUnwillingToPerform, "only simple bind is supported"}
+	}
+	password := auth.str()
+
+	sess.boundDN = ""
+	if name == "" && password == "" {
+		return nil // anonymous bind
+	}
+
+	entry, ok := s.directory.Get(name)
+	if !ok || password == "" {
+		return &ldapError{resultInvalidCredentials, "invalid credentials"}
+	}
+	for _, stored := range entry.get("userPassword") {
+		if stored == password {
+			sess.boundDN = entry.DN
+			return nil
+		}
+	}
+	return &ldapError{resultInvalidCredentials, "invalid credentials"}
+}
+
+func (s *Server) search(sess *session, id int64, op *packet) error {
+	if len(op.children) < 8 {
+		return sess.reply(id, result(opSearchDone, resultProtocolError, "malformed search request"))
+	}
+	base := op.children[0].str()
+	scope := op.children[1].int()
+	sizeLimit := op.children[3].int()
+	typesOnly := len(op.children[5].value) > 0 && op.children[5].value[0] != 0
+	filter := op.children[6]
+
+	var requested []string
+	for _, attr := range op.children[7].children {
+		requested = append(requested, attr.str())
+	}
+
+	entries, ok := s.directory.search(base, scope, func(e *Entry) bool { return matchFilter(e, filter) })
+	if !ok {
+		return sess.reply(id, result(opSearchDone, resultNoSuchObject, "no such object"))
+	}
+
+	for i, entry := range entries {
+		if sizeLimit > 0 && int64(i) >= sizeLimit {
+			break
+		}
+		if err := sess.reply(id, encodeEntry(entry, requested, typesOnly)); err != nil {
+			return err
+		}
+	}
+	return sess.reply(id, result(opSearchDone, resultSuccess, ""))
+}
+
+// encodeEntry builds a SearchResultEntry containing the requested attributes.
+// userPassword is only returned when asked for by name.
+func encodeEntry(entry *Entry, requested []string, typesOnly bool) *packet {
+	all := len(requested) == 0
+	wanted := make(map[string]bool)
+	for _, name := range requested {
+		switch name {
+		case "*":
+			all = true
+		case "1.1":
+		default:
+			wanted[strings.ToLower(name)] = true
+		}
+	}
+
+	attrs := newConstructed(tagSequence)
+	for name, values := range entry.Attributes {
+		lower := strings.ToLower(name)
+		if !wanted[lower] && (!all || lower == "userpassword") {
+			continue
+		}
+		vals := newConstructed(tagSet)
+		if !typesOnly {
+			for _, v := range values {
+				vals.children = append(vals.children, newString(tagOctetString, v))
+			}
+		}
+		attrs.children = append(attrs.children, newConstructed(tagSequence, newString(tagOctetString, name), vals))
+	}
+	return newConstructed(opSearchEntry, newString(tagOctetString, entry.DN), attrs)
+}
+
+func (s *Server) add(op *packet) error {
+	if len(op.children) < 2 {
+		return &ldapError{resultProtocolError, "malformed add request"}
+	}
+	entry := &Entry{DN: op.children[0].str(), Attributes: make(map[string][]string)}
+	for _, attr := range op.children[1].children {
+		if len(attr.children) < 2 {
+			continue
+		}
+		var values []string
+		for _, v := range attr.children[1].children {
+			values = append(values, v.str())
+		}
+		entry.Attributes[attr.children[0].str()] = values
+	}
+	return s.directory.Add(entry)
+}
+
+// Modify operation types
+const (
+	modAdd     = 0
+	modDelete  = 1
+	modReplace = 2
+)
+
+func (s *Server) modify(op *packet) error {
+	if len(op.children) < 2 {
+		return &ldapError{resultProtocolError, "malformed modify request"}
+	}
+
+	return s.directory.Modify(op.children[0].str(), func(entry *Entry) error {
+		for _, change := range op.children[1].children {
+			if len(change.children) < 2 || len(change.children[1].children) < 2 {
+				return &ldapError{resultProtocolError, "malformed modification"}
+			}
+			operation := change.children[0].int()
+			name := change.children[1].children[0].str()
+			var values []string
+			for _, v := range change.children[1].children[1].children {
+				values = append(values, v.str())
+			}
+
+			switch operation {
+			case modAdd:
+				entry.set(name, append(entry.get(name), values...))
+			case modDelete:
+				if len(values) == 0 {
+					entry.set(name, nil)
+					continue
+				}
+				var kept []string
+				for _, existing := range entry.get(name) {
+					if !containsFold(values, existing) {
+						kept = append(kept, existing)
+					}
+				}
+				entry.set(name, kept)
+			case modReplace:
+				entry.set(name, values)
+			default:
+				return &ldapError{resultProtocolError, fmt.Sprintf("unknown modify operation %d", operation)}
+			}
+		}
+		return nil
+	})
+}
+
+func containsFold(values []string, s string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,245 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package mockldap
+
+import (
+	"bufio"
+	"net"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+// testClient speaks just enough LDAP to drive the server
+type testClient struct {
+	t      *testing.T
+	conn   net.Conn
+	reader *bufio.Reader
+	nextID int64
+}
+
+func newTestServer(t *testing.T) *testClient {
+	t.Helper()
+
+	directory, err := NewDirectory(&Fixtures{
+		BaseDN: "dc=example,dc=com",
+		Users: []User{
+			{Username: "alice", Password: "alice-pw", Groups: []string{"admins", "dev"}, Attributes: map[string][]string{"mail": {"alice@example.com"}}},
+			{Username: "bob", Password: "bob-pw", Groups: []string{"dev"}},
+		},
+		Entries: []*Entry{
+			{DN: "cn=vault,dc=example,dc=com", Attributes: map[string][]string{"userPassword": {"bind-pw"}}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewDirectory failed: %v", err)
+	}
+
+	server := NewServer(directory, hclog.NewNullLogger())
+	addr, err := server.ListenAndServe("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenAndServe failed: %v", err)
+	}
+	t.Cleanup(func() { server.Close() })
+
+	conn, err := net.Dial("tcp", addr.String())
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return &testClient{t: t, conn: conn, reader: bufio.NewReader(conn)}
+}
+
+// send writes a request and returns all responses up to and including the final one
+func (c *testClient) send(op *packet, final byte) []*packet {
+	c.t.Helper()
+
+	c.nextID++
+	msg := newConstructed(tagSequence, newInt(tagInteger, c.nextID), op)
+	if _, err := c.conn.Write(msg.encode()); err != nil {
+		c.t.Fatalf("write failed: %v", err)
+	}
+
+	var responses []*packet
+	for {
+		resp, err := readPacket(c.reader)
+		if err != nil {
+			c.t.Fatalf("read failed: %v", err)
+		}
+		if resp.children[0].int() != c.nextID {
+			c.t.Fatalf("message id = %d, want %d", resp.children[0].int(), c.nextID)
+		}
+		responses = append(responses, resp.children[1])
+		if resp.children[1].tag == final {
+			return responses
+		}
+	}
+}
+
+func (c *testClient) bind(dn, password string) int64 {
+	resp := c.send(newConstructed(opBindRequest,
+		newInt(tagInteger, 3),
+		newString(tagOctetString, dn),
+		newString(classContext|0, password),
+	), opBindResponse)
+	return resp[0].children[0].int()
+}
+
+func (c *testClient) search(base string, scope int64, filter *packet, attrs ...string) ([]*packet, int64) {
+	attrList := newConstructed(tagSequence)
+	for _, a := range attrs {
+		attrList.children = append(attrList.children, newString(tagOctetString, a))
+	}
+	resp := c.send(newConstructed(opSearchRequest,
+		newString(tagOctetString, base),
+		newInt(tagEnumerated, scope),
+		newInt(tagEnumerated, 0),
+		newInt(tagInteger, 0),
+		newInt(tagInteger, 0),
+		&packet{tag: tagBoolean, value: []byte{0}},
+		filter,
+		attrList,
+	), opSearchDone)
+	return resp[:len(resp)-1], resp[len(resp)-1].children[0].int()
+}
+
+func eq(attr, value string) *packet {
+	return newConstructed(filterEquality, newString(tagOctetString, attr), newString(tagOctetString, value))
+}
+
+func attrValues(entry *packet, name string) []string {
+	for _, attr := range entry.children[1].children {
+		if attr.children[0].str() == name {
+			var values []string
+			for _, v := range attr.children[1].children {
+				values = append(values, v.str())
+			}
+			return values
+		}
+	}
+	return nil
+}
+
+func TestBind(t *testing.T) {
+	c := newTestServer(t)
+
+	if code := c.bind("cn=alice,ou=users,dc=example,dc=com", "alice-pw"); code != resultSuccess {
+		t.Errorf("valid bind = %d", code)
+	}
+	if code := c.bind("CN=Alice, OU=users, DC=example, DC=com", "alice-pw"); code != resultSuccess {
+		t.Errorf("bind with unnormalised DN = %d", code)
+	}
+	if code := c.bind("cn=alice,ou=users,dc=example,dc=com", "wrong"); code != resultInvalidCredentials {
+		t.Errorf("bad password = %d", code)
+	}
+	if code := c.bind("cn=alice,ou=users,dc=example,dc=com", ""); code != resultInvalidCredentials {
+		t.Errorf("unauthenticated bind = %d", code)
+	}
+	if code := c.bind("", ""); code != resultSuccess {
+		t.Errorf("anonymous bind = %d", code)
+	}
+}
+
+// TestLDAPAuthFlow mirrors what the LDAP auth method does: find the user, bind as them, find their groups
+func TestLDAPAuthFlow(t *testing.T) {
+	c := newTestServer(t)
+	c.bind("cn=vault,dc=example,dc=com", "bind-pw")
+
+	entries, code := c.search("ou=users,dc=example,dc=com", scopeSubtree, eq("cn", "Alice"), "mail")
+	if code != resultSuccess || len(entries) != 1 {
+		t.Fatalf("user search = %d entries, code %d", len(entries), code)
+	}
+	userDN := entries[0].children[0].str()
+	if mail := attrValues(entries[0], "mail"); len(mail) != 1 || mail[0] != "alice@example.com" {
+		t.Errorf("mail = %v", mail)
+	}
+	if len(attrValues(entries[0], "cn")) != 0 {
+		t.Error("unrequested attribute returned")
+	}
+
+	if code := c.bind(userDN, "alice-pw"); code != resultSuccess {
+		t.Fatalf("user bind = %d", code)
+	}
+
+	groupFilter := newConstructed(filterOr,
+		eq("memberUid", "alice"),
+		eq("member", userDN),
+		eq("uniqueMember", userDN),
+	)
+	groups, _ := c.search("ou=groups,dc=example,dc=com", scopeSubtree, groupFilter, "cn")
+	var names []string
+	for _, g := range groups {
+		names = append(names, attrValues(g, "cn")...)
+	}
+	if len(names) != 2 || names[0] != "admins" || names[1] != "dev" {
+		t.Errorf("groups = %v", names)
+	}
+}
+
+func TestSearchFilters(t *testing.T) {
+	c := newTestServer(t)
+
+	tests := []struct {
+		name   string
+		filter *packet
+		want   int
+	}{
+		{"present", newString(filterPresent, "mail"), 1},
+		{"substring", newConstructed(filterSubstrings, newString(tagOctetString, "uid"),
+			newConstructed(tagSequence, newString(substringInitial, "b"), newString(substringFinal, "b"))), 1},
+		{"not", newConstructed(filterAnd, eq("objectClass", "inetOrgPerson"),
+			newConstructed(filterNot, eq("uid", "alice"))), 1},
+		{"and", newConstructed(filterAnd, eq("objectClass", "person"), eq("memberOf", "cn=dev,ou=groups,dc=example,dc=com")), 2},
+	}
+
+	for _, tt := range tests {
+		entries, code := c.search("dc=example,dc=com", scopeSubtree, tt.filter)
+		if code != resultSuccess || len(entries) != tt.want {
+			t.Errorf("%s: %d entries (code %d), want %d", tt.name, len(entries), code, tt.want)
+		}
+	}
+
+	if entries, _ := c.search("dc=example,dc=com", scopeOne, newString(filterPresent, "objectClass")); len(entries) != 3 {
+		t.Errorf("one-level search returned %d entries, want 3", len(entries))
+	}
+	if _, code := c.search("ou=missing,dc=example,dc=com", scopeSubtree, newString(filterPresent, "objectClass")); code != resultNoSuchObject {
+		t.Errorf("missing base = %d", code)
+	}
+}
+
+func TestModifyRotatesPassword(t *testing.T) {
+	c := newTestServer(t)
+	dn := "cn=bob,ou=users,dc=example,dc=com"
+
+	modify := func() int64 {
+		change := newConstructed(tagSequence,
+			newInt(tagEnumerated, modReplace),
+			newConstructed(tagSequence,
+				newString(tagOctetString, "userPassword"),
+				newConstructed(tagSet, newString(tagOctetString, "rotated")),
+			),
+		)
+		resp := c.send(newConstructed(opModifyRequest,
+			newString(tagOctetString, dn),
+			newConstructed(tagSequence, change),
+		), opModifyResponse)
+		return resp[0].children[0].int()
+	}
+
+	if code := modify(); code != resultInsufficientAccess {
+		t.Errorf("anonymous modify = %d", code)
+	}
+
+	c.bind("cn=vault,dc=example,dc=com", "bind-pw")
+	if code := modify(); code != resultSuccess {
+		t.Fatalf("modify = %d", code)
+	}
+	if code := c.bind(dn, "bob-pw"); code != resultInvalidCredentials {
+		t.Errorf("old password still accepted: %d", code)
+	}
+	if code := c.bind(dn, "rotated"); code != resultSuccess {
+		t.Errorf("new password rejected: %d", code)
+	}
+}
@@ -0,0 +1,154 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// stringsFlag is a flag that may be repeated, collecting every value in order
+type stringsFlag []string
+
+func (f *stringsFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *stringsFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+// repeatedFlag defines a repeatable string flag
+func repeatedFlag(name, usage string) *stringsFlag {
+	var values stringsFlag
+	flag.Var(&values, name, usage)
+	return &values
+}
+
+// defaultMount is the mount path used when a single plugin is given without -mount
+const defaultMount = "plugin"
+
+// mountSpec pairs a plugin binary with its mount path. The binary is empty for an attached plugin.
+type mountSpec struct {
+	plugin string
+	path   string
+}
+
+// resolveMounts pairs -plugin and -mount flags in order. When attaching, the attached
+// plugin takes the first mount and the -plugin binaries pair with the rest.
+func resolveMounts(plugins, mounts []string, attach bool) ([]mountSpec, error) {
+	if attach {
+		plugins = append([]string{""}, plugins...)
+	}
+	if len(plugins) == 0 {
+		return nil, fmt.Errorf("plugin path required when not in attach mode. Use -plugin flag to specify the plugin binary")
+	}
+	if len(mounts) == 0 && len(plugins) == 1 {
+		mounts = []string{defaultMount}
+	}
+	if len(mounts) != len(plugins) {
+		return nil, fmt.Errorf("got %d plugins and %d -mount flags; give one -mount per plugin, in the same order", len(plugins), len(mounts))
+	}
+
+	specs := make([]mountSpec, len(plugins))
+	seen := make(map[string]bool)
+	for i := range plugins {
+		path := strings.Trim(mounts[i], "/")
+		if seen[path] {
+			return nil, fmt.Errorf("mount path %q is given more than once", path)
+		}
+		seen[path] = true
+		specs[i] = mountSpec{plugin: plugins[i], path: path}
+	}
+	return specs, nil
+}
+
+// loadMountsFile reads a JSON object mapping mount paths to the options accepted by
+// POST /v1/sys/mounts/<path>, e.g. {"db": {"plugin": "./db-plugin", "config": {...}}}.
+// Paths are returned in sorted order.
+func loadMountsFile(path string) ([]string, map[string]map[string]interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var mounts map[string]map[string]interface{}
+	if err := json.Unmarshal(data, &mounts); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse mounts file: %w", err)
+	}
+
+	paths := make([]string, 0, len(mounts))
+	for mount, options := range mounts {
+		if _, ok := options["plugin"].(string); !ok {
+			return nil, nil, fmt.Errorf("mount %q: plugin is required", mount)
+		}
+		paths = append(paths, mount)
+	}
+	sort.Strings(paths)
+	return paths, mounts, nil
+}
+
+// pluginCatalog is the set of binaries that mounts created through POST /v1/sys/mounts
+// may launch, like Vault's plugin catalog: the plugins given at startup and, with
+// -plugin-dir, the binaries in that directory. Nothing else is ever executed.
+type pluginCatalog struct {
+	allowed map[string]bool // resolved paths of the startup plugins
+	dir     string          // resolved -plugin-dir, empty when unset
+}
+
+// newPluginCatalog builds the catalog from the startup plugins and the plugin directory
+func newPluginCatalog(plugins []string, dir string) (*pluginCatalog, error) {
+	catalog := &pluginCatalog{allowed: make(map[string]bool)}
+	for _, plugin := range plugins {
+		if plugin == "" {
+			continue
+		}
+		if resolved, err := resolvePluginPath(plugin); err == nil {
+			catalog.allowed[resolved] = true
+		}
+	}
+	if dir != "" {
+		resolved, err := resolvePluginPath(dir)
+		if err != nil {
+			return nil, fmt.Errorf("invalid -plugin-dir: %w", err)
+		}
+		if info, err := os.Stat(resolved); err != nil || !info.IsDir() {
+			return nil, fmt.Errorf("invalid -plugin-dir: %s is not a directory", dir)
+		}
+		catalog.dir = resolved
+	}
+	return catalog, nil
+}
+
+// resolvePluginPath makes a path absolute and resolves its symlinks, so a link cannot
+// smuggle another binary into the catalog
+func resolvePluginPath(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	return filepath.EvalSymlinks(abs)
+}
+
+// resolve returns the path of a catalog binary. A bare name refers to a binary in the
+// plugin directory.
+func (c *pluginCatalog) resolve(plugin string) (string, error) {
+	if plugin == "" {
+		return "", fmt.Errorf("plugin is required")
+	}
+	if c.dir != "" && !strings.ContainsRune(plugin, filepath.Separator) {
+		plugin = filepath.Join(c.dir, plugin)
+	}
+	resolved, err := resolvePluginPath(plugin)
+	if err == nil && (c.allowed[resolved] || (c.dir != "" && filepath.Dir(resolved) == c.dir)) {
+		return resolved, nil
+	}
+	return "", fmt.Errorf("plugin %q is not in the plugin catalog; only plugins given at startup or placed in -plugin-dir can be mounted", plugin)
+}
@@ -0,0 +1,166 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"vault-plugin-host/handlers"
+)
+
+func TestResolveMounts(t *testing.T) {
+	tests := []struct {
+		name    string
+		plugins []string
+		mounts  []string
+		attach  bool
+		want    []mountSpec
+		wantErr bool
+	}{
+		{name: "single plugin uses default mount", plugins: []string{"a"}, want: []mountSpec{{"a", "plugin"}}},
+		{name: "pairs in order", plugins: []string{"a", "b"}, mounts: []string{"db", "/auth/ldap/"}, want: []mountSpec{{"a", "db"}, {"b", "auth/ldap"}}},
+		{name: "attach takes first mount", plugins: []string{"b"}, mounts: []string{"x", "y"}, attach: true, want: []mountSpec{{"", "x"}, {"b", "y"}}},
+		{name: "attach alone", attach: true, want: []mountSpec{{"", "plugin"}}},
+		{name: "missing plugin", wantErr: true},
+		{name: "missing mounts", plugins: []string{"a", "b"}, wantErr: true},
+		{name: "count mismatch", plugins: []string{"a", "b"}, mounts: []string{"x"}, wantErr: true},
+		{name: "duplicate mount", plugins: []string{"a", "b"}, mounts: []string{"x", "x/"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveMounts(tt.plugins, tt.mounts, tt.attach)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadMountsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mounts.json")
+	os.WriteFile(path, []byte(`{"db": {"plugin": "./db"}, "auth/ldap": {"plugin": "./ldap", "config": {"url": "ldap://x"}}}`), 0o600)
+
+	paths, mounts, err := loadMountsFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(paths, []string{"auth/ldap", "db"}) {
+		t.Fatalf("unexpected paths %v", paths)
+	}
+	if mounts["auth/ldap"]["config"] == nil {
+		t.Fatalf("config not loaded: %v", mounts)
+	}
+
+	os.WriteFile(path, []byte(`{"db": {"config": {}}}`), 0o600)
+	if _, _, err := loadMountsFile(path); err == nil {
+		t.Fatal("expected a mount without a plugin to fail")
+	}
+}
+
+func TestPluginCatalog(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "plugins")
+	os.Mkdir(dir, 0o755)
+	startup := filepath.Join(root, "startup-plugin")
+	for _, path := range []string{startup, filepath.Join(dir, "kv"), filepath.Join(root, "other")} {
+		os.WriteFile(path, []byte("#!/bin/sh\n"), 0o755)
+	}
+	os.Symlink(filepath.Join(root, "other"), filepath.Join(dir, "link"))
+
+	catalog, err := newPluginCatalog([]string{startup}, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, plugin := range []string{startup, "kv", filepath.Join(dir, "kv")} {
+		if _, err := catalog.resolve(plugin); err != nil {
+			t.Errorf("resolve(%q) failed: %v", plugin, err)
+		}
+	}
+	for _, plugin := range []string{"", filepath.Join(root, "other"), "link", "/bin/sh", filepath.Join(dir, "..", "other")} {
+		if _, err := catalog.resolve(plugin); err == nil {
+			t.Errorf("resolve(%q) should be rejected", plugin)
+		}
+	}
+
+	// Without -plugin-dir only the startup plugins can be mounted
+	catalog, _ = newPluginCatalog([]string{startup}, "")
+	if _, err := catalog.resolve(filepath.Join(dir, "kv")); err == nil {
+		t.Error("a binary outside the catalog should be rejected")
+	}
+	if _, err := newPluginCatalog(nil, startup); err == nil {
+		t.Error("a -plugin-dir that is not a directory should fail")
+	}
+}
+
+func TestConfigureMount(t *testing.T) {
+	defer func(timeout time.Duration, examples int) {
+		*requestTimeout, *recordExamples = timeout, examples
+	}(*requestTimeout, *recordExamples)
+	*requestTimeout, *recordExamples = time.Second, 5
+
+	router := handlers.NewRouter()
+	for _, path := range []string{"plugin", "kv"} {
+		host, err := NewPluginHost("/fake/path", false, nil, path)
+		if err != nil {
+			t.Fatalf("NewPluginHost failed: %v", err)
+		}
+		configureMount(host, systemViewConfig)
+		router.Mount(path, host.handler, nil)
+	}
+	primary, _ := router.Lookup("plugin")
+
+	// Every mount records examples, and forMount picks the mount to report on
+	examples := forMount(router, primary, (*handlers.Handler).HandleExamples)
+	for _, target := range []string{"/v1/sys/host/examples", "/v1/sys/host/examples?mount=kv"} {
+		w := httptest.NewRecorder()
+		examples(w, httptest.NewRequest("GET", target, nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("GET %s: status = %d, body = %s", target, w.Code, w.Body.String())
+		}
+	}
+	w := httptest.NewRecorder()
+	examples(w, httptest.NewRequest("GET", "/v1/sys/host/examples?mount=missing", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("an unknown mount: status = %d, want 404", w.Code)
+	}
+
+	// Lease endpoints go to the mount that issued the lease
+	kv, _ := router.Lookup("kv")
+	var served *handlers.Handler
+	var sent string
+	leases := forLease(router, primary, func(h *handlers.Handler, w http.ResponseWriter, r *http.Request) {
+		if body, _ := io.ReadAll(r.Body); string(body) != sent {
+			t.Errorf("body = %q, want %q passed on", body, sent)
+		}
+		served = h
+	})
+	for _, tt := range []struct {
+		method, target, body string
+		want                 *handlers.Handler
+	}{
+		{"PUT", "/v1/sys/leases/renew", `{"lease_id":"kv/creds/web/abc"}`, kv},
+		{"PUT", "/v1/sys/leases/renew", `{"lease_id":"plugin/creds/web/abc"}`, primary},
+		{"PUT", "/v1/sys/leases/renew", `{"lease_id":"unknown/creds/abc"}`, primary},
+		{"LIST", "/v1/sys/leases/lookup/kv/creds/", "", kv},
+		{"PUT", "/v1/sys/leases/revoke/kv/creds", "", kv},
+	} {
+		served, sent = nil, tt.body
+		leases(httptest.NewRecorder(), httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body)))
+		if served != tt.want {
+			t.Errorf("%s %s %s went to the wrong mount", tt.method, tt.target, tt.body)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"sync"
+
+	"github.com/hashicorp/go-plugin"
+	"github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/sdk/helper/pluginutil"
+	"github.com/hashicorp/vault/sdk/logical"
+	backendplugin "github.com/hashicorp/vault/sdk/plugin"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"vault-plugin-host/handlers"
+)
+
+// multiplexConn tags every call on a plugin connection with a backend instance's
+// multiplex ID, as Vault does, so a multiplexed plugin routes it to that instance
+type multiplexConn struct {
+	conn *grpc.ClientConn
+	id   string
+}
+
+func (c *multiplexConn) Invoke(ctx context.Context, method string, args, reply interface{}, opts ...grpc.CallOption) error {
+	ctx = metadata.AppendToOutgoingContext(ctx, pluginutil.MultiplexingCtxKey, c.id)
+	return c.conn.Invoke(ctx, method, args, reply, opts...)
+}
+
+func (c *multiplexConn) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	ctx = metadata.AppendToOutgoingContext(ctx, pluginutil.MultiplexingCtxKey, c.id)
+	return c.conn.NewStream(ctx, desc, method, opts...)
+}
+
+// multiplexClient is the pluginutil.PluginClient through which a backend instance of
+// a multiplexed plugin is dispensed. The process is shared, so closing or reloading
+// one instance leaves it to the host.
+type multiplexClient struct {
+	plugin.ClientProtocol
+	conn *multiplexConn
+}
+
+func (c *multiplexClient) Conn() grpc.ClientConnInterface { return c.conn }
+
+func (c *multiplexClient) Reload() error { return nil }
+
+func (c *multiplexClient) Close() error { return nil }
+
+// dispenseBackend dispenses a backend from a plugin connection. When the plugin
+// serves with multiplexing (plugin.ServeMultiplex), the backend is a new instance
+// identified by a fresh multiplex ID, which is returned; otherwise the ID is empty.
+func dispenseBackend(rpcClient plugin.ClientProtocol, name string) (logical.Backend, string, error) {
+	grpcClient, ok := rpcClient.(*plugin.GRPCClient)
+	if ok {
+		multiplexed, err := pluginutil.MultiplexingSupported(context.Background(), grpcClient.Conn, name)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to check multiplexing support: %w", err)
+		}
+		if multiplexed {
+			id, err := uuid.GenerateUUID()
+			if err != nil {
+				return nil, "", fmt.Errorf("failed to generate multiplex ID: %w", err)
+			}
+			raw, err := backendplugin.Dispense(rpcClient, &multiplexClient{
+				ClientProtocol: rpcClient,
+				conn:           &multiplexConn{conn: grpcClient.Conn, id: id},
+			})
+			if err != nil {
+				return nil, "", fmt.Errorf("failed to dispense plugin: %w", err)
+			}
+			// The wrapper closes the plugin client on Cleanup; the host manages the process
+			return raw.(*backendplugin.BackendPluginClientV5).Backend, id, nil
+		}
+	}
+
+	raw, err := rpcClient.Dispense("backend")
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to dispense plugin: %w", err)
+	}
+	backend, ok := raw.(logical.Backend)
+	if !ok {
+		return nil, "", fmt.Errorf("dispensed plugin is not a logical.Backend")
+	}
+	return backend, "", nil
+}
+
+// pluginProcess is a multiplexed plugin process that several mounts share
+type pluginProcess struct {
+	client     *plugin.Client
+	cmd        *exec.Cmd
+	connection *handlers.PluginConnection
+	refs       int
+}
+
+// processRegistry tracks the multiplexed plugin processes shared under -multiplex, so
+// further mounts of the same binary join the running process instead of launching one
+type processRegistry struct {
+	mu       sync.Mutex
+	byPath   map[string]*pluginProcess // the process new mounts of a binary join
+	byClient map[*plugin.Client]*pluginProcess
+}
+
+// sharedProcesses is the registry of the host's multiplexed plugin processes
+var sharedProcesses = &processRegistry{
+	byPath:   make(map[string]*pluginProcess),
+	byClient: make(map[*plugin.Client]*pluginProcess),
+}
+
+// acquire returns the running process of a binary and counts another user of it, or
+// nil when there is none or it no longer answers
+func (r *processRegistry) acquire(path string) *pluginProcess {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	process := r.byPath[path]
+	if process == nil {
+		return nil
+	}
+	rpcClient, err := process.client.Client()
+	if err != nil || rpcClient.Ping() != nil {
+		delete(r.byPath, path)
+		return nil
+	}
+	process.refs++
+	return process
+}
+
+// register makes a newly launched process the one further mounts of its binary join
+func (r *processRegistry) register(path string, client *plugin.Client, cmd *exec.Cmd, connection *handlers.PluginConnection) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	process := &pluginProcess{client: client, cmd: cmd, connection: connection, refs: 1}
+	r.byPath[path] = process
+	r.byClient[client] = process
+}
+
+// release drops a user of the process behind client and reports whether it was the
+// last one, so the process should be stopped. Unshared processes always are.
+func (r *processRegistry) release(client *plugin.Client) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	process, ok := r.byClient[client]
+	if !ok {
+		return true
+	}
+	process.refs--
+	if process.refs > 0 {
+		return false
+	}
+	delete(r.byClient, client)
+	for path, p := range r.byPath {
+		if p == process {
+			delete(r.byPath, path)
+		}
+	}
+	return true
+}
@@ -0,0 +1,145 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/go-plugin"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	backendplugin "github.com/hashicorp/vault/sdk/plugin"
+)
+
+// valueBackend stores the "value" field written to "value"
+func valueBackend(ctx context.Context, conf *logical.BackendConfig) (logical.Backend, error) {
+	b := &framework.Backend{
+		BackendType: logical.TypeLogical,
+		Paths: []*framework.Path{{
+			Pattern: "value",
+			Fields:  map[string]*framework.FieldSchema{"value": {Type: framework.TypeString}},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.UpdateOperation: &framework.PathOperation{Callback: func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+					return nil, req.Storage.Put(ctx, &logical.StorageEntry{Key: "value", Value: []byte(d.Get("value").(string))})
+				}},
+				logical.ReadOperation: &framework.PathOperation{Callback: func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+					entry, err := req.Storage.Get(ctx, "value")
+					if err != nil || entry == nil {
+						return nil, err
+					}
+					return &logical.Response{Data: map[string]interface{}{"value": string(entry.Value)}}, nil
+				}},
+			},
+		}},
+	}
+	if err := b.Setup(ctx, conf); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func TestDispenseMultiplexedBackends(t *testing.T) {
+	client, _ := plugin.TestPluginGRPCConn(t, false, map[string]plugin.Plugin{
+		"backend": &backendplugin.GRPCBackendPlugin{Factory: valueBackend, MultiplexingSupport: true},
+	})
+	defer client.Close()
+
+	ctx := context.Background()
+	type mount struct {
+		backend logical.Backend
+		id      string
+		storage *InMemoryStorage
+	}
+	var mounts []mount
+	for range 2 {
+		backend, id, err := dispenseBackend(client, "test-plugin")
+		if err != nil {
+			t.Fatalf("dispenseBackend failed: %v", err)
+		}
+		if id == "" {
+			t.Fatal("a multiplexed plugin should get a multiplex ID")
+		}
+		storage := NewInMemoryStorage()
+		if err := backend.Setup(ctx, &logical.BackendConfig{
+			StorageView: storage,
+			Logger:      hclog.NewNullLogger(),
+			System:      &TestSystemView{},
+		}); err != nil {
+			t.Fatalf("Setup failed: %v", err)
+		}
+		mounts = append(mounts, mount{backend: backend, id: id, storage: storage})
+	}
+	if mounts[0].id == mounts[1].id {
+		t.Fatal("each backend instance should get its own multiplex ID")
+	}
+
+	for i, value := range []string{"first", "second"} {
+		_, err := mounts[i].backend.HandleRequest(ctx, &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "value",
+			Data:      map[string]interface{}{"value": value},
+			Storage:   mounts[i].storage,
+		})
+		if err != nil {
+			t.Fatalf("write to instance %d failed: %v", i, err)
+		}
+	}
+	for i, want := range []string{"first", "second"} {
+		resp, err := mounts[i].backend.HandleRequest(ctx, &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "value",
+			Storage:   mounts[i].storage,
+		})
+		if err != nil || resp == nil || resp.Data["value"] != want {
+			t.Errorf("instance %d read %v, %v; want %q", i, resp, err, want)
+		}
+	}
+}
+
+func TestDispenseSingleBackend(t *testing.T) {
+	client, _ := plugin.TestPluginGRPCConn(t, false, map[string]plugin.Plugin{
+		"backend": &backendplugin.GRPCBackendPlugin{Factory: valueBackend},
+	})
+	defer client.Close()
+
+	backend, id, err := dispenseBackend(client, "test-plugin")
+	if err != nil {
+		t.Fatalf("dispenseBackend failed: %v", err)
+	}
+	if id != "" {
+		t.Errorf("a plugin served without multiplexing got multiplex ID %q", id)
+	}
+	if err := backend.Setup(context.Background(), &logical.BackendConfig{
+		StorageView: NewInMemoryStorage(),
+		Logger:      hclog.NewNullLogger(),
+		System:      &TestSystemView{},
+	}); err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+}
+
+func TestProcessRegistry(t *testing.T) {
+	registry := &processRegistry{
+		byPath:   make(map[string]*pluginProcess),
+		byClient: make(map[*plugin.Client]*pluginProcess),
+	}
+	shared := &plugin.Client{}
+	registry.register("/bin/plugin", shared, nil, nil)
+	registry.byClient[shared].refs++ // a second mount joined
+
+	if registry.release(shared) {
+		t.Error("the process should stay up while another mount uses it")
+	}
+	if !registry.release(shared) {
+		t.Error("the last mount should stop the process")
+	}
+	if registry.byPath["/bin/plugin"] != nil {
+		t.Error("a stopped process should not be joined")
+	}
+	if !registry.release(&plugin.Client{}) {
+		t.Error("an unshared process should always be stopped")
+	}
+}
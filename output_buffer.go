@@ -0,0 +1,62 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"sync"
+)
+
+// tailBuffer is a thread-safe io.Writer that retains the last max bytes written
+type tailBuffer struct {
+	mu      sync.Mutex
+	buf     []byte
+	max     int
+	written int64
+}
+
+// newTailBuffer creates a buffer retaining at most max bytes
+func newTailBuffer(max int) *tailBuffer {
+	return &tailBuffer{max: max}
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.buf = append(b.buf, p...)
+	if len(b.buf) > b.max {
+		b.buf = b.buf[len(b.buf)-b.max:]
+	}
+	b.written += int64(len(p))
+	return len(p), nil
+}
+
+// Written returns the total number of bytes ever written
+func (b *tailBuffer) Written() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.written
+}
+
+// Since returns the retained output written after the given offset
+func (b *tailBuffer) Since(offset int64) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	start := int64(len(b.buf)) - (b.written - offset)
+	if start < 0 {
+		start = 0
+	}
+	if start > int64(len(b.buf)) {
+		start = int64(len(b.buf))
+	}
+	return string(b.buf[start:])
+}
+
+// String returns all retained output
+func (b *tailBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return string(b.buf)
+}
@@ -0,0 +1,37 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"testing"
+)
+
+func TestTailBufferTruncates(t *testing.T) {
+	buf := newTailBuffer(8)
+
+	buf.Write([]byte("hello "))
+	buf.Write([]byte("world"))
+
+	if got := buf.String(); got != "lo world" {
+		t.Errorf("String() = %q, want %q", got, "lo world")
+	}
+	if got := buf.Written(); got != 11 {
+		t.Errorf("Written() = %d, want 11", got)
+	}
+}
+
+func TestTailBufferSince(t *testing.T) {
+	buf := newTailBuffer(1024)
+
+	buf.Write([]byte("before\n"))
+	offset := buf.Written()
+	buf.Write([]byte("goroutine 1 [running]:\n"))
+
+	if got := buf.Since(offset); got != "goroutine 1 [running]:\n" {
+		t.Errorf("Since() = %q", got)
+	}
+	if got := buf.Since(buf.Written()); got != "" {
+		t.Errorf("Since(end) = %q, want empty", got)
+	}
+}
@@ -0,0 +1,60 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// defaultPeriodicInterval matches the cadence of Vault's rollback manager
+const defaultPeriodicInterval = time.Minute
+
+// SetPeriodicInterval sets how often the plugin's periodic function is triggered (0 disables
+// it); it must be called before Start
+func (h *PluginHost) SetPeriodicInterval(interval time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.periodicInterval = interval
+}
+
+// startPeriodic launches the periodic ticker; the caller must hold h.mu
+func (h *PluginHost) startPeriodic() {
+	if h.periodicInterval <= 0 {
+		return
+	}
+	h.periodicStop = make(chan struct{})
+	go h.runPeriodic(h.periodicInterval, h.periodicStop)
+}
+
+// stopPeriodic stops the periodic ticker; the caller must hold h.mu
+func (h *PluginHost) stopPeriodic() {
+	if h.periodicStop != nil {
+		close(h.periodicStop)
+		h.periodicStop = nil
+	}
+}
+
+// runPeriodic sends a rollback request to the backend on every tick until stop is closed,
+// as Vault does to drive framework.Backend's PeriodicFunc and WAL rollbacks
+func (h *PluginHost) runPeriodic(interval time.Duration, stop <-chan struct{}) {
+	logger := h.logger.Named("periodic")
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), interval)
+			if err := h.handler.Periodic(ctx); err != nil {
+				logger.Error("periodic function failed", "error", err)
+			} else {
+				logger.Debug("periodic function completed")
+			}
+			cancel()
+		}
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// rollbackCounter counts the rollback requests it receives
+type rollbackCounter struct {
+	rollbacks int32
+}
+
+func (b *rollbackCounter) HandleRequest(ctx context.Context, req *logical.Request) (*logical.Response, error) {
+	if req.Operation == logical.RollbackOperation && req.Path == "" {
+		atomic.AddInt32(&b.rollbacks, 1)
+	}
+	return nil, nil
+}
+
+func TestRunPeriodic(t *testing.T) {
+	host, err := NewPluginHost("/fake/path", false, nil, "plugin")
+	if err != nil {
+		t.Fatalf("NewPluginHost failed: %v", err)
+	}
+	if host.periodicInterval != defaultPeriodicInterval {
+		t.Errorf("default interval = %s, want %s", host.periodicInterval, defaultPeriodicInterval)
+	}
+
+	backend := &rollbackCounter{}
+	host.handler.SetBackend(backend)
+
+	host.SetPeriodicInterval(10 * time.Millisecond)
+	host.mu.Lock()
+	host.startPeriodic()
+	host.mu.Unlock()
+
+	deadline := time.Now().Add(time.Second)
+	for atomic.LoadInt32(&backend.rollbacks) < 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if n := atomic.LoadInt32(&backend.rollbacks); n < 2 {
+		t.Fatalf("got %d rollback requests, want at least 2", n)
+	}
+
+	host.mu.Lock()
+	host.stopPeriodic()
+	host.mu.Unlock()
+
+	// Allow an in-progress tick to finish before checking that the ticker stopped
+	time.Sleep(20 * time.Millisecond)
+	stopped := atomic.LoadInt32(&backend.rollbacks)
+	time.Sleep(50 * time.Millisecond)
+	if n := atomic.LoadInt32(&backend.rollbacks); n != stopped {
+		t.Errorf("got %d rollback requests after stopping, want none", n-stopped)
+	}
+}
+
+func TestPeriodicDisabled(t *testing.T) {
+	host, err := NewPluginHost("/fake/path", false, nil, "plugin")
+	if err != nil {
+		t.Fatalf("NewPluginHost failed: %v", err)
+	}
+	host.SetPeriodicInterval(0)
+
+	host.mu.Lock()
+	host.startPeriodic()
+	host.mu.Unlock()
+
+	if host.periodicStop != nil {
+		t.Error("a zero interval should not start the ticker")
+	}
+}
@@ -0,0 +1,258 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"vault-plugin-host/handlers"
+)
+
+// maxPipelineLine bounds the size of a single NDJSON request line
+const maxPipelineLine = 64 << 20
+
+// pipelineRequest is one line of pipeline input. A line setting checkpoint or
+// assert_checkpoint captures or checks the mount's state instead of sending a request,
+// a line setting vars only assigns variables, and a line setting wait polls until a
+// condition holds. {{ name }} references in path and data are replaced with variables
+// before the request is sent.
+type pipelineRequest struct {
+	ID        interface{}            `json:"id,omitempty"`
+	Operation string                 `json:"operation"`
+	Path      string                 `json:"path"`
+	Data      map[string]interface{} `json:"data,omitempty"`
+	Capture   map[string]string      `json:"capture,omitempty"` // variable name -> response field
+	Vars      map[string]interface{} `json:"vars,omitempty"`
+	Wait      *pipelineWait          `json:"wait,omitempty"`
+	Events    *handlers.EventQuery   `json:"events,omitempty"`
+
+	Checkpoint       string   `json:"checkpoint,omitempty"`
+	AssertCheckpoint string   `json:"assert_checkpoint,omitempty"`
+	Ignore           []string `json:"ignore,omitempty"` // keys skipped by assert_checkpoint
+}
+
+// pipelineResponse is one line of pipeline output
+type pipelineResponse struct {
+	ID     interface{}     `json:"id,omitempty"`
+	Status int             `json:"status"`
+	Body   json.RawMessage `json:"body,omitempty"`
+	// CaptureErrors lists the captures the response had no field for
+	CaptureErrors []string `json:"capture_errors,omitempty"`
+	// Attempts is the number of polls a wait line made
+	Attempts int `json:"attempts,omitempty"`
+	// Row is the matrix row the line ran for, counting from 1
+	Row int `json:"row,omitempty"`
+
+	met bool // a wait line's condition held, whatever the status of the last poll
+}
+
+// pipelineMethods maps logical operations to the HTTP methods HandleRequest understands.
+// Lifecycle operations without a method of their own are sent as POST with ?operation=.
+var pipelineMethods = map[string]string{
+	"read":   http.MethodGet,
+	"list":   "LIST",
+	"create": http.MethodPost,
+	"update": http.MethodPost,
+	"delete": http.MethodDelete,
+}
+
+// pipelineWriter collects a handler response in memory
+type pipelineWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (w *pipelineWriter) Header() http.Header { return w.header }
+
+func (w *pipelineWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.body.Write(b)
+}
+
+func (w *pipelineWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+// runPipeline reads newline-delimited JSON requests from in, sends each to the plugin
+// and writes one JSON response line per request to out, in input order. vars are the
+// variables the run starts with.
+func runPipeline(handler *handlers.Handler, mount string, vars map[string]string, in io.Reader, out io.Writer) error {
+	bw := bufio.NewWriter(out)
+	encoder := json.NewEncoder(bw)
+	checkpoints := handlers.NewCheckpoints(handler)
+	variables := newPipelineVars(vars)
+
+	return scanPipeline(in, func(line []byte) error {
+		if err := encoder.Encode(servePipelineLine(handler, checkpoints, variables, mount, line)); err != nil {
+			return err
+		}
+		return bw.Flush()
+	})
+}
+
+// scanPipeline calls fn with every non-empty line of in
+func scanPipeline(in io.Reader, fn func(line []byte) error) error {
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxPipelineLine)
+
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if err := fn(line); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// servePipelineLine handles a single request line
+func servePipelineLine(handler *handlers.Handler, checkpoints *handlers.Checkpoints, vars *pipelineVars, mount string, line []byte) pipelineResponse {
+	var req pipelineRequest
+	if err := json.Unmarshal(line, &req); err != nil {
+		return pipelineError(nil, http.StatusBadRequest, fmt.Sprintf("invalid request: %v", err))
+	}
+	if req.Checkpoint != "" || req.AssertCheckpoint != "" {
+		return servePipelineCheckpoint(checkpoints, req)
+	}
+	if req.Vars != nil && req.Path == "" && req.Operation == "" {
+		if err := vars.set(req.Vars); err != nil {
+			return pipelineError(req.ID, http.StatusBadRequest, err.Error())
+		}
+		body, _ := json.Marshal(map[string]interface{}{"vars": len(req.Vars)})
+		return pipelineResponse{ID: req.ID, Status: http.StatusOK, Body: body}
+	}
+
+	if req.Wait != nil {
+		return servePipelineWait(handler, vars, mount, req)
+	}
+	if req.Events != nil {
+		resp := servePipelineEvents(handler, req)
+		resp.CaptureErrors = vars.capture(req.Capture, resp)
+		return resp
+	}
+
+	path, data, err := vars.expandRequest(req.Path, req.Data)
+	if err != nil {
+		resp := pipelineError(req.ID, http.StatusBadRequest, err.Error())
+		resp.CaptureErrors = vars.capture(req.Capture, resp)
+		return resp
+	}
+	resp := sendPipelineRequest(handler, mount, req.ID, req.Operation, path, data)
+	resp.CaptureErrors = vars.capture(req.Capture, resp)
+	return resp
+}
+
+// sendPipelineRequest sends a request, with its variables already expanded, to the plugin
+func sendPipelineRequest(handler *handlers.Handler, mount string, id interface{}, operation, path string, data map[string]interface{}) pipelineResponse {
+	operation = strings.ToLower(operation)
+	if operation == "" {
+		operation = "read"
+	}
+
+	target := "/v1/" + mount + "/" + strings.TrimPrefix(path, "/")
+	method, ok := pipelineMethods[operation]
+	if !ok {
+		method = http.MethodPost
+		target += "?operation=" + url.QueryEscape(operation)
+	}
+
+	var body io.Reader = http.NoBody
+	if data != nil {
+		raw, err := json.Marshal(data)
+		if err != nil {
+			return pipelineError(id, http.StatusBadRequest, fmt.Sprintf("invalid data: %v", err))
+		}
+		body = bytes.NewReader(raw)
+	}
+
+	httpReq, err := http.NewRequest(method, target, body)
+	if err != nil {
+		return pipelineError(id, http.StatusBadRequest, fmt.Sprintf("invalid request: %v", err))
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	w := &pipelineWriter{header: make(http.Header)}
+	handler.HandleRequest(w, httpReq)
+
+	resp := pipelineResponse{ID: id, Status: w.status}
+	if resp.Status == 0 {
+		resp.Status = http.StatusOK
+	}
+	if raw := bytes.TrimSpace(w.body.Bytes()); len(raw) > 0 {
+		if json.Valid(raw) {
+			resp.Body = raw
+		} else {
+			resp.Body, _ = json.Marshal(string(raw))
+		}
+	}
+	return resp
+}
+
+// servePipelineCheckpoint takes a checkpoint, or compares the current state with one.
+// A comparison answers 200 when nothing changed and 409 with the differences otherwise.
+func servePipelineCheckpoint(checkpoints *handlers.Checkpoints, req pipelineRequest) pipelineResponse {
+	ctx := context.Background()
+	if req.Checkpoint != "" {
+		checkpoint, err := checkpoints.Take(ctx, req.Checkpoint)
+		if err != nil {
+			return pipelineError(req.ID, http.StatusInternalServerError, err.Error())
+		}
+		body, _ := json.Marshal(map[string]interface{}{"checkpoint": checkpoint.Name, "entries": len(checkpoint.Entries)})
+		return pipelineResponse{ID: req.ID, Status: http.StatusOK, Body: body}
+	}
+
+	differences, err := checkpoints.Compare(ctx, req.AssertCheckpoint, req.Ignore)
+	if errors.Is(err, handlers.ErrNoCheckpoint) {
+		return pipelineError(req.ID, http.StatusNotFound, err.Error())
+	}
+	if err != nil {
+		return pipelineError(req.ID, http.StatusInternalServerError, err.Error())
+	}
+	status := http.StatusOK
+	if len(differences) > 0 {
+		status = http.StatusConflict
+	}
+	body, _ := json.Marshal(map[string]interface{}{
+		"checkpoint":  req.AssertCheckpoint,
+		"match":       len(differences) == 0,
+		"differences": differences,
+	})
+	return pipelineResponse{ID: req.ID, Status: status, Body: body}
+}
+
+// servePipelineEvents answers an events line with the logged events matching its query
+func servePipelineEvents(handler *handlers.Handler, req pipelineRequest) pipelineResponse {
+	bus := handler.EventBus()
+	if bus == nil || bus.EventLog() == nil {
+		return pipelineError(req.ID, http.StatusNotFound, "event persistence is not enabled")
+	}
+	events, err := bus.EventLog().Query(context.Background(), *req.Events)
+	if err != nil {
+		return pipelineError(req.ID, http.StatusInternalServerError, err.Error())
+	}
+	body, _ := json.Marshal(map[string]interface{}{"events": events, "count": len(events)})
+	return pipelineResponse{ID: req.ID, Status: http.StatusOK, Body: body}
+}
+
+// pipelineError builds a response carrying a Vault-style error body
+func pipelineError(id interface{}, status int, message string) pipelineResponse {
+	body, _ := json.Marshal(map[string]interface{}{"errors": []string{message}})
+	return pipelineResponse{ID: id, Status: status, Body: body}
+}
@@ -0,0 +1,137 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"vault-plugin-host/handlers"
+)
+
+// pipelineMatrixSummary is the last line of a matrix run: how many rows ran and which
+// of them failed
+type pipelineMatrixSummary struct {
+	Rows   int                  `json:"rows"`
+	Passed int                  `json:"passed"`
+	Failed []pipelineRowFailure `json:"failed,omitempty"`
+}
+
+// pipelineRowFailure names a failed row, its variables and the lines that failed, by
+// id or, for lines without one, by position
+type pipelineRowFailure struct {
+	Row   int                    `json:"row"`
+	Vars  map[string]interface{} `json:"vars"`
+	Lines []interface{}          `json:"lines"`
+}
+
+// loadPipelineMatrix reads the rows of a scenario matrix. A .json file holds an array
+// of objects whose values keep their JSON types; any other file is CSV with a header
+// row naming the variables.
+func loadPipelineMatrix(path string) ([]map[string]interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []map[string]interface{}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		if err := json.Unmarshal(data, &rows); err != nil {
+			return nil, fmt.Errorf("invalid matrix %s: %w", path, err)
+		}
+	} else {
+		records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+		if err != nil {
+			return nil, fmt.Errorf("invalid matrix %s: %w", path, err)
+		}
+		if len(records) == 0 {
+			return nil, fmt.Errorf("matrix %s has no header row", path)
+		}
+		header := records[0]
+		for _, record := range records[1:] {
+			row := make(map[string]interface{}, len(header))
+			for i, name := range header {
+				row[strings.TrimSpace(name)] = record[i]
+			}
+			rows = append(rows, row)
+		}
+	}
+
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("matrix %s has no rows", path)
+	}
+	for i, row := range rows {
+		for name := range row {
+			if name == "" || strings.HasPrefix(name, "env.") {
+				return nil, fmt.Errorf("matrix %s row %d: invalid variable name %q", path, i+1, name)
+			}
+		}
+	}
+	return rows, nil
+}
+
+// runPipelineMatrix runs the pipeline read from in once per row, with the row's
+// values set as variables on top of vars. Every response line carries its row, and
+// the run ends with a summary line. A row fails when any of its lines answers with an
+// error status or misses a capture; a wait line whose condition held passes even when
+// it waited for an error, such as a 404 after a delete.
+func runPipelineMatrix(handler *handlers.Handler, mount string, vars map[string]string, rows []map[string]interface{}, in io.Reader, out io.Writer) (pipelineMatrixSummary, error) {
+	var lines [][]byte
+	if err := scanPipeline(in, func(line []byte) error {
+		lines = append(lines, append([]byte(nil), line...))
+		return nil
+	}); err != nil {
+		return pipelineMatrixSummary{}, err
+	}
+
+	bw := bufio.NewWriter(out)
+	encoder := json.NewEncoder(bw)
+	summary := pipelineMatrixSummary{Rows: len(rows)}
+
+	for i, row := range rows {
+		// Each row starts from the same variables and its own checkpoints
+		variables := newPipelineVars(vars)
+		for name, value := range row {
+			variables.values[name] = value
+		}
+		checkpoints := handlers.NewCheckpoints(handler)
+
+		var failed []interface{}
+		for n, line := range lines {
+			resp := servePipelineLine(handler, checkpoints, variables, mount, line)
+			resp.Row = i + 1
+			if (resp.Status >= 400 && !resp.met) || len(resp.CaptureErrors) > 0 {
+				if resp.ID != nil {
+					failed = append(failed, resp.ID)
+				} else {
+					failed = append(failed, n+1)
+				}
+			}
+			if err := encoder.Encode(resp); err != nil {
+				return summary, err
+			}
+		}
+		if err := bw.Flush(); err != nil {
+			return summary, err
+		}
+
+		if len(failed) == 0 {
+			summary.Passed++
+		} else {
+			summary.Failed = append(summary.Failed, pipelineRowFailure{Row: i + 1, Vars: row, Lines: failed})
+		}
+	}
+
+	if err := encoder.Encode(map[string]interface{}{"summary": summary}); err != nil {
+		return summary, err
+	}
+	return summary, bw.Flush()
+}
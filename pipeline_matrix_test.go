@@ -0,0 +1,107 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadPipelineMatrix(t *testing.T) {
+	dir := t.TempDir()
+	csvPath := filepath.Join(dir, "roles.csv")
+	os.WriteFile(csvPath, []byte("role,ttl\nweb, 1h\ndb,2h\n"), 0o600)
+	rows, err := loadPipelineMatrix(csvPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || rows[0]["role"] != "web" || rows[0]["ttl"] != " 1h" || rows[1]["ttl"] != "2h" {
+		t.Errorf("CSV rows = %v", rows)
+	}
+
+	jsonPath := filepath.Join(dir, "keys.json")
+	os.WriteFile(jsonPath, []byte(`[{"type": "rsa", "bits": 2048}, {"type": "ec", "bits": 256}]`), 0o600)
+	rows, err = loadPipelineMatrix(jsonPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || rows[1]["bits"] != float64(256) {
+		t.Errorf("JSON rows = %v", rows)
+	}
+
+	for name, content := range map[string]string{
+		"empty.csv":    "role,ttl\n",
+		"ragged.csv":   "role,ttl\nweb\n",
+		"env.csv":      "env.HOME\nx\n",
+		"object.json":  `{"role": "web"}`,
+		"nothing.json": `[]`,
+	} {
+		path := filepath.Join(dir, name)
+		os.WriteFile(path, []byte(content), 0o600)
+		if _, err := loadPipelineMatrix(path); err == nil {
+			t.Errorf("%s should be rejected", name)
+		}
+	}
+}
+
+func TestRunPipelineMatrix(t *testing.T) {
+	host, err := NewPluginHost("/fake/path", false, nil, "plugin")
+	if err != nil {
+		t.Fatalf("NewPluginHost failed: %v", err)
+	}
+	host.handler.SetBackend(echoBackend{})
+
+	input := strings.Join([]string{
+		`{"id": "write", "operation": "update", "path": "roles/{{ role }}", "data": {"ttl": "{{ ttl }}"}, "capture": {"echoed": "body.data.data.ttl"}}`,
+		`{"operation": "read", "path": "{{ missing }}"}`,
+		`{"id": "gone", "wait": {"storage": "unset", "until": "$.status", "equals": 404}}`,
+	}, "\n")
+	rows := []map[string]interface{}{
+		{"role": "web", "ttl": float64(3600), "missing": "x"},
+		{"role": "db", "ttl": "2h"},
+	}
+
+	var out bytes.Buffer
+	summary, err := runPipelineMatrix(host.handler, host.mountPath, map[string]string{"missing": ""}, rows, strings.NewReader(input), &out)
+	if err != nil {
+		t.Fatalf("runPipelineMatrix failed: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 7 {
+		t.Fatalf("got %d lines, want 6 responses and a summary:\n%s", len(lines), out.String())
+	}
+	var first pipelineResponse
+	json.Unmarshal([]byte(lines[0]), &first)
+	if first.Row != 1 || !strings.Contains(lines[0], `"path":"roles/web"`) || !strings.Contains(lines[0], `"ttl":3600`) {
+		t.Errorf("first row should expand its typed variables: %s", lines[0])
+	}
+	if !strings.Contains(lines[3], `"row":2`) || !strings.Contains(lines[3], `"path":"roles/db"`) {
+		t.Errorf("second row should use its own variables: %s", lines[3])
+	}
+
+	// The second row takes missing from -var, and a wait for a 404 that arrives
+	// passes, so both rows pass
+	if summary.Rows != 2 || summary.Passed != 2 || len(summary.Failed) != 0 {
+		t.Errorf("summary = %+v", summary)
+	}
+	if !strings.HasPrefix(lines[6], `{"summary":{"rows":2,"passed":2`) {
+		t.Errorf("last line should be the summary: %s", lines[6])
+	}
+
+	// A row whose lines fail is reported with its variables and the failing lines
+	out.Reset()
+	summary, _ = runPipelineMatrix(host.handler, host.mountPath, nil, rows, strings.NewReader(input), &out)
+	if summary.Passed != 1 || len(summary.Failed) != 1 {
+		t.Fatalf("summary = %+v", summary)
+	}
+	failure := summary.Failed[0]
+	if failure.Row != 2 || failure.Vars["role"] != "db" || len(failure.Lines) != 1 || failure.Lines[0] != 2 {
+		t.Errorf("failure = %+v", failure)
+	}
+}
@@ -0,0 +1,298 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+
+	"vault-plugin-host/handlers"
+)
+
+// echoBackend returns the operation, path and data it received. The path doubles as
+// "keys" so list responses keep the same shape.
+type echoBackend struct{}
+
+func (echoBackend) HandleRequest(ctx context.Context, req *logical.Request) (*logical.Response, error) {
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"operation": string(req.Operation),
+			"path":      req.Path,
+			"data":      req.Data,
+			"keys":      []string{req.Path},
+		},
+	}, nil
+}
+
+func TestRunPipeline(t *testing.T) {
+	host, err := NewPluginHost("/fake/path", false, nil, "plugin")
+	if err != nil {
+		t.Fatalf("NewPluginHost failed: %v", err)
+	}
+	host.handler.SetBackend(echoBackend{})
+
+	input := strings.Join([]string{
+		`{"id": 1, "operation": "read", "path": "config"}`,
+		``,
+		`{"id": "two", "operation": "update", "path": "/roles/test", "data": {"ttl": "1h"}}`,
+		`{"id": 3, "operation": "list", "path": "roles/"}`,
+		`{"id": 4, "operation": "rotate", "path": "root"}`,
+		`not json`,
+	}, "\n")
+
+	var out bytes.Buffer
+	if err := runPipeline(host.handler, host.mountPath, nil, strings.NewReader(input), &out); err != nil {
+		t.Fatalf("runPipeline failed: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("got %d response lines, want 5:\n%s", len(lines), out.String())
+	}
+
+	want := []struct {
+		id        interface{}
+		operation string
+		path      string
+	}{
+		{float64(1), "read", "config"},
+		{"two", "update", "roles/test"},
+		{float64(3), "list", "roles/"},
+		{float64(4), "rotate", "root"},
+	}
+
+	for i, w := range want {
+		var resp struct {
+			ID     interface{} `json:"id"`
+			Status int         `json:"status"`
+			Body   struct {
+				Data map[string]interface{} `json:"data"`
+			} `json:"body"`
+		}
+		if err := json.Unmarshal([]byte(lines[i]), &resp); err != nil {
+			t.Fatalf("line %d: %v", i, err)
+		}
+		if resp.ID != w.id || resp.Status != 200 {
+			t.Errorf("line %d: id=%v status=%d", i, resp.ID, resp.Status)
+		}
+		if resp.Body.Data["operation"] != w.operation || resp.Body.Data["path"] != w.path {
+			t.Errorf("line %d: got %v", i, resp.Body.Data)
+		}
+	}
+
+	if !strings.Contains(lines[4], `"status":400`) || !strings.Contains(lines[4], "invalid request") {
+		t.Errorf("malformed line response = %s", lines[4])
+	}
+}
+
+// storeBackend writes the data of update requests to storage under the request path
+type storeBackend struct{}
+
+func (storeBackend) HandleRequest(ctx context.Context, req *logical.Request) (*logical.Response, error) {
+	if req.Operation == logical.UpdateOperation {
+		entry, err := logical.StorageEntryJSON(req.Path, req.Data)
+		if err != nil {
+			return nil, err
+		}
+		return nil, req.Storage.Put(ctx, entry)
+	}
+	return nil, nil
+}
+
+func TestRunPipelineCheckpoints(t *testing.T) {
+	host, err := NewPluginHost("/fake/path", false, nil, "plugin")
+	if err != nil {
+		t.Fatalf("NewPluginHost failed: %v", err)
+	}
+	host.handler.SetBackend(storeBackend{})
+
+	input := strings.Join([]string{
+		`{"checkpoint": "empty"}`,
+		`{"operation": "update", "path": "config", "data": {"url": "a"}}`,
+		`{"checkpoint": "configured"}`,
+		`{"assert_checkpoint": "configured"}`,
+		`{"operation": "update", "path": "roles/test", "data": {"ttl": "1h"}}`,
+		`{"assert_checkpoint": "configured"}`,
+		`{"assert_checkpoint": "configured", "ignore": ["storage/roles/"]}`,
+		`{"assert_checkpoint": "missing"}`,
+	}, "\n")
+
+	var out bytes.Buffer
+	if err := runPipeline(host.handler, host.mountPath, nil, strings.NewReader(input), &out); err != nil {
+		t.Fatalf("runPipeline failed: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	wantStatus := []int{200, 204, 200, 200, 204, 409, 200, 404}
+	if len(lines) != len(wantStatus) {
+		t.Fatalf("got %d response lines, want %d:\n%s", len(lines), len(wantStatus), out.String())
+	}
+	for i, want := range wantStatus {
+		var resp pipelineResponse
+		if err := json.Unmarshal([]byte(lines[i]), &resp); err != nil {
+			t.Fatalf("line %d: %v", i, err)
+		}
+		if resp.Status != want {
+			t.Errorf("line %d: status %d, want %d: %s", i, resp.Status, want, lines[i])
+		}
+	}
+	if !strings.Contains(lines[5], `"key":"storage/roles/test","change":"added"`) {
+		t.Errorf("assertion should report the new role: %s", lines[5])
+	}
+}
+
+// eventBackend sends a write event for the path of each update request
+type eventBackend struct {
+	sender logical.EventSender
+}
+
+func (b eventBackend) HandleRequest(ctx context.Context, req *logical.Request) (*logical.Response, error) {
+	if req.Operation == logical.UpdateOperation {
+		return nil, logical.SendEvent(ctx, b.sender, "test/write", logical.EventMetadataPath, req.Path)
+	}
+	return nil, nil
+}
+
+func TestRunPipelineEvents(t *testing.T) {
+	host, err := NewPluginHost("/fake/path", false, nil, "plugin")
+	if err != nil {
+		t.Fatalf("NewPluginHost failed: %v", err)
+	}
+	bus := handlers.NewEventBus()
+	host.handler.SetEventBus(bus)
+	host.handler.SetBackend(eventBackend{sender: bus.Sender(host.mountPath, "test")})
+
+	input := strings.Join([]string{
+		`{"events": {}}`,
+		`{"operation": "update", "path": "roles/a"}`,
+		`{"operation": "update", "path": "roles/b"}`,
+		`{"events": {"type": "test/*", "metadata": {"path": "plugin/roles/b"}}, "capture": {"seq": "body.events.0.sequence"}}`,
+	}, "\n")
+
+	var out bytes.Buffer
+	if err := runPipeline(host.handler, host.mountPath, nil, strings.NewReader(input), &out); err != nil {
+		t.Fatalf("runPipeline failed: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("got %d response lines, want 4:\n%s", len(lines), out.String())
+	}
+	if !strings.Contains(lines[0], `"status":404`) {
+		t.Errorf("events without persistence = %s", lines[0])
+	}
+	if err := bus.SetEventLog(handlers.NewEventLog(&logical.InmemStorage{})); err != nil {
+		t.Fatalf("SetEventLog failed: %v", err)
+	}
+
+	// Only the events of the second run are logged, numbered after those of the first
+	out.Reset()
+	if err := runPipeline(host.handler, host.mountPath, nil, strings.NewReader(input), &out); err != nil {
+		t.Fatalf("runPipeline failed: %v", err)
+	}
+	lines = strings.Split(strings.TrimSpace(out.String()), "\n")
+	var resp pipelineResponse
+	if err := json.Unmarshal([]byte(lines[3]), &resp); err != nil {
+		t.Fatalf("invalid response line: %v", err)
+	}
+	var body struct {
+		Count  int                    `json:"count"`
+		Events []handlers.LoggedEvent `json:"events"`
+	}
+	json.Unmarshal(resp.Body, &body)
+	if resp.Status != 200 || body.Count != 1 || body.Events[0].Sequence != 4 || len(resp.CaptureErrors) != 0 {
+		t.Errorf("events line = %s", lines[3])
+	}
+}
+
+func TestRunPipelineVariables(t *testing.T) {
+	host, err := NewPluginHost("/fake/path", false, nil, "plugin")
+	if err != nil {
+		t.Fatalf("NewPluginHost failed: %v", err)
+	}
+	host.handler.SetBackend(echoBackend{})
+	t.Setenv("PIPELINE_TEST_REGION", "eu-west-1")
+
+	input := strings.Join([]string{
+		`{"vars": {"prefix": "ci", "role": "{{ prefix }}-{{ run }}"}}`,
+		`{"id": 1, "operation": "update", "path": "roles/{{role}}", "data": {"region": "{{ env.PIPELINE_TEST_REGION }}"}, "capture": {"created": "body.data.path", "op": "body.data.operation"}}`,
+		`{"id": 2, "operation": "update", "path": "copy", "data": {"from": "{{ created }}", "nested": ["{{ op }}"]}, "capture": {"keys": "body.data.keys", "missing": "body.data.nope"}}`,
+		`{"id": 3, "operation": "update", "path": "echo", "data": {"keys": "{{ keys }}", "first": "{{ keys }}/x"}}`,
+		`{"id": 4, "operation": "read", "path": "roles/{{ undefined }}"}`,
+		`{"id": 5, "operation": "read", "path": "{{ env.PIPELINE_TEST_UNSET }}"}`,
+	}, "\n")
+
+	var out bytes.Buffer
+	if err := runPipeline(host.handler, host.mountPath, map[string]string{"run": "42"}, strings.NewReader(input), &out); err != nil {
+		t.Fatalf("runPipeline failed: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 6 {
+		t.Fatalf("got %d response lines, want 6:\n%s", len(lines), out.String())
+	}
+
+	type echoed struct {
+		Status int `json:"status"`
+		Body   struct {
+			Data struct {
+				Path string                 `json:"path"`
+				Data map[string]interface{} `json:"data"`
+			} `json:"data"`
+		} `json:"body"`
+		CaptureErrors []string `json:"capture_errors"`
+	}
+	decode := func(i int) echoed {
+		var resp echoed
+		if err := json.Unmarshal([]byte(lines[i]), &resp); err != nil {
+			t.Fatalf("line %d: %v", i, err)
+		}
+		return resp
+	}
+
+	if resp := decode(1); resp.Body.Data.Path != "roles/ci-42" || resp.Body.Data.Data["region"] != "eu-west-1" {
+		t.Errorf("variables were not substituted: %s", lines[1])
+	}
+	resp := decode(2)
+	if resp.Body.Data.Data["from"] != "roles/ci-42" || resp.Body.Data.Data["nested"].([]interface{})[0] != "update" {
+		t.Errorf("captured values were not substituted: %s", lines[2])
+	}
+	if len(resp.CaptureErrors) != 1 || !strings.Contains(resp.CaptureErrors[0], "missing") {
+		t.Errorf("capture errors = %v", resp.CaptureErrors)
+	}
+	// A whole-string reference keeps the captured list; a longer string gets its JSON text
+	resp = decode(3)
+	if keys, ok := resp.Body.Data.Data["keys"].([]interface{}); !ok || len(keys) != 1 || keys[0] != "copy" {
+		t.Errorf("keys = %#v", resp.Body.Data.Data["keys"])
+	}
+	if resp.Body.Data.Data["first"] != `["copy"]/x` {
+		t.Errorf("first = %#v", resp.Body.Data.Data["first"])
+	}
+	for _, i := range []int{4, 5} {
+		if !strings.Contains(lines[i], `"status":400`) {
+			t.Errorf("line %d: expected 400 for an unresolved reference: %s", i, lines[i])
+		}
+	}
+	if !strings.Contains(lines[4], "undefined variable") || !strings.Contains(lines[5], "PIPELINE_TEST_UNSET") {
+		t.Errorf("unexpected errors: %s %s", lines[4], lines[5])
+	}
+}
+
+func TestParsePipelineVars(t *testing.T) {
+	vars, err := parsePipelineVars([]string{"role=ci-1", "empty=", "url=http://a/?b=c"})
+	if err != nil {
+		t.Fatalf("parsePipelineVars failed: %v", err)
+	}
+	if vars["role"] != "ci-1" || vars["empty"] != "" || vars["url"] != "http://a/?b=c" {
+		t.Errorf("vars = %v", vars)
+	}
+	for _, bad := range []string{"novalue", "=x", "env.HOME=x"} {
+		if _, err := parsePipelineVars([]string{bad}); err == nil {
+			t.Errorf("expected an error for %q", bad)
+		}
+	}
+}
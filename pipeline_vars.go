@@ -0,0 +1,240 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// pipelinePlaceholder matches a {{ name }} reference in a pipeline line. env.NAME
+// refers to an environment variable of the host.
+var pipelinePlaceholder = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_.-]*)\s*\}\}`)
+
+// pipelineVars holds the variables of a pipeline run: those given with -var, set by
+// "vars" lines and captured from earlier responses
+type pipelineVars struct {
+	values map[string]interface{}
+	lookup func(string) (string, bool) // environment lookup, os.LookupEnv outside tests
+}
+
+// newPipelineVars creates the variables of a run, starting with initial
+func newPipelineVars(initial map[string]string) *pipelineVars {
+	vars := &pipelineVars{values: make(map[string]interface{}), lookup: os.LookupEnv}
+	for name, value := range initial {
+		vars.values[name] = value
+	}
+	return vars
+}
+
+// parsePipelineVars parses -var flags of the form name=value
+func parsePipelineVars(flags []string) (map[string]string, error) {
+	vars := make(map[string]string, len(flags))
+	for _, f := range flags {
+		name, value, ok := strings.Cut(f, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid -var %q (expected name=value)", f)
+		}
+		if strings.HasPrefix(name, "env.") {
+			return nil, fmt.Errorf("invalid -var %q: the env. prefix is reserved for environment variables", f)
+		}
+		vars[name] = value
+	}
+	return vars, nil
+}
+
+// get returns the value of a variable or environment binding
+func (v *pipelineVars) get(name string) (interface{}, error) {
+	if env, ok := strings.CutPrefix(name, "env."); ok {
+		value, ok := v.lookup(env)
+		if !ok {
+			return nil, fmt.Errorf("environment variable %q is not set", env)
+		}
+		return value, nil
+	}
+	value, ok := v.values[name]
+	if !ok {
+		return nil, fmt.Errorf("undefined variable %q", name)
+	}
+	return value, nil
+}
+
+// expandString substitutes the references in s. A string that is exactly one
+// reference takes the variable's value with its type, so captured numbers, lists
+// and objects can be passed on as they are; references inside longer strings are
+// replaced with the value's text.
+func (v *pipelineVars) expandString(s string) (interface{}, error) {
+	if m := pipelinePlaceholder.FindStringSubmatchIndex(s); m != nil && m[0] == 0 && m[1] == len(s) {
+		return v.get(s[m[2]:m[3]])
+	}
+	return v.interpolate(s)
+}
+
+// interpolate replaces every reference in s with the text of its value
+func (v *pipelineVars) interpolate(s string) (string, error) {
+	var err error
+	expanded := pipelinePlaceholder.ReplaceAllStringFunc(s, func(ref string) string {
+		value, getErr := v.get(pipelinePlaceholder.FindStringSubmatch(ref)[1])
+		if getErr != nil {
+			if err == nil {
+				err = getErr
+			}
+			return ref
+		}
+		return pipelineText(value)
+	})
+	return expanded, err
+}
+
+// expand substitutes the references in every string of a request's data
+func (v *pipelineVars) expand(value interface{}) (interface{}, error) {
+	switch value := value.(type) {
+	case string:
+		return v.expandString(value)
+	case map[string]interface{}:
+		expanded := make(map[string]interface{}, len(value))
+		for key, item := range value {
+			item, err := v.expand(item)
+			if err != nil {
+				return nil, err
+			}
+			expanded[key] = item
+		}
+		return expanded, nil
+	case []interface{}:
+		expanded := make([]interface{}, len(value))
+		for i, item := range value {
+			item, err := v.expand(item)
+			if err != nil {
+				return nil, err
+			}
+			expanded[i] = item
+		}
+		return expanded, nil
+	default:
+		return value, nil
+	}
+}
+
+// expandRequest substitutes the references in the path and data of a request
+func (v *pipelineVars) expandRequest(path string, data map[string]interface{}) (string, map[string]interface{}, error) {
+	path, err := v.interpolate(path)
+	if err != nil {
+		return "", nil, err
+	}
+	if data == nil {
+		return path, nil, nil
+	}
+	expanded, err := v.expand(data)
+	if err != nil {
+		return "", nil, err
+	}
+	return path, expanded.(map[string]interface{}), nil
+}
+
+// set assigns variables from a "vars" line, expanding references in their values
+func (v *pipelineVars) set(values map[string]interface{}) error {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if name == "" || strings.HasPrefix(name, "env.") {
+			return fmt.Errorf("invalid variable name %q", name)
+		}
+		value, err := v.expand(values[name])
+		if err != nil {
+			return err
+		}
+		v.values[name] = value
+	}
+	return nil
+}
+
+// capture assigns variables from fields of a response. Fields are dotted paths into
+// the response line, such as "body.lease_id" or "body.data.keys.0"; a missing field
+// is reported and leaves its variable unset.
+func (v *pipelineVars) capture(captures map[string]string, resp pipelineResponse) []string {
+	if len(captures) == 0 {
+		return nil
+	}
+	root := pipelineRoot(resp)
+
+	names := make([]string, 0, len(captures))
+	for name := range captures {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var failures []string
+	for _, name := range names {
+		value, ok := pipelineField(root, captures[name])
+		if !ok {
+			failures = append(failures, fmt.Sprintf("%s: response has no field %q", name, captures[name]))
+			continue
+		}
+		v.values[name] = value
+	}
+	return failures
+}
+
+// pipelineRoot is the document captures and wait conditions address: the status and
+// decoded body of a response
+func pipelineRoot(resp pipelineResponse) map[string]interface{} {
+	var body interface{}
+	if len(resp.Body) > 0 {
+		json.Unmarshal(resp.Body, &body)
+	}
+	return map[string]interface{}{"status": float64(resp.Status), "body": body}
+}
+
+// pipelineField looks up a dotted path in a decoded JSON value
+func pipelineField(value interface{}, path string) (interface{}, bool) {
+	return pipelineWalk(value, strings.Split(path, "."))
+}
+
+// pipelineWalk follows path segments into a decoded JSON value; numeric segments
+// index into lists
+func pipelineWalk(value interface{}, segments []string) (interface{}, bool) {
+	for _, segment := range segments {
+		switch current := value.(type) {
+		case map[string]interface{}:
+			next, ok := current[segment]
+			if !ok {
+				return nil, false
+			}
+			value = next
+		case []interface{}:
+			i, err := strconv.Atoi(segment)
+			if err != nil || i < 0 || i >= len(current) {
+				return nil, false
+			}
+			value = current[i]
+		default:
+			return nil, false
+		}
+	}
+	return value, value != nil
+}
+
+// pipelineText is the text a value is substituted with inside a longer string
+func pipelineText(value interface{}) string {
+	switch value := value.(type) {
+	case string:
+		return value
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	case int:
+		return strconv.Itoa(value)
+	default:
+		data, _ := json.Marshal(value)
+		return string(data)
+	}
+}
@@ -0,0 +1,217 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"vault-plugin-host/handlers"
+)
+
+const (
+	// defaultWaitTimeout bounds a wait line that sets no timeout
+	defaultWaitTimeout = 30 * time.Second
+	// defaultWaitInterval is the pause between polls of a wait line that sets no interval
+	defaultWaitInterval = time.Second
+)
+
+// pipelineWait polls a plugin path, or a storage key, until a field of the result
+// meets a condition. It is meant for plugins that finish operations in the background.
+type pipelineWait struct {
+	Operation string                 `json:"operation,omitempty"`
+	Path      string                 `json:"path,omitempty"`
+	Data      map[string]interface{} `json:"data,omitempty"`
+	Storage   string                 `json:"storage,omitempty"` // storage key polled instead of a path
+
+	// Until is a JSONPath into the polled result, such as $.body.data.status. Without
+	// equals or not_equals the condition is that the field exists.
+	Until     string          `json:"until"`
+	Equals    json.RawMessage `json:"equals,omitempty"`
+	NotEquals json.RawMessage `json:"not_equals,omitempty"`
+
+	Timeout  string `json:"timeout,omitempty"`
+	Interval string `json:"interval,omitempty"`
+}
+
+// pipelineCondition is the parsed condition of a wait line
+type pipelineCondition struct {
+	path   []string
+	equals bool // whether the field must equal value or, otherwise, differ from it
+	value  interface{}
+	set    bool // whether a value was given at all
+}
+
+// parsePipelineCondition parses the condition of a wait line, expanding variables in
+// the value it compares with
+func parsePipelineCondition(wait *pipelineWait, vars *pipelineVars) (*pipelineCondition, error) {
+	path, err := parseJSONPath(wait.Until)
+	if err != nil {
+		return nil, err
+	}
+	condition := &pipelineCondition{path: path}
+
+	raw := wait.Equals
+	condition.equals = true
+	if len(wait.NotEquals) > 0 {
+		if len(raw) > 0 {
+			return nil, fmt.Errorf("wait cannot set both equals and not_equals")
+		}
+		raw = wait.NotEquals
+		condition.equals = false
+	}
+	if len(raw) > 0 {
+		var value interface{}
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return nil, fmt.Errorf("invalid condition value: %w", err)
+		}
+		if condition.value, err = vars.expand(value); err != nil {
+			return nil, err
+		}
+		condition.set = true
+	}
+	return condition, nil
+}
+
+// met reports whether a polled result meets the condition, and the field's value
+func (c *pipelineCondition) met(resp pipelineResponse) (bool, interface{}) {
+	value, ok := pipelineWalk(pipelineRoot(resp), c.path)
+	if !c.set {
+		return ok, value
+	}
+	// Round-trip the expected value so numbers compare as the decoded field does
+	data, _ := json.Marshal(c.value)
+	var expected interface{}
+	json.Unmarshal(data, &expected)
+	return ok && reflect.DeepEqual(value, expected) == c.equals, value
+}
+
+// parseJSONPath splits a JSONPath such as $.body.data.keys[0] or $['body']['data']
+// into segments. The leading $ is optional, so the dotted paths of capture work too.
+func parseJSONPath(path string) ([]string, error) {
+	rest := strings.TrimPrefix(strings.TrimSpace(path), "$")
+	if rest == "" {
+		return nil, fmt.Errorf("wait requires an until path")
+	}
+	var segments []string
+	for rest != "" {
+		switch {
+		case rest[0] == '.':
+			rest = rest[1:]
+		case rest[0] == '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid JSONPath %q: unclosed [", path)
+			}
+			segment := strings.TrimSpace(rest[1:end])
+			if unquoted, err := strconv.Unquote(strings.ReplaceAll(segment, "'", `"`)); err == nil {
+				segment = unquoted
+			} else if _, err := strconv.Atoi(segment); err != nil {
+				return nil, fmt.Errorf("invalid JSONPath %q: unsupported selector [%s]", path, segment)
+			}
+			segments = append(segments, segment)
+			rest = rest[end+1:]
+			continue
+		}
+		end := strings.IndexAny(rest, ".[")
+		if end < 0 {
+			end = len(rest)
+		}
+		if end == 0 {
+			return nil, fmt.Errorf("invalid JSONPath %q: empty segment", path)
+		}
+		segments = append(segments, rest[:end])
+		rest = rest[end:]
+	}
+	return segments, nil
+}
+
+// servePipelineWait polls until the condition of a wait line holds or its timeout
+// passes. The response is the last polled result, so its fields can be captured; a
+// timeout answers 408 with the last value of the field.
+func servePipelineWait(handler *handlers.Handler, vars *pipelineVars, mount string, req pipelineRequest) pipelineResponse {
+	wait := req.Wait
+	timeout, interval := defaultWaitTimeout, defaultWaitInterval
+	for _, d := range []struct {
+		name  string
+		value string
+		into  *time.Duration
+	}{{"timeout", wait.Timeout, &timeout}, {"interval", wait.Interval, &interval}} {
+		if d.value == "" {
+			continue
+		}
+		parsed, err := time.ParseDuration(d.value)
+		if err != nil || parsed <= 0 {
+			return pipelineError(req.ID, http.StatusBadRequest, fmt.Sprintf("invalid wait %s %q", d.name, d.value))
+		}
+		*d.into = parsed
+	}
+	if (wait.Path == "") == (wait.Storage == "") {
+		return pipelineError(req.ID, http.StatusBadRequest, "wait requires exactly one of path and storage")
+	}
+	condition, err := parsePipelineCondition(wait, vars)
+	if err != nil {
+		return pipelineError(req.ID, http.StatusBadRequest, err.Error())
+	}
+
+	// Variables cannot change while waiting, so the request is expanded once
+	path, data, err := vars.expandRequest(wait.Path, wait.Data)
+	if err != nil {
+		return pipelineError(req.ID, http.StatusBadRequest, err.Error())
+	}
+	key, err := vars.interpolate(wait.Storage)
+	if err != nil {
+		return pipelineError(req.ID, http.StatusBadRequest, err.Error())
+	}
+	poll := func() pipelineResponse {
+		if key != "" {
+			return pollPipelineStorage(handler, req.ID, key)
+		}
+		return sendPipelineRequest(handler, mount, req.ID, wait.Operation, path, data)
+	}
+
+	deadline := time.Now().Add(timeout)
+	for attempts := 1; ; attempts++ {
+		resp := poll()
+		met, value := condition.met(resp)
+		if met {
+			resp.Attempts = attempts
+			resp.met = true
+			resp.CaptureErrors = vars.capture(req.Capture, resp)
+			return resp
+		}
+		if time.Now().Add(interval).After(deadline) {
+			last, _ := json.Marshal(value)
+			timedOut := pipelineError(req.ID, http.StatusRequestTimeout,
+				fmt.Sprintf("condition on %s not met within %s (last value %s)", wait.Until, timeout, last))
+			timedOut.Attempts = attempts
+			return timedOut
+		}
+		time.Sleep(interval)
+	}
+}
+
+// pollPipelineStorage reads a storage key as a result shaped like a response: status
+// 200 with the key and its JSON value in the body, or 404 when the key is unset
+func pollPipelineStorage(handler *handlers.Handler, id interface{}, key string) pipelineResponse {
+	entry, err := handler.StorageEntry(context.Background(), key)
+	if err != nil {
+		return pipelineError(id, http.StatusInternalServerError, fmt.Sprintf("failed to read storage entry %q: %v", key, err))
+	}
+	if entry == nil {
+		return pipelineError(id, http.StatusNotFound, fmt.Sprintf("storage key %q is not set", key))
+	}
+	var value interface{} = string(entry.Value)
+	if json.Valid(entry.Value) {
+		value = json.RawMessage(entry.Value)
+	}
+	body, _ := json.Marshal(map[string]interface{}{"key": key, "value": value})
+	return pipelineResponse{ID: id, Status: http.StatusOK, Body: body}
+}
@@ -0,0 +1,110 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// jobBackend reports a job as pending for the first reads and done afterwards; updates
+// are stored like storeBackend does
+type jobBackend struct {
+	reads *int32
+}
+
+func (b jobBackend) HandleRequest(ctx context.Context, req *logical.Request) (*logical.Response, error) {
+	if req.Operation == logical.UpdateOperation {
+		return storeBackend{}.HandleRequest(ctx, req)
+	}
+	status := "pending"
+	if atomic.AddInt32(b.reads, 1) >= 3 {
+		status = "done"
+	}
+	return &logical.Response{Data: map[string]interface{}{"status": status, "id": req.Path}}, nil
+}
+
+func TestRunPipelineWait(t *testing.T) {
+	host, err := NewPluginHost("/fake/path", false, nil, "plugin")
+	if err != nil {
+		t.Fatalf("NewPluginHost failed: %v", err)
+	}
+	var reads int32
+	host.handler.SetBackend(jobBackend{reads: &reads})
+
+	input := strings.Join([]string{
+		`{"id": 1, "wait": {"path": "jobs/{{ job }}", "until": "$.body.data.status", "equals": "done", "interval": "1ms"}, "capture": {"finished": "body.data.id"}}`,
+		`{"id": 2, "wait": {"path": "jobs/a", "until": "$.body.data.status", "equals": "failed", "interval": "5ms", "timeout": "20ms"}}`,
+		`{"id": 3, "operation": "update", "path": "state", "data": {"phase": "ready", "nodes": [1, 2]}}`,
+		`{"id": 4, "wait": {"storage": "state", "until": "$.body.value.nodes[1]", "equals": 2, "interval": "1ms"}}`,
+		`{"id": 5, "wait": {"storage": "state", "until": "body.value.phase", "not_equals": "ready", "interval": "1ms", "timeout": "5ms"}}`,
+		`{"id": 6, "wait": {"storage": "missing", "until": "$.status", "equals": 404}}`,
+		`{"id": 7, "wait": {"path": "jobs/a", "storage": "state", "until": "$.status"}}`,
+		`{"id": 8, "wait": {"path": "jobs/{{ undefined }}", "until": "$.status"}}`,
+		`{"id": 9, "wait": {"path": "jobs/a", "until": "$.status", "timeout": "soon"}}`,
+	}, "\n")
+
+	var out bytes.Buffer
+	if err := runPipeline(host.handler, host.mountPath, map[string]string{"job": "a"}, strings.NewReader(input), &out); err != nil {
+		t.Fatalf("runPipeline failed: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	wantStatus := []int{200, 408, 204, 200, 408, 404, 400, 400, 400}
+	if len(lines) != len(wantStatus) {
+		t.Fatalf("got %d response lines, want %d:\n%s", len(lines), len(wantStatus), out.String())
+	}
+	responses := make([]pipelineResponse, len(lines))
+	for i, want := range wantStatus {
+		if err := json.Unmarshal([]byte(lines[i]), &responses[i]); err != nil {
+			t.Fatalf("line %d: %v", i, err)
+		}
+		if responses[i].Status != want {
+			t.Errorf("line %d: status %d, want %d: %s", i, responses[i].Status, want, lines[i])
+		}
+	}
+
+	if responses[0].Attempts != 3 || !strings.Contains(lines[0], `"status":"done"`) {
+		t.Errorf("wait should return the response that met the condition: %s", lines[0])
+	}
+	if responses[1].Attempts < 2 || !strings.Contains(lines[1], `last value \"done\"`) {
+		t.Errorf("timeout should report the last value: %s", lines[1])
+	}
+	if !strings.Contains(lines[4], `last value \"ready\"`) {
+		t.Errorf("not_equals should time out while the value is unchanged: %s", lines[4])
+	}
+	if !strings.Contains(lines[7], "undefined variable") || responses[7].Attempts != 0 {
+		t.Errorf("an unresolved reference should fail without polling: %s", lines[7])
+	}
+}
+
+func TestParseJSONPath(t *testing.T) {
+	tests := map[string][]string{
+		"$.body.data.status":          {"body", "data", "status"},
+		"body.data.keys.0":            {"body", "data", "keys", "0"},
+		"$.body.data.keys[0]":         {"body", "data", "keys", "0"},
+		`$['body']["data"]['a.b'][2]`: {"body", "data", "a.b", "2"},
+	}
+	for path, want := range tests {
+		got, err := parseJSONPath(path)
+		if err != nil {
+			t.Errorf("parseJSONPath(%q) failed: %v", path, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("parseJSONPath(%q) = %q, want %q", path, got, want)
+		}
+	}
+	for _, bad := range []string{"", "$", "$.body[0", "$.body[*]", "$..body"} {
+		if _, err := parseJSONPath(bad); err == nil {
+			t.Errorf("expected an error for %q", bad)
+		}
+	}
+}
@@ -0,0 +1,620 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"vault-plugin-host/handlers"
+
+	"github.com/hashicorp/vault/sdk/helper/consts"
+	"github.com/hashicorp/vault/sdk/helper/pluginutil"
+)
+
+const (
+	// maxPluginArtifact bounds a downloaded artifact and what is extracted from it
+	maxPluginArtifact = 512 << 20
+
+	// pluginDownloadTimeout bounds one download, from the manifest to the last byte
+	pluginDownloadTimeout = 5 * time.Minute
+)
+
+// ociManifestTypes are the manifests accepted from a registry: OCI images and ORAS
+// artifacts, and Docker images
+var ociManifestTypes = []string{
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// pluginDownloader fetches plugin artifacts into the plugin catalog directory, as
+// Vault does for plugins registered with download=true: from a release URL, checked
+// against a SHA256SUMS file, or from an OCI registry, checked against the digests of
+// the manifest. The extracted binary is checked against the runner's SHA256 if it has
+// one. A nil downloader downloads nothing.
+type pluginDownloader struct {
+	url      string // template of the artifact URL
+	sums     string // template of the URL of the SHA256SUMS listing the artifact
+	registry string // repository prefix of plugin images, <registry>/<name>:<version>
+	dir      string // catalog directory the binaries are written to
+	client   *http.Client
+}
+
+// newPluginDownloader checks the -plugin-download-* flags. It returns nil when no
+// download source is given.
+func newPluginDownloader(urlTemplate, sumsTemplate, registry, dir string) (*pluginDownloader, error) {
+	if urlTemplate == "" && sumsTemplate == "" && registry == "" {
+		return nil, nil
+	}
+	if sumsTemplate != "" && urlTemplate == "" {
+		return nil, fmt.Errorf("-plugin-download-sums needs -plugin-download-url")
+	}
+	if dir == "" {
+		return nil, fmt.Errorf("plugin downloads need -plugin-dir to extract into")
+	}
+	for _, template := range []string{urlTemplate, sumsTemplate} {
+		if template == "" {
+			continue
+		}
+		u, err := url.Parse(expandDownloadURL(template, "plugin", "1.0.0"))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return nil, fmt.Errorf("%q is not an http or https URL template", template)
+		}
+	}
+	return &pluginDownloader{
+		url:      urlTemplate,
+		sums:     sumsTemplate,
+		registry: strings.TrimSuffix(registry, "/"),
+		dir:      dir,
+		client:   &http.Client{Timeout: pluginDownloadTimeout},
+	}, nil
+}
+
+// String describes the download sources for the startup output
+func (d *pluginDownloader) String() string {
+	var parts []string
+	if d.url != "" {
+		source := d.url
+		if d.sums != "" {
+			source += fmt.Sprintf(" (checked against %s)", d.sums)
+		}
+		parts = append(parts, source)
+	}
+	if d.registry != "" {
+		parts = append(parts, fmt.Sprintf("oci://%s/<name>", d.registry))
+	}
+	return fmt.Sprintf("%s into %s", strings.Join(parts, ", "), d.dir)
+}
+
+// expandDownloadURL fills in the {{name}}, {{version}}, {{os}} and {{arch}} of a URL
+// template. The version loses its leading v, as release URLs are named.
+func expandDownloadURL(template, name, version string) string {
+	return strings.NewReplacer(
+		"{{name}}", name,
+		"{{version}}", strings.TrimPrefix(version, "v"),
+		"{{os}}", runtime.GOOS,
+		"{{arch}}", runtime.GOARCH,
+	).Replace(template)
+}
+
+// download fetches the artifact of runner, extracts its binary into the catalog
+// directory and points runner.Command at it. An OCI image on the runner is pulled
+// from its registry; otherwise the URL template is used, then -plugin-download-registry.
+func (d *pluginDownloader) download(ctx context.Context, runner *pluginutil.PluginRunner) error {
+	if d == nil {
+		return fmt.Errorf("plugin downloads are not configured; see -plugin-download-url and -plugin-download-registry")
+	}
+	if runner.Name == "" || runner.Name != filepath.Base(runner.Name) || strings.HasPrefix(runner.Name, ".") {
+		return fmt.Errorf("invalid plugin name %q", runner.Name)
+	}
+	if runner.Version == "" {
+		return fmt.Errorf("a version is required to download plugin %s", runner.Name)
+	}
+	ctx, cancel := context.WithTimeout(ctx, pluginDownloadTimeout)
+	defer cancel()
+
+	var artifact []byte
+	var err error
+	switch {
+	case runner.OCIImage != "":
+		artifact, err = d.pull(ctx, runner.OCIImage, runner.Name, runner.Version)
+	case d.url != "":
+		// Only the registry's digests vouch for an artifact by themselves
+		if d.sums == "" && len(runner.Sha256) == 0 {
+			return fmt.Errorf("plugin %s has no sha256 to check the download against, and -plugin-download-sums is not set", runner.Name)
+		}
+		artifact, err = d.fetchRelease(ctx, runner.Name, runner.Version)
+	case d.registry != "":
+		artifact, err = d.pull(ctx, d.registry+"/"+runner.Name, runner.Name, runner.Version)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to download plugin %s %s: %w", runner.Name, runner.Version, err)
+	}
+
+	binary, err := extractPluginBinary(artifact, runner.Name)
+	if err != nil {
+		return fmt.Errorf("failed to extract plugin %s %s: %w", runner.Name, runner.Version, err)
+	}
+	sum := sha256.Sum256(binary)
+	if len(runner.Sha256) > 0 && !bytes.Equal(runner.Sha256, sum[:]) {
+		return fmt.Errorf("SHA256 of the downloaded plugin %s is %x, but %x was expected", runner.Name, sum, runner.Sha256)
+	}
+
+	command, err := d.install(runner.Name, binary)
+	if err != nil {
+		return err
+	}
+	runner.Command = command
+	runner.Sha256 = sum[:]
+	return nil
+}
+
+// fetchRelease downloads an artifact from the URL template and checks it against the
+// SHA256SUMS template, when one is given
+func (d *pluginDownloader) fetchRelease(ctx context.Context, name, version string) ([]byte, error) {
+	artifactURL := expandDownloadURL(d.url, name, version)
+	artifact, err := d.get(ctx, artifactURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if d.sums == "" {
+		return artifact, nil
+	}
+
+	sumsURL := expandDownloadURL(d.sums, name, version)
+	listing, err := d.get(ctx, sumsURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	sums, err := parseSHA256Sums(listing, sumsURL)
+	if err != nil {
+		return nil, err
+	}
+	u, _ := url.Parse(artifactURL)
+	file := path.Base(u.Path)
+	want, ok := sums[file]
+	if !ok {
+		return nil, fmt.Errorf("%s is not listed in %s", file, sumsURL)
+	}
+	sum := sha256.Sum256(artifact)
+	if got := hex.EncodeToString(sum[:]); got != want {
+		return nil, fmt.Errorf("SHA256 of %s is %s, but %s lists %s", file, got, sumsURL, want)
+	}
+	return artifact, nil
+}
+
+// pull downloads the artifact of a plugin image through the registry API: the
+// manifest of the image's tag (the plugin version unless the reference has one or a
+// digest), then the layer holding the plugin, checked against its digest
+func (d *pluginDownloader) pull(ctx context.Context, image, name, version string) ([]byte, error) {
+	registry, repository, reference, err := parseImageReference(strings.TrimPrefix(image, "oci://"))
+	if err != nil {
+		return nil, err
+	}
+	if reference == "" {
+		reference = version
+	}
+	base := registryScheme(registry) + "://" + registry + "/v2/" + repository
+	auth := &registryAuth{}
+
+	data, err := d.get(ctx, base+"/manifests/"+reference, auth, ociManifestTypes...)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(reference, "sha256:") {
+		if err := checkDigest(data, reference); err != nil {
+			return nil, fmt.Errorf("manifest of %s: %w", image, err)
+		}
+	}
+	var manifest struct {
+		Layers []struct {
+			Digest      string            `json:"digest"`
+			Size        int64             `json:"size"`
+			Annotations map[string]string `json:"annotations"`
+		} `json:"layers"`
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("invalid manifest of %s: %w", image, err)
+	}
+
+	// An artifact pushed with oras names its files in the title annotation
+	layer := -1
+	for i, l := range manifest.Layers {
+		if title := l.Annotations["org.opencontainers.image.title"]; title == name || strings.HasPrefix(title, name+"_") {
+			layer = i
+			break
+		}
+	}
+	if layer < 0 && len(manifest.Layers) == 1 {
+		layer = 0
+	}
+	if layer < 0 {
+		return nil, fmt.Errorf("%s has %d layers and none is titled %s", image, len(manifest.Layers), name)
+	}
+	digest := manifest.Layers[layer].Digest
+	if !strings.HasPrefix(digest, "sha256:") {
+		return nil, fmt.Errorf("layer %q of %s is not a SHA256 digest", digest, image)
+	}
+	if manifest.Layers[layer].Size > maxPluginArtifact {
+		return nil, fmt.Errorf("layer %s of %s is larger than %d bytes", digest, image, maxPluginArtifact)
+	}
+
+	blob, err := d.get(ctx, base+"/blobs/"+digest, auth)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkDigest(blob, digest); err != nil {
+		return nil, fmt.Errorf("layer of %s: %w", image, err)
+	}
+	return blob, nil
+}
+
+// parseImageReference splits registry/repository[:tag][@digest]. The first component
+// is always the registry, so references must be fully qualified.
+func parseImageReference(image string) (registry, repository, reference string, err error) {
+	name := image
+	if i := strings.Index(name, "@"); i >= 0 {
+		name, reference = name[:i], name[i+1:]
+	} else if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name, reference = name[:i], name[i+1:]
+	}
+	registry, repository, ok := strings.Cut(name, "/")
+	if !ok || registry == "" || repository == "" {
+		return "", "", "", fmt.Errorf("image %q is not of the form registry/repository[:tag]", image)
+	}
+	return registry, repository, reference, nil
+}
+
+// registryScheme reaches registries on the loopback interface over plain HTTP, as
+// Docker does, and all others over HTTPS
+func registryScheme(registry string) string {
+	host, _, err := net.SplitHostPort(registry)
+	if err != nil {
+		host = registry
+	}
+	if host == "localhost" {
+		return "http"
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return "http"
+	}
+	return "https"
+}
+
+// checkDigest checks data against a sha256:<hex> digest
+func checkDigest(data []byte, digest string) error {
+	sum := sha256.Sum256(data)
+	if got := "sha256:" + hex.EncodeToString(sum[:]); got != digest {
+		return fmt.Errorf("digest is %s, expected %s", got, digest)
+	}
+	return nil
+}
+
+// registryAuth holds the anonymous bearer token a registry handed out for a pull
+type registryAuth struct {
+	token string
+}
+
+// get fetches a URL, at most maxPluginArtifact bytes of it. With auth, a registry's
+// bearer challenge is answered with an anonymous token, as for public images.
+func (d *pluginDownloader) get(ctx context.Context, rawURL string, auth *registryAuth, accept ...string) ([]byte, error) {
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		if len(accept) > 0 {
+			req.Header.Set("Accept", strings.Join(accept, ", "))
+		}
+		if auth != nil && auth.token != "" {
+			req.Header.Set("Authorization", "Bearer "+auth.token)
+		}
+		resp, err := d.client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(io.LimitReader(resp.Body, maxPluginArtifact+1))
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", rawURL, err)
+		}
+
+		if resp.StatusCode == http.StatusUnauthorized && auth != nil && attempt == 0 {
+			if auth.token, err = d.registryToken(ctx, resp.Header.Get("WWW-Authenticate")); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("GET %s: %s", rawURL, resp.Status)
+		}
+		if len(data) > maxPluginArtifact {
+			return nil, fmt.Errorf("%s is larger than %d bytes", rawURL, maxPluginArtifact)
+		}
+		return data, nil
+	}
+}
+
+// registryToken asks the token service named in a Bearer challenge for an anonymous
+// token
+func (d *pluginDownloader) registryToken(ctx context.Context, challenge string) (string, error) {
+	scheme, params, _ := strings.Cut(challenge, " ")
+	if !strings.EqualFold(scheme, "Bearer") {
+		return "", fmt.Errorf("registry asks for %q authentication; only anonymous pulls are supported", scheme)
+	}
+	values := url.Values{}
+	var realm string
+	for _, param := range splitChallengeParams(params) {
+		key, value, _ := strings.Cut(param, "=")
+		value = strings.Trim(value, `"`)
+		if key == "realm" {
+			realm = value
+		} else if key == "service" || key == "scope" {
+			values.Set(key, value)
+		}
+	}
+	if realm == "" {
+		return "", fmt.Errorf("registry challenge %q has no realm", challenge)
+	}
+	tokenURL := realm
+	if len(values) > 0 {
+		tokenURL += "?" + values.Encode()
+	}
+
+	data, err := d.get(ctx, tokenURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to get a registry token: %w", err)
+	}
+	var response struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.Unmarshal(data, &response); err != nil {
+		return "", fmt.Errorf("invalid registry token response: %w", err)
+	}
+	if response.Token != "" {
+		return response.Token, nil
+	}
+	if response.AccessToken != "" {
+		return response.AccessToken, nil
+	}
+	return "", errors.New("registry token response has no token")
+}
+
+// splitChallengeParams splits the parameters of a challenge on commas outside quotes,
+// since scopes carry commas of their own
+func splitChallengeParams(params string) []string {
+	var parts []string
+	var quoted bool
+	start := 0
+	for i, c := range params {
+		switch {
+		case c == '"':
+			quoted = !quoted
+		case c == ',' && !quoted:
+			parts = append(parts, strings.TrimSpace(params[start:i]))
+			start = i + 1
+		}
+	}
+	return append(parts, strings.TrimSpace(params[start:]))
+}
+
+// archiveFile is a regular file read from an artifact
+type archiveFile struct {
+	name string
+	data []byte
+}
+
+// extractPluginBinary returns the plugin binary in a zip or tar.gz artifact, or the
+// artifact itself when it is neither. In an archive the binary is the file named after
+// the plugin, or name_<version> as HashiCorp releases name them, or the only file.
+func extractPluginBinary(artifact []byte, name string) ([]byte, error) {
+	var files []archiveFile
+	var err error
+	switch {
+	case bytes.HasPrefix(artifact, []byte("PK\x03\x04")):
+		files, err = readZip(artifact)
+	case bytes.HasPrefix(artifact, []byte{0x1f, 0x8b}):
+		files, err = readTarGz(artifact)
+	default:
+		return artifact, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []archiveFile
+	for _, f := range files {
+		base := path.Base(f.name)
+		if base == name || strings.HasPrefix(base, name+"_") {
+			matches = append(matches, f)
+		}
+	}
+	if len(matches) == 0 && len(files) == 1 {
+		matches = files
+	}
+	if len(matches) != 1 {
+		names := make([]string, len(files))
+		for i, f := range files {
+			names[i] = f.name
+		}
+		return nil, fmt.Errorf("expected one binary named %s in the archive, found %s", name, strings.Join(names, ", "))
+	}
+	return matches[0].data, nil
+}
+
+func readZip(artifact []byte) ([]archiveFile, error) {
+	archive, err := zip.NewReader(bytes.NewReader(artifact), int64(len(artifact)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid zip archive: %w", err)
+	}
+	var files []archiveFile
+	var total int
+	for _, f := range archive.File {
+		if !f.Mode().IsRegular() {
+			continue
+		}
+		r, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("invalid zip archive: %w", err)
+		}
+		data, err := readArchiveFile(r, &total)
+		r.Close()
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, archiveFile{name: f.Name, data: data})
+	}
+	return files, nil
+}
+
+func readTarGz(artifact []byte) ([]archiveFile, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(artifact))
+	if err != nil {
+		return nil, fmt.Errorf("invalid gzip archive: %w", err)
+	}
+	defer gz.Close()
+
+	archive := tar.NewReader(gz)
+	var files []archiveFile
+	var total int
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			return files, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid tar archive: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		data, err := readArchiveFile(archive, &total)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, archiveFile{name: header.Name, data: data})
+	}
+}
+
+// readArchiveFile reads a file of an archive, keeping the total extracted within
+// maxPluginArtifact so a compressed bomb cannot exhaust memory
+func readArchiveFile(r io.Reader, total *int) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, int64(maxPluginArtifact-*total+1)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract archive: %w", err)
+	}
+	*total += len(data)
+	if *total > maxPluginArtifact {
+		return nil, fmt.Errorf("archive extracts to more than %d bytes", maxPluginArtifact)
+	}
+	return data, nil
+}
+
+// install writes a binary into the catalog directory under the plugin's name. It is
+// renamed into place, so a running copy of the plugin is not disturbed.
+func (d *pluginDownloader) install(name string, binary []byte) (string, error) {
+	tmp, err := os.CreateTemp(d.dir, "."+name+"-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to install plugin %s: %w", name, err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(binary); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("failed to install plugin %s: %w", name, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("failed to install plugin %s: %w", name, err)
+	}
+	if err := os.Chmod(tmp.Name(), 0o755); err != nil {
+		return "", fmt.Errorf("failed to install plugin %s: %w", name, err)
+	}
+	command := filepath.Join(d.dir, name)
+	if err := os.Rename(tmp.Name(), command); err != nil {
+		return "", fmt.Errorf("failed to install plugin %s: %w", name, err)
+	}
+	return command, nil
+}
+
+// HandleCatalog serves PUT /v1/sys/plugins/catalog/<type>/<name> for plugins
+// registered with download=true, as Vault's plugin register -download does: the plugin
+// is downloaded through the system view into -plugin-dir, where POST /v1/sys/mounts can
+// launch it by name. Binaries are otherwise placed in -plugin-dir by hand.
+func (d *pluginDownloader) HandleCatalog(w http.ResponseWriter, r *http.Request) {
+	if d == nil {
+		handlers.WriteError(w, http.StatusNotFound, "plugin downloads are not configured")
+		return
+	}
+	if r.Method != http.MethodPut && r.Method != http.MethodPost {
+		handlers.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	typeName, name, ok := strings.Cut(strings.TrimPrefix(r.URL.Path, "/v1/sys/plugins/catalog/"), "/")
+	pluginType, err := consts.ParsePluginType(typeName)
+	if !ok || err != nil || name == "" || strings.Contains(name, "/") {
+		handlers.WriteError(w, http.StatusBadRequest, "expected /v1/sys/plugins/catalog/<auth|secret|database>/<name>")
+		return
+	}
+
+	var body struct {
+		Version  string `json:"version"`
+		SHA256   string `json:"sha256"`
+		OCIImage string `json:"oci_image"`
+		Download bool   `json:"download"`
+	}
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&body); err != nil {
+		handlers.WriteError(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+		return
+	}
+	if !body.Download {
+		handlers.WriteError(w, http.StatusBadRequest, "only download=true registrations are supported; place other plugins in -plugin-dir")
+		return
+	}
+	sum, err := hex.DecodeString(body.SHA256)
+	if err != nil || (len(sum) != 0 && len(sum) != sha256.Size) {
+		handlers.WriteError(w, http.StatusBadRequest, fmt.Sprintf("invalid sha256 %q", body.SHA256))
+		return
+	}
+
+	runner := &pluginutil.PluginRunner{
+		Name:     name,
+		Type:     pluginType,
+		Version:  body.Version,
+		OCIImage: body.OCIImage,
+		Sha256:   sum,
+		Download: true,
+	}
+	view := &TestSystemView{downloads: d}
+	if err := view.DownloadExtractVerifyPlugin(r.Context(), runner); err != nil {
+		handlers.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	handlers.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"data": map[string]interface{}{
+			"name":    runner.Name,
+			"type":    runner.Type.String(),
+			"version": runner.Version,
+			"command": runner.Command,
+			"sha256":  hex.EncodeToString(runner.Sha256),
+		},
+	})
+}
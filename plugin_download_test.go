@@ -0,0 +1,292 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/helper/pluginutil"
+)
+
+func zipArtifact(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, content := range files {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte(content))
+	}
+	w.Close()
+	return buf.Bytes()
+}
+
+func tarGzArtifact(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	w := tar.NewWriter(gz)
+	for name, content := range files {
+		w.WriteHeader(&tar.Header{Name: name, Mode: 0o755, Size: int64(len(content)), Typeflag: tar.TypeReg})
+		w.Write([]byte(content))
+	}
+	w.Close()
+	gz.Close()
+	return buf.Bytes()
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestExtractPluginBinary(t *testing.T) {
+	for name, tc := range map[string]struct {
+		artifact []byte
+		want     string
+	}{
+		"raw":           {[]byte("binary"), "binary"},
+		"zip by name":   {zipArtifact(t, map[string]string{"vault-plugin-x_v1.0.0": "binary", "LICENSE.txt": "license"}), "binary"},
+		"tar.gz single": {tarGzArtifact(t, map[string]string{"dist/plugin": "binary"}), "binary"},
+	} {
+		got, err := extractPluginBinary(tc.artifact, "vault-plugin-x")
+		if err != nil || string(got) != tc.want {
+			t.Errorf("%s: extractPluginBinary = %q, %v", name, got, err)
+		}
+	}
+
+	ambiguous := zipArtifact(t, map[string]string{"README": "readme", "LICENSE": "license"})
+	if _, err := extractPluginBinary(ambiguous, "vault-plugin-x"); err == nil {
+		t.Error("an archive without the plugin binary should be refused")
+	}
+}
+
+func TestPluginDownloaderRelease(t *testing.T) {
+	binary := "plugin build 1"
+	file := fmt.Sprintf("vault-plugin-x_1.2.0_%s_%s.zip", runtime.GOOS, runtime.GOARCH)
+	artifact := zipArtifact(t, map[string]string{"vault-plugin-x_v1.2.0": binary})
+	sums := sha256Hex(artifact) + "  " + file + "\n"
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/vault-plugin-x/1.2.0/" + file:
+			w.Write(artifact)
+		case "/vault-plugin-x/1.2.0/SHA256SUMS":
+			w.Write([]byte(sums))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	d, err := newPluginDownloader(
+		server.URL+"/{{name}}/{{version}}/{{name}}_{{version}}_{{os}}_{{arch}}.zip",
+		server.URL+"/{{name}}/{{version}}/SHA256SUMS", "", dir)
+	if err != nil {
+		t.Fatalf("newPluginDownloader failed: %v", err)
+	}
+	view := &TestSystemView{downloads: d}
+
+	runner := &pluginutil.PluginRunner{Name: "vault-plugin-x", Version: "v1.2.0", Download: true}
+	if err := view.DownloadExtractVerifyPlugin(context.Background(), runner); err != nil {
+		t.Fatalf("DownloadExtractVerifyPlugin failed: %v", err)
+	}
+	if runner.Command != filepath.Join(dir, "vault-plugin-x") || hex.EncodeToString(runner.Sha256) != sha256Hex([]byte(binary)) {
+		t.Errorf("runner = %s %x", runner.Command, runner.Sha256)
+	}
+	if data, _ := os.ReadFile(runner.Command); string(data) != binary {
+		t.Errorf("installed %q", data)
+	}
+	if info, _ := os.Stat(runner.Command); info.Mode().Perm() != 0o755 {
+		t.Errorf("installed with mode %v", info.Mode())
+	}
+
+	wrong := &pluginutil.PluginRunner{Name: "vault-plugin-x", Version: "v1.2.0", Sha256: make([]byte, sha256.Size)}
+	if err := view.DownloadExtractVerifyPlugin(context.Background(), wrong); err == nil || !strings.Contains(err.Error(), "was expected") {
+		t.Errorf("a binary with another SHA256 should be refused, got %v", err)
+	}
+
+	sums = strings.Repeat("0", 64) + "  " + file + "\n"
+	if err := view.DownloadExtractVerifyPlugin(context.Background(), runner); err == nil || !strings.Contains(err.Error(), "SHA256SUMS lists") {
+		t.Errorf("an artifact not matching SHA256SUMS should be refused, got %v", err)
+	}
+
+	if err := view.DownloadExtractVerifyPlugin(context.Background(), &pluginutil.PluginRunner{Name: "../x", Version: "1"}); err == nil {
+		t.Error("a plugin name with a path should be refused")
+	}
+	if err := (&TestSystemView{}).DownloadExtractVerifyPlugin(context.Background(), runner); err == nil {
+		t.Error("without a downloader the call should fail")
+	}
+}
+
+func TestNewPluginDownloader(t *testing.T) {
+	if d, err := newPluginDownloader("", "", "", ""); d != nil || err != nil {
+		t.Errorf("newPluginDownloader with no options = %v, %v", d, err)
+	}
+	for _, args := range [][4]string{
+		{"https://example.com/{{name}}.zip", "", "", ""},
+		{"", "https://example.com/SHA256SUMS", "", "/tmp"},
+		{"ftp://example.com/{{name}}.zip", "", "", "/tmp"},
+	} {
+		if _, err := newPluginDownloader(args[0], args[1], args[2], args[3]); err == nil {
+			t.Errorf("newPluginDownloader%q should fail", args)
+		}
+	}
+
+	// Without SHA256SUMS a release download needs the runner's SHA256
+	d, _ := newPluginDownloader("https://example.com/{{name}}.zip", "", "", t.TempDir())
+	if err := d.download(context.Background(), &pluginutil.PluginRunner{Name: "x", Version: "1"}); err == nil || !strings.Contains(err.Error(), "no sha256") {
+		t.Errorf("an unverifiable download should be refused, got %v", err)
+	}
+}
+
+// ociRegistry serves one image through the registry API, behind an anonymous token
+func ociRegistry(t *testing.T, repository, tag string, layer []byte, title string) *httptest.Server {
+	t.Helper()
+	layerDigest := "sha256:" + sha256Hex(layer)
+	manifest, _ := json.Marshal(map[string]interface{}{
+		"schemaVersion": 2,
+		"mediaType":     "application/vnd.oci.image.manifest.v1+json",
+		"layers": []map[string]interface{}{
+			{"digest": "sha256:" + sha256Hex([]byte("other")), "size": 5, "annotations": map[string]string{"org.opencontainers.image.title": "README.md"}},
+			{"digest": layerDigest, "size": len(layer), "annotations": map[string]string{"org.opencontainers.image.title": title}},
+		},
+	})
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			if r.URL.Query().Get("scope") != "repository:"+repository+":pull" {
+				http.Error(w, "bad scope", http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte(`{"token":"anonymous"}`))
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer anonymous" {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="registry",scope="repository:%s:pull"`, server.URL, repository))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/v2/" + repository + "/manifests/" + tag:
+			w.Header().Set("Content-Type", "application/vnd.oci.image.manifest.v1+json")
+			w.Write(manifest)
+		case "/v2/" + repository + "/blobs/" + layerDigest:
+			w.Write(layer)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	return server
+}
+
+func TestPluginDownloaderRegistry(t *testing.T) {
+	binary := "plugin build 2"
+	layer := tarGzArtifact(t, map[string]string{"vault-plugin-x": binary})
+	server := ociRegistry(t, "plugins/vault-plugin-x", "1.2.0", layer, "vault-plugin-x_1.2.0_linux_amd64.tar.gz")
+	defer server.Close()
+	registry := strings.TrimPrefix(server.URL, "http://")
+
+	dir := t.TempDir()
+	d, err := newPluginDownloader("", "", registry+"/plugins", dir)
+	if err != nil {
+		t.Fatalf("newPluginDownloader failed: %v", err)
+	}
+	sum := sha256.Sum256([]byte(binary))
+	runner := &pluginutil.PluginRunner{Name: "vault-plugin-x", Version: "1.2.0", Sha256: sum[:]}
+	if err := d.download(context.Background(), runner); err != nil {
+		t.Fatalf("download failed: %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "vault-plugin-x")); string(data) != binary {
+		t.Errorf("installed %q", data)
+	}
+
+	// An image on the runner is pulled instead, at its own tag
+	runner = &pluginutil.PluginRunner{Name: "vault-plugin-x", Version: "9.9.9", OCIImage: registry + "/plugins/vault-plugin-x:1.2.0"}
+	if err := d.download(context.Background(), runner); err != nil {
+		t.Errorf("download of the runner's image failed: %v", err)
+	}
+	runner = &pluginutil.PluginRunner{Name: "vault-plugin-x", Version: "2.0.0"}
+	if err := d.download(context.Background(), runner); err == nil {
+		t.Error("a missing tag should fail the download")
+	}
+}
+
+func TestParseImageReference(t *testing.T) {
+	for image, want := range map[string][3]string{
+		"ghcr.io/org/plugin":             {"ghcr.io", "org/plugin", ""},
+		"localhost:5000/plugin:1.0":      {"localhost:5000", "plugin", "1.0"},
+		"ghcr.io/org/plugin@sha256:abcd": {"ghcr.io", "org/plugin", "sha256:abcd"},
+	} {
+		registry, repository, reference, err := parseImageReference(image)
+		if err != nil || [3]string{registry, repository, reference} != want {
+			t.Errorf("parseImageReference(%q) = %s %s %s, %v", image, registry, repository, reference, err)
+		}
+	}
+	if _, _, _, err := parseImageReference("plugin:1.0"); err == nil {
+		t.Error("a reference without a registry should be refused")
+	}
+	if registryScheme("127.0.0.1:5000") != "http" || registryScheme("ghcr.io") != "https" {
+		t.Error("only loopback registries should be reached over plain HTTP")
+	}
+}
+
+func TestHandleCatalog(t *testing.T) {
+	binary := []byte("plugin build 3")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(binary)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	d, _ := newPluginDownloader(server.URL+"/{{name}}", "", "", dir)
+	register := func(d *pluginDownloader, path, body string) (int, map[string]interface{}) {
+		w := httptest.NewRecorder()
+		d.HandleCatalog(w, httptest.NewRequest(http.MethodPut, path, strings.NewReader(body)))
+		var response map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return w.Code, response
+	}
+
+	code, response := register(d, "/v1/sys/plugins/catalog/secret/vault-plugin-x", `{"version":"v1.0.0","sha256":"`+sha256Hex(binary)+`","download":true}`)
+	if code != http.StatusOK {
+		t.Fatalf("register = %d %v", code, response)
+	}
+	data := response["data"].(map[string]interface{})
+	if data["command"] != filepath.Join(dir, "vault-plugin-x") || data["type"] != "secret" {
+		t.Errorf("register = %v", data)
+	}
+
+	for path, body := range map[string]string{
+		"/v1/sys/plugins/catalog/secret/vault-plugin-x": `{"version":"v1.0.0","command":"x"}`,
+		"/v1/sys/plugins/catalog/widget/vault-plugin-x": `{"version":"v1.0.0","download":true}`,
+		"/v1/sys/plugins/catalog/auth/vault-plugin-y":   `{"version":"v1.0.0","sha256":"nothex","download":true}`,
+	} {
+		if code, _ := register(d, path, body); code != http.StatusBadRequest {
+			t.Errorf("PUT %s %s = %d, want 400", path, body, code)
+		}
+	}
+	if code, _ := register(nil, "/v1/sys/plugins/catalog/secret/x", `{}`); code != http.StatusNotFound {
+		t.Errorf("register without downloads = %d, want 404", code)
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// parsePluginEnv checks -plugin-env values. KEY=VALUE sets a variable; a bare KEY
+// passes on the host's value, if any, which matters with -plugin-clear-env.
+func parsePluginEnv(specs []string) ([]string, error) {
+	var env []string
+	for _, spec := range specs {
+		key, _, hasValue := strings.Cut(spec, "=")
+		if key == "" || strings.ContainsAny(key, " \t\x00") {
+			return nil, fmt.Errorf("invalid -plugin-env %q: expected KEY=VALUE or KEY", spec)
+		}
+		if !hasValue {
+			value, ok := os.LookupEnv(key)
+			if !ok {
+				continue
+			}
+			spec = key + "=" + value
+		}
+		env = append(env, spec)
+	}
+	return env, nil
+}
+
+// splitPluginArgs splits -plugin-args into words as a shell would, on unquoted spaces,
+// honouring single and double quotes and backslashes but expanding nothing
+func splitPluginArgs(s string) ([]string, error) {
+	var (
+		args  []string
+		word  strings.Builder
+		inArg bool
+		quote rune
+	)
+	runes := []rune(s)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\\' && (quote == 0 || (i+1 < len(runes) && strings.ContainsRune(`"\`, runes[i+1]))):
+			if i+1 == len(runes) {
+				return nil, fmt.Errorf("invalid -plugin-args: trailing backslash")
+			}
+			i++
+			word.WriteRune(runes[i])
+			inArg = true
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inArg = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				args = append(args, word.String())
+				word.Reset()
+				inArg = false
+			}
+		default:
+			word.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("invalid -plugin-args: unterminated %c quote", quote)
+	}
+	if inArg {
+		args = append(args, word.String())
+	}
+	return args, nil
+}
@@ -0,0 +1,55 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParsePluginEnv(t *testing.T) {
+	t.Setenv("PLUGIN_ENV_TEST_PROXY", "http://proxy:3128")
+	env, err := parsePluginEnv([]string{"FEATURE_X=on", "NO_PROXY=localhost,10.0.0.0/8", "EMPTY=", "PLUGIN_ENV_TEST_PROXY", "PLUGIN_ENV_TEST_UNSET"})
+	if err != nil {
+		t.Fatalf("parsePluginEnv failed: %v", err)
+	}
+	want := []string{"FEATURE_X=on", "NO_PROXY=localhost,10.0.0.0/8", "EMPTY=", "PLUGIN_ENV_TEST_PROXY=http://proxy:3128"}
+	if !slices.Equal(env, want) {
+		t.Errorf("env = %q, want %q", env, want)
+	}
+
+	for _, spec := range []string{"=value", "", "A KEY=value"} {
+		if _, err := parsePluginEnv([]string{spec}); err == nil {
+			t.Errorf("parsePluginEnv(%q) should fail", spec)
+		}
+	}
+}
+
+func TestSplitPluginArgs(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		want []string
+	}{
+		{"", nil},
+		{"  -v  --mode=dev ", []string{"-v", "--mode=dev"}},
+		{`--name "a b" 'c "d"' e\ f`, []string{"--name", "a b", `c "d"`, "e f"}},
+		{`"" x`, []string{"", "x"}},
+		{`"say \"hi\"" 'a\b'`, []string{`say "hi"`, `a\b`}},
+	} {
+		got, err := splitPluginArgs(tc.in)
+		if err != nil {
+			t.Errorf("splitPluginArgs(%q) failed: %v", tc.in, err)
+			continue
+		}
+		if !slices.Equal(got, tc.want) {
+			t.Errorf("splitPluginArgs(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+
+	for _, in := range []string{`"open`, `'open`, `trailing\`} {
+		if _, err := splitPluginArgs(in); err == nil {
+			t.Errorf("splitPluginArgs(%q) should fail", in)
+		}
+	}
+}
@@ -4,15 +4,17 @@
 package main
 
 import (
-	"bufio"
 	"context"
 	"fmt"
+	"io"
 	"net"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"vault-plugin-host/handlers"
 
@@ -23,6 +25,21 @@ import (
 	backendplugin "github.com/hashicorp/vault/sdk/plugin"
 )
 
+// pluginStderrBufferSize is how much recent plugin stderr output is retained
+const pluginStderrBufferSize = 1 << 20
+
+// defaultPluginLogLines is how many lines of plugin stdout and stderr are kept by default
+const defaultPluginLogLines = 1000
+
+// pprofAddrEnv is the environment variable through which the plugin is told where to serve net/http/pprof
+const pprofAddrEnv = "VAULT_PLUGIN_PPROF_ADDR"
+
+// defaultBackendUUID is the backend UUID of a mount with a storage of its own
+const defaultBackendUUID = "6669da05-b1c8-4f49-97d9-c8e5bed98e20"
+
+// reloadDrainTimeout bounds how long a reload waits for calls to the old plugin to finish
+const reloadDrainTimeout = 10 * time.Second
+
 // versionedPluginSet creates a plugin set that supports versions 3, 4, and 5
 var versionedPluginSet = map[int]plugin.PluginSet{
 	3: {
@@ -36,19 +53,42 @@ var versionedPluginSet = map[int]plugin.PluginSet{
 	},
 }
 
+// logOutput is where host logs are written
+var logOutput io.Writer = os.Stdout
+
 // PluginHost manages the plugin lifecycle and HTTP server
 type PluginHost struct {
-	backend    logical.Backend
-	client     *plugin.Client
-	pluginCmd  *exec.Cmd
-	storage    *InMemoryStorage
-	logger     hclog.Logger
-	pluginPath string
-	config     map[string]string
-	mountPath  string
-	oasDoc     *framework.OASDocument
-	handler    *handlers.Handler
-	mu         sync.RWMutex
+	backend      logical.Backend
+	client       *plugin.Client
+	pluginCmd    *exec.Cmd
+	storage      Storage
+	backendUUID  string // of the backend, and of its storage prefix under a view
+	logger       hclog.Logger
+	pluginPath   string
+	config       map[string]string
+	mountPath    string
+	oasDoc       *framework.OASDocument
+	handler      *handlers.Handler
+	attach       string     // plugin attach string; when set the host attaches instead of launching
+	pprofAddr    string     // "auto", an explicit host:port, or empty to disable the pprof proxy
+	pprofAuto    bool       // pprofAddr was allocated for "auto", so a new process gets a new one
+	artifactsDir string     // directory offered to the plugin for debug files
+	env          []string   // extra environment for the plugin process
+	args         []string   // arguments the plugin process is started with
+	flavor       hostFlavor // server whose handshake the plugin is launched with
+	stderr       *tailBuffer
+	systemView   SystemViewConfig // mount tuning reported to the plugin
+	multiplex    bool             // share one process between mounts of a multiplexed plugin
+	multiplexID  string           // ID of this mount's backend instance in a multiplexed plugin
+	sandbox      *pluginSandbox   // restrictions on the plugin process (nil for none)
+	verifier     *pluginVerifier  // checks the plugin binary before each launch (nil for none)
+	sha256       string           // hex SHA256 the plugin binary must have, as registered in Vault's catalog
+	mu           sync.RWMutex
+
+	periodicInterval time.Duration // how often the periodic function runs; 0 disables it
+	periodicStop     chan struct{}
+
+	downloads *pluginDownloader // fetches plugins for the system view (nil for none)
 }
 
 // NewPluginHost creates a new plugin host
@@ -61,7 +101,7 @@ func NewPluginHost(pluginPath string, verbose bool, config map[string]string, mo
 	logger := hclog.New(&hclog.LoggerOptions{
 		Name:   "plugin-host",
 		Level:  logLevel,
-		Output: os.Stdout,
+		Output: logOutput,
 	})
 
 	if config == nil {
@@ -70,19 +110,110 @@ func NewPluginHost(pluginPath string, verbose bool, config map[string]string, mo
 
 	storage := NewInMemoryStorage()
 	handler := handlers.NewHandler(nil, storage, logger, mountPath)
+	handler.SetTraceOutput(logOutput)
+	handler.SetPluginType(filepath.Base(pluginPath))
+	handler.SetPluginLogs(handlers.NewPluginLogs(logger.Named("plugin.stdio"), defaultPluginLogLines))
 
 	return &PluginHost{
-		pluginPath: pluginPath,
-		storage:    storage,
-		logger:     logger,
-		config:     config,
-		mountPath:  mountPath,
-		handler:    handler,
+		pluginPath:  pluginPath,
+		storage:     storage,
+		logger:      logger,
+		config:      config,
+		mountPath:   mountPath,
+		handler:     handler,
+		stderr:      newTailBuffer(pluginStderrBufferSize),
+		systemView:  DefaultSystemViewConfig(),
+		flavor:      hostFlavors["vault"],
+		backendUUID: defaultBackendUUID,
+
+		periodicInterval: defaultPeriodicInterval,
 	}, nil
 }
 
+// SetStorageView gives the plugin a view of base below logical/<uuid>/, with a UUID
+// of its own, in place of a storage of its own; it must be called before Start
+func (h *PluginHost) SetStorageView(base Storage) {
+	h.mu.Lock()
+	h.backendUUID = mountBackendUUID(h.mountPath)
+	h.mu.Unlock()
+	h.SetStorage(newMountStorageView(base, h.backendUUID))
+}
+
+// SetStorage replaces the storage given to the plugin; it must be called before Start
+func (h *PluginHost) SetStorage(storage Storage) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.storage = storage
+	h.handler.SetStorage(storage)
+}
+
+// SetSystemViewConfig sets the mount tuning the system view reports to the plugin; it
+// must be called before Start
+func (h *PluginHost) SetSystemViewConfig(config SystemViewConfig) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.systemView = config
+}
+
+// SetMultiplex makes mounts of the same multiplexed plugin binary share one process,
+// as Vault does; it must be called before Start
+func (h *PluginHost) SetMultiplex(multiplex bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.multiplex = multiplex
+}
+
+// SetFlavor launches the plugin as flavor's server does; it must be called before Start
+func (h *PluginHost) SetFlavor(flavor hostFlavor) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.flavor = flavor
+}
+
+// SetSandbox restricts the plugin process launched by Start; nil removes the restrictions
+func (h *PluginHost) SetSandbox(sandbox *pluginSandbox) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.sandbox = sandbox
+}
+
+// SetPluginLogLines makes the host keep the last n lines the plugin writes to stdout
+// and stderr for /v1/sys/plugin/logs
+func (h *PluginHost) SetPluginLogLines(n int) {
+	h.handler.SetPluginLogs(handlers.NewPluginLogs(h.logger.Named("plugin.stdio"), n))
+}
+
+// SetVerifier makes every launch of the plugin check its binary first
+func (h *PluginHost) SetVerifier(verifier *pluginVerifier) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.verifier = verifier
+}
+
+// SetSHA256 makes every launch of the plugin check that its binary has the hex SHA256
+// digest; empty removes the check
+func (h *PluginHost) SetSHA256(digest string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.sha256 = digest
+}
+
+// SetDownloader lets the system view download plugin artifacts into the catalog
+func (h *PluginHost) SetDownloader(downloads *pluginDownloader) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.downloads = downloads
+}
+
 // Start launches the plugin process
 func (h *PluginHost) Start() error {
+	return h.start(true)
+}
+
+// start launches the plugin process, or with share set and multiplexing enabled, joins
+// the running process of the same binary
+func (h *PluginHost) start(share bool) error {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
@@ -91,15 +222,29 @@ func (h *PluginHost) Start() error {
 	}
 
 	pluginLogger := h.logger.Named("plugin")
+	logs := h.handler.PluginLogs()
+
+	if h.multiplex && share && h.attach == "" {
+		if process := sharedProcesses.acquire(h.pluginPath); process != nil {
+			h.logger.Info("joining multiplexed plugin process", "pid", process.cmd.Process.Pid)
+			if err := h.setupBackend(process.client, process.connection, pluginLogger); err != nil {
+				sharedProcesses.release(process.client)
+				return err
+			}
+			h.pluginCmd = process.cmd
+			return nil
+		}
+	}
 
 	var cmd *exec.Cmd
 	var clientConfig *plugin.ClientConfig
+	var connection *handlers.PluginConnection
 
 	// Check if attach string was provided via command-line flag
-	if attachString != nil && *attachString != "" {
-		h.logger.Info("parsing plugin attach string", "string", *attachString)
+	if h.attach != "" {
+		h.logger.Info("parsing plugin attach string", "string", h.attach)
 
-		parts := strings.Split(strings.TrimSuffix(*attachString, "|"), "|")
+		parts := strings.Split(strings.TrimSuffix(strings.TrimSpace(h.attach), "|"), "|")
 		if len(parts) < 5 {
 			return fmt.Errorf("invalid attach string format, expected 'version|maxversion|network|socket|protocol|'")
 		}
@@ -120,6 +265,13 @@ func (h *PluginHost) Start() error {
 			"socket", socketPath,
 			"protocol", protoType,
 			"version", protoVersion)
+		connection = &handlers.PluginConnection{
+			Protocol:        protoType,
+			ProtocolVersion: protoVersion,
+			Network:         network,
+			Address:         socketPath,
+			Attached:        true,
+		}
 
 		external := &plugin.ExternalConfig{
 			Protocol:        protocol,
@@ -135,137 +287,156 @@ func (h *PluginHost) Start() error {
 			VersionedPlugins: versionedPluginSet,
 			External:         external,
 			Logger:           pluginLogger,
+			SyncStdout:       logs.Writer("stdout"),
+			SyncStderr:       logs.Writer("stderr"),
 		}
 	} else {
-		// Start plugin process manually and capture reattach info
-		h.logger.Info("starting plugin process manually to capture reattach info")
-
-		cmd = exec.Command(h.pluginPath)
-		cmd.Env = append(os.Environ(),
-			"PLUGIN_PROTOCOL_VERSIONS=4",
-			"VAULT_BACKEND_PLUGIN=6669da05-b1c8-4f49-97d9-c8e5bed98e20",
-			"VAULT_PLUGIN_AUTOMTLS_ENABLED=true",
-			"VAULT_VERSION=1.18.0",
-		)
-
-		// Capture stdout to get reattach info
-		stdout, err := cmd.StdoutPipe()
+		// Refuse a binary that is not the one the pipeline promoted
+		digest, err := h.verifier.verify(h.pluginPath)
 		if err != nil {
-			return fmt.Errorf("failed to create stdout pipe: %w", err)
+			return fmt.Errorf("plugin binary failed verification: %w", err)
 		}
-
-		// Start the process
-		if err := cmd.Start(); err != nil {
-			return fmt.Errorf("failed to start plugin process: %w", err)
-		}
-
-		// Read the reattach string from stdout
-		scanner := bufio.NewScanner(stdout)
-		var reattachInfo string
-		for scanner.Scan() {
-			line := scanner.Text()
-			h.logger.Debug("plugin output", "line", line)
-			// Look for the reattach string (format: 1|5|unix|/path|grpc|)
-			if strings.Contains(line, "|unix|") || strings.Contains(line, "|tcp|") {
-				reattachInfo = strings.TrimSpace(line)
-				break
+		if h.sha256 != "" {
+			if err := checkSHA256(h.pluginPath, h.sha256); err != nil {
+				return fmt.Errorf("plugin binary failed verification: %w", err)
 			}
+			digest = h.sha256
 		}
-
-		if reattachInfo == "" {
-			cmd.Process.Kill()
-			return fmt.Errorf("failed to get reattach info from plugin")
+		if digest != "" {
+			h.logger.Info("plugin binary verified", "sha256", digest)
 		}
 
-		h.logger.Info("captured reattach info", "info", reattachInfo)
+		// go-plugin adds the AutoMTLS client certificate; the other variables the
+		// plugin needs to serve the host come after the -plugin-env ones, so they win
+		env := append(append([]string{}, h.env...), h.flavor.handshakeEnv()...)
 
-		// Parse the reattach string
-		parts := strings.Split(strings.TrimSuffix(reattachInfo, "|"), "|")
-		if len(parts) < 5 {
-			cmd.Process.Kill()
-			return fmt.Errorf("invalid reattach string format: %s", reattachInfo)
+		// Offer the plugin a pprof listen address via the env contract
+		if h.pprofAddr == "auto" || h.pprofAuto {
+			addr, err := freeLocalAddr()
+			if err != nil {
+				return fmt.Errorf("failed to allocate pprof address: %w", err)
+			}
+			h.pprofAddr = addr
+			h.pprofAuto = true
 		}
-
-		protoVersion, _ := strconv.Atoi(parts[1])
-		network := parts[2]
-		socketPath := parts[3]
-		protoType := parts[4]
-
-		var protocol plugin.Protocol
-		if protoType == "grpc" {
-			protocol = plugin.ProtocolGRPC
-		} else {
-			protocol = plugin.ProtocolNetRPC
+		if h.pprofAddr != "" {
+			env = append(env, pprofAddrEnv+"="+h.pprofAddr)
+		}
+		if h.artifactsDir != "" {
+			env = append(env, artifactsDirEnv+"="+h.artifactsDir)
 		}
 
-		// Store the command so we can kill it later
-		h.mu.Unlock()
-		h.pluginCmd = cmd
-		h.mu.Lock()
-
-		external := &plugin.ExternalConfig{
-			Protocol:        protocol,
-			ProtocolVersion: protoVersion,
-			Addr: &net.UnixAddr{
-				Name: socketPath,
-				Net:  network,
-			},
+		cmd, err = h.sandbox.command(h.pluginPath, h.args, env)
+		if err != nil {
+			return err
 		}
 
 		clientConfig = &plugin.ClientConfig{
-			HandshakeConfig:  backendplugin.HandshakeConfig,
-			VersionedPlugins: versionedPluginSet,
-			External:         external,
-			Logger:           pluginLogger,
+			HandshakeConfig:  h.flavor.handshakeConfig(),
+			VersionedPlugins: map[int]plugin.PluginSet{pluginProtocolVersion: versionedPluginSet[pluginProtocolVersion]},
+			AllowedProtocols: []plugin.Protocol{plugin.ProtocolGRPC, plugin.ProtocolNetRPC},
+			Cmd:              cmd,
+			Managed:          true,
+			// As Vault does, so the plugin only serves the host
+			AutoMTLS:     true,
+			SecureConfig: secureConfig(cmd, h.pluginPath, digest),
+			// The sandbox already decided which of the host's variables the plugin gets
+			SkipHostEnv: true,
+			Logger:      pluginLogger,
+			// go-plugin logs stderr; retain it so goroutine dumps and panics can be inspected
+			Stderr:     io.MultiWriter(h.stderr, logs.Keeper("stderr")),
+			SyncStdout: logs.Writer("stdout"),
+			SyncStderr: logs.Writer("stderr"),
 		}
 	}
 
 	client := plugin.NewClient(clientConfig)
+	if cmd != nil {
+		// Launch the process here, so the connection its handshake announced is known
+		if _, err := client.Start(); err != nil {
+			client.Kill()
+			return fmt.Errorf("failed to start plugin process: %w", err)
+		}
+		reattach := client.ReattachConfig()
+		connection = &handlers.PluginConnection{
+			Protocol:        string(reattach.Protocol),
+			ProtocolVersion: client.NegotiatedVersion(),
+			Network:         reattach.Addr.Network(),
+			Address:         reattach.Addr.String(),
+			AutoMTLS:        true,
+			PID:             reattach.Pid,
+		}
+		h.logger.Info("plugin process started", "pid", reattach.Pid, "address", reattach.Addr.String(), "protocol", reattach.Protocol)
+	}
+	if err := h.setupBackend(client, connection, pluginLogger); err != nil {
+		client.Kill()
+		return err
+	}
+	h.pluginCmd = cmd
+	if h.multiplex && h.multiplexID != "" && cmd != nil {
+		// Further mounts of the same binary join this process
+		sharedProcesses.register(h.pluginPath, client, cmd, connection)
+	}
+	return nil
+}
 
+// setupBackend dispenses a backend from the plugin process behind client, sets it up
+// and makes the handler serve it. connection describes how client reaches the process.
+// The caller must hold h.mu.
+func (h *PluginHost) setupBackend(client *plugin.Client, connection *handlers.PluginConnection, pluginLogger hclog.Logger) error {
 	rpcClient, err := client.Client()
 	if err != nil {
-		client.Kill()
 		return fmt.Errorf("failed to get RPC client: %w", err)
 	}
 
-	h.logger.Debug("attempting to dispense backend plugin",
-		"external", clientConfig.External != nil,
-		"versions", fmt.Sprintf("%v", clientConfig.VersionedPlugins))
+	h.logger.Debug("attempting to dispense backend plugin")
 
-	raw, err := rpcClient.Dispense("backend")
+	backend, multiplexID, err := dispenseBackend(rpcClient, filepath.Base(h.pluginPath))
 	if err != nil {
-		client.Kill()
-		return fmt.Errorf("failed to dispense plugin: %w", err)
+		return err
 	}
-
-	backend, ok := raw.(logical.Backend)
-	if !ok {
-		client.Kill()
-		return fmt.Errorf("dispensed plugin is not a logical.Backend")
+	if multiplexID != "" {
+		h.logger.Info("plugin supports multiplexing", "multiplex_id", multiplexID)
 	}
 
-	systemView := &TestSystemView{}
+	replication := h.handler.Replication()
+	tuning := h.systemView
+	systemView := &TestSystemView{wraps: h.handler.WrapStore(), replication: replication, clock: h.handler.Clock(), passwords: h.handler.PasswordPolicies(), downloads: h.downloads, tuning: &tuning}
+	var events logical.EventSender
+	if bus := h.handler.EventBus(); bus != nil {
+		events = bus.Sender(h.mountPath, filepath.Base(h.pluginPath))
+	}
 	backendConfig := &logical.BackendConfig{
-		BackendUUID:         "6669da05-b1c8-4f49-97d9-c8e5bed98e20",
-		StorageView:         h.storage,
+		BackendUUID:         h.backendUUID,
+		StorageView:         h.handler.CallStorage(replication.Storage(h.handler.StorageFaults().Storage(h.storage))),
 		Logger:              pluginLogger,
 		System:              systemView,
 		Config:              h.config,
-		EventsSender:        nil,
+		EventsSender:        events,
 		ObservationRecorder: nil,
 	}
 
 	if err := backend.Setup(context.Background(), backendConfig); err != nil {
-		client.Kill()
 		return fmt.Errorf("failed to setup backend: %w", err)
 	}
 
+	// Initialize backend lifecycle functions before requests reach the backend
+	h.initializeBackendLifecycle(backend)
+
 	h.backend = backend
 	h.client = client
+	h.multiplexID = multiplexID
 	h.handler.SetBackend(backend)
+	if h.pprofAddr != "" && h.pprofAddr != "auto" {
+		h.handler.SetPprofAddr(h.pprofAddr)
+	}
+	if grpcClient, ok := rpcClient.(*plugin.GRPCClient); ok {
+		h.handler.SetGRPCConn(grpcClient.Conn)
+	}
+	h.handler.SetPluginConnection(connection)
+	h.handler.SetBackendSetup(&handlers.BackendSetup{MultiplexID: multiplexID, Config: h.config})
 
-	// Initialize backend lifecycle functions
-	h.initializeBackendLifecycle(backend)
+	h.startPeriodic()
 
 	h.logger.Info("plugin started successfully")
 
@@ -280,27 +451,125 @@ func (h *PluginHost) Stop() {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
-	if h.backend != nil {
-		// Call cleanup lifecycle functions
-		h.cleanupBackendLifecycle()
-		h.backend.Cleanup(context.Background())
-	}
-	if h.client != nil {
-		h.client.Kill()
-	}
-	if h.pluginCmd != nil && h.pluginCmd.Process != nil {
-		h.logger.Info("killing manually started plugin process", "pid", h.pluginCmd.Process.Pid)
-		h.pluginCmd.Process.Kill()
-		h.pluginCmd.Wait() // Clean up zombie process
-	}
+	h.stopPeriodic()
+	h.stopPlugin(h.backend, h.client)
 
 	h.backend = nil
 	h.client = nil
 	h.pluginCmd = nil
 	h.handler.SetBackend(nil)
+	h.handler.SetGRPCConn(nil)
+	h.handler.SetPluginConnection(nil)
+	h.handler.SetBackendSetup(nil)
 	h.logger.Info("plugin stopped")
 }
 
+// stopPlugin cleans up a backend and ends its plugin process
+func (h *PluginHost) stopPlugin(backend logical.Backend, client *plugin.Client) {
+	if backend != nil {
+		// Call cleanup lifecycle functions
+		h.cleanupBackendLifecycle(backend)
+		backend.Cleanup(context.Background())
+	}
+	if client != nil && !sharedProcesses.release(client) {
+		h.logger.Info("leaving multiplexed plugin process running for other mounts")
+		return
+	}
+	if client != nil {
+		// go-plugin asks the process to exit, kills it if it does not and reaps it
+		client.Kill()
+	}
+}
+
+// Restart stops the plugin and launches it again
+func (h *PluginHost) Restart() error {
+	h.Stop()
+	return h.Start()
+}
+
+// Reload launches a new plugin process from the binary and swaps it in for the running
+// one. The old process keeps serving until the new backend is set up and initialized,
+// finishes the calls it has in flight (up to reloadDrainTimeout) and is then stopped.
+// If the new process fails to start the old one stays in place.
+func (h *PluginHost) Reload() error {
+	h.mu.Lock()
+	if h.attach != "" {
+		h.mu.Unlock()
+		return fmt.Errorf("cannot reload a plugin in attach mode")
+	}
+	backend, client, cmd := h.backend, h.client, h.pluginCmd
+	h.stopPeriodic()
+	h.backend = nil
+	h.mu.Unlock()
+
+	draining := make(map[uint64]bool)
+	for _, call := range h.handler.InflightCalls() {
+		draining[call.ID] = true
+	}
+
+	// A reload always launches a new process, which later mounts of the binary then join
+	if err := h.start(false); err != nil {
+		h.mu.Lock()
+		h.backend, h.client, h.pluginCmd = backend, client, cmd
+		if backend != nil {
+			h.startPeriodic()
+		}
+		h.mu.Unlock()
+		return err
+	}
+
+	// Start swapped the handler over, so only calls made before it use the old backend
+	deadline := time.Now().Add(reloadDrainTimeout)
+	for len(draining) > 0 && time.Now().Before(deadline) {
+		time.Sleep(50 * time.Millisecond)
+		still := make(map[uint64]bool)
+		for _, call := range h.handler.InflightCalls() {
+			if draining[call.ID] {
+				still[call.ID] = true
+			}
+		}
+		draining = still
+	}
+
+	h.stopPlugin(backend, client)
+	return nil
+}
+
+// Running reports whether a plugin backend is serving requests
+func (h *PluginHost) Running() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.backend != nil
+}
+
+// Ping checks that the plugin process still answers over its gRPC connection
+func (h *PluginHost) Ping() error {
+	h.mu.RLock()
+	client := h.client
+	h.mu.RUnlock()
+
+	if client == nil {
+		return fmt.Errorf("plugin not started")
+	}
+	rpcClient, err := client.Client()
+	if err != nil {
+		return err
+	}
+	return rpcClient.Ping()
+}
+
+// signalPlugin sends a signal to the launched plugin process
+func (h *PluginHost) signalPlugin(sig os.Signal) error {
+	h.mu.RLock()
+	cmd := h.pluginCmd
+	h.mu.RUnlock()
+
+	if cmd == nil || cmd.Process == nil {
+		return fmt.Errorf("no plugin process to signal")
+	}
+	return cmd.Process.Signal(sig)
+}
+
 // initializeBackendLifecycle initializes backend lifecycle functions
 func (h *PluginHost) initializeBackendLifecycle(backend logical.Backend) {
 	ctx := context.Background()
@@ -315,18 +584,14 @@ func (h *PluginHost) initializeBackendLifecycle(backend logical.Backend) {
 }
 
 // cleanupBackendLifecycle handles cleanup of backend lifecycle functions
-func (h *PluginHost) cleanupBackendLifecycle() {
-	if h.backend == nil {
-		return
-	}
-
+func (h *PluginHost) cleanupBackendLifecycle(backend logical.Backend) {
 	ctx := context.Background()
 
 	// Call InvalidateKey method (standard logical.Backend interface)
 	h.logger.Info("calling backend InvalidateKey for shutdown")
-	h.backend.InvalidateKey(ctx, "shutdown")
+	backend.InvalidateKey(ctx, "shutdown")
 
-	// Note: Cleanup() is called separately in Stop() method
+	// Note: Cleanup() is called separately in stopPlugin()
 }
 
 // listPluginPaths displays all paths and operations supported by the plugin
@@ -469,3 +734,13 @@ func (h *PluginHost) GetOpenAPIDoc() interface{} {
 	defer h.mu.RUnlock()
 	return h.oasDoc
 }
+
+// freeLocalAddr returns a currently unused loopback TCP address
+func freeLocalAddr() (string, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", err
+	}
+	defer l.Close()
+	return l.Addr().String(), nil
+}
@@ -0,0 +1,268 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-plugin"
+)
+
+// cosignTimeout bounds a keyless verification by the cosign CLI, which talks to the
+// Sigstore transparency log when the bundle does not carry its inclusion proof
+const cosignTimeout = time.Minute
+
+// pluginVerifier checks a plugin binary before every launch, as a promotion pipeline
+// would: its SHA256 must be listed in a checksums file, and it must carry a cosign
+// signature made with a given key (cosign sign-blob --key) or, keyless, by a given
+// identity (cosign sign-blob --bundle). A nil verifier admits every binary.
+type pluginVerifier struct {
+	sums     map[string]string // base name of a binary → hex SHA256
+	sumsFile string
+	key      crypto.PublicKey // verifies <binary>.sig
+	keyFile  string
+	identity string // certificate identity of a keyless signature in <binary>.bundle
+	issuer   string // OIDC issuer of that identity
+}
+
+// newPluginVerifier loads the -plugin-sha256sums file and -plugin-cosign-key. It
+// returns nil when no verification is asked for.
+func newPluginVerifier(sumsFile, keyFile, identity, issuer string) (*pluginVerifier, error) {
+	if sumsFile == "" && keyFile == "" && identity == "" && issuer == "" {
+		return nil, nil
+	}
+	v := &pluginVerifier{sumsFile: sumsFile, keyFile: keyFile, identity: identity, issuer: issuer}
+
+	if sumsFile != "" {
+		sums, err := readSHA256Sums(sumsFile)
+		if err != nil {
+			return nil, err
+		}
+		v.sums = sums
+	}
+	if keyFile != "" {
+		key, err := readCosignKey(keyFile)
+		if err != nil {
+			return nil, err
+		}
+		v.key = key
+	}
+	if (identity == "") != (issuer == "") {
+		return nil, fmt.Errorf("-plugin-cosign-identity and -plugin-cosign-issuer must be given together")
+	}
+	if identity != "" {
+		if keyFile != "" {
+			return nil, fmt.Errorf("-plugin-cosign-key and -plugin-cosign-identity are exclusive")
+		}
+		if _, err := exec.LookPath("cosign"); err != nil {
+			return nil, fmt.Errorf("keyless verification needs the cosign CLI: %w", err)
+		}
+	}
+	return v, nil
+}
+
+// readSHA256Sums reads a checksums file as written by sha256sum or goreleaser: lines of
+// a hex digest, spaces, an optional "*" and a file name
+func readSHA256Sums(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checksums: %w", err)
+	}
+	return parseSHA256Sums(data, path)
+}
+
+// parseSHA256Sums parses the checksums read from source, keyed by base file name
+func parseSHA256Sums(data []byte, source string) (map[string]string, error) {
+	sums := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s:%d: expected a digest and a file name", source, line)
+		}
+		digest, err := parseSHA256Digest(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", source, line, err)
+		}
+		sums[filepath.Base(strings.TrimPrefix(fields[1], "*"))] = digest
+	}
+	if len(sums) == 0 {
+		return nil, fmt.Errorf("no checksums in %s", source)
+	}
+	return sums, nil
+}
+
+// parseSHA256Digest returns a hex SHA256 digest in lower case
+func parseSHA256Digest(digest string) (string, error) {
+	digest = strings.ToLower(strings.TrimSpace(digest))
+	if decoded, err := hex.DecodeString(digest); err != nil || len(decoded) != sha256.Size {
+		return "", fmt.Errorf("%q is not a SHA256 digest", digest)
+	}
+	return digest, nil
+}
+
+// checkSHA256 checks that the binary at path has the SHA256 digest want, as Vault
+// checks a plugin against the sha256 it was registered in its catalog with
+func checkSHA256(path, want string) error {
+	binary, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read plugin binary: %w", err)
+	}
+	sum := sha256.Sum256(binary)
+	if digest := hex.EncodeToString(sum[:]); digest != want {
+		return fmt.Errorf("SHA256 of %s is %s, but %s was expected", path, digest, want)
+	}
+	return nil
+}
+
+// readCosignKey reads a PEM public key as written by cosign generate-key-pair
+func readCosignKey(path string) (crypto.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cosign key: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM public key in %s", path)
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key in %s: %w", path, err)
+	}
+	switch key.(type) {
+	case *ecdsa.PublicKey, *rsa.PublicKey, ed25519.PublicKey:
+		return key, nil
+	}
+	return nil, fmt.Errorf("unsupported public key type %T in %s", key, path)
+}
+
+// String describes the verification for the startup output
+func (v *pluginVerifier) String() string {
+	var parts []string
+	if v.sumsFile != "" {
+		parts = append(parts, fmt.Sprintf("SHA256 listed in %s", v.sumsFile))
+	}
+	if v.keyFile != "" {
+		parts = append(parts, fmt.Sprintf("cosign signature by %s", v.keyFile))
+	}
+	if v.identity != "" {
+		parts = append(parts, fmt.Sprintf("keyless cosign signature by %s (%s)", v.identity, v.issuer))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// verify checks the plugin binary at path and returns its SHA256
+func (v *pluginVerifier) verify(path string) (string, error) {
+	if v == nil {
+		return "", nil
+	}
+	binary, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read plugin binary: %w", err)
+	}
+	sum := sha256.Sum256(binary)
+	digest := hex.EncodeToString(sum[:])
+
+	if v.sums != nil {
+		want, ok := v.sums[filepath.Base(path)]
+		if !ok {
+			return digest, fmt.Errorf("%s is not listed in %s", filepath.Base(path), v.sumsFile)
+		}
+		if digest != want {
+			return digest, fmt.Errorf("SHA256 of %s is %s, but %s lists %s", path, digest, v.sumsFile, want)
+		}
+	}
+	if v.key != nil {
+		if err := verifyCosignSignature(v.key, binary, sum[:], path+".sig"); err != nil {
+			return digest, err
+		}
+	}
+	if v.identity != "" {
+		if err := v.verifyKeyless(path); err != nil {
+			return digest, err
+		}
+	}
+	return digest, nil
+}
+
+// verifyCosignSignature checks the base64 signature in sigFile over binary, as cosign
+// verify-blob --key does: ECDSA and RSA keys sign the SHA256 digest, Ed25519 keys the
+// binary itself
+func verifyCosignSignature(key crypto.PublicKey, binary, digest []byte, sigFile string) error {
+	encoded, err := os.ReadFile(sigFile)
+	if err != nil {
+		return fmt.Errorf("failed to read signature: %w", err)
+	}
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(encoded)))
+	if err != nil {
+		return fmt.Errorf("signature %s is not base64: %w", sigFile, err)
+	}
+
+	var valid bool
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		valid = ecdsa.VerifyASN1(k, digest, sig)
+	case *rsa.PublicKey:
+		valid = rsa.VerifyPKCS1v15(k, crypto.SHA256, digest, sig) == nil
+	case ed25519.PublicKey:
+		valid = ed25519.Verify(k, binary, sig)
+	}
+	if !valid {
+		return fmt.Errorf("signature %s does not match the plugin binary", sigFile)
+	}
+	return nil
+}
+
+// verifyKeyless has the cosign CLI check the Sigstore bundle next to the binary
+// against the certificate identity and issuer
+func (v *pluginVerifier) verifyKeyless(path string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), cosignTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "cosign", "verify-blob",
+		"--bundle", path+".bundle",
+		"--certificate-identity", v.identity,
+		"--certificate-oidc-issuer", v.issuer,
+		path)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("cosign verify-blob failed: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// secureConfig makes go-plugin check that the binary cmd executes is still the one
+// verify found with digest, so it cannot be swapped between the check and the launch.
+// There is nothing to pin when the binary was not verified or the sandbox launcher
+// executes it.
+func secureConfig(cmd *exec.Cmd, path, digest string) *plugin.SecureConfig {
+	if digest == "" || cmd.Path != path {
+		return nil
+	}
+	checksum, err := hex.DecodeString(digest)
+	if err != nil {
+		return nil
+	}
+	return &plugin.SecureConfig{Checksum: checksum, Hash: sha256.New()}
+}
@@ -0,0 +1,204 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeCosignKey writes public to dir as a PEM public key, as cosign does
+func writeCosignKey(t *testing.T, dir string, public crypto.PublicKey) string {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(public)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "cosign.pub")
+	os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o644)
+	return path
+}
+
+func TestPluginVerifierSHA256Sums(t *testing.T) {
+	dir := t.TempDir()
+	binary := filepath.Join(dir, "vault-plugin-secrets-example")
+	os.WriteFile(binary, []byte("plugin build 1"), 0o755)
+	sum := sha256.Sum256([]byte("plugin build 1"))
+	digest := hex.EncodeToString(sum[:])
+
+	sums := filepath.Join(dir, "checksums.txt")
+	os.WriteFile(sums, []byte("# release 1.2.3\n"+digest+" *dist/vault-plugin-secrets-example\n"+strings.Repeat("0", 64)+"  other-plugin\n"), 0o644)
+	v, err := newPluginVerifier(sums, "", "", "")
+	if err != nil {
+		t.Fatalf("newPluginVerifier failed: %v", err)
+	}
+	if got, err := v.verify(binary); err != nil || got != digest {
+		t.Fatalf("verify = %s, %v, want %s", got, err, digest)
+	}
+
+	os.WriteFile(binary, []byte("plugin build 2"), 0o755)
+	if _, err := v.verify(binary); err == nil || !strings.Contains(err.Error(), "lists "+digest) {
+		t.Errorf("a changed binary should fail verification, got %v", err)
+	}
+	unlisted := filepath.Join(dir, "unlisted")
+	os.WriteFile(unlisted, nil, 0o755)
+	if _, err := v.verify(unlisted); err == nil {
+		t.Error("a binary missing from the checksums should fail verification")
+	}
+
+	var none *pluginVerifier
+	if _, err := none.verify(unlisted); err != nil {
+		t.Errorf("a nil verifier should admit every binary, got %v", err)
+	}
+	if v, err := newPluginVerifier("", "", "", ""); v != nil || err != nil {
+		t.Errorf("newPluginVerifier with no options = %v, %v", v, err)
+	}
+	for _, content := range []string{"", "not-a-digest  plugin\n", digest + "\n"} {
+		os.WriteFile(sums, []byte(content), 0o644)
+		if _, err := newPluginVerifier(sums, "", "", ""); err == nil {
+			t.Errorf("checksums %q should be refused", content)
+		}
+	}
+}
+
+func TestCheckSHA256(t *testing.T) {
+	binary := filepath.Join(t.TempDir(), "vault-plugin-secrets-example")
+	os.WriteFile(binary, []byte("plugin build 1"), 0o755)
+	sum := sha256.Sum256([]byte("plugin build 1"))
+	digest, err := parseSHA256Digest(" " + strings.ToUpper(hex.EncodeToString(sum[:])) + "\n")
+	if err != nil || digest != hex.EncodeToString(sum[:]) {
+		t.Fatalf("parseSHA256Digest = %s, %v", digest, err)
+	}
+	if err := checkSHA256(binary, digest); err != nil {
+		t.Errorf("checkSHA256 of the expected binary failed: %v", err)
+	}
+
+	os.WriteFile(binary, []byte("plugin build 2"), 0o755)
+	if err := checkSHA256(binary, digest); err == nil || !strings.Contains(err.Error(), digest+" was expected") {
+		t.Errorf("the wrong artifact should fail the check, got %v", err)
+	}
+	for _, bad := range []string{"", "abc", strings.Repeat("g", 64)} {
+		if _, err := parseSHA256Digest(bad); err == nil {
+			t.Errorf("parseSHA256Digest(%q) should fail", bad)
+		}
+	}
+}
+
+func TestSecureConfig(t *testing.T) {
+	dir := t.TempDir()
+	binary := filepath.Join(dir, "vault-plugin-secrets-example")
+	os.WriteFile(binary, []byte("plugin build 1"), 0o755)
+	sum := sha256.Sum256([]byte("plugin build 1"))
+
+	config := secureConfig(exec.Command(binary), binary, hex.EncodeToString(sum[:]))
+	if config == nil {
+		t.Fatal("a verified binary should be pinned")
+	}
+	os.WriteFile(binary, []byte("plugin build 2"), 0o755)
+	if ok, err := config.Check(binary); ok || err != nil {
+		t.Errorf("a binary swapped after verification should fail the check, got %v, %v", ok, err)
+	}
+
+	if secureConfig(exec.Command(binary), binary, "") != nil {
+		t.Error("an unverified binary has no digest to pin")
+	}
+	if secureConfig(exec.Command(os.Args[0], "--", binary), binary, hex.EncodeToString(sum[:])) != nil {
+		t.Error("the sandbox launcher is not the verified binary")
+	}
+}
+
+func TestPluginVerifierCosignKey(t *testing.T) {
+	content := []byte("plugin build 1")
+	digest := sha256.Sum256(content)
+
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	ecSig, _ := ecdsa.SignASN1(rand.Reader, ecKey, digest[:])
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	rsaSig, _ := rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, digest[:])
+	edPublic, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	edSig := ed25519.Sign(edKey, content)
+
+	for name, tc := range map[string]struct {
+		public crypto.PublicKey
+		sig    []byte
+	}{
+		"ecdsa":   {&ecKey.PublicKey, ecSig},
+		"rsa":     {&rsaKey.PublicKey, rsaSig},
+		"ed25519": {edPublic, edSig},
+	} {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			binary := filepath.Join(dir, "plugin")
+			os.WriteFile(binary, content, 0o755)
+			os.WriteFile(binary+".sig", []byte(base64.StdEncoding.EncodeToString(tc.sig)+"\n"), 0o644)
+
+			v, err := newPluginVerifier("", writeCosignKey(t, dir, tc.public), "", "")
+			if err != nil {
+				t.Fatalf("newPluginVerifier failed: %v", err)
+			}
+			if _, err := v.verify(binary); err != nil {
+				t.Fatalf("verify failed: %v", err)
+			}
+
+			os.WriteFile(binary, []byte("tampered"), 0o755)
+			if _, err := v.verify(binary); err == nil || !strings.Contains(err.Error(), "does not match") {
+				t.Errorf("a tampered binary should fail verification, got %v", err)
+			}
+			os.Remove(binary + ".sig")
+			if _, err := v.verify(binary); err == nil {
+				t.Error("an unsigned binary should fail verification")
+			}
+		})
+	}
+
+	dir := t.TempDir()
+	notKey := filepath.Join(dir, "cosign.pub")
+	os.WriteFile(notKey, []byte("not a key"), 0o644)
+	if _, err := newPluginVerifier("", notKey, "", ""); err == nil {
+		t.Error("a file without a PEM key should be refused")
+	}
+}
+
+func TestPluginVerifierKeyless(t *testing.T) {
+	if _, err := newPluginVerifier("", "", "release@example.com", ""); err == nil {
+		t.Error("an identity without an issuer should be refused")
+	}
+
+	// A stand-in cosign that accepts the bundle of the expected identity only
+	bin := t.TempDir()
+	script := "#!/bin/sh\ncase \"$*\" in *\"--certificate-identity release@example.com \"*) exit 0;; esac\necho 'none of the expected identities matched' >&2\nexit 1\n"
+	os.WriteFile(filepath.Join(bin, "cosign"), []byte(script), 0o755)
+	t.Setenv("PATH", bin)
+
+	dir := t.TempDir()
+	binary := filepath.Join(dir, "plugin")
+	os.WriteFile(binary, []byte("plugin build 1"), 0o755)
+
+	v, err := newPluginVerifier("", "", "release@example.com", "https://token.actions.githubusercontent.com")
+	if err != nil {
+		t.Fatalf("newPluginVerifier failed: %v", err)
+	}
+	if _, err := v.verify(binary); err != nil {
+		t.Errorf("verify failed: %v", err)
+	}
+
+	v.identity = "someone@example.com"
+	if _, err := v.verify(binary); err == nil || !strings.Contains(err.Error(), "none of the expected identities") {
+		t.Errorf("a signature by another identity should fail verification, got %v", err)
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/hashicorp/go-hclog"
+)
+
+// watchDebounce is how long the plugin binary must stay unchanged before a reload, so
+// a build that writes the file in several steps triggers only one
+const watchDebounce = 500 * time.Millisecond
+
+// PluginWatcher reloads a plugin whenever its binary changes on disk
+type PluginWatcher struct {
+	host    *PluginHost
+	watcher *fsnotify.Watcher
+	logger  hclog.Logger
+	reloads chan struct{} // receives after every reload attempt; used by tests
+}
+
+// NewPluginWatcher starts watching the host's plugin binary. The directory is watched
+// rather than the file, because builds commonly replace the binary by renaming a new
+// file over it.
+func NewPluginWatcher(host *PluginHost) (*PluginWatcher, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file watcher: %w", err)
+	}
+	if err := watcher.Add(filepath.Dir(host.pluginPath)); err != nil {
+		watcher.Close()
+		return nil, fmt.Errorf("failed to watch %s: %w", filepath.Dir(host.pluginPath), err)
+	}
+	return &PluginWatcher{
+		host:    host,
+		watcher: watcher,
+		logger:  host.logger.Named("watch"),
+	}, nil
+}
+
+// Run reloads the plugin after changes to its binary until stop is closed
+func (pw *PluginWatcher) Run(stop <-chan struct{}) {
+	defer pw.watcher.Close()
+
+	debounce := time.NewTimer(watchDebounce)
+	debounce.Stop()
+	defer debounce.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case event, ok := <-pw.watcher.Events:
+			if !ok {
+				return
+			}
+			if filepath.Clean(event.Name) != filepath.Clean(pw.host.pluginPath) {
+				continue
+			}
+			if event.Has(fsnotify.Create) || event.Has(fsnotify.Write) || event.Has(fsnotify.Chmod) {
+				debounce.Reset(watchDebounce)
+			}
+		case err, ok := <-pw.watcher.Errors:
+			if !ok {
+				return
+			}
+			pw.logger.Error("file watcher failed", "error", err)
+		case <-debounce.C:
+			pw.reload()
+		}
+	}
+}
+
+// reload restarts the plugin from its binary, unless the binary is gone or unusable
+func (pw *PluginWatcher) reload() {
+	defer func() {
+		if pw.reloads != nil {
+			pw.reloads <- struct{}{}
+		}
+	}()
+
+	info, err := os.Stat(pw.host.pluginPath)
+	if err != nil || info.IsDir() || info.Mode()&0o111 == 0 {
+		pw.logger.Warn("plugin binary changed but is not an executable file, keeping the running plugin", "path", pw.host.pluginPath)
+		return
+	}
+
+	pw.logger.Info("plugin binary changed, reloading", "path", pw.host.pluginPath)
+	start := time.Now()
+	if err := pw.host.Reload(); err != nil {
+		pw.logger.Error("failed to reload plugin, keeping the running plugin", "error", err)
+		return
+	}
+	pw.logger.Info("plugin reloaded", "duration", time.Since(start))
+}
@@ -0,0 +1,68 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPluginWatcherKeepsPluginWhenReloadFails(t *testing.T) {
+	// A "plugin" that exits without a handshake, so every reload fails
+	binary := filepath.Join(t.TempDir(), "plugin")
+	if err := os.WriteFile(binary, []byte("#!/bin/sh\nexit 0\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	host, err := NewPluginHost(binary, false, nil, "plugin")
+	if err != nil {
+		t.Fatalf("NewPluginHost failed: %v", err)
+	}
+	host.handler.SetBackend(echoBackend{})
+
+	watcher, err := NewPluginWatcher(host)
+	if err != nil {
+		t.Fatalf("NewPluginWatcher failed: %v", err)
+	}
+	watcher.reloads = make(chan struct{}, 1)
+	stop := make(chan struct{})
+	defer close(stop)
+	go watcher.Run(stop)
+
+	// Changes to other files in the directory are ignored
+	os.WriteFile(filepath.Join(filepath.Dir(binary), "notes.txt"), []byte("x"), 0o644)
+	select {
+	case <-watcher.reloads:
+		t.Fatal("reloaded after an unrelated file changed")
+	case <-time.After(2 * watchDebounce):
+	}
+
+	os.WriteFile(binary, []byte("#!/bin/sh\nexit 1\n"), 0o755)
+	select {
+	case <-watcher.reloads:
+	case <-time.After(10 * time.Second):
+		t.Fatal("no reload after the binary changed")
+	}
+
+	w := httptest.NewRecorder()
+	host.handler.HandleRequest(w, httptest.NewRequest("GET", "/v1/plugin/config", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("after a failed reload: status = %d, want the old backend to answer 200", w.Code)
+	}
+}
+
+func TestReloadInAttachMode(t *testing.T) {
+	host, err := NewPluginHost("/fake/path", false, nil, "plugin")
+	if err != nil {
+		t.Fatalf("NewPluginHost failed: %v", err)
+	}
+	host.attach = "1|5|unix|/tmp/plugin.sock|grpc|"
+	if err := host.Reload(); err == nil {
+		t.Error("Reload should fail in attach mode")
+	}
+}
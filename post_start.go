@@ -0,0 +1,74 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// postStartMountEnv tells a -post-start-check command which mount serves the plugin
+const postStartMountEnv = "VAULT_PLUGIN_HOST_MOUNT"
+
+// postStartCheck is the -post-start-check run once the host serves the plugin: a
+// command, or a path of the host such as /v1/plugin/health
+type postStartCheck struct {
+	check   string
+	handler http.Handler // serves a path check in-process, so TLS and -allow-ips do not apply
+	addr    string       // VAULT_ADDR of a command
+	token   string       // sent with a path check; VAULT_TOKEN of a command
+	mount   string
+	timeout time.Duration
+	out     io.Writer // receives the output of a command
+}
+
+// run returns an error when the check fails: a command that exits non-zero or a path
+// that answers with a status of 400 or above
+func (c *postStartCheck) run() error {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	if strings.HasPrefix(c.check, "/v1/") {
+		req := httptest.NewRequest(http.MethodGet, c.check, nil).WithContext(ctx)
+		req.Header.Set("X-Vault-Token", c.token)
+		w := httptest.NewRecorder()
+		c.handler.ServeHTTP(w, req)
+		if w.Code < http.StatusBadRequest {
+			return nil
+		}
+		var body struct {
+			Errors []string `json:"errors"`
+		}
+		if json.Unmarshal(w.Body.Bytes(), &body) == nil && len(body.Errors) > 0 {
+			return fmt.Errorf("GET %s answered %d: %s", c.check, w.Code, strings.Join(body.Errors, "; "))
+		}
+		return fmt.Errorf("GET %s answered %d", c.check, w.Code)
+	}
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", c.check)
+	cmd.Env = append(os.Environ(),
+		"VAULT_ADDR="+c.addr,
+		"VAULT_TOKEN="+c.token,
+		postStartMountEnv+"="+c.mount,
+	)
+	cmd.Stdout = c.out
+	cmd.Stderr = c.out
+	// Children of a killed command may hold its output open
+	cmd.WaitDelay = time.Second
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("%s did not finish within %s", c.check, c.timeout)
+		}
+		return fmt.Errorf("%s failed: %w", c.check, err)
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"vault-plugin-host/handlers"
+)
+
+func TestPostStartCheck(t *testing.T) {
+	router := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "root" {
+			handlers.WriteError(w, http.StatusForbidden, "permission denied")
+			return
+		}
+		if r.URL.Path == "/v1/plugin/health" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		handlers.WriteError(w, http.StatusInternalServerError, "connection not configured")
+	})
+	var out bytes.Buffer
+	check := func(command string) error {
+		c := &postStartCheck{check: command, handler: router, addr: "http://localhost:8300", token: "root", mount: "plugin", timeout: 5 * time.Second, out: &out}
+		return c.run()
+	}
+
+	if err := check("/v1/plugin/health"); err != nil {
+		t.Errorf("path check failed: %v", err)
+	}
+	if err := check("/v1/plugin/creds/app"); err == nil || !strings.Contains(err.Error(), "answered 500: connection not configured") {
+		t.Errorf("failing path check = %v", err)
+	}
+
+	if err := check(`test "$VAULT_ADDR $VAULT_TOKEN $` + postStartMountEnv + `" = "http://localhost:8300 root plugin" && echo ok`); err != nil {
+		t.Errorf("command check failed: %v", err)
+	}
+	if out.String() != "ok\n" {
+		t.Errorf("command output = %q", out.String())
+	}
+	if err := check("exit 2"); err == nil || !strings.Contains(err.Error(), "exit status 2") {
+		t.Errorf("failing command check = %v", err)
+	}
+	c := &postStartCheck{check: "sleep 5", timeout: 50 * time.Millisecond, out: &out}
+	if err := c.run(); err == nil || !strings.Contains(err.Error(), "did not finish within") {
+		t.Errorf("slow command check = %v", err)
+	}
+}
@@ -0,0 +1,386 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"debug/buildinfo"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"os/exec"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Modules whose versions decide whether a plugin and the host agree
+const (
+	vaultSDKModule   = "github.com/hashicorp/vault/sdk"
+	openBaoSDKModule = "github.com/openbao/openbao/sdk/v2"
+	goPluginModule   = "github.com/hashicorp/go-plugin"
+	grpcModule       = "google.golang.org/grpc"
+)
+
+// probeModules are the modules the probe reports, with their names in the report
+var probeModules = []struct{ path, name string }{
+	{vaultSDKModule, "Vault SDK"},
+	{openBaoSDKModule, "OpenBao SDK"},
+	{goPluginModule, "go-plugin"},
+	{grpcModule, "gRPC"},
+}
+
+// probeHandshakeTimeout is how long the probe waits for the plugin's handshake line
+var probeHandshakeTimeout = 10 * time.Second
+
+// probeModule is the version of a module a binary was built with
+type probeModule struct {
+	Version string
+	Replace string // "path version" of a replace directive, if any
+}
+
+func (m probeModule) String() string {
+	if m.Replace != "" {
+		return m.Version + " => " + m.Replace
+	}
+	return m.Version
+}
+
+// probeHandshake is the handshake line a plugin prints once it serves, as
+// 1|5|unix|/tmp/plugin.sock|grpc|<server certificate>
+type probeHandshake struct {
+	CoreVersion     int
+	ProtocolVersion int
+	Network         string
+	Address         string
+	Protocol        string
+	AutoMTLS        bool // the plugin answered with its server certificate
+}
+
+// parseProbeHandshake parses a handshake line
+func parseProbeHandshake(line string) (*probeHandshake, error) {
+	parts := strings.Split(strings.TrimSpace(line), "|")
+	if len(parts) < 5 {
+		return nil, fmt.Errorf("invalid handshake line %q", line)
+	}
+	core, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid core protocol version in handshake line %q", line)
+	}
+	protocolVersion, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid protocol version in handshake line %q", line)
+	}
+	return &probeHandshake{
+		CoreVersion:     core,
+		ProtocolVersion: protocolVersion,
+		Network:         parts[2],
+		Address:         parts[3],
+		Protocol:        parts[4],
+		AutoMTLS:        len(parts) > 5 && parts[5] != "",
+	}, nil
+}
+
+// probeFinding is a known incompatibility between a plugin and the host. Errors stop
+// the host from serving the plugin; warnings are differences worth knowing about.
+type probeFinding struct {
+	Error   bool
+	Message string
+}
+
+// probeReport is what the probe learned about a plugin binary
+type probeReport struct {
+	Plugin         string
+	GoVersion      string
+	MainPath       string
+	Modules        map[string]probeModule // of the plugin; nil without build info
+	HostModules    map[string]probeModule
+	Handshake      *probeHandshake
+	HandshakeError string
+	Findings       []probeFinding
+}
+
+// buildModules returns the versions of the probe's modules in a binary's build info
+func buildModules(info *debug.BuildInfo) map[string]probeModule {
+	modules := make(map[string]probeModule)
+	if info == nil {
+		return modules
+	}
+	for _, dep := range info.Deps {
+		module := probeModule{Version: dep.Version}
+		if dep.Replace != nil {
+			module.Replace = strings.TrimSpace(dep.Replace.Path + " " + dep.Replace.Version)
+		}
+		modules[dep.Path] = module
+	}
+	return modules
+}
+
+// probePlugin reads the build info of the plugin binary and launches it as the host
+// does, reading its handshake line, then stops it
+func probePlugin(path string, flavor hostFlavor) *probeReport {
+	report := &probeReport{Plugin: path}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		report.HostModules = buildModules(info)
+	}
+	if info, err := buildinfo.ReadFile(path); err == nil {
+		report.GoVersion = info.GoVersion
+		report.MainPath = info.Path
+		report.Modules = buildModules(info)
+	}
+
+	handshake, err := readProbeHandshake(path, flavor)
+	if err != nil {
+		report.HandshakeError = err.Error()
+	}
+	report.Handshake = handshake
+	report.Findings = checkProbe(report, flavor)
+	return report
+}
+
+// readProbeHandshake launches the plugin with the handshake environment of flavor and
+// returns the handshake line it answers with. The stderr of a plugin that exits
+// without one is part of the error, since it usually says why.
+func readProbeHandshake(path string, flavor hostFlavor) (*probeHandshake, error) {
+	clientCert, err := probeClientCertEnv()
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.Command(path)
+	cmd.Env = append(append(os.Environ(), flavor.handshakeEnv()...), clientCert)
+	stderr := newTailBuffer(4096)
+	cmd.Stderr = stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stdout pipe: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start plugin: %w", err)
+	}
+	defer func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	}()
+
+	lines := make(chan string, 1)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(stdout)
+		for scanner.Scan() {
+			if line := scanner.Text(); strings.Contains(line, "|unix|") || strings.Contains(line, "|tcp|") {
+				lines <- line
+				return
+			}
+		}
+	}()
+
+	select {
+	case line, ok := <-lines:
+		if ok {
+			return parseProbeHandshake(line)
+		}
+		cmd.Wait()
+		if output := strings.TrimSpace(stderr.String()); output != "" {
+			return nil, fmt.Errorf("plugin exited without a handshake: %s", output)
+		}
+		return nil, fmt.Errorf("plugin exited without a handshake")
+	case <-time.After(probeHandshakeTimeout):
+		return nil, fmt.Errorf("no handshake from the plugin within %s", probeHandshakeTimeout)
+	}
+}
+
+// probeClientCertEnv returns PLUGIN_CLIENT_CERT with a one-off client certificate shaped
+// like the ones go-plugin's AutoMTLS generates: a self-signed P-521 certificate for
+// "localhost". A plugin only answers with its server certificate when it is given one.
+// Launched plugins get theirs from go-plugin, but the probe reads the handshake line
+// itself, so that plugins go-plugin would refuse can still be described, and go-plugin
+// does not export how it makes the certificate. The probe never connects, so the key
+// is thrown away.
+func probeClientCertEnv() (string, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate plugin client key: %w", err)
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return "", fmt.Errorf("failed to generate plugin client certificate serial: %w", err)
+	}
+	template := &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "localhost", Organization: []string{"HashiCorp"}},
+		DNSNames:              []string{"localhost"},
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment | x509.KeyUsageKeyAgreement | x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		SerialNumber:          serial,
+		NotBefore:             time.Now().Add(-30 * time.Second),
+		NotAfter:              time.Now().Add(probeHandshakeTimeout + time.Minute),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		return "", fmt.Errorf("failed to create plugin client certificate: %w", err)
+	}
+	return "PLUGIN_CLIENT_CERT=" + string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})), nil
+}
+
+// checkProbe compares what the probe learned with what the host expects
+func checkProbe(report *probeReport, flavor hostFlavor) []probeFinding {
+	var findings []probeFinding
+	warn := func(format string, args ...interface{}) {
+		findings = append(findings, probeFinding{Message: fmt.Sprintf(format, args...)})
+	}
+	fail := func(format string, args ...interface{}) {
+		findings = append(findings, probeFinding{Error: true, Message: fmt.Sprintf(format, args...)})
+	}
+
+	vaultSDK, hasVaultSDK := report.Modules[vaultSDKModule]
+	_, hasOpenBaoSDK := report.Modules[openBaoSDKModule]
+	switch {
+	case report.Modules == nil:
+		warn("the plugin has no Go build info, so the SDK it was built with is unknown")
+	case !hasVaultSDK && !hasOpenBaoSDK:
+		warn("the plugin links neither the Vault nor the OpenBao SDK")
+	case hasOpenBaoSDK && !hasVaultSDK && flavor.name != "openbao":
+		fail("the plugin was built against the OpenBao SDK; run the host with -flavor openbao")
+	case hasVaultSDK && !hasOpenBaoSDK && flavor.name == "openbao":
+		fail("the plugin was built against the Vault SDK; run the host without -flavor openbao")
+	}
+	if hostSDK, ok := report.HostModules[vaultSDKModule]; ok && hasVaultSDK {
+		if cmp, ok := compareModuleVersions(vaultSDK.Version, hostSDK.Version); ok && cmp > 0 {
+			warn("the plugin's Vault SDK %s is newer than the host's %s; request and response fields added since are dropped", vaultSDK.Version, hostSDK.Version)
+		}
+	}
+
+	handshake := report.Handshake
+	if handshake == nil {
+		message := "the plugin did not complete the handshake"
+		if report.HandshakeError != "" {
+			message += ": " + report.HandshakeError
+		}
+		// go-plugin prints this when the magic cookie variable is not set
+		if strings.Contains(report.HandshakeError, "This binary is a plugin") {
+			message += fmt.Sprintf(" (it did not find %sBACKEND_PLUGIN; check -flavor)", flavor.envPrefix)
+		}
+		fail("%s", message)
+		return findings
+	}
+	if handshake.CoreVersion != 1 {
+		fail("the plugin speaks go-plugin core protocol %d; the host speaks 1", handshake.CoreVersion)
+	}
+	if _, ok := versionedPluginSet[handshake.ProtocolVersion]; !ok {
+		fail("the plugin serves protocol version %d; the host supports 3, 4 and 5", handshake.ProtocolVersion)
+	}
+	if handshake.Protocol != "grpc" {
+		fail("the plugin serves %s; the host only speaks gRPC, as Vault does since net/rpc plugins were removed", handshake.Protocol)
+	}
+	if !handshake.AutoMTLS {
+		fail("the plugin did not answer with a server certificate, so AutoMTLS fails; its go-plugin is too old or it disables TLS")
+	}
+	if *multiplex && handshake.ProtocolVersion < 5 {
+		warn("-multiplex has no effect: the plugin serves protocol version %d, and multiplexing needs 5", handshake.ProtocolVersion)
+	}
+	return findings
+}
+
+// compareModuleVersions compares the major, minor and patch numbers of two module
+// versions; ok is false when either is not a version
+func compareModuleVersions(a, b string) (cmp int, ok bool) {
+	parse := func(version string) ([3]int, bool) {
+		var numbers [3]int
+		version, _, _ = strings.Cut(strings.TrimPrefix(version, "v"), "-")
+		fields := strings.Split(version, ".")
+		if len(fields) != 3 {
+			return numbers, false
+		}
+		for i, field := range fields {
+			n, err := strconv.Atoi(field)
+			if err != nil {
+				return numbers, false
+			}
+			numbers[i] = n
+		}
+		return numbers, true
+	}
+	x, okA := parse(a)
+	y, okB := parse(b)
+	if !okA || !okB {
+		return 0, false
+	}
+	for i := range x {
+		if x[i] != y[i] {
+			if x[i] < y[i] {
+				return -1, true
+			}
+			return 1, true
+		}
+	}
+	return 0, true
+}
+
+// writeProbeReport prints a report as the probe subcommand shows it
+func writeProbeReport(out io.Writer, report *probeReport) {
+	fmt.Fprintf(out, "Plugin:        %s\n", report.Plugin)
+	if report.Modules != nil {
+		fmt.Fprintf(out, "Built with:    %s (%s)\n", report.GoVersion, report.MainPath)
+	}
+	for _, module := range probeModules {
+		version, ok := report.Modules[module.path]
+		if !ok {
+			continue
+		}
+		line := version.String()
+		if host, ok := report.HostModules[module.path]; ok {
+			line += " (host " + host.String() + ")"
+		}
+		fmt.Fprintf(out, "%-15s%s\n", module.name+":", line)
+	}
+	if handshake := report.Handshake; handshake != nil {
+		security := "AutoMTLS"
+		if !handshake.AutoMTLS {
+			security = "no TLS"
+		}
+		fmt.Fprintf(out, "Handshake:     core %d, protocol %d (%s over %s, %s)\n",
+			handshake.CoreVersion, handshake.ProtocolVersion, handshake.Protocol, handshake.Network, security)
+		multiplexing := "not supported"
+		if handshake.ProtocolVersion >= 5 {
+			multiplexing = "supported"
+		}
+		fmt.Fprintf(out, "Multiplexing:  %s\n", multiplexing)
+	}
+	for _, finding := range report.Findings {
+		severity := "WARNING"
+		if finding.Error {
+			severity = "ERROR"
+		}
+		fmt.Fprintf(out, "%s: %s\n", severity, finding.Message)
+	}
+	if len(report.Findings) == 0 {
+		fmt.Fprintln(out, "No known incompatibilities with the host")
+	}
+}
+
+// runProbeCommand probes the plugin binary and prints the report. It returns the
+// process exit code: 1 when the host cannot serve the plugin.
+func runProbeCommand(path string, flavor hostFlavor) int {
+	if _, err := os.Stat(path); err != nil {
+		fmt.Fprintf(os.Stderr, "Cannot probe plugin: %v\n", err)
+		return 1
+	}
+	report := probePlugin(path, flavor)
+	writeProbeReport(os.Stdout, report)
+	for _, finding := range report.Findings {
+		if finding.Error {
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,120 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"crypto/x509"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestProbePlugin(t *testing.T) {
+	dir := t.TempDir()
+	serving := filepath.Join(dir, "serving")
+	os.WriteFile(serving, []byte("#!/bin/sh\necho starting\necho \"1|5|unix|/tmp/plugin.sock|grpc|$VAULT_VERSION\"\nexec sleep 5\n"), 0o755)
+	report := probePlugin(serving, hostFlavors["vault"])
+
+	if report.Modules != nil {
+		t.Errorf("a shell script has build info: %v", report.Modules)
+	}
+	want := probeHandshake{CoreVersion: 1, ProtocolVersion: 5, Network: "unix", Address: "/tmp/plugin.sock", Protocol: "grpc", AutoMTLS: true}
+	if report.Handshake == nil || *report.Handshake != want {
+		t.Fatalf("handshake = %+v (%s), want %+v", report.Handshake, report.HandshakeError, want)
+	}
+	if len(report.Findings) != 1 || report.Findings[0].Error || !strings.Contains(report.Findings[0].Message, "no Go build info") {
+		t.Errorf("findings = %+v", report.Findings)
+	}
+	var out bytes.Buffer
+	writeProbeReport(&out, report)
+	for _, line := range []string{"Handshake:     core 1, protocol 5 (grpc over unix, AutoMTLS)", "Multiplexing:  supported", "WARNING: the plugin has no Go build info"} {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("report lacks %q:\n%s", line, out.String())
+		}
+	}
+
+	// A plugin that exits without a handshake is explained by its stderr
+	refusing := filepath.Join(dir, "refusing")
+	os.WriteFile(refusing, []byte("#!/bin/sh\necho 'This binary is a plugin.' >&2\nexit 1\n"), 0o755)
+	report = probePlugin(refusing, hostFlavors["openbao"])
+	if report.Handshake != nil || len(report.Findings) != 2 {
+		t.Fatalf("report = %+v", report)
+	}
+	if finding := report.Findings[1]; !finding.Error || !strings.Contains(finding.Message, "did not find BAO_BACKEND_PLUGIN") {
+		t.Errorf("finding = %+v", finding)
+	}
+}
+
+func TestProbeClientCertEnv(t *testing.T) {
+	env, err := probeClientCertEnv()
+	if err != nil {
+		t.Fatalf("probeClientCertEnv failed: %v", err)
+	}
+	certPEM, ok := strings.CutPrefix(env, "PLUGIN_CLIENT_CERT=")
+	if !ok {
+		t.Fatalf("env = %q", env)
+	}
+	// go-plugin's server adds the certificate to the pool of clients it accepts
+	if !x509.NewCertPool().AppendCertsFromPEM([]byte(certPEM)) {
+		t.Error("the client certificate is not valid PEM")
+	}
+}
+
+func TestCheckProbe(t *testing.T) {
+	host := map[string]probeModule{vaultSDKModule: {Version: "v0.20.0"}}
+	handshake := &probeHandshake{CoreVersion: 1, ProtocolVersion: 4, Protocol: "grpc", AutoMTLS: true}
+	tests := []struct {
+		name      string
+		modules   map[string]probeModule
+		handshake *probeHandshake
+		flavor    string
+		want      []string
+	}{
+		{"compatible", map[string]probeModule{vaultSDKModule: {Version: "v0.20.0"}}, handshake, "vault", nil},
+		{"older SDK", map[string]probeModule{vaultSDKModule: {Version: "v0.9.2"}}, handshake, "vault", nil},
+		{"newer SDK", map[string]probeModule{vaultSDKModule: {Version: "v0.21.1"}}, handshake, "vault", []string{"WARNING newer than the host's v0.20.0"}},
+		{"OpenBao SDK", map[string]probeModule{openBaoSDKModule: {Version: "v2.2.0"}}, handshake, "vault", []string{"ERROR -flavor openbao"}},
+		{"no SDK", map[string]probeModule{}, handshake, "vault", []string{"WARNING neither"}},
+		{"net/rpc", map[string]probeModule{vaultSDKModule: {Version: "v0.20.0"}}, &probeHandshake{CoreVersion: 1, ProtocolVersion: 4, Protocol: "netrpc", AutoMTLS: true}, "vault", []string{"ERROR only speaks gRPC"}},
+		{"no AutoMTLS", map[string]probeModule{vaultSDKModule: {Version: "v0.20.0"}}, &probeHandshake{CoreVersion: 1, ProtocolVersion: 6, Protocol: "grpc"}, "vault", []string{"ERROR protocol version 6", "ERROR AutoMTLS"}},
+		{"no handshake", map[string]probeModule{vaultSDKModule: {Version: "v0.20.0"}}, nil, "vault", []string{"ERROR did not complete the handshake"}},
+	}
+	for _, tt := range tests {
+		report := &probeReport{Modules: tt.modules, HostModules: host, Handshake: tt.handshake}
+		findings := checkProbe(report, hostFlavors[tt.flavor])
+		if len(findings) != len(tt.want) {
+			t.Errorf("%s: findings = %+v, want %d", tt.name, findings, len(tt.want))
+			continue
+		}
+		for i, want := range tt.want {
+			severity, text, _ := strings.Cut(want, " ")
+			if findings[i].Error != (severity == "ERROR") || !strings.Contains(findings[i].Message, text) {
+				t.Errorf("%s: finding %d = %+v, want %s", tt.name, i, findings[i], want)
+			}
+		}
+	}
+}
+
+func TestCompareModuleVersions(t *testing.T) {
+	for _, tt := range []struct {
+		a, b string
+		want int
+		ok   bool
+	}{
+		{"v0.20.0", "v0.20.0", 0, true},
+		{"v0.9.2", "v0.20.0", -1, true},
+		{"v1.0.0", "v0.20.0", 1, true},
+		{"v0.0.0-20250123190657-2be956e23206", "v0.1.0", -1, true},
+		{"(devel)", "v0.20.0", 0, false},
+	} {
+		if got, ok := compareModuleVersions(tt.a, tt.b); got != tt.want || ok != tt.ok {
+			t.Errorf("compareModuleVersions(%s, %s) = %d, %v, want %d, %v", tt.a, tt.b, got, ok, tt.want, tt.ok)
+		}
+	}
+	if _, err := parseProbeHandshake("1|x|unix|/tmp/p.sock|grpc"); err == nil {
+		t.Error("a handshake line with an invalid protocol version should fail")
+	}
+}
@@ -0,0 +1,48 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"vault-plugin-host/handlers"
+)
+
+// runReplayCommand replays the requests of a -record file against the mounted plugins,
+// writing each response that differs from the recorded one to stdout as a JSON line
+// and a summary to console. It returns 1 when a response differed.
+func runReplayCommand(handler http.Handler, path string, ignore []string, console io.Writer) int {
+	f, err := os.Open(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Cannot replay: %v\n", err)
+		return 1
+	}
+	defer f.Close()
+
+	// Recordings hold no tokens, so requests carry the root token when one is required
+	opts := handlers.ReplayOptions{Ignore: ignore}
+	if tokenStore.Enforced() {
+		opts.Token = tokenStore.RootToken()
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	diverged := 0
+	replayed, err := handlers.ReplayRecording(handler, f, opts, func(divergence handlers.ReplayDivergence) error {
+		diverged++
+		return encoder.Encode(divergence)
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Replay failed: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(console, "Replay: %d of %d requests matched the recording\n", replayed-diverged, replayed)
+	if diverged > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,254 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/vault/sdk/framework"
+)
+
+// robustnessCase is an odd value substituted for the path parameters of a plugin path
+type robustnessCase struct {
+	Name string
+	// Value is inserted into the URL as is when Raw is set, so it can carry percent
+	// encodings and relative segments, and percent-encoded otherwise
+	Value     string
+	Raw       bool
+	Traversal bool // the decoded path climbs out of the parameter with ".."
+}
+
+// robustnessCases are the values tried for every templated plugin path
+var robustnessCases = []robustnessCase{
+	{Name: "percent-encoded slash", Value: "smoke%2Ftest", Raw: true},
+	{Name: "percent-encoded NUL", Value: "smoke%00test", Raw: true},
+	{Name: "percent-encoded newline", Value: "smoke%0Atest", Raw: true},
+	{Name: "double percent-encoding", Value: "smoke%252e%252e", Raw: true},
+	{Name: "invalid UTF-8", Value: "smoke%ff%fe", Raw: true},
+	{Name: "empty segment", Value: "smoke//test", Raw: true},
+	{Name: "whitespace", Value: " smoke test "},
+	{Name: "unicode", Value: "ünïcødé-名前"},
+	{Name: "emoji", Value: "smoke-🔑"},
+	{Name: "right-to-left override", Value: "smoke\u202etset"},
+	{Name: "zero-width joiner", Value: "smoke\u200dtest"},
+	{Name: "combining characters", Value: "smo\u0301ke"},
+	{Name: "very long segment", Value: strings.Repeat("a", 8192)},
+	{Name: "many segments", Value: strings.TrimSuffix(strings.Repeat("a/", 256), "/"), Raw: true},
+	{Name: "path traversal", Value: "../" + smokeName, Raw: true, Traversal: true},
+	{Name: "encoded path traversal", Value: "..%2F..%2F" + smokeName, Raw: true, Traversal: true},
+	{Name: "encoded dot segments", Value: "%2e%2e/" + smokeName, Raw: true, Traversal: true},
+}
+
+// Kinds of robustness findings
+const (
+	findingPanic       = "panic"                // the plugin crashed or the request panicked
+	findingServerError = "server-error"         // the plugin answered with a 5xx
+	findingRouting     = "inconsistent-routing" // a write could not be read back from the same path
+	findingTraversal   = "traversal-accepted"   // a write was accepted for a path containing ".."
+)
+
+// RobustnessFinding is a problem found while exercising a plugin path
+type RobustnessFinding struct {
+	Kind   string `json:"kind"`
+	Case   string `json:"case"`
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	Status int    `json:"status,omitempty"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// RobustnessReport is the outcome of a robustness pass
+type RobustnessReport struct {
+	Requests int                 `json:"requests"`
+	Findings []RobustnessFinding `json:"findings"`
+	Aborted  string              `json:"aborted,omitempty"` // why the pass stopped early
+}
+
+// robustnessTarget is the plugin a robustness pass sends its requests to
+type robustnessTarget struct {
+	mount string
+	serve http.Handler
+	alive func() error // reports whether the plugin process is still running
+	// output returns the plugin's output written since an offset, to show the panic
+	output func(offset int64) string
+	offset func() int64
+}
+
+// robustnessResult is the outcome of one request
+type robustnessResult struct {
+	status int
+	body   string
+	panic  string // the in-process panic, if the request panicked
+}
+
+// send serves one request, recovering from a panic of an in-process backend
+func (t *robustnessTarget) send(method, path string, body interface{}) (result robustnessResult) {
+	var reader io.Reader
+	if body != nil {
+		data, _ := json.Marshal(body)
+		reader = bytes.NewReader(data)
+	}
+	req := httptest.NewRequest(method, "/v1/"+t.mount+"/"+path, reader)
+	w := httptest.NewRecorder()
+
+	defer func() {
+		if r := recover(); r != nil {
+			result = robustnessResult{status: http.StatusInternalServerError, panic: fmt.Sprint(r)}
+		}
+	}()
+	t.serve.ServeHTTP(w, req)
+	return robustnessResult{status: w.Code, body: w.Body.String()}
+}
+
+// robustnessPath substitutes every parameter of a path template with the case's value
+func robustnessPath(template string, c robustnessCase) string {
+	value := c.Value
+	if !c.Raw {
+		value = url.PathEscape(value)
+	}
+	return oasPathParam.ReplaceAllLiteralString(strings.TrimPrefix(template, "/"), value)
+}
+
+// runRobustness exercises every templated path of the plugin's OpenAPI document with
+// each robustness case: it writes (with the smoke test's example body), reads back and
+// deletes. The pass stops at the first crash, since later results would only show the
+// plugin being down.
+func runRobustness(doc *framework.OASDocument, target *robustnessTarget) RobustnessReport {
+	templates := make([]string, 0, len(doc.Paths))
+	for template, item := range doc.Paths {
+		if item != nil && oasPathParam.MatchString(template) {
+			templates = append(templates, template)
+		}
+	}
+	sort.Strings(templates)
+
+	report := RobustnessReport{Findings: []RobustnessFinding{}}
+	for _, template := range templates {
+		item := doc.Paths[template]
+		for _, c := range robustnessCases {
+			path := robustnessPath(template, c)
+			finding := func(kind, method string, status int, detail string) {
+				report.Findings = append(report.Findings, RobustnessFinding{
+					Kind: kind, Case: c.Name, Method: method, Path: path, Status: status, Detail: detail,
+				})
+			}
+			// check records a failed request and reports whether the plugin survived it
+			check := func(method string, offset int64, result robustnessResult) bool {
+				report.Requests++
+				if result.panic != "" {
+					finding(findingPanic, method, result.status, result.panic)
+					return true
+				}
+				if result.status < http.StatusInternalServerError {
+					return true
+				}
+				if err := target.alive(); err != nil {
+					finding(findingPanic, method, result.status, strings.TrimSpace(target.output(offset)))
+					report.Aborted = fmt.Sprintf("plugin crashed on %s %s (%s): %v", method, path, c.Name, err)
+					return false
+				}
+				finding(findingServerError, method, result.status, strings.TrimSpace(result.body))
+				return true
+			}
+
+			wrote := false
+			if item.Post != nil {
+				offset := target.offset()
+				result := target.send(http.MethodPost, path, exampleBody(doc, item.Post))
+				if !check(http.MethodPost, offset, result) {
+					return report
+				}
+				wrote = result.status < http.StatusBadRequest
+				if wrote && c.Traversal {
+					finding(findingTraversal, http.MethodPost, result.status, "the write was accepted for a path containing a '..' segment")
+				}
+			}
+			if item.Get != nil && !isListOperation(item.Get) {
+				offset := target.offset()
+				result := target.send(http.MethodGet, path, nil)
+				if !check(http.MethodGet, offset, result) {
+					return report
+				}
+				if wrote && result.status == http.StatusNotFound {
+					finding(findingRouting, http.MethodGet, result.status, "the write succeeded but reading the same path found nothing")
+				}
+			}
+			if item.Delete != nil {
+				offset := target.offset()
+				if !check(http.MethodDelete, offset, target.send(http.MethodDelete, path, nil)) {
+					return report
+				}
+			}
+		}
+	}
+	return report
+}
+
+// writeRobustnessReport prints a report for people
+func writeRobustnessReport(w io.Writer, report RobustnessReport) {
+	for _, f := range report.Findings {
+		path := f.Path
+		if len(path) > 80 {
+			path = path[:77] + "..."
+		}
+		fmt.Fprintf(w, "%-20s %-6s %-60s %s", strings.ToUpper(f.Kind), f.Method, path, f.Case)
+		if f.Status != 0 {
+			fmt.Fprintf(w, " (%d)", f.Status)
+		}
+		fmt.Fprintln(w)
+		if f.Detail != "" {
+			for _, line := range strings.Split(f.Detail, "\n") {
+				fmt.Fprintf(w, "    %s\n", line)
+			}
+		}
+	}
+	if report.Aborted != "" {
+		fmt.Fprintf(w, "Stopped early: %s\n", report.Aborted)
+	}
+	fmt.Fprintf(w, "%d requests, %d findings\n", report.Requests, len(report.Findings))
+}
+
+// runRobustnessCommand runs a robustness pass against the running plugin, prints the
+// report as text or, with format "json", as JSON, and returns the process exit code
+func runRobustnessCommand(host *PluginHost, format string) int {
+	defer host.Stop()
+
+	doc, err := oasDocument(host.GetOpenAPIDoc())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Cannot run robustness pass: %v\n", err)
+		return 1
+	}
+	if format != "text" && format != "json" {
+		fmt.Fprintf(os.Stderr, "Unknown robustness report format %q (expected text or json)\n", format)
+		return 1
+	}
+
+	report := runRobustness(doc, &robustnessTarget{
+		mount:  strings.Trim(host.mountPath, "/"),
+		serve:  http.HandlerFunc(host.handler.HandleRequest),
+		alive:  host.Ping,
+		output: host.stderr.Since,
+		offset: host.stderr.Written,
+	})
+	if format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(report)
+	} else {
+		writeRobustnessReport(os.Stdout, report)
+	}
+	if len(report.Findings) > 0 {
+		return 1
+	}
+	return 0
+}
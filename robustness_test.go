@@ -0,0 +1,131 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// oddPathBackend stores writes under the request path but has the bugs the robustness
+// pass looks for: it panics on a NUL byte, fails on very long paths and trims the
+// path on reads, so values with surrounding whitespace cannot be read back
+type oddPathBackend struct{}
+
+func (oddPathBackend) HandleRequest(ctx context.Context, req *logical.Request) (*logical.Response, error) {
+	if strings.ContainsRune(req.Path, 0) {
+		panic("unexpected NUL in path")
+	}
+	if len(req.Path) > 4096 {
+		return nil, errors.New("path too long")
+	}
+	switch req.Operation {
+	case logical.UpdateOperation:
+		entry, err := logical.StorageEntryJSON(req.Path, req.Data)
+		if err != nil {
+			return nil, err
+		}
+		return nil, req.Storage.Put(ctx, entry)
+	case logical.ReadOperation:
+		entry, err := req.Storage.Get(ctx, strings.TrimSpace(req.Path))
+		if err != nil || entry == nil {
+			return nil, err
+		}
+		return &logical.Response{Data: map[string]interface{}{"stored": true}}, nil
+	case logical.DeleteOperation:
+		return nil, req.Storage.Delete(ctx, req.Path)
+	}
+	return nil, nil
+}
+
+func newRobustnessTarget(t *testing.T, alive func() error) *robustnessTarget {
+	host, err := NewPluginHost("/fake/path", false, nil, "plugin")
+	if err != nil {
+		t.Fatalf("NewPluginHost failed: %v", err)
+	}
+	host.handler.SetBackend(oddPathBackend{})
+	return &robustnessTarget{
+		mount:  "plugin",
+		serve:  http.HandlerFunc(host.handler.HandleRequest),
+		alive:  alive,
+		output: func(int64) string { return "panic: boom" },
+		offset: func() int64 { return 0 },
+	}
+}
+
+func TestRunRobustness(t *testing.T) {
+	report := runRobustness(testOASDocument(), newRobustnessTarget(t, func() error { return nil }))
+	if report.Aborted != "" {
+		t.Fatalf("pass aborted: %s", report.Aborted)
+	}
+	// Only /roles/{name} is templated; it has POST, GET and DELETE
+	if report.Requests != 3*len(robustnessCases) {
+		t.Errorf("requests = %d, want %d", report.Requests, 3*len(robustnessCases))
+	}
+
+	found := make(map[string]map[string]bool)
+	for _, f := range report.Findings {
+		if found[f.Kind] == nil {
+			found[f.Kind] = make(map[string]bool)
+		}
+		found[f.Kind][f.Case] = true
+		if !strings.HasPrefix(f.Path, "roles/") {
+			t.Errorf("finding for unexpected path %q", f.Path)
+		}
+	}
+
+	if !found[findingPanic]["percent-encoded NUL"] {
+		t.Errorf("panic on NUL not reported: %+v", report.Findings)
+	}
+	if !found[findingServerError]["very long segment"] {
+		t.Errorf("server error on long segment not reported: %+v", report.Findings)
+	}
+	if !found[findingRouting]["whitespace"] {
+		t.Errorf("unreadable write not reported: %+v", report.Findings)
+	}
+	for _, name := range []string{"path traversal", "encoded path traversal", "encoded dot segments"} {
+		if !found[findingTraversal][name] {
+			t.Errorf("accepted traversal %q not reported", name)
+		}
+	}
+	if found[findingRouting]["unicode"] || found[findingServerError]["unicode"] {
+		t.Error("unicode names are handled correctly and should not be reported")
+	}
+}
+
+func TestRunRobustnessStopsOnCrash(t *testing.T) {
+	crashed := errors.New("plugin exited")
+	report := runRobustness(testOASDocument(), newRobustnessTarget(t, func() error { return crashed }))
+	if report.Aborted == "" || !strings.Contains(report.Aborted, "very long segment") {
+		t.Fatalf("aborted = %q, want a crash on the long segment", report.Aborted)
+	}
+	last := report.Findings[len(report.Findings)-1]
+	if last.Kind != findingPanic || last.Detail != "panic: boom" {
+		t.Errorf("last finding = %+v, want the crash with the plugin output", last)
+	}
+
+	var out bytes.Buffer
+	writeRobustnessReport(&out, report)
+	if !strings.Contains(out.String(), "Stopped early") || !strings.Contains(out.String(), "PANIC") {
+		t.Errorf("report output:\n%s", out.String())
+	}
+}
+
+func TestRobustnessPath(t *testing.T) {
+	cases := map[string]robustnessCase{
+		"roles/a%2Fb/keys/a%2Fb":                           {Value: "a%2Fb", Raw: true},
+		"roles/%20smoke%20test%20/keys/%20smoke%20test%20": {Value: " smoke test "},
+	}
+	for want, c := range cases {
+		if got := robustnessPath("/roles/{name}/keys/{key}", c); got != want {
+			t.Errorf("robustnessPath(%q) = %q, want %q", c.Value, got, want)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"strconv"
+	"strings"
+)
+
+// sandboxExecArg makes the host binary the launcher of a sandboxed plugin: it confines
+// itself (AppArmor profile, seccomp filter, user) and then execs the plugin, so the
+// plugin starts confined from its first instruction
+const sandboxExecArg = "__sandbox-exec"
+
+// pluginSandbox restricts the process of an untrusted plugin. The zero value runs the
+// plugin as the host runs, with the host's environment.
+type pluginSandbox struct {
+	user     *sandboxUser // run the plugin as this user instead of the host's
+	workDir  string       // working directory of the plugin (empty inherits the host's)
+	clearEnv bool         // start the plugin with only the variables the host sets
+	apparmor string       // AppArmor profile the plugin is confined by
+	seccomp  bool         // deny the plugin syscalls it has no business making
+}
+
+// sandboxUser is the user and group a plugin runs as
+type sandboxUser struct {
+	name     string
+	uid, gid uint32
+	groups   []uint32
+}
+
+// parseSandboxUser looks up a user name or numeric uid, optionally followed by
+// :group (a group name or numeric gid) overriding the user's primary group
+func parseSandboxUser(spec string) (*sandboxUser, error) {
+	name, groupName, hasGroup := strings.Cut(spec, ":")
+	u := &sandboxUser{name: name}
+
+	if uid, err := strconv.ParseUint(name, 10, 32); err == nil {
+		u.uid, u.gid = uint32(uid), uint32(uid)
+		if account, err := user.LookupId(name); err == nil {
+			gid, _ := strconv.ParseUint(account.Gid, 10, 32)
+			u.gid = uint32(gid)
+			u.groups = supplementaryGroups(account)
+		}
+	} else {
+		account, err := user.Lookup(name)
+		if err != nil {
+			return nil, fmt.Errorf("unknown user %q", name)
+		}
+		uid, _ := strconv.ParseUint(account.Uid, 10, 32)
+		gid, _ := strconv.ParseUint(account.Gid, 10, 32)
+		u.uid, u.gid = uint32(uid), uint32(gid)
+		u.groups = supplementaryGroups(account)
+	}
+
+	if hasGroup {
+		gid, err := strconv.ParseUint(groupName, 10, 32)
+		if err != nil {
+			group, lookupErr := user.LookupGroup(groupName)
+			if lookupErr != nil {
+				return nil, fmt.Errorf("unknown group %q", groupName)
+			}
+			gid, _ = strconv.ParseUint(group.Gid, 10, 32)
+		}
+		u.gid = uint32(gid)
+	}
+	return u, nil
+}
+
+// supplementaryGroups returns the groups an account belongs to besides its primary one
+func supplementaryGroups(account *user.User) []uint32 {
+	ids, err := account.GroupIds()
+	if err != nil {
+		return nil
+	}
+	var groups []uint32
+	for _, id := range ids {
+		if gid, err := strconv.ParseUint(id, 10, 32); err == nil && id != account.Gid {
+			groups = append(groups, uint32(gid))
+		}
+	}
+	return groups
+}
+
+// newPluginSandbox validates the sandbox flags. It returns nil when none is set.
+func newPluginSandbox(userSpec, workDir string, clearEnv bool, apparmor string, seccomp bool) (*pluginSandbox, error) {
+	if userSpec == "" && workDir == "" && !clearEnv && apparmor == "" && !seccomp {
+		return nil, nil
+	}
+	sandbox := &pluginSandbox{workDir: workDir, clearEnv: clearEnv, apparmor: apparmor, seccomp: seccomp}
+	if userSpec != "" {
+		u, err := parseSandboxUser(userSpec)
+		if err != nil {
+			return nil, err
+		}
+		sandbox.user = u
+	}
+	if workDir != "" {
+		info, err := os.Stat(workDir)
+		if err != nil {
+			return nil, fmt.Errorf("working directory: %w", err)
+		}
+		if !info.IsDir() {
+			return nil, fmt.Errorf("working directory %s is not a directory", workDir)
+		}
+	}
+	if err := checkSandboxSupport(sandbox); err != nil {
+		return nil, err
+	}
+	if sandbox.user != nil && int(sandbox.user.uid) != os.Geteuid() {
+		shareHostSockets()
+	}
+	return sandbox, nil
+}
+
+// String describes the sandbox for the startup output
+func (s *pluginSandbox) String() string {
+	var parts []string
+	if s.user != nil {
+		parts = append(parts, fmt.Sprintf("user %s (uid %d, gid %d)", s.user.name, s.user.uid, s.user.gid))
+	}
+	if s.workDir != "" {
+		parts = append(parts, "working directory "+s.workDir)
+	}
+	if s.clearEnv {
+		parts = append(parts, "cleared environment")
+	}
+	if s.apparmor != "" {
+		parts = append(parts, "AppArmor profile "+s.apparmor)
+	}
+	if s.seccomp {
+		parts = append(parts, "seccomp filter")
+	}
+	return strings.Join(parts, ", ")
+}
+
+// command returns the command that starts the plugin at path with args in the sandbox,
+// with env added to the environment. A nil sandbox starts the plugin unconfined.
+func (s *pluginSandbox) command(path string, args, env []string) (*exec.Cmd, error) {
+	if s == nil {
+		cmd := exec.Command(path, args...)
+		cmd.Env = append(os.Environ(), env...)
+		return cmd, nil
+	}
+
+	var cmd *exec.Cmd
+	if s.apparmor != "" || s.seccomp {
+		self, err := os.Executable()
+		if err != nil {
+			return nil, fmt.Errorf("failed to find the host binary to launch the sandbox: %w", err)
+		}
+		launch := []string{sandboxExecArg}
+		if s.apparmor != "" {
+			launch = append(launch, "-apparmor", s.apparmor)
+		}
+		if s.seccomp {
+			launch = append(launch, "-seccomp")
+		}
+		if s.user != nil {
+			launch = append(launch, "-uid", strconv.FormatUint(uint64(s.user.uid), 10), "-gid", strconv.FormatUint(uint64(s.user.gid), 10))
+			for _, gid := range s.user.groups {
+				launch = append(launch, "-group", strconv.FormatUint(uint64(gid), 10))
+			}
+		}
+		launch = append(launch, "--", path)
+		cmd = exec.Command(self, append(launch, args...)...)
+	} else {
+		cmd = exec.Command(path, args...)
+		if s.user != nil {
+			setProcessUser(cmd, s.user)
+		}
+	}
+
+	cmd.Dir = s.workDir
+	if s.clearEnv {
+		cmd.Env = env
+	} else {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	return cmd, nil
+}
+
+// runSandboxExec is the launcher of a sandboxed plugin, started as
+// "vault-plugin-host __sandbox-exec [flags] -- plugin [args]". It only returns on failure.
+func runSandboxExec(args []string) int {
+	flags := flag.NewFlagSet(sandboxExecArg, flag.ContinueOnError)
+	apparmor := flags.String("apparmor", "", "")
+	seccomp := flags.Bool("seccomp", false, "")
+	uid := flags.Int("uid", -1, "")
+	gid := flags.Int("gid", -1, "")
+	var groups stringsFlag
+	flags.Var(&groups, "group", "")
+	if err := flags.Parse(args); err != nil || flags.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "usage: vault-plugin-host "+sandboxExecArg+" [flags] -- plugin [args]")
+		return 2
+	}
+
+	confinement := sandboxExec{apparmor: *apparmor, seccomp: *seccomp, uid: *uid, gid: *gid}
+	for _, group := range groups {
+		id, err := strconv.Atoi(group)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "sandbox: invalid group %q\n", group)
+			return 2
+		}
+		confinement.groups = append(confinement.groups, id)
+	}
+	if err := confinement.exec(flags.Args()); err != nil {
+		fmt.Fprintf(os.Stderr, "sandbox: %v\n", err)
+	}
+	return 1
+}
+
+// sandboxExec is how the launcher confines itself before it execs the plugin
+type sandboxExec struct {
+	apparmor string
+	seccomp  bool
+	uid, gid int // -1 keeps the launcher's
+	groups   []int
+}
@@ -0,0 +1,157 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build linux
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// seccompDenied are the syscalls the -plugin-seccomp filter fails with EPERM: ones a
+// secrets engine or auth method never needs, which an escaping plugin would
+var seccompDenied = []uintptr{
+	unix.SYS_PTRACE, unix.SYS_PROCESS_VM_READV, unix.SYS_PROCESS_VM_WRITEV,
+	unix.SYS_MOUNT, unix.SYS_UMOUNT2, unix.SYS_PIVOT_ROOT, unix.SYS_SETNS, unix.SYS_UNSHARE,
+	unix.SYS_OPEN_BY_HANDLE_AT, unix.SYS_SWAPON, unix.SYS_SWAPOFF, unix.SYS_ACCT,
+	unix.SYS_REBOOT, unix.SYS_KEXEC_LOAD, unix.SYS_INIT_MODULE, unix.SYS_FINIT_MODULE, unix.SYS_DELETE_MODULE,
+	unix.SYS_BPF, unix.SYS_PERF_EVENT_OPEN, unix.SYS_USERFAULTFD,
+	unix.SYS_KEYCTL, unix.SYS_ADD_KEY, unix.SYS_REQUEST_KEY,
+	unix.SYS_SETTIMEOFDAY, unix.SYS_CLOCK_SETTIME, unix.SYS_SETHOSTNAME, unix.SYS_SETDOMAINNAME,
+}
+
+// seccompArch is the audit architecture the filter admits; syscalls made through
+// another ABI kill the plugin, so they cannot bypass the syscall numbers checked
+var seccompArch = map[string]uint32{
+	"amd64": unix.AUDIT_ARCH_X86_64,
+	"arm64": unix.AUDIT_ARCH_AARCH64,
+}
+
+// checkSandboxSupport reports sandbox options the host cannot apply
+func checkSandboxSupport(s *pluginSandbox) error {
+	if s.seccomp {
+		if _, ok := seccompArch[runtime.GOARCH]; !ok {
+			return fmt.Errorf("-plugin-seccomp is not supported on %s", runtime.GOARCH)
+		}
+	}
+	if s.apparmor != "" {
+		if _, err := os.Stat("/sys/kernel/security/apparmor"); err != nil {
+			return fmt.Errorf("-plugin-apparmor: AppArmor is not enabled on this host")
+		}
+	}
+	if s.user != nil && os.Geteuid() != 0 && int(s.user.uid) != os.Geteuid() {
+		return fmt.Errorf("-plugin-user: the host must run as root to start a plugin as another user")
+	}
+	return nil
+}
+
+// shareHostSockets lets a plugin running as another user connect back to the host:
+// go-plugin creates the sockets of its broker (which serves the plugin storage and the
+// system view) with the host's umask, which would leave them writable by the host's
+// user alone. The connections are still authenticated by AutoMTLS, and the files the
+// host writes all carry explicit modes.
+func shareHostSockets() {
+	syscall.Umask(0)
+}
+
+// setProcessUser makes cmd run as u
+func setProcessUser(cmd *exec.Cmd, u *sandboxUser) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Credential: &syscall.Credential{Uid: u.uid, Gid: u.gid, Groups: u.groups},
+	}
+}
+
+// exec confines the launcher and replaces it with the plugin. The AppArmor profile
+// takes effect at the exec; the seccomp filter is installed while the launcher may
+// still have the privileges to do so without no_new_privs, then the user is dropped.
+func (c sandboxExec) exec(argv []string) error {
+	// The AppArmor exec attribute belongs to the thread that execs
+	runtime.LockOSThread()
+
+	if c.apparmor != "" {
+		if err := setAppArmorOnExec(c.apparmor); err != nil {
+			return err
+		}
+	}
+	if c.seccomp {
+		if err := installSeccompFilter(); err != nil {
+			return err
+		}
+	}
+	if c.uid >= 0 {
+		if err := syscall.Setgroups(c.groups); err != nil {
+			return fmt.Errorf("setgroups: %w", err)
+		}
+		if err := syscall.Setgid(c.gid); err != nil {
+			return fmt.Errorf("setgid %d: %w", c.gid, err)
+		}
+		if err := syscall.Setuid(c.uid); err != nil {
+			return fmt.Errorf("setuid %d: %w", c.uid, err)
+		}
+	}
+	return syscall.Exec(argv[0], argv, os.Environ())
+}
+
+// setAppArmorOnExec asks AppArmor to confine the next exec of this thread by profile
+func setAppArmorOnExec(profile string) error {
+	value := []byte("exec " + profile)
+	err := os.WriteFile("/proc/thread-self/attr/apparmor/exec", value, 0)
+	if os.IsNotExist(err) {
+		// Kernels without the per-LSM attribute directory
+		err = os.WriteFile("/proc/thread-self/attr/exec", value, 0)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to set AppArmor profile %s (is it loaded?): %w", profile, err)
+	}
+	return nil
+}
+
+// seccompFilter builds the BPF program of the -plugin-seccomp filter
+func seccompFilter() []unix.SockFilter {
+	const (
+		load  = unix.BPF_LD | unix.BPF_W | unix.BPF_ABS
+		jeq   = unix.BPF_JMP | unix.BPF_JEQ | unix.BPF_K
+		ret   = unix.BPF_RET | unix.BPF_K
+		nrOff = 0 // offsetof(struct seccomp_data, nr)
+		arOff = 4 // offsetof(struct seccomp_data, arch)
+	)
+	filter := []unix.SockFilter{
+		{Code: load, K: arOff},
+		{Code: jeq, Jt: 1, K: seccompArch[runtime.GOARCH]},
+		{Code: ret, K: unix.SECCOMP_RET_KILL_PROCESS},
+		{Code: load, K: nrOff},
+	}
+	for _, nr := range seccompDenied {
+		filter = append(filter,
+			unix.SockFilter{Code: jeq, Jf: 1, K: uint32(nr)},
+			unix.SockFilter{Code: ret, K: unix.SECCOMP_RET_ERRNO | uint32(unix.EPERM)},
+		)
+	}
+	return append(filter, unix.SockFilter{Code: ret, K: unix.SECCOMP_RET_ALLOW})
+}
+
+// installSeccompFilter installs the -plugin-seccomp filter on every thread of the
+// launcher; the plugin inherits it across exec. Without CAP_SYS_ADMIN the kernel
+// requires no_new_privs first.
+func installSeccompFilter() error {
+	if os.Geteuid() != 0 {
+		if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+			return fmt.Errorf("failed to set no_new_privs: %w", err)
+		}
+	}
+	filter := seccompFilter()
+	prog := unix.SockFprog{Len: uint16(len(filter)), Filter: &filter[0]}
+	_, _, errno := unix.Syscall(unix.SYS_SECCOMP, unix.SECCOMP_SET_MODE_FILTER, unix.SECCOMP_FILTER_FLAG_TSYNC, uintptr(unsafe.Pointer(&prog)))
+	if errno != 0 {
+		return fmt.Errorf("failed to install seccomp filter: %w", errno)
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build linux
+
+package main
+
+import (
+	"runtime"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestSeccompFilter(t *testing.T) {
+	if _, ok := seccompArch[runtime.GOARCH]; !ok {
+		t.Skipf("seccomp is not supported on %s", runtime.GOARCH)
+	}
+	filter := seccompFilter()
+	if len(filter) != 4+2*len(seccompDenied)+1 {
+		t.Fatalf("filter has %d instructions for %d denied syscalls", len(filter), len(seccompDenied))
+	}
+	if filter[1].K != seccompArch[runtime.GOARCH] || filter[2].K != unix.SECCOMP_RET_KILL_PROCESS {
+		t.Error("the filter should kill syscalls of another architecture")
+	}
+	for i, nr := range seccompDenied {
+		check, deny := filter[4+2*i], filter[5+2*i]
+		if check.K != uint32(nr) || check.Jf != 1 || deny.K != unix.SECCOMP_RET_ERRNO|uint32(unix.EPERM) {
+			t.Errorf("syscall %d is not denied with EPERM", nr)
+		}
+	}
+	if last := filter[len(filter)-1]; last.K != unix.SECCOMP_RET_ALLOW {
+		t.Error("the filter should allow every other syscall")
+	}
+}
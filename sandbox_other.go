@@ -0,0 +1,35 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !linux
+
+package main
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+// checkSandboxSupport reports sandbox options the host cannot apply: only the working
+// directory and environment can be restricted outside Linux
+func checkSandboxSupport(s *pluginSandbox) error {
+	switch {
+	case s.user != nil:
+		return fmt.Errorf("-plugin-user is only supported on Linux")
+	case s.apparmor != "":
+		return fmt.Errorf("-plugin-apparmor is only supported on Linux")
+	case s.seccomp:
+		return fmt.Errorf("-plugin-seccomp is only supported on Linux")
+	}
+	return nil
+}
+
+// shareHostSockets is never called: checkSandboxSupport refuses -plugin-user
+func shareHostSockets() {}
+
+// setProcessUser is never called: checkSandboxSupport refuses -plugin-user
+func setProcessUser(cmd *exec.Cmd, u *sandboxUser) {}
+
+func (c sandboxExec) exec(argv []string) error {
+	return fmt.Errorf("sandboxing is only supported on Linux")
+}
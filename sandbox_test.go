@@ -0,0 +1,99 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestNewPluginSandbox(t *testing.T) {
+	if sandbox, err := newPluginSandbox("", "", false, "", false); sandbox != nil || err != nil {
+		t.Errorf("newPluginSandbox with no options = %v, %v", sandbox, err)
+	}
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file")
+	os.WriteFile(file, nil, 0o600)
+	for _, workDir := range []string{filepath.Join(dir, "missing"), file} {
+		if _, err := newPluginSandbox("", workDir, false, "", false); err == nil {
+			t.Errorf("newPluginSandbox should refuse working directory %s", workDir)
+		}
+	}
+	if _, err := newPluginSandbox("no-such-user-for-the-sandbox", "", false, "", false); err == nil {
+		t.Error("newPluginSandbox should refuse an unknown user")
+	}
+
+	sandbox, err := newPluginSandbox("", dir, true, "", false)
+	if err != nil {
+		t.Fatalf("newPluginSandbox failed: %v", err)
+	}
+	if got := sandbox.String(); got != "working directory "+dir+", cleared environment" {
+		t.Errorf("String() = %q", got)
+	}
+}
+
+func TestParseSandboxUser(t *testing.T) {
+	u, err := parseSandboxUser("12345:54321")
+	if err != nil {
+		t.Fatalf("parseSandboxUser failed: %v", err)
+	}
+	if u.uid != 12345 || u.gid != 54321 {
+		t.Errorf("uid, gid = %d, %d, want 12345, 54321", u.uid, u.gid)
+	}
+	if _, err := parseSandboxUser("12345:no-such-group-for-the-sandbox"); err == nil {
+		t.Error("parseSandboxUser should refuse an unknown group")
+	}
+}
+
+func TestPluginSandboxCommand(t *testing.T) {
+	t.Setenv("SANDBOX_TEST_HOST_VAR", "1")
+	env := []string{"VAULT_BACKEND_PLUGIN=cookie"}
+
+	var unconfined *pluginSandbox
+	cmd, err := unconfined.command("/bin/plugin", []string{"-mode", "dev"}, env)
+	if err != nil {
+		t.Fatalf("command failed: %v", err)
+	}
+	if !slices.Contains(cmd.Env, "SANDBOX_TEST_HOST_VAR=1") || !slices.Contains(cmd.Env, env[0]) {
+		t.Errorf("an unconfined plugin should get the host's environment and env, got %v", cmd.Env)
+	}
+	if want := []string{"/bin/plugin", "-mode", "dev"}; !slices.Equal(cmd.Args, want) {
+		t.Errorf("args = %v, want %v", cmd.Args, want)
+	}
+
+	dir := t.TempDir()
+	sandbox := &pluginSandbox{workDir: dir, clearEnv: true}
+	cmd, err = sandbox.command("/bin/plugin", nil, env)
+	if err != nil {
+		t.Fatalf("command failed: %v", err)
+	}
+	if cmd.Path != "/bin/plugin" || cmd.Dir != dir {
+		t.Errorf("path, dir = %s, %s, want /bin/plugin, %s", cmd.Path, cmd.Dir, dir)
+	}
+	if !slices.Equal(cmd.Env, env) {
+		t.Errorf("a cleared environment should hold only %v, got %v", env, cmd.Env)
+	}
+
+	// Seccomp and AppArmor confinement go through the launcher
+	sandbox = &pluginSandbox{seccomp: true, apparmor: "plugin", user: &sandboxUser{uid: 65534, gid: 65534, groups: []uint32{100}}}
+	cmd, err = sandbox.command("/bin/plugin", []string{"-v"}, env)
+	if err != nil {
+		t.Fatalf("command failed: %v", err)
+	}
+	want := []string{sandboxExecArg, "-apparmor", "plugin", "-seccomp", "-uid", "65534", "-gid", "65534", "-group", "100", "--", "/bin/plugin", "-v"}
+	if !slices.Equal(cmd.Args[1:], want) {
+		t.Errorf("launcher args = %v, want %v", cmd.Args[1:], want)
+	}
+}
+
+func TestRunSandboxExecUsage(t *testing.T) {
+	for _, args := range [][]string{nil, {"-uid"}, {"-group", "x", "--", "/bin/plugin"}, {"-uid", "1"}} {
+		if code := runSandboxExec(args); code != 2 {
+			t.Errorf("runSandboxExec(%q) = %d, want 2", args, code)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// scaffoldFS holds the starter scenario suites written by -scaffold: one directory per
+// plugin archetype, plus run.sh, which every suite gets
+//
+//go:embed scaffolds
+var scaffoldFS embed.FS
+
+// scaffoldArchetypes returns the names of the suites -scaffold can write
+func scaffoldArchetypes() []string {
+	entries, _ := fs.ReadDir(scaffoldFS, "scaffolds")
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// writeScaffold writes the scenario suite of an archetype into dir and returns the
+// files it wrote. Existing files are never overwritten, so the suite can be
+// scaffolded into a directory that already holds scenarios.
+func writeScaffold(archetype, dir string) ([]string, error) {
+	suite := path.Join("scaffolds", archetype)
+	entries, err := fs.ReadDir(scaffoldFS, suite)
+	if err != nil || archetype == "" || strings.ContainsAny(archetype, `/\.`) {
+		return nil, fmt.Errorf("unknown archetype %q (expected one of %s)", archetype, strings.Join(scaffoldArchetypes(), ", "))
+	}
+
+	files := map[string]string{"run.sh": "scaffolds/run.sh"}
+	for _, entry := range entries {
+		files[entry.Name()] = path.Join(suite, entry.Name())
+	}
+	names := make([]string, 0, len(files))
+	for name := range files {
+		target := filepath.Join(dir, name)
+		if _, err := os.Stat(target); err == nil {
+			return nil, fmt.Errorf("%s already exists", target)
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	var written []string
+	for _, name := range names {
+		data, err := scaffoldFS.ReadFile(files[name])
+		if err != nil {
+			return written, err
+		}
+		mode := os.FileMode(0o644)
+		if strings.HasSuffix(name, ".sh") {
+			mode = 0o755
+		}
+		target := filepath.Join(dir, name)
+		if err := os.WriteFile(target, data, mode); err != nil {
+			return written, err
+		}
+		written = append(written, target)
+	}
+	return written, nil
+}
+
+// runScaffoldCommand writes a scenario suite for -scaffold and returns the process
+// exit code
+func runScaffoldCommand(archetype, dir string) int {
+	written, err := writeScaffold(archetype, dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Cannot scaffold scenarios: %v\n", err)
+		return 1
+	}
+	for _, file := range written {
+		fmt.Println(file)
+	}
+	fmt.Fprintf(os.Stderr, "Wrote the %s scenario suite; run it with %s /path/to/plugin-binary\n", archetype, filepath.Join(dir, "run.sh"))
+	return 0
+}
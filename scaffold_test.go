@@ -0,0 +1,75 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestScaffoldArchetypes(t *testing.T) {
+	if got := scaffoldArchetypes(); !reflect.DeepEqual(got, []string{"dynamic-creds", "kv", "pki-like"}) {
+		t.Errorf("archetypes = %v", got)
+	}
+}
+
+func TestWriteScaffold(t *testing.T) {
+	for _, archetype := range scaffoldArchetypes() {
+		dir := filepath.Join(t.TempDir(), "scenarios")
+		written, err := writeScaffold(archetype, dir)
+		if err != nil {
+			t.Fatalf("%s: %v", archetype, err)
+		}
+		if info, err := os.Stat(filepath.Join(dir, "run.sh")); err != nil || info.Mode().Perm()&0o100 == 0 {
+			t.Errorf("%s: run.sh should be executable", archetype)
+		}
+
+		// Every scenario parses, has a matrix, and only references its matrix columns
+		// and the variables its earlier lines captured
+		for _, file := range written {
+			if !strings.HasSuffix(file, ".ndjson") {
+				continue
+			}
+			rows, err := loadPipelineMatrix(strings.TrimSuffix(file, ".ndjson") + ".csv")
+			if err != nil {
+				t.Errorf("%s: %v", file, err)
+				continue
+			}
+			defined := make(map[string]bool)
+			for name := range rows[0] {
+				defined[name] = true
+			}
+			data, _ := os.ReadFile(file)
+			for n, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+				var req pipelineRequest
+				if err := json.Unmarshal([]byte(line), &req); err != nil {
+					t.Errorf("%s line %d: %v", file, n+1, err)
+					continue
+				}
+				for _, ref := range pipelinePlaceholder.FindAllStringSubmatch(line, -1) {
+					if !defined[ref[1]] {
+						t.Errorf("%s line %d references undefined variable %q", file, n+1, ref[1])
+					}
+				}
+				for name := range req.Capture {
+					defined[name] = true
+				}
+			}
+		}
+
+		if _, err := writeScaffold(archetype, dir); err == nil {
+			t.Errorf("%s: scaffolding over existing files should fail", archetype)
+		}
+	}
+
+	for _, archetype := range []string{"", "unknown", "../scaffolds", "kv/."} {
+		if _, err := writeScaffold(archetype, t.TempDir()); err == nil {
+			t.Errorf("archetype %q should be rejected", archetype)
+		}
+	}
+}
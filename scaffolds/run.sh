@@ -0,0 +1,23 @@
+#!/bin/sh
+# Runs every scenario of this suite against a plugin, once per row of its matrix:
+#
+#   ./run.sh /path/to/plugin-binary [vault-plugin-host flags...]
+#
+# Responses go to <scenario>.out; the host exits non-zero when a row fails.
+set -u
+
+plugin=${1:?usage: ./run.sh /path/to/plugin-binary [flags...]}
+shift
+host=${VAULT_PLUGIN_HOST:-vault-plugin-host}
+cd "$(dirname "$0")" || exit 1
+
+status=0
+for scenario in *.ndjson; do
+	name=${scenario%.ndjson}
+	echo "== $name"
+	if ! "$host" -plugin "$plugin" -pipeline -matrix "$name.csv" "$@" <"$scenario" >"$name.out"; then
+		tail -n 1 "$name.out"
+		status=1
+	fi
+done
+exit $status
@@ -0,0 +1,256 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"vault-plugin-host/handlers"
+)
+
+// scenarioFile is a file of the test subcommand: variables and the steps run with them
+type scenarioFile struct {
+	Vars  map[string]interface{} `yaml:"vars"`
+	Steps []yaml.Node            `yaml:"steps"`
+}
+
+// scenarioStep is one step of a scenario. Its request is a pipeline line, such as a
+// request, a wait or a checkpoint, built from every key of the step but name and expect.
+type scenarioStep struct {
+	Name    string
+	Line    int // in the scenario file
+	Expect  scenarioExpect
+	request []byte
+}
+
+// scenarioExpect is what a step's response must look like. Fields and present use the
+// paths of captures, such as body.data.username; expected values may hold variables.
+type scenarioExpect struct {
+	Status  *int                   `json:"status,omitempty"`
+	Fields  map[string]interface{} `json:"fields,omitempty"`
+	Present []string               `json:"present,omitempty"`
+}
+
+// scenario is a parsed scenario file
+type scenario struct {
+	Path  string
+	Vars  map[string]interface{}
+	Steps []scenarioStep
+}
+
+// loadScenario reads a scenario file. YAML is a superset of JSON, so both are accepted.
+func loadScenario(path string) (*scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file scenarioFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid scenario %s: %w", path, err)
+	}
+	if len(file.Steps) == 0 {
+		return nil, fmt.Errorf("scenario %s has no steps", path)
+	}
+
+	parsed := &scenario{Path: path, Vars: file.Vars}
+	for i, node := range file.Steps {
+		var fields map[string]interface{}
+		if err := node.Decode(&fields); err != nil {
+			return nil, fmt.Errorf("scenario %s line %d: invalid step: %w", path, node.Line, err)
+		}
+		step := scenarioStep{Line: node.Line}
+		if name, ok := fields["name"].(string); ok {
+			step.Name = name
+		} else {
+			step.Name = fmt.Sprintf("step %d", i+1)
+		}
+		if expect, ok := fields["expect"]; ok {
+			raw, _ := json.Marshal(expect)
+			decoder := json.NewDecoder(bytes.NewReader(raw))
+			decoder.DisallowUnknownFields()
+			if err := decoder.Decode(&step.Expect); err != nil {
+				return nil, fmt.Errorf("scenario %s line %d: invalid expect (expected status, fields and present): %w", path, node.Line, err)
+			}
+		}
+		delete(fields, "name")
+		delete(fields, "expect")
+		if _, ok := fields["id"]; !ok {
+			fields["id"] = step.Name
+		}
+		if step.request, err = json.Marshal(fields); err != nil {
+			return nil, fmt.Errorf("scenario %s line %d: invalid step: %w", path, node.Line, err)
+		}
+		parsed.Steps = append(parsed.Steps, step)
+	}
+	return parsed, nil
+}
+
+// scenarioResult is the outcome of one step
+type scenarioResult struct {
+	Step     string
+	Line     int
+	Status   int
+	Failures []string
+}
+
+// runScenario runs the steps of a scenario in order against the plugin and writes a
+// PASS or FAIL line per step to out. The scenario's variables are defaults, so vars
+// given with -var take precedence. Steps keep running after one fails, so a run
+// reports every failure.
+func runScenario(handler *handlers.Handler, mount string, vars map[string]string, sc *scenario, out io.Writer) ([]scenarioResult, error) {
+	variables := newPipelineVars(vars)
+	defaults := make(map[string]interface{}, len(sc.Vars))
+	for name, value := range sc.Vars {
+		if _, set := vars[name]; !set {
+			defaults[name] = value
+		}
+	}
+	if err := variables.set(defaults); err != nil {
+		return nil, fmt.Errorf("scenario %s: %w", sc.Path, err)
+	}
+	checkpoints := handlers.NewCheckpoints(handler)
+
+	results := make([]scenarioResult, 0, len(sc.Steps))
+	for _, step := range sc.Steps {
+		resp := servePipelineLine(handler, checkpoints, variables, mount, step.request)
+		result := scenarioResult{
+			Step:     step.Name,
+			Line:     step.Line,
+			Status:   resp.Status,
+			Failures: checkScenarioStep(resp, step.Expect, variables),
+		}
+		results = append(results, result)
+
+		verdict := "PASS"
+		if len(result.Failures) > 0 {
+			verdict = "FAIL"
+		}
+		fmt.Fprintf(out, "%s  %s:%d %s (%d)\n", verdict, sc.Path, step.Line, step.Name, resp.Status)
+		for _, failure := range result.Failures {
+			fmt.Fprintf(out, "      %s\n", failure)
+		}
+	}
+	return results, nil
+}
+
+// checkScenarioStep returns the ways a response falls short of a step's expectations.
+// Without an expected status, a step fails as a matrix row does: on a status of 400 or
+// above, unless it was a wait whose condition held.
+func checkScenarioStep(resp pipelineResponse, expect scenarioExpect, vars *pipelineVars) []string {
+	var failures []string
+	switch {
+	case expect.Status != nil && resp.Status != *expect.Status:
+		failures = append(failures, fmt.Sprintf("status %d, want %d%s", resp.Status, *expect.Status, scenarioErrors(resp)))
+	case expect.Status == nil && resp.Status >= http.StatusBadRequest && !resp.met:
+		failures = append(failures, fmt.Sprintf("status %d%s", resp.Status, scenarioErrors(resp)))
+	}
+	failures = append(failures, resp.CaptureErrors...)
+
+	root := pipelineRoot(resp)
+	for _, field := range expect.Present {
+		if _, ok := scenarioField(root, field); !ok {
+			failures = append(failures, fmt.Sprintf("%s is missing", field))
+		}
+	}
+
+	names := make([]string, 0, len(expect.Fields))
+	for name := range expect.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		want, err := vars.expand(expect.Fields[name])
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", name, err))
+			continue
+		}
+		got, ok := scenarioField(root, name)
+		if !ok {
+			failures = append(failures, fmt.Sprintf("%s is missing", name))
+			continue
+		}
+		// Round-trip the expected value so numbers compare as the decoded field does
+		data, _ := json.Marshal(want)
+		var expected interface{}
+		json.Unmarshal(data, &expected)
+		if !reflect.DeepEqual(got, expected) {
+			actual, _ := json.Marshal(got)
+			failures = append(failures, fmt.Sprintf("%s = %s, want %s", name, actual, data))
+		}
+	}
+	return failures
+}
+
+// scenarioField looks up a dotted path or JSONPath in a response
+func scenarioField(root map[string]interface{}, path string) (interface{}, bool) {
+	segments, err := parseJSONPath(path)
+	if err != nil {
+		return nil, false
+	}
+	return pipelineWalk(root, segments)
+}
+
+// scenarioErrors returns the errors of a Vault error response, for failure messages
+func scenarioErrors(resp pipelineResponse) string {
+	var body struct {
+		Errors []string `json:"errors"`
+	}
+	if json.Unmarshal(resp.Body, &body) != nil || len(body.Errors) == 0 {
+		return ""
+	}
+	return ": " + strings.Join(body.Errors, "; ")
+}
+
+// runTestCommand runs the scenario files of the test subcommand in order against the
+// -plugin mount, printing a line per step and a summary, and returns the process exit
+// code: 1 when a step failed or a file could not be read. Files share the plugin and
+// its storage, but each starts with fresh variables and checkpoints.
+func runTestCommand(host *PluginHost, files []string, varFlags []string) int {
+	defer host.Stop()
+
+	vars, err := parsePipelineVars(varFlags)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Cannot run scenarios: %v\n", err)
+		return 1
+	}
+	scenarios := make([]*scenario, 0, len(files))
+	for _, file := range files {
+		sc, err := loadScenario(file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Cannot run scenarios: %v\n", err)
+			return 1
+		}
+		scenarios = append(scenarios, sc)
+	}
+
+	steps, failed := 0, 0
+	for _, sc := range scenarios {
+		results, err := runScenario(host.handler, host.mountPath, vars, sc, os.Stdout)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Cannot run scenarios: %v\n", err)
+			return 1
+		}
+		for _, result := range results {
+			steps++
+			if len(result.Failures) > 0 {
+				failed++
+			}
+		}
+	}
+	fmt.Printf("Scenarios: %d of %d steps passed\n", steps-failed, steps)
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,114 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunScenario(t *testing.T) {
+	host, err := NewPluginHost("/fake/path", false, nil, "plugin")
+	if err != nil {
+		t.Fatalf("NewPluginHost failed: %v", err)
+	}
+	host.handler.SetBackend(echoBackend{})
+
+	path := filepath.Join(t.TempDir(), "roles.yaml")
+	os.WriteFile(path, []byte(`
+vars:
+  role: web
+steps:
+  - name: create role
+    operation: update
+    path: "roles/{{ role }}"
+    data:
+      ttl: 3600
+    capture:
+      created: body.data.path
+    expect:
+      status: 200
+      fields:
+        body.data.path: "roles/{{ role }}"
+        body.data.data.ttl: 3600
+  - name: read it back
+    path: "{{ created }}"
+    expect:
+      present: [body.data.operation]
+  - name: wrong expectations
+    path: roles/web
+    expect:
+      status: 404
+      fields:
+        $.body.data.operation: list
+      present: [body.data.password]
+  - operation: list
+    path: roles/
+`), 0o644)
+
+	sc, err := loadScenario(path)
+	if err != nil {
+		t.Fatalf("loadScenario failed: %v", err)
+	}
+	var out bytes.Buffer
+	results, err := runScenario(host.handler, host.mountPath, map[string]string{"unused": "x"}, sc, &out)
+	if err != nil {
+		t.Fatalf("runScenario failed: %v", err)
+	}
+
+	if len(results) != 4 {
+		t.Fatalf("got %d results, want 4:\n%s", len(results), out.String())
+	}
+	for _, i := range []int{0, 1, 3} {
+		if len(results[i].Failures) > 0 {
+			t.Errorf("step %q failed: %v", results[i].Step, results[i].Failures)
+		}
+	}
+	if results[3].Step != "step 4" || results[3].Line != 28 {
+		t.Errorf("unnamed step = %+v, want step 4 at line 28", results[3])
+	}
+	failures := strings.Join(results[2].Failures, "\n")
+	for _, want := range []string{"status 200, want 404", `$.body.data.operation = "read", want "list"`, "body.data.password is missing"} {
+		if !strings.Contains(failures, want) {
+			t.Errorf("failures of the wrong step lack %q:\n%s", want, failures)
+		}
+	}
+	if !strings.Contains(out.String(), "FAIL  "+path+":21 wrong expectations (200)") {
+		t.Errorf("output:\n%s", out.String())
+	}
+
+	// Variables given with -var override the scenario's own
+	sc = &scenario{Path: "override.yaml", Vars: map[string]interface{}{"role": "web"}}
+	sc.Steps = []scenarioStep{{
+		Name:    "read",
+		request: []byte(`{"path": "roles/{{ role }}"}`),
+		Expect:  scenarioExpect{Fields: map[string]interface{}{"body.data.path": "roles/api"}},
+	}}
+	results, err = runScenario(host.handler, host.mountPath, map[string]string{"role": "api"}, sc, &out)
+	if err != nil || len(results[0].Failures) > 0 {
+		t.Errorf("scenario with an overridden variable: %v, %+v", err, results)
+	}
+}
+
+func TestLoadScenarioErrors(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"empty.yaml":   "vars: {a: 1}\n",
+		"invalid.yaml": "steps: [\n",
+		"expect.yaml":  "steps:\n  - path: config\n    expect: {code: 200}\n",
+		"step.yaml":    "steps:\n  - just a string\n",
+	} {
+		path := filepath.Join(dir, name)
+		os.WriteFile(path, []byte(content), 0o644)
+		if _, err := loadScenario(path); err == nil {
+			t.Errorf("loadScenario(%s) should fail", name)
+		}
+	}
+	if _, err := loadScenario(filepath.Join(dir, "missing.yaml")); err == nil {
+		t.Error("loading a missing scenario should fail")
+	}
+}
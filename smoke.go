@@ -0,0 +1,291 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/vault/sdk/framework"
+)
+
+// smokeName is the value used for path parameters, so everything the smoke test
+// creates is easy to recognize and is deleted again at the end of each path
+const smokeName = "smoke-test"
+
+// smokeStep is one request of a smoke test
+type smokeStep struct {
+	Method string      // POST, GET, LIST or DELETE
+	Path   string      // relative to the mount
+	Body   interface{} // request body for POST, or nil
+}
+
+var oasPathParam = regexp.MustCompile(`\{([^}]+)\}`)
+
+// smokeSteps derives requests exercising every path of an OpenAPI document with
+// example data. For each path it writes, reads, lists and finally deletes; deletes are
+// only issued on paths with parameters, where they remove the smoke-test object the
+// write created rather than shared configuration.
+func smokeSteps(doc *framework.OASDocument) []smokeStep {
+	paths := make([]string, 0, len(doc.Paths))
+	for path := range doc.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var steps []smokeStep
+	for _, template := range paths {
+		item := doc.Paths[template]
+		if item == nil {
+			continue
+		}
+
+		templated := oasPathParam.MatchString(template)
+		path := oasPathParam.ReplaceAllStringFunc(strings.TrimPrefix(template, "/"), func(param string) string {
+			return pathParamValue(item.Parameters, strings.Trim(param, "{}"))
+		})
+
+		if item.Post != nil {
+			steps = append(steps, smokeStep{Method: "POST", Path: path, Body: exampleBody(doc, item.Post)})
+		}
+		if item.Get != nil {
+			if isListOperation(item.Get) {
+				steps = append(steps, smokeStep{Method: "LIST", Path: strings.TrimSuffix(path, "/")})
+			} else {
+				steps = append(steps, smokeStep{Method: "GET", Path: path})
+			}
+		}
+		if item.Delete != nil && templated {
+			steps = append(steps, smokeStep{Method: "DELETE", Path: path})
+		}
+	}
+	return steps
+}
+
+// isListOperation reports whether a GET operation is a list (it takes list=true)
+func isListOperation(op *framework.OASOperation) bool {
+	for _, param := range op.Parameters {
+		if param.Name == "list" && param.In == "query" {
+			return true
+		}
+	}
+	return false
+}
+
+// pathParamValue picks the value of a path parameter: its example or first enum
+// value when the schema has one, otherwise smokeName
+func pathParamValue(params []framework.OASParameter, name string) string {
+	for _, param := range params {
+		if param.Name != name || param.Schema == nil {
+			continue
+		}
+		if param.Schema.Example != nil {
+			return fmt.Sprint(param.Schema.Example)
+		}
+		if len(param.Schema.Enum) > 0 {
+			return fmt.Sprint(param.Schema.Enum[0])
+		}
+	}
+	return smokeName
+}
+
+// exampleBody builds a request body holding the required fields and the fields with
+// an example value of an operation's request schema
+func exampleBody(doc *framework.OASDocument, op *framework.OASOperation) map[string]interface{} {
+	body := map[string]interface{}{}
+	if op.RequestBody == nil {
+		return body
+	}
+	media := op.RequestBody.Content["application/json"]
+	if media == nil {
+		return body
+	}
+	schema := resolveSchema(doc, media.Schema)
+	if schema == nil {
+		return body
+	}
+
+	required := make(map[string]bool)
+	for _, name := range schema.Required {
+		required[name] = true
+	}
+	for name, prop := range schema.Properties {
+		if prop == nil || prop.Deprecated || (!required[name] && prop.Example == nil) {
+			continue
+		}
+		body[name] = exampleValue(doc, prop)
+	}
+	return body
+}
+
+// resolveSchema follows a #/components/schemas reference
+func resolveSchema(doc *framework.OASDocument, schema *framework.OASSchema) *framework.OASSchema {
+	if schema == nil || schema.Ref == "" {
+		return schema
+	}
+	return doc.Components.Schemas[strings.TrimPrefix(schema.Ref, "#/components/schemas/")]
+}
+
+// exampleValue returns a plausible value for a schema: its example, default or first
+// enum value, or else a placeholder of the right type
+func exampleValue(doc *framework.OASDocument, schema *framework.OASSchema) interface{} {
+	schema = resolveSchema(doc, schema)
+	switch {
+	case schema == nil:
+		return smokeName
+	case schema.Example != nil:
+		return schema.Example
+	case schema.Default != nil:
+		return schema.Default
+	case len(schema.Enum) > 0:
+		return schema.Enum[0]
+	}
+
+	switch schema.Type {
+	case "integer", "number":
+		if schema.Format == "seconds" {
+			return 3600
+		}
+		return 1
+	case "boolean":
+		return true
+	case "array":
+		if schema.Items != nil {
+			return []interface{}{exampleValue(doc, schema.Items)}
+		}
+		return []interface{}{}
+	case "object":
+		return map[string]interface{}{}
+	}
+	if schema.Format == "duration" || schema.Format == "seconds" {
+		return "1h"
+	}
+	return smokeName
+}
+
+// writeSmokeScript writes a POSIX shell script that runs the steps with curl against
+// $VAULT_ADDR and exits non-zero if any request fails
+func writeSmokeScript(w io.Writer, mount string, steps []smokeStep) {
+	fmt.Fprintf(w, `#!/bin/sh
+# Smoke test for the plugin mounted at %s/, generated from its OpenAPI document.
+# Usage: VAULT_ADDR=https://vault.example.com VAULT_TOKEN=... [MOUNT=%s] ./smoke.sh
+#
+# Objects are created under the name %q and deleted again where the plugin supports it.
+set -u
+: "${VAULT_ADDR:?VAULT_ADDR must be set}"
+: "${VAULT_TOKEN:?VAULT_TOKEN must be set}"
+MOUNT="${MOUNT:-%s}"
+pass=0
+fail=0
+
+check() {
+  method=$1
+  path=$2
+  body=${3:-}
+  if [ -n "$body" ]; then
+    status=$(curl -sS -o /dev/null -w '%%{http_code}' -X "$method" -H "X-Vault-Token: $VAULT_TOKEN" \
+      -H 'Content-Type: application/json' -d "$body" "$VAULT_ADDR/v1/$MOUNT/$path")
+  else
+    status=$(curl -sS -o /dev/null -w '%%{http_code}' -X "$method" -H "X-Vault-Token: $VAULT_TOKEN" \
+      "$VAULT_ADDR/v1/$MOUNT/$path")
+  fi
+  case "$status" in
+    2??) pass=$((pass + 1)); echo "PASS $method $path ($status)" ;;
+    *) fail=$((fail + 1)); echo "FAIL $method $path ($status)" ;;
+  esac
+}
+
+`, mount, mount, smokeName, mount)
+
+	for _, step := range steps {
+		if step.Body != nil {
+			body, _ := json.Marshal(step.Body)
+			fmt.Fprintf(w, "check %s %s %s\n", step.Method, shellQuote(step.Path), shellQuote(string(body)))
+		} else {
+			fmt.Fprintf(w, "check %s %s\n", step.Method, shellQuote(step.Path))
+		}
+	}
+
+	fmt.Fprint(w, `
+echo "$pass passed, $fail failed"
+[ "$fail" -eq 0 ]
+`)
+}
+
+// writeSmokeAnsible writes an Ansible task list that runs the steps with the uri module
+// against the vault_addr variable
+func writeSmokeAnsible(w io.Writer, mount string, steps []smokeStep) {
+	fmt.Fprintf(w, `# Smoke test for the plugin mounted at %s/, generated from its OpenAPI document.
+# Include these tasks with vault_addr and vault_token set (vault_mount defaults to %s).
+# Objects are created under the name %q and deleted again where the plugin supports it.
+`, mount, mount, smokeName)
+
+	for _, step := range steps {
+		fmt.Fprintf(w, "\n- name: %s %s\n", step.Method, step.Path)
+		fmt.Fprintf(w, "  ansible.builtin.uri:\n")
+		fmt.Fprintf(w, "    url: \"{{ vault_addr }}/v1/{{ vault_mount | default('%s') }}/%s\"\n", mount, step.Path)
+		fmt.Fprintf(w, "    method: %s\n", step.Method)
+		fmt.Fprintf(w, "    headers:\n      X-Vault-Token: \"{{ vault_token }}\"\n")
+		if step.Body != nil {
+			// JSON is valid YAML, so the body is written inline
+			body, _ := json.Marshal(step.Body)
+			fmt.Fprintf(w, "    body_format: json\n    body: %s\n", body)
+		}
+		fmt.Fprintf(w, "    status_code: [200, 204]\n")
+	}
+}
+
+// shellQuote quotes s for a POSIX shell
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// oasDocument converts the document fetched from the plugin to its typed form
+func oasDocument(doc interface{}) (*framework.OASDocument, error) {
+	if typed, ok := doc.(*framework.OASDocument); ok && typed != nil {
+		return typed, nil
+	}
+	if doc == nil {
+		return nil, fmt.Errorf("the plugin did not provide an OpenAPI document")
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	var typed framework.OASDocument
+	if err := json.Unmarshal(data, &typed); err != nil {
+		return nil, fmt.Errorf("failed to parse OpenAPI document: %w", err)
+	}
+	return &typed, nil
+}
+
+// runSmokeCommand prints a smoke test for the running plugin in the given format
+// ("sh" or "ansible") and returns the process exit code
+func runSmokeCommand(host *PluginHost, format string) int {
+	defer host.Stop()
+
+	doc, err := oasDocument(host.GetOpenAPIDoc())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Cannot generate smoke test: %v\n", err)
+		return 1
+	}
+
+	steps := smokeSteps(doc)
+	mount := strings.Trim(host.mountPath, "/")
+	switch format {
+	case "sh":
+		writeSmokeScript(os.Stdout, mount, steps)
+	case "ansible":
+		writeSmokeAnsible(os.Stdout, mount, steps)
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown smoke test format %q (expected sh or ansible)\n", format)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,116 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/framework"
+)
+
+// testOASDocument describes a plugin with a config path and templated roles
+func testOASDocument() *framework.OASDocument {
+	roleBody := &framework.OASRequestBody{Content: framework.OASContent{
+		"application/json": {Schema: &framework.OASSchema{Ref: "#/components/schemas/RoleRequest"}},
+	}}
+	return &framework.OASDocument{
+		Paths: map[string]*framework.OASPathItem{
+			"/config": {
+				Get:    &framework.OASOperation{},
+				Post:   &framework.OASOperation{},
+				Delete: &framework.OASOperation{},
+			},
+			"/roles/": {
+				Get: &framework.OASOperation{Parameters: []framework.OASParameter{{Name: "list", In: "query"}}},
+			},
+			"/roles/{name}": {
+				Parameters: []framework.OASParameter{{Name: "name", In: "path"}},
+				Get:        &framework.OASOperation{},
+				Post:       &framework.OASOperation{RequestBody: roleBody},
+				Delete:     &framework.OASOperation{},
+			},
+		},
+		Components: framework.OASComponents{Schemas: map[string]*framework.OASSchema{
+			"RoleRequest": {
+				Required: []string{"ttl", "enabled"},
+				Properties: map[string]*framework.OASSchema{
+					"ttl":      {Type: "string", Format: "duration"},
+					"enabled":  {Type: "boolean"},
+					"region":   {Type: "string", Example: "eu-west-1"},
+					"optional": {Type: "string"},
+					"old":      {Type: "string", Deprecated: true, Example: "x"},
+				},
+			},
+		}},
+	}
+}
+
+func TestSmokeSteps(t *testing.T) {
+	var got []string
+	for _, step := range smokeSteps(testOASDocument()) {
+		got = append(got, step.Method+" "+step.Path)
+	}
+	// Config is written and read but never deleted; roles are cleaned up
+	want := []string{
+		"POST config",
+		"GET config",
+		"LIST roles",
+		"POST roles/smoke-test",
+		"GET roles/smoke-test",
+		"DELETE roles/smoke-test",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("steps:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestWriteSmokeScript(t *testing.T) {
+	var out bytes.Buffer
+	writeSmokeScript(&out, "plugin", smokeSteps(testOASDocument()))
+	script := out.String()
+
+	for _, want := range []string{
+		"#!/bin/sh",
+		`MOUNT="${MOUNT:-plugin}"`,
+		`check POST 'roles/smoke-test' '{"enabled":true,"region":"eu-west-1","ttl":"1h"}'`,
+		"check LIST 'roles'",
+		"check DELETE 'roles/smoke-test'",
+		`[ "$fail" -eq 0 ]`,
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("script is missing %q:\n%s", want, script)
+		}
+	}
+}
+
+func TestWriteSmokeAnsible(t *testing.T) {
+	var out bytes.Buffer
+	writeSmokeAnsible(&out, "plugin", smokeSteps(testOASDocument()))
+	tasks := out.String()
+
+	for _, want := range []string{
+		"- name: POST roles/smoke-test",
+		`url: "{{ vault_addr }}/v1/{{ vault_mount | default('plugin') }}/roles/smoke-test"`,
+		`body: {"enabled":true,"region":"eu-west-1","ttl":"1h"}`,
+		"method: LIST",
+	} {
+		if !strings.Contains(tasks, want) {
+			t.Errorf("tasks are missing %q:\n%s", want, tasks)
+		}
+	}
+}
+
+func TestOASDocumentConversion(t *testing.T) {
+	if _, err := oasDocument(nil); err == nil {
+		t.Error("a missing document should be an error")
+	}
+	doc, err := oasDocument(map[string]interface{}{
+		"paths": map[string]interface{}{"/config": map[string]interface{}{"get": map[string]interface{}{}}},
+	})
+	if err != nil || doc.Paths["/config"] == nil || doc.Paths["/config"].Get == nil {
+		t.Errorf("oasDocument = %+v, %v", doc, err)
+	}
+}
@@ -5,23 +5,96 @@ package main
 
 import (
 	"context"
-	"strings"
+	"fmt"
+	"net/http"
+	"os"
+	"runtime"
 	"sync"
+	"sync/atomic"
 
+	"vault-plugin-host/handlers"
+
+	iradix "github.com/hashicorp/go-immutable-radix"
 	"github.com/hashicorp/vault/sdk/logical"
 )
 
+// maxLayerDepth is the number of frozen layers after which a snapshot flattens the chain
+const maxLayerDepth = 16
+
+// Storage is the storage backing a plugin host
+type Storage interface {
+	logical.Storage
+	Stats() StorageStats
+	HandleStats(w http.ResponseWriter, r *http.Request)
+}
+
+// storageLayer is an immutable set of writes shared between a storage and its snapshots
+type storageLayer struct {
+	parent  *storageLayer
+	entries map[string]*logical.StorageEntry // a nil entry marks a deletion
+	depth   int
+}
+
 // InMemoryStorage implements logical.Storage
+//
+// Snapshots are copy-on-write: taking one freezes the current writes into a shared
+// layer in O(1), and both sides record further writes in their own top layer.
+// Visible keys are also kept in an immutable radix tree, so List walks only the
+// requested prefix and snapshots share the index without copying it.
+//
+// Values are never copied: Put keeps the caller's entry and Get returns it as-is,
+// so entries must be treated as immutable once stored.
 type InMemoryStorage struct {
-	data map[string]*logical.StorageEntry
-	mu   sync.RWMutex
+	data  map[string]*logical.StorageEntry // writable layer; a nil entry shadows a key in base
+	base  *storageLayer                    // frozen layers shared with snapshots
+	index *iradix.Tree                     // sorted index of visible keys to their entry size
+	bytes int64                            // total size of visible entries
+	mu    sync.RWMutex
+
+	gets         atomic.Uint64
+	puts         atomic.Uint64
+	deletes      atomic.Uint64
+	bytesRead    atomic.Uint64
+	bytesWritten atomic.Uint64
+}
+
+// StorageStats describes the size of the stored data and the traffic it has seen
+type StorageStats struct {
+	Entries      int    `json:"entries"`
+	Bytes        int64  `json:"bytes"`
+	LargestKey   string `json:"largest_key,omitempty"`
+	LargestBytes int    `json:"largest_bytes"`
+	Gets         uint64 `json:"gets"`
+	Puts         uint64 `json:"puts"`
+	Deletes      uint64 `json:"deletes"`
+	BytesRead    uint64 `json:"bytes_read"`
+	BytesWritten uint64 `json:"bytes_written"`
+}
+
+// entrySize is the number of bytes accounted to a storage entry
+func entrySize(entry *logical.StorageEntry) int {
+	return len(entry.Key) + len(entry.Value)
 }
 
 // NewInMemoryStorage creates a new in-memory storage instance
 func NewInMemoryStorage() *InMemoryStorage {
 	return &InMemoryStorage{
-		data: make(map[string]*logical.StorageEntry),
+		data:  make(map[string]*logical.StorageEntry),
+		index: iradix.New(),
+	}
+}
+
+// lookup finds the visible entry for key; the caller must hold s.mu
+func (s *InMemoryStorage) lookup(key string) *logical.StorageEntry {
+	if entry, ok := s.data[key]; ok {
+		return entry
+	}
+	for layer := s.base; layer != nil; layer = layer.parent {
+		if entry, ok := layer.entries[key]; ok {
+			return entry
+		}
 	}
+	return nil
 }
 
 func (s *InMemoryStorage) List(ctx context.Context, prefix string) ([]string, error) {
@@ -29,21 +102,21 @@ func (s *InMemoryStorage) List(ctx context.Context, prefix string) ([]string, er
 	defer s.mu.RUnlock()
 
 	var keys []string
-	for k := range s.data {
-		if strings.HasPrefix(k, prefix) {
-			keys = append(keys, k)
-		}
-	}
+	s.index.Root().WalkPrefix([]byte(prefix), func(k []byte, _ interface{}) bool {
+		keys = append(keys, string(k))
+		return false
+	})
 	return keys, nil
 }
 
 func (s *InMemoryStorage) Get(ctx context.Context, key string) (*logical.StorageEntry, error) {
 	s.mu.RLock()
-	defer s.mu.RUnlock()
+	entry := s.lookup(key)
+	s.mu.RUnlock()
 
-	entry, ok := s.data[key]
-	if !ok {
-		return nil, nil
+	s.gets.Add(1)
+	if entry != nil {
+		s.bytesRead.Add(uint64(len(entry.Value)))
 	}
 	return entry, nil
 }
@@ -52,7 +125,16 @@ func (s *InMemoryStorage) Put(ctx context.Context, entry *logical.StorageEntry)
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	size := entrySize(entry)
+	if old := s.lookup(entry.Key); old != nil {
+		s.bytes -= int64(entrySize(old))
+	}
+	s.index, _, _ = s.index.Insert([]byte(entry.Key), size)
 	s.data[entry.Key] = entry
+	s.bytes += int64(size)
+
+	s.puts.Add(1)
+	s.bytesWritten.Add(uint64(len(entry.Value)))
 	return nil
 }
 
@@ -60,6 +142,191 @@ func (s *InMemoryStorage) Delete(ctx context.Context, key string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	delete(s.data, key)
+	s.deletes.Add(1)
+	if old := s.lookup(key); old != nil {
+		s.bytes -= int64(entrySize(old))
+	}
+	s.index, _, _ = s.index.Delete([]byte(key))
+	if s.base == nil {
+		delete(s.data, key)
+	} else {
+		s.data[key] = nil
+	}
 	return nil
 }
+
+// freeze moves the writable layer into the shared base; the caller must hold s.mu
+func (s *InMemoryStorage) freeze() *storageLayer {
+	if len(s.data) > 0 {
+		depth := 1
+		if s.base != nil {
+			depth = s.base.depth + 1
+		}
+		s.base = &storageLayer{parent: s.base, entries: s.data, depth: depth}
+		s.data = make(map[string]*logical.StorageEntry)
+	}
+
+	if s.base != nil && s.base.depth > maxLayerDepth {
+		s.base = flatten(s.base)
+	}
+	return s.base
+}
+
+// flatten collapses a layer chain into a single layer without tombstones
+func flatten(top *storageLayer) *storageLayer {
+	entries := make(map[string]*logical.StorageEntry)
+	for layer := top; layer != nil; layer = layer.parent {
+		for k, entry := range layer.entries {
+			if _, ok := entries[k]; !ok {
+				entries[k] = entry
+			}
+		}
+	}
+	for k, entry := range entries {
+		if entry == nil {
+			delete(entries, k)
+		}
+	}
+	return &storageLayer{entries: entries, depth: 1}
+}
+
+// Snapshot returns an independent copy of the storage without copying entries
+func (s *InMemoryStorage) Snapshot() *InMemoryStorage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return &InMemoryStorage{
+		data:  make(map[string]*logical.StorageEntry),
+		base:  s.freeze(),
+		index: s.index,
+		bytes: s.bytes,
+	}
+}
+
+// Restore replaces the contents of the storage with those of a snapshot without copying entries
+func (s *InMemoryStorage) Restore(snapshot *InMemoryStorage) {
+	snapshot.mu.Lock()
+	base := snapshot.freeze()
+	index := snapshot.index
+	bytes := snapshot.bytes
+	snapshot.mu.Unlock()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.base = base
+	s.index = index
+	s.bytes = bytes
+	s.data = make(map[string]*logical.StorageEntry)
+}
+
+// RestoreEntries swaps in the contents a storage snapshot restore leaves behind: the
+// entries, plus the current ones when merging. The new contents are built aside, so
+// readers see either the old storage or the restored one.
+func (s *InMemoryStorage) RestoreEntries(ctx context.Context, entries []*logical.StorageEntry, merge bool) error {
+	s.Restore(s.restoredContents(ctx, entries, merge))
+	return nil
+}
+
+// restoredContents builds the storage a snapshot restore of entries produces
+func (s *InMemoryStorage) restoredContents(ctx context.Context, entries []*logical.StorageEntry, merge bool) *InMemoryStorage {
+	next := NewInMemoryStorage()
+	if merge {
+		next = s.Snapshot()
+	}
+	for _, entry := range entries {
+		next.Put(ctx, entry)
+	}
+	return next
+}
+
+// Stats returns size accounting for the visible entries and operation counters
+func (s *InMemoryStorage) Stats() StorageStats {
+	s.mu.RLock()
+	index := s.index
+	stats := StorageStats{
+		Entries: index.Len(),
+		Bytes:   s.bytes,
+	}
+	s.mu.RUnlock()
+
+	// The index is immutable, so the walk does not need the lock
+	index.Root().Walk(func(k []byte, v interface{}) bool {
+		if size := v.(int); size > stats.LargestBytes {
+			stats.LargestKey = string(k)
+			stats.LargestBytes = size
+		}
+		return false
+	})
+
+	stats.Gets = s.gets.Load()
+	stats.Puts = s.puts.Load()
+	stats.Deletes = s.deletes.Load()
+	stats.BytesRead = s.bytesRead.Load()
+	stats.BytesWritten = s.bytesWritten.Load()
+	return stats
+}
+
+// HandleStats serves storage size accounting and host allocation metrics at /v1/sys/host/storage
+func (s *InMemoryStorage) HandleStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		handlers.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	handlers.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"storage": s.Stats(),
+		"allocations": map[string]interface{}{
+			"heap_alloc":  mem.HeapAlloc,
+			"heap_inuse":  mem.HeapInuse,
+			"total_alloc": mem.TotalAlloc,
+			"mallocs":     mem.Mallocs,
+			"frees":       mem.Frees,
+			"num_gc":      mem.NumGC,
+		},
+	})
+}
+
+// seedHostStorage loads a storage snapshot file into the host's storage, keeping
+// entries that are not in the snapshot, and returns the number of entries loaded. An
+// encrypted snapshot is opened with passphrase.
+func seedHostStorage(host *PluginHost, path, passphrase string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	body, err := handlers.OpenExport(f, passphrase)
+	if err != nil {
+		return 0, err
+	}
+	snapshot, err := handlers.ReadStorageSnapshot(body)
+	if err != nil {
+		return 0, err
+	}
+	if err := host.handler.Restore(context.Background(), snapshot, true); err != nil {
+		return 0, err
+	}
+	return len(snapshot.Entries), nil
+}
+
+// runDecryptCommand writes the decrypted content of an encrypted export to stdout and
+// returns the process exit code
+func runDecryptCommand(path, passphrase string) int {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Cannot decrypt: %v\n", err)
+		return 1
+	}
+	plaintext, err := handlers.DecryptExport(data, passphrase)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Cannot decrypt %s: %v\n", path, err)
+		return 1
+	}
+	os.Stdout.Write(plaintext)
+	return 0
+}
@@ -5,8 +5,14 @@ package main
 
 import (
 	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	"vault-plugin-host/handlers"
+
 	"github.com/hashicorp/vault/sdk/logical"
 )
 
@@ -195,3 +201,245 @@ func TestStorageConcurrency(t *testing.T) {
 		t.Fatal("Get returned nil after concurrent writes")
 	}
 }
+
+func TestStorageSnapshotIsolation(t *testing.T) {
+	storage := NewInMemoryStorage()
+	ctx := context.Background()
+
+	storage.Put(ctx, &logical.StorageEntry{Key: "shared", Value: []byte("v1")})
+	storage.Put(ctx, &logical.StorageEntry{Key: "doomed", Value: []byte("v1")})
+
+	snapshot := storage.Snapshot()
+
+	// Writes on either side must not leak into the other
+	storage.Put(ctx, &logical.StorageEntry{Key: "shared", Value: []byte("v2")})
+	storage.Delete(ctx, "doomed")
+	snapshot.Put(ctx, &logical.StorageEntry{Key: "sandbox-only", Value: []byte("x")})
+
+	if entry, _ := snapshot.Get(ctx, "shared"); entry == nil || string(entry.Value) != "v1" {
+		t.Errorf("snapshot shared = %v, want v1", entry)
+	}
+	if entry, _ := snapshot.Get(ctx, "doomed"); entry == nil {
+		t.Error("delete in original leaked into snapshot")
+	}
+	if entry, _ := storage.Get(ctx, "doomed"); entry != nil {
+		t.Error("deleted key still visible in original")
+	}
+	if entry, _ := storage.Get(ctx, "sandbox-only"); entry != nil {
+		t.Error("snapshot write leaked into original")
+	}
+
+	keys, _ := storage.List(ctx, "")
+	if len(keys) != 1 {
+		t.Errorf("original lists %v, want [shared]", keys)
+	}
+	keys, _ = snapshot.List(ctx, "")
+	if len(keys) != 3 {
+		t.Errorf("snapshot lists %v, want 3 keys", keys)
+	}
+}
+
+func TestStorageRestore(t *testing.T) {
+	storage := NewInMemoryStorage()
+	ctx := context.Background()
+
+	storage.Put(ctx, &logical.StorageEntry{Key: "seed", Value: []byte("seed")})
+	checkpoint := storage.Snapshot()
+
+	storage.Put(ctx, &logical.StorageEntry{Key: "scratch", Value: []byte("x")})
+	storage.Delete(ctx, "seed")
+
+	storage.Restore(checkpoint)
+
+	if entry, _ := storage.Get(ctx, "seed"); entry == nil {
+		t.Error("seed missing after restore")
+	}
+	if entry, _ := storage.Get(ctx, "scratch"); entry != nil {
+		t.Error("scratch still present after restore")
+	}
+}
+
+func TestStorageRestoreEntries(t *testing.T) {
+	storage := NewInMemoryStorage()
+	ctx := context.Background()
+
+	storage.Put(ctx, &logical.StorageEntry{Key: "stale", Value: []byte("x")})
+	entries := []*logical.StorageEntry{{Key: "config", Value: []byte("a")}}
+
+	storage.RestoreEntries(ctx, []*logical.StorageEntry{{Key: "extra", Value: []byte("x")}}, true)
+	storage.RestoreEntries(ctx, entries, true)
+	if keys, _ := storage.List(ctx, ""); strings.Join(keys, ",") != "config,extra,stale" {
+		t.Errorf("keys after merge = %v", keys)
+	}
+
+	storage.RestoreEntries(ctx, entries, false)
+	if keys, _ := storage.List(ctx, ""); strings.Join(keys, ",") != "config" {
+		t.Errorf("keys after restore = %v, want config", keys)
+	}
+	if stats := storage.Stats(); stats.Entries != 1 || stats.Bytes != int64(entrySize(entries[0])) {
+		t.Errorf("stats after restore = %+v", stats)
+	}
+}
+
+func TestStorageSnapshotFlattensDeepChains(t *testing.T) {
+	storage := NewInMemoryStorage()
+	ctx := context.Background()
+
+	for i := 0; i < maxLayerDepth*2; i++ {
+		storage.Put(ctx, &logical.StorageEntry{Key: "key", Value: []byte{byte(i)}})
+		storage.Snapshot()
+	}
+
+	if storage.base.depth > maxLayerDepth {
+		t.Errorf("layer depth = %d, want <= %d", storage.base.depth, maxLayerDepth)
+	}
+	if entry, _ := storage.Get(ctx, "key"); entry == nil || entry.Value[0] != byte(maxLayerDepth*2-1) {
+		t.Errorf("latest value lost after flattening: %v", entry)
+	}
+}
+
+func BenchmarkStorageSnapshot(b *testing.B) {
+	storage := NewInMemoryStorage()
+	ctx := context.Background()
+	for i := 0; i < 100000; i++ {
+		storage.Put(ctx, &logical.StorageEntry{Key: fmt.Sprintf("seed/%d", i), Value: []byte("value")})
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		storage.Snapshot()
+	}
+}
+
+func TestStorageListSortedAcrossSnapshots(t *testing.T) {
+	storage := NewInMemoryStorage()
+	ctx := context.Background()
+
+	for _, key := range []string{"creds/c", "creds/a", "config", "creds/b"} {
+		storage.Put(ctx, &logical.StorageEntry{Key: key, Value: []byte(key)})
+	}
+	// Overwriting a key must not duplicate it in the index
+	storage.Put(ctx, &logical.StorageEntry{Key: "creds/a", Value: []byte("updated")})
+
+	snapshot := storage.Snapshot()
+	storage.Delete(ctx, "creds/b")
+	snapshot.Put(ctx, &logical.StorageEntry{Key: "creds/d", Value: []byte("d")})
+
+	keys, _ := storage.List(ctx, "creds/")
+	if got := strings.Join(keys, ","); got != "creds/a,creds/c" {
+		t.Errorf("List after delete = %s, want creds/a,creds/c", got)
+	}
+
+	keys, _ = snapshot.List(ctx, "creds/")
+	if got := strings.Join(keys, ","); got != "creds/a,creds/b,creds/c,creds/d" {
+		t.Errorf("snapshot List = %s, want creds/a,creds/b,creds/c,creds/d", got)
+	}
+
+	storage.Restore(snapshot)
+	keys, _ = storage.List(ctx, "")
+	if got := strings.Join(keys, ","); got != "config,creds/a,creds/b,creds/c,creds/d" {
+		t.Errorf("List after restore = %s", got)
+	}
+}
+
+func BenchmarkStorageListPrefix(b *testing.B) {
+	storage := NewInMemoryStorage()
+	ctx := context.Background()
+	for i := 0; i < 100000; i++ {
+		storage.Put(ctx, &logical.StorageEntry{Key: fmt.Sprintf("seed/%d", i), Value: []byte("value")})
+	}
+	for i := 0; i < 100; i++ {
+		storage.Put(ctx, &logical.StorageEntry{Key: fmt.Sprintf("roles/%d", i), Value: []byte("value")})
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		storage.List(ctx, "roles/")
+	}
+}
+
+func TestStorageSizeAccounting(t *testing.T) {
+	storage := NewInMemoryStorage()
+	ctx := context.Background()
+
+	blob := &logical.StorageEntry{Key: "ca", Value: make([]byte, 4<<20)}
+	storage.Put(ctx, blob)
+	storage.Put(ctx, &logical.StorageEntry{Key: "role", Value: []byte("abc")})
+
+	// Values are stored and returned without copying
+	if entry, _ := storage.Get(ctx, "ca"); entry != blob {
+		t.Error("Get returned a copy of the stored entry")
+	}
+
+	stats := storage.Stats()
+	if stats.Entries != 2 || stats.Bytes != int64(2+4<<20+4+3) {
+		t.Errorf("stats = %+v", stats)
+	}
+	if stats.LargestKey != "ca" || stats.LargestBytes != 2+4<<20 {
+		t.Errorf("largest = %s (%d)", stats.LargestKey, stats.LargestBytes)
+	}
+	if stats.BytesRead != 4<<20 || stats.Puts != 2 || stats.Gets != 1 {
+		t.Errorf("counters = %+v", stats)
+	}
+
+	snapshot := storage.Snapshot()
+	storage.Put(ctx, &logical.StorageEntry{Key: "role", Value: []byte("abcdef")})
+	storage.Delete(ctx, "ca")
+
+	if stats := storage.Stats(); stats.Entries != 1 || stats.Bytes != 4+6 {
+		t.Errorf("stats after update = %+v", stats)
+	}
+	if stats := snapshot.Stats(); stats.Entries != 2 || stats.Bytes != int64(2+4<<20+4+3) {
+		t.Errorf("snapshot stats = %+v", stats)
+	}
+}
+
+func TestSeedHostStorage(t *testing.T) {
+	host, err := NewPluginHost("/fake/path", false, nil, "plugin")
+	if err != nil {
+		t.Fatalf("NewPluginHost failed: %v", err)
+	}
+	ctx := context.Background()
+	host.storage.Put(ctx, &logical.StorageEntry{Key: "existing", Value: []byte("kept")})
+
+	path := filepath.Join(t.TempDir(), "seed.json")
+	seed := `{"version": 1, "mount": "plugin", "entries": [{"key": "config", "value": "eyJ1cmwiOiJhIn0="}]}`
+	if err := os.WriteFile(path, []byte(seed), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := seedHostStorage(host, path, "")
+	if err != nil || n != 1 {
+		t.Fatalf("seedHostStorage = %d, %v", n, err)
+	}
+	if entry, _ := host.storage.Get(ctx, "config"); entry == nil || string(entry.Value) != `{"url":"a"}` {
+		t.Errorf("seeded entry missing, got %v", entry)
+	}
+	if entry, _ := host.storage.Get(ctx, "existing"); entry == nil {
+		t.Error("seeding should keep entries that are not in the snapshot")
+	}
+}
+
+func TestSeedHostStorageEncrypted(t *testing.T) {
+	host, err := NewPluginHost("/fake/path", false, nil, "plugin")
+	if err != nil {
+		t.Fatalf("NewPluginHost failed: %v", err)
+	}
+
+	seed := `{"version": 1, "mount": "plugin", "entries": [{"key": "config", "value": "eyJ1cmwiOiJhIn0="}]}`
+	encrypted, err := handlers.EncryptExport([]byte(seed), "passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "seed.json.enc")
+	if err := os.WriteFile(path, encrypted, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := seedHostStorage(host, path, ""); err == nil {
+		t.Fatal("seeding an encrypted snapshot without a passphrase should fail")
+	}
+	if n, err := seedHostStorage(host, path, "passphrase"); err != nil || n != 1 {
+		t.Fatalf("seedHostStorage = %d, %v", n, err)
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"net/http"
+	"strings"
+
+	"github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// mountStorageView is a mount's view of a storage shared by all mounts, below
+// logical/<uuid>/ as Vault's barrier views are. Keys of the plugin are relative to the
+// prefix, and keys with ".." are refused with logical.ErrRelativePath, as in Vault.
+type mountStorageView struct {
+	*logical.StorageView
+	base Storage
+}
+
+// newMountStorageView creates the view of base for the backend with the given UUID
+func newMountStorageView(base Storage, backendUUID string) *mountStorageView {
+	return &mountStorageView{
+		StorageView: logical.NewStorageView(base, "logical/"+backendUUID+"/"),
+		base:        base,
+	}
+}
+
+// List returns the keys under prefix relative to the view. The host's storage lists
+// whole keys, which logical.StorageView would hand back with the view's prefix.
+func (v *mountStorageView) List(ctx context.Context, prefix string) ([]string, error) {
+	keys, err := v.StorageView.List(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+	for i, key := range keys {
+		keys[i] = strings.TrimPrefix(key, v.Prefix())
+	}
+	return keys, nil
+}
+
+// Stats returns the accounting of the shared storage
+func (v *mountStorageView) Stats() StorageStats {
+	return v.base.Stats()
+}
+
+// HandleStats serves the accounting of the shared storage at /v1/sys/host/storage
+func (v *mountStorageView) HandleStats(w http.ResponseWriter, r *http.Request) {
+	v.base.HandleStats(w, r)
+}
+
+// mountBackendUUID derives a mount's backend UUID from its path, so the mount keeps
+// its storage prefix across restarts of the host as it would in Vault's mount table
+func mountBackendUUID(mountPath string) string {
+	sum := sha256.Sum256([]byte(mountPath))
+	id, _ := uuid.FormatUUID(sum[:16])
+	return id
+}
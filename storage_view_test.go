@@ -0,0 +1,62 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestMountStorageView(t *testing.T) {
+	ctx := context.Background()
+	base := NewInMemoryStorage()
+
+	primary, err := NewPluginHost("/fake/path", false, nil, "plugin")
+	if err != nil {
+		t.Fatalf("NewPluginHost failed: %v", err)
+	}
+	other, err := NewPluginHost("/fake/path", false, nil, "other")
+	if err != nil {
+		t.Fatalf("NewPluginHost failed: %v", err)
+	}
+	primary.SetStorageView(base)
+	other.SetStorageView(base)
+	if primary.backendUUID == defaultBackendUUID || primary.backendUUID == other.backendUUID {
+		t.Fatalf("backend UUIDs %s and %s should be of their own", primary.backendUUID, other.backendUUID)
+	}
+	if primary.backendUUID != mountBackendUUID("plugin") {
+		t.Errorf("backend UUID changes between runs: %s", primary.backendUUID)
+	}
+
+	primary.storage.Put(ctx, &logical.StorageEntry{Key: "config", Value: []byte("a")})
+	other.storage.Put(ctx, &logical.StorageEntry{Key: "config", Value: []byte("b")})
+
+	// The mounts share the storage below their own prefixes
+	if keys, _ := base.List(ctx, ""); len(keys) != 2 {
+		t.Errorf("shared storage keys = %v, want one per mount", keys)
+	}
+	entry, _ := base.Get(ctx, "logical/"+primary.backendUUID+"/config")
+	if entry == nil || string(entry.Value) != "a" {
+		t.Errorf("entry of the primary mount = %v", entry)
+	}
+	if keys, _ := primary.storage.List(ctx, ""); !reflect.DeepEqual(keys, []string{"config"}) {
+		t.Errorf("keys seen by the mount = %v, want [config]", keys)
+	}
+	if entry, _ := other.storage.Get(ctx, "config"); entry == nil || string(entry.Value) != "b" {
+		t.Errorf("entry seen by the other mount = %v", entry)
+	}
+
+	// As in Vault, a plugin cannot climb out of its view
+	err = primary.storage.Put(ctx, &logical.StorageEntry{Key: "../" + other.backendUUID + "/config", Value: []byte("c")})
+	if !errors.Is(err, logical.ErrRelativePath) {
+		t.Errorf("Put of a relative key = %v, want ErrRelativePath", err)
+	}
+	if stats := primary.storage.Stats(); stats.Entries != 2 {
+		t.Errorf("stats count %d entries, want the 2 of the shared storage", stats.Entries)
+	}
+}
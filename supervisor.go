@@ -0,0 +1,106 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+const (
+	// superviseInterval is how often the supervisor checks that the plugin is alive
+	superviseInterval = time.Second
+	// restartMinBackoff and restartMaxBackoff bound the wait between restart attempts
+	restartMinBackoff = time.Second
+	restartMaxBackoff = time.Minute
+)
+
+// Supervisor restarts a plugin whose process died, for example after a panic or
+// being killed for running out of memory
+type Supervisor struct {
+	host   *PluginHost
+	logger hclog.Logger
+
+	// ping and restart default to the host's; tests replace them
+	ping    func() error
+	restart func() error
+
+	backoff     time.Duration // wait before the next restart attempt
+	lastRestart time.Time
+}
+
+// NewSupervisor creates a supervisor for the given host
+func NewSupervisor(host *PluginHost) *Supervisor {
+	return &Supervisor{
+		host:    host,
+		logger:  host.logger.Named("supervisor"),
+		ping:    host.Ping,
+		restart: host.Restart,
+	}
+}
+
+// Run checks the plugin until stop is closed and restarts it when it has died. A
+// plugin that was stopped on purpose is left alone.
+func (s *Supervisor) Run(stop <-chan struct{}) {
+	ticker := time.NewTicker(superviseInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if !s.host.Running() {
+				continue
+			}
+			if err := s.ping(); err != nil {
+				s.recover(err, stop)
+			}
+		}
+	}
+}
+
+// recover restarts the plugin, waiting between attempts with exponential backoff. The
+// backoff carries over when the plugin crashes again soon after a restart, so a plugin
+// that keeps crashing is not restarted in a tight loop.
+func (s *Supervisor) recover(cause error, stop <-chan struct{}) {
+	s.logger.Error("plugin process died", "error", cause)
+	s.host.handler.RecordCrash(cause)
+
+	if time.Since(s.lastRestart) > restartMaxBackoff {
+		s.backoff = 0
+	}
+	for {
+		if s.backoff > 0 {
+			s.logger.Info("restarting plugin", "in", s.backoff)
+			select {
+			case <-stop:
+				return
+			case <-time.After(s.backoff):
+			}
+		}
+		s.backoff = nextBackoff(s.backoff)
+
+		err := s.restart()
+		if err == nil {
+			s.lastRestart = time.Now()
+			s.host.handler.RecordRestart()
+			s.logger.Info("plugin restarted after crash")
+			return
+		}
+		s.logger.Error("failed to restart plugin", "error", err)
+	}
+}
+
+// nextBackoff doubles a backoff between restartMinBackoff and restartMaxBackoff
+func nextBackoff(backoff time.Duration) time.Duration {
+	if backoff < restartMinBackoff {
+		return restartMinBackoff
+	}
+	if backoff*2 > restartMaxBackoff {
+		return restartMaxBackoff
+	}
+	return backoff * 2
+}
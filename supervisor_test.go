@@ -0,0 +1,94 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNextBackoff(t *testing.T) {
+	backoff := time.Duration(0)
+	var got []time.Duration
+	for i := 0; i < 8; i++ {
+		backoff = nextBackoff(backoff)
+		got = append(got, backoff)
+	}
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second,
+		16 * time.Second, 32 * time.Second, time.Minute, time.Minute}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("backoffs = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestSupervisorRestartsCrashedPlugin(t *testing.T) {
+	host, err := NewPluginHost("/fake/path", false, nil, "plugin")
+	if err != nil {
+		t.Fatalf("NewPluginHost failed: %v", err)
+	}
+
+	attempts := 0
+	s := NewSupervisor(host)
+	s.restart = func() error {
+		attempts++
+		if attempts == 1 {
+			return errors.New("plugin exited during startup")
+		}
+		return nil
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	start := time.Now()
+	s.recover(errors.New("connection refused"), stop)
+
+	// The first attempt is immediate, the second follows after the minimum backoff
+	if attempts != 2 {
+		t.Errorf("restart attempts = %d, want 2", attempts)
+	}
+	if elapsed := time.Since(start); elapsed < restartMinBackoff {
+		t.Errorf("second attempt after %s, want at least %s", elapsed, restartMinBackoff)
+	}
+
+	w := httptest.NewRecorder()
+	host.handler.HandleHealth(w, httptest.NewRequest("GET", "/v1/sys/health", nil))
+	var health struct {
+		Restarts struct {
+			Crashes   int    `json:"crashes"`
+			Restarts  int    `json:"restarts"`
+			LastError string `json:"last_error"`
+		} `json:"restarts"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &health)
+	if health.Restarts.Crashes != 1 || health.Restarts.Restarts != 1 || health.Restarts.LastError != "connection refused" {
+		t.Errorf("health = %s", w.Body.String())
+	}
+}
+
+func TestSupervisorGivesUpOnStop(t *testing.T) {
+	host, err := NewPluginHost("/fake/path", false, nil, "plugin")
+	if err != nil {
+		t.Fatalf("NewPluginHost failed: %v", err)
+	}
+	s := NewSupervisor(host)
+	s.restart = func() error { return errors.New("still broken") }
+
+	stop := make(chan struct{})
+	close(stop)
+	done := make(chan struct{})
+	go func() {
+		s.recover(errors.New("crashed"), stop)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("recover kept retrying after stop was closed")
+	}
+}
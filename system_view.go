@@ -6,8 +6,11 @@ package main
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
+	"vault-plugin-host/handlers"
+
 	"github.com/hashicorp/vault/sdk/helper/consts"
 	"github.com/hashicorp/vault/sdk/helper/license"
 	"github.com/hashicorp/vault/sdk/helper/pluginutil"
@@ -19,20 +22,198 @@ import (
 // TestSystemView implements logical.SystemView with stub implementations
 type TestSystemView struct {
 	logical.SystemView
+
+	wraps       *handlers.WrapStore        // backs ResponseWrapData when set
+	replication *handlers.Replication      // reported by ReplicationState when set
+	clock       *handlers.Clock            // skews the times reported to the plugin when set
+	passwords   *handlers.PasswordPolicies // backs GeneratePasswordFromPolicy when set
+	downloads   *pluginDownloader          // backs DownloadExtractVerifyPlugin when set
+	tuning      *SystemViewConfig          // mount tuning reported to the plugin; nil uses the defaults
+}
+
+// SystemViewConfig is the mount tuning and cluster information the system view reports
+// to the plugin
+type SystemViewConfig struct {
+	DefaultLeaseTTL time.Duration
+	MaxLeaseTTL     time.Duration
+	Mlock           bool
+	LocalMount      bool
+	CachingDisabled bool
+	ClusterID       string
+	VaultVersion    string
+
+	// Header passthrough and wrapping are mount tuning the handler applies rather than
+	// the system view
+	PassthroughRequestHeaders []string      // request headers passed to the plugin
+	AllowedResponseHeaders    []string      // plugin response headers sent on to the client
+	ResponseWrapTTL           time.Duration // wrap TTL of responses whose request asks for none
+	MaxResponseWrapTTL        time.Duration // largest wrap TTL a request may ask for
+}
+
+// DefaultSystemViewConfig returns the values reported when nothing is configured
+func DefaultSystemViewConfig() SystemViewConfig {
+	return SystemViewConfig{
+		DefaultLeaseTTL: 30 * time.Second,
+		MaxLeaseTTL:     60 * time.Minute,
+		ClusterID:       "test-cluster",
+		VaultVersion:    "test-version",
+	}
+}
+
+// Validate checks that the TTLs are consistent, as Vault does when a mount is tuned
+func (c SystemViewConfig) Validate() error {
+	if c.DefaultLeaseTTL < 0 || c.MaxLeaseTTL < 0 {
+		return fmt.Errorf("lease TTLs must not be negative")
+	}
+	if c.MaxLeaseTTL > 0 && c.DefaultLeaseTTL > c.MaxLeaseTTL {
+		return fmt.Errorf("default lease TTL %s exceeds max lease TTL %s", c.DefaultLeaseTTL, c.MaxLeaseTTL)
+	}
+	if c.ResponseWrapTTL < 0 || c.MaxResponseWrapTTL < 0 {
+		return fmt.Errorf("wrap TTLs must not be negative")
+	}
+	if c.MaxResponseWrapTTL > 0 && c.ResponseWrapTTL > c.MaxResponseWrapTTL {
+		return fmt.Errorf("response wrap TTL %s exceeds max response wrap TTL %s", c.ResponseWrapTTL, c.MaxResponseWrapTTL)
+	}
+	return nil
+}
+
+// withOverrides returns c with the fields set in a mount's "system_view" object
+// replaced. TTLs are durations ("1h") or numbers of seconds.
+func (c SystemViewConfig) withOverrides(raw map[string]interface{}) (SystemViewConfig, error) {
+	for key, value := range raw {
+		var err error
+		switch key {
+		case "default_lease_ttl":
+			c.DefaultLeaseTTL, err = parseSystemViewTTL(value)
+		case "max_lease_ttl":
+			c.MaxLeaseTTL, err = parseSystemViewTTL(value)
+		case "mlock":
+			c.Mlock, err = parseSystemViewBool(value)
+		case "local_mount":
+			c.LocalMount, err = parseSystemViewBool(value)
+		case "caching_disabled":
+			c.CachingDisabled, err = parseSystemViewBool(value)
+		case "cluster_id":
+			c.ClusterID, err = parseSystemViewString(value)
+		case "vault_version":
+			c.VaultVersion, err = parseSystemViewString(value)
+		case "passthrough_request_headers":
+			c.PassthroughRequestHeaders, err = parseSystemViewList(value)
+		case "allowed_response_headers":
+			c.AllowedResponseHeaders, err = parseSystemViewList(value)
+		case "response_wrap_ttl":
+			c.ResponseWrapTTL, err = parseSystemViewTTL(value)
+		case "max_response_wrap_ttl":
+			c.MaxResponseWrapTTL, err = parseSystemViewTTL(value)
+		default:
+			err = fmt.Errorf("unknown setting")
+		}
+		if err != nil {
+			return c, fmt.Errorf("system_view %s: %w", key, err)
+		}
+	}
+	return c, c.Validate()
+}
+
+func parseSystemViewTTL(value interface{}) (time.Duration, error) {
+	switch v := value.(type) {
+	case float64:
+		return time.Duration(v) * time.Second, nil
+	case string:
+		if d, err := time.ParseDuration(v); err == nil {
+			return d, nil
+		}
+		if d, err := time.ParseDuration(v + "s"); err == nil {
+			return d, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid TTL %v", value)
 }
 
-func (s *TestSystemView) DefaultLeaseTTL() time.Duration                     { return 30 * time.Second }
-func (s *TestSystemView) MaxLeaseTTL() time.Duration                         { return 60 * time.Minute }
+func parseSystemViewBool(value interface{}) (bool, error) {
+	if v, ok := value.(bool); ok {
+		return v, nil
+	}
+	return false, fmt.Errorf("expected a boolean, got %v", value)
+}
+
+func parseSystemViewString(value interface{}) (string, error) {
+	if v, ok := value.(string); ok {
+		return v, nil
+	}
+	return "", fmt.Errorf("expected a string, got %v", value)
+}
+
+// parseSystemViewList accepts a list of strings or a comma-separated string
+func parseSystemViewList(value interface{}) ([]string, error) {
+	switch v := value.(type) {
+	case string:
+		return splitList(v), nil
+	case []interface{}:
+		list := make([]string, 0, len(v))
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("expected a list of strings, got %v", value)
+			}
+			list = append(list, s)
+		}
+		return list, nil
+	}
+	return nil, fmt.Errorf("expected a list of strings, got %v", value)
+}
+
+// splitList splits a comma-separated list, dropping empty items
+func splitList(s string) []string {
+	var list []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// config returns the tuning reported to the plugin
+func (s *TestSystemView) config() SystemViewConfig {
+	if s.tuning == nil {
+		return DefaultSystemViewConfig()
+	}
+	return *s.tuning
+}
+
+func (s *TestSystemView) DefaultLeaseTTL() time.Duration                     { return s.config().DefaultLeaseTTL }
+func (s *TestSystemView) MaxLeaseTTL() time.Duration                         { return s.config().MaxLeaseTTL }
 func (s *TestSystemView) SudoPrivilege(context.Context, string, string) bool { return false }
 func (s *TestSystemView) Tainted() bool                                      { return false }
-func (s *TestSystemView) CachingDisabled() bool                              { return false }
-func (s *TestSystemView) LocalMount() bool                                   { return false }
-func (s *TestSystemView) MlockEnabled() bool                                 { return false }
-func (s *TestSystemView) ReplicationState() consts.ReplicationState          { return consts.ReplicationUnknown }
+func (s *TestSystemView) CachingDisabled() bool                              { return s.config().CachingDisabled }
+func (s *TestSystemView) LocalMount() bool                                   { return s.config().LocalMount }
+func (s *TestSystemView) MlockEnabled() bool                                 { return s.config().Mlock }
+func (s *TestSystemView) ReplicationState() consts.ReplicationState          { return s.replication.State() }
 func (s *TestSystemView) HasFeature(feature license.Features) bool           { return false }
 
 func (s *TestSystemView) ResponseWrapData(ctx context.Context, data map[string]interface{}, ttl time.Duration, jwt bool) (*wrapping.ResponseWrapInfo, error) {
-	return nil, fmt.Errorf("not implemented")
+	if s.wraps == nil {
+		return nil, fmt.Errorf("not implemented")
+	}
+	if jwt {
+		return nil, fmt.Errorf("JWT wrapping tokens are not supported")
+	}
+	info, err := s.wraps.Wrap(ctx, map[string]interface{}{
+		"lease_id":       "",
+		"renewable":      false,
+		"lease_duration": 0,
+		"data":           data,
+		"wrap_info":      nil,
+		"warnings":       nil,
+		"auth":           nil,
+	}, ttl, "sys/wrapping/wrap")
+	if err != nil {
+		return nil, err
+	}
+	reported := *info
+	reported.CreationTime = s.clock.Reported(info.CreationTime)
+	return &reported, nil
 }
 
 func (s *TestSystemView) LookupPlugin(ctx context.Context, name string, pluginType consts.PluginType) (*pluginutil.PluginRunner, error) {
@@ -60,11 +241,14 @@ func (s *TestSystemView) PluginEnv(ctx context.Context) (*logical.PluginEnvironm
 }
 
 func (s *TestSystemView) GeneratePasswordFromPolicy(ctx context.Context, policyName string) (string, error) {
-	return "", fmt.Errorf("not implemented")
+	if s.passwords == nil {
+		return "", fmt.Errorf("not implemented")
+	}
+	return s.passwords.Generate(ctx, policyName)
 }
 
 func (s *TestSystemView) ClusterID(ctx context.Context) (string, error) {
-	return "test-cluster", nil
+	return s.config().ClusterID, nil
 }
 
 func (s *TestSystemView) NewPluginClient(ctx context.Context, config pluginutil.PluginClientConfig) (pluginutil.PluginClient, error) {
@@ -72,7 +256,7 @@ func (s *TestSystemView) NewPluginClient(ctx context.Context, config pluginutil.
 }
 
 func (s *TestSystemView) VaultVersion(ctx context.Context) (string, error) {
-	return "test-version", nil
+	return s.config().VaultVersion, nil
 }
 
 func (s *TestSystemView) DeregisterRotationJob(ctx context.Context, req *rotation.RotationJobDeregisterRequest) error {
@@ -87,8 +271,13 @@ func (s *TestSystemView) RegisterRotationJob(ctx context.Context, req *rotation.
 	return "", fmt.Errorf("not implemented")
 }
 
+// DownloadExtractVerifyPlugin is only ever called in the host's process: the SDK refuses
+// the call from a plugin backend
 func (s *TestSystemView) DownloadExtractVerifyPlugin(ctx context.Context, runner *pluginutil.PluginRunner) error {
-	return fmt.Errorf("not implemented")
+	if s.downloads == nil {
+		return fmt.Errorf("not implemented")
+	}
+	return s.downloads.download(ctx, runner)
 }
 
 func (s *TestSystemView) GenerateIdentityToken(ctx context.Context, req *pluginutil.IdentityTokenRequest) (*pluginutil.IdentityTokenResponse, error) {
@@ -5,9 +5,13 @@ package main
 
 import (
 	"context"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
+	"vault-plugin-host/handlers"
+
 	"github.com/hashicorp/vault/sdk/helper/consts"
 )
 
@@ -87,6 +91,33 @@ func TestTestSystemViewNotImplementedMethods(t *testing.T) {
 		}
 	})
 
+	t.Run("ResponseWrapDataWithStore", func(t *testing.T) {
+		wraps := handlers.NewWrapStore(NewInMemoryStorage())
+		view := &TestSystemView{wraps: wraps}
+		info, err := view.ResponseWrapData(ctx, map[string]interface{}{"secret_id": "abc"}, time.Minute, false)
+		if err != nil {
+			t.Fatalf("ResponseWrapData() failed: %v", err)
+		}
+		response, err := wraps.Unwrap(ctx, info.Token)
+		if err != nil {
+			t.Fatalf("Unwrap() failed: %v", err)
+		}
+		if data, _ := response["data"].(map[string]interface{}); data["secret_id"] != "abc" {
+			t.Errorf("unwrapped response = %v", response)
+		}
+	})
+
+	t.Run("ResponseWrapDataWithSkew", func(t *testing.T) {
+		view := &TestSystemView{wraps: handlers.NewWrapStore(NewInMemoryStorage()), clock: handlers.NewClock(-time.Hour)}
+		info, err := view.ResponseWrapData(ctx, map[string]interface{}{"secret_id": "abc"}, time.Minute, false)
+		if err != nil {
+			t.Fatalf("ResponseWrapData() failed: %v", err)
+		}
+		if age := time.Since(info.CreationTime); age < 59*time.Minute || age > 61*time.Minute {
+			t.Errorf("creation time should be an hour early, got %v", info.CreationTime)
+		}
+	})
+
 	t.Run("LookupPlugin", func(t *testing.T) {
 		_, err := view.LookupPlugin(ctx, "test", consts.PluginTypeSecrets)
 		if err == nil {
@@ -139,6 +170,27 @@ func TestTestSystemViewNotImplementedMethods(t *testing.T) {
 		}
 	})
 
+	t.Run("GeneratePasswordFromPolicyWithStore", func(t *testing.T) {
+		policies := handlers.NewPasswordPolicies(NewInMemoryStorage())
+		if err := policies.Set(ctx, "pin", `length = 6
+rule "charset" {
+  charset = "0123456789"
+}`); err != nil {
+			t.Fatalf("Set() failed: %v", err)
+		}
+		view := &TestSystemView{passwords: policies}
+		password, err := view.GeneratePasswordFromPolicy(ctx, "pin")
+		if err != nil {
+			t.Fatalf("GeneratePasswordFromPolicy() failed: %v", err)
+		}
+		if len(password) != 6 || strings.Trim(password, "0123456789") != "" {
+			t.Errorf("GeneratePasswordFromPolicy() = %q, want 6 digits", password)
+		}
+		if _, err := view.GeneratePasswordFromPolicy(ctx, "missing"); err == nil {
+			t.Error("GeneratePasswordFromPolicy() should fail for an unknown policy")
+		}
+	})
+
 	t.Run("ClusterID", func(t *testing.T) {
 		id, err := view.ClusterID(ctx)
 		if err != nil {
@@ -199,3 +251,79 @@ func TestTestSystemViewNotImplementedMethods(t *testing.T) {
 		}
 	})
 }
+
+func TestSystemViewConfig(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Tuning", func(t *testing.T) {
+		view := &TestSystemView{tuning: &SystemViewConfig{
+			DefaultLeaseTTL: time.Hour,
+			MaxLeaseTTL:     24 * time.Hour,
+			Mlock:           true,
+			LocalMount:      true,
+			CachingDisabled: true,
+			ClusterID:       "cluster-a",
+			VaultVersion:    "1.18.0",
+		}}
+		if view.DefaultLeaseTTL() != time.Hour || view.MaxLeaseTTL() != 24*time.Hour {
+			t.Errorf("TTLs = %v/%v, want 1h/24h", view.DefaultLeaseTTL(), view.MaxLeaseTTL())
+		}
+		if !view.MlockEnabled() || !view.LocalMount() || !view.CachingDisabled() {
+			t.Error("mlock, local mount and caching disabled should be reported")
+		}
+		if id, _ := view.ClusterID(ctx); id != "cluster-a" {
+			t.Errorf("ClusterID() = %s, want cluster-a", id)
+		}
+		if version, _ := view.VaultVersion(ctx); version != "1.18.0" {
+			t.Errorf("VaultVersion() = %s, want 1.18.0", version)
+		}
+	})
+
+	t.Run("Overrides", func(t *testing.T) {
+		config, err := DefaultSystemViewConfig().withOverrides(map[string]interface{}{
+			"default_lease_ttl": "5m",
+			"max_lease_ttl":     float64(3600),
+			"local_mount":       true,
+			"vault_version":     "1.17.2",
+
+			"passthrough_request_headers": []interface{}{"X-Tenant", "X-Request-Source"},
+			"allowed_response_headers":    "X-Plugin-Trace, Retry-After",
+			"response_wrap_ttl":           "2m",
+			"max_response_wrap_ttl":       "10m",
+		})
+		if err != nil {
+			t.Fatalf("withOverrides() failed: %v", err)
+		}
+		if config.DefaultLeaseTTL != 5*time.Minute || config.MaxLeaseTTL != time.Hour {
+			t.Errorf("TTLs = %v/%v, want 5m/1h", config.DefaultLeaseTTL, config.MaxLeaseTTL)
+		}
+		if !config.LocalMount || config.Mlock {
+			t.Errorf("local mount/mlock = %v/%v, want true/false", config.LocalMount, config.Mlock)
+		}
+		if config.VaultVersion != "1.17.2" || config.ClusterID != "test-cluster" {
+			t.Errorf("version/cluster = %s/%s", config.VaultVersion, config.ClusterID)
+		}
+		if !reflect.DeepEqual(config.PassthroughRequestHeaders, []string{"X-Tenant", "X-Request-Source"}) ||
+			!reflect.DeepEqual(config.AllowedResponseHeaders, []string{"X-Plugin-Trace", "Retry-After"}) {
+			t.Errorf("headers = %v/%v", config.PassthroughRequestHeaders, config.AllowedResponseHeaders)
+		}
+		if config.ResponseWrapTTL != 2*time.Minute || config.MaxResponseWrapTTL != 10*time.Minute {
+			t.Errorf("wrap TTLs = %v/%v, want 2m/10m", config.ResponseWrapTTL, config.MaxResponseWrapTTL)
+		}
+	})
+
+	t.Run("InvalidOverrides", func(t *testing.T) {
+		for name, raw := range map[string]map[string]interface{}{
+			"unknown key":       {"tainted": true},
+			"bad TTL":           {"default_lease_ttl": "soon"},
+			"bad bool":          {"mlock": "yes"},
+			"bad header list":   {"passthrough_request_headers": []interface{}{1}},
+			"default above max": {"default_lease_ttl": "2h"},
+			"wrap above max":    {"response_wrap_ttl": "1h", "max_response_wrap_ttl": "5m"},
+		} {
+			if _, err := DefaultSystemViewConfig().withOverrides(raw); err == nil {
+				t.Errorf("%s: expected an error", name)
+			}
+		}
+	})
+}
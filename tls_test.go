@@ -0,0 +1,143 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testCert is a certificate and key signed by a test CA
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	der  []byte
+}
+
+// issueCert creates a certificate for usage; with a nil parent it is a self-signed CA
+// and usage is ignored
+func issueCert(t *testing.T, parent *testCert, name string, usage x509.ExtKeyUsage) *testCert {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+
+	signer, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage = x509.KeyUsageCertSign
+		template.ExtKeyUsage = nil
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCert{cert: cert, key: key, der: der}
+}
+
+// writePEM writes the certificate and key to dir and returns their paths
+func (c *testCert) writePEM(t *testing.T, dir, name string) (string, string) {
+	t.Helper()
+
+	certPath := filepath.Join(dir, name+".pem")
+	keyPath := filepath.Join(dir, name+"-key.pem")
+	keyDER, _ := x509.MarshalECPrivateKey(c.key)
+	os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.der}), 0o600)
+	os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600)
+	return certPath, keyPath
+}
+
+func (c *testCert) tlsCertificate() tls.Certificate {
+	return tls.Certificate{Certificate: [][]byte{c.der}, PrivateKey: c.key}
+}
+
+func TestLoadServerTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := issueCert(t, nil, "test-ca", x509.ExtKeyUsageAny)
+	server := issueCert(t, ca, "localhost", x509.ExtKeyUsageServerAuth)
+	client := issueCert(t, ca, "client", x509.ExtKeyUsageClientAuth)
+	caPath, _ := ca.writePEM(t, dir, "ca")
+	certPath, keyPath := server.writePEM(t, dir, "server")
+
+	config, err := loadServerTLS(certPath, keyPath, caPath)
+	if err != nil {
+		t.Fatalf("loadServerTLS failed: %v", err)
+	}
+
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	ts.TLS = config
+	ts.StartTLS()
+	defer ts.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+
+	// Without a client certificate the handshake is rejected
+	anonymous := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+	if resp, err := anonymous.Get(ts.URL); err == nil {
+		resp.Body.Close()
+		t.Fatal("request without a client certificate should fail")
+	}
+
+	mutual := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+		RootCAs:      roots,
+		Certificates: []tls.Certificate{client.tlsCertificate()},
+	}}}
+	resp, err := mutual.Get(ts.URL)
+	if err != nil {
+		t.Fatalf("mTLS request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want 200", resp.StatusCode)
+	}
+}
+
+func TestLoadServerTLSFlags(t *testing.T) {
+	if config, err := loadServerTLS("", "", ""); config != nil || err != nil {
+		t.Errorf("no flags: got %v, %v; want plain HTTP", config, err)
+	}
+
+	tests := [][3]string{
+		{"cert.pem", "", ""},
+		{"", "key.pem", ""},
+		{"", "", "ca.pem"},
+		{"missing.pem", "missing-key.pem", ""},
+	}
+	for _, tt := range tests {
+		if _, err := loadServerTLS(tt[0], tt[1], tt[2]); err == nil {
+			t.Errorf("loadServerTLS(%q, %q, %q) should fail", tt[0], tt[1], tt[2])
+		}
+	}
+}
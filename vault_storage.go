@@ -0,0 +1,310 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"runtime"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"vault-plugin-host/handlers"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// vaultStorageTimeout bounds each request to the Vault server behind -storage=vault
+const vaultStorageTimeout = 30 * time.Second
+
+// VaultStorage implements logical.Storage by reading and writing through a KV mount of
+// a real Vault server, so a development build of a plugin can run against state kept
+// there. Each storage entry is a secret below the storage's path holding the entry's
+// value, base64 encoded. Nothing is cached: every call is a request to Vault.
+type VaultStorage struct {
+	client    *http.Client
+	addr      string
+	token     string
+	mount     string // the KV mount, with a trailing slash
+	prefix    string // the path of the storage below the mount; empty or ending in a slash
+	kvVersion int
+
+	gets, puts, deletes     atomic.Uint64
+	bytesRead, bytesWritten atomic.Uint64
+}
+
+// vaultStorageSecret is the secret a storage entry is kept in
+type vaultStorageSecret struct {
+	Value    []byte `json:"value"`
+	SealWrap bool   `json:"seal_wrap,omitempty"`
+}
+
+// NewVaultStorage connects to the KV mount of the Vault server at addr that path lies
+// in, detecting whether it is KV version 1 or 2 as the vault CLI does
+func NewVaultStorage(addr, token, path string) (*VaultStorage, error) {
+	if addr == "" {
+		return nil, fmt.Errorf("no Vault address (set -storage-vault-addr or VAULT_ADDR)")
+	}
+	if token == "" {
+		return nil, fmt.Errorf("no Vault token (set -storage-vault-token or VAULT_TOKEN)")
+	}
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return nil, fmt.Errorf("no KV path (set -storage-vault-path)")
+	}
+	s := &VaultStorage{
+		client: &http.Client{Timeout: vaultStorageTimeout},
+		addr:   strings.TrimSuffix(addr, "/"),
+		token:  token,
+	}
+
+	var mount struct {
+		Data struct {
+			Path    string            `json:"path"`
+			Type    string            `json:"type"`
+			Options map[string]string `json:"options"`
+		} `json:"data"`
+	}
+	status, err := s.do(context.Background(), http.MethodGet, "sys/internal/ui/mounts/"+path, nil, &mount)
+	if err != nil {
+		return nil, err
+	}
+	if status == http.StatusNotFound || mount.Data.Path == "" {
+		return nil, fmt.Errorf("no KV mount at %s on %s", path, s.addr)
+	}
+	if mount.Data.Type != "kv" && mount.Data.Type != "generic" {
+		return nil, fmt.Errorf("%s on %s is a %s mount, not kv", mount.Data.Path, s.addr, mount.Data.Type)
+	}
+	s.mount = mount.Data.Path
+	s.prefix = strings.TrimPrefix(path+"/", s.mount)
+	s.kvVersion = 1
+	if mount.Data.Options["version"] == "2" {
+		s.kvVersion = 2
+	}
+	return s, nil
+}
+
+// Path returns the server and KV path of the storage
+func (s *VaultStorage) Path() string {
+	return fmt.Sprintf("%s/v1/%s%s (kv v%d)", s.addr, s.mount, strings.TrimSuffix(s.prefix, "/"), s.kvVersion)
+}
+
+// secretPath is the API path of the secret holding key; KV version 2 keeps secrets
+// below data/ and their listings below metadata/
+func (s *VaultStorage) secretPath(section, key string) string {
+	segments := strings.Split(s.prefix+key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	if s.kvVersion == 2 {
+		return s.mount + section + "/" + strings.Join(segments, "/")
+	}
+	return s.mount + strings.Join(segments, "/")
+}
+
+// do sends a request to Vault, decoding a successful response into out. A 404 is
+// returned as a status rather than an error; any other status of 400 or above is an
+// error carrying Vault's errors.
+func (s *VaultStorage) do(ctx context.Context, method, path string, body, out interface{}) (int, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return 0, err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, s.addr+"/v1/"+path, reader)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("X-Vault-Token", s.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("vault storage: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, fmt.Errorf("vault storage: %s %s: %w", method, path, err)
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		return resp.StatusCode, nil
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		var vaultErr struct {
+			Errors []string `json:"errors"`
+		}
+		if json.Unmarshal(data, &vaultErr) == nil && len(vaultErr.Errors) > 0 {
+			return resp.StatusCode, fmt.Errorf("vault storage: %s %s: %d %s", method, path, resp.StatusCode, strings.Join(vaultErr.Errors, "; "))
+		}
+		return resp.StatusCode, fmt.Errorf("vault storage: %s %s: %d", method, path, resp.StatusCode)
+	}
+	if out != nil && len(data) > 0 {
+		if err := json.Unmarshal(data, out); err != nil {
+			return resp.StatusCode, fmt.Errorf("vault storage: %s %s: %w", method, path, err)
+		}
+	}
+	return resp.StatusCode, nil
+}
+
+// List returns every key below prefix, as InMemoryStorage does, listing each
+// directory of the KV path in turn
+func (s *VaultStorage) List(ctx context.Context, prefix string) ([]string, error) {
+	dir := prefix[:strings.LastIndex(prefix, "/")+1]
+	var keys []string
+	if err := s.listDir(ctx, dir, prefix, &keys); err != nil {
+		return nil, err
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// listDir adds the keys below dir that start with prefix to keys
+func (s *VaultStorage) listDir(ctx context.Context, dir, prefix string, keys *[]string) error {
+	var list struct {
+		Data struct {
+			Keys []string `json:"keys"`
+		} `json:"data"`
+	}
+	if _, err := s.do(ctx, "LIST", s.secretPath("metadata", dir), nil, &list); err != nil {
+		return err
+	}
+	for _, name := range list.Data.Keys {
+		key := dir + name
+		if !strings.HasPrefix(key, prefix) && !strings.HasPrefix(prefix, key) {
+			continue
+		}
+		if strings.HasSuffix(name, "/") {
+			if err := s.listDir(ctx, key, prefix, keys); err != nil {
+				return err
+			}
+		} else if strings.HasPrefix(key, prefix) {
+			*keys = append(*keys, key)
+		}
+	}
+	return nil
+}
+
+func (s *VaultStorage) Get(ctx context.Context, key string) (*logical.StorageEntry, error) {
+	entry, err := s.get(ctx, key)
+	s.gets.Add(1)
+	if entry != nil {
+		s.bytesRead.Add(uint64(len(entry.Value)))
+	}
+	return entry, err
+}
+
+// get reads an entry without counting it
+func (s *VaultStorage) get(ctx context.Context, key string) (*logical.StorageEntry, error) {
+	var resp struct {
+		Data json.RawMessage `json:"data"`
+	}
+	status, err := s.do(ctx, http.MethodGet, s.secretPath("data", key), nil, &resp)
+	if err != nil || status == http.StatusNotFound {
+		return nil, err
+	}
+	data := resp.Data
+	if s.kvVersion == 2 {
+		var version struct {
+			Data json.RawMessage `json:"data"`
+		}
+		if err := json.Unmarshal(data, &version); err != nil {
+			return nil, fmt.Errorf("vault storage: %s: %w", key, err)
+		}
+		data = version.Data
+	}
+	var secret vaultStorageSecret
+	if err := json.Unmarshal(data, &secret); err != nil {
+		return nil, fmt.Errorf("vault storage: %s is not a storage entry: %w", key, err)
+	}
+	return &logical.StorageEntry{Key: key, Value: secret.Value, SealWrap: secret.SealWrap}, nil
+}
+
+func (s *VaultStorage) Put(ctx context.Context, entry *logical.StorageEntry) error {
+	var body interface{} = vaultStorageSecret{Value: entry.Value, SealWrap: entry.SealWrap}
+	if s.kvVersion == 2 {
+		body = map[string]interface{}{"data": body}
+	}
+	if _, err := s.do(ctx, http.MethodPut, s.secretPath("data", entry.Key), body, nil); err != nil {
+		return err
+	}
+	s.puts.Add(1)
+	s.bytesWritten.Add(uint64(len(entry.Value)))
+	return nil
+}
+
+// Delete removes the secret holding key; with KV version 2 every version of it goes,
+// so it no longer shows up in listings
+func (s *VaultStorage) Delete(ctx context.Context, key string) error {
+	s.deletes.Add(1)
+	_, err := s.do(ctx, http.MethodDelete, s.secretPath("metadata", key), nil, nil)
+	return err
+}
+
+// Stats returns size accounting for the entries, read from Vault without counting the
+// reads, and operation counters
+func (s *VaultStorage) Stats() StorageStats {
+	var stats StorageStats
+	ctx := context.Background()
+	keys, err := s.List(ctx, "")
+	if err == nil {
+		for _, key := range keys {
+			entry, err := s.get(ctx, key)
+			if err != nil || entry == nil {
+				continue
+			}
+			size := entrySize(entry)
+			stats.Entries++
+			stats.Bytes += int64(size)
+			if size > stats.LargestBytes {
+				stats.LargestKey = key
+				stats.LargestBytes = size
+			}
+		}
+	}
+
+	stats.Gets = s.gets.Load()
+	stats.Puts = s.puts.Load()
+	stats.Deletes = s.deletes.Load()
+	stats.BytesRead = s.bytesRead.Load()
+	stats.BytesWritten = s.bytesWritten.Load()
+	return stats
+}
+
+// HandleStats serves storage size accounting and host allocation metrics at /v1/sys/host/storage
+func (s *VaultStorage) HandleStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		handlers.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	handlers.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"storage": s.Stats(),
+		"vault":   s.Path(),
+		"allocations": map[string]interface{}{
+			"heap_alloc":  mem.HeapAlloc,
+			"heap_inuse":  mem.HeapInuse,
+			"total_alloc": mem.TotalAlloc,
+			"mallocs":     mem.Mallocs,
+			"frees":       mem.Frees,
+			"num_gc":      mem.NumGC,
+		},
+	})
+}
@@ -0,0 +1,166 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// fakeKV serves a KV mount at secret/ of the given version, as a Vault server does
+func fakeKV(t *testing.T, version string) *httptest.Server {
+	var mu sync.Mutex
+	secrets := make(map[string]json.RawMessage)
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "root" {
+			w.WriteHeader(http.StatusForbidden)
+			io.WriteString(w, `{"errors":["permission denied"]}`)
+			return
+		}
+		path := strings.TrimPrefix(r.URL.Path, "/v1/")
+		if strings.HasPrefix(path, "sys/internal/ui/mounts/secret") {
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": map[string]interface{}{"path": "secret/", "type": "kv", "options": map[string]string{"version": version}},
+			})
+			return
+		}
+		path, ok := strings.CutPrefix(path, "secret/")
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if version == "2" {
+			section, rest, _ := strings.Cut(path, "/")
+			if (section == "data") != (r.Method == http.MethodGet || r.Method == http.MethodPut) {
+				t.Errorf("%s %s uses the wrong KV v2 section", r.Method, r.URL.Path)
+			}
+			path = rest
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.Method {
+		case http.MethodPut:
+			body, _ := io.ReadAll(r.Body)
+			if version == "2" {
+				var wrapped struct {
+					Data json.RawMessage `json:"data"`
+				}
+				json.Unmarshal(body, &wrapped)
+				body = wrapped.Data
+			}
+			secrets[path] = body
+		case http.MethodGet:
+			secret, ok := secrets[path]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			if version == "2" {
+				secret, _ = json.Marshal(map[string]json.RawMessage{"data": secret})
+			}
+			json.NewEncoder(w).Encode(map[string]json.RawMessage{"data": secret})
+		case http.MethodDelete:
+			delete(secrets, path)
+		case "LIST":
+			seen := make(map[string]bool)
+			var keys []string
+			for key := range secrets {
+				if rest, ok := strings.CutPrefix(key, path); ok {
+					if i := strings.Index(rest, "/"); i >= 0 {
+						rest = rest[:i+1]
+					}
+					if !seen[rest] {
+						seen[rest] = true
+						keys = append(keys, rest)
+					}
+				}
+			}
+			if len(keys) == 0 {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			sort.Strings(keys)
+			json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{"keys": keys}})
+		}
+	}))
+}
+
+func TestVaultStorage(t *testing.T) {
+	for _, version := range []string{"1", "2"} {
+		t.Run("kv-v"+version, func(t *testing.T) {
+			server := fakeKV(t, version)
+			defer server.Close()
+			ctx := context.Background()
+
+			storage, err := NewVaultStorage(server.URL, "root", "/secret/dev/plugin/")
+			if err != nil {
+				t.Fatalf("NewVaultStorage failed: %v", err)
+			}
+			if storage.kvVersion != int(version[0]-'0') || storage.prefix != "dev/plugin/" {
+				t.Errorf("storage = %+v", storage)
+			}
+
+			for _, key := range []string{"config", "roles/a", "roles/b", "creds/a/1"} {
+				if err := storage.Put(ctx, &logical.StorageEntry{Key: key, Value: []byte("v-" + key), SealWrap: key == "config"}); err != nil {
+					t.Fatalf("Put %s failed: %v", key, err)
+				}
+			}
+			if err := storage.Delete(ctx, "roles/b"); err != nil {
+				t.Fatalf("Delete failed: %v", err)
+			}
+
+			entry, err := storage.Get(ctx, "config")
+			if err != nil || entry == nil || string(entry.Value) != "v-config" || !entry.SealWrap {
+				t.Errorf("Get config = %+v, %v", entry, err)
+			}
+			if entry, err := storage.Get(ctx, "roles/b"); entry != nil || err != nil {
+				t.Errorf("Get of a deleted key = %+v, %v", entry, err)
+			}
+
+			keys, err := storage.List(ctx, "")
+			if got := strings.Join(keys, ","); err != nil || got != "config,creds/a/1,roles/a" {
+				t.Errorf("List = %q, %v", got, err)
+			}
+			keys, _ = storage.List(ctx, "cr")
+			if got := strings.Join(keys, ","); got != "creds/a/1" {
+				t.Errorf("List cr = %q", got)
+			}
+			keys, err = storage.List(ctx, "missing/")
+			if len(keys) != 0 || err != nil {
+				t.Errorf("List missing/ = %v, %v", keys, err)
+			}
+
+			stats := storage.Stats()
+			if stats.Entries != 3 || stats.Puts != 4 || stats.Deletes != 1 || stats.Gets != 2 {
+				t.Errorf("stats = %+v", stats)
+			}
+		})
+	}
+}
+
+func TestVaultStorageErrors(t *testing.T) {
+	server := fakeKV(t, "2")
+	defer server.Close()
+
+	if _, err := NewVaultStorage(server.URL, "wrong", "secret/plugin"); err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Errorf("NewVaultStorage with a bad token = %v, want permission denied", err)
+	}
+	if _, err := NewVaultStorage(server.URL, "root", "kv/plugin"); err == nil || !strings.Contains(err.Error(), "no KV mount") {
+		t.Errorf("NewVaultStorage of an unknown mount = %v", err)
+	}
+	if _, err := NewVaultStorage("", "root", "secret/plugin"); err == nil {
+		t.Error("NewVaultStorage without an address should fail")
+	}
+}
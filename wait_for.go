@@ -0,0 +1,144 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// dependencyPollInterval is how often an unreachable dependency is checked again
+const dependencyPollInterval = 500 * time.Millisecond
+
+// dependency is an external service the plugin needs before it can start, such as the
+// database it manages
+type dependency struct {
+	target string // as given to -wait-for
+	scheme string // tcp, http or https
+	addr   string // host:port of a tcp dependency, the URL of an http one
+}
+
+// parseDependencies parses the comma-separated dependencies of -wait-for. A dependency
+// is tcp://host:port, reachable once it accepts connections, or an http:// or
+// https:// URL, reachable once it answers with a status below 400.
+func parseDependencies(list string) ([]dependency, error) {
+	var deps []dependency
+	for _, target := range strings.Split(list, ",") {
+		target = strings.TrimSpace(target)
+		if target == "" {
+			continue
+		}
+		u, err := url.Parse(target)
+		if err != nil {
+			return nil, fmt.Errorf("invalid dependency %q: %w", target, err)
+		}
+		switch u.Scheme {
+		case "tcp":
+			if _, _, err := net.SplitHostPort(u.Host); err != nil {
+				return nil, fmt.Errorf("invalid dependency %q: expected tcp://host:port", target)
+			}
+			deps = append(deps, dependency{target: target, scheme: "tcp", addr: u.Host})
+		case "http", "https":
+			if u.Host == "" {
+				return nil, fmt.Errorf("invalid dependency %q: no host", target)
+			}
+			deps = append(deps, dependency{target: target, scheme: u.Scheme, addr: target})
+		default:
+			return nil, fmt.Errorf("invalid dependency %q: expected tcp://, http:// or https://", target)
+		}
+	}
+	return deps, nil
+}
+
+// check returns nil when the dependency is reachable
+func (d dependency) check(ctx context.Context, client *http.Client) error {
+	if d.scheme == "tcp" {
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, "tcp", d.addr)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, d.addr, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// waitForDependencies checks the dependencies until all are reachable, writing a line
+// to out as each becomes reachable. It fails after timeout with the last error of each
+// dependency that is still unreachable.
+func waitForDependencies(deps []dependency, timeout, interval time.Duration, out io.Writer) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	client := &http.Client{Timeout: 5 * time.Second}
+	start := time.Now()
+
+	var mu sync.Mutex
+	failures := make(map[string]error)
+	var wg sync.WaitGroup
+	for _, dep := range deps {
+		wg.Add(1)
+		go func(dep dependency) {
+			defer wg.Done()
+			var last error
+			for {
+				err := dep.check(ctx, client)
+				if err == nil {
+					mu.Lock()
+					fmt.Fprintf(out, "Dependency reachable: %s (after %s)\n", dep.target, time.Since(start).Round(time.Millisecond))
+					mu.Unlock()
+					return
+				}
+				// A check cut short by the timeout says less than the one before it
+				if last == nil || ctx.Err() == nil {
+					last = err
+				}
+				select {
+				case <-ctx.Done():
+					mu.Lock()
+					failures[dep.target] = last
+					mu.Unlock()
+					return
+				case <-time.After(interval):
+				}
+			}
+		}(dep)
+	}
+	wg.Wait()
+
+	if len(failures) == 0 {
+		return nil
+	}
+	targets := make([]string, 0, len(failures))
+	for target := range failures {
+		targets = append(targets, target)
+	}
+	sort.Strings(targets)
+	reasons := make([]string, len(targets))
+	for i, target := range targets {
+		reasons[i] = fmt.Sprintf("%s (%v)", target, failures[target])
+	}
+	return fmt.Errorf("dependencies unreachable after %s: %s", timeout, strings.Join(reasons, ", "))
+}
@@ -0,0 +1,66 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParseDependencies(t *testing.T) {
+	deps, err := parseDependencies("tcp://db:5432, http://idp:8080/health,https://vault.example.com,")
+	if err != nil {
+		t.Fatalf("parseDependencies failed: %v", err)
+	}
+	if len(deps) != 3 || deps[0].addr != "db:5432" || deps[1].addr != "http://idp:8080/health" || deps[2].scheme != "https" {
+		t.Errorf("dependencies = %+v", deps)
+	}
+	for _, list := range []string{"db:5432", "tcp://db", "udp://db:53", "http:///health"} {
+		if _, err := parseDependencies(list); err == nil {
+			t.Errorf("parseDependencies(%q) should fail", list)
+		}
+	}
+}
+
+func TestWaitForDependencies(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+
+	// The health endpoint comes up after a few checks
+	var checks atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if checks.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	deps, _ := parseDependencies("tcp://" + listener.Addr().String() + "," + server.URL + "/health")
+	var out bytes.Buffer
+	if err := waitForDependencies(deps, 5*time.Second, 10*time.Millisecond, &out); err != nil {
+		t.Fatalf("waitForDependencies failed: %v", err)
+	}
+	if checks.Load() != 3 || strings.Count(out.String(), "Dependency reachable") != 2 {
+		t.Errorf("checks = %d, output:\n%s", checks.Load(), out.String())
+	}
+
+	// A dependency that never comes up fails the wait with its last error
+	closed, _ := net.Listen("tcp", "127.0.0.1:0")
+	addr := closed.Addr().String()
+	closed.Close()
+	deps, _ = parseDependencies("tcp://" + addr + "," + server.URL)
+	err = waitForDependencies(deps, 200*time.Millisecond, 10*time.Millisecond, &out)
+	if err == nil || !strings.Contains(err.Error(), "tcp://"+addr+" (") || !strings.Contains(err.Error(), "refused") || strings.Contains(err.Error(), server.URL) {
+		t.Errorf("waitForDependencies = %v, want the refused dependency only", err)
+	}
+}
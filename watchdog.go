@@ -0,0 +1,185 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"net/http"
+	"sync"
+	"syscall"
+	"time"
+
+	"vault-plugin-host/handlers"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+const (
+	// dumpSettleTime is how long stderr must stay quiet before a goroutine dump is considered complete
+	dumpSettleTime = 500 * time.Millisecond
+	// dumpMaxWait bounds how long to wait for the plugin to write its goroutine dump
+	dumpMaxWait = 5 * time.Second
+	// maxHangDumps is the number of dumps retained for the admin API
+	maxHangDumps = 20
+)
+
+// HangDump records a backend call that exceeded the hang threshold
+type HangDump struct {
+	Call       handlers.InflightCall `json:"call"`
+	DetectedAt time.Time             `json:"detected_at"`
+	Elapsed    string                `json:"elapsed"`
+	Dump       string                `json:"dump,omitempty"`
+	Error      string                `json:"error,omitempty"`
+	Restarted  bool                  `json:"restarted"`
+}
+
+// Watchdog detects hung backend calls, captures a goroutine dump from the plugin
+// via SIGQUIT and optionally restarts it
+type Watchdog struct {
+	host      *PluginHost
+	threshold time.Duration
+	restart   bool
+	logger    hclog.Logger
+
+	mu      sync.Mutex
+	dumps   []*HangDump
+	handled map[uint64]bool
+}
+
+// NewWatchdog creates a watchdog for the given host
+func NewWatchdog(host *PluginHost, threshold time.Duration, restart bool) *Watchdog {
+	return &Watchdog{
+		host:      host,
+		threshold: threshold,
+		restart:   restart,
+		logger:    host.logger.Named("watchdog"),
+		handled:   make(map[uint64]bool),
+	}
+}
+
+// Run polls in-flight backend calls until stop is closed
+func (wd *Watchdog) Run(stop <-chan struct{}) {
+	interval := wd.threshold / 4
+	if interval < 100*time.Millisecond {
+		interval = 100 * time.Millisecond
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			wd.check()
+		}
+	}
+}
+
+// check looks for calls running longer than the threshold and handles the oldest one
+func (wd *Watchdog) check() {
+	calls := wd.host.handler.InflightCalls()
+
+	var hung *handlers.InflightCall
+	for i := range calls {
+		if !wd.handled[calls[i].ID] && time.Since(calls[i].Started) > wd.threshold {
+			hung = &calls[i]
+			break
+		}
+	}
+	if hung == nil {
+		return
+	}
+
+	// SIGQUIT takes down every call in flight, so don't report them again
+	for _, call := range calls {
+		wd.handled[call.ID] = true
+	}
+
+	dump := wd.capture(*hung)
+
+	wd.mu.Lock()
+	wd.dumps = append(wd.dumps, dump)
+	if len(wd.dumps) > maxHangDumps {
+		wd.dumps = wd.dumps[len(wd.dumps)-maxHangDumps:]
+	}
+	wd.mu.Unlock()
+}
+
+// capture signals the plugin and collects the goroutine dump it writes to stderr
+func (wd *Watchdog) capture(call handlers.InflightCall) *HangDump {
+	elapsed := time.Since(call.Started)
+	dump := &HangDump{
+		Call:       call,
+		DetectedAt: time.Now(),
+		Elapsed:    elapsed.String(),
+	}
+
+	wd.logger.Warn("backend call exceeded hang threshold",
+		"path", call.Path, "operation", call.Operation, "elapsed", elapsed, "threshold", wd.threshold)
+
+	offset := wd.host.stderr.Written()
+	if err := wd.host.signalPlugin(syscall.SIGQUIT); err != nil {
+		dump.Error = err.Error()
+		wd.logger.Error("failed to signal plugin", "error", err)
+		return dump
+	}
+
+	deadline := time.Now().Add(dumpMaxWait)
+	last := offset
+	for time.Now().Before(deadline) {
+		time.Sleep(dumpSettleTime)
+		written := wd.host.stderr.Written()
+		if written > offset && written == last {
+			break
+		}
+		last = written
+	}
+	dump.Dump = wd.host.stderr.Since(offset)
+
+	// A Go plugin exits after SIGQUIT, so either bring it back or mark it stopped
+	if wd.restart {
+		if err := wd.host.Restart(); err != nil {
+			dump.Error = "restart failed: " + err.Error()
+			wd.logger.Error("failed to restart plugin", "error", err)
+		} else {
+			dump.Restarted = true
+			wd.logger.Info("plugin restarted after hang")
+		}
+	} else {
+		wd.host.Stop()
+	}
+
+	return dump
+}
+
+// Dumps returns the captured hang dumps, oldest first
+func (wd *Watchdog) Dumps() []*HangDump {
+	wd.mu.Lock()
+	defer wd.mu.Unlock()
+
+	result := make([]*HangDump, len(wd.dumps))
+	copy(result, wd.dumps)
+	return result
+}
+
+// HandleHangs lists (GET) or clears (DELETE) captured dumps at /v1/sys/host/hangs
+func (wd *Watchdog) HandleHangs(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		handlers.WriteJSON(w, http.StatusOK, map[string]interface{}{
+			"threshold": wd.threshold.String(),
+			"restart":   wd.restart,
+			"inflight":  wd.host.handler.InflightCalls(),
+			"hangs":     wd.Dumps(),
+		})
+	case http.MethodDelete:
+		wd.mu.Lock()
+		wd.dumps = nil
+		wd.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		handlers.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
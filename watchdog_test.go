@@ -0,0 +1,70 @@
+// Copyright 2025 vault-plugin-host Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// blockingBackend blocks every request until release is closed
+type blockingBackend struct {
+	release chan struct{}
+}
+
+func (b *blockingBackend) HandleRequest(ctx context.Context, req *logical.Request) (*logical.Response, error) {
+	<-b.release
+	return nil, nil
+}
+
+func TestWatchdogDetectsHang(t *testing.T) {
+	host, err := NewPluginHost("/fake/path", false, nil, "plugin")
+	if err != nil {
+		t.Fatalf("NewPluginHost failed: %v", err)
+	}
+
+	backend := &blockingBackend{release: make(chan struct{})}
+	host.handler.SetBackend(backend)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		req := httptest.NewRequest("GET", "/v1/plugin/slow", nil)
+		host.handler.HandleRequest(httptest.NewRecorder(), req)
+	}()
+
+	deadline := time.Now().Add(time.Second)
+	for len(host.handler.InflightCalls()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	wd := NewWatchdog(host, 10*time.Millisecond, false)
+	time.Sleep(20 * time.Millisecond)
+	wd.check()
+
+	dumps := wd.Dumps()
+	if len(dumps) != 1 {
+		t.Fatalf("got %d dumps, want 1", len(dumps))
+	}
+	if dumps[0].Call.Path != "slow" {
+		t.Errorf("dump path = %s, want slow", dumps[0].Call.Path)
+	}
+	// No plugin process was launched, so signalling must fail gracefully
+	if dumps[0].Error == "" {
+		t.Error("expected an error when there is no plugin process to signal")
+	}
+
+	// The same call must not be reported twice
+	wd.check()
+	if len(wd.Dumps()) != 1 {
+		t.Errorf("hang reported more than once")
+	}
+
+	close(backend.release)
+	<-done
+}
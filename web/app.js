@@ -4,6 +4,7 @@ const API_BASE = '/v1';
 // Global state
 let storageData = [];
 let openAPIData = null;
+let generatedSnippets = [];
 
 // Initialize on page load
 document.addEventListener('DOMContentLoaded', function() {
@@ -19,6 +20,26 @@ document.addEventListener('DOMContentLoaded', function() {
             initSwaggerUI();
         }
     });
+
+    document.getElementById('leases-tab').addEventListener('shown.bs.tab', function() {
+        loadLeaseAnalytics();
+    });
+
+    document.getElementById('egress-tab').addEventListener('shown.bs.tab', function() {
+        loadEgress();
+    });
+
+    document.getElementById('logs-tab').addEventListener('shown.bs.tab', function() {
+        followPluginLogs();
+    });
+
+    document.getElementById('logs-tab').addEventListener('hidden.bs.tab', function() {
+        stopPluginLogs();
+    });
+
+    document.getElementById('debug-tab').addEventListener('shown.bs.tab', function() {
+        loadRPCConsole();
+    });
 });
 
 // Refresh all data
@@ -30,6 +51,14 @@ function refreshAll() {
         loadStorage();
     } else if (activeTab === 'openapi-tab') {
         loadOpenAPI();
+    } else if (activeTab === 'leases-tab') {
+        loadLeaseAnalytics();
+    } else if (activeTab === 'egress-tab') {
+        loadEgress();
+    } else if (activeTab === 'logs-tab') {
+        followPluginLogs();
+    } else if (activeTab === 'debug-tab') {
+        loadRPCConsole();
     }
 }
 
@@ -41,7 +70,13 @@ async function loadDashboard() {
             fetch(`${API_BASE}/sys/storage`).then(r => r.json())
         ]);
         
-        renderPluginStatus(health);
+        // Type, special paths and protocol as the backend reports them
+        const backend = await fetch(`${API_BASE}/sys/host/backend`)
+            .then(r => r.ok ? r.json() : null)
+            .then(body => body ? body.data : null)
+            .catch(() => null);
+
+        renderPluginStatus(health, backend);
         renderQuickStats(health, storage);
         
         // Try to load OpenAPI for endpoints
@@ -58,7 +93,10 @@ async function loadDashboard() {
 }
 
 // Render plugin status
-function renderPluginStatus(health) {
+function renderPluginStatus(health, backend) {
+    backend = backend || {};
+    const paths = backend.special_paths || {};
+    const pathList = list => list && list.length ? list.map(p => `<code>${escapeHtml(p)}</code>`).join(', ') : '<span class="text-muted">none</span>';
     const statusHtml = `
         <div class="mb-2">
             <strong>Status:</strong>
@@ -66,10 +104,15 @@ function renderPluginStatus(health) {
                 ${health.plugin_running ? '✓ Running' : '✗ Stopped'}
             </span>
         </div>
-        ${health.plugin_type ? `<div><strong>Type:</strong> ${health.plugin_type}</div>` : ''}
-        ${health.mount_path ? `<div><strong>Mount Path:</strong> ${health.mount_path}</div>` : ''}
-        ${health.plugin_name ? `<div><strong>Plugin Name:</strong> ${health.plugin_name}</div>` : ''}
-        ${health.plugin_version ? `<div><strong>Version:</strong> ${health.plugin_version}</div>` : ''}
+        ${backend.type ? `<div><strong>Type:</strong> ${escapeHtml(backend.type)}</div>` : ''}
+        ${backend.mount ? `<div><strong>Mount Path:</strong> ${escapeHtml(backend.mount)}</div>` : ''}
+        ${backend.plugin ? `<div><strong>Plugin Name:</strong> ${escapeHtml(backend.plugin)}</div>` : ''}
+        ${backend.plugin_version ? `<div><strong>Version:</strong> ${escapeHtml(backend.plugin_version)}</div>` : ''}
+        ${backend.protocol ? `<div><strong>Protocol:</strong> ${escapeHtml(backend.protocol)} v${backend.protocol_version}${backend.multiplexed ? ' (multiplexed)' : ''}</div>` : ''}
+        ${backend.special_paths ? `
+            <div><strong>Unauthenticated:</strong> ${pathList(paths.unauthenticated)}</div>
+            <div><strong>Root:</strong> ${pathList(paths.root)}</div>
+        ` : ''}
     `;
     document.getElementById('pluginStatus').innerHTML = statusHtml;
 }
@@ -432,6 +475,12 @@ async function executeRequest(endpointId, path, method) {
             }
         };
         
+        // Send the token saved by the user when the host runs with -token
+        const token = localStorage.getItem('vaultToken');
+        if (token) {
+            options.headers['X-Vault-Token'] = token;
+        }
+        
         // Add request body if present
         const bodyTextarea = document.getElementById(`${endpointId}-body`);
         if (bodyTextarea && bodyTextarea.value && method.toUpperCase() !== 'GET') {
@@ -462,6 +511,7 @@ async function executeRequest(endpointId, path, method) {
         
         // Render response
         renderResponse(responseDiv, response.status, responseBody, duration, curlCmd);
+        renderSnippets(responseDiv, options.method, url, options.body);
         
     } catch (error) {
         responseDiv.innerHTML = `<div class="response-section"><div class="alert alert-danger mb-0">` +
@@ -518,6 +568,34 @@ function renderResponse(container, status, body, duration, curlCmd) {
     container.innerHTML = html;
 }
 
+// Render "copy as" snippets for an executed request
+async function renderSnippets(container, method, url, body) {
+    try {
+        const request = { method: method, path: url, data: body ? JSON.parse(body) : undefined };
+        const response = await fetch(`${API_BASE}/sys/host/snippets`, {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify(request)
+        });
+        if (!response.ok) return;
+        const snippets = await response.json();
+        
+        const labels = { vault_cli: 'Vault CLI', go: 'Go', terraform: 'Terraform' };
+        let html = '<details class="mt-3"><summary class="text-muted" style="cursor: pointer;"><i class="bi bi-code-square"></i> Copy As</summary>';
+        for (const [key, label] of Object.entries(labels)) {
+            const index = generatedSnippets.push(snippets[key]) - 1;
+            html += `<h6 class="mt-2">${label}</h6>`;
+            html += `<pre class="curl-command">${escapeHtml(snippets[key])}</pre>`;
+            html += `<button class="btn btn-sm btn-secondary" onclick="copyToClipboard(generatedSnippets[${index}])">`;
+            html += `<i class="bi bi-clipboard"></i> Copy ${label}</button>`;
+        }
+        html += '</details>';
+        container.querySelector('.response-section').insertAdjacentHTML('beforeend', html);
+    } catch (error) {
+        // Snippets are optional; the response itself is already shown
+    }
+}
+
 // Clear response
 function clearResponse(endpointId) {
     const responseDiv = document.getElementById(`${endpointId}-response`);
@@ -608,6 +686,295 @@ function copyToClipboard(text) {
     });
 }
 
+// Load lease TTL, renewal and lifetime analytics
+async function loadLeaseAnalytics() {
+    const content = document.getElementById('leasesContent');
+    content.innerHTML = '<div class="spinner-border spinner-border-sm" role="status"></div> Loading...';
+
+    try {
+        const response = await fetch(`${API_BASE}/sys/host/leases/analytics`);
+        const data = await response.json();
+        renderLeaseAnalytics(data);
+    } catch (error) {
+        content.innerHTML = `<div class="alert alert-danger">Error loading lease analytics: ${error.message}</div>`;
+    }
+}
+
+// Render lease counters and histograms
+function renderLeaseAnalytics(data) {
+    const content = document.getElementById('leasesContent');
+    const ended = data.ended || {};
+
+    const stat = (label, value) => `<div class="col"><div class="border rounded p-2 text-center"><div class="fs-4">${value}</div><small class="text-muted">${label}</small></div></div>`;
+    let html = `<div class="row g-2 mb-4">
+        ${stat('Issued', data.issued)}
+        ${stat('Active', data.active)}
+        ${stat('Renewals', data.renewals)}
+        ${stat('Revoked', ended.revoked || 0)}
+        ${stat('Expired', ended.expired || 0)}
+    </div>`;
+
+    html += '<div class="row">';
+    html += `<div class="col-md-4">${renderHistogram('Issued TTL', data.ttl.histogram, data.ttl)}</div>`;
+    html += `<div class="col-md-4">${renderHistogram('Actual lifetime', data.lifetime.histogram, data.lifetime)}</div>`;
+    html += `<div class="col-md-4">${renderHistogram('Renewals per lease', data.renewals_per_lease)}</div>`;
+    html += '</div>';
+    content.innerHTML = html;
+}
+
+// Render a histogram as horizontal bars, with min/mean/max when a summary is given
+function renderHistogram(title, buckets, summary) {
+    const max = Math.max(1, ...buckets.map(b => b.count));
+    let html = `<h6>${escapeHtml(title)}</h6>`;
+    if (summary && summary.count > 0) {
+        html += `<p class="text-muted small">min ${escapeHtml(summary.min)}, mean ${escapeHtml(summary.mean)}, max ${escapeHtml(summary.max)}</p>`;
+    }
+    buckets.forEach(b => {
+        const width = Math.round(100 * b.count / max);
+        html += `<div class="d-flex align-items-center mb-1">
+            <small class="text-muted text-end me-2" style="width: 5rem">&le; ${escapeHtml(b.le)}</small>
+            <div class="progress flex-grow-1" style="height: 1rem">
+                <div class="progress-bar" style="width: ${width}%"></div>
+            </div>
+            <small class="ms-2" style="width: 2rem">${b.count}</small>
+        </div>`;
+    });
+    return html;
+}
+
+// Reset lease analytics
+async function resetLeaseAnalytics() {
+    await fetch(`${API_BASE}/sys/host/leases/analytics`, { method: 'DELETE' });
+    loadLeaseAnalytics();
+}
+
+// Load outbound traffic recorded by the egress proxy
+async function loadEgress() {
+    const content = document.getElementById('egressContent');
+    content.innerHTML = '<div class="spinner-border spinner-border-sm" role="status"></div> Loading...';
+
+    try {
+        const response = await fetch(`${API_BASE}/sys/host/egress`);
+        if (response.status === 404) {
+            content.innerHTML = '<p class="text-muted">The egress proxy is disabled. Start the host with <code>-egress record</code> or <code>-egress replay</code>.</p>';
+            return;
+        }
+        const data = await response.json();
+        renderEgress(data);
+    } catch (error) {
+        content.innerHTML = `<div class="alert alert-danger">Error loading egress traffic: ${error.message}</div>`;
+    }
+}
+
+// Render recorded interactions, newest first
+function renderEgress(data) {
+    const content = document.getElementById('egressContent');
+    const interactions = (data.interactions || []).slice().reverse();
+    const violations = renderEgressViolations(data.violations || []);
+
+    if (interactions.length === 0) {
+        content.innerHTML = violations + `<p class="text-muted">No outbound requests yet (${escapeHtml(data.mode)} mode)</p>`;
+        return;
+    }
+
+    const sourceBadge = {live: 'bg-primary', cassette: 'bg-success', unmatched: 'bg-danger', blocked: 'bg-danger'};
+    let html = violations + `<p class="text-muted">${escapeHtml(data.mode)} mode</p><div class="accordion" id="egressAccordion">`;
+
+    interactions.forEach(item => {
+        const status = item.response.status;
+        const statusClass = item.error || status >= 400 ? 'text-danger' : 'text-success';
+        const request = `${item.request.method} ${item.request.url}\n\n${formatHeaders(item.request.headers)}\n${item.request.body || ''}`;
+        const response = `${status}\n\n${formatHeaders(item.response.headers)}\n${item.response.body || ''}`;
+
+        html += `<div class="accordion-item">
+            <h2 class="accordion-header">
+                <button class="accordion-button collapsed" type="button" data-bs-toggle="collapse" data-bs-target="#egressCollapse${item.id}">
+                    <div class="d-flex justify-content-between w-100 me-3">
+                        <span><code>${escapeHtml(item.request.method)}</code> ${escapeHtml(item.request.url)}</span>
+                        <span>
+                            <span class="badge ${sourceBadge[item.source] || 'bg-secondary'} me-2">${escapeHtml(item.source)}</span>
+                            <span class="${statusClass} me-2">${status}</span>
+                            <small class="text-muted">${escapeHtml(item.duration || '')}</small>
+                        </span>
+                    </div>
+                </button>
+            </h2>
+            <div id="egressCollapse${item.id}" class="accordion-collapse collapse" data-bs-parent="#egressAccordion">
+                <div class="accordion-body">
+                    ${item.error ? `<div class="alert alert-danger">${escapeHtml(item.error)}</div>` : ''}
+                    <h6>Request</h6>
+                    <pre>${escapeHtml(request)}</pre>
+                    <h6>Response</h6>
+                    <pre>${escapeHtml(response)}</pre>
+                </div>
+            </div>
+        </div>`;
+    });
+
+    html += '</div>';
+    content.innerHTML = html;
+}
+
+// Render egress policy violations as a warning list
+function renderEgressViolations(violations) {
+    if (violations.length === 0) {
+        return '';
+    }
+    const items = violations.map(v => `<li><code>${escapeHtml(v.method)}</code> ${escapeHtml(v.url)} &mdash; ${escapeHtml(v.reason)}${v.blocked ? ' (blocked)' : ''}</li>`);
+    return `<div class="alert alert-warning"><strong>Policy violations (${violations.length})</strong><ul class="mb-0">${items.join('')}</ul></div>`;
+}
+
+// Format a header map as "Name: value" lines
+function formatHeaders(headers) {
+    return Object.entries(headers || {})
+        .map(([name, values]) => values.map(v => `${name}: ${v}`).join('\n'))
+        .join('\n');
+}
+
+// Clear recorded egress traffic
+async function clearEgress() {
+    await fetch(`${API_BASE}/sys/host/egress`, { method: 'DELETE' });
+    loadEgress();
+}
+
+// Follow the plugin's stdout and stderr. EventSource cannot send the token header, so
+// the server-sent events are read from a streamed fetch instead.
+let pluginLogsAbort = null;
+
+async function followPluginLogs() {
+    stopPluginLogs();
+    const content = document.getElementById('pluginLogsContent');
+    content.textContent = '';
+
+    const abort = new AbortController();
+    pluginLogsAbort = abort;
+    const headers = {};
+    const token = localStorage.getItem('vaultToken');
+    if (token) {
+        headers['X-Vault-Token'] = token;
+    }
+
+    try {
+        const response = await fetch(`${API_BASE}/sys/plugin/logs?follow=true&lines=500`, { headers, signal: abort.signal });
+        if (!response.ok) {
+            const data = await response.json().catch(() => ({}));
+            throw new Error((data.errors || [response.statusText]).join(', '));
+        }
+        const reader = response.body.getReader();
+        const decoder = new TextDecoder();
+        let buffer = '';
+        while (true) {
+            const { value, done } = await reader.read();
+            if (done) {
+                break;
+            }
+            buffer += decoder.decode(value, { stream: true });
+            let end;
+            while ((end = buffer.indexOf('\n\n')) >= 0) {
+                appendPluginLogEvent(content, buffer.slice(0, end));
+                buffer = buffer.slice(end + 2);
+            }
+        }
+    } catch (error) {
+        if (error.name !== 'AbortError') {
+            content.insertAdjacentHTML('beforeend', `<span class="text-danger">Error following plugin output: ${escapeHtml(error.message)}</span>\n`);
+        }
+    }
+    if (pluginLogsAbort === abort) {
+        pluginLogsAbort = null;
+    }
+}
+
+// Append one server-sent event of /v1/sys/plugin/logs, keeping the view at the bottom
+// unless the user scrolled up
+function appendPluginLogEvent(content, event) {
+    let type = 'message';
+    let data = '';
+    event.split('\n').forEach(line => {
+        if (line.startsWith('event: ')) {
+            type = line.slice(7);
+        } else if (line.startsWith('data: ')) {
+            data += line.slice(6);
+        }
+    });
+
+    const row = document.createElement('span');
+    if (type === 'plugin-log-gap') {
+        row.className = 'text-warning';
+        row.textContent = `... ${JSON.parse(data).missed} lines dropped\n`;
+    } else if (type === 'plugin-log') {
+        const entry = JSON.parse(data);
+        row.className = entry.stream === 'stderr' ? 'text-warning-emphasis' : '';
+        row.textContent = `${entry.time.slice(11, 23)} ${entry.stream} ${entry.line}\n`;
+    } else {
+        return;
+    }
+
+    const atBottom = content.scrollTop + content.clientHeight >= content.scrollHeight - 5;
+    content.appendChild(row);
+    if (atBottom) {
+        content.scrollTop = content.scrollHeight;
+    }
+}
+
+// Stop following the plugin's output
+function stopPluginLogs() {
+    if (pluginLogsAbort) {
+        pluginLogsAbort.abort();
+        pluginLogsAbort = null;
+    }
+}
+
+// Clear the plugin output shown; the host keeps it
+function clearPluginLogs() {
+    document.getElementById('pluginLogsContent').textContent = '';
+}
+
+// Load gRPC services and available RPCs
+async function loadRPCConsole() {
+    const services = document.getElementById('grpcServices');
+    services.innerHTML = '<div class="spinner-border spinner-border-sm" role="status"></div> Loading...';
+
+    try {
+        const data = await fetch(`${API_BASE}/sys/host/rpc`).then(r => r.json());
+
+        if (data.services_error) {
+            services.innerHTML = `<div class="alert alert-warning">${escapeHtml(data.services_error)}</div>`;
+        } else {
+            let html = '<ul class="list-unstyled">';
+            (data.services || []).forEach(svc => {
+                html += `<li class="mb-2"><code>${escapeHtml(svc.name)}</code>`;
+                if (svc.methods && svc.methods.length > 0) {
+                    html += '<ul>' + svc.methods.map(m => `<li><small>${escapeHtml(m)}</small></li>`).join('') + '</ul>';
+                }
+                html += '</li>';
+            });
+            html += '</ul>';
+            services.innerHTML = html;
+        }
+
+        document.getElementById('rpcButtons').innerHTML = (data.rpcs || []).map(name =>
+            `<button class="btn btn-sm btn-secondary me-2" onclick="invokeRPC('${name}')">${name}</button>`
+        ).join('');
+    } catch (error) {
+        services.innerHTML = `<div class="alert alert-danger">Error loading RPC console: ${error.message}</div>`;
+    }
+}
+
+// Invoke a backend RPC and show the result
+async function invokeRPC(name) {
+    const result = document.getElementById('rpcResult');
+    result.classList.remove('d-none');
+    result.textContent = 'Calling...';
+
+    try {
+        const data = await fetch(`${API_BASE}/sys/host/rpc/${name}`, { method: 'POST' }).then(r => r.json());
+        result.textContent = JSON.stringify(data, null, 2);
+    } catch (error) {
+        result.textContent = `Error: ${error.message}`;
+    }
+}
+
 // Escape HTML
 function escapeHtml(text) {
     const div = document.createElement('div');